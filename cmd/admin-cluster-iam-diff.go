@@ -0,0 +1,708 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/klauspost/compress/zip"
+	"github.com/openstor/madmin-go/v4"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var iamDiffFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "apply",
+		Usage: "change TARGET to match SRC, including removals",
+	},
+}
+
+var adminClusterIAMDiffCmd = cli.Command{
+	Name:            "diff",
+	Usage:           "compare IAM info of clusters or exports",
+	Action:          mainClusterIAMDiff,
+	OnUsageError:    onUsageError,
+	Before:          setGlobalsFromContext,
+	Flags:           append(iamDiffFlags, globalFlags...),
+	HideHelpCommand: true,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] SRC TARGET
+
+  SRC and TARGET are aliases or zip files of 'mc admin cluster iam export'.
+  Policies are compared statement by statement, secret keys are not
+  compared. With --apply, TARGET must be an alias; policy mappings of STS
+  users are reported but not applied.
+
+  < - only in SRC.
+  > - only in TARGET.
+  ! - different.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Compare the IAM info of two clusters.
+     {{.Prompt}} {{.HelpName}} site1 site2
+
+  2. Detect the drift of a cluster since an export.
+     {{.Prompt}} {{.HelpName}} /tmp/myminio-iam-info.zip myminio
+
+  3. Restore the IAM info of a cluster as it was in an export.
+     {{.Prompt}} {{.HelpName}} --apply /tmp/myminio-iam-info.zip myminio
+`,
+}
+
+// Files of the zip of an IAM export.
+const (
+	iamExportPoliciesFile      = "policies.json"
+	iamExportUsersFile         = "users.json"
+	iamExportGroupsFile        = "groups.json"
+	iamExportSvcAcctsFile      = "svcaccts.json"
+	iamExportUserMappingsFile  = "user_mappings.json"
+	iamExportGroupMappingsFile = "group_mappings.json"
+	iamExportSTSMappingsFile   = "stsuser_mappings.json"
+	iamExportMaxFileSize       = 256 << 20
+)
+
+// Differences between SRC and TARGET, and the entities which differ.
+const (
+	iamDiffSourceOnly = "source-only"
+	iamDiffTargetOnly = "target-only"
+	iamDiffDifferent  = "different"
+
+	iamDiffTypePolicy         = "policy"
+	iamDiffTypeUser           = "user"
+	iamDiffTypeGroup          = "group"
+	iamDiffTypeUserPolicy     = "user-policy"
+	iamDiffTypeGroupPolicy    = "group-policy"
+	iamDiffTypeSTSPolicy      = "sts-policy"
+	iamDiffTypeServiceAccount = "service-account"
+)
+
+// iamMappedPolicy - policies attached to a user or a group, comma
+// separated.
+type iamMappedPolicy struct {
+	Policies string `json:"policy"`
+}
+
+func (m iamMappedPolicy) list() []string {
+	var policies []string
+	for _, p := range strings.Split(m.Policies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			policies = append(policies, p)
+		}
+	}
+	sort.Strings(policies)
+	return policies
+}
+
+// iamState - the IAM info of a cluster, as exported.
+type iamState struct {
+	Policies        map[string]json.RawMessage
+	Users           map[string]madmin.AddOrUpdateUserReq
+	Groups          map[string]madmin.GroupDesc
+	ServiceAccounts map[string]madmin.SRSvcAccCreate
+	UserPolicies    map[string]iamMappedPolicy
+	GroupPolicies   map[string]iamMappedPolicy
+	STSPolicies     map[string]iamMappedPolicy
+}
+
+// readIAMState reads the zip of an IAM export.
+func readIAMState(r io.ReaderAt, size int64) (*iamState, error) {
+	zr, e := zip.NewReader(r, size)
+	if e != nil {
+		return nil, e
+	}
+	s := &iamState{}
+	files := map[string]any{
+		iamExportPoliciesFile:      &s.Policies,
+		iamExportUsersFile:         &s.Users,
+		iamExportGroupsFile:        &s.Groups,
+		iamExportSvcAcctsFile:      &s.ServiceAccounts,
+		iamExportUserMappingsFile:  &s.UserPolicies,
+		iamExportGroupMappingsFile: &s.GroupPolicies,
+		iamExportSTSMappingsFile:   &s.STSPolicies,
+	}
+	for _, f := range zr.File {
+		v, ok := files[path.Base(f.Name)]
+		if !ok {
+			continue
+		}
+		if f.UncompressedSize64 > iamExportMaxFileSize {
+			return nil, fmt.Errorf("%s is too large", f.Name)
+		}
+		rc, e := f.Open()
+		if e != nil {
+			return nil, e
+		}
+		e = json.NewDecoder(rc).Decode(v)
+		rc.Close()
+		if e != nil && e != io.EOF {
+			return nil, fmt.Errorf("%s: %w", f.Name, e)
+		}
+	}
+	return s, nil
+}
+
+// loadIAMState reads the IAM info of an alias, returning its client, or
+// of an export.
+func loadIAMState(ctx context.Context, arg string) (*iamState, *madmin.AdminClient, *probe.Error) {
+	if st, e := os.Stat(arg); e == nil && !st.IsDir() {
+		f, e := os.Open(arg)
+		if e != nil {
+			return nil, nil, probe.NewError(e)
+		}
+		defer f.Close()
+		s, e := readIAMState(f, st.Size())
+		return s, nil, probe.NewError(e)
+	}
+
+	aliasedURL := filepath.Clean(filepath.ToSlash(arg))
+	client, err := newAdminClient(aliasedURL)
+	if err != nil {
+		return nil, nil, err.Trace(aliasedURL)
+	}
+	r, e := client.ExportIAM(ctx)
+	if e != nil {
+		return nil, nil, probe.NewError(e).Trace(aliasedURL)
+	}
+	defer r.Close()
+	buf, e := io.ReadAll(r)
+	if e != nil {
+		return nil, nil, probe.NewError(e).Trace(aliasedURL)
+	}
+	s, e := readIAMState(bytes.NewReader(buf), int64(len(buf)))
+	return s, client, probe.NewError(e)
+}
+
+// canonicalIAMPolicy returns the statements of a policy and the rest of
+// the policy in a canonical form, with sorted actions and resources.
+func canonicalIAMPolicy(p json.RawMessage) (statements []string, rest string, e error) {
+	var doc map[string]any
+	if e = json.Unmarshal(p, &doc); e != nil {
+		return nil, "", e
+	}
+	var list []any
+	switch s := doc["Statement"].(type) {
+	case []any:
+		list = s
+	case nil:
+	default:
+		list = []any{s}
+	}
+	delete(doc, "Statement")
+	for _, s := range list {
+		if stmt, ok := s.(map[string]any); ok {
+			for k, v := range stmt {
+				switch k {
+				case "Action", "NotAction", "Resource", "NotResource", "Principal", "NotPrincipal", "Condition":
+					stmt[k] = canonicalIAMValue(v)
+				}
+			}
+		}
+		b, e := json.Marshal(s)
+		if e != nil {
+			return nil, "", e
+		}
+		statements = append(statements, string(b))
+	}
+	sort.Strings(statements)
+	b, e := json.Marshal(doc)
+	return statements, string(b), e
+}
+
+// canonicalIAMValue sorts lists of strings and makes single strings
+// lists, so that equivalent actions, resources, principals and condition
+// values are equal.
+func canonicalIAMValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return []any{v}
+	case []any:
+		for _, s := range v {
+			if _, ok := s.(string); !ok {
+				return v
+			}
+		}
+		sort.Slice(v, func(i, j int) bool { return v[i].(string) < v[j].(string) })
+		return v
+	case map[string]any:
+		for k, s := range v {
+			v[k] = canonicalIAMValue(s)
+		}
+	}
+	return v
+}
+
+// iamDiffMessage - a difference between the IAM info of SRC and TARGET.
+type iamDiffMessage struct {
+	Status     string            `json:"status"`
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Diff       string            `json:"diff"`
+	Details    []string          `json:"details,omitempty"`
+	SourceOnly []json.RawMessage `json:"sourceOnlyStatements,omitempty"`
+	TargetOnly []json.RawMessage `json:"targetOnlyStatements,omitempty"`
+	Applied    bool              `json:"applied,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+func (d iamDiffMessage) JSON() string {
+	d.Status = "success"
+	if d.Error != "" {
+		d.Status = "error"
+	}
+	b, e := json.MarshalIndent(d, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(b)
+}
+
+func (d iamDiffMessage) String() string {
+	var b strings.Builder
+	msg := d.Type + " " + d.Name
+	if len(d.Details) > 0 {
+		msg += ": " + strings.Join(d.Details, ", ")
+	}
+	switch d.Diff {
+	case iamDiffSourceOnly:
+		b.WriteString(console.Colorize("DiffOnlyInFirst", "< "+msg))
+	case iamDiffTargetOnly:
+		b.WriteString(console.Colorize("DiffOnlyInSecond", "> "+msg))
+	default:
+		b.WriteString(console.Colorize("DiffType", "! "+msg))
+	}
+	switch {
+	case d.Error != "":
+		b.WriteString(console.Colorize("DiffFailed", " (failed: "+d.Error+")"))
+	case d.Applied:
+		b.WriteString(console.Colorize("DiffApplied", " (applied)"))
+	}
+	for _, s := range d.SourceOnly {
+		b.WriteString("\n" + console.Colorize("DiffOnlyInFirst", "  < "+string(s)))
+	}
+	for _, s := range d.TargetOnly {
+		b.WriteString("\n" + console.Colorize("DiffOnlyInSecond", "  > "+string(s)))
+	}
+	return b.String()
+}
+
+// iamDiffPhase orders the differences so that they can be applied:
+// entities are created before policies are attached to them, and
+// removed after their policies are detached.
+func iamDiffPhase(d iamDiffMessage) int {
+	removed := d.Diff == iamDiffTargetOnly
+	switch d.Type {
+	case iamDiffTypePolicy:
+		if removed {
+			return 8
+		}
+		return 0
+	case iamDiffTypeUser:
+		if removed {
+			return 7
+		}
+		return 1
+	case iamDiffTypeGroup:
+		if removed {
+			return 6
+		}
+		return 2
+	case iamDiffTypeServiceAccount:
+		if removed {
+			return 5
+		}
+		return 4
+	}
+	return 3
+}
+
+func iamStatusDetail(src, tgt string) string {
+	return fmt.Sprintf("status %s → %s", tgt, src)
+}
+
+// setDiff returns the elements only in a and only in b.
+func setDiff(a, b []string) (onlyA, onlyB []string) {
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			onlyA = append(onlyA, s)
+		}
+	}
+	for _, s := range b {
+		if !slices.Contains(a, s) {
+			onlyB = append(onlyB, s)
+		}
+	}
+	return onlyA, onlyB
+}
+
+// diffIAMStates returns the differences between src and tgt, in the
+// order in which they are applied.
+func diffIAMStates(src, tgt *iamState) ([]iamDiffMessage, error) {
+	var diffs []iamDiffMessage
+	// compare reports the names only in src or tgt and calls different
+	// for the names in both.
+	compare := func(typ string, srcNames, tgtNames []string, different func(name string) (*iamDiffMessage, error)) error {
+		onlySrc, onlyTgt := setDiff(srcNames, tgtNames)
+		for _, name := range onlySrc {
+			diffs = append(diffs, iamDiffMessage{Type: typ, Name: name, Diff: iamDiffSourceOnly})
+		}
+		for _, name := range onlyTgt {
+			diffs = append(diffs, iamDiffMessage{Type: typ, Name: name, Diff: iamDiffTargetOnly})
+		}
+		for _, name := range srcNames {
+			if !slices.Contains(tgtNames, name) {
+				continue
+			}
+			d, e := different(name)
+			if e != nil {
+				return fmt.Errorf("%s %s: %w", typ, name, e)
+			}
+			if d != nil {
+				d.Type, d.Name, d.Diff = typ, name, iamDiffDifferent
+				diffs = append(diffs, *d)
+			}
+		}
+		return nil
+	}
+
+	e := compare(iamDiffTypePolicy, mapKeys(src.Policies), mapKeys(tgt.Policies), func(name string) (*iamDiffMessage, error) {
+		srcStmts, srcRest, e := canonicalIAMPolicy(src.Policies[name])
+		if e != nil {
+			return nil, e
+		}
+		tgtStmts, tgtRest, e := canonicalIAMPolicy(tgt.Policies[name])
+		if e != nil {
+			return nil, e
+		}
+		onlySrc, onlyTgt := setDiff(srcStmts, tgtStmts)
+		if len(onlySrc)+len(onlyTgt) == 0 && srcRest == tgtRest {
+			return nil, nil
+		}
+		d := &iamDiffMessage{}
+		for _, s := range onlySrc {
+			d.SourceOnly = append(d.SourceOnly, json.RawMessage(s))
+		}
+		for _, s := range onlyTgt {
+			d.TargetOnly = append(d.TargetOnly, json.RawMessage(s))
+		}
+		if srcRest != tgtRest {
+			d.Details = append(d.Details, "version or id")
+		}
+		return d, nil
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	compare(iamDiffTypeUser, mapKeys(src.Users), mapKeys(tgt.Users), func(name string) (*iamDiffMessage, error) {
+		if s, t := src.Users[name].Status, tgt.Users[name].Status; s != t {
+			return &iamDiffMessage{Details: []string{iamStatusDetail(string(s), string(t))}}, nil
+		}
+		return nil, nil
+	})
+
+	compare(iamDiffTypeGroup, mapKeys(src.Groups), mapKeys(tgt.Groups), func(name string) (*iamDiffMessage, error) {
+		s, t := src.Groups[name], tgt.Groups[name]
+		var details []string
+		if s.Status != t.Status {
+			details = append(details, iamStatusDetail(s.Status, t.Status))
+		}
+		onlySrc, onlyTgt := setDiff(s.Members, t.Members)
+		for _, m := range onlySrc {
+			details = append(details, "member < "+m)
+		}
+		for _, m := range onlyTgt {
+			details = append(details, "member > "+m)
+		}
+		if len(details) == 0 {
+			return nil, nil
+		}
+		return &iamDiffMessage{Details: details}, nil
+	})
+
+	for _, m := range []struct {
+		typ      string
+		src, tgt map[string]iamMappedPolicy
+	}{
+		{iamDiffTypeUserPolicy, src.UserPolicies, tgt.UserPolicies},
+		{iamDiffTypeGroupPolicy, src.GroupPolicies, tgt.GroupPolicies},
+		{iamDiffTypeSTSPolicy, src.STSPolicies, tgt.STSPolicies},
+	} {
+		start := len(diffs)
+		compare(m.typ, mapKeys(m.src), mapKeys(m.tgt), func(name string) (*iamDiffMessage, error) {
+			onlySrc, onlyTgt := setDiff(m.src[name].list(), m.tgt[name].list())
+			if len(onlySrc)+len(onlyTgt) == 0 {
+				return nil, nil
+			}
+			d := &iamDiffMessage{}
+			for _, p := range onlySrc {
+				d.Details = append(d.Details, "policy < "+p)
+			}
+			for _, p := range onlyTgt {
+				d.Details = append(d.Details, "policy > "+p)
+			}
+			return d, nil
+		})
+		for i := start; i < len(diffs); i++ {
+			switch diffs[i].Diff {
+			case iamDiffSourceOnly:
+				diffs[i].Details = []string{"policy < " + m.src[diffs[i].Name].Policies}
+			case iamDiffTargetOnly:
+				diffs[i].Details = []string{"policy > " + m.tgt[diffs[i].Name].Policies}
+			}
+		}
+	}
+
+	e = compare(iamDiffTypeServiceAccount, mapKeys(src.ServiceAccounts), mapKeys(tgt.ServiceAccounts), func(name string) (*iamDiffMessage, error) {
+		s, t := src.ServiceAccounts[name], tgt.ServiceAccounts[name]
+		var details []string
+		if s.Parent != t.Parent {
+			details = append(details, fmt.Sprintf("parent %s → %s", t.Parent, s.Parent))
+		}
+		if s.Status != t.Status {
+			details = append(details, iamStatusDetail(s.Status, t.Status))
+		}
+		srcPolicy, tgtPolicy := iamSessionPolicy(s.SessionPolicy), iamSessionPolicy(t.SessionPolicy)
+		if (srcPolicy == nil) != (tgtPolicy == nil) {
+			details = append(details, "session policy")
+		} else if srcPolicy != nil {
+			srcStmts, _, e := canonicalIAMPolicy(srcPolicy)
+			if e != nil {
+				return nil, e
+			}
+			tgtStmts, _, e := canonicalIAMPolicy(tgtPolicy)
+			if e != nil {
+				return nil, e
+			}
+			if !slices.Equal(srcStmts, tgtStmts) {
+				details = append(details, "session policy")
+			}
+		}
+		if len(details) == 0 {
+			return nil, nil
+		}
+		return &iamDiffMessage{Details: details}, nil
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		if pi, pj := iamDiffPhase(diffs[i]), iamDiffPhase(diffs[j]); pi != pj {
+			return pi < pj
+		}
+		if diffs[i].Type != diffs[j].Type {
+			return diffs[i].Type < diffs[j].Type
+		}
+		return diffs[i].Name < diffs[j].Name
+	})
+	return diffs, nil
+}
+
+// iamSessionPolicy returns the session policy of a service account, nil
+// if it has the policies of its parent.
+func iamSessionPolicy(p madmin.SRSessionPolicy) json.RawMessage {
+	if s := strings.TrimSpace(string(p)); s == "" || s == "null" || s == "{}" {
+		return nil
+	}
+	return json.RawMessage(p)
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// applyIAMDiff changes the target so that it matches the source.
+func applyIAMDiff(ctx context.Context, client *madmin.AdminClient, src, tgt *iamState, d iamDiffMessage) error {
+	switch d.Type {
+	case iamDiffTypePolicy:
+		if d.Diff == iamDiffTargetOnly {
+			return client.RemoveCannedPolicy(ctx, d.Name)
+		}
+		return client.AddCannedPolicy(ctx, d.Name, src.Policies[d.Name])
+
+	case iamDiffTypeUser:
+		switch d.Diff {
+		case iamDiffTargetOnly:
+			return client.RemoveUser(ctx, d.Name)
+		case iamDiffSourceOnly:
+			u := src.Users[d.Name]
+			return client.SetUser(ctx, d.Name, u.SecretKey, u.Status)
+		}
+		return client.SetUserStatus(ctx, d.Name, src.Users[d.Name].Status)
+
+	case iamDiffTypeGroup:
+		s, t := src.Groups[d.Name], tgt.Groups[d.Name]
+		if d.Diff == iamDiffTargetOnly {
+			if len(t.Members) > 0 {
+				if e := client.UpdateGroupMembers(ctx, madmin.GroupAddRemove{Group: d.Name, Members: t.Members, IsRemove: true}); e != nil {
+					return e
+				}
+			}
+			return client.UpdateGroupMembers(ctx, madmin.GroupAddRemove{Group: d.Name, IsRemove: true})
+		}
+		add, remove := setDiff(s.Members, t.Members)
+		if len(add) > 0 || d.Diff == iamDiffSourceOnly {
+			if e := client.UpdateGroupMembers(ctx, madmin.GroupAddRemove{Group: d.Name, Members: add}); e != nil {
+				return e
+			}
+		}
+		if len(remove) > 0 {
+			if e := client.UpdateGroupMembers(ctx, madmin.GroupAddRemove{Group: d.Name, Members: remove, IsRemove: true}); e != nil {
+				return e
+			}
+		}
+		if s.Status != t.Status && s.Status != "" {
+			return client.SetGroupStatus(ctx, d.Name, madmin.GroupStatus(s.Status))
+		}
+		return nil
+
+	case iamDiffTypeUserPolicy, iamDiffTypeGroupPolicy:
+		srcMap, tgtMap := src.UserPolicies, tgt.UserPolicies
+		req := func(policies []string) madmin.PolicyAssociationReq {
+			return madmin.PolicyAssociationReq{Policies: policies, User: d.Name}
+		}
+		if d.Type == iamDiffTypeGroupPolicy {
+			srcMap, tgtMap = src.GroupPolicies, tgt.GroupPolicies
+			req = func(policies []string) madmin.PolicyAssociationReq {
+				return madmin.PolicyAssociationReq{Policies: policies, Group: d.Name}
+			}
+		}
+		attach, detach := setDiff(srcMap[d.Name].list(), tgtMap[d.Name].list())
+		if len(attach) > 0 {
+			if _, e := client.AttachPolicy(ctx, req(attach)); e != nil {
+				return e
+			}
+		}
+		if len(detach) > 0 {
+			if _, e := client.DetachPolicy(ctx, req(detach)); e != nil {
+				return e
+			}
+		}
+		return nil
+
+	case iamDiffTypeServiceAccount:
+		if d.Diff == iamDiffTargetOnly {
+			return client.DeleteServiceAccount(ctx, d.Name)
+		}
+		s, t := src.ServiceAccounts[d.Name], tgt.ServiceAccounts[d.Name]
+		if d.Diff == iamDiffDifferent && s.Parent == t.Parent {
+			return client.UpdateServiceAccount(ctx, d.Name, madmin.UpdateServiceAccountReq{
+				NewPolicy: iamSessionPolicy(s.SessionPolicy),
+				NewStatus: s.Status,
+			})
+		}
+		if d.Diff == iamDiffDifferent {
+			// Service accounts cannot change of parent.
+			if e := client.DeleteServiceAccount(ctx, d.Name); e != nil {
+				return e
+			}
+		}
+		_, e := client.AddServiceAccount(ctx, madmin.AddServiceAccountReq{
+			Policy:      iamSessionPolicy(s.SessionPolicy),
+			TargetUser:  s.Parent,
+			AccessKey:   s.AccessKey,
+			SecretKey:   s.SecretKey,
+			Name:        s.Name,
+			Description: s.Description,
+			Expiration:  s.Expiration,
+		})
+		if e == nil && s.Status == "off" {
+			e = client.UpdateServiceAccount(ctx, d.Name, madmin.UpdateServiceAccountReq{NewStatus: s.Status})
+		}
+		return e
+	}
+	return errors.New("not applied, STS users are managed by the identity provider")
+}
+
+func checkIAMDiffSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 2 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+}
+
+// mainClusterIAMDiff - iam info diff command
+func mainClusterIAMDiff(ctx context.Context, cmd *cli.Command) error {
+	checkIAMDiffSyntax(ctx, cmd)
+
+	console.SetColor("DiffOnlyInFirst", color.New(color.FgRed))
+	console.SetColor("DiffOnlyInSecond", color.New(color.FgGreen))
+	console.SetColor("DiffType", color.New(color.FgYellow))
+	console.SetColor("DiffApplied", color.New(color.FgGreen, color.Bold))
+	console.SetColor("DiffFailed", color.New(color.FgRed, color.Bold))
+
+	args := cmd.Args()
+	srcArg, tgtArg := args.Get(0), args.Get(1)
+	src, _, err := loadIAMState(ctx, srcArg)
+	fatalIf(err.Trace(srcArg), "Unable to read IAM info of `%s`.", srcArg)
+	tgt, client, err := loadIAMState(ctx, tgtArg)
+	fatalIf(err.Trace(tgtArg), "Unable to read IAM info of `%s`.", tgtArg)
+
+	apply := cmd.Bool("apply")
+	if apply && client == nil {
+		fatalIf(errInvalidArgument().Trace(tgtArg), "--apply needs an alias as TARGET.")
+	}
+
+	diffs, e := diffIAMStates(src, tgt)
+	fatalIf(probe.NewError(e), "Unable to compare IAM info.")
+	if len(diffs) == 0 && !globalJSON {
+		console.Infoln("No differences found.")
+		return nil
+	}
+
+	failed := false
+	for _, d := range diffs {
+		if apply {
+			if e := applyIAMDiff(ctx, client, src, tgt, d); e != nil {
+				d.Error = e.Error()
+				if d.Type != iamDiffTypeSTSPolicy {
+					failed = true
+				}
+			} else {
+				d.Applied = true
+			}
+		}
+		printMsg(d)
+	}
+	if failed {
+		return exitStatus(globalErrorExitStatus)
+	}
+	return nil
+}
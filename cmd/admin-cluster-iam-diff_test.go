@@ -0,0 +1,165 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/klauspost/compress/zip"
+	"github.com/openstor/madmin-go/v4"
+)
+
+func testIAMExport(t *testing.T, files map[string]string) *iamState {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, e := zw.Create("iam-assets/" + name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		w.Write([]byte(content))
+	}
+	if e := zw.Close(); e != nil {
+		t.Fatal(e)
+	}
+	s, e := readIAMState(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if e != nil {
+		t.Fatal(e)
+	}
+	return s
+}
+
+func TestDiffIAMStates(t *testing.T) {
+	src := testIAMExport(t, map[string]string{
+		"policies.json": `{
+ "readonly": {"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["s3:GetObject", "s3:GetBucketLocation"], "Resource": ["arn:aws:s3:::*"]}]},
+ "app": {"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["s3:*"], "Resource": ["arn:aws:s3:::app/*"]}]}
+}`,
+		"users.json":         `{"alice": {"secretKey": "alice-secret", "status": "enabled"}, "bob": {"secretKey": "bob-secret", "status": "enabled"}}`,
+		"groups.json":        `{"devs": {"name": "devs", "status": "enabled", "members": ["alice", "bob"]}}`,
+		"svcaccts.json":      `{"svc1": {"parent": "alice", "accessKey": "svc1", "secretKey": "svc1-secret", "status": "on", "sessionPolicy": null}}`,
+		"user_mappings.json": `{"alice": {"version": 1, "policy": "readonly,app"}}`,
+	})
+	tgt := testIAMExport(t, map[string]string{
+		"policies.json": `{
+ "readonly": {"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": ["s3:GetBucketLocation", "s3:GetObject"], "Resource": "arn:aws:s3:::*"}},
+ "app": {"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["s3:GetObject"], "Resource": ["arn:aws:s3:::app/*"]}]},
+ "old": {"Version": "2012-10-17", "Statement": []}
+}`,
+		"users.json":         `{"alice": {"status": "disabled"}, "carol": {"status": "enabled"}}`,
+		"groups.json":        `{"devs": {"name": "devs", "status": "enabled", "members": ["alice", "carol"]}}`,
+		"svcaccts.json":      `{"svc1": {"parent": "carol", "accessKey": "svc1", "status": "on", "sessionPolicy": {}}}`,
+		"user_mappings.json": `{"alice": {"version": 1, "policy": "readonly"}, "carol": {"version": 1, "policy": "old"}}`,
+	})
+
+	diffs, e := diffIAMStates(src, tgt)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var got []string
+	for _, d := range diffs {
+		got = append(got, d.Diff+" "+d.Type+" "+d.Name+" "+strings.Join(d.Details, ","))
+	}
+	expected := []string{
+		"different policy app ",
+		"different user alice status disabled → enabled",
+		"source-only user bob ",
+		"different group devs member < bob,member > carol",
+		"different user-policy alice policy < app",
+		"target-only user-policy carol policy > old",
+		"different service-account svc1 parent carol → alice",
+		"target-only user carol ",
+		"target-only policy old ",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if app := diffs[0]; len(app.SourceOnly) != 1 || len(app.TargetOnly) != 1 || !strings.Contains(string(app.SourceOnly[0]), `"s3:*"`) {
+		t.Errorf("unexpected statements %s %s", app.SourceOnly, app.TargetOnly)
+	}
+
+	// The differences are applied in order.
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Paths are /minio/admin/VERSION/CALL.
+		call := strings.SplitN(r.URL.Path, "/", 5)[4]
+		for _, k := range []string{"name", "accessKey", "group", "isGroup"} {
+			if v := r.URL.Query().Get(k); v != "" {
+				call += " " + v
+			}
+		}
+		calls = append(calls, call)
+		// Responses with credentials are encrypted with the secret key.
+		var resp any
+		switch call {
+		case "idp/builtin/policy/attach", "idp/builtin/policy/detach":
+			resp = madmin.PolicyAssociationResp{}
+		case "add-service-account":
+			resp = madmin.AddServiceAccountResp{}
+		case "delete-service-account svc1":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			return
+		}
+		b, _ := json.Marshal(resp)
+		b, _ = madmin.EncryptData("password", b)
+		w.Write(b)
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	client, e := madmin.New(u.Host, "admin", "password", false)
+	if e != nil {
+		t.Fatal(e)
+	}
+	for _, d := range diffs {
+		if e := applyIAMDiff(context.Background(), client, src, tgt, d); e != nil {
+			t.Fatalf("%s %s: %v (%q)", d.Type, d.Name, e, calls)
+		}
+	}
+	expectedCalls := []string{
+		"add-canned-policy app",
+		"set-user-status alice",
+		"add-user bob",
+		"update-group-members",
+		"update-group-members",
+		"idp/builtin/policy/attach",
+		"idp/builtin/policy/detach",
+		"delete-service-account svc1",
+		"add-service-account",
+		"remove-user carol",
+		"remove-canned-policy old",
+	}
+	if strings.Join(calls, "\n") != strings.Join(expectedCalls, "\n") {
+		t.Errorf("expected calls\n%s\ngot\n%s", strings.Join(expectedCalls, "\n"), strings.Join(calls, "\n"))
+	}
+}
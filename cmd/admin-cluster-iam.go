@@ -26,6 +26,7 @@ import (
 var adminClusterIAMSubcommands = []*cli.Command{
 	&adminClusterIAMImportCmd,
 	&adminClusterIAMExportCmd,
+	&adminClusterIAMDiffCmd,
 }
 
 var adminClusterIAMCmd = cli.Command{
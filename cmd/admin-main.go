@@ -32,35 +32,35 @@ const (
 	check = "✔"
 )
 
-var adminCmdSubcommands = []cli.Command{
-	adminServiceCmd,
-	adminServerUpdateCmd,
-	adminInfoCmd,
-	adminInspectCmd,
-	adminUserCmd,
-	adminGroupCmd,
-	*adminPolicyCmd,
-	adminReplicateCmd,
-	adminIDPCmd,
-	adminConfigCmd,
-	adminDecommissionCmd,
-	adminHealCmd,
-	adminPrometheusCmd,
-	adminKMSCmd,
+var adminCmdSubcommands = []*cli.Command{
+	&adminServiceCmd,
+	&adminServerUpdateCmd,
+	&adminInfoCmd,
+	&adminInspectCmd,
+	&adminUserCmd,
+	&adminGroupCmd,
+	adminPolicyCmd,
+	&adminReplicateCmd,
+	&adminIDPCmd,
+	&adminConfigCmd,
+	&adminDecommissionCmd,
+	&adminHealCmd,
+	&adminPrometheusCmd,
+	&adminKMSCmd,
 	adminHealthCmd(),
-	adminSubnetCmd,
-	adminBucketCmd,
-	adminTierCmd,
-	adminSpeedtestCmd,
-	adminProfileCmd,
-	adminScannerCmd,
-	adminTopCmd,
-	adminTraceCmd,
-	adminConsoleCmd,
-	adminClusterCmd,
-	adminRebalanceCmd,
-	adminLogsCmd,
-	adminAccesskeyCmd,
+	&adminSubnetCmd,
+	&adminBucketCmd,
+	&adminTierCmd,
+	&adminSpeedtestCmd,
+	&adminProfileCmd,
+	&adminScannerCmd,
+	&adminTopCmd,
+	&adminTraceCmd,
+	&adminConsoleCmd,
+	&adminClusterCmd,
+	&adminRebalanceCmd,
+	&adminLogsCmd,
+	&adminAccesskeyCmd,
 }
 
 var adminCmd = cli.Command{
@@ -70,13 +70,18 @@ var adminCmd = cli.Command{
 	HideHelp: true,
 	Before:   setGlobalsFromContext,
 	Flags:    append(adminFlags, globalFlags...),
+	Commands: adminCmdSubcommands,
 }
 
 const dateTimeFormatFilename = "2006-01-02T15-04-05.999999-07-00"
 
 // mainAdmin is the handle for "mc admin" command.
 func mainAdmin(ctx context.Context, cmd *cli.Command) error {
-	commandNotFound(ctx, cmd, adminCmdSubcommands)
+	var cmds []cli.Command
+	for _, c := range adminCmdSubcommands {
+		cmds = append(cmds, *c)
+	}
+	commandNotFound(ctx, cmd, cmds)
 	return nil
 	// Sub-commands like "service", "heal", "top" have their own main.
 }
@@ -0,0 +1,414 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/openstor/madmin-go/v4"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/openstor/pkg/v3/policy"
+	"github.com/openstor/pkg/v3/wildcard"
+	"github.com/urfave/cli/v3"
+)
+
+var policyGenerateFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "from-trace",
+		Usage: "trace saved by 'mc admin trace --verbose --json', '-' reads from stdin",
+	},
+	&cli.StringFlag{
+		Name:  "user",
+		Usage: "only generate the policy of this access key",
+	},
+	&cli.IntFlag{
+		Name:  "prefix-depth",
+		Usage: "allow objects by their first N folders instead of by name",
+	},
+	&cli.StringSliceFlag{
+		Name:  "allow",
+		Usage: "additional action to allow on the buckets or objects used",
+	},
+}
+
+var adminPolicyGenerateCmd = &cli.Command{
+	Name:         "generate",
+	Usage:        "generate least-privilege policies from a saved trace",
+	Action:       mainAdminPolicyGenerate,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(policyGenerateFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} --from-trace FILE [FLAGS]
+
+  The access key of a call is read from its Authorization header or its
+  presigned URL, traces must be saved with --verbose. Calls denied by the
+  server are ignored. A policy is generated for every access key of the
+  trace, unless --user is given.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Generate the policy of service account 'svc-etl' from a day of traces.
+     {{.Prompt}} mc admin trace --verbose --json myminio > /tmp/trace.json
+     {{.Prompt}} {{.HelpName}} --from-trace /tmp/trace.json --user svc-etl > /tmp/svc-etl.json
+     {{.Prompt}} mc admin user svcacct edit myminio svc-etl --policy /tmp/svc-etl.json
+
+  2. Allow objects by their top level folder and allow to delete them as well.
+     {{.Prompt}} {{.HelpName}} --from-trace /tmp/trace.json --user svc-etl --prefix-depth 1 --allow s3:DeleteObject
+
+  3. Generate the policies of all access keys of a trace.
+     {{.Prompt}} {{.HelpName}} --from-trace /tmp/trace.json
+`,
+}
+
+// Actions of the S3 APIs whose names differ from their action, other APIs
+// need the action of the same name.
+var traceAPIActions = map[string][]policy.Action{
+	"HeadObject":                    {policy.GetObjectAction},
+	"HeadBucket":                    {policy.ListBucketAction},
+	"ListBuckets":                   {policy.ListAllMyBucketsAction},
+	"ListObjectsV1":                 {policy.ListBucketAction},
+	"ListObjectsV2":                 {policy.ListBucketAction},
+	"ListObjectVersions":            {policy.ListBucketVersionsAction},
+	"CopyObject":                    {policy.PutObjectAction},
+	"CopyObjectPart":                {policy.PutObjectAction},
+	"NewMultipartUpload":            {policy.PutObjectAction},
+	"PutObjectPart":                 {policy.PutObjectAction},
+	"CompleteMultipartUpload":       {policy.PutObjectAction},
+	"ListObjectParts":               {policy.ListMultipartUploadPartsAction},
+	"ListMultipartUploads":          {policy.ListBucketMultipartUploadsAction},
+	"DeleteMultipleObjects":         {policy.DeleteObjectAction},
+	"PostPolicyBucket":              {policy.PutObjectAction},
+	"SelectObjectContent":           {policy.GetObjectAction},
+	"PutBucket":                     {policy.CreateBucketAction},
+	"PutBucketLifecycle":            {policy.PutBucketLifecycleAction},
+	"GetBucketLifecycle":            {policy.GetBucketLifecycleAction},
+	"DeleteBucketLifecycle":         {policy.PutBucketLifecycleAction},
+	"PutBucketEncryption":           {policy.PutBucketEncryptionAction},
+	"GetBucketEncryption":           {policy.GetBucketEncryptionAction},
+	"DeleteBucketEncryption":        {policy.PutBucketEncryptionAction},
+	"PutBucketObjectLockConfig":     {policy.PutBucketObjectLockConfigurationAction},
+	"GetBucketObjectLockConfig":     {policy.GetBucketObjectLockConfigurationAction},
+	"PutBucketReplicationConfig":    {policy.PutReplicationConfigurationAction},
+	"GetBucketReplicationConfig":    {policy.GetReplicationConfigurationAction},
+	"DeleteBucketReplicationConfig": {policy.PutReplicationConfigurationAction},
+	"DeleteBucketTagging":           {policy.PutBucketTaggingAction},
+}
+
+// traceCallActions returns the actions a call needs, nil when unknown.
+func traceCallActions(api string) []policy.Action {
+	if actions, ok := traceAPIActions[api]; ok {
+		return actions
+	}
+	if action := policy.Action("s3:" + api); action.IsValid() {
+		return []policy.Action{action}
+	}
+	return nil
+}
+
+// traceAccessKey returns the access key which signed a call, from its
+// Authorization header or its presigned URL.
+func traceAccessKey(t madmin.TraceInfo) string {
+	if t.HTTP == nil {
+		return ""
+	}
+	auth := t.HTTP.ReqInfo.Headers.Get("Authorization")
+	switch {
+	case strings.HasPrefix(auth, "AWS4-HMAC-SHA256 "):
+		if _, cred, ok := strings.Cut(auth, "Credential="); ok {
+			key, _, _ := strings.Cut(cred, "/")
+			return key
+		}
+	case strings.HasPrefix(auth, "AWS "):
+		key, _, _ := strings.Cut(strings.TrimPrefix(auth, "AWS "), ":")
+		return key
+	}
+	query, _ := url.ParseQuery(t.HTTP.ReqInfo.RawQuery)
+	if cred := query.Get("X-Amz-Credential"); cred != "" {
+		key, _, _ := strings.Cut(cred, "/")
+		return key
+	}
+	return query.Get("AWSAccessKeyId")
+}
+
+// policyUsage - the actions an access key used, by resource ARN.
+type policyUsage struct {
+	calls     int
+	resources map[string]map[policy.Action]bool
+	buckets   map[string]bool
+	objects   map[string]bool
+}
+
+func newPolicyUsage() *policyUsage {
+	return &policyUsage{
+		resources: make(map[string]map[policy.Action]bool),
+		buckets:   make(map[string]bool),
+		objects:   make(map[string]bool),
+	}
+}
+
+// add records an action on a bucket or an object. Objects are
+// generalized to their first depth folders.
+func (u *policyUsage) add(action policy.Action, bucket, object string, depth int) {
+	var resource string
+	switch {
+	case bucket == "" || action == policy.ListAllMyBucketsAction:
+		resource = policy.ResourceARNPrefix + "*"
+	case !action.IsObjectAction():
+		resource = policy.ResourceARNPrefix + bucket
+		u.buckets[resource] = true
+	default:
+		if object == "" {
+			object = "*"
+		} else if folders := strings.Split(object, "/"); depth > 0 && len(folders) > 1 {
+			folders = folders[:len(folders)-1]
+			object = strings.Join(folders[:min(depth, len(folders))], "/") + "/*"
+		}
+		resource = policy.ResourceARNPrefix + bucket + "/" + object
+		u.buckets[policy.ResourceARNPrefix+bucket] = true
+		u.objects[resource] = true
+	}
+	u.grant(resource, action)
+}
+
+func (u *policyUsage) grant(resource string, action policy.Action) {
+	if u.resources[resource] == nil {
+		u.resources[resource] = make(map[policy.Action]bool)
+	}
+	u.resources[resource][action] = true
+}
+
+// allow adds actions on all buckets or objects used.
+func (u *policyUsage) allow(actions []policy.Action) {
+	for _, action := range actions {
+		switch {
+		case action == policy.ListAllMyBucketsAction:
+			u.add(action, "", "", 0)
+		case !action.IsObjectAction():
+			for resource := range u.buckets {
+				u.grant(resource, action)
+			}
+		case len(u.objects) > 0:
+			for resource := range u.objects {
+				u.grant(resource, action)
+			}
+		default:
+			for resource := range u.buckets {
+				u.grant(resource+"/*", action)
+			}
+		}
+	}
+}
+
+// generatedStatement - a statement of a generated policy, its fields are
+// sorted so that policies of the same usage are equal.
+type generatedStatement struct {
+	Effect   policy.Effect `json:"Effect"`
+	Action   []string      `json:"Action"`
+	Resource []string      `json:"Resource"`
+}
+
+type generatedPolicy struct {
+	Version   string               `json:"Version"`
+	Statement []generatedStatement `json:"Statement"`
+}
+
+// policy returns the policy which allows the usage, resources with the
+// same actions share a statement. Actions allowed by a wildcard resource
+// are not repeated for the resources it matches.
+func (u *policyUsage) policy() generatedPolicy {
+	statements := make(map[string]*generatedStatement)
+	for resource, actions := range u.resources {
+		var names []string
+		for action := range actions {
+			covered := false
+			for other, otherActions := range u.resources {
+				if other != resource && strings.HasSuffix(other, "*") && otherActions[action] && wildcard.Match(other, resource) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				names = append(names, string(action))
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+		key := strings.Join(names, ",")
+		if statements[key] == nil {
+			statements[key] = &generatedStatement{Effect: policy.Allow, Action: names}
+		}
+		statements[key].Resource = append(statements[key].Resource, resource)
+	}
+
+	p := generatedPolicy{Version: policy.DefaultVersion}
+	for _, st := range statements {
+		sort.Strings(st.Resource)
+		p.Statement = append(p.Statement, *st)
+	}
+	sort.Slice(p.Statement, func(i, j int) bool {
+		return strings.Join(p.Statement[i].Action, ",") < strings.Join(p.Statement[j].Action, ",")
+	})
+	return p
+}
+
+// traceUsage collects the actions each access key used in a trace. It
+// also returns the APIs whose actions are unknown.
+func traceUsage(calls []madmin.TraceInfo, user string, depth int) (map[string]*policyUsage, []string) {
+	usage := make(map[string]*policyUsage)
+	unknown := make(map[string]bool)
+	for _, t := range calls {
+		if t.TraceType != madmin.TraceS3 || t.HTTP == nil {
+			continue
+		}
+		// Calls the server denied are not part of the usage.
+		if status := t.HTTP.RespInfo.StatusCode; status == http.StatusUnauthorized || status == http.StatusForbidden {
+			continue
+		}
+		key := traceAccessKey(t)
+		if key == "" || (user != "" && key != user) {
+			continue
+		}
+		api := strings.TrimPrefix(t.FuncName, "s3.")
+		actions := traceCallActions(api)
+		if actions == nil {
+			unknown[api] = true
+			continue
+		}
+		if usage[key] == nil {
+			usage[key] = newPolicyUsage()
+		}
+		u := usage[key]
+		u.calls++
+		bucket, object, _ := strings.Cut(strings.TrimPrefix(t.Path, "/"), "/")
+		for _, action := range actions {
+			u.add(action, bucket, object, depth)
+		}
+		if api == "CopyObject" || api == "CopyObjectPart" {
+			if src, e := url.PathUnescape(t.HTTP.ReqInfo.Headers.Get("X-Amz-Copy-Source")); e == nil && src != "" {
+				src, _, _ = strings.Cut(src, "?")
+				bucket, object, _ := strings.Cut(strings.TrimPrefix(path.Clean("/"+src), "/"), "/")
+				u.add(policy.GetObjectAction, bucket, object, depth)
+			}
+		}
+	}
+	return usage, mapKeys(unknown)
+}
+
+// policyGenerateMessage - the policy generated for an access key.
+type policyGenerateMessage struct {
+	Status    string          `json:"status"`
+	AccessKey string          `json:"accessKey"`
+	Calls     int             `json:"calls"`
+	Policy    json.RawMessage `json:"policy"`
+
+	// Only the policy is printed when a single access key was asked for,
+	// so that it can be saved to a file.
+	policyOnly bool
+}
+
+func (m policyGenerateMessage) String() string {
+	var b bytes.Buffer
+	json.Indent(&b, m.Policy, "", " ")
+	if m.policyOnly {
+		return b.String()
+	}
+	return console.Colorize("PolicyMessage", fmt.Sprintf("Policy of `%s` from %d calls:", m.AccessKey, m.Calls)) + "\n" + b.String()
+}
+
+func (m policyGenerateMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(jsonMessageBytes)
+}
+
+// mainAdminPolicyGenerate is the handler for "mc admin policy generate" command.
+func mainAdminPolicyGenerate(ctx context.Context, cmd *cli.Command) error {
+	file := cmd.String("from-trace")
+	if cmd.Args().Len() != 0 || file == "" {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+	depth := cmd.Int("prefix-depth")
+	if depth < 0 {
+		fatalIf(errInvalidArgument().Trace(fmt.Sprint(depth)), "--prefix-depth cannot be negative.")
+	}
+	var allow []policy.Action
+	for _, name := range cmd.StringSlice("allow") {
+		action := policy.Action(name)
+		if !action.IsValid() {
+			fatalIf(errInvalidArgument().Trace(name), "Unknown action `%s`.", name)
+		}
+		allow = append(allow, action)
+	}
+
+	console.SetColor("PolicyMessage", color.New(color.FgGreen))
+
+	in, e := openSavedTrace(file)
+	fatalIf(probe.NewError(e).Trace(file), "Unable to open input")
+	defer in.Close()
+	var calls []madmin.TraceInfo
+	_, e = readTraceRecords(in, func(ti madmin.ServiceTraceInfo) {
+		calls = append(calls, ti.Trace)
+	})
+	fatalIf(probe.NewError(e).Trace(file), "Unable to read trace")
+
+	user := cmd.String("user")
+	usage, unknown := traceUsage(calls, user, depth)
+	for _, api := range unknown {
+		errorIf(errInvalidArgument().Trace(api), "No action is known for API `%s`, allow it with --allow if needed.", api)
+	}
+	if len(usage) == 0 {
+		if user != "" {
+			fatalIf(errInvalidArgument().Trace(file, user), "No call of `%s` in `%s`, traces must be saved with --verbose", user, file)
+		}
+		fatalIf(errInvalidArgument().Trace(file), "No signed call in `%s`, traces must be saved with --verbose", file)
+	}
+
+	for _, key := range mapKeys(usage) {
+		u := usage[key]
+		u.allow(allow)
+		buf, e := json.Marshal(u.policy())
+		fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+		printMsg(policyGenerateMessage{
+			AccessKey:  key,
+			Calls:      u.calls,
+			Policy:     buf,
+			policyOnly: user != "",
+		})
+	}
+	return nil
+}
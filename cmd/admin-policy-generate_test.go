@@ -0,0 +1,135 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openstor/madmin-go/v4"
+	"github.com/openstor/pkg/v3/policy"
+)
+
+func TestTraceUsage(t *testing.T) {
+	const v4 = "AWS4-HMAC-SHA256 Credential=%s/20240601/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=**REDACTED**"
+	call := func(api, path, key string, status int, headers map[string]string, query string) string {
+		hdrs := map[string]string{}
+		for k, v := range headers {
+			hdrs[k] = v
+		}
+		if key != "" {
+			hdrs["Authorization"] = fmt.Sprintf(v4, key)
+		}
+		buf, _ := json.Marshal(map[string]any{
+			"type": "S3",
+			"api":  "s3." + api,
+			"path": path,
+			"request": map[string]any{
+				"method":   "GET",
+				"rawQuery": query,
+				"headers":  hdrs,
+			},
+			"response": map[string]any{"statusCode": status},
+		})
+		return string(buf)
+	}
+	trace := strings.Join([]string{
+		call("ListObjectsV2", "/etl", "svc-etl", 200, nil, "list-type=2&prefix=in%2F"),
+		call("GetObject", "/etl/in/2024/06/a.csv", "svc-etl", 200, nil, ""),
+		call("HeadObject", "/etl/in/2024/06/b.csv", "svc-etl", 404, nil, ""),
+		call("PutObject", "/etl/out/report.csv", "svc-etl", 200, nil, ""),
+		call("CopyObject", "/archive/2024/a.csv", "svc-etl", 200, map[string]string{"X-Amz-Copy-Source": "/etl/in/2024/06/a.csv"}, ""),
+		call("DeleteObject", "/etl/in/2024/06/a.csv", "svc-etl", 403, nil, ""),
+		call("GetObject", "/public/index.html", "", 200, nil, ""),
+		call("GetObject", "/etl/shared/x.csv", "", 200, nil, "X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Credential=reader%2F20240601%2Fus-east-1%2Fs3%2Faws4_request"),
+		call("ListBuckets", "/", "reader", 200, nil, ""),
+		call("ListenBucketNotificationV9", "/etl", "svc-etl", 200, nil, ""),
+		`{"type": "Bootstrap", "message": "starting"}`,
+	}, "\n")
+
+	var calls []madmin.TraceInfo
+	skipped, e := readTraceRecords(strings.NewReader(trace), func(ti madmin.ServiceTraceInfo) {
+		calls = append(calls, ti.Trace)
+	})
+	if e != nil || skipped != 1 || len(calls) != 10 {
+		t.Fatalf("unexpected read of %d calls, %d skipped: %v", len(calls), skipped, e)
+	}
+
+	usage, unknown := traceUsage(calls, "", 0)
+	if !reflect.DeepEqual(mapKeys(usage), []string{"reader", "svc-etl"}) || !reflect.DeepEqual(unknown, []string{"ListenBucketNotificationV9"}) {
+		t.Fatalf("unexpected access keys %v, unknown APIs %v", mapKeys(usage), unknown)
+	}
+	if usage["svc-etl"].calls != 5 || usage["reader"].calls != 2 {
+		t.Fatalf("unexpected calls %d and %d", usage["svc-etl"].calls, usage["reader"].calls)
+	}
+	expected := generatedPolicy{
+		Version: policy.DefaultVersion,
+		Statement: []generatedStatement{
+			{Effect: policy.Allow, Action: []string{"s3:GetObject"}, Resource: []string{"arn:aws:s3:::etl/in/2024/06/a.csv", "arn:aws:s3:::etl/in/2024/06/b.csv"}},
+			{Effect: policy.Allow, Action: []string{"s3:ListBucket"}, Resource: []string{"arn:aws:s3:::etl"}},
+			{Effect: policy.Allow, Action: []string{"s3:PutObject"}, Resource: []string{"arn:aws:s3:::archive/2024/a.csv", "arn:aws:s3:::etl/out/report.csv"}},
+		},
+	}
+	if got := usage["svc-etl"].policy(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected policy %+v", got)
+	}
+
+	// Folders and extra actions.
+	usage, _ = traceUsage(calls, "svc-etl", 1)
+	u := usage["svc-etl"]
+	u.allow([]policy.Action{policy.DeleteObjectAction, policy.GetBucketLocationAction})
+	expected.Statement = []generatedStatement{
+		{Effect: policy.Allow, Action: []string{"s3:DeleteObject", "s3:GetObject"}, Resource: []string{"arn:aws:s3:::etl/in/*"}},
+		{Effect: policy.Allow, Action: []string{"s3:DeleteObject", "s3:PutObject"}, Resource: []string{"arn:aws:s3:::archive/2024/*", "arn:aws:s3:::etl/out/*"}},
+		{Effect: policy.Allow, Action: []string{"s3:GetBucketLocation"}, Resource: []string{"arn:aws:s3:::archive"}},
+		{Effect: policy.Allow, Action: []string{"s3:GetBucketLocation", "s3:ListBucket"}, Resource: []string{"arn:aws:s3:::etl"}},
+	}
+	got := u.policy()
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected policy %+v", got)
+	}
+
+	// The policy is valid and allows the calls it was generated from.
+	buf, e := json.Marshal(got)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = policy.ParseConfig(bytes.NewReader(buf)); e != nil {
+		t.Fatalf("invalid policy %s: %v", buf, e)
+	}
+	statements, e := parseSimulatedPolicy("generated", buf)
+	if e != nil {
+		t.Fatal(e)
+	}
+	for _, req := range []simulatedRequest{
+		{action: policy.GetObjectAction, bucket: "etl", object: "in/2024/07/c.csv"},
+		{action: policy.ListBucketAction, bucket: "etl"},
+		{action: policy.PutObjectAction, bucket: "archive", object: "2024/b.csv"},
+	} {
+		if msg := simulatePolicies(req, statements); msg.Decision != policySimulateAllow {
+			t.Errorf("%s on %s/%s is not allowed", req.action, req.bucket, req.object)
+		}
+	}
+	if msg := simulatePolicies(simulatedRequest{action: policy.PutObjectAction, bucket: "etl", object: "in/x.csv"}, statements); msg.Decision != policySimulateDeny {
+		t.Error("s3:PutObject on etl/in/x.csv is allowed")
+	}
+}
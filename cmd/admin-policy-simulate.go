@@ -0,0 +1,553 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/openstor/madmin-go/v4"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/openstor/pkg/v3/policy"
+	"github.com/openstor/pkg/v3/policy/condition"
+	"github.com/urfave/cli/v3"
+)
+
+var policySimulateFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:  "policy",
+		Usage: "policy document to evaluate, '-' reads from stdin",
+	},
+	&cli.StringSliceFlag{
+		Name:  "policy-name",
+		Usage: "policy of TARGET to evaluate",
+	},
+	&cli.StringFlag{
+		Name:  "user",
+		Usage: "evaluate the policies of a user of TARGET and of its groups",
+	},
+	&cli.StringFlag{
+		Name:  "group",
+		Usage: "evaluate the policies of a group of TARGET",
+	},
+	&cli.StringFlag{
+		Name:  "action",
+		Usage: "action of the request, e.g. 's3:GetObject'",
+	},
+	&cli.StringFlag{
+		Name:  "resource",
+		Usage: "resource ARN of the request, e.g. 'arn:aws:s3:::mybucket/myobject'",
+	},
+	&cli.StringFlag{
+		Name:  "principal",
+		Usage: "access key matched against the Principal of bucket policies, anonymous by default",
+	},
+	&cli.StringFlag{
+		Name:  "source-ip",
+		Usage: "IP address of the client",
+	},
+	&cli.StringFlag{
+		Name:  "prefix",
+		Usage: "prefix of a ListObjects request",
+	},
+	&cli.BoolFlag{
+		Name:  "secure-transport",
+		Usage: "the request is made over TLS",
+	},
+	&cli.StringSliceFlag{
+		Name:  "condition",
+		Usage: "value of any other condition key, as KEY=VALUE",
+	},
+}
+
+var adminPolicySimulateCmd = &cli.Command{
+	Name:         "simulate",
+	Usage:        "evaluate policies for a request offline",
+	Action:       mainAdminPolicySimulate,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(policySimulateFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] [TARGET]
+
+  Policies are read from files with --policy, which accepts policy
+  documents and the output of 'mc admin policy info' and 'mc anonymous
+  get-json', or from TARGET with --policy-name, --user and --group.
+  Documents with a Principal are evaluated as bucket policies.
+
+  As in S3, an explicit Deny wins over any Allow and a request no
+  statement allows is denied.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Check whether a policy document allows to download an object.
+     {{.Prompt}} {{.HelpName}} --policy /tmp/policy.json --action s3:GetObject \
+           --resource arn:aws:s3:::mybucket/photos/2024/beach.jpg
+
+  2. Check whether user 'foobar' of myminio can upload objects from a given IP address.
+     {{.Prompt}} {{.HelpName}} myminio --user foobar --action s3:PutObject \
+           --resource arn:aws:s3:::mybucket/uploads/report.csv --source-ip 10.0.0.12
+
+  3. Check whether anonymous users may list a prefix, using the bucket policy.
+     {{.Prompt}} mc anonymous get-json myminio/mybucket > /tmp/anonymous.json
+     {{.Prompt}} {{.HelpName}} --policy /tmp/anonymous.json --action s3:ListBucket \
+           --resource arn:aws:s3:::mybucket --prefix public/
+`,
+}
+
+// checkAdminPolicySimulateSyntax - validate all the passed arguments
+func checkAdminPolicySimulateSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() > 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+	remote := len(cmd.StringSlice("policy-name")) > 0 || cmd.IsSet("user") || cmd.IsSet("group")
+	if remote != (cmd.Args().Len() == 1) {
+		fatalIf(errInvalidArgument(), "TARGET is required with --policy-name, --user and --group, and only with them.")
+	}
+	if !remote && len(cmd.StringSlice("policy")) == 0 {
+		fatalIf(errInvalidArgument(), "No policy to evaluate, use --policy or TARGET.")
+	}
+	if cmd.IsSet("user") && cmd.IsSet("group") {
+		fatalIf(errInvalidArgument(), "--user and --group cannot be used together.")
+	}
+	if cmd.String("action") == "" {
+		fatalIf(errInvalidArgument(), "--action is required.")
+	}
+	if ip := cmd.String("source-ip"); ip != "" && net.ParseIP(ip) == nil {
+		fatalIf(errInvalidArgument().Trace(ip), "Invalid --source-ip `%s`.", ip)
+	}
+}
+
+// simulatedStatement - a statement of an IAM or a bucket policy, with
+// the policy it belongs to.
+type simulatedStatement struct {
+	source     string
+	attachedTo string
+	index      int
+	iam        *policy.Statement
+	bucket     *policy.BPStatement
+}
+
+func (s simulatedStatement) effect() policy.Effect {
+	if s.bucket != nil {
+		return s.bucket.Effect
+	}
+	return s.iam.Effect
+}
+
+// matches returns whether the statement applies to the request. IsAllowed
+// of a Deny statement is false when the statement applies.
+func (s simulatedStatement) matches(req simulatedRequest) bool {
+	if s.bucket != nil {
+		return s.bucket.IsAllowed(policy.BucketPolicyArgs{
+			AccountName:     req.principal,
+			Groups:          req.groups,
+			Action:          req.action,
+			BucketName:      req.bucket,
+			ObjectName:      req.object,
+			ConditionValues: req.conditions,
+		}) == (s.bucket.Effect == policy.Allow)
+	}
+	return s.iam.IsAllowed(policy.Args{
+		AccountName:     req.principal,
+		Groups:          req.groups,
+		Action:          req.action,
+		BucketName:      req.bucket,
+		ObjectName:      req.object,
+		ConditionValues: req.conditions,
+	}) == (s.iam.Effect == policy.Allow)
+}
+
+func (s simulatedStatement) message() policySimulateStatement {
+	msg := policySimulateStatement{
+		Source:     s.source,
+		AttachedTo: s.attachedTo,
+		Index:      s.index,
+		Effect:     string(s.effect()),
+	}
+	var e error
+	if s.bucket != nil {
+		msg.Sid = string(s.bucket.SID)
+		msg.Statement, e = json.Marshal(s.bucket)
+	} else {
+		msg.Sid = string(s.iam.SID)
+		msg.Statement, e = json.Marshal(s.iam)
+	}
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return msg
+}
+
+// simulatedRequest - the request policies are evaluated for.
+type simulatedRequest struct {
+	action     policy.Action
+	resource   string
+	bucket     string
+	object     string
+	principal  string
+	groups     []string
+	conditions map[string][]string
+}
+
+// parseSimulatedResource splits a resource ARN, or a bucket/object path,
+// into its bucket and object.
+func parseSimulatedResource(resource string) (bucket, object string, e error) {
+	if strings.HasPrefix(resource, "arn:") && !strings.HasPrefix(resource, policy.ResourceARNPrefix) {
+		return "", "", fmt.Errorf("only S3 resources (%s...) are supported", policy.ResourceARNPrefix)
+	}
+	bucket, object, _ = strings.Cut(strings.TrimPrefix(resource, policy.ResourceARNPrefix), "/")
+	return bucket, object, nil
+}
+
+// simulatedConditions returns the condition values of a request, keyed
+// like the MinIO server does without the "aws:" and "s3:" prefixes.
+func simulatedConditions(cmd *cli.Command, principal string, groups []string, now time.Time) (map[string][]string, error) {
+	conditions := map[string][]string{
+		"SecureTransport": {strconv.FormatBool(cmd.Bool("secure-transport"))},
+		"CurrentTime":     {now.Format(time.RFC3339)},
+		"EpochTime":       {strconv.FormatInt(now.Unix(), 10)},
+		"principaltype":   {"Anonymous"},
+	}
+	if principal != "" {
+		conditions["principaltype"] = []string{"User"}
+		conditions["username"] = []string{principal}
+		conditions["userid"] = []string{principal}
+	}
+	if len(groups) > 0 {
+		conditions["groups"] = groups
+	}
+	if ip := cmd.String("source-ip"); ip != "" {
+		conditions["SourceIp"] = []string{ip}
+	}
+	if cmd.IsSet("prefix") {
+		conditions["prefix"] = []string{cmd.String("prefix")}
+	}
+	for _, kv := range cmd.StringSlice("condition") {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid condition `%s`, expected KEY=VALUE", kv)
+		}
+		name := condition.KeyName(key).Name()
+		conditions[name] = append(conditions[name], value)
+	}
+	return conditions, nil
+}
+
+// parseSimulatedPolicy parses the statements of a policy document. The
+// output of `mc admin policy info` and `mc anonymous get-json`, with or
+// without --json, is unwrapped. Documents whose statements have a
+// Principal are bucket policies.
+func parseSimulatedPolicy(source string, data []byte) ([]simulatedStatement, error) {
+	var doc map[string]json.RawMessage
+	if e := json.Unmarshal(data, &doc); e != nil {
+		return nil, e
+	}
+	for _, key := range []string{"policyInfo", "Policy", "anonymous"} {
+		if _, ok := doc["Statement"]; ok {
+			break
+		}
+		if inner, ok := doc[key]; ok {
+			data, doc = inner, nil
+			if e := json.Unmarshal(data, &doc); e != nil {
+				return nil, e
+			}
+		}
+	}
+	if _, ok := doc["Statement"]; !ok {
+		return nil, errors.New("no Statement in policy")
+	}
+
+	var raw []map[string]json.RawMessage
+	if e := json.Unmarshal(doc["Statement"], &raw); e != nil {
+		return nil, e
+	}
+	isBucketPolicy := false
+	for _, st := range raw {
+		if _, ok := st["Principal"]; ok {
+			isBucketPolicy = true
+		}
+	}
+
+	var statements []simulatedStatement
+	if isBucketPolicy {
+		var p policy.BucketPolicy
+		if e := json.Unmarshal(data, &p); e != nil {
+			return nil, e
+		}
+		for i := range p.Statements {
+			statements = append(statements, simulatedStatement{source: source, index: i + 1, bucket: &p.Statements[i]})
+		}
+		return statements, nil
+	}
+	p, e := policy.ParseConfig(bytes.NewReader(data))
+	if e != nil {
+		return nil, e
+	}
+	for i := range p.Statements {
+		statements = append(statements, simulatedStatement{source: source, index: i + 1, iam: &p.Statements[i]})
+	}
+	return statements, nil
+}
+
+// readSimulatedPolicy reads a policy document from a file or stdin.
+func readSimulatedPolicy(file string) ([]byte, error) {
+	if file == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(file)
+}
+
+// fetchSimulatedPolicies fetches the policies of TARGET given by name, or
+// attached to a user, its enabled groups or a group. It also returns the
+// groups of the request.
+func fetchSimulatedPolicies(ctx context.Context, client *madmin.AdminClient, names []string, user, group string) ([]simulatedStatement, []string, *probe.Error) {
+	type attachment struct{ name, attachedTo string }
+	var attached []attachment
+	for _, name := range names {
+		attached = append(attached, attachment{name: name})
+	}
+	splitPolicies := func(policies, attachedTo string) {
+		for _, name := range strings.Split(policies, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				attached = append(attached, attachment{name, attachedTo})
+			}
+		}
+	}
+
+	var groups []string
+	if user != "" {
+		info, e := client.GetUserInfo(ctx, user)
+		if e != nil {
+			return nil, nil, probe.NewError(e).Trace(user)
+		}
+		splitPolicies(info.PolicyName, "user "+user)
+		groups = info.MemberOf
+	}
+	if group != "" {
+		groups = []string{group}
+	}
+	var enabled []string
+	for _, g := range groups {
+		desc, e := client.GetGroupDescription(ctx, g)
+		if e != nil {
+			return nil, nil, probe.NewError(e).Trace(g)
+		}
+		if desc.Status == string(madmin.GroupDisabled) {
+			continue
+		}
+		enabled = append(enabled, g)
+		splitPolicies(desc.Policy, "group "+g)
+	}
+
+	var statements []simulatedStatement
+	seen := make(map[string]bool)
+	for _, a := range attached {
+		if seen[a.name] {
+			continue
+		}
+		seen[a.name] = true
+		info, e := getPolicyInfo(client, a.name)
+		if e != nil {
+			return nil, nil, probe.NewError(e).Trace(a.name)
+		}
+		st, e := parseSimulatedPolicy(a.name, info.Policy)
+		if e != nil {
+			return nil, nil, probe.NewError(e).Trace(a.name)
+		}
+		for i := range st {
+			st[i].attachedTo = a.attachedTo
+		}
+		statements = append(statements, st...)
+	}
+	return statements, enabled, nil
+}
+
+// policySimulateStatement - a statement which applies to the request.
+type policySimulateStatement struct {
+	Source     string          `json:"source"`
+	AttachedTo string          `json:"attachedTo,omitempty"`
+	Index      int             `json:"index"`
+	Sid        string          `json:"sid,omitempty"`
+	Effect     string          `json:"effect"`
+	Statement  json.RawMessage `json:"statement"`
+}
+
+func (s policySimulateStatement) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "statement %d", s.Index)
+	if s.Sid != "" {
+		fmt.Fprintf(&b, " (%s)", s.Sid)
+	}
+	fmt.Fprintf(&b, " of `%s`", s.Source)
+	if s.AttachedTo != "" {
+		fmt.Fprintf(&b, " attached to %s", s.AttachedTo)
+	}
+	return b.String()
+}
+
+// Decisions of the simulation, and why they were made.
+const (
+	policySimulateAllow        = "allow"
+	policySimulateDeny         = "deny"
+	policySimulateExplicitDeny = "explicit-deny"
+	policySimulateImplicitDeny = "implicit-deny"
+)
+
+// policySimulateMessage - the decision for a request, with the statement
+// which decided it and all statements which apply.
+type policySimulateMessage struct {
+	Status    string                    `json:"status"`
+	Action    string                    `json:"action"`
+	Resource  string                    `json:"resource,omitempty"`
+	Principal string                    `json:"principal,omitempty"`
+	Decision  string                    `json:"decision"`
+	Reason    string                    `json:"reason"`
+	Statement *policySimulateStatement  `json:"statement,omitempty"`
+	Matched   []policySimulateStatement `json:"matched,omitempty"`
+}
+
+func (m policySimulateMessage) String() string {
+	request := fmt.Sprintf("`%s`", m.Action)
+	if m.Resource != "" {
+		request += fmt.Sprintf(" on `%s`", m.Resource)
+	}
+	switch m.Reason {
+	case policySimulateAllow:
+		return console.Colorize("PolicyAllow", "ALLOW ") + request + ", allowed by " + m.Statement.String() + ":\n" + indentPolicyStatement(m.Statement.Statement)
+	case policySimulateExplicitDeny:
+		return console.Colorize("PolicyDeny", "DENY ") + request + ", denied by " + m.Statement.String() + ":\n" + indentPolicyStatement(m.Statement.Statement)
+	}
+	return console.Colorize("PolicyDeny", "DENY ") + request + ", no statement allows the request."
+}
+
+func (m policySimulateMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(jsonMessageBytes)
+}
+
+func indentPolicyStatement(statement json.RawMessage) string {
+	var b bytes.Buffer
+	if e := json.Indent(&b, statement, "  ", "  "); e != nil {
+		return "  " + string(statement)
+	}
+	return "  " + b.String()
+}
+
+// simulatePolicies evaluates statements for a request: an explicit Deny
+// wins, then any Allow, the request is denied when no statement applies.
+func simulatePolicies(req simulatedRequest, statements []simulatedStatement) policySimulateMessage {
+	msg := policySimulateMessage{
+		Action:    string(req.action),
+		Resource:  req.resource,
+		Principal: req.principal,
+		Decision:  policySimulateDeny,
+		Reason:    policySimulateImplicitDeny,
+	}
+	allow, deny := -1, -1
+	for _, st := range statements {
+		if !st.matches(req) {
+			continue
+		}
+		switch {
+		case st.effect() == policy.Deny && deny < 0:
+			deny = len(msg.Matched)
+		case st.effect() == policy.Allow && allow < 0:
+			allow = len(msg.Matched)
+		}
+		msg.Matched = append(msg.Matched, st.message())
+	}
+	switch {
+	case deny >= 0:
+		msg.Reason, msg.Statement = policySimulateExplicitDeny, &msg.Matched[deny]
+	case allow >= 0:
+		msg.Decision, msg.Reason, msg.Statement = policySimulateAllow, policySimulateAllow, &msg.Matched[allow]
+	}
+	return msg
+}
+
+// mainAdminPolicySimulate is the handler for "mc admin policy simulate" command.
+func mainAdminPolicySimulate(ctx context.Context, cmd *cli.Command) error {
+	checkAdminPolicySimulateSyntax(ctx, cmd)
+
+	console.SetColor("PolicyAllow", color.New(color.FgGreen, color.Bold))
+	console.SetColor("PolicyDeny", color.New(color.FgRed, color.Bold))
+
+	var statements []simulatedStatement
+	for _, file := range cmd.StringSlice("policy") {
+		data, e := readSimulatedPolicy(file)
+		fatalIf(probe.NewError(e).Trace(file), "Unable to read policy `%s`.", file)
+		st, e := parseSimulatedPolicy(file, data)
+		fatalIf(probe.NewError(e).Trace(file), "Unable to parse policy `%s`.", file)
+		statements = append(statements, st...)
+	}
+
+	var groups []string
+	if cmd.Args().Len() == 1 {
+		aliasedURL := cmd.Args().Get(0)
+		client, err := newAdminClient(aliasedURL)
+		fatalIf(err, "Unable to initialize admin connection.")
+
+		st, g, err := fetchSimulatedPolicies(ctx, client, cmd.StringSlice("policy-name"), cmd.String("user"), cmd.String("group"))
+		fatalIf(err.Trace(aliasedURL), "Unable to fetch policies.")
+		statements, groups = append(statements, st...), g
+	}
+
+	action := policy.Action(cmd.String("action"))
+	if !action.IsValid() && !policy.AdminAction(action).IsValid() && !policy.STSAction(action).IsValid() && !policy.KMSAction(action).IsValid() {
+		fatalIf(errInvalidArgument().Trace(string(action)), "Unknown action `%s`.", action)
+	}
+	resource := cmd.String("resource")
+	bucket, object, e := parseSimulatedResource(resource)
+	fatalIf(probe.NewError(e).Trace(resource), "Invalid --resource `%s`.", resource)
+
+	principal := cmd.String("principal")
+	if principal == "" {
+		principal = cmd.String("user")
+	}
+	conditions, e := simulatedConditions(cmd, principal, groups, UTCNow())
+	fatalIf(probe.NewError(e), "Invalid --condition.")
+
+	printMsg(simulatePolicies(simulatedRequest{
+		action:     action,
+		resource:   resource,
+		bucket:     bucket,
+		object:     object,
+		principal:  principal,
+		groups:     groups,
+		conditions: conditions,
+	}, statements))
+	return nil
+}
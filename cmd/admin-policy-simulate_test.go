@@ -0,0 +1,130 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/openstor/pkg/v3/policy"
+)
+
+func TestSimulatePolicies(t *testing.T) {
+	// Output of `mc admin policy info --json`.
+	readWrite := `{
+ "status": "success",
+ "policy": "readwrite-uploads",
+ "policyInfo": {
+  "PolicyName": "readwrite-uploads",
+  "Policy": {
+   "Version": "2012-10-17",
+   "Statement": [
+    {"Effect": "Allow", "Action": ["s3:GetObject", "s3:PutObject"], "Resource": ["arn:aws:s3:::mybucket/uploads/*"]},
+    {"Effect": "Allow", "Action": ["s3:ListBucket"], "Resource": ["arn:aws:s3:::mybucket"], "Condition": {"StringLike": {"s3:prefix": ["uploads/*"]}}}
+   ]
+  }
+ }
+}`
+	// A policy document.
+	denyOutside := `{
+ "Version": "2012-10-17",
+ "Statement": [
+  {"Sid": "DenyOutside", "Effect": "Deny", "Action": ["s3:PutObject"], "Resource": ["arn:aws:s3:::mybucket/*"], "Condition": {"NotIpAddress": {"aws:SourceIp": ["10.0.0.0/8"]}}}
+ ]
+}`
+	// Output of `mc anonymous get-json --json`.
+	anonymous := `{
+ "operation": "get-json",
+ "status": "success",
+ "bucket": "mybucket",
+ "permission": "",
+ "anonymous": {
+  "Version": "2012-10-17",
+  "Statement": [
+   {"Effect": "Allow", "Principal": {"AWS": ["*"]}, "Action": ["s3:GetObject"], "Resource": ["arn:aws:s3:::mybucket/public/*"]}
+  ]
+ }
+}`
+
+	var statements []simulatedStatement
+	for _, doc := range []struct{ source, data string }{
+		{"readwrite-uploads", readWrite},
+		{"deny-outside.json", denyOutside},
+		{"anonymous.json", anonymous},
+	} {
+		st, e := parseSimulatedPolicy(doc.source, []byte(doc.data))
+		if e != nil {
+			t.Fatalf("%s: %v", doc.source, e)
+		}
+		statements = append(statements, st...)
+	}
+	if len(statements) != 4 || statements[3].bucket == nil || statements[0].iam == nil {
+		t.Fatalf("unexpected statements %+v", statements)
+	}
+
+	testCases := []struct {
+		action     string
+		resource   string
+		conditions map[string][]string
+		decision   string
+		reason     string
+		source     string
+		index      int
+		matched    int
+	}{
+		{"s3:GetObject", "arn:aws:s3:::mybucket/uploads/a.txt", nil, policySimulateAllow, policySimulateAllow, "readwrite-uploads", 1, 1},
+		{"s3:PutObject", "arn:aws:s3:::mybucket/uploads/a.txt", map[string][]string{"SourceIp": {"10.1.2.3"}}, policySimulateAllow, policySimulateAllow, "readwrite-uploads", 1, 1},
+		{"s3:PutObject", "arn:aws:s3:::mybucket/uploads/a.txt", map[string][]string{"SourceIp": {"192.168.1.1"}}, policySimulateDeny, policySimulateExplicitDeny, "deny-outside.json", 1, 2},
+		{"s3:ListBucket", "arn:aws:s3:::mybucket", map[string][]string{"prefix": {"uploads/2024/"}}, policySimulateAllow, policySimulateAllow, "readwrite-uploads", 2, 1},
+		{"s3:ListBucket", "arn:aws:s3:::mybucket", map[string][]string{"prefix": {"private/"}}, policySimulateDeny, policySimulateImplicitDeny, "", 0, 0},
+		{"s3:GetObject", "mybucket/public/index.html", nil, policySimulateAllow, policySimulateAllow, "anonymous.json", 1, 1},
+		{"s3:DeleteObject", "arn:aws:s3:::mybucket/uploads/a.txt", nil, policySimulateDeny, policySimulateImplicitDeny, "", 0, 0},
+	}
+	for i, tc := range testCases {
+		bucket, object, e := parseSimulatedResource(tc.resource)
+		if e != nil {
+			t.Fatalf("Test %d: %v", i+1, e)
+		}
+		msg := simulatePolicies(simulatedRequest{
+			action:     policy.Action(tc.action),
+			resource:   tc.resource,
+			bucket:     bucket,
+			object:     object,
+			conditions: tc.conditions,
+		}, statements)
+		if msg.Decision != tc.decision || msg.Reason != tc.reason || len(msg.Matched) != tc.matched {
+			t.Errorf("Test %d: expected %s (%s) with %d matches, got %s (%s) with %d", i+1, tc.decision, tc.reason, tc.matched, msg.Decision, msg.Reason, len(msg.Matched))
+			continue
+		}
+		if tc.source == "" {
+			if msg.Statement != nil {
+				t.Errorf("Test %d: unexpected statement %v", i+1, msg.Statement)
+			}
+			continue
+		}
+		if msg.Statement == nil || msg.Statement.Source != tc.source || msg.Statement.Index != tc.index {
+			t.Errorf("Test %d: expected statement %d of %s, got %v", i+1, tc.index, tc.source, msg.Statement)
+		}
+	}
+
+	if _, _, e := parseSimulatedResource("arn:aws:iam:::user/foo"); e == nil {
+		t.Error("expected an error for a non S3 resource")
+	}
+	if _, e := parseSimulatedPolicy("bad.json", []byte(`{"PolicyName": "x"}`)); e == nil {
+		t.Error("expected an error for a document without statements")
+	}
+}
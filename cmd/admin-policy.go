@@ -35,6 +35,8 @@ var adminPolicySubcommands = []*cli.Command{
 	adminPolicySetCmd,
 	adminPolicyUnsetCmd,
 	adminPolicyUpdateCmd,
+	adminPolicySimulateCmd,
+	adminPolicyGenerateCmd,
 }
 
 var adminPolicyCmd = &cli.Command{
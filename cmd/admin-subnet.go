@@ -44,8 +44,8 @@ func mainAdminSubnet(ctx context.Context, cmd *cli.Command) error {
 	// Sub-commands like "health", "register" have their own main.
 }
 
-func adminHealthCmd() cli.Command {
+func adminHealthCmd() *cli.Command {
 	cmd := adminSubnetHealthCmd
 	cmd.Hidden = true
-	return cmd
+	return &cmd
 }
@@ -0,0 +1,859 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/klauspost/compress/zstd"
+	"github.com/olekukonko/tablewriter"
+	"github.com/openstor/madmin-go/v4"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+var adminTraceAnalyzeFlags = append([]cli.Flag{
+	&cli.StringFlag{
+		Name:  "format",
+		Usage: "output format, one of 'table', 'json' or 'csv'",
+		Value: "table",
+	},
+	&cli.StringFlag{
+		Name:  "section",
+		Usage: "report written with --format csv, one of 'groups', 'errors', 'slowest' or 'throughput'",
+		Value: "groups",
+	},
+	&cli.IntFlag{
+		Name:  "top",
+		Usage: "number of slowest calls to report",
+		Value: 10,
+	},
+	&cli.DurationFlag{
+		Name:  "interval",
+		Usage: "duration of the intervals of the throughput report",
+		Value: time.Minute,
+	},
+	&cli.StringFlag{
+		Name:  "since",
+		Usage: "analyze only calls from this time, in RFC3339 format",
+	},
+	&cli.StringFlag{
+		Name:  "until",
+		Usage: "analyze only calls before this time, in RFC3339 format",
+	},
+}, traceAnalyzeFilterFlags()...)
+
+var adminTraceAnalyzeCmd = cli.Command{
+	Name:         "analyze",
+	Usage:        "analyze a saved trace",
+	Action:       mainAdminTraceAnalyze,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(adminTraceAnalyzeFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] FILE
+
+  FILE is the output of 'mc admin trace --json', with or without --verbose,
+  compressed with zstd if it ends with '.zst'. Use '-' to read from stdin.
+  Bootstrap calls are ignored.
+
+  The report has latency histograms and percentiles, errors and bytes
+  transferred per API, node, bucket and client, the errors by status, the
+  slowest calls and the throughput per interval. In the table, use 'tab' or
+  the left and right keys to switch between the reports.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Save the trace of the S3 calls of 'myminio', then analyze it.
+     {{.Prompt}} mc admin trace --json -v myminio > trace.json
+     {{.Prompt}} {{.HelpName}} trace.json
+
+  2. Analyze the calls of an incident which took more than 1s.
+     {{.Prompt}} {{.HelpName}} --since 2024-06-01T10:00:00Z --until 2024-06-01T10:30:00Z --response-duration 1s trace.json
+
+  3. Export the statistics of the groups of calls as CSV.
+     {{.Prompt}} {{.HelpName}} --format csv trace.json > groups.csv
+
+  4. Export the throughput of the failed PutObject calls per 10 seconds as CSV.
+     {{.Prompt}} {{.HelpName}} --format csv --section throughput --interval 10s --errors --funcname s3.PutObject trace.json
+`,
+}
+
+// traceAnalyzeFilterFlags returns copies of the flags of 'mc admin trace'
+// which filter calls.
+func traceAnalyzeFilterFlags() []cli.Flag {
+	names := map[string]bool{
+		"status-code": true, "method": true, "funcname": true, "path": true, "node": true,
+		"request-header": true, "request-query": true, "errors": true,
+		"filter-request": true, "filter-response": true, "filter-size": true, "response-duration": true,
+	}
+	var flags []cli.Flag
+	for _, flag := range adminTraceFlags {
+		if !names[strings.TrimSpace(strings.Split(flag.Names()[0], ",")[0])] {
+			continue
+		}
+		switch f := flag.(type) {
+		case *cli.BoolFlag:
+			c := *f
+			flags = append(flags, &c)
+		case *cli.StringFlag:
+			c := *f
+			flags = append(flags, &c)
+		case *cli.StringSliceFlag:
+			c := *f
+			flags = append(flags, &c)
+		case *cli.IntSliceFlag:
+			c := *f
+			flags = append(flags, &c)
+		case *cli.DurationFlag:
+			c := *f
+			flags = append(flags, &c)
+		}
+	}
+	return flags
+}
+
+// Dimensions of the groups of calls.
+var traceAnalyzeDimensions = []string{"api", "node", "bucket", "client"}
+
+// Upper bounds of the latency histograms, the last bucket has the slower
+// calls.
+var traceAnalyzeBounds = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond,
+	50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// traceRecord is a call saved by 'mc admin trace --json', with the fields
+// of both the short and the verbose formats.
+type traceRecord struct {
+	Type       string            `json:"type"`
+	Host       string            `json:"host"`
+	Time       time.Time         `json:"time"`
+	Client     string            `json:"client"`
+	CallStats  *callStats        `json:"callStats"`
+	Duration   time.Duration     `json:"duration"`
+	FuncName   string            `json:"api"`
+	Path       string            `json:"path"`
+	Query      string            `json:"query"`
+	StatusCode int               `json:"statusCode"`
+	StatusMsg  string            `json:"statusMsg"`
+	Size       int64             `json:"size"`
+	Error      string            `json:"error"`
+	Message    string            `json:"message"`
+	Extra      map[string]string `json:"extra"`
+	Request    *requestInfo      `json:"request"`
+	Response   *responseInfo     `json:"response"`
+}
+
+// traceInfo returns the call in the format of the live traces, so that
+// the same filters apply.
+func (r traceRecord) traceInfo() madmin.ServiceTraceInfo {
+	t := madmin.TraceInfo{
+		TraceType: madmin.FindTraceType(r.Type),
+		NodeName:  r.Host,
+		FuncName:  r.FuncName,
+		Time:      r.Time,
+		Path:      r.Path,
+		Duration:  r.Duration,
+		Bytes:     r.Size,
+		Message:   r.StatusMsg,
+		Error:     r.Error,
+		Custom:    r.Extra,
+	}
+	if t.Message == "" {
+		t.Message = r.Message
+	}
+	if r.CallStats == nil && r.Request == nil && r.Response == nil {
+		return madmin.ServiceTraceInfo{Trace: t}
+	}
+	t.HTTP = &madmin.TraceHTTPStats{
+		ReqInfo: madmin.TraceRequestInfo{
+			RawQuery: r.Query,
+			Client:   r.Client,
+		},
+		RespInfo: madmin.TraceResponseInfo{
+			StatusCode: r.StatusCode,
+		},
+	}
+	if r.CallStats != nil {
+		t.HTTP.CallStats = madmin.TraceCallStats{
+			InputBytes:      r.CallStats.Rx,
+			OutputBytes:     r.CallStats.Tx,
+			TimeToFirstByte: r.CallStats.TTFB,
+		}
+	}
+	if rq := r.Request; rq != nil {
+		t.HTTP.ReqInfo.Time = rq.Time
+		t.HTTP.ReqInfo.Proto = rq.Proto
+		t.HTTP.ReqInfo.Method = rq.Method
+		t.HTTP.ReqInfo.RawQuery = rq.RawQuery
+		t.HTTP.ReqInfo.Headers = http.Header{}
+		for k, v := range rq.Headers {
+			t.HTTP.ReqInfo.Headers.Set(k, v)
+		}
+	}
+	if rs := r.Response; rs != nil {
+		t.HTTP.RespInfo.StatusCode = rs.StatusCode
+		t.HTTP.RespInfo.Headers = http.Header{}
+		for k, v := range rs.Headers {
+			t.HTTP.RespInfo.Headers.Set(k, v)
+		}
+	}
+	return madmin.ServiceTraceInfo{Trace: t}
+}
+
+// traceCallFailed returns true for calls with an error or an HTTP error
+// status.
+func traceCallFailed(t madmin.TraceInfo) bool {
+	return t.Error != "" || (t.HTTP != nil && t.HTTP.RespInfo.StatusCode >= http.StatusBadRequest)
+}
+
+// traceCallGroups returns the name of the call in each dimension, empty
+// if unknown.
+func traceCallGroups(t madmin.TraceInfo) [4]string {
+	groups := [4]string{t.FuncName, t.NodeName}
+	if t.TraceType == madmin.TraceS3 {
+		bucket, _, _ := strings.Cut(strings.TrimPrefix(t.Path, "/"), "/")
+		groups[2] = bucket
+	}
+	if t.HTTP != nil {
+		client := t.HTTP.ReqInfo.Client
+		if client == "" {
+			// Verbose traces have the client in the forwarded headers only.
+			client, _, _ = strings.Cut(t.HTTP.ReqInfo.Headers.Get("X-Forwarded-For"), ",")
+		}
+		groups[3] = strings.TrimSpace(client)
+	}
+	return groups
+}
+
+type traceAnalyzeBucket struct {
+	LE    string `json:"le"`
+	Count int    `json:"count"`
+}
+
+// traceAnalyzeGroup - statistics of the calls of an API, a node, a
+// bucket or a client.
+type traceAnalyzeGroup struct {
+	Name      string               `json:"name"`
+	Calls     int                  `json:"calls"`
+	Errors    int                  `json:"errors"`
+	Rx        int64                `json:"rx"`
+	Tx        int64                `json:"tx"`
+	Size      int64                `json:"size,omitempty"`
+	Min       time.Duration        `json:"min"`
+	Avg       time.Duration        `json:"avg"`
+	P50       time.Duration        `json:"p50"`
+	P90       time.Duration        `json:"p90"`
+	P99       time.Duration        `json:"p99"`
+	Max       time.Duration        `json:"max"`
+	AvgTTFB   time.Duration        `json:"avgTTFB,omitempty"`
+	Histogram []traceAnalyzeBucket `json:"histogram"`
+
+	durations []time.Duration
+	total     time.Duration
+	ttfb      time.Duration
+}
+
+// traceAnalyzeError - the number of calls of an API which failed with
+// a status or an error.
+type traceAnalyzeError struct {
+	API    string `json:"api"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Count  int    `json:"count"`
+}
+
+// traceAnalyzeCall - a call of the slowest calls.
+type traceAnalyzeCall struct {
+	Time     time.Time     `json:"time"`
+	API      string        `json:"api"`
+	Node     string        `json:"node"`
+	Client   string        `json:"client,omitempty"`
+	Path     string        `json:"path"`
+	Status   int           `json:"status,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// traceAnalyzeInterval - the calls which started in an interval.
+type traceAnalyzeInterval struct {
+	Time   time.Time `json:"time"`
+	Calls  int       `json:"calls"`
+	Errors int       `json:"errors"`
+	Rx     int64     `json:"rx"`
+	Tx     int64     `json:"tx"`
+}
+
+// traceAnalysis - the analysis of a saved trace.
+type traceAnalysis struct {
+	Status     string                         `json:"status"`
+	File       string                         `json:"file"`
+	Calls      int                            `json:"calls"`
+	Errors     int                            `json:"errors"`
+	Skipped    int                            `json:"skipped,omitempty"`
+	Start      time.Time                      `json:"start"`
+	End        time.Time                      `json:"end"`
+	Rx         int64                          `json:"rx"`
+	Tx         int64                          `json:"tx"`
+	Groups     map[string][]traceAnalyzeGroup `json:"groups"`
+	ErrorCalls []traceAnalyzeError            `json:"errorBreakdown"`
+	Slowest    []traceAnalyzeCall             `json:"slowest"`
+	Throughput []traceAnalyzeInterval         `json:"throughput"`
+	Interval   time.Duration                  `json:"interval"`
+
+	groups    [4]map[string]*traceAnalyzeGroup
+	errors    map[traceAnalyzeError]int
+	intervals map[time.Time]*traceAnalyzeInterval
+	stats     [4]*statTrace
+	top       int
+}
+
+func newTraceAnalysis(file string, top int, interval time.Duration) *traceAnalysis {
+	a := &traceAnalysis{
+		Status:    "success",
+		File:      file,
+		Interval:  interval,
+		errors:    map[traceAnalyzeError]int{},
+		intervals: map[time.Time]*traceAnalyzeInterval{},
+		top:       top,
+	}
+	for i := range traceAnalyzeDimensions {
+		a.groups[i] = map[string]*traceAnalyzeGroup{}
+		a.stats[i] = &statTrace{Calls: map[string]statItem{}}
+	}
+	return a
+}
+
+// add counts a call.
+func (a *traceAnalysis) add(ti madmin.ServiceTraceInfo) {
+	t := ti.Trace
+	failed := traceCallFailed(t)
+	var rx, tx int64
+	var ttfb time.Duration
+	status := 0
+	if t.HTTP != nil {
+		rx, tx = int64(t.HTTP.CallStats.InputBytes), int64(t.HTTP.CallStats.OutputBytes)
+		ttfb = t.HTTP.CallStats.TimeToFirstByte
+		status = t.HTTP.RespInfo.StatusCode
+	}
+
+	a.Calls++
+	a.Rx += rx
+	a.Tx += tx
+	if a.Start.IsZero() || t.Time.Before(a.Start) {
+		a.Start = t.Time
+	}
+	if end := t.Time.Add(t.Duration); end.After(a.End) {
+		a.End = end
+	}
+	if failed {
+		a.Errors++
+		a.errors[traceAnalyzeError{API: t.FuncName, Status: status, Error: t.Error}]++
+	}
+
+	// The tables of calls count the calls with an error.
+	stat := ti
+	if failed && stat.Trace.Error == "" {
+		stat.Trace.Error = http.StatusText(status)
+	}
+	groups := traceCallGroups(t)
+	for i, name := range groups {
+		if name == "" {
+			continue
+		}
+		g := a.groups[i][name]
+		if g == nil {
+			g = &traceAnalyzeGroup{Name: name}
+			a.groups[i][name] = g
+		}
+		g.Calls++
+		if failed {
+			g.Errors++
+		}
+		g.Rx += rx
+		g.Tx += tx
+		g.Size += t.Bytes
+		g.total += t.Duration
+		g.ttfb += ttfb
+		g.durations = append(g.durations, t.Duration)
+		a.stats[i].addAs(name, stat)
+	}
+
+	if a.Interval > 0 {
+		at := t.Time.Truncate(a.Interval)
+		in := a.intervals[at]
+		if in == nil {
+			in = &traceAnalyzeInterval{Time: at}
+			a.intervals[at] = in
+		}
+		in.Calls++
+		if failed {
+			in.Errors++
+		}
+		in.Rx += rx
+		in.Tx += tx
+	}
+
+	if a.top > 0 && (len(a.Slowest) < a.top || t.Duration > a.Slowest[len(a.Slowest)-1].Duration) {
+		call := traceAnalyzeCall{
+			Time:     t.Time,
+			API:      t.FuncName,
+			Node:     t.NodeName,
+			Client:   groups[3],
+			Path:     t.Path,
+			Status:   status,
+			Error:    t.Error,
+			Duration: t.Duration,
+		}
+		i := sort.Search(len(a.Slowest), func(i int) bool { return a.Slowest[i].Duration < call.Duration })
+		a.Slowest = append(a.Slowest, traceAnalyzeCall{})
+		copy(a.Slowest[i+1:], a.Slowest[i:])
+		a.Slowest[i] = call
+		if len(a.Slowest) > a.top {
+			a.Slowest = a.Slowest[:a.top]
+		}
+	}
+}
+
+// finish computes the statistics of the groups, sorted by number of
+// calls.
+func (a *traceAnalysis) finish() {
+	a.Groups = make(map[string][]traceAnalyzeGroup, len(traceAnalyzeDimensions))
+	for i, dim := range traceAnalyzeDimensions {
+		groups := make([]traceAnalyzeGroup, 0, len(a.groups[i]))
+		for _, g := range a.groups[i] {
+			sort.Slice(g.durations, func(i, j int) bool { return g.durations[i] < g.durations[j] })
+			g.Min, g.Max = g.durations[0], g.durations[len(g.durations)-1]
+			g.Avg = g.total / time.Duration(g.Calls)
+			g.AvgTTFB = g.ttfb / time.Duration(g.Calls)
+			g.P50, g.P90, g.P99 = percentile(g.durations, 50), percentile(g.durations, 90), percentile(g.durations, 99)
+			g.Histogram = make([]traceAnalyzeBucket, len(traceAnalyzeBounds)+1)
+			for j, bound := range traceAnalyzeBounds {
+				g.Histogram[j].LE = bound.String()
+			}
+			g.Histogram[len(traceAnalyzeBounds)].LE = "+Inf"
+			for _, d := range g.durations {
+				j := sort.Search(len(traceAnalyzeBounds), func(j int) bool { return d <= traceAnalyzeBounds[j] })
+				g.Histogram[j].Count++
+			}
+			groups = append(groups, *g)
+		}
+		sort.Slice(groups, func(i, j int) bool {
+			if groups[i].Calls == groups[j].Calls {
+				return groups[i].Name < groups[j].Name
+			}
+			return groups[i].Calls > groups[j].Calls
+		})
+		a.Groups[dim] = groups
+	}
+
+	a.ErrorCalls = make([]traceAnalyzeError, 0, len(a.errors))
+	for e, n := range a.errors {
+		e.Count = n
+		a.ErrorCalls = append(a.ErrorCalls, e)
+	}
+	sort.Slice(a.ErrorCalls, func(i, j int) bool {
+		x, y := a.ErrorCalls[i], a.ErrorCalls[j]
+		switch {
+		case x.Count != y.Count:
+			return x.Count > y.Count
+		case x.API != y.API:
+			return x.API < y.API
+		case x.Status != y.Status:
+			return x.Status < y.Status
+		}
+		return x.Error < y.Error
+	})
+
+	a.Throughput = make([]traceAnalyzeInterval, 0, len(a.intervals))
+	for _, in := range a.intervals {
+		a.Throughput = append(a.Throughput, *in)
+	}
+	sort.Slice(a.Throughput, func(i, j int) bool { return a.Throughput[i].Time.Before(a.Throughput[j].Time) })
+}
+
+func (a *traceAnalysis) JSON() string {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetIndent("", " ")
+	enc.SetEscapeHTML(false)
+	fatalIf(probe.NewError(enc.Encode(a)), "Unable to marshal into JSON.")
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// String renders all the reports, when the output is not a terminal.
+func (a *traceAnalysis) String() string {
+	var s strings.Builder
+	for i, title := range traceAnalyzeTitles {
+		if i > 0 {
+			s.WriteString("\n")
+		}
+		s.WriteString(console.Colorize("metrics-top-title", title) + "\n")
+		s.WriteString(a.render(i, 0))
+	}
+	return strings.TrimSuffix(s.String(), "\n")
+}
+
+var traceAnalyzeTitles = []string{"Per API", "Per node", "Per bucket", "Per client", "Latency", "Errors", "Slowest", "Throughput"}
+
+// render returns a report, rows of the tables of groups start at offset.
+func (a *traceAnalysis) render(report, offset int) string {
+	if report < len(traceAnalyzeDimensions) {
+		ui := newTraceStatsUI(false, 0, a.stats[report])
+		ui.offset = offset
+		return ui.View()
+	}
+
+	var rows [][]string
+	var s strings.Builder
+	switch traceAnalyzeTitles[report] {
+	case "Latency":
+		header := []string{"Call", "P50", "P90", "P99"}
+		for _, b := range traceAnalyzeBounds {
+			header = append(header, "≤"+b.String())
+		}
+		rows = append(rows, append(header, ">"+traceAnalyzeBounds[len(traceAnalyzeBounds)-1].String()))
+		for _, g := range a.Groups["api"] {
+			row := []string{metricsTitle(g.Name), roundDur(g.P50).String(), roundDur(g.P90).String(), roundDur(g.P99).String()}
+			for _, b := range g.Histogram {
+				row = append(row, strconv.Itoa(b.Count))
+			}
+			rows = append(rows, row)
+		}
+	case "Errors":
+		rows = append(rows, []string{"Call", "Status", "Error", "Count"})
+		for _, e := range a.ErrorCalls {
+			status := "-"
+			if e.Status > 0 {
+				status = fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status))
+			}
+			rows = append(rows, []string{metricsTitle(e.API), status, e.Error, console.Colorize("metrics-error", strconv.Itoa(e.Count))})
+		}
+	case "Slowest":
+		rows = append(rows, []string{"Time", "Call", "Node", "Path", "Status", "Duration"})
+		for _, c := range a.Slowest {
+			status := strconv.Itoa(c.Status)
+			if c.Error != "" {
+				status = c.Error
+			}
+			rows = append(rows, []string{
+				c.Time.Local().Format(traceTimeFormat), metricsTitle(c.API), c.Node, c.Path, status,
+				console.Colorize("metrics-dur-high", roundDur(c.Duration).String()),
+			})
+		}
+	case "Throughput":
+		rows = append(rows, []string{"Time", "Calls", "Errors", "RX", "TX"})
+		for _, in := range a.Throughput {
+			rows = append(rows, []string{
+				in.Time.Local().Format(traceTimeFormat), strconv.Itoa(in.Calls), strconv.Itoa(in.Errors),
+				ibytesShort(uint64(in.Rx)), ibytesShort(uint64(in.Tx)),
+			})
+		}
+	}
+	if len(rows) == 1 {
+		return "(no calls)\n"
+	}
+	for i := range rows[0] {
+		rows[0][i] = console.Colorize("metrics-top-title", rows[0][i])
+	}
+	if offset = min(max(0, offset), len(rows)-2); offset > 0 {
+		rows = append(rows[:1], rows[1+offset:]...)
+	}
+
+	table := tablewriter.NewWriter(&s)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(true)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+	table.SetHeaderLine(false)
+	table.SetBorder(false)
+	table.SetTablePadding("  ")
+	table.SetNoWhiteSpace(true)
+	table.AppendBulk(rows)
+	table.Render()
+	return s.String()
+}
+
+// writeCSV writes a report as CSV, durations are in milliseconds.
+func (a *traceAnalysis) writeCSV(w io.Writer, section string) error {
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+	}
+	cw := csv.NewWriter(w)
+	switch section {
+	case "groups":
+		header := []string{"dimension", "name", "calls", "errors", "rx", "tx", "size", "min_ms", "avg_ms", "p50_ms", "p90_ms", "p99_ms", "max_ms", "avg_ttfb_ms"}
+		for _, b := range traceAnalyzeBounds {
+			header = append(header, "le_"+b.String())
+		}
+		cw.Write(append(header, "le_inf"))
+		for _, dim := range traceAnalyzeDimensions {
+			for _, g := range a.Groups[dim] {
+				row := []string{
+					dim, g.Name, strconv.Itoa(g.Calls), strconv.Itoa(g.Errors),
+					strconv.FormatInt(g.Rx, 10), strconv.FormatInt(g.Tx, 10), strconv.FormatInt(g.Size, 10),
+					ms(g.Min), ms(g.Avg), ms(g.P50), ms(g.P90), ms(g.P99), ms(g.Max), ms(g.AvgTTFB),
+				}
+				for _, b := range g.Histogram {
+					row = append(row, strconv.Itoa(b.Count))
+				}
+				cw.Write(row)
+			}
+		}
+	case "errors":
+		cw.Write([]string{"api", "status", "error", "count"})
+		for _, e := range a.ErrorCalls {
+			cw.Write([]string{e.API, strconv.Itoa(e.Status), e.Error, strconv.Itoa(e.Count)})
+		}
+	case "slowest":
+		cw.Write([]string{"time", "api", "node", "client", "path", "status", "error", "duration_ms"})
+		for _, c := range a.Slowest {
+			cw.Write([]string{c.Time.Format(time.RFC3339Nano), c.API, c.Node, c.Client, c.Path, strconv.Itoa(c.Status), c.Error, ms(c.Duration)})
+		}
+	case "throughput":
+		cw.Write([]string{"time", "calls", "errors", "rx", "tx"})
+		for _, in := range a.Throughput {
+			cw.Write([]string{in.Time.Format(time.RFC3339), strconv.Itoa(in.Calls), strconv.Itoa(in.Errors), strconv.FormatInt(in.Rx, 10), strconv.FormatInt(in.Tx, 10)})
+		}
+	default:
+		return fmt.Errorf("unknown section `%s`", section)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// traceAnalyzeUI shows the reports one at a time.
+type traceAnalyzeUI struct {
+	analysis *traceAnalysis
+	report   int
+	offset   int
+}
+
+func (m *traceAnalyzeUI) Init() tea.Cmd {
+	return nil
+}
+
+func (m *traceAnalyzeUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "tab", "right":
+			m.report, m.offset = (m.report+1)%len(traceAnalyzeTitles), 0
+		case "shift+tab", "left":
+			m.report, m.offset = (m.report+len(traceAnalyzeTitles)-1)%len(traceAnalyzeTitles), 0
+		case "down":
+			m.offset++
+		case "up":
+			m.offset = max(0, m.offset-1)
+		case "home":
+			m.offset = 0
+		}
+	}
+	return m, nil
+}
+
+func (m *traceAnalyzeUI) View() string {
+	var s strings.Builder
+	for i, title := range traceAnalyzeTitles {
+		if i == m.report {
+			title = console.Colorize("metrics-top-title", "["+title+"]")
+		}
+		s.WriteString(title + "  ")
+	}
+	s.WriteString("\n")
+	fmt.Fprintf(&s, "%d calls, %d errors from %s to %s\n", m.analysis.Calls, m.analysis.Errors,
+		m.analysis.Start.Local().Format(traceTimeFormat), m.analysis.End.Local().Format(traceTimeFormat))
+	s.WriteString(m.analysis.render(m.report, m.offset))
+	return s.String()
+}
+
+// savedTraceReader decompresses a trace saved with zstd.
+type savedTraceReader struct {
+	*zstd.Decoder
+	f *os.File
+}
+
+func (r savedTraceReader) Close() error {
+	r.Decoder.Close()
+	return r.f.Close()
+}
+
+// openSavedTrace opens a trace saved by 'mc admin trace --json', '-' is
+// stdin and .zst files are decompressed.
+func openSavedTrace(file string) (io.ReadCloser, error) {
+	if file == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	f, e := os.Open(file)
+	if e != nil {
+		return nil, e
+	}
+	if !strings.HasSuffix(file, ".zst") {
+		return f, nil
+	}
+	zr, e := zstd.NewReader(f)
+	if e != nil {
+		f.Close()
+		return nil, e
+	}
+	return savedTraceReader{Decoder: zr, f: f}, nil
+}
+
+// readTraceRecords calls fn with the calls of a trace saved by 'mc admin
+// trace --json', and returns the number of other records.
+func readTraceRecords(r io.Reader, fn func(madmin.ServiceTraceInfo)) (skipped int, e error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var rec traceRecord
+		e = dec.Decode(&rec)
+		if e == io.EOF {
+			return skipped, nil
+		}
+		if e != nil {
+			if _, ok := e.(*json.UnmarshalTypeError); ok {
+				skipped++
+				continue
+			}
+			return skipped, e
+		}
+		if rec.FuncName == "" || rec.Type == "Bootstrap" {
+			// Ignore bootstrap, since their times skews averages.
+			skipped++
+			continue
+		}
+		fn(rec.traceInfo())
+	}
+}
+
+// readTraceAnalysis analyzes the calls of a saved trace which match
+// the filters.
+func readTraceAnalysis(r io.Reader, a *traceAnalysis, match func(madmin.ServiceTraceInfo) bool) error {
+	skipped, e := readTraceRecords(r, func(ti madmin.ServiceTraceInfo) {
+		if match(ti) {
+			a.add(ti)
+		}
+	})
+	a.Skipped += skipped
+	if e != nil {
+		return e
+	}
+	a.finish()
+	return nil
+}
+
+// mainAdminTraceAnalyze is the handle for "mc admin trace analyze" command.
+func mainAdminTraceAnalyze(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+	if (cmd.Bool("filter-request") || cmd.Bool("filter-response")) && cmd.String("filter-size") == "" {
+		showCommandHelpAndExit(ctx, cmd, 1)
+	}
+	format := cmd.String("format")
+	if globalJSON {
+		format = "json"
+	}
+	if format != "table" && format != "json" && format != "csv" {
+		fatalIf(errInvalidArgument().Trace(format), "Unknown format `%s`, use 'table', 'json' or 'csv'.", format)
+	}
+	switch section := cmd.String("section"); section {
+	case "groups", "errors", "slowest", "throughput":
+	default:
+		fatalIf(errInvalidArgument().Trace(section), "Unknown section `%s`, use 'groups', 'errors', 'slowest' or 'throughput'.", section)
+	}
+	var since, until time.Time
+	for _, t := range []struct {
+		flag  string
+		value *time.Time
+	}{{"since", &since}, {"until", &until}} {
+		if v := cmd.String(t.flag); v != "" {
+			var e error
+			*t.value, e = time.Parse(time.RFC3339, v)
+			fatalIf(probe.NewError(e).Trace(v), "Unable to parse --%s.", t.flag)
+		}
+	}
+
+	file := cmd.Args().First()
+	in, e := openSavedTrace(file)
+	fatalIf(probe.NewError(e), "Unable to open input")
+	defer in.Close()
+
+	mopts := matchingOpts(ctx, cmd)
+	onlyErrors := cmd.Bool("errors")
+	threshold := cmd.Duration("response-duration")
+	match := func(ti madmin.ServiceTraceInfo) bool {
+		t := ti.Trace
+		switch {
+		case !since.IsZero() && t.Time.Before(since):
+			return false
+		case !until.IsZero() && !t.Time.Before(until):
+			return false
+		case onlyErrors && !traceCallFailed(t):
+			return false
+		case threshold > 0 && t.Duration < threshold:
+			return false
+		}
+		return mopts.matches(ti)
+	}
+
+	analysis := newTraceAnalysis(file, cmd.Int("top"), cmd.Duration("interval"))
+	setTraceStatsColors()
+	e = readTraceAnalysis(in, analysis, match)
+	fatalIf(probe.NewError(e).Trace(file), "Unable to read trace")
+
+	switch {
+	case format == "csv":
+		e = analysis.writeCSV(os.Stdout, cmd.String("section"))
+		fatalIf(probe.NewError(e), "Unable to write CSV.")
+	case format == "json" || !term.IsTerminal(int(os.Stdout.Fd())):
+		if format == "json" {
+			globalJSON = true
+		}
+		printMsg(analysis)
+	default:
+		_, e = tea.NewProgram(&traceAnalyzeUI{analysis: analysis}).Run()
+		fatalIf(probe.NewError(e), "Unable to show the analysis")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openstor/madmin-go/v4"
+)
+
+func TestReadTraceAnalysis(t *testing.T) {
+	// Short and verbose records, as printed by 'mc admin trace --json'.
+	trace := `{
+ "status": "success",
+ "host": "node1:9000",
+ "time": "2024-06-01T10:00:10Z",
+ "client": "10.0.0.1",
+ "callStats": {"rx": 100, "tx": 2000, "duration": 30000000, "timeToFirstByte": 0},
+ "duration": 30000000,
+ "api": "s3.GetObject",
+ "path": "/photos/a.jpg",
+ "statusCode": 200,
+ "type": "S3"
+}
+{"type":"S3","host":"node2:9000","api":"s3.GetObject","time":"2024-06-01T10:00:20Z","duration":2000000,"path":"/photos/b.jpg","request":{"time":"2024-06-01T10:00:20Z","proto":"HTTP/1.1","method":"GET","headers":{"X-Forwarded-For":"10.0.0.2, 10.0.0.9"}},"response":{"time":"2024-06-01T10:00:20Z","statusCode":404},"callStats":{"rx":0,"tx":300,"duration":2000000,"timeToFirstByte":0}}
+{"type":"S3","host":"node1:9000","api":"s3.PutObject","time":"2024-06-01T10:01:05Z","duration":700000000,"path":"/docs/c.txt","statusCode":200,"client":"10.0.0.1","callStats":{"rx":5000,"tx":0,"duration":700000000,"timeToFirstByte":0}}
+{"type":"Bootstrap","host":"node1:9000","api":"initServer","time":"2024-06-01T09:00:00Z","duration":9000000000}
+{"type":"Internal","host":"node2:9000","api":"internal.ReadAll","time":"2024-06-01T10:01:10Z","duration":1000000,"error":"file not found"}
+`
+	a := newTraceAnalysis("trace.json", 2, time.Minute)
+	all := func(madmin.ServiceTraceInfo) bool { return true }
+	if e := readTraceAnalysis(strings.NewReader(trace), a, all); e != nil {
+		t.Fatal(e)
+	}
+	if a.Calls != 4 || a.Errors != 2 || a.Skipped != 1 || a.Rx != 5100 || a.Tx != 2300 {
+		t.Fatalf("unexpected totals %+v", a)
+	}
+
+	names := func(dim string) (s []string) {
+		for _, g := range a.Groups[dim] {
+			s = append(s, g.Name)
+		}
+		return s
+	}
+	for dim, expected := range map[string]string{
+		"api":    "s3.GetObject internal.ReadAll s3.PutObject",
+		"node":   "node1:9000 node2:9000",
+		"bucket": "photos docs",
+		"client": "10.0.0.1 10.0.0.2",
+	} {
+		if got := strings.Join(names(dim), " "); got != expected {
+			t.Errorf("%s: expected %q, got %q", dim, expected, got)
+		}
+	}
+
+	get := a.Groups["api"][0]
+	if get.Calls != 2 || get.Errors != 1 || get.Min != 2*time.Millisecond || get.Max != 30*time.Millisecond || get.P50 != 2*time.Millisecond {
+		t.Errorf("unexpected GetObject stats %+v", get)
+	}
+	// 2ms is in the 5ms bucket, 30ms in the 50ms one.
+	if get.Histogram[1].Count != 1 || get.Histogram[4].Count != 1 {
+		t.Errorf("unexpected GetObject histogram %+v", get.Histogram)
+	}
+
+	if len(a.ErrorCalls) != 2 || a.ErrorCalls[0] != (traceAnalyzeError{API: "internal.ReadAll", Error: "file not found", Count: 1}) ||
+		a.ErrorCalls[1] != (traceAnalyzeError{API: "s3.GetObject", Status: 404, Count: 1}) {
+		t.Errorf("unexpected errors %+v", a.ErrorCalls)
+	}
+	if len(a.Slowest) != 2 || a.Slowest[0].API != "s3.PutObject" || a.Slowest[1].Duration != 30*time.Millisecond {
+		t.Errorf("unexpected slowest calls %+v", a.Slowest)
+	}
+	if len(a.Throughput) != 2 || a.Throughput[0].Calls != 2 || a.Throughput[1].Calls != 2 || a.Throughput[1].Rx != 5000 {
+		t.Errorf("unexpected throughput %+v", a.Throughput)
+	}
+
+	var buf bytes.Buffer
+	if e := a.writeCSV(&buf, "errors"); e != nil {
+		t.Fatal(e)
+	}
+	if expected := "api,status,error,count\ninternal.ReadAll,0,file not found,1\ns3.GetObject,404,,1\n"; buf.String() != expected {
+		t.Errorf("expected CSV %q, got %q", expected, buf.String())
+	}
+}
@@ -195,6 +195,7 @@ var adminTraceCmd = cli.Command{
 	OnUsageError:    onUsageError,
 	Before:          setGlobalsFromContext,
 	Flags:           append(adminTraceFlags, globalFlags...),
+	Commands:        []*cli.Command{&adminTraceAnalyzeCmd},
 	HideHelpCommand: true,
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
@@ -1005,7 +1006,11 @@ func (s *statTrace) String() string {
 }
 
 func (s *statTrace) add(t madmin.ServiceTraceInfo) {
-	id := t.Trace.FuncName
+	s.addAs(t.Trace.FuncName, t)
+}
+
+// addAs adds a call to the stats of id.
+func (s *statTrace) addAs(id string, t madmin.ServiceTraceInfo) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if t.Trace.TraceType != madmin.TraceBootstrap {
@@ -34,6 +34,9 @@ USAGE:
     "path": "auto"
   }
 
+  Secret keys kept in a credential store are not exported, the exported
+  "credentialStore" field refers to the store instead.
+
 FLAGS:
   {{range .VisibleFlags}}{{.}}
   {{end}}
@@ -65,10 +68,10 @@ func checkAliasExportSyntax(ctx context.Context, cmd *cli.Command) {
 
 // exportAlias - get an alias config
 func exportAlias(alias string) {
-	mcCfgV10, err := loadMcConfig()
+	mcCfgV11, err := loadMcConfig()
 	fatalIf(err.Trace(globalMCConfigVersion), "Unable to load config `"+mustGetMcConfigPath()+"`.")
 
-	cfg, ok := mcCfgV10.Aliases[alias]
+	cfg, ok := mcCfgV11.Aliases[alias]
 	if !ok {
 		fatalIf(errInvalidArgument().Trace(alias), "Unable to export credentials")
 	}
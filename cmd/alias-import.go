@@ -28,13 +28,18 @@ import (
 )
 
 var aliasImportCmd = cli.Command{
-	Name:            "import",
-	Aliases:         []string{"i"},
-	Usage:           "import configuration info to configuration file from a JSON formatted string ",
-	Action:          mainAliasImport,
-	OnUsageError:    onUsageError,
-	Before:          setGlobalsFromContext,
-	Flags:           globalFlags,
+	Name:         "import",
+	Aliases:      []string{"i"},
+	Usage:        "import configuration info to configuration file from a JSON formatted string ",
+	Action:       mainAliasImport,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:  "credential-store",
+			Usage: "keep the imported secret key out of the configuration file. Valid options are '[keystore, helper:NAME]'",
+		},
+	}, globalFlags...),
 	HideHelpCommand: true,
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
@@ -52,6 +57,9 @@ USAGE:
     "path": "auto"
   }
 
+  A "credentialStore" field keeps the secret key in that credential store,
+  the secret key may then be omitted if the store already holds it.
+
 FLAGS:
   {{range .VisibleFlags}}{{.}}
   {{end}}
@@ -61,6 +69,9 @@ EXAMPLES:
 
   2. Import the credentials through standard input as 'myminio' to the config:
      {{ .Prompt }} cat credentials.json | {{ .HelpName }} myminio/
+
+  3. Import the credentials as 'myminio', keeping the secret key in the passphrase protected keystore:
+     {{ .Prompt }} {{ .HelpName }} --credential-store keystore myminio/ ./credentials.json
 `,
 }
 
@@ -83,7 +94,7 @@ func checkAliasImportSyntax(ctx context.Context, cmd *cli.Command) {
 	}
 }
 
-func checkCredentialsSyntax(credentials aliasConfigV10) {
+func checkCredentialsSyntax(credentials aliasConfigV11) {
 	if !isValidHostURL(credentials.URL) {
 		fatalIf(errInvalidURL(credentials.URL), "Invalid URL.")
 	}
@@ -98,6 +109,11 @@ func checkCredentialsSyntax(credentials aliasConfigV10) {
 			"Invalid secret key.")
 	}
 
+	if !isValidCredentialStore(credentials.CredentialStore) {
+		fatalIf(errInvalidArgument().Trace(credentials.CredentialStore),
+			"Unrecognized credential store. Valid options are `[keystore, helper:NAME]`.")
+	}
+
 	if credentials.API != "" && !isValidAPI(credentials.API) { // Empty value set to default "S3v4".
 		fatalIf(errInvalidArgument().Trace(credentials.API),
 			"Unrecognized API signature. Valid options are `[S3v4, S3v2]`.")
@@ -109,22 +125,34 @@ func checkCredentialsSyntax(credentials aliasConfigV10) {
 }
 
 // importAlias - set an alias config based on imported values.
-func importAlias(alias string, aliasCfgV10 aliasConfigV10) aliasMessage {
-	checkCredentialsSyntax(aliasCfgV10)
+func importAlias(alias string, aliasCfgV11 aliasConfigV11) aliasMessage {
+	checkCredentialsSyntax(aliasCfgV11)
 
-	mcCfgV10, err := loadMcConfig()
+	mcCfgV11, err := loadMcConfig()
 	fatalIf(err.Trace(globalMCConfigVersion), "Unable to load config `"+mustGetMcConfigPath()+"`.")
 
+	// Secrets of the previous definition are not left behind.
+	if prevCfg, ok := mcCfgV11.Aliases[alias]; ok && prevCfg.CredentialStore != aliasCfgV11.CredentialStore {
+		errorIf(eraseAliasCredentials(alias, prevCfg), "Unable to remove the previous credentials of `%s`.", alias)
+	}
+
+	// Imported secrets go to the credential store, if any.
+	if aliasCfgV11.SecretKey != "" {
+		err = storeAliasCredentials(alias, &aliasCfgV11)
+		fatalIf(err.Trace(alias), "Unable to store the credentials of `%s` in `%s`.", alias, aliasCfgV11.CredentialStore)
+	}
+
 	// Add new host.
-	mcCfgV10.Aliases[alias] = aliasCfgV10
-	fatalIf(saveMcConfig(mcCfgV10).Trace(alias), "Unable to import credentials to `"+mustGetMcConfigPath()+"`.")
+	mcCfgV11.Aliases[alias] = aliasCfgV11
+	fatalIf(saveMcConfig(mcCfgV11).Trace(alias), "Unable to import credentials to `"+mustGetMcConfigPath()+"`.")
 	return aliasMessage{
-		Alias:     alias,
-		URL:       mcCfgV10.Aliases[alias].URL,
-		AccessKey: mcCfgV10.Aliases[alias].AccessKey,
-		SecretKey: mcCfgV10.Aliases[alias].SecretKey,
-		API:       mcCfgV10.Aliases[alias].API,
-		Path:      mcCfgV10.Aliases[alias].Path,
+		Alias:           alias,
+		URL:             mcCfgV11.Aliases[alias].URL,
+		AccessKey:       mcCfgV11.Aliases[alias].AccessKey,
+		SecretKey:       mcCfgV11.Aliases[alias].SecretKey,
+		API:             mcCfgV11.Aliases[alias].API,
+		Path:            mcCfgV11.Aliases[alias].Path,
+		CredentialStore: mcCfgV11.Aliases[alias].CredentialStore,
 	}
 }
 
@@ -135,7 +163,7 @@ func mainAliasImport(ctx context.Context, cmd *cli.Command) error {
 	)
 
 	checkAliasImportSyntax(ctx, cmd)
-	var credentialsJSON aliasConfigV10
+	var credentialsJSON aliasConfigV11
 
 	credsFile := strings.TrimSpace(args.Get(1))
 	if credsFile == "" {
@@ -147,6 +175,10 @@ func mainAliasImport(ctx context.Context, cmd *cli.Command) error {
 	e = json.Unmarshal(input, &credentialsJSON)
 	fatalIf(probe.NewError(e).Trace(credsFile), "Unable to parse input credentials")
 
+	if credentialStore := cmd.String("credential-store"); credentialStore != "" {
+		credentialsJSON.CredentialStore = credentialStore
+	}
+
 	msg := importAlias(alias, credentialsJSON)
 	msg.op = cmd.Name
 
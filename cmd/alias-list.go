@@ -103,7 +103,7 @@ func printAliases(aliases ...aliasMessage) {
 			// Format properly for alignment based on alias length only in non json mode.
 			alias.Alias = fmt.Sprintf("%-*.*s", maxAlias, maxAlias, alias.Alias)
 		}
-		if alias.AccessKey == "" || alias.SecretKey == "" {
+		if alias.CredentialStore == "" && (alias.AccessKey == "" || alias.SecretKey == "") {
 			alias.AccessKey = ""
 			alias.SecretKey = ""
 			alias.API = ""
@@ -119,7 +119,7 @@ func (d byAlias) Len() int           { return len(d) }
 func (d byAlias) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
 func (d byAlias) Less(i, j int) bool { return d[i].Alias < d[j].Alias }
 
-func buildAliasMessage(alias string, deprecated bool, aliasCfg *aliasConfigV10) aliasMessage {
+func buildAliasMessage(alias string, deprecated bool, aliasCfg *aliasConfigV11) aliasMessage {
 	aliasMsg := aliasMessage{
 		prettyPrint: false,
 		Alias:       alias,
@@ -128,6 +128,12 @@ func buildAliasMessage(alias string, deprecated bool, aliasCfg *aliasConfigV10)
 		SecretKey:   aliasCfg.SecretKey,
 		API:         aliasCfg.API,
 		Src:         aliasCfg.Src,
+		Profile:     aliasCfg.Profile,
+	}
+	// Secrets kept in a credential store are never displayed.
+	if aliasCfg.CredentialStore != "" {
+		aliasMsg.SecretKey = ""
+		aliasMsg.CredentialStore = aliasCfg.CredentialStore
 	}
 
 	if deprecated {
@@ -142,7 +148,20 @@ func buildAliasMessage(alias string, deprecated bool, aliasCfg *aliasConfigV10)
 func listAliases(alias string, deprecated bool) (aliases []aliasMessage) {
 	// If specific alias is requested, look for it and print.
 	if alias != "" {
-		aliasCfg := mustGetHostConfig(alias)
+		// Listing does not need secrets, configured aliases are
+		// not resolved from their credential store.
+		aliasCfg, _ := expandAliasFromEnv(env.Get(mcEnvHostPrefix+alias, ""))
+		if aliasCfg == nil {
+			aliasCfg = aliasToConfigMap[alias]
+		}
+		if aliasCfg == nil {
+			if conf, err := loadMcConfig(); err == nil {
+				if v, ok := conf.Aliases[alias]; ok {
+					v.Src = mustGetMcConfigPath()
+					aliasCfg = &v
+				}
+			}
+		}
 		if aliasCfg != nil {
 			return []aliasMessage{buildAliasMessage(alias, deprecated, aliasCfg)}
 		}
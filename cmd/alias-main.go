@@ -45,6 +45,7 @@ var aliasSubcommands = []*cli.Command{
 	&aliasRemoveCmd,
 	&aliasImportCmd,
 	&aliasExportCmd,
+	&aliasProfileCmd,
 }
 
 var aliasCmd = cli.Command{
@@ -80,6 +81,10 @@ type aliasMessage struct {
 	API         string `json:"api,omitempty"`
 	Path        string `json:"path,omitempty"`
 	Src         string `json:"src,omitempty"`
+	// Secret key is kept in this credential store
+	CredentialStore string `json:"credentialStore,omitempty"`
+	// Default options of the alias
+	Profile string `json:"profile,omitempty"`
 	// Deprecated field, replaced by Path
 	Lookup string `json:"lookup,omitempty"`
 }
@@ -91,21 +96,29 @@ func (h aliasMessage) String() string {
 	switch h.op {
 	case "list":
 		// Create a new pretty table with cols configuration
-		t := newPrettyRecord(2,
-			Row{"Alias", "Alias"},
-			Row{"URL", "URL"},
-			Row{"AccessKey", "AccessKey"},
-			Row{"SecretKey", "SecretKey"},
-			Row{"API", "API"},
-			Row{"Path", "Path"},
-			Row{"Src", "Src"},
-		)
+		rows := []Row{
+			{"Alias", "Alias"},
+			{"URL", "URL"},
+			{"AccessKey", "AccessKey"},
+			{"SecretKey", "SecretKey"},
+			{"API", "API"},
+			{"Path", "Path"},
+			{"Src", "Src"},
+		}
+		if h.Profile != "" {
+			rows = append(rows, Row{"Profile", "Profile"})
+		}
+		t := newPrettyRecord(2, rows...)
 		// Handle deprecated lookup
 		path := h.Path
 		if path == "" {
 			path = h.Lookup
 		}
-		return t.buildRecord(h.Alias, h.URL, h.AccessKey, h.SecretKey, h.API, path, h.Src)
+		secretKey := h.SecretKey
+		if h.CredentialStore != "" {
+			secretKey = "<" + h.CredentialStore + ">"
+		}
+		return t.buildRecord(h.Alias, h.URL, h.AccessKey, secretKey, h.API, path, h.Src, h.Profile)
 	case "remove":
 		return console.Colorize("AliasMessage", "Removed `"+h.Alias+"` successfully.")
 	case "add": // add is deprecated
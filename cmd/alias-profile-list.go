@@ -0,0 +1,90 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var aliasProfileListCmd = cli.Command{
+	Name:    "list",
+	Aliases: []string{"ls"},
+	Usage:   "list profiles of default options",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		return mainAliasProfileList(ctx, cmd)
+	},
+	OnUsageError:    onUsageError,
+	Before:          setGlobalsFromContext,
+	Flags:           globalFlags,
+	HideHelpCommand: true,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [PROFILE]
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. List all profiles and the aliases using them.
+     {{.Prompt}} {{.HelpName}}
+
+  2. Show the options of the profile "wan".
+     {{.Prompt}} {{.HelpName}} wan
+`,
+}
+
+// mainAliasProfileList is the handle for "mc alias profile list" command.
+func mainAliasProfileList(ctx context.Context, cmd *cli.Command) error {
+	if cmd.NArg() > 1 {
+		showCommandHelpAndExit(ctx, cmd, 1)
+	}
+	console.SetColor("Profile", color.New(color.FgCyan, color.Bold))
+
+	mcCfg, err := loadMcConfig()
+	fatalIf(err.Trace(globalMCConfigVersion), "Unable to load config `"+mustGetMcConfigPath()+"`.")
+
+	var names []string
+	if name := cmd.Args().First(); name != "" {
+		if _, ok := mcCfg.Profiles[name]; !ok {
+			fatalIf(errInvalidArgument().Trace(name), "No such profile `"+name+"`.")
+		}
+		names = append(names, name)
+	} else {
+		for name := range mcCfg.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		printMsg(aliasProfileMessage{
+			op:      "list",
+			Profile: name,
+			Options: profileKeyValues(mcCfg.Profiles[name]),
+			Aliases: getProfileAliases(mcCfg, name),
+		})
+	}
+	return nil
+}
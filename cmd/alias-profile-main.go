@@ -0,0 +1,86 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	json "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var aliasProfileSubcommands = []*cli.Command{
+	&aliasProfileSetCmd,
+	&aliasProfileListCmd,
+	&aliasProfileRemoveCmd,
+}
+
+var aliasProfileCmd = cli.Command{
+	Name:            "profile",
+	Usage:           "manage named profiles of default options for aliases",
+	Action:          mainAliasProfile,
+	Before:          setGlobalsFromContext,
+	HideHelpCommand: true,
+	Flags:           globalFlags,
+	Commands:        aliasProfileSubcommands,
+}
+
+func mainAliasProfile(ctx context.Context, cmd *cli.Command) error {
+	commandNotFound(ctx, cmd, []cli.Command{})
+	return nil
+}
+
+// aliasProfileMessage container for profile messages
+type aliasProfileMessage struct {
+	op      string
+	Status  string   `json:"status"`
+	Profile string   `json:"profile"`
+	Options []string `json:"options,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+func (p aliasProfileMessage) String() string {
+	switch p.op {
+	case "list":
+		var b strings.Builder
+		b.WriteString(console.Colorize("Profile", p.Profile) + "\n")
+		if len(p.Aliases) > 0 {
+			b.WriteString("  Aliases : " + strings.Join(p.Aliases, ", ") + "\n")
+		}
+		for _, kv := range p.Options {
+			b.WriteString("  " + kv + "\n")
+		}
+		return b.String()
+	case "remove":
+		return console.Colorize("AliasMessage", "Removed profile `"+p.Profile+"` successfully.")
+	case "set":
+		return console.Colorize("AliasMessage", "Profile `"+p.Profile+"` updated successfully.")
+	}
+	return ""
+}
+
+func (p aliasProfileMessage) JSON() string {
+	p.Status = "success"
+	jsonMessageBytes, e := json.MarshalIndent(p, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(jsonMessageBytes)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var aliasProfileRemoveCmd = cli.Command{
+	Name:    "remove",
+	Aliases: []string{"rm"},
+	Usage:   "remove a profile of default options",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		return mainAliasProfileRemove(ctx, cmd)
+	},
+	OnUsageError:    onUsageError,
+	Before:          setGlobalsFromContext,
+	Flags:           globalFlags,
+	HideHelpCommand: true,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} PROFILE
+
+  A profile still used by an alias cannot be removed.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Remove the profile "wan".
+     {{.Prompt}} {{.HelpName}} wan
+`,
+}
+
+// mainAliasProfileRemove is the handle for "mc alias profile remove" command.
+func mainAliasProfileRemove(ctx context.Context, cmd *cli.Command) error {
+	if cmd.NArg() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1)
+	}
+	console.SetColor("AliasMessage", color.New(color.FgGreen))
+
+	name := cmd.Args().First()
+	mcCfg, err := loadMcConfig()
+	fatalIf(err.Trace(globalMCConfigVersion), "Unable to load config `"+mustGetMcConfigPath()+"`.")
+
+	if _, ok := mcCfg.Profiles[name]; !ok {
+		fatalIf(errInvalidArgument().Trace(name), "No such profile `"+name+"`.")
+	}
+	if aliases := getProfileAliases(mcCfg, name); len(aliases) > 0 {
+		fatalIf(errInvalidArgument().Trace(name),
+			"Profile `"+name+"` is used by `"+strings.Join(aliases, ", ")+"`.")
+	}
+	errorIf(eraseProfileEncKey(name, mcCfg.Profiles[name]), "Unable to remove the encryption key of `%s`.", name)
+	delete(mcCfg.Profiles, name)
+
+	err = saveMcConfig(mcCfg)
+	fatalIf(err.Trace(name), "Unable to update profiles in config `"+mustGetMcConfigPath()+"`.")
+
+	printMsg(aliasProfileMessage{op: "remove", Profile: name})
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var aliasProfileSetFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "credential-store",
+		Usage: "credential store keeping the enc-c key, 'keystore' by default. Valid options are '[keystore, helper:NAME]'",
+	},
+}
+
+var aliasProfileSetCmd = cli.Command{
+	Name:  "set",
+	Usage: "create or update a profile of default options",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		return mainAliasProfileSet(ctx, cmd)
+	},
+	OnUsageError:    onUsageError,
+	Before:          setGlobalsFromContext,
+	Flags:           append(aliasProfileSetFlags, globalFlags...),
+	HideHelpCommand: true,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} PROFILE KEY=VALUE [KEY=VALUE...]
+
+  Options of a profile apply to every command using an alias of the profile,
+  flags given on the command line always take precedence. An empty value
+  removes the option from the profile.
+
+KEYS:
+  limit-upload    upload bandwidth limit, e.g. 10MiB
+  limit-download  download bandwidth limit, e.g. 10MiB
+  insecure        disable TLS certificate verification, 'on' or 'off'
+  custom-header   add a custom header 'NAME: VALUE' to all requests, repeatable
+  resolve         resolve HOST[:PORT] to IP as 'HOST[:PORT]=IP', repeatable
+  ca-bundle       PEM file of additional trusted certificate authorities
+  region          region of the server
+  storage-class   default storage class of uploaded objects
+  checksum        checksum added to uploaded objects, e.g. CRC32C
+  multipart-size  size of each part of multipart uploads, e.g. 64MiB
+  enc-kms         server-side encryption key name used with the alias
+  enc-s3          encrypt with server-side default keys, 'on' or 'off'
+  enc-c           client provided encryption key, RawBase64 or Hex, kept in
+                  the credential store given by --credential-store
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Create a profile "wan" limiting the bandwidth and using a custom CA bundle.
+     {{.Prompt}} {{.HelpName}} wan limit-upload=10MiB limit-download=20MiB ca-bundle=/etc/ssl/corp-ca.pem
+
+  2. Upload with the CRC32C checksum and the STANDARD_IA storage class by default.
+     {{.Prompt}} {{.HelpName}} archive checksum=CRC32C storage-class=STANDARD_IA
+
+  3. Remove the upload bandwidth limit from the profile "wan".
+     {{.Prompt}} {{.HelpName}} wan limit-upload=
+
+  4. Encrypt with a client provided key kept in the credential helper "pass".
+     {{.Prompt}} {{.HelpName}} secure enc-c=MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE --credential-store helper:pass
+`,
+}
+
+// mainAliasProfileSet is the handle for "mc alias profile set" command.
+func mainAliasProfileSet(ctx context.Context, cmd *cli.Command) error {
+	if cmd.NArg() < 2 {
+		showCommandHelpAndExit(ctx, cmd, 1)
+	}
+	console.SetColor("AliasMessage", color.New(color.FgGreen))
+
+	args := cmd.Args()
+	name := args.First()
+	if !isValidAlias(name) {
+		fatalIf(errInvalidArgument().Trace(name), "Invalid profile name `"+name+"`.")
+	}
+
+	mcCfg, err := loadMcConfig()
+	fatalIf(err.Trace(globalMCConfigVersion), "Unable to load config `"+mustGetMcConfigPath()+"`.")
+	if mcCfg.Profiles == nil {
+		mcCfg.Profiles = make(map[string]profileConfigV11)
+	}
+
+	credentialStore := cmd.String("credential-store")
+	if !isValidCredentialStore(credentialStore) {
+		fatalIf(errInvalidArgument().Trace(credentialStore),
+			"Unrecognized credential store. Valid options are `[keystore, helper:NAME]`.")
+	}
+
+	prevProfile := mcCfg.Profiles[name]
+	profile := prevProfile
+	for _, kv := range args.Tail() {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			fatalIf(errInvalidArgument().Trace(kv), "Option `"+kv+"` is not in KEY=VALUE format.")
+		}
+		fatalIf(setProfileKey(&profile, key, value).Trace(name),
+			"Unable to set `"+key+"`. Valid keys are `"+strings.Join(profileKeys, ", ")+"`.")
+	}
+
+	// The encryption key never reaches the config file.
+	if profile.EncC != "" {
+		if credentialStore != "" {
+			profile.EncCStore = credentialStore
+		} else if profile.EncCStore == "" {
+			profile.EncCStore = credentialStoreKeystore
+		}
+	}
+	if prevProfile.EncCStore != "" && prevProfile.EncCStore != profile.EncCStore {
+		errorIf(eraseProfileEncKey(name, prevProfile), "Unable to remove the previous encryption key of `%s`.", name)
+	}
+	err = storeProfileEncKey(name, &profile)
+	fatalIf(err.Trace(name), "Unable to store the encryption key of `%s` in `%s`.", name, profile.EncCStore)
+	mcCfg.Profiles[name] = profile
+
+	err = saveMcConfig(mcCfg)
+	fatalIf(err.Trace(name), "Unable to update profiles in config `"+mustGetMcConfigPath()+"`.")
+
+	printMsg(aliasProfileMessage{op: "set", Profile: name})
+	return nil
+}
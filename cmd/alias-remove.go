@@ -96,8 +96,17 @@ func removeAlias(alias string) aliasMessage {
 	conf, err := loadMcConfig()
 	fatalIf(err.Trace(globalMCConfigVersion), "Unable to load config version `"+globalMCConfigVersion+"`.")
 
-	// check if alias is valid
-	aliasMustExist(alias)
+	// check if alias is valid, aliases in the config file are checked
+	// without resolving their secrets from a credential store.
+	aliasCfg, ok := conf.Aliases[alias]
+	if !ok {
+		aliasMustExist(alias)
+	}
+
+	// Remove the secrets of the alias from its credential store.
+	if ok {
+		errorIf(eraseAliasCredentials(alias, aliasCfg), "Unable to remove the credentials of `%s`.", alias)
+	}
 
 	// Remove the alias from the config.
 	delete(conf.Aliases, alias)
@@ -49,6 +49,14 @@ var aliasSetFlags = []cli.Flag{
 		Name:  "api",
 		Usage: "API signature. Valid options are '[S3v4, S3v2]'",
 	},
+	&cli.StringFlag{
+		Name:  "credential-store",
+		Usage: "keep the secret key out of the configuration file. Valid options are '[keystore, helper:NAME]'",
+	},
+	&cli.StringFlag{
+		Name:  "profile",
+		Usage: "apply the default options of a profile to commands using the alias",
+	},
 }
 
 var aliasSetCmd = cli.Command{
@@ -95,6 +103,18 @@ EXAMPLES:
      {{.Prompt}} echo -e "BKIKJAA5BMMU2RHO6IBB\nV8f1CwQqAcwo80UEIJEjc5gVQUSSx5ohQ9GSrr12" | \
                  {{.HelpName}} mys3 https://s3.amazonaws.com --api "s3v4" --path "off"
      {{.EnableHistory}}
+  6. Add MinIO service under "myminio" alias, keeping the secret key in a passphrase protected keystore.
+     {{.Prompt}} {{.HelpName}} myminio http://localhost:9000 --credential-store keystore
+     Enter Access Key: minio
+     Enter Secret Key: minio123
+     Enter new keystore passphrase:
+  7. Add MinIO service under "myminio" alias, keeping the secret key in the credential helper 'mc-credential-pass'.
+     {{.Prompt}} {{.HelpName}} myminio http://localhost:9000 --credential-store helper:pass
+  8. Add MinIO service under "myminio" alias, using the default options of the profile "wan".
+     {{.Prompt}} {{.HelpName}} myminio http://localhost:9000 minio minio123 --profile wan
+
+ENVIRONMENT VARIABLES:
+  MC_KEYSTORE_PASSPHRASE: passphrase of the keystore, prompted for when not set.
 `,
 }
 
@@ -136,6 +156,18 @@ func checkAliasSetSyntax(ctx context.Context, cmd *cli.Command, accessKey, secre
 			"Invalid secret key `"+secretKey+"`.")
 	}
 
+	if credentialStore := cmd.String("credential-store"); !isValidCredentialStore(credentialStore) {
+		fatalIf(errInvalidArgument().Trace(credentialStore),
+			"Unrecognized credential store. Valid options are `[keystore, helper:NAME]`.")
+	}
+
+	if profile := cmd.String("profile"); profile != "" {
+		if _, ok := getProfileConfig(profile); !ok {
+			fatalIf(errInvalidArgument().Trace(profile),
+				"No such profile `"+profile+"`, create it with `mc alias profile set`.")
+		}
+	}
+
 	if api != "" && !isValidAPI(api) { // Empty value set to default "S3v4".
 		fatalIf(errInvalidArgument().Trace(api),
 			"Unrecognized API signature. Valid options are `[S3v4, S3v2]`.")
@@ -155,23 +187,33 @@ func checkAliasSetSyntax(ctx context.Context, cmd *cli.Command, accessKey, secre
 }
 
 // setAlias - set an alias config.
-func setAlias(alias string, aliasCfgV10 aliasConfigV10) aliasMessage {
-	mcCfgV10, err := loadMcConfig()
+func setAlias(alias string, aliasCfgV11 aliasConfigV11) aliasMessage {
+	mcCfgV11, err := loadMcConfig()
 	fatalIf(err.Trace(globalMCConfigVersion), "Unable to load config `"+mustGetMcConfigPath()+"`.")
 
+	// Secrets of the previous definition are not left behind.
+	if prevCfg, ok := mcCfgV11.Aliases[alias]; ok && prevCfg.CredentialStore != aliasCfgV11.CredentialStore {
+		errorIf(eraseAliasCredentials(alias, prevCfg), "Unable to remove the previous credentials of `%s`.", alias)
+	}
+
+	err = storeAliasCredentials(alias, &aliasCfgV11)
+	fatalIf(err.Trace(alias), "Unable to store the credentials of `%s` in `%s`.", alias, aliasCfgV11.CredentialStore)
+
 	// Add new host.
-	mcCfgV10.Aliases[alias] = aliasCfgV10
+	mcCfgV11.Aliases[alias] = aliasCfgV11
 
-	err = saveMcConfig(mcCfgV10)
+	err = saveMcConfig(mcCfgV11)
 	fatalIf(err.Trace(alias), "Unable to update hosts in config version `"+mustGetMcConfigPath()+"`.")
 
 	return aliasMessage{
-		Alias:     alias,
-		URL:       aliasCfgV10.URL,
-		AccessKey: aliasCfgV10.AccessKey,
-		SecretKey: aliasCfgV10.SecretKey,
-		API:       aliasCfgV10.API,
-		Path:      aliasCfgV10.Path,
+		Alias:           alias,
+		URL:             aliasCfgV11.URL,
+		AccessKey:       aliasCfgV11.AccessKey,
+		SecretKey:       aliasCfgV11.SecretKey,
+		API:             aliasCfgV11.API,
+		Path:            aliasCfgV11.Path,
+		CredentialStore: aliasCfgV11.CredentialStore,
+		Profile:         aliasCfgV11.Profile,
 	}
 }
 
@@ -179,6 +221,7 @@ func setAlias(alias string, aliasCfgV10 aliasConfigV10) aliasMessage {
 // using v4 signature then v2 in case of failure.
 func probeS3Signature(ctx context.Context, accessKey, secretKey, url string, peerCert *x509.Certificate) (string, *probe.Error) {
 	probeBucketName := randString(60, rand.NewSource(time.Now().UnixNano()), "probe-bsign-")
+	uploadLimit, downloadLimit := transferLimits("", url, nil)
 	// Test s3 connection for API auto probe
 	s3Config := &Config{
 		// S3 connection parameters
@@ -189,8 +232,8 @@ func probeS3Signature(ctx context.Context, accessKey, secretKey, url string, pee
 		Debug:             globalDebug,
 		ConnReadDeadline:  globalConnReadDeadline,
 		ConnWriteDeadline: globalConnWriteDeadline,
-		UploadLimit:       int64(globalLimitUpload),
-		DownloadLimit:     int64(globalLimitDownload),
+		UploadLimit:       uploadLimit,
+		DownloadLimit:     downloadLimit,
 	}
 	if peerCert != nil {
 		configurePeerCertificate(s3Config, peerCert)
@@ -235,7 +278,7 @@ func probeS3Signature(ctx context.Context, accessKey, secretKey, url string, pee
 // BuildS3Config constructs an S3 Config and does
 // signature auto-probe when needed.
 func BuildS3Config(ctx context.Context, alias, url, accessKey, secretKey, api, path string, peerCert *x509.Certificate) (*Config, *probe.Error) {
-	s3Config := NewS3Config(alias, url, &aliasConfigV10{
+	s3Config := NewS3Config(alias, url, &aliasConfigV11{
 		AccessKey: accessKey,
 		SecretKey: secretKey,
 		URL:       url,
@@ -341,12 +384,14 @@ func mainAliasSet(ctx context.Context, cmd *cli.Command, deprecated bool) error
 	s3Config, err := BuildS3Config(ctx, alias, url, accessKey, secretKey, api, path, peerCert)
 	fatalIf(err.Trace(alias, url, accessKey), "Unable to initialize new alias from the provided credentials.")
 
-	msg := setAlias(alias, aliasConfigV10{
-		URL:       s3Config.HostURL,
-		AccessKey: s3Config.AccessKey,
-		SecretKey: s3Config.SecretKey,
-		API:       s3Config.Signature,
-		Path:      path,
+	msg := setAlias(alias, aliasConfigV11{
+		URL:             s3Config.HostURL,
+		AccessKey:       s3Config.AccessKey,
+		SecretKey:       s3Config.SecretKey,
+		API:             s3Config.Signature,
+		Path:            path,
+		CredentialStore: cmd.String("credential-store"),
+		Profile:         cmd.String("profile"),
 	}) // Add an alias with specified credentials.
 
 	msg.op = "set"
@@ -374,7 +419,7 @@ func configurePeerCertificate(s3Config *Config, peerCert *x509.Certificate) {
 		tr = &http.Transport{
 			Proxy:                 http.ProxyFromEnvironment,
 			DialContext:           newCustomDialContext(&Config{}),
-			DialTLSContext:        newCustomDialTLSContext(&tls.Config{RootCAs: globalRootCAs}),
+			DialTLSContext:        newCustomDialTLSContext(&tls.Config{RootCAs: globalRootCAs}, globalResolvers),
 			MaxIdleConnsPerHost:   256,
 			IdleConnTimeout:       90 * time.Second,
 			TLSHandshakeTimeout:   10 * time.Second,
@@ -385,10 +430,10 @@ func configurePeerCertificate(s3Config *Config, peerCert *x509.Certificate) {
 		if globalRootCAs != nil {
 			globalRootCAs.AddCert(peerCert)
 		}
-		tr.DialTLSContext = newCustomDialTLSContext(&tls.Config{RootCAs: globalRootCAs})
+		tr.DialTLSContext = newCustomDialTLSContext(&tls.Config{RootCAs: globalRootCAs}, globalResolvers)
 	default:
 		tr.TLSClientConfig.RootCAs.AddCert(peerCert)
-		tr.DialTLSContext = newCustomDialTLSContext(tr.TLSClientConfig)
+		tr.DialTLSContext = newCustomDialTLSContext(tr.TLSClientConfig, globalResolvers)
 	}
 	s3Config.Transport = tr
 }
@@ -0,0 +1,400 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// archiveFormat - formats of the archives read by --archive and --extract.
+type archiveFormat int
+
+const (
+	archiveZip archiveFormat = iota + 1
+	archiveTar
+	archiveTarGzip
+	archiveTarZstd
+)
+
+var archiveSuffixes = []struct {
+	suffix string
+	format archiveFormat
+}{
+	{".zip", archiveZip},
+	{".tar", archiveTar},
+	{".tar.gz", archiveTarGzip},
+	{".tgz", archiveTarGzip},
+	{".tar.zst", archiveTarZstd},
+	{".tzst", archiveTarZstd},
+}
+
+// archiveFormatOf returns the format of an archive from its name.
+func archiveFormatOf(name string) (archiveFormat, bool) {
+	name = strings.ToLower(name)
+	for _, s := range archiveSuffixes {
+		if strings.HasSuffix(name, s.suffix) {
+			return s.format, true
+		}
+	}
+	return 0, false
+}
+
+// seekable returns true if the members of the archive can be read
+// without reading the archive from its start.
+func (f archiveFormat) seekable() bool {
+	return f == archiveZip || f == archiveTar
+}
+
+const (
+	// Idle streams kept open by an archiveReaderAt.
+	archiveMaxStreams = 8
+	// Reads ahead of an open stream up to archiveMaxSkip skip the bytes
+	// in between instead of sending a new request.
+	archiveMaxSkip = 256 << 10
+)
+
+// archiveReaderAt reads an object at any offset with ranged GETs. The
+// streams of sequential reads are kept open and reused, so that reading
+// the members of an archive one after the other sends few requests.
+type archiveReaderAt struct {
+	ctx  context.Context
+	clnt Client
+	opts GetOptions
+	size int64
+
+	mu      sync.Mutex
+	streams []*archiveStream
+}
+
+type archiveStream struct {
+	reader io.ReadCloser
+	offset int64
+}
+
+func (r *archiveReaderAt) Size() int64 {
+	return r.size
+}
+
+// take returns an idle stream which can read at offset, if any.
+func (r *archiveReaderAt) take(offset int64) *archiveStream {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, s := range r.streams {
+		if s.offset <= offset && offset-s.offset <= archiveMaxSkip {
+			r.streams = append(r.streams[:i], r.streams[i+1:]...)
+			return s
+		}
+	}
+	return nil
+}
+
+func (r *archiveReaderAt) release(s *archiveStream) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.streams) == archiveMaxStreams {
+		r.streams[0].reader.Close()
+		r.streams = r.streams[1:]
+	}
+	r.streams = append(r.streams, s)
+}
+
+func (r *archiveReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	if offset >= r.size {
+		return 0, io.EOF
+	}
+	s := r.take(offset)
+	if s != nil && s.offset < offset {
+		if _, e := io.CopyN(io.Discard, s.reader, offset-s.offset); e != nil {
+			s.reader.Close()
+			s = nil
+		} else {
+			s.offset = offset
+		}
+	}
+	if s == nil {
+		opts := r.opts
+		opts.RangeStart = offset
+		reader, _, err := r.clnt.Get(r.ctx, opts)
+		if err != nil {
+			return 0, err.ToGoError()
+		}
+		s = &archiveStream{reader: reader, offset: offset}
+	}
+
+	want := min(int64(len(p)), r.size-offset)
+	n, e := io.ReadFull(s.reader, p[:want])
+	s.offset += int64(n)
+	if e != nil {
+		s.reader.Close()
+		if e == io.ErrUnexpectedEOF {
+			e = io.EOF
+		}
+		return n, e
+	}
+	r.release(s)
+	if want < int64(len(p)) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close closes the idle streams.
+func (r *archiveReaderAt) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.streams {
+		s.reader.Close()
+	}
+	r.streams = nil
+	return nil
+}
+
+// archiveEntry - a member of an archive, names of folders end with "/".
+type archiveEntry struct {
+	name    string
+	size    int64
+	modTime time.Time
+	mode    os.FileMode
+
+	// Member of zip archives.
+	zipFile *zip.File
+	// Offset of the content in uncompressed tar archives.
+	offset int64
+}
+
+func (e *archiveEntry) isDir() bool {
+	return strings.HasSuffix(e.name, "/")
+}
+
+// archiveIndex - the members of an archive sorted by name, folders which
+// are not members themselves are added.
+type archiveIndex struct {
+	format  archiveFormat
+	reader  *archiveReaderAt
+	entries []*archiveEntry
+	byName  map[string]*archiveEntry
+}
+
+var errArchiveMemberNotFound = errors.New("member not found in the archive")
+
+// cleanArchiveName returns the name of a member relative to the root of
+// the archive, false if the name points outside of it.
+func cleanArchiveName(name string, isDir bool) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return "", false
+		}
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "", false
+	}
+	if isDir {
+		name += "/"
+	}
+	return name, true
+}
+
+func (idx *archiveIndex) add(e *archiveEntry) {
+	name, ok := cleanArchiveName(e.name, e.isDir())
+	if !ok {
+		return
+	}
+	e.name = name
+	idx.byName[name] = e
+	// Add the parent folders.
+	for dir := path.Dir(strings.TrimSuffix(name, "/")); dir != "."; dir = path.Dir(dir) {
+		if _, ok := idx.byName[dir+"/"]; ok {
+			break
+		}
+		idx.byName[dir+"/"] = &archiveEntry{name: dir + "/", modTime: e.modTime, mode: os.ModeDir | 0o755}
+	}
+}
+
+// readArchiveIndex reads the central directory of a zip archive, or all
+// headers of a tar archive. Headers of uncompressed tar archives are read
+// with ranged requests which skip the content of the members, compressed
+// ones are read entirely.
+func readArchiveIndex(ctx context.Context, reader *archiveReaderAt, format archiveFormat) (*archiveIndex, error) {
+	idx := &archiveIndex{format: format, reader: reader, byName: make(map[string]*archiveEntry)}
+	switch format {
+	case archiveZip:
+		zr, e := zip.NewReader(reader, reader.size)
+		if e != nil {
+			return nil, e
+		}
+		for _, f := range zr.File {
+			mode := f.Mode()
+			if !mode.IsRegular() && !mode.IsDir() {
+				continue
+			}
+			idx.add(&archiveEntry{
+				name:    f.Name,
+				size:    int64(f.UncompressedSize64),
+				modTime: f.Modified,
+				mode:    mode,
+				zipFile: f,
+			})
+		}
+	case archiveTar:
+		sr := io.NewSectionReader(reader, 0, reader.size)
+		tr := tar.NewReader(sr)
+		for {
+			hdr, e := tr.Next()
+			if e == io.EOF {
+				break
+			}
+			if e != nil {
+				return nil, e
+			}
+			offset, _ := sr.Seek(0, io.SeekCurrent)
+			if entry := newTarArchiveEntry(hdr); entry != nil {
+				entry.offset = offset
+				idx.add(entry)
+			}
+		}
+	default:
+		e := scanTarArchive(ctx, reader, format, func(hdr *tar.Header, _ io.Reader) (bool, error) {
+			if entry := newTarArchiveEntry(hdr); entry != nil {
+				idx.add(entry)
+			}
+			return true, nil
+		})
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	idx.entries = make([]*archiveEntry, 0, len(idx.byName))
+	for _, e := range idx.byName {
+		idx.entries = append(idx.entries, e)
+	}
+	sort.Slice(idx.entries, func(i, j int) bool { return idx.entries[i].name < idx.entries[j].name })
+	return idx, nil
+}
+
+// newTarArchiveEntry returns the entry of regular files and folders.
+func newTarArchiveEntry(hdr *tar.Header) *archiveEntry {
+	entry := &archiveEntry{
+		name:    hdr.Name,
+		size:    hdr.Size,
+		modTime: hdr.ModTime,
+		mode:    hdr.FileInfo().Mode(),
+	}
+	switch hdr.Typeflag {
+	case tar.TypeReg, tar.TypeRegA:
+	case tar.TypeDir:
+		entry.size = 0
+		if !strings.HasSuffix(entry.name, "/") {
+			entry.name += "/"
+		}
+	default:
+		return nil
+	}
+	return entry
+}
+
+// scanTarArchive reads a tar archive from its start and calls fn with
+// each member until it returns false.
+func scanTarArchive(ctx context.Context, reader *archiveReaderAt, format archiveFormat, fn func(hdr *tar.Header, r io.Reader) (bool, error)) error {
+	opts := reader.opts
+	body, _, err := reader.clnt.Get(ctx, opts)
+	if err != nil {
+		return err.ToGoError()
+	}
+	defer body.Close()
+
+	var r io.Reader = body
+	switch format {
+	case archiveTarGzip:
+		gr, e := gzip.NewReader(body)
+		if e != nil {
+			return e
+		}
+		defer gr.Close()
+		r = gr
+	case archiveTarZstd:
+		zr, e := zstd.NewReader(body)
+		if e != nil {
+			return e
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, e := tr.Next()
+		if e == io.EOF {
+			return nil
+		}
+		if e != nil {
+			return e
+		}
+		more, e := fn(hdr, tr)
+		if e != nil || !more {
+			return e
+		}
+	}
+}
+
+// open returns the content of a file of the archive.
+func (idx *archiveIndex) open(ctx context.Context, entry *archiveEntry) (io.ReadCloser, error) {
+	switch idx.format {
+	case archiveZip:
+		return entry.zipFile.Open()
+	case archiveTar:
+		return io.NopCloser(io.NewSectionReader(idx.reader, entry.offset, entry.size)), nil
+	}
+
+	// Compressed archives are read until the member.
+	pr, pw := io.Pipe()
+	go func() {
+		found := false
+		e := scanTarArchive(ctx, idx.reader, idx.format, func(hdr *tar.Header, r io.Reader) (bool, error) {
+			if e := newTarArchiveEntry(hdr); e == nil || e.isDir() {
+				return true, nil
+			}
+			if name, ok := cleanArchiveName(hdr.Name, false); !ok || name != entry.name {
+				return true, nil
+			}
+			found = true
+			_, e := io.Copy(pw, r)
+			return false, e
+		})
+		if e == nil && !found {
+			e = errArchiveMemberNotFound
+		}
+		pw.CloseWithError(e)
+	}()
+	return pr, nil
+}
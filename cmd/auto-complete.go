@@ -240,6 +240,7 @@ var completeCmds = map[string]complete.Predictor{
 	"/diff":      complete.PredictOr(s3Completer, fsCompleter),
 	"/find":      complete.PredictOr(s3Completer, fsCompleter),
 	"/mirror":    complete.PredictOr(s3Completer, fsCompleter),
+	"/sync":      complete.PredictOr(s3Completer, fsCompleter),
 	"/pipe":      complete.PredictOr(s3Completer, fsCompleter),
 	"/stat":      complete.PredictOr(s3Completer, fsCompleter),
 	"/watch":     complete.PredictOr(s3Completer, fsCompleter),
@@ -296,6 +297,18 @@ var completeCmds = map[string]complete.Predictor{
 	"/share/list":     nil,
 	"/share/upload":   s3Completer,
 
+	"/serve": complete.PredictOr(s3Completer, fsCompleter),
+	"/mount": complete.PredictOr(s3Completer, fsCompleter),
+
+	"/backup/create":  complete.PredictOr(s3Completer, fsCompleter),
+	"/backup/list":    complete.PredictOr(s3Completer, fsCompleter),
+	"/backup/restore": complete.PredictOr(s3Completer, fsCompleter),
+	"/backup/prune":   complete.PredictOr(s3Completer, fsCompleter),
+	"/backup/check":   complete.PredictOr(s3Completer, fsCompleter),
+
+	"/session/list":  nil,
+	"/session/clear": nil,
+
 	"/ilm/list":    s3Complete{deepLevel: 2},
 	"/ilm/add":     s3Complete{deepLevel: 2},
 	"/ilm/edit":    s3Complete{deepLevel: 2},
@@ -339,13 +352,14 @@ var completeCmds = map[string]complete.Predictor{
 	"/admin/rebalance/status": aliasCompleter,
 	"/admin/rebalance/stop":   aliasCompleter,
 
-	"/admin/trace":     aliasCompleter,
-	"/admin/speedtest": aliasCompleter,
-	"/admin/console":   aliasCompleter,
-	"/admin/update":    aliasCompleter,
-	"/admin/inspect":   s3Completer,
-	"/admin/top/locks": aliasCompleter,
-	"/admin/top/api":   aliasCompleter,
+	"/admin/trace":         aliasCompleter,
+	"/admin/trace/analyze": fsCompleter,
+	"/admin/speedtest":     aliasCompleter,
+	"/admin/console":       aliasCompleter,
+	"/admin/update":        aliasCompleter,
+	"/admin/inspect":       s3Completer,
+	"/admin/top/locks":     aliasCompleter,
+	"/admin/top/api":       aliasCompleter,
 
 	"/admin/scanner/status": aliasCompleter,
 	"/admin/scanner/trace":  aliasCompleter,
@@ -422,6 +436,8 @@ var completeCmds = map[string]complete.Predictor{
 	"/admin/policy/attach":   aliasCompleter,
 	"/admin/policy/detach":   aliasCompleter,
 	"/admin/policy/entities": aliasCompleter,
+	"/admin/policy/simulate": aliasCompleter,
+	"/admin/policy/generate": nil,
 
 	"/admin/user/add":     aliasCompleter,
 	"/admin/user/disable": aliasCompleter,
@@ -490,6 +506,7 @@ var completeCmds = map[string]complete.Predictor{
 	"/admin/cluster/bucket/import": aliasCompleter,
 	"/admin/cluster/iam/export":    aliasCompleter,
 	"/admin/cluster/iam/import":    aliasCompleter,
+	"/admin/cluster/iam/diff":      complete.PredictOr(aliasCompleter, fsCompleter),
 
 	"/alias/set":    nil,
 	"/alias/list":   aliasCompleter,
@@ -497,6 +514,10 @@ var completeCmds = map[string]complete.Predictor{
 	"/alias/import": nil,
 	"/alias/export": aliasCompleter,
 
+	"/alias/profile/set":    nil,
+	"/alias/profile/list":   nil,
+	"/alias/profile/remove": nil,
+
 	"/support/callhome":     aliasCompleter,
 	"/support/register":     aliasCompleter,
 	"/support/diag":         aliasCompleter,
@@ -526,11 +547,28 @@ var completeCmds = map[string]complete.Predictor{
 	"/od":             nil,
 	"/batch/generate": aliasCompleter,
 	"/batch/start":    aliasCompleter,
+	"/batch/run":      aliasCompleter,
 	"/batch/list":     aliasCompleter,
 	"/batch/status":   aliasCompleter,
 	"/batch/describe": aliasCompleter,
 	"/batch/cancel":   aliasCompleter,
 
+	"/bucket/plan":  nil,
+	"/bucket/apply": nil,
+
+	"/index/build":  s3Completer,
+	"/index/info":   s3Completer,
+	"/index/remove": s3Completer,
+
+	"/limit/set":    aliasCompleter,
+	"/limit/list":   aliasCompleter,
+	"/limit/remove": aliasCompleter,
+	"/limit/serve":  nil,
+
+	"/trace-file/summarize": fsCompleter,
+
+	"/inventory/generate": s3Completer,
+
 	"/quota/set":   aliasCompleter,
 	"/quota/info":  aliasCompleter,
 	"/quota/clear": aliasCompleter,
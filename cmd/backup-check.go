@@ -0,0 +1,212 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	json "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/urfave/cli/v3"
+)
+
+var backupCheckFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "read-data",
+		Usage: "download every chunk and verify its content, not only that it exists",
+	},
+	backupWorkersFlag,
+}
+
+var backupCheckCmd = cli.Command{
+	Name:         "check",
+	Usage:        "verify that the chunks of all snapshots are in a repository",
+	Action:       mainBackupCheck,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(append(backupCheckFlags, encFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] REPOSITORY
+
+  Unreferenced chunks are left by interrupted commands, they are removed by
+  'mc backup prune'.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Verify that no chunk of any snapshot is missing.
+     {{.Prompt}} {{.HelpName}} s3/backups/projects
+
+  2. Verify the content of every chunk.
+     {{.Prompt}} {{.HelpName}} --read-data s3/backups/projects
+`,
+}
+
+// backupCheckMessage container for the result of a check.
+type backupCheckMessage struct {
+	Status       string `json:"status"`
+	Snapshots    int    `json:"snapshots"`
+	Files        int64  `json:"files"`
+	Chunks       int    `json:"chunks"`
+	Missing      int64  `json:"missing"`
+	Corrupted    int64  `json:"corrupted"`
+	Unreferenced int    `json:"unreferenced"`
+}
+
+func (m backupCheckMessage) String() string {
+	msg := fmt.Sprintf("Checked %d snapshots (%d files, %d chunks): ", m.Snapshots, m.Files, m.Chunks)
+	if m.Missing == 0 && m.Corrupted == 0 {
+		msg += "no errors found"
+	} else {
+		msg += fmt.Sprintf("%d chunks missing, %d chunks corrupted", m.Missing, m.Corrupted)
+	}
+	if m.Unreferenced > 0 {
+		msg += fmt.Sprintf(", %d unreferenced chunks", m.Unreferenced)
+	}
+	return msg + "."
+}
+
+func (m backupCheckMessage) JSON() string {
+	m.Status = "success"
+	if m.Missing > 0 || m.Corrupted > 0 {
+		m.Status = "error"
+	}
+	buf, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(buf)
+}
+
+// mainBackupCheck is the handle for "mc backup check" command.
+func mainBackupCheck(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+
+	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	repoURL := cmd.Args().Get(0)
+	repoAlias, _ := url2Alias(repoURL)
+	repo, err := openBackupRepo(ctx, repoURL, encKeyDB[repoAlias], false)
+	fatalIf(err.Trace(repoURL), "Unable to open repository `%s`.", repoURL)
+
+	msg, err := backupCheck(ctx, repo, cmd.Bool("read-data"), backupWorkers(cmd))
+	fatalIf(err.Trace(repoURL), "Unable to check `%s`.", repoURL)
+	printMsg(msg)
+	if msg.Missing > 0 || msg.Corrupted > 0 {
+		return exitStatus(globalErrorExitStatus)
+	}
+	return nil
+}
+
+// backupCheck verifies that the repository has the chunks of all its
+// snapshots, and their content if readData is set.
+func backupCheck(ctx context.Context, repo *backupRepo, readData bool, workers int) (backupCheckMessage, *probe.Error) {
+	var msg backupCheckMessage
+
+	stored := map[string]bool{}
+	err := repo.list(ctx, backupChunksPrefix, func(name string, _ *ClientContent) *probe.Error {
+		if hash, ok := backupChunkOf(name); ok {
+			stored[hash] = false
+		}
+		return nil
+	})
+	if err != nil {
+		return msg, err
+	}
+
+	snapshots, err := repo.snapshots(ctx)
+	if err != nil {
+		return msg, err
+	}
+	msg.Snapshots = len(snapshots)
+	for _, s := range snapshots {
+		err = repo.files(ctx, s.ID, func(f *backupFile) *probe.Error {
+			msg.Files++
+			for _, hash := range f.Chunks {
+				checked, ok := stored[hash]
+				if !ok {
+					msg.Missing++
+					errorIf(probe.NewError(ObjectMissing{}).Trace(hash), "Chunk %s of `%s` in snapshot %s is missing.", hash, f.Path, s.ID)
+					continue
+				}
+				if !checked {
+					stored[hash] = true
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return msg, err
+		}
+	}
+
+	var referenced []string
+	for hash, used := range stored {
+		if used {
+			referenced = append(referenced, hash)
+		} else {
+			msg.Unreferenced++
+		}
+	}
+	msg.Chunks = len(referenced)
+	if !readData {
+		return msg, nil
+	}
+
+	var (
+		wg        sync.WaitGroup
+		corrupted atomic.Int64
+	)
+	hashes := make(chan string)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for hash := range hashes {
+				if _, err := repo.getChunk(ctx, hash); err != nil {
+					corrupted.Add(1)
+					errorIf(err.Trace(hash), "Chunk %s is corrupted.", hash)
+				}
+			}
+		}()
+	}
+	for _, hash := range referenced {
+		select {
+		case hashes <- hash:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(hashes)
+	wg.Wait()
+	msg.Corrupted = corrupted.Load()
+	if ctx.Err() != nil {
+		return msg, probe.NewError(ctx.Err())
+	}
+	return msg, nil
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"io"
+	"math/bits"
+)
+
+// backupChunkerParams are the parameters of the content defined chunking
+// of a repository, chunks of repositories with other parameters do not
+// deduplicate.
+type backupChunkerParams struct {
+	Min  int    `json:"min"`
+	Avg  int    `json:"avg"`
+	Max  int    `json:"max"`
+	Seed uint64 `json:"seed"`
+}
+
+var defaultBackupChunkerParams = backupChunkerParams{
+	Min:  512 << 10,
+	Avg:  1 << 20,
+	Max:  8 << 20,
+	Seed: 0x6d632d6261636b75,
+}
+
+func (p backupChunkerParams) valid() bool {
+	return p.Min > 0 && p.Min <= p.Avg && p.Avg <= p.Max && p.Avg&(p.Avg-1) == 0
+}
+
+// backupChunker splits a stream with FastCDC: a gear hash rolls over the
+// data and a chunk ends where its top bits are zero. Until the average
+// size a stricter mask is used and after it a looser one, which keeps
+// the sizes of the chunks close to the average.
+type backupChunker struct {
+	reader io.Reader
+	params backupChunkerParams
+	gear   [256]uint64
+	maskS  uint64
+	maskL  uint64
+
+	buf        []byte
+	start, end int
+	eof        bool
+}
+
+func newBackupChunker(reader io.Reader, params backupChunkerParams) *backupChunker {
+	c := &backupChunker{
+		reader: reader,
+		params: params,
+		buf:    make([]byte, params.Max),
+	}
+	// The gear table is derived from the seed with splitmix64.
+	state := params.Seed
+	for i := range c.gear {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		c.gear[i] = z ^ (z >> 31)
+	}
+	avgBits := bits.Len(uint(params.Avg)) - 1
+	c.maskS = backupChunkerMask(avgBits + 1)
+	c.maskL = backupChunkerMask(avgBits - 1)
+	return c
+}
+
+// backupChunkerMask returns a mask of the n top bits, they depend on the
+// last 64 bytes rolled in.
+func backupChunkerMask(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	return ^uint64(0) << (64 - n)
+}
+
+// cut returns the length of the chunk at the start of data.
+func (c *backupChunker) cut(data []byte) int {
+	n := len(data)
+	if n <= c.params.Min {
+		return n
+	}
+	if n > c.params.Max {
+		n = c.params.Max
+	}
+	normal := min(c.params.Avg, n)
+
+	var fp uint64
+	i := c.params.Min
+	for ; i < normal; i++ {
+		fp = (fp << 1) + c.gear[data[i]]
+		if fp&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = (fp << 1) + c.gear[data[i]]
+		if fp&c.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// Next returns the next chunk, which is only valid until the next call,
+// or io.EOF at the end of the stream.
+func (c *backupChunker) Next() ([]byte, error) {
+	if c.end-c.start < c.params.Max && !c.eof {
+		c.end = copy(c.buf, c.buf[c.start:c.end])
+		c.start = 0
+		n, e := io.ReadFull(c.reader, c.buf[c.end:])
+		c.end += n
+		switch e {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			c.eof = true
+		default:
+			return nil, e
+		}
+	}
+	if c.start == c.end {
+		return nil, io.EOF
+	}
+	n := c.cut(c.buf[c.start:c.end])
+	chunk := c.buf[c.start : c.start+n]
+	c.start += n
+	return chunk, nil
+}
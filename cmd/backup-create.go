@@ -0,0 +1,393 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+	json "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var backupCreateFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "parent",
+		Usage: "snapshot whose unchanged files are not read again (default: latest snapshot of the source)",
+	},
+	backupWorkersFlag,
+}
+
+var backupCreateCmd = cli.Command{
+	Name:         "create",
+	Usage:        "create a deduplicated snapshot of a folder or prefix",
+	Action:       mainBackupCreate,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(append(backupCreateFlags, encFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] SOURCE REPOSITORY
+
+  Files are split in chunks of about 1MiB at boundaries found from their
+  content, so that inserting or removing data only changes the chunks around
+  it. Chunks are stored once in the repository, compressed and named after
+  their SHA-256, and each snapshot lists the chunks of its files.
+
+  The repository is created on the first snapshot. Files with the size and
+  modification time they had in the parent snapshot are not read again.
+  Snapshots are not created while the repository is pruned.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Create a snapshot of a local folder in a repository on another alias.
+     {{.Prompt}} {{.HelpName}} /data/projects/ s3/backups/projects
+
+  2. Create a snapshot of a bucket with 32 parallel transfers.
+     {{.Prompt}} {{.HelpName}} --max-workers 32 myminio/dataset s3/backups/dataset
+`,
+}
+
+// backupCreateMessage container for a created snapshot.
+type backupCreateMessage struct {
+	Status string `json:"status"`
+	Repo   string `json:"repository"`
+	backupSnapshot
+	Failed int64 `json:"failed,omitempty"`
+}
+
+func (m backupCreateMessage) String() string {
+	msg := fmt.Sprintf("Created snapshot %s of `%s` (%d files, %s), added %d chunks (%s) to `%s`.",
+		console.Colorize("SnapshotID", m.ID), m.Source, m.Files, humanize.IBytes(uint64(m.Size)),
+		m.NewChunks, humanize.IBytes(uint64(m.NewSize)), m.Repo)
+	if m.Failed > 0 {
+		msg += fmt.Sprintf(" %d files could not be read.", m.Failed)
+	}
+	return msg
+}
+
+func (m backupCreateMessage) JSON() string {
+	m.Status = "success"
+	buf, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(buf)
+}
+
+func checkBackupCreateSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 2 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+}
+
+// backupCreate adds the files of a source to a repository.
+type backupCreate struct {
+	repo      *backupRepo
+	srcURL    string
+	srcKeys   []prefixSSEPair
+	snapshot  backupSnapshot
+	parent    map[string]*backupFile
+	files     *backupFilesWriter
+	failed    atomic.Int64
+	newChunks atomic.Int64
+	newSize   atomic.Int64
+
+	// Chunks known to be in the repository or being uploaded, an
+	// upload failure aborts the snapshot.
+	known  sync.Map
+	chunks chan backupChunkJob
+}
+
+type backupChunkJob struct {
+	hash string
+	data []byte
+	wg   *sync.WaitGroup
+}
+
+// mainBackupCreate is the handle for "mc backup create" command.
+func mainBackupCreate(ctx context.Context, cmd *cli.Command) error {
+	checkBackupCreateSyntax(ctx, cmd)
+	console.SetColor("SnapshotID", color.New(color.FgYellow, color.Bold))
+
+	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	srcURL, repoURL := cmd.Args().Get(0), cmd.Args().Get(1)
+	srcAlias, _ := url2Alias(srcURL)
+	repoAlias, _ := url2Alias(repoURL)
+
+	repo, err := openBackupRepo(ctx, repoURL, encKeyDB[repoAlias], true)
+	fatalIf(err.Trace(repoURL), "Unable to open repository `%s`.", repoURL)
+	unlock, err := repo.lock(ctx, "create", false)
+	fatalIf(err.Trace(repoURL), "Unable to lock repository `%s`.", repoURL)
+	defer unlock()
+
+	b := newBackupCreate(repo, srcURL, encKeyDB[srcAlias])
+	fatalIf(b.loadParent(ctx, cmd.String("parent")), "Unable to read the parent snapshot.")
+
+	e := b.run(ctx, backupWorkers(cmd))
+	fatalIf(probe.NewError(e), "Unable to create a snapshot of `%s`.", srcURL)
+
+	printMsg(backupCreateMessage{Repo: repoURL, backupSnapshot: b.snapshot, Failed: b.failed.Load()})
+	if b.failed.Load() > 0 {
+		return exitStatus(globalErrorExitStatus)
+	}
+	return nil
+}
+
+func newBackupCreate(repo *backupRepo, srcURL string, srcKeys []prefixSSEPair) *backupCreate {
+	return &backupCreate{
+		repo:    repo,
+		srcURL:  srcURL,
+		srcKeys: srcKeys,
+		parent:  map[string]*backupFile{},
+		snapshot: backupSnapshot{
+			ID:     UTCNow().Format("20060102T150405Z") + "-" + uuid.NewString()[:8],
+			Time:   UTCNow(),
+			Source: srcURL,
+		},
+	}
+}
+
+// loadParent reads the files of the parent snapshot, their chunks are
+// known to be in the repository.
+func (b *backupCreate) loadParent(ctx context.Context, id string) *probe.Error {
+	if id == "" {
+		snapshots, err := b.repo.snapshots(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(snapshots) - 1; i >= 0; i-- {
+			if snapshots[i].Source == b.srcURL {
+				id = snapshots[i].ID
+				break
+			}
+		}
+		if id == "" {
+			return nil
+		}
+	} else {
+		parent, err := b.repo.snapshot(ctx, id)
+		if err != nil {
+			return err
+		}
+		id = parent.ID
+	}
+	b.snapshot.Parent = id
+	return b.repo.files(ctx, id, func(f *backupFile) *probe.Error {
+		b.parent[f.Path] = f
+		for _, hash := range f.Chunks {
+			b.known.Store(hash, struct{}{})
+		}
+		return nil
+	})
+}
+
+func (b *backupCreate) run(ctx context.Context, workers int) error {
+	clnt, err := newClient(b.srcURL)
+	if err != nil {
+		return err.Trace(b.srcURL).ToGoError()
+	}
+	basePath := filepath.ToSlash(clnt.GetURL().Path)
+	if !strings.HasSuffix(basePath, "/") {
+		basePath += "/"
+	}
+
+	b.files, err = b.repo.newFilesWriter(ctx, b.snapshot.ID)
+	if err != nil {
+		return err.ToGoError()
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// Chunks are uploaded by their own workers, so that a large file is
+	// not uploaded one chunk at a time.
+	b.chunks = make(chan backupChunkJob, workers)
+	var uploaders sync.WaitGroup
+	for range workers {
+		uploaders.Add(1)
+		go func() {
+			defer uploaders.Done()
+			for job := range b.chunks {
+				if err := b.uploadChunk(ctx, job.hash, job.data); err != nil {
+					cancel(err.ToGoError())
+				}
+				job.wg.Done()
+			}
+		}()
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		files int64
+		size  int64
+	)
+	jobs := make(chan *ClientContent)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for content := range jobs {
+				rel := strings.TrimPrefix(filepath.ToSlash(content.URL.Path), basePath)
+				f, err := b.backupFile(ctx, rel, content)
+				if err != nil {
+					if ctx.Err() == nil {
+						errorIf(err, "Unable to back up `%s`.", b.sourceURL(rel))
+						b.failed.Add(1)
+					}
+					continue
+				}
+				if err = b.files.add(f); err != nil {
+					cancel(err.ToGoError())
+					continue
+				}
+				mu.Lock()
+				files++
+				size += f.Size
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var listErr *probe.Error
+	for content := range clnt.List(ctx, ListOptions{Recursive: true, ShowDir: DirNone}) {
+		if content.Err != nil {
+			listErr = content.Err.Trace(b.srcURL)
+			break
+		}
+		if content.Type.IsDir() {
+			continue
+		}
+		select {
+		case jobs <- content:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(b.chunks)
+	uploaders.Wait()
+
+	e := context.Cause(ctx)
+	if e == nil && listErr != nil {
+		e = listErr.ToGoError()
+	}
+	if err := b.files.close(e); err != nil {
+		return err.ToGoError()
+	}
+
+	b.snapshot.Files = files
+	b.snapshot.Size = size
+	b.snapshot.NewChunks = b.newChunks.Load()
+	b.snapshot.NewSize = b.newSize.Load()
+	if err := b.repo.putJSON(ctx, backupSnapshotsPrefix+b.snapshot.ID+backupSnapshotSuffix, b.snapshot); err != nil {
+		return err.ToGoError()
+	}
+	return nil
+}
+
+func (b *backupCreate) sourceURL(rel string) string {
+	return strings.TrimSuffix(b.srcURL, "/") + "/" + rel
+}
+
+// backupFile returns the chunks of a file, the file is only read if it
+// changed since the parent snapshot.
+func (b *backupCreate) backupFile(ctx context.Context, rel string, content *ClientContent) (*backupFile, *probe.Error) {
+	if p, ok := b.parent[rel]; ok && p.Size == content.Size && p.ModTime.Equal(content.Time) {
+		return p, nil
+	}
+
+	url := b.sourceURL(rel)
+	clnt, err := newClient(url)
+	if err != nil {
+		return nil, err.Trace(url)
+	}
+	reader, _, err := clnt.Get(ctx, GetOptions{SSE: getSSE(url, b.srcKeys)})
+	if err != nil {
+		return nil, err.Trace(url)
+	}
+	defer reader.Close()
+
+	f := &backupFile{Path: rel, ModTime: content.Time}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	chunker := newBackupChunker(reader, b.repo.config.Chunker)
+	for {
+		chunk, e := chunker.Next()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, probe.NewError(e).Trace(url)
+		}
+		hash := backupChunkHash(chunk)
+		f.Chunks = append(f.Chunks, hash)
+		f.Size += int64(len(chunk))
+		if _, loaded := b.known.LoadOrStore(hash, struct{}{}); loaded {
+			continue
+		}
+		wg.Add(1)
+		select {
+		case b.chunks <- backupChunkJob{hash: hash, data: append([]byte(nil), chunk...), wg: &wg}:
+		case <-ctx.Done():
+			wg.Done()
+			return nil, probe.NewError(ctx.Err())
+		}
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, probe.NewError(ctx.Err())
+	}
+	return f, nil
+}
+
+// uploadChunk writes a chunk unless the repository already has it.
+func (b *backupCreate) uploadChunk(ctx context.Context, hash string, data []byte) *probe.Error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	ok, err := b.repo.hasChunk(ctx, hash)
+	if err != nil || ok {
+		return err
+	}
+	n, err := b.repo.putChunk(ctx, hash, data)
+	if err != nil {
+		return err
+	}
+	b.newChunks.Add(1)
+	b.newSize.Add(n)
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	json "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var backupListCmd = cli.Command{
+	Name:         "list",
+	Aliases:      []string{"ls"},
+	Usage:        "list the snapshots of a repository",
+	Action:       mainBackupList,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(encFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] REPOSITORY
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. List the snapshots of a repository, oldest first.
+     {{.Prompt}} {{.HelpName}} s3/backups/projects
+`,
+}
+
+// backupListMessage container for a snapshot.
+type backupListMessage struct {
+	Status string `json:"status"`
+	backupSnapshot
+}
+
+func (m backupListMessage) String() string {
+	return fmt.Sprintf("%s %s %6d files %10s  %s",
+		console.Colorize("Time", "["+m.Time.Local().Format(printDate)+"]"),
+		console.Colorize("SnapshotID", m.ID),
+		m.Files, humanize.IBytes(uint64(m.Size)), m.Source)
+}
+
+func (m backupListMessage) JSON() string {
+	m.Status = "success"
+	buf, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(buf)
+}
+
+// mainBackupList is the handle for "mc backup list" command.
+func mainBackupList(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+	console.SetColor("Time", color.New(color.FgGreen))
+	console.SetColor("SnapshotID", color.New(color.FgYellow, color.Bold))
+
+	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	repoURL := cmd.Args().Get(0)
+	repoAlias, _ := url2Alias(repoURL)
+	repo, err := openBackupRepo(ctx, repoURL, encKeyDB[repoAlias], false)
+	fatalIf(err.Trace(repoURL), "Unable to open repository `%s`.", repoURL)
+
+	snapshots, err := repo.snapshots(ctx)
+	fatalIf(err.Trace(repoURL), "Unable to list the snapshots of `%s`.", repoURL)
+	for _, s := range snapshots {
+		printMsg(backupListMessage{backupSnapshot: s})
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+)
+
+var backupSubcommands = []*cli.Command{
+	&backupCreateCmd,
+	&backupListCmd,
+	&backupRestoreCmd,
+	&backupPruneCmd,
+	&backupCheckCmd,
+}
+
+var backupCmd = cli.Command{
+	Name:            "backup",
+	Usage:           "manage deduplicated snapshots of folders and prefixes",
+	Action:          mainBackup,
+	Before:          setGlobalsFromContext,
+	Flags:           globalFlags,
+	Commands:        backupSubcommands,
+	HideHelpCommand: true,
+}
+
+// mainBackup is the handle for "mc backup" command.
+func mainBackup(ctx context.Context, cmd *cli.Command) error {
+	var subCmds []cli.Command
+	for _, c := range backupSubcommands {
+		subCmds = append(subCmds, *c)
+	}
+	commandNotFound(ctx, cmd, subCmds)
+	return nil
+	// Sub-commands like "create", "restore" have their own main.
+}
+
+// backupWorkers returns the number of parallel transfers of a backup command.
+func backupWorkers(cmd *cli.Command) int {
+	if workers := cmd.Int("max-workers"); workers > 0 {
+		return workers
+	}
+	return defaultWorkerFactor
+}
+
+var backupWorkersFlag = &cli.IntFlag{
+	Name:  "max-workers",
+	Usage: "maximum number of parallel transfers (default: autodetect)",
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	json "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var backupPruneFlags = []cli.Flag{
+	&cli.IntFlag{
+		Name:  "keep-last",
+		Usage: "keep the N latest snapshots",
+	},
+	&cli.IntFlag{
+		Name:  "keep-daily",
+		Usage: "keep the latest snapshot of each of the last N days with snapshots",
+	},
+	&cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "print what would be removed",
+	},
+}
+
+var backupPruneCmd = cli.Command{
+	Name:         "prune",
+	Usage:        "remove old snapshots and the chunks only they use",
+	Action:       mainBackupPrune,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(append(backupPruneFlags, encFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] REPOSITORY
+
+  Snapshots kept by any of the --keep flags are kept, days are in local time.
+  Prune locks the repository, it does not run while snapshots are being
+  created and snapshots are not created while it runs. The locks of commands
+  which were interrupted are removed with 'mc rm --recursive --force REPOSITORY/locks/'.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Keep a snapshot for each of the last 7 days.
+     {{.Prompt}} {{.HelpName}} --keep-daily 7 s3/backups/projects
+
+  2. Print what keeping the 3 latest snapshots and one per day for 30 days would remove.
+     {{.Prompt}} {{.HelpName}} --keep-last 3 --keep-daily 30 --dry-run s3/backups/projects
+`,
+}
+
+// backupPruneMessage container for a removed snapshot, or the summary of
+// the removed chunks.
+type backupPruneMessage struct {
+	Status   string          `json:"status"`
+	DryRun   bool            `json:"dryRun,omitempty"`
+	Snapshot *backupSnapshot `json:"snapshot,omitempty"`
+	Kept     int             `json:"kept,omitempty"`
+	Chunks   int64           `json:"chunks,omitempty"`
+	Size     int64           `json:"size,omitempty"`
+}
+
+func (m backupPruneMessage) String() string {
+	verb := "Removed"
+	if m.DryRun {
+		verb = "Would remove"
+	}
+	if m.Snapshot != nil {
+		return fmt.Sprintf("%s snapshot %s %s", verb, console.Colorize("SnapshotID", m.Snapshot.ID),
+			console.Colorize("Time", "["+m.Snapshot.Time.Local().Format(printDate)+"]"))
+	}
+	return fmt.Sprintf("%s %d unreferenced chunks (%s), %d snapshots kept.", verb, m.Chunks, humanize.IBytes(uint64(m.Size)), m.Kept)
+}
+
+func (m backupPruneMessage) JSON() string {
+	m.Status = "success"
+	buf, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(buf)
+}
+
+// backupSnapshotsToKeep returns the IDs of the snapshots kept by a
+// retention policy, snapshots are sorted oldest first.
+func backupSnapshotsToKeep(snapshots []backupSnapshot, keepLast, keepDaily int) map[string]bool {
+	keep := map[string]bool{}
+	days := map[string]bool{}
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		s := snapshots[i]
+		if len(snapshots)-1-i < keepLast {
+			keep[s.ID] = true
+		}
+		day := s.Time.Local().Format("2006-01-02")
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep[s.ID] = true
+		}
+	}
+	return keep
+}
+
+// mainBackupPrune is the handle for "mc backup prune" command.
+func mainBackupPrune(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+	keepLast, keepDaily := cmd.Int("keep-last"), cmd.Int("keep-daily")
+	if keepLast < 0 || keepDaily < 0 || keepLast+keepDaily == 0 {
+		fatalIf(errInvalidArgument(), "--keep-last or --keep-daily must be larger than zero.")
+	}
+	dryRun := cmd.Bool("dry-run")
+	console.SetColor("Time", color.New(color.FgGreen))
+	console.SetColor("SnapshotID", color.New(color.FgYellow, color.Bold))
+
+	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	repoURL := cmd.Args().Get(0)
+	repoAlias, _ := url2Alias(repoURL)
+	repo, err := openBackupRepo(ctx, repoURL, encKeyDB[repoAlias], false)
+	fatalIf(err.Trace(repoURL), "Unable to open repository `%s`.", repoURL)
+	fatalIf(backupPrune(ctx, repo, keepLast, keepDaily, dryRun), "Unable to prune `%s`.", repoURL)
+	return nil
+}
+
+// backupPrune removes the snapshots not kept by the retention policy and
+// the chunks used by none of the kept snapshots. The repository is locked
+// meanwhile, snapshots created since the start would use chunks which are
+// not referenced yet.
+func backupPrune(ctx context.Context, repo *backupRepo, keepLast, keepDaily int, dryRun bool) *probe.Error {
+	unlock, err := repo.lock(ctx, "prune", true)
+	if err != nil {
+		return err.Trace(repo.url)
+	}
+	defer unlock()
+
+	snapshots, err := repo.snapshots(ctx)
+	if err != nil {
+		return err.Trace(repo.url)
+	}
+	keep := backupSnapshotsToKeep(snapshots, keepLast, keepDaily)
+
+	// Snapshots are removed first, a snapshot without its chunks is
+	// never listed.
+	for i, s := range snapshots {
+		if keep[s.ID] {
+			continue
+		}
+		printMsg(backupPruneMessage{DryRun: dryRun, Snapshot: &snapshots[i]})
+		if dryRun {
+			continue
+		}
+		if err = repo.removeNames(ctx, backupSnapshotsPrefix+s.ID+backupSnapshotSuffix); err != nil {
+			return err.Trace(repo.url)
+		}
+		err = repo.removeNames(ctx, backupSnapshotsPrefix+s.ID+backupFilesSuffix)
+		errorIf(err.Trace(repo.url), "Unable to remove the files of snapshot `%s`.", s.ID)
+	}
+
+	used := map[string]struct{}{}
+	for _, s := range snapshots {
+		if !keep[s.ID] {
+			continue
+		}
+		err = repo.files(ctx, s.ID, func(f *backupFile) *probe.Error {
+			for _, hash := range f.Chunks {
+				used[hash] = struct{}{}
+			}
+			return nil
+		})
+		if err != nil {
+			return err.Trace(repo.url)
+		}
+	}
+
+	var (
+		chunks, size int64
+		names        = make(chan string)
+		removed      = make(chan *probe.Error, 1)
+	)
+	if dryRun {
+		go func() {
+			for range names {
+			}
+			removed <- nil
+		}()
+	} else {
+		go func() { removed <- repo.remove(ctx, names) }()
+	}
+	err = repo.list(ctx, backupChunksPrefix, func(name string, content *ClientContent) *probe.Error {
+		hash, ok := backupChunkOf(name)
+		if !ok {
+			return nil
+		}
+		if _, ok = used[hash]; ok {
+			return nil
+		}
+		chunks++
+		size += content.Size
+		names <- name
+		return nil
+	})
+	close(names)
+	rerr := <-removed
+	if err != nil {
+		return err.Trace(repo.url)
+	}
+	if rerr != nil {
+		return rerr.Trace(repo.url)
+	}
+
+	printMsg(backupPruneMessage{DryRun: dryRun, Kept: len(keep), Chunks: chunks, Size: size})
+	return nil
+}
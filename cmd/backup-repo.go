@@ -0,0 +1,519 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/openstor-go/v7/pkg/encrypt"
+)
+
+// A backup repository is a prefix or a folder holding:
+//
+//	config.json                  chunking parameters of the repository
+//	chunks/XX/HASH               zstd compressed chunks, named after the
+//	                             SHA-256 of their content
+//	snapshots/ID.files.zst       JSON lines of the files of a snapshot
+//	snapshots/ID.json            summary of a snapshot, written last
+//	locks/ID.json                commands adding chunks to the repository,
+//	                             or removing them
+const (
+	backupRepoVersion     = 1
+	backupConfigName      = "config.json"
+	backupChunksPrefix    = "chunks/"
+	backupSnapshotsPrefix = "snapshots/"
+	backupLocksPrefix     = "locks/"
+
+	backupSnapshotSuffix = ".json"
+	backupFilesSuffix    = ".files.zst"
+)
+
+var errBackupNoSnapshot = errors.New("no such snapshot")
+
+// backupConfig is the configuration of a repository.
+type backupConfig struct {
+	Version     int                 `json:"version"`
+	ID          string              `json:"id"`
+	Created     time.Time           `json:"created"`
+	Chunker     backupChunkerParams `json:"chunker"`
+	Compression string              `json:"compression"`
+}
+
+// backupSnapshot is the summary of a snapshot.
+type backupSnapshot struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	Parent    string    `json:"parent,omitempty"`
+	Files     int64     `json:"files"`
+	Size      int64     `json:"size"`
+	NewChunks int64     `json:"newChunks"`
+	NewSize   int64     `json:"newSize"`
+}
+
+// backupFile is a file of a snapshot, its content is the concatenation
+// of its chunks.
+type backupFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Chunks  []string  `json:"chunks"`
+}
+
+// backupLock is written by the commands adding chunks, which run
+// together, and by prune, which runs alone.
+type backupLock struct {
+	ID        string    `json:"id"`
+	Command   string    `json:"command"`
+	Exclusive bool      `json:"exclusive,omitempty"`
+	Time      time.Time `json:"time"`
+	Hostname  string    `json:"hostname"`
+}
+
+var (
+	backupEncoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, _ := zstd.NewWriter(nil)
+		return enc
+	})
+	backupDecoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, _ := zstd.NewReader(nil)
+		return dec
+	})
+)
+
+// backupRepo is a repository opened through the Client interface.
+type backupRepo struct {
+	url    string
+	config backupConfig
+	keys   []prefixSSEPair
+}
+
+func (r *backupRepo) objectURL(name string) string {
+	return strings.TrimSuffix(r.url, "/") + "/" + name
+}
+
+func (r *backupRepo) client(name string) (Client, encrypt.ServerSide, *probe.Error) {
+	url := r.objectURL(name)
+	clnt, err := newClient(url)
+	if err != nil {
+		return nil, nil, err.Trace(url)
+	}
+	return clnt, getSSE(url, r.keys), nil
+}
+
+func (r *backupRepo) get(ctx context.Context, name string) ([]byte, *probe.Error) {
+	clnt, sse, err := r.client(name)
+	if err != nil {
+		return nil, err
+	}
+	reader, _, err := clnt.Get(ctx, GetOptions{SSE: sse})
+	if err != nil {
+		return nil, err.Trace(r.objectURL(name))
+	}
+	defer reader.Close()
+	data, e := io.ReadAll(reader)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(r.objectURL(name))
+	}
+	return data, nil
+}
+
+func (r *backupRepo) put(ctx context.Context, name string, reader io.Reader, size int64, contentType string) *probe.Error {
+	clnt, sse, err := r.client(name)
+	if err != nil {
+		return err
+	}
+	_, err = clnt.Put(ctx, reader, size, nil, PutOptions{
+		metadata: map[string]string{"Content-Type": contentType},
+		sse:      sse,
+	})
+	return err.Trace(r.objectURL(name))
+}
+
+func (r *backupRepo) putJSON(ctx context.Context, name string, v any) *probe.Error {
+	buf, e := json.MarshalIndent(v, "", " ")
+	if e != nil {
+		return probe.NewError(e)
+	}
+	return r.put(ctx, name, bytes.NewReader(buf), int64(len(buf)), "application/json")
+}
+
+func (r *backupRepo) getJSON(ctx context.Context, name string, v any) *probe.Error {
+	data, err := r.get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if e := json.Unmarshal(data, v); e != nil {
+		return probe.NewError(e).Trace(r.objectURL(name))
+	}
+	return nil
+}
+
+// remove removes the objects sent on namesCh.
+func (r *backupRepo) remove(ctx context.Context, namesCh <-chan string) *probe.Error {
+	clnt, err := newClient(r.url)
+	if err != nil {
+		return err.Trace(r.url)
+	}
+	var urlErr, rerr *probe.Error
+	contentCh := make(chan *ClientContent)
+	go func() {
+		defer close(contentCh)
+		for name := range namesCh {
+			// Objects are removed by their resolved URL.
+			object, err := newClient(r.objectURL(name))
+			if err != nil {
+				urlErr = err.Trace(r.objectURL(name))
+				continue
+			}
+			select {
+			case contentCh <- &ClientContent{URL: object.GetURL()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for result := range clnt.Remove(ctx, false, false, false, false, contentCh) {
+		if result.Err != nil && rerr == nil {
+			rerr = result.Err.Trace(r.url)
+		}
+	}
+	// contentCh is closed once the results are read.
+	if rerr == nil {
+		rerr = urlErr
+	}
+	return rerr
+}
+
+func (r *backupRepo) removeNames(ctx context.Context, names ...string) *probe.Error {
+	namesCh := make(chan string, len(names))
+	for _, name := range names {
+		namesCh <- name
+	}
+	close(namesCh)
+	return r.remove(ctx, namesCh)
+}
+
+// list sends the names of the objects under prefix.
+func (r *backupRepo) list(ctx context.Context, prefix string, fn func(name string, content *ClientContent) *probe.Error) *probe.Error {
+	clnt, err := newClient(r.objectURL(prefix))
+	if err != nil {
+		return err.Trace(r.objectURL(prefix))
+	}
+	basePath := filepath.ToSlash(clnt.GetURL().Path)
+	for content := range clnt.List(ctx, ListOptions{Recursive: true, ShowDir: DirNone}) {
+		if content.Err != nil {
+			switch content.Err.ToGoError().(type) {
+			case PathNotFound, ObjectMissing:
+				// Nothing written under prefix yet.
+				continue
+			}
+			return content.Err.Trace(r.objectURL(prefix))
+		}
+		if content.Type.IsDir() {
+			continue
+		}
+		rel := strings.TrimPrefix(filepath.ToSlash(content.URL.Path), basePath)
+		if err := fn(prefix+rel, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openBackupRepo reads the configuration of the repository at url, it is
+// created first if init is true and url has none.
+func openBackupRepo(ctx context.Context, url string, keys []prefixSSEPair, init bool) (*backupRepo, *probe.Error) {
+	r := &backupRepo{url: url, keys: keys}
+	err := r.getJSON(ctx, backupConfigName, &r.config)
+	if err != nil {
+		switch err.ToGoError().(type) {
+		case ObjectMissing, PathNotFound:
+			if !init {
+				return nil, probe.NewError(fmt.Errorf("`%s` is not a backup repository", url))
+			}
+			r.config = backupConfig{
+				Version:     backupRepoVersion,
+				ID:          uuid.NewString(),
+				Created:     UTCNow(),
+				Chunker:     defaultBackupChunkerParams,
+				Compression: "zstd",
+			}
+			return r, r.putJSON(ctx, backupConfigName, r.config)
+		}
+		return nil, err
+	}
+	if r.config.Version != backupRepoVersion {
+		return nil, probe.NewError(fmt.Errorf("unsupported repository version %d", r.config.Version)).Trace(url)
+	}
+	if !r.config.Chunker.valid() || r.config.Compression != "zstd" {
+		return nil, probe.NewError(errors.New("invalid repository configuration")).Trace(url)
+	}
+	return r, nil
+}
+
+func backupChunkHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func backupChunkName(hash string) string {
+	return backupChunksPrefix + hash[:2] + "/" + hash
+}
+
+// backupChunkOf returns the hash of a chunk from its object name.
+func backupChunkOf(name string) (string, bool) {
+	hash, ok := strings.CutPrefix(name, backupChunksPrefix)
+	if !ok || len(hash) != 2+1+sha256.Size*2 || hash[2] != '/' {
+		return "", false
+	}
+	return hash[3:], strings.HasPrefix(hash[3:], hash[:2])
+}
+
+// hasChunk returns true if the repository has the chunk.
+func (r *backupRepo) hasChunk(ctx context.Context, hash string) (bool, *probe.Error) {
+	clnt, sse, err := r.client(backupChunkName(hash))
+	if err != nil {
+		return false, err
+	}
+	if _, err = clnt.Stat(ctx, StatOptions{sse: sse, headOnly: true}); err != nil {
+		switch err.ToGoError().(type) {
+		case ObjectMissing, PathNotFound:
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// putChunk compresses and writes a chunk, it returns the stored size.
+func (r *backupRepo) putChunk(ctx context.Context, hash string, data []byte) (int64, *probe.Error) {
+	buf := backupEncoder().EncodeAll(data, make([]byte, 0, len(data)/2))
+	return int64(len(buf)), r.put(ctx, backupChunkName(hash), bytes.NewReader(buf), int64(len(buf)), "application/zstd")
+}
+
+// getChunk reads a chunk and verifies its content.
+func (r *backupRepo) getChunk(ctx context.Context, hash string) ([]byte, *probe.Error) {
+	buf, err := r.get(ctx, backupChunkName(hash))
+	if err != nil {
+		return nil, err
+	}
+	data, e := backupDecoder().DecodeAll(buf, nil)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(r.objectURL(backupChunkName(hash)))
+	}
+	if backupChunkHash(data) != hash {
+		return nil, probe.NewError(errors.New("chunk content does not match its hash")).Trace(r.objectURL(backupChunkName(hash)))
+	}
+	return data, nil
+}
+
+// snapshots returns the snapshots of the repository, oldest first.
+func (r *backupRepo) snapshots(ctx context.Context) ([]backupSnapshot, *probe.Error) {
+	var snapshots []backupSnapshot
+	err := r.list(ctx, backupSnapshotsPrefix, func(name string, _ *ClientContent) *probe.Error {
+		if !strings.HasSuffix(name, backupSnapshotSuffix) {
+			return nil
+		}
+		var s backupSnapshot
+		if err := r.getJSON(ctx, name, &s); err != nil {
+			return err
+		}
+		snapshots = append(snapshots, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if !snapshots[i].Time.Equal(snapshots[j].Time) {
+			return snapshots[i].Time.Before(snapshots[j].Time)
+		}
+		return snapshots[i].ID < snapshots[j].ID
+	})
+	return snapshots, nil
+}
+
+// snapshot returns the snapshot with id, "latest" is the newest one.
+func (r *backupRepo) snapshot(ctx context.Context, id string) (*backupSnapshot, *probe.Error) {
+	snapshots, err := r.snapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if id == "latest" && len(snapshots) > 0 {
+		return &snapshots[len(snapshots)-1], nil
+	}
+	for i := range snapshots {
+		if snapshots[i].ID == id {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, probe.NewError(errBackupNoSnapshot).Trace(id)
+}
+
+// files calls fn for each file of a snapshot.
+func (r *backupRepo) files(ctx context.Context, id string, fn func(*backupFile) *probe.Error) *probe.Error {
+	name := backupSnapshotsPrefix + id + backupFilesSuffix
+	clnt, sse, err := r.client(name)
+	if err != nil {
+		return err
+	}
+	reader, _, err := clnt.Get(ctx, GetOptions{SSE: sse})
+	if err != nil {
+		return err.Trace(r.objectURL(name))
+	}
+	defer reader.Close()
+	dec, e := zstd.NewReader(reader)
+	if e != nil {
+		return probe.NewError(e).Trace(r.objectURL(name))
+	}
+	defer dec.Close()
+
+	jd := json.NewDecoder(bufio.NewReader(dec))
+	for {
+		var f backupFile
+		if e = jd.Decode(&f); e == io.EOF {
+			return nil
+		} else if e != nil {
+			return probe.NewError(e).Trace(r.objectURL(name))
+		}
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+}
+
+// backupFilesWriter streams the files of a snapshot to the repository.
+type backupFilesWriter struct {
+	mu   sync.Mutex
+	pipe *io.PipeWriter
+	enc  *zstd.Encoder
+	json *json.Encoder
+	done chan *probe.Error
+}
+
+func (r *backupRepo) newFilesWriter(ctx context.Context, id string) (*backupFilesWriter, *probe.Error) {
+	name := backupSnapshotsPrefix + id + backupFilesSuffix
+	reader, writer := io.Pipe()
+	enc, e := zstd.NewWriter(writer)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	w := &backupFilesWriter{pipe: writer, enc: enc, json: json.NewEncoder(enc), done: make(chan *probe.Error, 1)}
+	go func() {
+		err := r.put(ctx, name, reader, -1, "application/zstd")
+		if err != nil {
+			reader.CloseWithError(err.ToGoError())
+		} else {
+			reader.Close()
+		}
+		w.done <- err
+	}()
+	return w, nil
+}
+
+func (w *backupFilesWriter) add(f *backupFile) *probe.Error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return probe.NewError(w.json.Encode(f))
+}
+
+// close finishes the upload, it is aborted if abort is not nil.
+func (w *backupFilesWriter) close(abort error) *probe.Error {
+	if abort == nil {
+		abort = w.enc.Close()
+	}
+	if abort != nil {
+		w.pipe.CloseWithError(abort)
+		<-w.done
+		return probe.NewError(abort)
+	}
+	w.pipe.Close()
+	return <-w.done
+}
+
+// lock registers a command, the returned function removes the lock. An
+// exclusive lock conflicts with any other lock, the others only with
+// exclusive locks. The lock is written before the other locks are read,
+// of two commands locking at the same time at least one fails.
+func (r *backupRepo) lock(ctx context.Context, command string, exclusive bool) (func(), *probe.Error) {
+	hostname, _ := os.Hostname()
+	l := backupLock{ID: uuid.NewString(), Command: command, Exclusive: exclusive, Time: UTCNow(), Hostname: hostname}
+	name := backupLocksPrefix + l.ID + ".json"
+	if err := r.putJSON(ctx, name, l); err != nil {
+		return nil, err
+	}
+	unlock := func() {
+		// Removed even if ctx is canceled, a stale lock blocks the
+		// other commands.
+		errorIf(r.removeNames(context.Background(), name), "Unable to remove lock `%s`.", r.objectURL(name))
+	}
+
+	locks, err := r.locks(ctx)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	var held []string
+	for _, other := range locks {
+		if other.ID != l.ID && (exclusive || other.Exclusive) {
+			held = append(held, fmt.Sprintf("%s on %s since %s", other.Command, other.Hostname, other.Time.Local().Format(printDate)))
+		}
+	}
+	if len(held) > 0 {
+		unlock()
+		return nil, probe.NewError(fmt.Errorf("repository is locked by %s", strings.Join(held, ", ")))
+	}
+	return unlock, nil
+}
+
+// locks returns the locks of the repository.
+func (r *backupRepo) locks(ctx context.Context) ([]backupLock, *probe.Error) {
+	var locks []backupLock
+	err := r.list(ctx, backupLocksPrefix, func(name string, _ *ClientContent) *probe.Error {
+		var l backupLock
+		if err := r.getJSON(ctx, name, &l); err != nil {
+			switch err.ToGoError().(type) {
+			case ObjectMissing, PathNotFound:
+				// Removed since listed.
+				return nil
+			}
+			return err
+		}
+		locks = append(locks, l)
+		return nil
+	})
+	return locks, err
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/urfave/cli/v3"
+)
+
+var backupRestoreFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "snapshot",
+		Value: "latest",
+		Usage: "ID of the snapshot to restore",
+	},
+	&cli.StringFlag{
+		Name:  "path",
+		Usage: "only restore the files under this path of the snapshot",
+	},
+	backupWorkersFlag,
+}
+
+var backupRestoreCmd = cli.Command{
+	Name:         "restore",
+	Usage:        "restore the files of a snapshot",
+	Action:       mainBackupRestore,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(append(backupRestoreFlags, encFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] REPOSITORY TARGET
+
+  The content of each chunk is verified against its SHA-256 before it is
+  written. Empty folders are not part of snapshots.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Restore the latest snapshot into a local folder.
+     {{.Prompt}} {{.HelpName}} s3/backups/projects /data/restore/
+
+  2. Restore the folder "site/" of a snapshot into a bucket.
+     {{.Prompt}} {{.HelpName}} --snapshot 20220101T000000Z-1a2b3c4d --path site/ s3/backups/projects myminio/www
+`,
+}
+
+// mainBackupRestore is the handle for "mc backup restore" command.
+func mainBackupRestore(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 2 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+
+	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	repoURL, tgtURL := cmd.Args().Get(0), cmd.Args().Get(1)
+	repoAlias, _ := url2Alias(repoURL)
+	tgtAlias, _ := url2Alias(tgtURL)
+	repo, err := openBackupRepo(ctx, repoURL, encKeyDB[repoAlias], false)
+	fatalIf(err.Trace(repoURL), "Unable to open repository `%s`.", repoURL)
+
+	snapshot, err := repo.snapshot(ctx, cmd.String("snapshot"))
+	fatalIf(err.Trace(repoURL), "Unable to find snapshot `%s`.", cmd.String("snapshot"))
+
+	prefix := strings.TrimPrefix(cmd.String("path"), "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if backupRestore(ctx, repo, snapshot.ID, prefix, tgtURL, encKeyDB[tgtAlias], backupWorkers(cmd)) {
+		return exitStatus(globalErrorExitStatus)
+	}
+	return nil
+}
+
+// backupRestore restores the files of a snapshot under prefix into
+// tgtURL, it returns true if any file failed.
+func backupRestore(ctx context.Context, repo *backupRepo, id, prefix, tgtURL string, tgtKeys []prefixSSEPair, workers int) bool {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+	jobs := make(chan *backupFile)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				target := strings.TrimSuffix(tgtURL, "/") + "/" + strings.TrimPrefix(f.Path, prefix)
+				if err := restoreBackupFile(ctx, repo, f, target, tgtKeys); err != nil {
+					errorIf(err.Trace(target), "Unable to restore `%s`.", f.Path)
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+					continue
+				}
+				printMsg(copyMessage{
+					Source: repo.url + "@" + id + "/" + f.Path,
+					Target: target,
+					Size:   f.Size,
+				})
+			}
+		}()
+	}
+
+	err := repo.files(ctx, id, func(f *backupFile) *probe.Error {
+		if _, ok := cleanArchiveName(f.Path, false); !ok {
+			errorIf(errInvalidArgument().Trace(f.Path), "Skipping invalid path `%s`.", f.Path)
+			return nil
+		}
+		if !strings.HasPrefix(f.Path, prefix) {
+			return nil
+		}
+		select {
+		case jobs <- f:
+			return nil
+		case <-ctx.Done():
+			return probe.NewError(ctx.Err())
+		}
+	})
+	close(jobs)
+	wg.Wait()
+	fatalIf(err.Trace(repo.url), "Unable to read snapshot `%s`.", id)
+	return failed || ctx.Err() != nil
+}
+
+// restoreBackupFile writes the chunks of a file to target.
+func restoreBackupFile(ctx context.Context, repo *backupRepo, f *backupFile, target string, tgtKeys []prefixSSEPair) *probe.Error {
+	clnt, err := newClient(target)
+	if err != nil {
+		return err.Trace(target)
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		for _, hash := range f.Chunks {
+			data, err := repo.getChunk(ctx, hash)
+			if err != nil {
+				writer.CloseWithError(err.ToGoError())
+				return
+			}
+			if _, e := writer.Write(data); e != nil {
+				return
+			}
+		}
+		writer.Close()
+	}()
+	defer reader.Close()
+
+	_, err = clnt.Put(ctx, reader, f.Size, nil, PutOptions{
+		metadata: map[string]string{"Content-Type": guessURLContentType(f.Path)},
+		sse:      getSSE(target, tgtKeys),
+	})
+	return err.Trace(target)
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testBackupChunkerParams = backupChunkerParams{Min: 256, Avg: 1024, Max: 4096, Seed: 1}
+
+func backupChunks(t *testing.T, data []byte, params backupChunkerParams) []string {
+	t.Helper()
+	var hashes []string
+	c := newBackupChunker(bytes.NewReader(data), params)
+	for {
+		chunk, e := c.Next()
+		if e == io.EOF {
+			return hashes
+		}
+		if e != nil {
+			t.Fatal(e)
+		}
+		if len(chunk) > params.Max {
+			t.Fatalf("chunk of %d bytes is larger than the maximum", len(chunk))
+		}
+		hashes = append(hashes, backupChunkHash(chunk))
+	}
+}
+
+func TestBackupChunker(t *testing.T) {
+	data := make([]byte, 256<<10)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	chunks := backupChunks(t, data, testBackupChunkerParams)
+	if n := len(chunks); n < 64 || n > 1024 {
+		t.Fatalf("unexpected number of chunks %d for an average of %d bytes", n, testBackupChunkerParams.Avg)
+	}
+
+	// Inserting data only changes the chunks around it.
+	edited := append(append(append([]byte{}, data[:100<<10]...), []byte("inserted")...), data[100<<10:]...)
+	seen := map[string]bool{}
+	for _, hash := range chunks {
+		seen[hash] = true
+	}
+	changed := 0
+	for _, hash := range backupChunks(t, edited, testBackupChunkerParams) {
+		if !seen[hash] {
+			changed++
+		}
+	}
+	if changed == 0 || changed > 3 {
+		t.Fatalf("expected 1 to 3 changed chunks, got %d", changed)
+	}
+}
+
+func TestBackupSnapshotsToKeep(t *testing.T) {
+	day := time.Date(2022, 1, 10, 12, 0, 0, 0, time.Local)
+	var snapshots []backupSnapshot
+	for i, d := range []time.Duration{-72 * time.Hour, -48 * time.Hour, -47 * time.Hour, -24 * time.Hour, 0, time.Hour} {
+		snapshots = append(snapshots, backupSnapshot{ID: string(rune('a' + i)), Time: day.Add(d)})
+	}
+	testCases := []struct {
+		keepLast, keepDaily int
+		expected            string
+	}{
+		{1, 0, "f"},
+		{0, 1, "f"},
+		{0, 3, "cdf"},
+		{2, 2, "def"},
+		{0, 10, "acdf"},
+	}
+	for _, tc := range testCases {
+		keep := backupSnapshotsToKeep(snapshots, tc.keepLast, tc.keepDaily)
+		got := ""
+		for _, s := range snapshots {
+			if keep[s.ID] {
+				got += s.ID
+			}
+		}
+		if got != tc.expected {
+			t.Errorf("keep-last %d keep-daily %d: expected %q, got %q", tc.keepLast, tc.keepDaily, tc.expected, got)
+		}
+	}
+}
+
+func TestBackupCreateRestore(t *testing.T) {
+	root := newServeTestRoot(t)
+	ctx := context.Background()
+	src := filepath.Join(root, "docs")
+	big := make([]byte, 64<<10)
+	rand.New(rand.NewSource(2)).Read(big)
+	if e := os.WriteFile(filepath.Join(src, "big.bin"), big, 0o644); e != nil {
+		t.Fatal(e)
+	}
+
+	repoURL := filepath.Join(root, "repo")
+	repo, err := openBackupRepo(ctx, repoURL, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.config.Chunker = testBackupChunkerParams
+
+	create := func() backupSnapshot {
+		t.Helper()
+		b := newBackupCreate(repo, src, nil)
+		if err := b.loadParent(ctx, ""); err != nil {
+			t.Fatal(err)
+		}
+		if e := b.run(ctx, 4); e != nil {
+			t.Fatal(e)
+		}
+		return b.snapshot
+	}
+	first := create()
+	if first.Files != 2 || first.Size != int64(len(big))+10 || first.NewChunks == 0 {
+		t.Fatalf("unexpected first snapshot %+v", first)
+	}
+
+	// Only the chunks around the change are added.
+	edited := append(append([]byte{}, big[:32<<10]...), big[32<<10+1:]...)
+	if e := os.WriteFile(filepath.Join(src, "big.bin"), edited, 0o644); e != nil {
+		t.Fatal(e)
+	}
+	second := create()
+	if second.Parent != first.ID || second.NewChunks == 0 || second.NewChunks > 3 {
+		t.Fatalf("unexpected second snapshot %+v", second)
+	}
+
+	for _, tc := range []struct {
+		snapshot backupSnapshot
+		big      []byte
+	}{{first, big}, {second, edited}} {
+		target := filepath.Join(root, "restore-"+tc.snapshot.ID)
+		if backupRestore(ctx, repo, tc.snapshot.ID, "", target, nil, 2) {
+			t.Fatalf("unable to restore %s", tc.snapshot.ID)
+		}
+		got, e := os.ReadFile(filepath.Join(target, "big.bin"))
+		if e != nil || !bytes.Equal(got, tc.big) {
+			t.Fatalf("%s: restored content differs (%v)", tc.snapshot.ID, e)
+		}
+		if got, e = os.ReadFile(filepath.Join(target, "a.txt")); e != nil || string(got) != "0123456789" {
+			t.Fatalf("%s: restored content differs (%v)", tc.snapshot.ID, e)
+		}
+	}
+
+	msg, err := backupCheck(ctx, repo, true, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Snapshots != 2 || msg.Missing != 0 || msg.Corrupted != 0 {
+		t.Fatalf("unexpected check %+v", msg)
+	}
+
+	// A corrupted chunk is reported.
+	chunkPath := filepath.Join(repoURL, filepath.FromSlash(backupChunkName(backupChunkHash([]byte("0123456789")))))
+	corrupted := backupEncoder().EncodeAll([]byte("012345678X"), nil)
+	if e := os.WriteFile(chunkPath, corrupted, 0o644); e != nil {
+		t.Fatal(e)
+	}
+	if msg, err = backupCheck(ctx, repo, true, 2); err != nil || msg.Corrupted != 1 {
+		t.Fatalf("expected a corrupted chunk, got %+v (%v)", msg, err)
+	}
+}
+
+func TestBackupPruneLock(t *testing.T) {
+	root := newServeTestRoot(t)
+	ctx := context.Background()
+	src := filepath.Join(root, "docs")
+
+	repo, err := openBackupRepo(ctx, filepath.Join(root, "repo"), nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.config.Chunker = testBackupChunkerParams
+
+	create := func() {
+		t.Helper()
+		b := newBackupCreate(repo, src, nil)
+		if err := b.loadParent(ctx, ""); err != nil {
+			t.Fatal(err)
+		}
+		if e := b.run(ctx, 2); e != nil {
+			t.Fatal(e)
+		}
+	}
+	create()
+	if e := os.WriteFile(filepath.Join(src, "a.txt"), []byte("9876543210"), 0o644); e != nil {
+		t.Fatal(e)
+	}
+
+	// A snapshot being created keeps prune from running.
+	unlock, err := repo.lock(ctx, "create", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = backupPrune(ctx, repo, 1, 0, false); err == nil {
+		t.Fatal("expected prune to fail while a snapshot is created")
+	}
+	create()
+	unlock()
+
+	// A prune keeps snapshots from being created.
+	unlock, err = repo.lock(ctx, "prune", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = repo.lock(ctx, "create", false); err == nil {
+		t.Fatal("expected create to fail while the repository is pruned")
+	}
+	if locks, err := repo.locks(ctx); err != nil || len(locks) != 1 || locks[0].Command != "prune" {
+		t.Fatalf("expected only the prune lock to be left, got %+v (%v)", locks, err)
+	}
+	unlock()
+
+	if err = backupPrune(ctx, repo, 1, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := backupCheck(ctx, repo, true, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Snapshots != 1 || msg.Missing != 0 || msg.Corrupted != 0 {
+		t.Fatalf("unexpected check after prune %+v", msg)
+	}
+	if locks, err := repo.locks(ctx); err != nil || len(locks) != 0 {
+		t.Fatalf("expected no locks after prune, got %+v (%v)", locks, err)
+	}
+}
@@ -26,6 +26,7 @@ import (
 var batchSubcommands = []*cli.Command{
 	&batchGenerateCmd,
 	&batchStartCmd,
+	&batchRunCmd,
 	&batchListCmd,
 	&batchStatusCmd,
 	&batchDescribeCmd,
@@ -0,0 +1,432 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/openstor/madmin-go/v4"
+	"github.com/openstor/pkg/v3/wildcard"
+	"gopkg.in/yaml.v2"
+)
+
+// Job definitions of 'mc batch run --local', they follow the schema of the
+// templates of 'mc batch generate'.
+type batchLocalJob struct {
+	Replicate *batchReplicateJob `yaml:"replicate"`
+	KeyRotate *batchKeyRotateJob `yaml:"keyrotate"`
+	Expire    *batchExpireJob    `yaml:"expire"`
+}
+
+type batchJobKV struct {
+	Key   string `yaml:"key"`
+	Value string `yaml:"value"`
+}
+
+type batchJobFilter struct {
+	NewerThan     string       `yaml:"newerThan"`
+	OlderThan     string       `yaml:"olderThan"`
+	CreatedAfter  string       `yaml:"createdAfter"`
+	CreatedBefore string       `yaml:"createdBefore"`
+	Tags          []batchJobKV `yaml:"tags"`
+	Metadata      []batchJobKV `yaml:"metadata"`
+	KMSKey        string       `yaml:"kmskey"`
+}
+
+type batchJobNotify struct {
+	Endpoint string `yaml:"endpoint"`
+	Token    string `yaml:"token"`
+}
+
+type batchJobRetry struct {
+	Attempts int    `yaml:"attempts"`
+	Delay    string `yaml:"delay"`
+}
+
+type batchJobFlags struct {
+	Filter batchJobFilter `yaml:"filter"`
+	Notify batchJobNotify `yaml:"notify"`
+	Retry  batchJobRetry  `yaml:"retry"`
+}
+
+type batchJobCredentials struct {
+	AccessKey    string `yaml:"accessKey"`
+	SecretKey    string `yaml:"secretKey"`
+	SessionToken string `yaml:"sessionToken"`
+}
+
+// batchJobEndpoint is the source or the target of a replication, the
+// alias given to 'mc batch run' is used if it has no endpoint.
+type batchJobEndpoint struct {
+	Type        string              `yaml:"type"`
+	Bucket      string              `yaml:"bucket"`
+	Prefix      string              `yaml:"prefix"`
+	Endpoint    string              `yaml:"endpoint"`
+	Path        string              `yaml:"path"`
+	Credentials batchJobCredentials `yaml:"credentials"`
+	// Archive transfers between MinIO servers, not used by local jobs.
+	Snowball yaml.MapSlice `yaml:"snowball"`
+}
+
+type batchReplicateJob struct {
+	APIVersion string           `yaml:"apiVersion"`
+	Source     batchJobEndpoint `yaml:"source"`
+	Target     batchJobEndpoint `yaml:"target"`
+	Flags      batchJobFlags    `yaml:"flags"`
+}
+
+type batchKeyRotateJob struct {
+	APIVersion string `yaml:"apiVersion"`
+	Bucket     string `yaml:"bucket"`
+	Prefix     string `yaml:"prefix"`
+	Encryption struct {
+		Type    string `yaml:"type"`
+		Key     string `yaml:"key"`
+		Context string `yaml:"context"`
+	} `yaml:"encryption"`
+	Flags batchJobFlags `yaml:"flags"`
+}
+
+type batchJobSize struct {
+	LessThan    string `yaml:"lessThan"`
+	GreaterThan string `yaml:"greaterThan"`
+}
+
+type batchExpireRule struct {
+	Type          string       `yaml:"type"`
+	Name          string       `yaml:"name"`
+	OlderThan     string       `yaml:"olderThan"`
+	CreatedBefore string       `yaml:"createdBefore"`
+	Tags          []batchJobKV `yaml:"tags"`
+	Metadata      []batchJobKV `yaml:"metadata"`
+	Size          batchJobSize `yaml:"size"`
+	Purge         struct {
+		RetainVersions int `yaml:"retainVersions"`
+	} `yaml:"purge"`
+}
+
+type batchExpireJob struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Bucket     string            `yaml:"bucket"`
+	Prefix     string            `yaml:"prefix"`
+	Rules      []batchExpireRule `yaml:"rules"`
+	Notify     batchJobNotify    `yaml:"notify"`
+	Retry      batchJobRetry     `yaml:"retry"`
+}
+
+const (
+	batchExpireObject  = "object"
+	batchExpireDeleted = "deleted"
+)
+
+// parseBatchLocalJob reads a job definition, exactly one job type must
+// be defined.
+func parseBatchLocalJob(buf []byte) (*batchLocalJob, madmin.BatchJobType, error) {
+	job := &batchLocalJob{}
+	if e := yaml.UnmarshalStrict(buf, job); e != nil {
+		return nil, "", e
+	}
+	var (
+		jobType madmin.BatchJobType
+		n       int
+	)
+	if job.Replicate != nil {
+		jobType, n = madmin.BatchJobReplicate, n+1
+	}
+	if job.KeyRotate != nil {
+		jobType, n = madmin.BatchJobKeyRotate, n+1
+	}
+	if job.Expire != nil {
+		jobType, n = madmin.BatchJobExpire, n+1
+	}
+	if n != 1 {
+		return nil, "", errors.New("the job must define one of 'replicate', 'keyrotate' or 'expire'")
+	}
+	return job, jobType, job.validate()
+}
+
+func (job *batchLocalJob) validate() error {
+	var apiVersion string
+	switch {
+	case job.Replicate != nil:
+		apiVersion = job.Replicate.APIVersion
+		if job.Replicate.Source.Bucket == "" || job.Replicate.Target.Bucket == "" {
+			return errors.New("replicate: source and target buckets are required")
+		}
+		if job.Replicate.Source.Endpoint != "" && job.Replicate.Target.Endpoint != "" &&
+			job.Replicate.Source.Endpoint == job.Replicate.Target.Endpoint &&
+			job.Replicate.Source.Bucket == job.Replicate.Target.Bucket {
+			return errors.New("replicate: source and target must differ")
+		}
+		if _, e := compileBatchFilter(job.Replicate.Flags.Filter); e != nil {
+			return fmt.Errorf("replicate: %w", e)
+		}
+	case job.KeyRotate != nil:
+		apiVersion = job.KeyRotate.APIVersion
+		if job.KeyRotate.Bucket == "" {
+			return errors.New("keyrotate: bucket is required")
+		}
+		switch job.KeyRotate.Encryption.Type {
+		case "sse-s3":
+		case "sse-kms":
+			if job.KeyRotate.Encryption.Key == "" {
+				return errors.New("keyrotate: sse-kms requires a key")
+			}
+		default:
+			return fmt.Errorf("keyrotate: unsupported encryption type %q", job.KeyRotate.Encryption.Type)
+		}
+		if _, e := compileBatchFilter(job.KeyRotate.Flags.Filter); e != nil {
+			return fmt.Errorf("keyrotate: %w", e)
+		}
+	case job.Expire != nil:
+		apiVersion = job.Expire.APIVersion
+		if job.Expire.Bucket == "" {
+			return errors.New("expire: bucket is required")
+		}
+		if len(job.Expire.Rules) == 0 {
+			return errors.New("expire: at least one rule is required")
+		}
+		for i, rule := range job.Expire.Rules {
+			if _, e := compileBatchExpireRule(rule); e != nil {
+				return fmt.Errorf("expire: rule %d: %w", i+1, e)
+			}
+		}
+	}
+	if apiVersion != "v1" {
+		return fmt.Errorf("unsupported apiVersion %q, only 'v1' is supported", apiVersion)
+	}
+	return nil
+}
+
+// retry returns the retries of each object of the job.
+func (job *batchLocalJob) retry() (attempts int, delay time.Duration, e error) {
+	var r batchJobRetry
+	switch {
+	case job.Replicate != nil:
+		r = job.Replicate.Flags.Retry
+	case job.KeyRotate != nil:
+		r = job.KeyRotate.Flags.Retry
+	case job.Expire != nil:
+		r = job.Expire.Retry
+	}
+	attempts, delay = 3, 500*time.Millisecond
+	if r.Attempts > 0 {
+		attempts = r.Attempts
+	}
+	if r.Delay != "" {
+		if delay, e = time.ParseDuration(r.Delay); e != nil {
+			return 0, 0, fmt.Errorf("invalid retry delay %q", r.Delay)
+		}
+	}
+	return attempts, delay, nil
+}
+
+func (job *batchLocalJob) notify() batchJobNotify {
+	switch {
+	case job.Replicate != nil:
+		return job.Replicate.Flags.Notify
+	case job.KeyRotate != nil:
+		return job.KeyRotate.Flags.Notify
+	default:
+		return job.Expire.Notify
+	}
+}
+
+// batchFilter is a compiled filter of a job or of an expiry rule.
+type batchFilter struct {
+	name          string
+	newerThan     time.Duration
+	olderThan     time.Duration
+	createdAfter  time.Time
+	createdBefore time.Time
+	tags          []batchJobKV
+	metadata      []batchJobKV
+	kmsKey        string
+	lessThan      int64
+	greaterThan   int64
+}
+
+func parseBatchAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, e := ParseDuration(s)
+	if e != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return time.Duration(d), nil
+}
+
+func parseBatchDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, e := time.Parse(time.RFC3339, s)
+	if e != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected RFC3339", s)
+	}
+	return t, nil
+}
+
+func compileBatchFilter(f batchJobFilter) (batchFilter, error) {
+	var (
+		c batchFilter
+		e error
+	)
+	if c.newerThan, e = parseBatchAge(f.NewerThan); e != nil {
+		return c, e
+	}
+	if c.olderThan, e = parseBatchAge(f.OlderThan); e != nil {
+		return c, e
+	}
+	if c.createdAfter, e = parseBatchDate(f.CreatedAfter); e != nil {
+		return c, e
+	}
+	if c.createdBefore, e = parseBatchDate(f.CreatedBefore); e != nil {
+		return c, e
+	}
+	c.tags, c.metadata, c.kmsKey = f.Tags, f.Metadata, f.KMSKey
+	return c, nil
+}
+
+// batchExpireRuleFilter is a compiled expiry rule.
+type batchExpireRuleFilter struct {
+	batchFilter
+	deleted bool
+	retain  int
+}
+
+func compileBatchExpireRule(rule batchExpireRule) (batchExpireRuleFilter, error) {
+	var (
+		c batchExpireRuleFilter
+		e error
+	)
+	switch rule.Type {
+	case batchExpireObject:
+	case batchExpireDeleted:
+		c.deleted = true
+		if len(rule.Tags) > 0 || len(rule.Metadata) > 0 || rule.Size != (batchJobSize{}) {
+			return c, errors.New("tags, metadata and size do not apply to delete markers")
+		}
+	default:
+		return c, fmt.Errorf("unsupported type %q, valid types are 'object' and 'deleted'", rule.Type)
+	}
+	if rule.Purge.RetainVersions < 0 {
+		return c, errors.New("retainVersions must not be negative")
+	}
+	c.retain = rule.Purge.RetainVersions
+	c.name = rule.Name
+	if c.olderThan, e = parseBatchAge(rule.OlderThan); e != nil {
+		return c, e
+	}
+	if c.createdBefore, e = parseBatchDate(rule.CreatedBefore); e != nil {
+		return c, e
+	}
+	for _, s := range []struct {
+		value string
+		size  *int64
+	}{{rule.Size.LessThan, &c.lessThan}, {rule.Size.GreaterThan, &c.greaterThan}} {
+		if s.value == "" {
+			continue
+		}
+		n, e := humanize.ParseBytes(s.value)
+		if e != nil {
+			return c, fmt.Errorf("invalid size %q", s.value)
+		}
+		*s.size = int64(n)
+	}
+	c.tags, c.metadata = rule.Tags, rule.Metadata
+	return c, nil
+}
+
+// match returns true if the object matches the conditions known from a
+// listing, name is the key of the object in its bucket.
+func (f batchFilter) match(name string, c *ClientContent, now time.Time) bool {
+	if f.name != "" && !wildcard.Match(f.name, name) {
+		return false
+	}
+	age := now.Sub(c.Time)
+	if f.newerThan > 0 && age >= f.newerThan {
+		return false
+	}
+	if f.olderThan > 0 && age < f.olderThan {
+		return false
+	}
+	if !f.createdAfter.IsZero() && !c.Time.After(f.createdAfter) {
+		return false
+	}
+	if !f.createdBefore.IsZero() && !c.Time.Before(f.createdBefore) {
+		return false
+	}
+	if f.lessThan > 0 && c.Size >= f.lessThan {
+		return false
+	}
+	if f.greaterThan > 0 && c.Size <= f.greaterThan {
+		return false
+	}
+	return true
+}
+
+// needsStat returns true if the filter needs the metadata of objects.
+func (f batchFilter) needsStat() bool {
+	return len(f.metadata) > 0 || f.kmsKey != ""
+}
+
+// matchMetadata matches the metadata filters with the headers and user
+// metadata of an object, keys are case insensitive and values wildcards.
+func (f batchFilter) matchMetadata(c *ClientContent) bool {
+	lookup := func(key string) (string, bool) {
+		key = strings.ToLower(key)
+		for k, v := range c.Metadata {
+			if k = strings.ToLower(k); k == key || k == "x-amz-meta-"+key {
+				return v, true
+			}
+		}
+		for k, v := range c.UserMetadata {
+			if k = strings.ToLower(k); k == key || "x-amz-meta-"+k == key {
+				return v, true
+			}
+		}
+		return "", false
+	}
+	for _, kv := range f.metadata {
+		if v, ok := lookup(kv.Key); !ok || !wildcard.Match(kv.Value, v) {
+			return false
+		}
+	}
+	if f.kmsKey != "" {
+		v, _ := lookup("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id")
+		if strings.TrimPrefix(v, "arn:aws:kms:") != strings.TrimPrefix(f.kmsKey, "arn:aws:kms:") {
+			return false
+		}
+	}
+	return true
+}
+
+func (f batchFilter) matchTags(tags map[string]string) bool {
+	for _, kv := range f.tags {
+		if v, ok := tags[kv.Key]; !ok || !wildcard.Match(kv.Value, v) {
+			return false
+		}
+	}
+	return true
+}
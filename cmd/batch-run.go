@@ -0,0 +1,844 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+	"github.com/openstor/madmin-go/v4"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/openstor-go/v7"
+	"github.com/openstor/openstor-go/v7/pkg/encrypt"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var batchRunFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "local",
+		Usage: "run the job in mc, with any S3 compatible source and target",
+	},
+	&cli.StringFlag{
+		Name:  "checkpoint",
+		Usage: "file saving the progress of the job (default: derived from the alias and the job)",
+	},
+	&cli.BoolFlag{
+		Name:  "fresh",
+		Usage: "ignore the checkpoint of a previous run of the job",
+	},
+	&cli.IntFlag{
+		Name:  "max-workers",
+		Usage: "maximum number of objects processed in parallel (default: autodetect)",
+	},
+}
+
+var batchRunCmd = cli.Command{
+	Name:         "run",
+	Usage:        "run a batch job on the client",
+	Action:       mainBatchRun,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(batchRunFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} --local ALIAS JOBFILE
+
+  Runs 'replicate', 'keyrotate' and 'expire' jobs written for 'mc batch start'
+  without a batch API on the server. Buckets without an endpoint in the job
+  are on ALIAS, endpoints and credentials in the job are used as they are.
+  Replication copies the latest version of each object with its metadata and
+  tags, snowball settings are ignored.
+
+  The progress is saved in a checkpoint, running the job again resumes it and
+  retries the objects which failed. The final report has the format of
+  'mc batch status --json'.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Replicate objects from AWS S3 to the bucket of a replication job on 's3'.
+     {{.Prompt}} {{.HelpName}} --local s3 ./replication.yaml
+
+  2. Expire objects of a bucket on 's3' and print the report as JSON.
+     {{.Prompt}} {{.HelpName}} --local --json s3 ./expire.yaml
+`,
+}
+
+const batchCheckpointInterval = 5 * time.Second
+
+// batchRunMessage is the report of a job run on the client, its JSON is
+// the one of 'mc batch status --json'.
+type batchRunMessage struct {
+	batchJobStatusMessage
+}
+
+func (m batchRunMessage) String() string {
+	metric := m.Metric
+	var summary string
+	switch {
+	case metric.Replicate != nil:
+		summary = fmt.Sprintf("%d objects replicated (%s), %d failed", metric.Replicate.Objects,
+			humanize.IBytes(uint64(metric.Replicate.BytesTransferred)), metric.Replicate.ObjectsFailed)
+	case metric.KeyRotate != nil:
+		summary = fmt.Sprintf("%d objects rotated, %d failed", metric.KeyRotate.Objects, metric.KeyRotate.ObjectsFailed)
+	case metric.Expired != nil:
+		summary = fmt.Sprintf("%d objects and %d delete markers expired, %d failed", metric.Expired.Objects,
+			metric.Expired.DeleteMarkers, metric.Expired.ObjectsFailed+metric.Expired.DeleteMarkersFailed)
+	}
+	elapsed := metric.LastUpdate.Sub(metric.StartTime).Round(time.Second)
+	return console.Colorize("BatchStart", fmt.Sprintf("Job `%s` (%s) %s after %s: %s.", metric.JobID, metric.JobType, m.Status, elapsed, summary))
+}
+
+func (m batchRunMessage) JSON() string {
+	return m.batchJobStatusMessage.JSON()
+}
+
+// batchCheckpoint is the saved progress of a job, the objects up to
+// LastObject are done except the Failed ones.
+type batchCheckpoint struct {
+	JobID      string           `json:"jobID"`
+	LastObject string           `json:"lastObject"`
+	Failed     []string         `json:"failed,omitempty"`
+	Metric     madmin.JobMetric `json:"metric"`
+}
+
+// batchLocation is a bucket and prefix of a job, on an alias or on an
+// endpoint of the job.
+type batchLocation struct {
+	cfg    *aliasConfigV11
+	base   string
+	prefix string
+}
+
+func newBatchLocation(alias string, ep batchJobEndpoint) batchLocation {
+	if ep.Endpoint == "" {
+		return batchLocation{base: alias + "/" + ep.Bucket, prefix: ep.Prefix}
+	}
+	path := ep.Path
+	if path == "" {
+		path = "auto"
+	}
+	return batchLocation{
+		cfg: &aliasConfigV11{
+			URL:          ep.Endpoint,
+			AccessKey:    ep.Credentials.AccessKey,
+			SecretKey:    ep.Credentials.SecretKey,
+			SessionToken: ep.Credentials.SessionToken,
+			API:          "S3v4",
+			Path:         path,
+		},
+		base:   strings.TrimSuffix(ep.Endpoint, "/") + "/" + ep.Bucket,
+		prefix: ep.Prefix,
+	}
+}
+
+func (l batchLocation) url(key string) string {
+	return l.base + "/" + key
+}
+
+func (l batchLocation) client(key string) (Client, *probe.Error) {
+	if l.cfg == nil {
+		return newClient(l.url(key))
+	}
+	return S3New(NewS3Config("", l.url(key), l.cfg))
+}
+
+// batchObject is an object of a job with all its listed versions,
+// latest first.
+type batchObject struct {
+	key      string
+	versions []*ClientContent
+}
+
+// list sends the objects under prefix, their versions are only listed
+// if versions is set.
+func (l batchLocation) list(ctx context.Context, prefix string, versions bool, fn func(batchObject) bool) *probe.Error {
+	root, err := l.client("")
+	if err != nil {
+		return err.Trace(l.base)
+	}
+	basePath := filepath.ToSlash(root.GetURL().Path)
+	if !strings.HasSuffix(basePath, "/") {
+		basePath += "/"
+	}
+	clnt, err := l.client(prefix)
+	if err != nil {
+		return err.Trace(l.url(prefix))
+	}
+
+	var obj batchObject
+	for content := range clnt.List(ctx, ListOptions{
+		Recursive:         true,
+		ShowDir:           DirNone,
+		WithOlderVersions: versions,
+		WithDeleteMarkers: versions,
+	}) {
+		if content.Err != nil {
+			return content.Err.Trace(l.url(prefix))
+		}
+		if content.Type.IsDir() {
+			continue
+		}
+		key := strings.TrimPrefix(filepath.ToSlash(content.URL.Path), basePath)
+		if key == obj.key && obj.versions != nil {
+			obj.versions = append(obj.versions, content)
+			continue
+		}
+		if obj.versions != nil && !fn(obj) {
+			return nil
+		}
+		obj = batchObject{key: key, versions: []*ClientContent{content}}
+	}
+	if obj.versions != nil {
+		fn(obj)
+	}
+	return nil
+}
+
+// batchRunner runs a job on the client.
+type batchRunner struct {
+	job      *batchLocalJob
+	jobType  madmin.BatchJobType
+	alias    string
+	attempts int
+	delay    time.Duration
+
+	source batchLocation
+	target batchLocation
+	filter batchFilter
+	rules  []batchExpireRuleFilter
+	sse    encrypt.ServerSide
+
+	checkpointFile string
+
+	mu         sync.Mutex
+	checkpoint batchCheckpoint
+	failed     map[string]bool
+	// Keys queued in listing order, the checkpoint moves over the keys
+	// which are done.
+	pending  map[uint64]string
+	done     map[uint64]bool
+	nextSeq  uint64
+	doneUpTo uint64
+}
+
+// mainBatchRun is the handle for "mc batch run" command.
+func mainBatchRun(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 2 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+	if !cmd.Bool("local") {
+		fatalIf(errInvalidArgument().Trace(), "Only --local is supported, use 'mc batch start' to run a job on MinIO.")
+	}
+	console.SetColor("BatchStart", color.New(color.FgGreen, color.Bold))
+
+	alias, jobFile := cmd.Args().Get(0), cmd.Args().Get(1)
+	buf, e := os.ReadFile(jobFile)
+	fatalIf(probe.NewError(e), "Unable to read %s", jobFile)
+	job, jobType, e := parseBatchLocalJob(buf)
+	fatalIf(probe.NewError(e).Trace(jobFile), "Unable to parse %s", jobFile)
+
+	r, err := newBatchRunner(job, jobType, alias)
+	fatalIf(err.Trace(jobFile), "Unable to start job.")
+
+	r.checkpointFile = cmd.String("checkpoint")
+	if r.checkpointFile == "" {
+		sum := sha256.Sum256(append([]byte(alias+"\n"), buf...))
+		r.checkpointFile = filepath.Join(mustGetMcConfigDir(), "batch", hex.EncodeToString(sum[:8])+".json")
+	}
+	if !cmd.Bool("fresh") {
+		fatalIf(r.loadCheckpoint(), "Unable to read checkpoint `%s`.", r.checkpointFile)
+	}
+
+	msg, failed := r.run(ctx, cmd.Int("max-workers"))
+	printMsg(msg)
+	r.notify(msg)
+	if failed {
+		return exitStatus(globalErrorExitStatus)
+	}
+	return nil
+}
+
+func newBatchRunner(job *batchLocalJob, jobType madmin.BatchJobType, alias string) (*batchRunner, *probe.Error) {
+	attempts, delay, e := job.retry()
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	r := &batchRunner{
+		job:      job,
+		jobType:  jobType,
+		alias:    alias,
+		attempts: attempts,
+		delay:    delay,
+		failed:   map[string]bool{},
+		pending:  map[uint64]string{},
+		done:     map[uint64]bool{},
+	}
+	switch {
+	case job.Replicate != nil:
+		r.source = newBatchLocation(alias, job.Replicate.Source)
+		r.target = newBatchLocation(alias, job.Replicate.Target)
+		r.filter, _ = compileBatchFilter(job.Replicate.Flags.Filter)
+	case job.KeyRotate != nil:
+		r.source = newBatchLocation(alias, batchJobEndpoint{Bucket: job.KeyRotate.Bucket, Prefix: job.KeyRotate.Prefix})
+		r.filter, _ = compileBatchFilter(job.KeyRotate.Flags.Filter)
+		enc := job.KeyRotate.Encryption
+		if enc.Type == "sse-kms" {
+			var kmsContext any
+			if enc.Context != "" {
+				if e = json.Unmarshal([]byte(enc.Context), &kmsContext); e != nil {
+					return nil, probe.NewError(fmt.Errorf("invalid KMS context: %w", e))
+				}
+			}
+			if r.sse, e = encrypt.NewSSEKMS(enc.Key, kmsContext); e != nil {
+				return nil, probe.NewError(e)
+			}
+		} else {
+			r.sse = encrypt.NewSSE()
+		}
+		clnt, err := r.source.client("")
+		if err != nil {
+			return nil, err
+		}
+		if clnt.GetURL().Type != objectStorage {
+			return nil, probe.NewError(errors.New("keyrotate needs an S3 alias"))
+		}
+	case job.Expire != nil:
+		r.source = newBatchLocation(alias, batchJobEndpoint{Bucket: job.Expire.Bucket, Prefix: job.Expire.Prefix})
+		for _, rule := range job.Expire.Rules {
+			c, _ := compileBatchExpireRule(rule)
+			r.rules = append(r.rules, c)
+		}
+	}
+	r.checkpoint = batchCheckpoint{
+		JobID: strings.ReplaceAll(uuid.NewString(), "-", ""),
+		Metric: madmin.JobMetric{
+			JobType:   string(jobType),
+			StartTime: UTCNow(),
+		},
+	}
+	r.checkpoint.Metric.JobID = r.checkpoint.JobID
+	switch jobType {
+	case madmin.BatchJobReplicate:
+		r.checkpoint.Metric.Replicate = &madmin.ReplicateInfo{}
+	case madmin.BatchJobKeyRotate:
+		r.checkpoint.Metric.KeyRotate = &madmin.KeyRotationInfo{}
+	case madmin.BatchJobExpire:
+		r.checkpoint.Metric.Expired = &madmin.ExpirationInfo{}
+	}
+	return r, nil
+}
+
+// loadCheckpoint resumes a previous run of the job, the objects which
+// failed are counted again when they are retried.
+func (r *batchRunner) loadCheckpoint() *probe.Error {
+	buf, e := os.ReadFile(r.checkpointFile)
+	if os.IsNotExist(e) {
+		return nil
+	}
+	if e != nil {
+		return probe.NewError(e)
+	}
+	var c batchCheckpoint
+	if e = json.Unmarshal(buf, &c); e != nil {
+		return probe.NewError(e)
+	}
+	if c.Metric.JobType != string(r.jobType) {
+		return probe.NewError(fmt.Errorf("checkpoint of a %s job", c.Metric.JobType))
+	}
+	c.Metric.Complete, c.Metric.Failed, c.Metric.Status = false, false, ""
+	c.Metric.RetryAttempts++
+	switch {
+	case c.Metric.Replicate != nil:
+		c.Metric.Replicate.ObjectsFailed, c.Metric.Replicate.BytesFailed = 0, 0
+	case c.Metric.KeyRotate != nil:
+		c.Metric.KeyRotate.ObjectsFailed = 0
+	case c.Metric.Expired != nil:
+		c.Metric.Expired.ObjectsFailed, c.Metric.Expired.DeleteMarkersFailed = 0, 0
+	default:
+		return probe.NewError(errors.New("checkpoint has no metrics"))
+	}
+	r.checkpoint = c
+	return nil
+}
+
+// saveCheckpoint writes the progress of the job, the file is removed
+// once the job completed without failures.
+func (r *batchRunner) saveCheckpoint(remove bool) *probe.Error {
+	if remove {
+		if e := os.Remove(r.checkpointFile); e != nil && !os.IsNotExist(e) {
+			return probe.NewError(e)
+		}
+		return nil
+	}
+	r.mu.Lock()
+	c := r.checkpoint
+	c.Failed = make([]string, 0, len(r.failed))
+	for key := range r.failed {
+		c.Failed = append(c.Failed, key)
+	}
+	sort.Strings(c.Failed)
+	buf, e := json.MarshalIndent(c, "", " ")
+	r.mu.Unlock()
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = os.MkdirAll(filepath.Dir(r.checkpointFile), 0o700); e != nil {
+		return probe.NewError(e)
+	}
+	tmp := r.checkpointFile + ".tmp"
+	if e = os.WriteFile(tmp, buf, 0o600); e != nil {
+		return probe.NewError(e)
+	}
+	return probe.NewError(os.Rename(tmp, r.checkpointFile))
+}
+
+// queue registers a key of the listing, in order.
+func (r *batchRunner) queue(key string) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seq := r.nextSeq
+	r.nextSeq++
+	r.pending[seq] = key
+	return seq
+}
+
+// finish records the result of an object, seq is false for the retries
+// of the objects which failed before.
+func (r *batchRunner) finish(seq uint64, listed bool, key string, e error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e != nil {
+		r.failed[key] = true
+	} else {
+		delete(r.failed, key)
+	}
+	r.checkpoint.Metric.LastUpdate = UTCNow()
+	if !listed {
+		return
+	}
+	r.done[seq] = true
+	for r.done[r.doneUpTo] {
+		r.checkpoint.LastObject = r.pending[r.doneUpTo]
+		delete(r.done, r.doneUpTo)
+		delete(r.pending, r.doneUpTo)
+		r.doneUpTo++
+	}
+}
+
+// update changes the metrics of the job.
+func (r *batchRunner) update(fn func(*madmin.JobMetric)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&r.checkpoint.Metric)
+}
+
+// withRetry runs fn up to the number of attempts of the job.
+func (r *batchRunner) withRetry(ctx context.Context, fn func() error) error {
+	var e error
+	for attempt := range r.attempts {
+		if attempt > 0 {
+			select {
+			case <-time.After(r.delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if e = fn(); e == nil || ctx.Err() != nil {
+			return e
+		}
+	}
+	return e
+}
+
+// run processes the objects which failed before, then the objects after
+// the checkpoint. It returns the report and true if any object failed.
+func (r *batchRunner) run(ctx context.Context, maxWorkers int) (batchRunMessage, bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	statusCh := make(chan URLs)
+	parallel := newParallelManager(statusCh, maxWorkers)
+	go func() {
+		for range statusCh {
+		}
+	}()
+
+	retries := r.checkpoint.Failed
+	r.checkpoint.Failed = nil
+	lastObject := r.checkpoint.LastObject
+
+	stopSaving := make(chan struct{})
+	saved := make(chan struct{})
+	go func() {
+		defer close(saved)
+		ticker := time.NewTicker(batchCheckpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				errorIf(r.saveCheckpoint(false), "Unable to save checkpoint `%s`.", r.checkpointFile)
+				if globalJSON {
+					r.mu.Lock()
+					metric := r.checkpoint.Metric
+					r.mu.Unlock()
+					printMsg(batchRunMessage{batchJobStatusMessage{Status: "in-progress", Metric: metric}})
+				}
+			case <-stopSaving:
+				return
+			}
+		}
+	}()
+
+	queue := func(obj batchObject, seq uint64, listed bool) {
+		size := int64(0)
+		if r.jobType == madmin.BatchJobReplicate {
+			size = obj.versions[0].Size
+		}
+		parallel.queueTask(func() URLs {
+			e := r.process(ctx, obj)
+			r.finish(seq, listed, obj.key, e)
+			return URLs{}
+		}, size)
+	}
+
+	versions := r.jobType == madmin.BatchJobExpire
+	var err *probe.Error
+	for _, key := range retries {
+		if ctx.Err() != nil {
+			break
+		}
+		found := false
+		err = r.source.list(ctx, key, versions, func(obj batchObject) bool {
+			if obj.key != key {
+				return obj.key < key
+			}
+			found = true
+			queue(obj, 0, false)
+			return false
+		})
+		if err != nil {
+			break
+		}
+		if !found {
+			// Removed since the previous run.
+			r.finish(0, false, key, nil)
+		}
+	}
+	if err == nil && ctx.Err() == nil {
+		err = r.source.list(ctx, r.source.prefix, versions, func(obj batchObject) bool {
+			if lastObject != "" && obj.key <= lastObject {
+				return true
+			}
+			queue(obj, r.queue(obj.key), true)
+			return ctx.Err() == nil
+		})
+	}
+	parallel.stopAndWait()
+	close(statusCh)
+	close(stopSaving)
+	<-saved
+
+	r.mu.Lock()
+	metric := r.checkpoint.Metric
+	failed := len(r.failed) > 0
+	r.mu.Unlock()
+	metric.LastUpdate = UTCNow()
+
+	status := "complete"
+	switch {
+	case err != nil:
+		errorIf(err, "Unable to list `%s`.", r.source.url(r.source.prefix))
+		metric.Failed, status = true, "failed"
+	case ctx.Err() != nil || globalContext.Err() != nil:
+		status = "in-progress"
+	default:
+		metric.Complete = true
+	}
+	metric.Status = status
+	r.update(func(m *madmin.JobMetric) { *m = metric })
+	errorIf(r.saveCheckpoint(metric.Complete && !failed), "Unable to save checkpoint `%s`.", r.checkpointFile)
+	return batchRunMessage{batchJobStatusMessage{Status: status, Metric: metric}}, failed || !metric.Complete
+}
+
+// process runs the job on an object.
+func (r *batchRunner) process(ctx context.Context, obj batchObject) error {
+	switch r.jobType {
+	case madmin.BatchJobReplicate:
+		return r.replicate(ctx, obj.key, obj.versions[0])
+	case madmin.BatchJobKeyRotate:
+		return r.rotate(ctx, obj.key, obj.versions[0])
+	default:
+		return r.expire(ctx, obj)
+	}
+}
+
+// matchObject applies the filter of the job to an object, its metadata
+// and tags are only read if the filter needs them.
+func (r *batchRunner) matchObject(ctx context.Context, clnt Client, key string, content *ClientContent, filter batchFilter) (bool, *ClientContent, map[string]string, error) {
+	if !filter.match(key, content, UTCNow()) {
+		return false, nil, nil, nil
+	}
+	if filter.needsStat() || r.jobType == madmin.BatchJobReplicate {
+		stat, err := clnt.Stat(ctx, StatOptions{versionID: content.VersionID})
+		if err != nil {
+			return false, nil, nil, err.ToGoError()
+		}
+		if !filter.matchMetadata(stat) {
+			return false, nil, nil, nil
+		}
+		content = stat
+	}
+	var tags map[string]string
+	if len(filter.tags) > 0 || (r.jobType == madmin.BatchJobReplicate && clnt.GetURL().Type == objectStorage) {
+		var err *probe.Error
+		if tags, err = clnt.GetTags(ctx, content.VersionID); err != nil {
+			// Objects are replicated without tags from servers without tagging.
+			if len(filter.tags) > 0 || openstor.ToErrorResponse(err.ToGoError()).Code != "NotImplemented" {
+				return false, nil, nil, err.ToGoError()
+			}
+		}
+		if !filter.matchTags(tags) {
+			return false, nil, nil, nil
+		}
+	}
+	return true, content, tags, nil
+}
+
+// replicate copies an object with its metadata and tags to the target.
+func (r *batchRunner) replicate(ctx context.Context, key string, content *ClientContent) error {
+	var size int64
+	matched := false
+	e := r.withRetry(ctx, func() error {
+		src, err := r.source.client(key)
+		if err != nil {
+			return err.ToGoError()
+		}
+		ok, stat, tags, e := r.matchObject(ctx, src, key, content, r.filter)
+		if e != nil || !ok {
+			return e
+		}
+		matched, size = true, stat.Size
+
+		metadata := map[string]string{}
+		for _, k := range []string{"Content-Type", "Cache-Control", "Content-Encoding", "Content-Disposition", "Content-Language"} {
+			if v, ok := stat.Metadata[k]; ok {
+				metadata[k] = v
+			}
+		}
+		for k, v := range stat.UserMetadata {
+			metadata[k] = v
+		}
+		if len(tags) > 0 {
+			values := url.Values{}
+			for k, v := range tags {
+				values.Set(k, v)
+			}
+			metadata["X-Amz-Tagging"] = values.Encode()
+		}
+
+		reader, _, err := src.Get(ctx, GetOptions{VersionID: stat.VersionID})
+		if err != nil {
+			return err.ToGoError()
+		}
+		defer reader.Close()
+
+		targetKey := key
+		if prefix := r.target.prefix; prefix != "" {
+			targetKey = strings.TrimSuffix(prefix, "/") + "/" + key
+		}
+		tgt, err := r.target.client(targetKey)
+		if err != nil {
+			return err.ToGoError()
+		}
+		_, err = tgt.Put(ctx, reader, stat.Size, nil, PutOptions{metadata: metadata})
+		return err.ToGoError()
+	})
+	r.update(func(m *madmin.JobMetric) {
+		m.Replicate.Bucket, m.Replicate.Object = r.source.bucket(), key
+		if e != nil {
+			m.Replicate.ObjectsFailed++
+			m.Replicate.BytesFailed += content.Size
+		} else if matched {
+			m.Replicate.Objects++
+			m.Replicate.BytesTransferred += size
+		}
+	})
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(key), "Unable to replicate `%s`.", r.source.url(key))
+	}
+	return e
+}
+
+// rotate copies an object over itself with the new encryption.
+func (r *batchRunner) rotate(ctx context.Context, key string, content *ClientContent) error {
+	matched := false
+	e := r.withRetry(ctx, func() error {
+		clnt, err := r.source.client(key)
+		if err != nil {
+			return err.ToGoError()
+		}
+		ok, stat, _, e := r.matchObject(ctx, clnt, key, content, r.filter)
+		if e != nil || !ok {
+			return e
+		}
+		matched = true
+		if stat == nil {
+			stat = content
+		}
+		return clnt.Copy(ctx, clnt.GetURL().Path, CopyOptions{
+			versionID: stat.VersionID,
+			size:      stat.Size,
+			tgtSSE:    r.sse,
+		}, nil).ToGoError()
+	})
+	r.update(func(m *madmin.JobMetric) {
+		m.KeyRotate.Bucket, m.KeyRotate.Object = r.source.bucket(), key
+		if e != nil {
+			m.KeyRotate.ObjectsFailed++
+		} else if matched {
+			m.KeyRotate.Objects++
+		}
+	})
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(key), "Unable to rotate the key of `%s`.", r.source.url(key))
+	}
+	return e
+}
+
+// expire removes the versions of an object selected by the first rule
+// matching its latest version.
+func (r *batchRunner) expire(ctx context.Context, obj batchObject) error {
+	latest := obj.versions[0]
+	var remove []*ClientContent
+	e := r.withRetry(ctx, func() error {
+		clnt, err := r.source.client(obj.key)
+		if err != nil {
+			return err.ToGoError()
+		}
+		remove = nil
+		for _, rule := range r.rules {
+			if rule.deleted != latest.IsDeleteMarker {
+				continue
+			}
+			var ok bool
+			if rule.deleted {
+				ok = rule.match(obj.key, latest, UTCNow())
+			} else {
+				var e error
+				if ok, _, _, e = r.matchObject(ctx, clnt, obj.key, latest, rule.batchFilter); e != nil {
+					return e
+				}
+			}
+			if !ok {
+				continue
+			}
+			if rule.retain < len(obj.versions) {
+				remove = obj.versions[rule.retain:]
+			}
+			break
+		}
+		if len(remove) == 0 {
+			return nil
+		}
+		root, err := r.source.client("")
+		if err != nil {
+			return err.ToGoError()
+		}
+		contentCh := make(chan *ClientContent, len(remove))
+		for _, v := range remove {
+			contentCh <- v
+		}
+		close(contentCh)
+		for result := range root.Remove(ctx, false, false, false, false, contentCh) {
+			if result.Err != nil {
+				return result.Err.ToGoError()
+			}
+		}
+		return nil
+	})
+	r.update(func(m *madmin.JobMetric) {
+		m.Expired.Bucket, m.Expired.Object = r.source.bucket(), obj.key
+		switch {
+		case e != nil && latest.IsDeleteMarker:
+			m.Expired.DeleteMarkersFailed++
+		case e != nil:
+			m.Expired.ObjectsFailed++
+		case len(remove) == 0:
+		case latest.IsDeleteMarker:
+			m.Expired.DeleteMarkers++
+		default:
+			m.Expired.Objects++
+		}
+	})
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(obj.key), "Unable to expire `%s`.", r.source.url(obj.key))
+	}
+	return e
+}
+
+func (l batchLocation) bucket() string {
+	return filepath.Base(filepath.ToSlash(l.base))
+}
+
+// notify sends the report to the notification endpoint of the job.
+func (r *batchRunner) notify(msg batchRunMessage) {
+	n := r.job.notify()
+	if n.Endpoint == "" {
+		return
+	}
+	buf, e := json.Marshal(msg.Metric)
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	ctx, cancel := context.WithTimeout(globalContext, 30*time.Second)
+	defer cancel()
+	req, e := http.NewRequestWithContext(ctx, http.MethodPost, n.Endpoint, bytes.NewReader(buf))
+	if e == nil {
+		req.Header.Set("Content-Type", "application/json")
+		if n.Token != "" {
+			req.Header.Set("Authorization", n.Token)
+		}
+		var resp *http.Response
+		if resp, e = http.DefaultClient.Do(req); e == nil {
+			resp.Body.Close()
+			if resp.StatusCode/100 != 2 {
+				e = fmt.Errorf("notification endpoint returned %s", resp.Status)
+			}
+		}
+	}
+	errorIf(probe.NewError(e).Trace(n.Endpoint), "Unable to notify `%s`.", n.Endpoint)
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openstor/madmin-go/v4"
+)
+
+func TestParseBatchLocalJob(t *testing.T) {
+	for _, tc := range []struct {
+		job     string
+		jobType madmin.BatchJobType
+	}{
+		{`replicate:
+  apiVersion: v1
+  source:
+    type: minio
+    bucket: photos
+    prefix: 2024/
+    endpoint: "https://play.min.io"
+    credentials:
+      accessKey: minioadmin
+      secretKey: minioadmin
+    snowball:
+      disable: false
+  target:
+    type: minio
+    bucket: archive
+  flags:
+    filter:
+      newerThan: "7d"
+      createdAfter: "2024-01-01T00:00:00Z"
+      tags:
+        - key: "env"
+          value: "prod"
+    retry:
+      attempts: 10
+      delay: "500ms"
+`, madmin.BatchJobReplicate},
+		{`keyrotate:
+  apiVersion: v1
+  bucket: photos
+  encryption:
+    type: sse-kms
+    key: my-key
+    context: '{"project":"photos"}'
+  flags:
+    filter:
+      kmskey: old-key
+`, madmin.BatchJobKeyRotate},
+		{`expire:
+  apiVersion: v1
+  bucket: logs
+  rules:
+    - type: object
+      name: "*.gz"
+      olderThan: 30d
+      size:
+        greaterThan: 1MiB
+    - type: deleted
+      olderThan: 7d
+      purge:
+        retainVersions: 0
+  notify:
+    endpoint: "https://notify.example.com"
+    token: "Bearer xxxxx"
+`, madmin.BatchJobExpire},
+	} {
+		_, jobType, e := parseBatchLocalJob([]byte(tc.job))
+		if e != nil {
+			t.Fatalf("%s: %v", tc.jobType, e)
+		}
+		if jobType != tc.jobType {
+			t.Errorf("expected %s, got %s", tc.jobType, jobType)
+		}
+	}
+
+	for _, job := range []string{
+		"replicate:\n  apiVersion: v2\n  source:\n    bucket: a\n  target:\n    bucket: b\n",
+		"replicate:\n  apiVersion: v1\n  unknown: true\n",
+		"replicate:\n  apiVersion: v1\n  flags:\n    filter:\n      olderThan: soon\n",
+		"",
+	} {
+		if _, _, e := parseBatchLocalJob([]byte(job)); e == nil {
+			t.Errorf("expected an error for %q", job)
+		}
+	}
+}
+
+func TestBatchFilterMatch(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	object := &ClientContent{Time: now.Add(-48 * time.Hour), Size: 10 << 10}
+	for _, tc := range []struct {
+		filter   batchJobFilter
+		rule     batchExpireRule
+		expected bool
+	}{
+		{expected: true},
+		{filter: batchJobFilter{OlderThan: "1d"}, expected: true},
+		{filter: batchJobFilter{OlderThan: "3d"}, expected: false},
+		{filter: batchJobFilter{NewerThan: "3d"}, expected: true},
+		{filter: batchJobFilter{NewerThan: "1d"}, expected: false},
+		{filter: batchJobFilter{CreatedAfter: "2024-05-29T00:00:00Z"}, expected: true},
+		{filter: batchJobFilter{CreatedBefore: "2024-05-29T00:00:00Z"}, expected: false},
+		{rule: batchExpireRule{Name: "logs/*.gz"}, expected: true},
+		{rule: batchExpireRule{Name: "*.txt"}, expected: false},
+		{rule: batchExpireRule{Size: batchJobSize{LessThan: "1MiB"}}, expected: true},
+		{rule: batchExpireRule{Size: batchJobSize{GreaterThan: "1MiB"}}, expected: false},
+	} {
+		var (
+			f batchFilter
+			e error
+		)
+		if tc.rule.Name != "" || tc.rule.Size != (batchJobSize{}) {
+			tc.rule.Type = batchExpireObject
+			var rule batchExpireRuleFilter
+			rule, e = compileBatchExpireRule(tc.rule)
+			f = rule.batchFilter
+		} else {
+			f, e = compileBatchFilter(tc.filter)
+		}
+		if e != nil {
+			t.Fatal(e)
+		}
+		if got := f.match("logs/app.gz", object, now); got != tc.expected {
+			t.Errorf("%+v %+v: expected %v, got %v", tc.filter, tc.rule, tc.expected, got)
+		}
+	}
+
+	f, _ := compileBatchFilter(batchJobFilter{
+		Tags:     []batchJobKV{{Key: "env", Value: "prod*"}},
+		Metadata: []batchJobKV{{Key: "content-type", Value: "text/*"}},
+	})
+	if !f.matchTags(map[string]string{"env": "production"}) || f.matchTags(map[string]string{"env": "dev"}) {
+		t.Error("unexpected match of tags")
+	}
+	if !f.matchMetadata(&ClientContent{Metadata: map[string]string{"Content-Type": "text/plain"}}) ||
+		f.matchMetadata(&ClientContent{Metadata: map[string]string{"Content-Type": "image/png"}}) {
+		t.Error("unexpected match of metadata")
+	}
+}
+
+func TestBatchRunLocal(t *testing.T) {
+	root := newServeTestRoot(t)
+	ctx := context.Background()
+	for _, name := range []string{"b.txt", "logs/c.log"} {
+		if e := os.MkdirAll(filepath.Dir(filepath.Join(root, "docs", name)), 0o755); e != nil {
+			t.Fatal(e)
+		}
+		if e := os.WriteFile(filepath.Join(root, "docs", name), []byte(name), 0o644); e != nil {
+			t.Fatal(e)
+		}
+	}
+	checkpoint := filepath.Join(root, "checkpoint.json")
+	run := func(job string) batchRunMessage {
+		t.Helper()
+		parsed, jobType, e := parseBatchLocalJob([]byte(job))
+		if e != nil {
+			t.Fatal(e)
+		}
+		r, err := newBatchRunner(parsed, jobType, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.checkpointFile = checkpoint
+		if err = r.loadCheckpoint(); err != nil {
+			t.Fatal(err)
+		}
+		msg, failed := r.run(ctx, 2)
+		if failed {
+			t.Fatalf("job failed: %s", msg.JSON())
+		}
+		return msg
+	}
+
+	msg := run(`replicate:
+  apiVersion: v1
+  source:
+    bucket: docs
+  target:
+    bucket: copy
+    prefix: backup
+  flags:
+    filter:
+      newerThan: 1h
+`)
+	if msg.Status != "complete" || msg.Metric.Replicate.Objects != 3 || msg.Metric.Replicate.BytesTransferred != 10+5+10 {
+		t.Fatalf("unexpected report %s", msg.JSON())
+	}
+	for _, name := range []string{"a.txt", "b.txt", "logs/c.log"} {
+		if _, e := os.Stat(filepath.Join(root, "copy", "backup", name)); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if _, e := os.Stat(checkpoint); !os.IsNotExist(e) {
+		t.Fatalf("checkpoint not removed: %v", e)
+	}
+
+	// Objects up to the checkpoint are skipped.
+	if e := os.WriteFile(checkpoint, []byte(`{"jobID":"resumed","lastObject":"b.txt","metric":{"jobID":"resumed","jobType":"expire","expired":{}}}`), 0o600); e != nil {
+		t.Fatal(e)
+	}
+	msg = run(`expire:
+  apiVersion: v1
+  bucket: docs
+  rules:
+    - type: object
+      purge:
+        retainVersions: 0
+`)
+	if msg.Metric.JobID != "resumed" || msg.Metric.RetryAttempts != 1 || msg.Metric.Expired.Objects != 1 {
+		t.Fatalf("unexpected report %s", msg.JSON())
+	}
+	for name, exists := range map[string]bool{"a.txt": true, "b.txt": true, "logs/c.log": false} {
+		if _, e := os.Stat(filepath.Join(root, "docs", name)); (e == nil) != exists {
+			t.Errorf("%s: expected exists %v, got %v", name, exists, e)
+		}
+	}
+}
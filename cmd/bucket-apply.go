@@ -0,0 +1,61 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+)
+
+var bucketApplyCmd = cli.Command{
+	Name:         "apply",
+	Usage:        "apply the desired state of buckets",
+	Action:       mainBucketApply,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(bucketStateFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} --file FILE
+
+  Missing buckets are created and only the settings which differ from the
+  document are changed, applying the same document again changes nothing.
+
+` + bucketStateHelp + `
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Apply the state described in buckets.yaml.
+     {{.Prompt}} {{.HelpName}} -f buckets.yaml
+
+  2. Apply a document generated by another tool.
+     {{.Prompt}} generate-buckets | {{.HelpName}} -f -
+`,
+}
+
+// mainBucketApply is the handle for "mc bucket apply" command.
+func mainBucketApply(ctx context.Context, cmd *cli.Command) error {
+	ctx, cancelBucketApply := context.WithCancel(globalContext)
+	defer cancelBucketApply()
+
+	return runBucketState(ctx, cmd, true)
+}
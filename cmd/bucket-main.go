@@ -0,0 +1,222 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+	json "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var bucketSubcommands = []*cli.Command{
+	&bucketPlanCmd,
+	&bucketApplyCmd,
+}
+
+var bucketCmd = cli.Command{
+	Name:            "bucket",
+	Usage:           "manage bucket configurations declaratively",
+	Action:          mainBucket,
+	Before:          setGlobalsFromContext,
+	Flags:           globalFlags,
+	Commands:        bucketSubcommands,
+	HideHelpCommand: true,
+}
+
+// mainBucket is the handle for "mc bucket" command.
+func mainBucket(ctx context.Context, cmd *cli.Command) error {
+	var subCmds []cli.Command
+	for _, c := range bucketSubcommands {
+		subCmds = append(subCmds, *c)
+	}
+	commandNotFound(ctx, cmd, subCmds)
+	return nil
+	// Sub-commands like "plan", "apply" have their own main.
+}
+
+var bucketStateFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "file",
+		Aliases: []string{"f"},
+		Usage:   "YAML document with the desired state of the buckets, '-' reads from stdin",
+	},
+}
+
+// bucketStateHelp describes the desired state document.
+const bucketStateHelp = `  The desired state document lists buckets as ALIAS/BUCKET, settings left out
+  of a bucket are not managed and keep their live value:
+
+  buckets:
+    - bucket: myminio/photos
+      region: us-east-1                  # used when creating the bucket
+      versioning: enabled                # enabled | suspended
+      objectLock:                        # enables object lock on creation
+        mode: GOVERNANCE                 # GOVERNANCE | COMPLIANCE | none
+        validity: 30d
+      encryption:
+        algorithm: sse-kms               # sse-s3 | sse-kms | none
+        kmsKey: photos-key
+      lifecycle:                         # same format as 'mc ilm rule export'
+        Rules:
+          - ID: expire-tmp
+            Status: Enabled
+            Filter: {Prefix: tmp/}
+            Expiration: {Days: 7}
+      cors:
+        - allowedOrigins: ["https://example.com"]
+          allowedMethods: [GET, PUT]
+      policy: download                   # none | download | upload | public, or a policy document
+      tags: {team: media}
+      quota: 1TiB                        # hard quota, none removes it
+      notifications:
+        - arn: arn:minio:sqs::primary:webhook
+          events: [put, delete]
+          suffix: .jpg
+
+  An empty list or map removes the setting, e.g. 'tags: {}' or 'cors: []'.
+`
+
+// bucketChangeMessage container for a planned or applied bucket change.
+type bucketChangeMessage struct {
+	Status string `json:"status"`
+	bucketChange
+}
+
+func (m bucketChangeMessage) String() string {
+	var sign, color, text string
+	switch m.Action {
+	case bucketActionCreate:
+		sign, color, text = "+", "BucketCreate", "create bucket"
+		if m.To != "" {
+			text += " in region `" + m.To + "`"
+		}
+	case bucketActionSet:
+		sign, color, text = "+", "BucketCreate", "set "+m.Setting+" to `"+lineTrunc(m.To, 60)+"`"
+	case bucketActionUpdate:
+		sign, color, text = "~", "BucketUpdate", "update "+m.Setting+" from `"+lineTrunc(m.From, 40)+"` to `"+lineTrunc(m.To, 40)+"`"
+	case bucketActionRemove:
+		sign, color, text = "-", "BucketRemove", "remove "+m.Setting
+	}
+	return console.Colorize(color, sign+" "+m.Bucket+": "+text)
+}
+
+func (m bucketChangeMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// bucketSummaryMessage container for the summary of a plan or apply.
+type bucketSummaryMessage struct {
+	op      string
+	Status  string `json:"status"`
+	Buckets int    `json:"buckets"`
+	Changes int    `json:"changes"`
+	Failed  int    `json:"failed,omitempty"`
+}
+
+func (m bucketSummaryMessage) String() string {
+	switch {
+	case m.Changes == 0 && m.Failed == 0:
+		return console.Colorize("BucketSummary", "No changes, all buckets match the desired state.")
+	case m.op == "plan":
+		return console.Colorize("BucketSummary", fmt.Sprintf("Plan: %d change(s) in %d bucket(s), %d bucket(s) failed.", m.Changes, m.Buckets, m.Failed))
+	default:
+		return console.Colorize("BucketSummary", fmt.Sprintf("Applied %d change(s) to %d bucket(s), %d bucket(s) failed.", m.Changes, m.Buckets, m.Failed))
+	}
+}
+
+func (m bucketSummaryMessage) JSON() string {
+	m.Status = "success"
+	if m.Failed > 0 {
+		m.Status = "error"
+	}
+	jsonMessageBytes, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+func setBucketStateColors() {
+	console.SetColor("BucketCreate", color.New(color.FgGreen))
+	console.SetColor("BucketUpdate", color.New(color.FgYellow))
+	console.SetColor("BucketRemove", color.New(color.FgRed))
+	console.SetColor("BucketSummary", color.New(color.Bold))
+}
+
+// checkBucketStateSyntax - validate all the passed arguments
+func checkBucketStateSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 0 || cmd.String("file") == "" {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+}
+
+// runBucketState plans every bucket of the document and applies the
+// changes when apply is true. Buckets are handled one after the other,
+// a failing bucket does not stop the others.
+func runBucketState(ctx context.Context, cmd *cli.Command, apply bool) error {
+	checkBucketStateSyntax(ctx, cmd)
+	setBucketStateColors()
+
+	doc, err := readBucketStateDoc(cmd.String("file"))
+	fatalIf(err.Trace(cmd.String("file")), "Unable to read the desired state of the buckets.")
+
+	op := "plan"
+	if apply {
+		op = "apply"
+	}
+	summary := bucketSummaryMessage{op: op}
+	for _, spec := range doc.Buckets {
+		plan, err := planBucket(ctx, spec)
+		if err != nil {
+			errorIf(err, "Unable to compare `%s` with its desired state.", spec.Bucket)
+			summary.Failed++
+			continue
+		}
+		if len(plan.changes) == 0 {
+			continue
+		}
+		changes := plan.changes
+		if apply {
+			changes, err = plan.apply(ctx)
+		}
+		// Changes applied before a failure are reported as well.
+		for _, change := range changes {
+			printMsg(bucketChangeMessage{bucketChange: change})
+		}
+		summary.Changes += len(changes)
+		if err != nil {
+			errorIf(err, "Unable to apply the desired state of `%s`, %d of %d changes applied.",
+				spec.Bucket, len(changes), len(plan.changes))
+			summary.Failed++
+			continue
+		}
+		summary.Buckets++
+	}
+	printMsg(summary)
+
+	if summary.Failed > 0 {
+		return exitStatus(globalErrorExitStatus)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+)
+
+var bucketPlanCmd = cli.Command{
+	Name:         "plan",
+	Usage:        "show the changes needed to reach the desired state of buckets",
+	Action:       mainBucketPlan,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(bucketStateFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} --file FILE
+
+  Nothing is changed, the live configuration of every bucket is compared
+  with the document and the differences are printed.
+
+` + bucketStateHelp + `
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Show the changes needed to reach the state described in buckets.yaml.
+     {{.Prompt}} {{.HelpName}} -f buckets.yaml
+
+  2. Show the changes as JSON lines.
+     {{.Prompt}} {{.HelpName}} -f buckets.yaml --json
+`,
+}
+
+// mainBucketPlan is the handle for "mc bucket plan" command.
+func mainBucketPlan(ctx context.Context, cmd *cli.Command) error {
+	ctx, cancelBucketPlan := context.WithCancel(globalContext)
+	defer cancelBucketPlan()
+
+	return runBucketState(ctx, cmd, false)
+}
@@ -0,0 +1,783 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/openstor/madmin-go/v4"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/openstor-go/v7"
+	"github.com/openstor/openstor-go/v7/pkg/cors"
+	"github.com/openstor/openstor-go/v7/pkg/lifecycle"
+	"gopkg.in/yaml.v2"
+)
+
+// bucketStateDoc - desired state of buckets read by 'mc bucket plan'
+// and 'mc bucket apply'. Settings left out of a bucket are not managed.
+type bucketStateDoc struct {
+	Buckets []bucketSpec `yaml:"buckets"`
+}
+
+// bucketSpec - desired state of a single bucket.
+type bucketSpec struct {
+	Bucket        string                   `yaml:"bucket"`
+	Region        string                   `yaml:"region,omitempty"`
+	Versioning    string                   `yaml:"versioning,omitempty"`
+	ObjectLock    *bucketLockSpec          `yaml:"objectLock,omitempty"`
+	Encryption    *bucketEncryptionSpec    `yaml:"encryption,omitempty"`
+	Lifecycle     interface{}              `yaml:"lifecycle,omitempty"`
+	CORS          []bucketCorsRuleSpec     `yaml:"cors,omitempty"`
+	Policy        interface{}              `yaml:"policy,omitempty"`
+	Tags          map[string]string        `yaml:"tags,omitempty"`
+	Quota         string                   `yaml:"quota,omitempty"`
+	Notifications []bucketNotificationSpec `yaml:"notifications,omitempty"`
+}
+
+// bucketLockSpec - default retention of a bucket with object lock.
+type bucketLockSpec struct {
+	Mode     string `yaml:"mode,omitempty"`
+	Validity string `yaml:"validity,omitempty"`
+}
+
+// bucketEncryptionSpec - default encryption of a bucket.
+type bucketEncryptionSpec struct {
+	Algorithm string `yaml:"algorithm"`
+	KMSKey    string `yaml:"kmsKey,omitempty"`
+}
+
+// bucketCorsRuleSpec - a CORS rule of a bucket.
+type bucketCorsRuleSpec struct {
+	ID             string   `yaml:"id,omitempty"`
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+	AllowedMethods []string `yaml:"allowedMethods"`
+	AllowedHeaders []string `yaml:"allowedHeaders,omitempty"`
+	ExposeHeaders  []string `yaml:"exposeHeaders,omitempty"`
+	MaxAgeSeconds  int      `yaml:"maxAgeSeconds,omitempty"`
+}
+
+// bucketNotificationSpec - a notification target of a bucket, events
+// are named the same way as for 'mc event add'.
+type bucketNotificationSpec struct {
+	ARN    string   `yaml:"arn"`
+	Events []string `yaml:"events"`
+	Prefix string   `yaml:"prefix,omitempty"`
+	Suffix string   `yaml:"suffix,omitempty"`
+}
+
+// bucketChange - difference between the desired and the live state.
+type bucketChange struct {
+	Bucket  string `json:"bucket"`
+	Setting string `json:"setting"`
+	Action  string `json:"action"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+}
+
+// Actions of a bucket change.
+const (
+	bucketActionCreate = "create"
+	bucketActionSet    = "set"
+	bucketActionUpdate = "update"
+	bucketActionRemove = "remove"
+)
+
+// bucketSetting - a managed setting of a bucket. Values are normalized
+// so that equal configurations compare equal, an empty value means the
+// setting is absent.
+type bucketSetting struct {
+	name    string
+	desired string
+	live    func(ctx context.Context) (string, *probe.Error)
+	apply   func(ctx context.Context) *probe.Error
+}
+
+// readBucketStateDoc reads and validates a desired state document.
+func readBucketStateDoc(file string) (*bucketStateDoc, *probe.Error) {
+	var data []byte
+	var e error
+	if file == "-" {
+		data, e = io.ReadAll(os.Stdin)
+	} else {
+		data, e = os.ReadFile(file)
+	}
+	if e != nil {
+		return nil, probe.NewError(e).Trace(file)
+	}
+	return parseBucketStateDoc(data)
+}
+
+// parseBucketStateDoc parses a desired state document.
+func parseBucketStateDoc(data []byte) (*bucketStateDoc, *probe.Error) {
+	doc := &bucketStateDoc{}
+	if e := yaml.UnmarshalStrict(data, doc); e != nil {
+		return nil, probe.NewError(e)
+	}
+	seen := make(map[string]bool)
+	for i := range doc.Buckets {
+		spec := &doc.Buckets[i]
+		spec.Bucket = strings.TrimSuffix(spec.Bucket, "/")
+		alias, bucket := url2Alias(spec.Bucket)
+		if alias == "" || bucket == "" || strings.Contains(bucket, "/") {
+			return nil, errInvalidArgument().Trace(spec.Bucket)
+		}
+		if seen[spec.Bucket] {
+			return nil, probe.NewError(fmt.Errorf("bucket %s is listed more than once", spec.Bucket))
+		}
+		seen[spec.Bucket] = true
+	}
+	return doc, nil
+}
+
+// errInvalidBucketSetting reports an unsupported value in the document.
+func errInvalidBucketSetting(bucket, setting, value string) *probe.Error {
+	return probe.NewError(fmt.Errorf("invalid %s `%s` for %s", setting, value, bucket))
+}
+
+// yamlToJSON converts a document fragment to JSON, strings are
+// expected to already hold JSON.
+func yamlToJSON(v interface{}) ([]byte, error) {
+	if s, ok := v.(string); ok {
+		return []byte(strings.TrimSpace(s)), nil
+	}
+	return json.Marshal(convertYAMLValue(v))
+}
+
+func convertYAMLValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = convertYAMLValue(v)
+		}
+		return m
+	case []interface{}:
+		for i := range t {
+			t[i] = convertYAMLValue(t[i])
+		}
+		return t
+	}
+	return v
+}
+
+// canonicalJSON returns the compact JSON of data with sorted keys.
+func canonicalJSON(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", nil
+	}
+	var v interface{}
+	if e := json.Unmarshal(data, &v); e != nil {
+		return "", e
+	}
+	if m, ok := v.(map[string]interface{}); ok && len(m) == 0 {
+		return "", nil
+	}
+	buf, e := json.Marshal(v)
+	return string(buf), e
+}
+
+// isBucketConfigNotFound returns true if err reports a bucket
+// sub-resource which was never configured.
+func isBucketConfigNotFound(err *probe.Error) bool {
+	switch openstor.ToErrorResponse(err.ToGoError()).Code {
+	case "NoSuchLifecycleConfiguration", "ServerSideEncryptionConfigurationNotFoundError",
+		"NoSuchCORSConfiguration", "NoSuchTagSet", "ObjectLockConfigurationNotFoundError",
+		"NoSuchBucketPolicy":
+		return true
+	}
+	return false
+}
+
+// versioningState normalizes a versioning status.
+func versioningState(status string) string {
+	return strings.ToLower(status)
+}
+
+// lockState normalizes the default retention of a bucket.
+func lockState(mode openstor.RetentionMode, validity uint64, unit openstor.ValidityUnit) string {
+	if mode == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s %d%s", strings.ToUpper(string(mode)), validity, unit)
+}
+
+// encryptionState normalizes the default encryption of a bucket.
+func encryptionState(algorithm, keyID string) string {
+	switch strings.ToLower(algorithm) {
+	case "aes256", "sse-s3":
+		return "sse-s3"
+	case "aws:kms", "sse-kms":
+		if keyID != "" {
+			return "sse-kms " + keyID
+		}
+		return "sse-kms"
+	}
+	return ""
+}
+
+// lifecycleState normalizes a lifecycle configuration. Rule IDs are
+// ignored when withIDs is false, servers generate missing ones.
+func lifecycleState(cfg *lifecycle.Configuration, withIDs bool) (string, error) {
+	if cfg == nil || len(cfg.Rules) == 0 {
+		return "", nil
+	}
+	rules := append([]lifecycle.Rule{}, cfg.Rules...)
+	if !withIDs {
+		for i := range rules {
+			rules[i].ID = ""
+		}
+	}
+	buf, e := json.Marshal(lifecycle.Configuration{Rules: rules})
+	return string(buf), e
+}
+
+// corsState normalizes CORS rules.
+func corsState(rules []cors.Rule) (string, error) {
+	if len(rules) == 0 {
+		return "", nil
+	}
+	buf, e := cors.NewConfig(rules).ToXML()
+	return string(buf), e
+}
+
+// tagsState normalizes bucket tags in the format accepted by SetTags.
+func tagsState(tags map[string]string) string {
+	values := make(url.Values, len(tags))
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// quotaState normalizes a hard quota.
+func quotaState(size uint64) string {
+	if size == 0 {
+		return ""
+	}
+	return humanize.IBytes(size)
+}
+
+// notificationLine normalizes a notification target.
+func notificationLine(config NotificationConfig) string {
+	events := append([]string{}, config.Events...)
+	sort.Strings(events)
+	return fmt.Sprintf("%s %s prefix=%s suffix=%s",
+		config.Arn, strings.Join(events, ","), config.Prefix, config.Suffix)
+}
+
+// notificationState normalizes notification targets, one per line.
+func notificationState(configs []NotificationConfig) string {
+	lines := make([]string, 0, len(configs))
+	for _, config := range configs {
+		lines = append(lines, notificationLine(config))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// diffNotifications returns the live targets missing from desired, and
+// the indexes of the desired targets missing from live.
+func diffNotifications(live, desired []NotificationConfig) (removed []NotificationConfig, added []int) {
+	desiredLines := make(map[string]bool, len(desired))
+	for _, config := range desired {
+		desiredLines[notificationLine(config)] = true
+	}
+	liveLines := make(map[string]bool, len(live))
+	for _, config := range live {
+		line := notificationLine(config)
+		liveLines[line] = true
+		if !desiredLines[line] {
+			removed = append(removed, config)
+		}
+	}
+	for i, config := range desired {
+		if !liveLines[notificationLine(config)] {
+			added = append(added, i)
+		}
+	}
+	return removed, added
+}
+
+// diffBucketSetting returns the change needed to go from live to desired.
+func diffBucketSetting(bucket, name, live, desired string) (bucketChange, bool) {
+	if live == desired {
+		return bucketChange{}, false
+	}
+	change := bucketChange{Bucket: bucket, Setting: name, From: live, To: desired}
+	switch {
+	case live == "":
+		change.Action = bucketActionSet
+	case desired == "":
+		change.Action = bucketActionRemove
+	default:
+		change.Action = bucketActionUpdate
+	}
+	return change, true
+}
+
+// bucketSettings returns the settings managed by spec, in the order
+// they are applied.
+func bucketSettings(spec bucketSpec, clnt Client) ([]bucketSetting, *probe.Error) {
+	var settings []bucketSetting
+	bucket := spec.Bucket
+
+	if spec.Versioning != "" {
+		desired := versioningState(spec.Versioning)
+		var status string
+		switch desired {
+		case "enabled":
+			status = "enable"
+		case "suspended":
+			status = "suspend"
+		default:
+			return nil, errInvalidBucketSetting(bucket, "versioning", spec.Versioning)
+		}
+		settings = append(settings, bucketSetting{
+			name:    "versioning",
+			desired: desired,
+			live: func(ctx context.Context) (string, *probe.Error) {
+				cfg, err := clnt.GetVersion(ctx)
+				if err != nil {
+					return "", err
+				}
+				return versioningState(cfg.Status), nil
+			},
+			apply: func(ctx context.Context) *probe.Error {
+				return clnt.SetVersion(ctx, status, nil, false)
+			},
+		})
+	}
+
+	if spec.ObjectLock != nil {
+		var mode openstor.RetentionMode
+		var validity uint64
+		var unit openstor.ValidityUnit
+		if spec.ObjectLock.Mode != "" && !strings.EqualFold(spec.ObjectLock.Mode, "none") {
+			mode = openstor.RetentionMode(strings.ToUpper(spec.ObjectLock.Mode))
+			if !mode.IsValid() || spec.ObjectLock.Validity == "" {
+				return nil, errInvalidBucketSetting(bucket, "objectLock", spec.ObjectLock.Mode)
+			}
+			var err *probe.Error
+			validity, unit, err = parseRetentionValidity(spec.ObjectLock.Validity)
+			if err != nil {
+				return nil, err.Trace(bucket, "objectLock")
+			}
+		}
+		settings = append(settings, bucketSetting{
+			name:    "object-lock",
+			desired: lockState(mode, validity, unit),
+			live: func(ctx context.Context) (string, *probe.Error) {
+				_, mode, validity, unit, err := clnt.GetObjectLockConfig(ctx)
+				if err != nil {
+					if isBucketConfigNotFound(err) {
+						return "", nil
+					}
+					return "", err
+				}
+				return lockState(mode, validity, unit), nil
+			},
+			apply: func(ctx context.Context) *probe.Error {
+				return clnt.SetObjectLockConfig(ctx, mode, validity, unit)
+			},
+		})
+	}
+
+	if spec.Encryption != nil {
+		algorithm := strings.ToLower(spec.Encryption.Algorithm)
+		switch algorithm {
+		case "sse-s3", "sse-kms", "none":
+		default:
+			return nil, errInvalidBucketSetting(bucket, "encryption", spec.Encryption.Algorithm)
+		}
+		settings = append(settings, bucketSetting{
+			name:    "encryption",
+			desired: encryptionState(algorithm, spec.Encryption.KMSKey),
+			live: func(ctx context.Context) (string, *probe.Error) {
+				algorithm, keyID, err := clnt.GetEncryption(ctx)
+				if err != nil {
+					if isBucketConfigNotFound(err) {
+						return "", nil
+					}
+					return "", err
+				}
+				return encryptionState(algorithm, keyID), nil
+			},
+			apply: func(ctx context.Context) *probe.Error {
+				if algorithm == "none" {
+					return clnt.DeleteEncryption(ctx)
+				}
+				return clnt.SetEncryption(ctx, algorithm, spec.Encryption.KMSKey)
+			},
+		})
+	}
+
+	if spec.Lifecycle != nil {
+		data, e := yamlToJSON(spec.Lifecycle)
+		if e != nil {
+			return nil, probe.NewError(e).Trace(bucket, "lifecycle")
+		}
+		cfg := lifecycle.NewConfiguration()
+		if len(data) > 0 {
+			if e = json.Unmarshal(data, cfg); e != nil {
+				return nil, probe.NewError(e).Trace(bucket, "lifecycle")
+			}
+		}
+		withIDs := true
+		for _, rule := range cfg.Rules {
+			withIDs = withIDs && rule.ID != ""
+		}
+		desired, e := lifecycleState(cfg, withIDs)
+		if e != nil {
+			return nil, probe.NewError(e).Trace(bucket, "lifecycle")
+		}
+		settings = append(settings, bucketSetting{
+			name:    "lifecycle",
+			desired: desired,
+			live: func(ctx context.Context) (string, *probe.Error) {
+				live, _, err := clnt.GetLifecycle(ctx)
+				if err != nil {
+					if isBucketConfigNotFound(err) {
+						return "", nil
+					}
+					return "", err
+				}
+				state, e := lifecycleState(live, withIDs)
+				return state, probe.NewError(e)
+			},
+			apply: func(ctx context.Context) *probe.Error {
+				return clnt.SetLifecycle(ctx, cfg)
+			},
+		})
+	}
+
+	if spec.CORS != nil {
+		rules := make([]cors.Rule, 0, len(spec.CORS))
+		for _, r := range spec.CORS {
+			rules = append(rules, cors.Rule{
+				ID:            r.ID,
+				AllowedOrigin: r.AllowedOrigins,
+				AllowedMethod: r.AllowedMethods,
+				AllowedHeader: r.AllowedHeaders,
+				ExposeHeader:  r.ExposeHeaders,
+				MaxAgeSeconds: r.MaxAgeSeconds,
+			})
+		}
+		desired, e := corsState(rules)
+		if e != nil {
+			return nil, probe.NewError(e).Trace(bucket, "cors")
+		}
+		settings = append(settings, bucketSetting{
+			name:    "cors",
+			desired: desired,
+			live: func(ctx context.Context) (string, *probe.Error) {
+				cfg, err := clnt.GetBucketCors(ctx)
+				if err != nil {
+					if isBucketConfigNotFound(err) {
+						return "", nil
+					}
+					return "", err
+				}
+				if cfg == nil {
+					return "", nil
+				}
+				state, e := corsState(cfg.CORSRules)
+				return state, probe.NewError(e)
+			},
+			apply: func(ctx context.Context) *probe.Error {
+				if desired == "" {
+					return clnt.DeleteBucketCors(ctx)
+				}
+				return clnt.SetBucketCors(ctx, []byte(desired))
+			},
+		})
+	}
+
+	if spec.Policy != nil {
+		setting, err := bucketPolicySetting(bucket, spec.Policy, clnt)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, setting)
+	}
+
+	if spec.Tags != nil {
+		desired := tagsState(spec.Tags)
+		settings = append(settings, bucketSetting{
+			name:    "tags",
+			desired: desired,
+			live: func(ctx context.Context) (string, *probe.Error) {
+				tags, err := clnt.GetTags(ctx, "")
+				if err != nil {
+					if isBucketConfigNotFound(err) {
+						return "", nil
+					}
+					return "", err
+				}
+				return tagsState(tags), nil
+			},
+			apply: func(ctx context.Context) *probe.Error {
+				if desired == "" {
+					return clnt.DeleteTags(ctx, "")
+				}
+				return clnt.SetTags(ctx, "", desired)
+			},
+		})
+	}
+
+	if spec.Quota != "" {
+		var size uint64
+		if !strings.EqualFold(spec.Quota, "none") {
+			var e error
+			if size, e = humanize.ParseBytes(spec.Quota); e != nil {
+				return nil, probe.NewError(e).Trace(bucket, "quota")
+			}
+		}
+		_, bucketName := url2Alias(bucket)
+		settings = append(settings, bucketSetting{
+			name:    "quota",
+			desired: quotaState(size),
+			live: func(ctx context.Context) (string, *probe.Error) {
+				admClnt, err := newAdminClient(bucket)
+				if err != nil {
+					return "", err
+				}
+				q, e := admClnt.GetBucketQuota(ctx, bucketName)
+				if e != nil {
+					return "", probe.NewError(e)
+				}
+				return quotaState(q.Size), nil
+			},
+			apply: func(ctx context.Context) *probe.Error {
+				admClnt, err := newAdminClient(bucket)
+				if err != nil {
+					return err
+				}
+				quota := &madmin.BucketQuota{}
+				if size > 0 {
+					quota = &madmin.BucketQuota{Size: size, Type: madmin.HardQuota}
+				}
+				return probe.NewError(admClnt.SetBucketQuota(ctx, bucketName, quota))
+			},
+		})
+	}
+
+	if spec.Notifications != nil {
+		configs := make([]NotificationConfig, 0, len(spec.Notifications))
+		for _, n := range spec.Notifications {
+			config := NotificationConfig{Arn: n.ARN, Prefix: n.Prefix, Suffix: n.Suffix}
+			for _, event := range n.Events {
+				eventTypes, ok := notificationEventTypes(event)
+				if !ok {
+					return nil, errInvalidBucketSetting(bucket, "notifications", event)
+				}
+				for _, eventType := range eventTypes {
+					config.Events = append(config.Events, string(eventType))
+				}
+			}
+			configs = append(configs, config)
+		}
+		s3Clnt, ok := clnt.(*S3Client)
+		if !ok {
+			return nil, probe.NewError(fmt.Errorf("notifications of %s need an S3 server", bucket))
+		}
+		settings = append(settings, bucketSetting{
+			name:    "notifications",
+			desired: notificationState(configs),
+			live: func(ctx context.Context) (string, *probe.Error) {
+				configs, err := s3Clnt.ListNotificationConfigs(ctx, "")
+				if err != nil {
+					return "", err
+				}
+				return notificationState(configs), nil
+			},
+			apply: func(ctx context.Context) *probe.Error {
+				// Only targets which differ are changed, a failure
+				// leaves the unchanged targets in place.
+				live, err := s3Clnt.ListNotificationConfigs(ctx, "")
+				if err != nil {
+					return err
+				}
+				removed, added := diffNotifications(live, configs)
+				for _, config := range removed {
+					if err := s3Clnt.removeNotificationConfigExact(ctx, config); err != nil {
+						return err
+					}
+				}
+				for _, i := range added {
+					n := spec.Notifications[i]
+					if err := s3Clnt.AddNotificationConfig(ctx, n.ARN, n.Events, n.Prefix, n.Suffix, false); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	}
+
+	return settings, nil
+}
+
+// cannedPolicies lists the policies accepted by 'mc anonymous set'.
+var cannedPolicies = []string{"none", "download", "upload", "public"}
+
+// bucketPolicySetting returns the anonymous access setting, either one
+// of the canned policies or a custom policy document.
+func bucketPolicySetting(bucket string, policy interface{}, clnt Client) (bucketSetting, *probe.Error) {
+	if name, ok := policy.(string); ok && !strings.HasPrefix(strings.TrimSpace(name), "{") {
+		name = strings.ToLower(name)
+		found := false
+		for _, canned := range cannedPolicies {
+			found = found || name == canned
+		}
+		if !found {
+			return bucketSetting{}, errInvalidBucketSetting(bucket, "policy", name)
+		}
+		desired := name
+		if name == "none" {
+			desired = ""
+		}
+		return bucketSetting{
+			name:    "policy",
+			desired: desired,
+			live: func(ctx context.Context) (string, *probe.Error) {
+				access, _, err := clnt.GetAccess(ctx)
+				if err != nil {
+					return "", err
+				}
+				if access == "none" {
+					return "", nil
+				}
+				return access, nil
+			},
+			apply: func(ctx context.Context) *probe.Error {
+				return clnt.SetAccess(ctx, name, false)
+			},
+		}, nil
+	}
+
+	data, e := yamlToJSON(policy)
+	if e != nil {
+		return bucketSetting{}, probe.NewError(e).Trace(bucket, "policy")
+	}
+	desired, e := canonicalJSON(data)
+	if e != nil {
+		return bucketSetting{}, probe.NewError(e).Trace(bucket, "policy")
+	}
+	return bucketSetting{
+		name:    "policy",
+		desired: desired,
+		live: func(ctx context.Context) (string, *probe.Error) {
+			_, policyJSON, err := clnt.GetAccess(ctx)
+			if err != nil {
+				return "", err
+			}
+			state, e := canonicalJSON([]byte(policyJSON))
+			return state, probe.NewError(e)
+		},
+		apply: func(ctx context.Context) *probe.Error {
+			if desired == "" {
+				return clnt.SetAccess(ctx, "none", false)
+			}
+			return clnt.SetAccess(ctx, desired, true)
+		},
+	}, nil
+}
+
+// bucketPlan - changes needed by a single bucket.
+type bucketPlan struct {
+	spec     bucketSpec
+	create   bool
+	changes  []bucketChange
+	settings []bucketSetting
+}
+
+// planBucket compares the desired state of a bucket with its live state.
+func planBucket(ctx context.Context, spec bucketSpec) (*bucketPlan, *probe.Error) {
+	alias, _ := url2Alias(spec.Bucket)
+	if mustGetHostConfig(alias) == nil {
+		return nil, errNoMatchingHost(alias).Trace(spec.Bucket)
+	}
+	clnt, err := newClient(spec.Bucket)
+	if err != nil {
+		return nil, err.Trace(spec.Bucket)
+	}
+	settings, err := bucketSettings(spec, clnt)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &bucketPlan{spec: spec}
+	if _, err = clnt.Stat(ctx, StatOptions{}); err != nil {
+		if _, ok := err.ToGoError().(BucketDoesNotExist); !ok {
+			return nil, err.Trace(spec.Bucket)
+		}
+		plan.create = true
+		plan.changes = append(plan.changes, bucketChange{
+			Bucket:  spec.Bucket,
+			Setting: "bucket",
+			Action:  bucketActionCreate,
+			To:      spec.Region,
+		})
+	}
+
+	for _, setting := range settings {
+		live := ""
+		if !plan.create {
+			if live, err = setting.live(ctx); err != nil {
+				return nil, err.Trace(spec.Bucket, setting.name)
+			}
+		}
+		if change, ok := diffBucketSetting(spec.Bucket, setting.name, live, setting.desired); ok {
+			plan.changes = append(plan.changes, change)
+			plan.settings = append(plan.settings, setting)
+		}
+	}
+	return plan, nil
+}
+
+// apply makes the live state of the bucket match the plan. It returns
+// the changes applied, which are all of them unless an error occurred.
+func (p *bucketPlan) apply(ctx context.Context) ([]bucketChange, *probe.Error) {
+	clnt, err := newClient(p.spec.Bucket)
+	if err != nil {
+		return nil, err.Trace(p.spec.Bucket)
+	}
+	applied := 0
+	if p.create {
+		withLock := p.spec.ObjectLock != nil
+		if err = clnt.MakeBucket(ctx, p.spec.Region, true, withLock); err != nil {
+			return nil, err.Trace(p.spec.Bucket)
+		}
+		applied++
+	}
+	for _, setting := range p.settings {
+		if err = setting.apply(ctx); err != nil {
+			return p.changes[:applied], err.Trace(p.spec.Bucket, setting.name)
+		}
+		applied++
+	}
+	return p.changes, nil
+}
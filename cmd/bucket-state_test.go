@@ -0,0 +1,223 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/openstor/mc/pkg/probe"
+)
+
+func TestParseBucketStateDoc(t *testing.T) {
+	doc, err := parseBucketStateDoc([]byte(`
+buckets:
+  - bucket: myminio/photos/
+    versioning: enabled
+    lifecycle:
+      Rules:
+        - ID: expire-tmp
+          Status: Enabled
+          Filter: {Prefix: tmp/}
+          Expiration: {Days: 7}
+    cors:
+      - allowedOrigins: ["https://example.com"]
+        allowedMethods: [GET]
+    policy: download
+    tags: {team: media, env: prod}
+    quota: 1GiB
+    notifications:
+      - arn: arn:minio:sqs::primary:webhook
+        events: [put, delete]
+  - bucket: myminio/logs
+    tags: {}
+    cors: []
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Buckets) != 2 || doc.Buckets[0].Bucket != "myminio/photos" {
+		t.Fatalf("unexpected buckets %+v", doc.Buckets)
+	}
+
+	settings, err := bucketSettings(doc.Buckets[0], nil)
+	if err == nil {
+		t.Fatal("expected an error, notifications need an S3 client")
+	}
+	spec := doc.Buckets[0]
+	spec.Notifications = nil
+	if settings, err = bucketSettings(spec, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"versioning": "enabled",
+		"lifecycle":  `{"Rules":[{"Expiration":{"Days":7},"ID":"expire-tmp","Filter":{"Prefix":"tmp/"},"Status":"Enabled"}]}`,
+		"policy":     "download",
+		"tags":       "env=prod&team=media",
+		"quota":      "1.0 GiB",
+	}
+	for _, setting := range settings {
+		if want, ok := expected[setting.name]; ok && setting.desired != want {
+			t.Errorf("%s: expected %q, got %q", setting.name, want, setting.desired)
+		}
+	}
+	if len(settings) != 6 {
+		t.Errorf("expected 6 settings, got %d", len(settings))
+	}
+
+	// Empty collections remove the setting.
+	if settings, err = bucketSettings(doc.Buckets[1], nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(settings) != 2 || settings[0].desired != "" || settings[1].desired != "" {
+		t.Fatalf("unexpected settings %+v", settings)
+	}
+
+	for _, bad := range []string{
+		"buckets:\n  - bucket: photos\n",
+		"buckets:\n  - bucket: myminio/photos/dir\n",
+		"buckets:\n  - bucket: myminio/a\n  - bucket: myminio/a\n",
+		"buckets:\n  - bucket: myminio/a\n    unknown: true\n",
+	} {
+		if _, err := parseBucketStateDoc([]byte(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestDiffBucketSetting(t *testing.T) {
+	testCases := []struct {
+		live, desired string
+		action        string
+	}{
+		{"", "", ""},
+		{"enabled", "enabled", ""},
+		{"", "enabled", bucketActionSet},
+		{"enabled", "", bucketActionRemove},
+		{"suspended", "enabled", bucketActionUpdate},
+	}
+	for _, testCase := range testCases {
+		change, ok := diffBucketSetting("myminio/photos", "versioning", testCase.live, testCase.desired)
+		if ok != (testCase.action != "") || change.Action != testCase.action {
+			t.Errorf("%q -> %q: expected %q, got %q", testCase.live, testCase.desired, testCase.action, change.Action)
+		}
+	}
+}
+
+func TestBucketStateNormalization(t *testing.T) {
+	if encryptionState("AES256", "") != encryptionState("sse-s3", "") {
+		t.Error("expected AES256 and sse-s3 to match")
+	}
+	if encryptionState("aws:kms", "key") != encryptionState("sse-kms", "key") {
+		t.Error("expected aws:kms and sse-kms to match")
+	}
+	a, _ := canonicalJSON([]byte(`{"b": 1, "a": [1, 2]}`))
+	b, _ := canonicalJSON([]byte(`{"a":[1,2],"b":1}`))
+	if a != b {
+		t.Errorf("expected %q and %q to match", a, b)
+	}
+	live := notificationState([]NotificationConfig{
+		{Arn: "arn:b", Events: []string{"s3:ObjectRemoved:*", "s3:ObjectCreated:*"}},
+		{Arn: "arn:a", Events: []string{"s3:ObjectAccessed:*"}, Prefix: "p/"},
+	})
+	desired := notificationState([]NotificationConfig{
+		{Arn: "arn:a", Events: []string{"s3:ObjectAccessed:*"}, Prefix: "p/"},
+		{Arn: "arn:b", Events: []string{"s3:ObjectCreated:*", "s3:ObjectRemoved:*"}},
+	})
+	if live != desired {
+		t.Errorf("expected %q and %q to match", live, desired)
+	}
+}
+
+func TestDiffNotifications(t *testing.T) {
+	kept := NotificationConfig{ID: "1", Arn: "arn:minio:sqs::1:webhook", Events: []string{"s3:ObjectCreated:*", "s3:ObjectRemoved:*"}, Prefix: "photos/"}
+	changed := NotificationConfig{ID: "2", Arn: "arn:minio:sqs::2:webhook", Events: []string{"s3:ObjectCreated:*"}}
+	live := []NotificationConfig{kept, changed}
+	desired := []NotificationConfig{
+		{Arn: kept.Arn, Events: []string{"s3:ObjectRemoved:*", "s3:ObjectCreated:*"}, Prefix: "photos/"},
+		{Arn: changed.Arn, Events: []string{"s3:ObjectCreated:*"}, Suffix: ".jpg"},
+		{Arn: "arn:minio:sqs::3:webhook", Events: []string{"s3:ObjectAccessed:*"}},
+	}
+
+	removed, added := diffNotifications(live, desired)
+	if !reflect.DeepEqual(removed, []NotificationConfig{changed}) {
+		t.Errorf("expected only the changed target to be removed, got %+v", removed)
+	}
+	if !reflect.DeepEqual(added, []int{1, 2}) {
+		t.Errorf("expected the changed and the new targets to be added, got %v", added)
+	}
+
+	if removed, added = diffNotifications(live, live); len(removed) != 0 || len(added) != 0 {
+		t.Errorf("expected no changes, got %+v and %v", removed, added)
+	}
+}
+
+func TestBucketPlanApplyPartial(t *testing.T) {
+	saved := loadMcConfig
+	loadMcConfig = func() (*configV11, *probe.Error) { return newMcConfig(), nil }
+	t.Cleanup(func() { loadMcConfig = saved })
+
+	var appliedSettings []string
+	setting := func(name string, err *probe.Error) bucketSetting {
+		return bucketSetting{
+			name: name,
+			apply: func(context.Context) *probe.Error {
+				if err == nil {
+					appliedSettings = append(appliedSettings, name)
+				}
+				return err
+			},
+		}
+	}
+	bucket := t.TempDir()
+	plan := &bucketPlan{
+		spec: bucketSpec{Bucket: bucket},
+		changes: []bucketChange{
+			{Bucket: bucket, Setting: "versioning", Action: bucketActionSet},
+			{Bucket: bucket, Setting: "tags", Action: bucketActionUpdate},
+			{Bucket: bucket, Setting: "quota", Action: bucketActionSet},
+		},
+		settings: []bucketSetting{
+			setting("versioning", nil),
+			setting("tags", probe.NewError(errors.New("access denied"))),
+			setting("quota", nil),
+		},
+	}
+
+	changes, err := plan.apply(context.Background())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !reflect.DeepEqual(changes, plan.changes[:1]) {
+		t.Errorf("expected only the versioning change to be reported, got %+v", changes)
+	}
+	if !reflect.DeepEqual(appliedSettings, []string{"versioning"}) {
+		t.Errorf("expected settings after the failure to be skipped, got %v", appliedSettings)
+	}
+
+	plan.settings[1] = setting("tags", nil)
+	appliedSettings = nil
+	if changes, err = plan.apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes, plan.changes) {
+		t.Errorf("expected all changes to be reported, got %+v", changes)
+	}
+}
@@ -47,6 +47,10 @@ var catFlags = []cli.Flag{
 		Name:  "zip",
 		Usage: "extract from remote zip file (MinIO server source only)",
 	},
+	&cli.BoolFlag{
+		Name:  "archive",
+		Usage: "display a file inside a zip, tar, tar.gz or tar.zst archive, read on the client",
+	},
 	&cli.Int64Flag{
 		Name:  "offset",
 		Usage: "start offset",
@@ -68,7 +72,7 @@ var catCmd = cli.Command{
 	Action:       mainCat,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(append(catFlags, &encCFlag), globalFlags...),
+	Flags:        append(append(append(catFlags, &encCFlag), encClientFlags...), globalFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -101,6 +105,12 @@ EXAMPLES:
 
   7. Display the content of a particular object version
      {{.Prompt}} {{.HelpName}} --vid "3ddac055-89a7-40fa-8cd3-530a5581b6b8" play/my-bucket/my-object
+
+  8. Display the content of an object encrypted on the client with the key stored in a file.
+     {{.Prompt}} {{.HelpName}} --enc-client-key ~/.mc/backup.key play/my-bucket/my-object
+
+  9. Display a file inside a tar.gz archive, on any S3 endpoint or local filesystem.
+     {{.Prompt}} {{.HelpName}} --archive play/my-bucket/logs.tar.gz/2022/app.log
 `,
 }
 
@@ -168,7 +178,9 @@ type catOpts struct {
 	tailO     int64
 	partN     int
 	isZip     bool
+	isArchive bool
 	stdinMode bool
+	cse       *clientEncryption
 }
 
 // parseCatSyntax performs command-line input validation for cat command.
@@ -200,9 +212,14 @@ func parseCatSyntax(ctx context.Context, cmd *cli.Command) catOpts {
 
 	o.timeRef = parseRewindFlag(rewind)
 	o.isZip = cmd.Bool("zip")
+	o.isArchive = cmd.Bool("archive")
 	o.startO = cmd.Int64("offset")
 	o.tailO = cmd.Int64("tail")
 	o.partN = cmd.Int("part-number")
+
+	var err *probe.Error
+	o.cse, err = parseClientEncryption(cmd)
+	fatalIf(err, "Unable to parse client-side encryption keys.")
 	if o.tailO != 0 && o.startO != 0 {
 		fatalIf(errInvalidArgument().Trace(), "You cannot specify both --tail and --offset")
 	}
@@ -218,6 +235,14 @@ func parseCatSyntax(ctx context.Context, cmd *cli.Command) catOpts {
 	if (o.tailO != 0 || o.startO != 0) && o.partN > 0 {
 		fatalIf(errInvalidArgument().Trace(), "You cannot use --part-number with --tail or --offset")
 	}
+	if o.isArchive && (o.isZip || o.versionID != "" || !o.timeRef.IsZero() || o.partN > 0 || o.cse != nil) {
+		fatalIf(errInvalidArgument().Trace(), "You cannot combine --archive with --zip, --version-id, --rewind, --part-number or client-side encryption")
+	}
+	for _, arg := range o.args {
+		if _, _, _, ok := splitArchiveURL(arg); o.isArchive && arg != "-" && !ok {
+			fatalIf(errInvalidArgument().Trace(arg), "`"+arg+"` is not inside an archive, only .zip, .tar, .tar.gz, .tgz, .tar.zst and .tzst are supported.")
+		}
+	}
 
 	return o
 }
@@ -226,9 +251,30 @@ func parseCatSyntax(ctx context.Context, cmd *cli.Command) catOpts {
 func catURL(ctx context.Context, sourceURL string, encKeyDB map[string][]prefixSSEPair, o catOpts) *probe.Error {
 	var reader io.ReadCloser
 	size := int64(-1)
-	switch sourceURL {
-	case "-":
+	switch {
+	case sourceURL == "-":
 		reader = os.Stdin
+	case o.isArchive:
+		alias, _ := url2Alias(sourceURL)
+		clnt, err := newArchiveClient(sourceURL, getSSE(sourceURL, encKeyDB[alias]))
+		if err != nil {
+			return err.Trace(sourceURL)
+		}
+		content, err := clnt.Stat(ctx, StatOptions{})
+		if err != nil {
+			return err.Trace(sourceURL)
+		}
+		if o.tailO > 0 {
+			o.startO = max(content.Size-o.tailO, 0)
+		}
+		if o.startO > content.Size {
+			return probe.NewError(fmt.Errorf("specified offset (%d) bigger than file (%d)", o.startO, content.Size)).Trace(sourceURL)
+		}
+		size = content.Size - o.startO
+		if reader, _, err = clnt.Get(ctx, GetOptions{RangeStart: o.startO}); err != nil {
+			return err.Trace(sourceURL)
+		}
+		defer reader.Close()
 	default:
 		versionID := o.versionID
 		var err *probe.Error
@@ -250,6 +296,9 @@ func catURL(ctx context.Context, sourceURL string, encKeyDB map[string][]prefixS
 			if o.versionID == "" {
 				versionID = content.VersionID
 			}
+			if o.cse != nil {
+				content.Size, _ = clientDecryptedSize(content)
+			}
 			if o.tailO > 0 && content.Size > 0 {
 				o.startO = content.Size - o.tailO
 				if o.startO < 0 {
@@ -274,6 +323,7 @@ func catURL(ctx context.Context, sourceURL string, encKeyDB map[string][]prefixS
 		if reader, err = getSourceStreamFromURL(ctx, sourceURL, encKeyDB, getSourceOpts{
 			GetOptions: gopts,
 			preserve:   false,
+			cse:        o.cse,
 		}); err != nil {
 			return err.Trace(sourceURL)
 		}
@@ -115,7 +115,7 @@ func newAdminClient(aliasedURL string) (*madmin.AdminClient, *probe.Error) {
 }
 
 func newAnonymousClient(aliasedURL string) (*madmin.AnonymousClient, *probe.Error) {
-	_, urlStrFull, aliasCfg, err := expandAlias(aliasedURL)
+	alias, urlStrFull, aliasCfg, err := expandAlias(aliasedURL)
 	if err != nil {
 		return nil, err.Trace(aliasedURL)
 	}
@@ -143,15 +143,16 @@ func newAnonymousClient(aliasedURL string) (*madmin.AnonymousClient, *probe.Erro
 		return nil, probe.NewError(e)
 	}
 
-	// Set custom transport
+	// Set custom transport, with the connection settings of the alias.
+	config := NewS3Config(alias, urlStrFull, aliasCfg)
 	var transport http.RoundTripper = &http.Transport{
 		Proxy:       ieproxy.GetProxyFunc(),
-		DialContext: newCustomDialContext(&Config{}),
+		DialContext: newCustomDialContext(config),
 		DialTLSContext: newCustomDialTLSContext(&tls.Config{
-			RootCAs:            globalRootCAs,
+			RootCAs:            config.rootCAs(),
 			MinVersion:         tls.VersionTLS12,
-			InsecureSkipVerify: globalInsecure,
-		}),
+			InsecureSkipVerify: config.Insecure,
+		}, config.resolvers()),
 		MaxIdleConnsPerHost:   256,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
@@ -0,0 +1,239 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/openstor-go/v7/pkg/encrypt"
+)
+
+// archiveClient lists and reads the members of a zip or tar archive, all
+// other operations go to the archive object. Archives are read on the
+// client with ranged requests, so that any S3 endpoint or local file
+// can be browsed.
+type archiveClient struct {
+	Client
+	sse    encrypt.ServerSide
+	format archiveFormat
+	// Path of a member or folder inside the archive, "" for its root.
+	inner string
+	// Rest of the URL after the archive, e.g. "/" or "/2022/".
+	suffix string
+}
+
+// Indexes of the archives read by this command, by URL.
+var (
+	archiveIndexesMu sync.Mutex
+	archiveIndexes   = make(map[string]*archiveIndex)
+)
+
+// splitArchiveURL splits an URL at its first element named like an
+// archive, e.g. play/bucket/logs.tar.gz/2022/ into play/bucket/logs.tar.gz
+// and /2022/.
+func splitArchiveURL(urlStr string) (archiveURL, suffix string, format archiveFormat, ok bool) {
+	elems := strings.Split(strings.ReplaceAll(urlStr, "\\", "/"), "/")
+	for i, elem := range elems {
+		if format, ok = archiveFormatOf(elem); ok {
+			archiveURL = strings.Join(elems[:i+1], "/")
+			return archiveURL, strings.ReplaceAll(urlStr, "\\", "/")[len(archiveURL):], format, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// newArchiveClient returns a client of the members of the archive named in
+// aliasedURL, sse is the encryption key of the archive object.
+func newArchiveClient(aliasedURL string, sse encrypt.ServerSide) (*archiveClient, *probe.Error) {
+	archiveURL, suffix, format, ok := splitArchiveURL(aliasedURL)
+	if !ok {
+		return nil, errInvalidArgument().Trace(aliasedURL)
+	}
+	clnt, err := newClient(archiveURL)
+	if err != nil {
+		return nil, err.Trace(aliasedURL)
+	}
+	return &archiveClient{
+		Client: clnt,
+		sse:    sse,
+		format: format,
+		inner:  strings.TrimPrefix(suffix, "/"),
+		suffix: suffix,
+	}, nil
+}
+
+// GetURL returns the URL of the archive followed by the inner path.
+func (c *archiveClient) GetURL() ClientURL {
+	url := c.Client.GetURL().Clone()
+	url.Path += strings.ReplaceAll(c.suffix, "/", string(url.Separator))
+	return url
+}
+
+// index returns the members of the archive, read once per command.
+func (c *archiveClient) index(ctx context.Context) (*archiveIndex, *probe.Error) {
+	archiveURL := c.Client.GetURL().String()
+	archiveIndexesMu.Lock()
+	defer archiveIndexesMu.Unlock()
+	if idx, ok := archiveIndexes[archiveURL]; ok {
+		return idx, nil
+	}
+
+	content, err := c.Client.Stat(ctx, StatOptions{sse: c.sse})
+	if err != nil {
+		return nil, err.Trace(archiveURL)
+	}
+	if content.Type.IsDir() {
+		return nil, probe.NewError(PathIsNotRegular{Path: archiveURL}).Trace(archiveURL)
+	}
+	reader := &archiveReaderAt{
+		ctx:  ctx,
+		clnt: c.Client,
+		opts: GetOptions{SSE: c.sse, VersionID: content.VersionID},
+		size: content.Size,
+	}
+	idx, e := readArchiveIndex(ctx, reader, c.format)
+	if e != nil {
+		reader.Close()
+		return nil, probe.NewError(e).Trace(archiveURL)
+	}
+	archiveIndexes[archiveURL] = idx
+	return idx, nil
+}
+
+// content returns the content a listing would return for a member.
+func (c *archiveClient) content(entry *archiveEntry) *ClientContent {
+	url := c.Client.GetURL().Clone()
+	url.Path = strings.TrimSuffix(url.Path, string(url.Separator)) + string(url.Separator) +
+		strings.ReplaceAll(entry.name, "/", string(url.Separator))
+	content := &ClientContent{
+		URL:  url,
+		Time: entry.modTime,
+		Size: entry.size,
+		Type: entry.mode,
+	}
+	if entry.isDir() {
+		content.Type = os.ModeDir | entry.mode.Perm()
+	}
+	return content
+}
+
+// entry returns the member of the inner path, folders are found with or
+// without trailing slash.
+func (c *archiveClient) entry(ctx context.Context) (*archiveIndex, *archiveEntry, *probe.Error) {
+	idx, err := c.index(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.inner == "" {
+		return idx, &archiveEntry{mode: os.ModeDir | 0o755}, nil
+	}
+	if entry, ok := idx.byName[c.inner]; ok {
+		return idx, entry, nil
+	}
+	if entry, ok := idx.byName[strings.TrimSuffix(c.inner, "/")+"/"]; ok {
+		return idx, entry, nil
+	}
+	return idx, nil, probe.NewError(ObjectMissing{}).Trace(c.GetURL().String())
+}
+
+// Stat returns the member or folder of the inner path.
+func (c *archiveClient) Stat(ctx context.Context, _ StatOptions) (*ClientContent, *probe.Error) {
+	_, entry, err := c.entry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if entry.name == "" {
+		content := c.content(entry)
+		content.URL = c.Client.GetURL()
+		return content, nil
+	}
+	return c.content(entry), nil
+}
+
+// List lists the members under the inner path, delimited by folders if
+// not recursive.
+func (c *archiveClient) List(ctx context.Context, opts ListOptions) <-chan *ClientContent {
+	contentCh := make(chan *ClientContent)
+	go func() {
+		defer close(contentCh)
+
+		send := func(content *ClientContent) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case contentCh <- content:
+				return true
+			}
+		}
+
+		idx, entry, err := c.entry(ctx)
+		if err != nil {
+			send(&ClientContent{Err: err})
+			return
+		}
+		if !entry.isDir() && entry.name != "" {
+			send(c.content(entry))
+			return
+		}
+		prefix := entry.name
+		for _, e := range idx.entries {
+			if !strings.HasPrefix(e.name, prefix) || e.name == prefix {
+				continue
+			}
+			rest := strings.TrimSuffix(e.name[len(prefix):], "/")
+			if opts.Recursive {
+				if e.isDir() && opts.ShowDir == DirNone {
+					continue
+				}
+			} else if strings.Contains(rest, "/") {
+				continue
+			}
+			if !send(c.content(e)) {
+				return
+			}
+		}
+	}()
+	return contentCh
+}
+
+// Get returns the content of the member of the inner path.
+func (c *archiveClient) Get(ctx context.Context, opts GetOptions) (io.ReadCloser, *ClientContent, *probe.Error) {
+	idx, entry, err := c.entry(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entry.isDir() || entry.name == "" {
+		return nil, nil, probe.NewError(PathIsNotRegular{Path: c.GetURL().String()}).Trace(c.GetURL().String())
+	}
+	reader, e := idx.open(ctx, entry)
+	if e != nil {
+		return nil, nil, probe.NewError(e).Trace(c.GetURL().String())
+	}
+	if opts.RangeStart > 0 {
+		if _, e = io.CopyN(io.Discard, reader, opts.RangeStart); e != nil {
+			reader.Close()
+			return nil, nil, probe.NewError(e).Trace(c.GetURL().String())
+		}
+	}
+	return reader, c.content(entry), nil
+}
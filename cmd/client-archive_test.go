@@ -0,0 +1,186 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitArchiveURL(t *testing.T) {
+	testCases := []struct {
+		url, archive, suffix string
+		format               archiveFormat
+		ok                   bool
+	}{
+		{"play/bucket/logs.tar.gz", "play/bucket/logs.tar.gz", "", archiveTarGzip, true},
+		{"play/bucket/logs.tar.gz/", "play/bucket/logs.tar.gz", "/", archiveTarGzip, true},
+		{"play/bucket/a.zip/2022/x.log", "play/bucket/a.zip", "/2022/x.log", archiveZip, true},
+		{"/tmp/b.TZST/dir/", "/tmp/b.TZST", "/dir/", archiveTarZstd, true},
+		{"play/bucket/x.tar/y.zip/z", "play/bucket/x.tar", "/y.zip/z", archiveTar, true},
+		{"play/bucket/logs.gz", "", "", 0, false},
+	}
+	for _, tc := range testCases {
+		archive, suffix, format, ok := splitArchiveURL(tc.url)
+		if archive != tc.archive || suffix != tc.suffix || format != tc.format || ok != tc.ok {
+			t.Errorf("%s: got %q %q %v %v", tc.url, archive, suffix, format, ok)
+		}
+	}
+}
+
+func TestCleanArchiveName(t *testing.T) {
+	testCases := []struct {
+		name  string
+		isDir bool
+		want  string
+		ok    bool
+	}{
+		{"./a/b.txt", false, "a/b.txt", true},
+		{"/a//b.txt", false, "a/b.txt", true},
+		{"a/b", true, "a/b/", true},
+		{"a\\b.txt", false, "a/b.txt", true},
+		{"../etc/passwd", false, "", false},
+		{"a/../../b", false, "", false},
+		{"./", true, "", false},
+	}
+	for _, tc := range testCases {
+		got, ok := cleanArchiveName(tc.name, tc.isDir)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%s: got %q %v", tc.name, got, ok)
+		}
+	}
+}
+
+var archiveTestFiles = []struct {
+	name, content string
+}{
+	{"a.txt", "one"},
+	{"d/b.txt", "two"},
+	{"d/e/c.txt", strings.Repeat("three", 100000)},
+	{"../evil.txt", "evil"},
+}
+
+func writeTestArchive(t *testing.T, name string, format archiveFormat) {
+	f, e := os.Create(name)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer f.Close()
+
+	if format == archiveZip {
+		zw := zip.NewWriter(f)
+		for _, file := range archiveTestFiles {
+			w, e := zw.Create(file.name)
+			if e != nil {
+				t.Fatal(e)
+			}
+			io.WriteString(w, file.content)
+		}
+		if e = zw.Close(); e != nil {
+			t.Fatal(e)
+		}
+		return
+	}
+
+	var w io.Writer = f
+	if format == archiveTarGzip {
+		gw := gzip.NewWriter(f)
+		defer gw.Close()
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	for _, file := range archiveTestFiles {
+		hdr := &tar.Header{Name: file.name, Mode: 0o644, Size: int64(len(file.content)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+		if e := tw.WriteHeader(hdr); e != nil {
+			t.Fatal(e)
+		}
+		io.WriteString(tw, file.content)
+	}
+	if e := tw.Close(); e != nil {
+		t.Fatal(e)
+	}
+}
+
+func TestArchiveClient(t *testing.T) {
+	root := newServeTestRoot(t)
+	ctx := context.Background()
+
+	for name, format := range map[string]archiveFormat{"t.zip": archiveZip, "t.tar": archiveTar, "t.tar.gz": archiveTarGzip} {
+		archive := filepath.Join(root, name)
+		writeTestArchive(t, archive, format)
+
+		clnt, err := newArchiveClient(archive+"/d/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for content := range clnt.List(ctx, ListOptions{Recursive: true}) {
+			if content.Err != nil {
+				t.Fatal(content.Err)
+			}
+			names = append(names, strings.TrimPrefix(filepath.ToSlash(content.URL.Path), filepath.ToSlash(archive)+"/"))
+		}
+		if strings.Join(names, ",") != "d/b.txt,d/e/c.txt" {
+			t.Errorf("%s: got members %v", name, names)
+		}
+
+		clnt, err = newArchiveClient(archive+"/d/e/c.txt", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, content, err := clnt.Get(ctx, GetOptions{RangeStart: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, e := io.ReadAll(reader)
+		reader.Close()
+		if e != nil || content.Size != 500000 || string(data) != strings.Repeat("three", 99999) {
+			t.Errorf("%s: got %d bytes of %d, %v", name, len(data), content.Size, e)
+		}
+
+		clnt, err = newArchiveClient(archive+"/evil.txt", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = clnt.Stat(ctx, StatOptions{}); err == nil {
+			t.Errorf("%s: member outside of the archive was found", name)
+		}
+
+		target := filepath.Join(root, "out-"+name)
+		if e := extractArchive(ctx, archive, target+"/", nil, 2, ""); e != nil {
+			t.Fatal(e)
+		}
+		for _, file := range archiveTestFiles[:3] {
+			got, e := os.ReadFile(filepath.Join(target, file.name))
+			if e != nil || string(got) != file.content {
+				t.Errorf("%s: extracted %s is %d bytes, %v", name, file.name, len(got), e)
+			}
+		}
+		if _, e := os.Stat(filepath.Join(root, "evil.txt")); !os.IsNotExist(e) {
+			t.Errorf("%s: member outside of the archive was extracted", name)
+		}
+	}
+}
@@ -0,0 +1,479 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/openstor-go/v7/pkg/notification"
+	"github.com/rjeczalik/notify"
+)
+
+// Modes of watching local folders.
+const (
+	fsWatchModeAuto   = "auto"
+	fsWatchModeNotify = "notify"
+	fsWatchModePoll   = "poll"
+)
+
+const (
+	// Polling interval used unless configured otherwise.
+	defaultFSPollInterval = 30 * time.Second
+
+	timeFormatFS = "2006-01-02T15:04:05.000Z"
+)
+
+// WatchMode tells how a local folder is watched.
+type WatchMode struct {
+	Path   string `json:"path"`
+	Mode   string `json:"mode"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// fsMount - a mounted filesystem, as listed by the OS.
+type fsMount struct {
+	Path string
+	Type string
+}
+
+// fsPollState - what is compared between two scans of a file.
+type fsPollState struct {
+	size    int64
+	modTime time.Time
+}
+
+// fsWatcher watches a local folder with notify where possible and polls
+// the subtrees that cannot be watched, like folders exceeding the inotify
+// limits or network filesystems whose remote changes are never notified.
+// Both report the same events.
+type fsWatcher struct {
+	root      string
+	recursive bool
+	events    []notify.Event
+	put, del  bool
+	interval  time.Duration
+
+	eventCh chan []EventInfo
+	errorCh chan *probe.Error
+	doneCh  chan struct{}
+	wg      sync.WaitGroup
+
+	ins   []chan notify.EventInfo
+	modes []WatchMode
+
+	// poller state, folders in skip are watched with notify.
+	poll          bool
+	skip          map[string]bool
+	skipRootFiles bool
+	states        map[string]fsPollState
+	pending       map[string]bool
+}
+
+// newFSWatcher prepares watching root for the events of options,
+// nothing is watched until start is called.
+func newFSWatcher(root string, options WatchOptions) *fsWatcher {
+	w := &fsWatcher{
+		root:      filepath.Clean(root),
+		recursive: options.Recursive,
+		interval:  options.FSPollInterval,
+		eventCh:   make(chan []EventInfo),
+		errorCh:   make(chan *probe.Error),
+		doneCh:    make(chan struct{}),
+		skip:      make(map[string]bool),
+		pending:   make(map[string]bool),
+	}
+	if w.interval <= 0 {
+		w.interval = defaultFSPollInterval
+	}
+	for _, event := range options.Events {
+		switch event {
+		case "put":
+			w.put = true
+			w.events = append(w.events, EventTypePut...)
+		case "delete":
+			w.del = true
+			w.events = append(w.events, EventTypeDelete...)
+		case "get":
+			w.events = append(w.events, EventTypeGet...)
+		default:
+			// Event type not supported by FS client, such as
+			// bucket creation or deletion, ignore it.
+		}
+	}
+	return w
+}
+
+// start sets up the watches according to mode, in auto mode notify is
+// preferred and subtrees are polled only when they cannot be watched.
+func (w *fsWatcher) start(mode string) error {
+	switch mode {
+	case fsWatchModeNotify:
+		if e := w.watchNotify(w.root, w.recursive); e != nil {
+			return e
+		}
+		w.addMode(w.root, fsWatchModeNotify, "")
+		return nil
+	case fsWatchModePoll:
+		w.pollAll("requested")
+		return nil
+	case "", fsWatchModeAuto:
+	default:
+		return fmt.Errorf("unknown watch mode `%s`", mode)
+	}
+
+	mounts := getFSMounts()
+	if fsType := fsMountType(w.root, mounts); isNetworkFSType(fsType) {
+		w.pollAll(fsType + " network filesystem")
+		return nil
+	}
+
+	var nested []fsMount
+	if w.recursive {
+		nested = networkMountsUnder(w.root, mounts)
+	}
+	if len(nested) == 0 {
+		e := w.watchNotify(w.root, w.recursive)
+		if e == nil {
+			w.addMode(w.root, fsWatchModeNotify, "")
+			return nil
+		}
+		if !w.recursive {
+			w.pollAll(fsWatchFallbackReason(e))
+			return nil
+		}
+	}
+
+	// Watch each top level folder on its own, so that only the ones
+	// which cannot be watched are polled.
+	if e := w.watchNotify(w.root, false); e != nil {
+		w.pollAll(fsWatchFallbackReason(e))
+		return nil
+	}
+	w.addMode(w.root, fsWatchModeNotify, "top level files only")
+	w.poll = true
+	w.skipRootFiles = true
+
+	entries, e := os.ReadDir(w.root)
+	if e != nil {
+		return e
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(w.root, entry.Name())
+		if mount, ok := mountUnder(path, nested); ok {
+			w.addMode(path, fsWatchModePoll, mount.Type+" network filesystem mounted at "+mount.Path)
+			continue
+		}
+		if e := w.watchNotify(path, true); e != nil {
+			w.addMode(path, fsWatchModePoll, fsWatchFallbackReason(e))
+			continue
+		}
+		w.skip[path] = true
+		w.addMode(path, fsWatchModeNotify, "")
+	}
+	w.addMode(filepath.Join(w.root, "*"), fsWatchModePoll, "top level folders created later")
+	return nil
+}
+
+func (w *fsWatcher) addMode(path, mode, reason string) {
+	w.modes = append(w.modes, WatchMode{Path: path, Mode: mode, Reason: reason})
+}
+
+// pollAll polls the whole tree.
+func (w *fsWatcher) pollAll(reason string) {
+	w.poll = true
+	w.addMode(w.root, fsWatchModePoll, reason)
+}
+
+// watchNotify watches path with notify, partially set up watches are
+// removed if it fails.
+func (w *fsWatcher) watchNotify(path string, recursive bool) error {
+	// Make the channel buffered to ensure no event is dropped. Notify will drop
+	// an event if the receiver is not able to keep up the sending pace.
+	in, out := PipeChan(1000)
+	if recursive {
+		path = filepath.Join(path, "...")
+	}
+	if e := notify.Watch(path, in, w.events...); e != nil {
+		notify.Stop(in)
+		close(in)
+		return e
+	}
+	w.ins = append(w.ins, in)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for event := range out {
+			if events := w.notifyEvents(event); len(events) > 0 && !w.sendEvents(events) {
+				// Drain until the channel is closed by stop.
+				for range out {
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// notifyEvents converts a notify event.
+func (w *fsWatcher) notifyEvents(event notify.EventInfo) []EventInfo {
+	if isIgnoredFile(event.Path()) {
+		return nil
+	}
+	switch {
+	case IsPutEvent(event.Event()):
+		// Look for any writes, send a response to indicate a full copy.
+		i, e := os.Stat(event.Path())
+		if e != nil {
+			if !os.IsNotExist(e) {
+				w.sendError(probe.NewError(e))
+			}
+			return nil
+		}
+		if i.IsDir() {
+			// we want files
+			return nil
+		}
+		return []EventInfo{{
+			Time: UTCNow().Format(timeFormatFS),
+			Size: i.Size(),
+			Path: event.Path(),
+			Type: notification.ObjectCreatedPut,
+		}}
+	case IsDeleteEvent(event.Event()):
+		return []EventInfo{{
+			Time: UTCNow().Format(timeFormatFS),
+			Path: event.Path(),
+			Type: notification.ObjectRemovedDelete,
+		}}
+	case IsGetEvent(event.Event()):
+		return []EventInfo{{
+			Time: UTCNow().Format(timeFormatFS),
+			Path: event.Path(),
+			Type: notification.ObjectAccessedGet,
+		}}
+	}
+	return nil
+}
+
+func (w *fsWatcher) sendEvents(events []EventInfo) bool {
+	select {
+	case w.eventCh <- events:
+		return true
+	case <-w.doneCh:
+		return false
+	}
+}
+
+func (w *fsWatcher) sendError(err *probe.Error) {
+	select {
+	case w.errorCh <- err:
+	case <-w.doneCh:
+	}
+}
+
+// run starts polling, if needed, and stops everything once doneCh is closed.
+func (w *fsWatcher) run() {
+	if w.poll {
+		w.states, _ = w.scan(nil)
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.pollLoop()
+		}()
+	}
+
+	go func() {
+		<-w.doneCh
+		for _, in := range w.ins {
+			notify.Stop(in)
+			// At this point, notify is guaranteed to not write
+			// in 'in' channel so we can close it.
+			close(in)
+		}
+		w.wg.Wait()
+		close(w.eventCh)
+		close(w.errorCh)
+	}()
+}
+
+func (w *fsWatcher) pollLoop() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.doneCh:
+			return
+		case <-ticker.C:
+			if events := w.pollOnce(); len(events) > 0 && !w.sendEvents(events) {
+				return
+			}
+		}
+	}
+}
+
+// pollOnce scans the polled folders and returns the changes since the
+// last scan. Files are reported once their size and modification time
+// did not change between two scans, so that files being written are
+// not reported half way, like notify reports them once closed.
+func (w *fsWatcher) pollOnce() (events []EventInfo) {
+	states, failed := w.scan(w.states)
+	now := UTCNow().Format(timeFormatFS)
+	for path, state := range states {
+		prev, ok := w.states[path]
+		switch {
+		case !ok || prev != state:
+			w.pending[path] = true
+		case w.pending[path]:
+			delete(w.pending, path)
+			if w.put {
+				events = append(events, EventInfo{
+					Time: now,
+					Size: state.size,
+					Path: path,
+					Type: notification.ObjectCreatedPut,
+				})
+			}
+		}
+	}
+	for path := range w.states {
+		if _, ok := states[path]; ok || failed(path) {
+			continue
+		}
+		delete(w.pending, path)
+		if w.del {
+			events = append(events, EventInfo{
+				Time: now,
+				Path: path,
+				Type: notification.ObjectRemovedDelete,
+			})
+		}
+	}
+	w.states = states
+	return events
+}
+
+// scan returns the size and modification time of the polled files.
+// The files of folders which could not be read are kept from prev,
+// failed tells whether a path is in such folder.
+func (w *fsWatcher) scan(prev map[string]fsPollState) (states map[string]fsPollState, failed func(string) bool) {
+	states = make(map[string]fsPollState)
+	var failedDirs []string
+
+	var walk func(dir string)
+	walk = func(dir string) {
+		entries, e := os.ReadDir(dir)
+		if e != nil {
+			if !os.IsNotExist(e) {
+				failedDirs = append(failedDirs, dir+string(filepath.Separator))
+			}
+			return
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() {
+				if w.recursive && !w.skip[path] {
+					walk(path)
+				}
+				continue
+			}
+			if (dir == w.root && w.skipRootFiles) || !entry.Type().IsRegular() || isIgnoredFile(path) {
+				continue
+			}
+			info, e := entry.Info()
+			if e != nil {
+				continue
+			}
+			states[path] = fsPollState{size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	walk(w.root)
+
+	failed = func(path string) bool {
+		for _, dir := range failedDirs {
+			if strings.HasPrefix(path, dir) {
+				return true
+			}
+		}
+		return false
+	}
+	for path, state := range prev {
+		if failed(path) {
+			states[path] = state
+		}
+	}
+	return states, failed
+}
+
+// fsMountType returns the filesystem type of path, the one of the
+// deepest mount point containing it.
+func fsMountType(path string, mounts []fsMount) (fsType string) {
+	depth := -1
+	for _, mount := range mounts {
+		if isPathUnder(path, mount.Path) && len(mount.Path) > depth {
+			depth = len(mount.Path)
+			fsType = mount.Type
+		}
+	}
+	return fsType
+}
+
+// networkMountsUnder returns the network filesystems mounted below path.
+func networkMountsUnder(path string, mounts []fsMount) (nested []fsMount) {
+	for _, mount := range mounts {
+		if mount.Path != path && isPathUnder(mount.Path, path) && isNetworkFSType(mount.Type) {
+			nested = append(nested, mount)
+		}
+	}
+	return nested
+}
+
+// mountUnder returns the first mount below or at path.
+func mountUnder(path string, mounts []fsMount) (fsMount, bool) {
+	for _, mount := range mounts {
+		if isPathUnder(mount.Path, path) {
+			return mount, true
+		}
+	}
+	return fsMount{}, false
+}
+
+// isPathUnder returns true if path is dir or is inside dir.
+func isPathUnder(path, dir string) bool {
+	rel, e := filepath.Rel(dir, path)
+	return e == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isNetworkFSType returns true for filesystems whose changes made by
+// other hosts are not notified.
+func isNetworkFSType(fsType string) bool {
+	switch fsType {
+	case "nfs", "nfs4", "cifs", "smb3", "smbfs", "9p", "ceph", "glusterfs", "lustre", "gpfs", "afs", "davfs", "sshfs":
+		return true
+	}
+	return strings.HasPrefix(fsType, "fuse.")
+}
@@ -0,0 +1,61 @@
+//go:build linux
+// +build linux
+
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// getFSMounts returns the mounted filesystems.
+func getFSMounts() (mounts []fsMount) {
+	f, e := os.Open("/proc/self/mounts")
+	if e != nil {
+		return nil
+	}
+	defer f.Close()
+
+	// Spaces and other special characters are escaped as octal, e.g. \040.
+	unescape := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		mounts = append(mounts, fsMount{Path: unescape.Replace(fields[1]), Type: fields[2]})
+	}
+	return mounts
+}
+
+// fsWatchFallbackReason describes why a folder could not be watched.
+func fsWatchFallbackReason(e error) string {
+	switch {
+	case errors.Is(e, syscall.ENOSPC):
+		return "inotify watch limit reached (fs.inotify.max_user_watches)"
+	case errors.Is(e, syscall.EMFILE):
+		return "inotify instance limit reached (fs.inotify.max_user_instances)"
+	}
+	return e.Error()
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/openstor/openstor-go/v7/pkg/notification"
+)
+
+func TestFSWatcherPoll(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, data string) {
+		path := filepath.Join(root, name)
+		if e := os.MkdirAll(filepath.Dir(path), 0o700); e != nil {
+			t.Fatal(e)
+		}
+		if e := os.WriteFile(path, []byte(data), 0o600); e != nil {
+			t.Fatal(e)
+		}
+	}
+	poll := func(w *fsWatcher) (events []string) {
+		for _, event := range w.pollOnce() {
+			rel, _ := filepath.Rel(root, event.Path)
+			events = append(events, string(event.Type)+" "+filepath.ToSlash(rel))
+		}
+		sort.Strings(events)
+		return events
+	}
+	expect := func(events []string, expected ...string) {
+		t.Helper()
+		if len(events) != len(expected) {
+			t.Fatalf("Expected events %v, got %v", expected, events)
+		}
+		for i := range events {
+			if events[i] != expected[i] {
+				t.Fatalf("Expected events %v, got %v", expected, events)
+			}
+		}
+	}
+
+	write("old.txt", "old")
+	w := newFSWatcher(root, WatchOptions{Recursive: true, Events: []string{"put", "delete"}})
+	if e := w.start(fsWatchModePoll); e != nil {
+		t.Fatal(e)
+	}
+	if len(w.modes) != 1 || w.modes[0].Mode != fsWatchModePoll {
+		t.Fatalf("Unexpected modes %v", w.modes)
+	}
+	w.states, _ = w.scan(nil)
+
+	write("a/b/new.txt", "new")
+	// Reported once unchanged between two scans.
+	expect(poll(w))
+	expect(poll(w), string(notification.ObjectCreatedPut)+" a/b/new.txt")
+	expect(poll(w))
+
+	write("old.txt", "changed")
+	os.Chtimes(filepath.Join(root, "old.txt"), time.Now(), time.Now().Add(time.Hour))
+	os.Remove(filepath.Join(root, "a", "b", "new.txt"))
+	expect(poll(w), string(notification.ObjectRemovedDelete)+" a/b/new.txt")
+	expect(poll(w), string(notification.ObjectCreatedPut)+" old.txt")
+}
+
+func TestFSWatcherNonRecursivePoll(t *testing.T) {
+	root := t.TempDir()
+	w := newFSWatcher(root, WatchOptions{Events: []string{"put"}})
+	if e := w.start(fsWatchModePoll); e != nil {
+		t.Fatal(e)
+	}
+	w.states, _ = w.scan(nil)
+
+	os.MkdirAll(filepath.Join(root, "sub"), 0o700)
+	os.WriteFile(filepath.Join(root, "sub", "a"), []byte("a"), 0o600)
+	os.WriteFile(filepath.Join(root, "b"), []byte("b"), 0o600)
+	w.pollOnce()
+	events := w.pollOnce()
+	if len(events) != 1 || events[0].Path != filepath.Join(root, "b") {
+		t.Fatalf("Unexpected events %v", events)
+	}
+}
+
+func TestFSMounts(t *testing.T) {
+	mounts := []fsMount{
+		{Path: "/", Type: "ext4"},
+		{Path: "/mnt/nfs", Type: "nfs4"},
+		{Path: "/data", Type: "xfs"},
+		{Path: "/data/shared/remote", Type: "fuse.sshfs"},
+	}
+	testCases := []struct {
+		path    string
+		fsType  string
+		network bool
+		nested  int
+	}{
+		{"/home/user", "ext4", false, 0},
+		{"/mnt/nfs/photos", "nfs4", true, 0},
+		{"/mnt/nfs2", "ext4", false, 0},
+		{"/data", "xfs", false, 1},
+		{"/data/shared/remote/x", "fuse.sshfs", true, 0},
+	}
+	for i, testCase := range testCases {
+		fsType := fsMountType(testCase.path, mounts)
+		if fsType != testCase.fsType {
+			t.Errorf("Test %d: expected %s, got %s", i+1, testCase.fsType, fsType)
+		}
+		if isNetworkFSType(fsType) != testCase.network {
+			t.Errorf("Test %d: expected network filesystem %v", i+1, testCase.network)
+		}
+		if nested := networkMountsUnder(testCase.path, mounts); len(nested) != testCase.nested {
+			t.Errorf("Test %d: expected %d nested mounts, got %v", i+1, testCase.nested, nested)
+		}
+	}
+	if _, ok := mountUnder("/data/shared", mounts[3:]); !ok {
+		t.Errorf("Expected a mount under /data/shared")
+	}
+}
@@ -45,7 +45,6 @@ import (
 	"github.com/openstor/openstor-go/v7/pkg/cors"
 	"github.com/openstor/openstor-go/v7/pkg/encrypt"
 	"github.com/openstor/openstor-go/v7/pkg/lifecycle"
-	"github.com/openstor/openstor-go/v7/pkg/notification"
 	"github.com/openstor/openstor-go/v7/pkg/replication"
 	"github.com/openstor/pkg/v3/console"
 )
@@ -146,101 +145,21 @@ func (f *fsClient) Select(_ context.Context, _ string, _ encrypt.ServerSide, _ S
 
 // Watches for all fs events on an input path.
 func (f *fsClient) Watch(_ context.Context, options WatchOptions) (*WatchObject, *probe.Error) {
-	eventChan := make(chan []EventInfo)
-	errorChan := make(chan *probe.Error)
-	doneChan := make(chan struct{})
-	// Make the channel buffered to ensure no event is dropped. Notify will drop
-	// an event if the receiver is not able to keep up the sending pace.
-	in, out := PipeChan(1000)
-
-	var fsEvents []notify.Event
-	for _, event := range options.Events {
-		switch event {
-		case "put":
-			fsEvents = append(fsEvents, EventTypePut...)
-		case "delete":
-			fsEvents = append(fsEvents, EventTypeDelete...)
-		case "get":
-			fsEvents = append(fsEvents, EventTypeGet...)
-		default:
-			// Event type not supported by FS client, such as
-			// bucket creation or deletion, ignore it.
+	w := newFSWatcher(f.PathURL.Path, options)
+	if e := w.start(options.FSMode); e != nil {
+		for _, in := range w.ins {
+			notify.Stop(in)
+			close(in)
 		}
-	}
-
-	// Set up a watchpoint listening for events within a directory tree rooted
-	// at current working directory. Dispatch remove events to c.
-	recursivePath := f.PathURL.Path
-	if options.Recursive {
-		recursivePath = f.PathURL.Path + "..."
-	}
-	if e := notify.Watch(recursivePath, in, fsEvents...); e != nil {
 		return nil, probe.NewError(e)
 	}
-
-	// wait for doneChan to close the watcher, eventChan and errorChan
-	go func() {
-		<-doneChan
-
-		close(eventChan)
-		close(errorChan)
-		notify.Stop(in)
-		// At this point, notify is guaranteed to not write
-		// in 'in' channel so we can close it.
-		close(in)
-	}()
-
-	timeFormatFS := "2006-01-02T15:04:05.000Z"
-
-	// Get fsnotify notifications for events and errors, and sent them
-	// using eventChan and errorChan
-	go func() {
-		for event := range out {
-			if isIgnoredFile(event.Path()) {
-				continue
-			}
-			var i os.FileInfo
-			if IsPutEvent(event.Event()) {
-				// Look for any writes, send a response to indicate a full copy.
-				var e error
-				i, e = os.Stat(event.Path())
-				if e != nil {
-					if os.IsNotExist(e) {
-						continue
-					}
-					errorChan <- probe.NewError(e)
-					continue
-				}
-				if i.IsDir() {
-					// we want files
-					continue
-				}
-				eventChan <- []EventInfo{{
-					Time: UTCNow().Format(timeFormatFS),
-					Size: i.Size(),
-					Path: event.Path(),
-					Type: notification.ObjectCreatedPut,
-				}}
-			} else if IsDeleteEvent(event.Event()) {
-				eventChan <- []EventInfo{{
-					Time: UTCNow().Format(timeFormatFS),
-					Path: event.Path(),
-					Type: notification.ObjectRemovedDelete,
-				}}
-			} else if IsGetEvent(event.Event()) {
-				eventChan <- []EventInfo{{
-					Time: UTCNow().Format(timeFormatFS),
-					Path: event.Path(),
-					Type: notification.ObjectAccessedGet,
-				}}
-			}
-		}
-	}()
+	w.run()
 
 	return &WatchObject{
-		EventInfoChan: eventChan,
-		ErrorChan:     errorChan,
-		DoneChan:      doneChan,
+		EventInfoChan: w.eventCh,
+		ErrorChan:     w.errorCh,
+		DoneChan:      w.doneCh,
+		Modes:         w.modes,
 	}, nil
 }
 
@@ -0,0 +1,150 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/openstor/mc/pkg/probe"
+)
+
+// indexOpenTimeout is how long listings wait for an index being updated.
+const indexOpenTimeout = 10 * time.Second
+
+// indexClient answers listings from the local index of a bucket, all
+// other operations go to the object storage.
+type indexClient struct {
+	Client
+	alias  string
+	bucket string
+}
+
+// newIndexClient returns a client listing clnt from the index of its
+// bucket, see `mc index build`.
+func newIndexClient(alias string, clnt Client) (Client, *probe.Error) {
+	if _, ok := clnt.(*S3Client); !ok {
+		return nil, errInvalidArgument().Trace(clnt.GetURL().String())
+	}
+	targetURL := clnt.GetURL()
+	bucket, _ := url2BucketAndObject(&targetURL)
+	if bucket == "" {
+		return nil, errInvalidArgument().Trace(clnt.GetURL().String())
+	}
+	if !isIndexExists(alias, bucket) {
+		return nil, errIndexNotFound(alias + "/" + bucket)
+	}
+	return &indexClient{Client: clnt, alias: alias, bucket: bucket}, nil
+}
+
+// content converts an index entry to the content a listing would return.
+func (c *indexClient) content(entry indexEntry) *ClientContent {
+	url := c.GetURL().Clone()
+	url.Path = string(url.Separator) + c.bucket + string(url.Separator) + entry.Key
+	content := &ClientContent{
+		URL:               url,
+		BucketName:        c.bucket,
+		Time:              entry.ModTime,
+		Size:              entry.Size,
+		Type:              os.FileMode(0o664),
+		StorageClass:      entry.StorageClass,
+		Metadata:          entry.Metadata,
+		UserMetadata:      entry.UserMetadata,
+		Tags:              entry.Tags,
+		ETag:              entry.ETag,
+		VersionID:         entry.VersionID,
+		IsDeleteMarker:    entry.IsDeleteMarker,
+		IsLatest:          entry.IsLatest,
+		ReplicationStatus: entry.ReplicationStatus,
+	}
+	if strings.HasSuffix(entry.Key, string(url.Separator)) {
+		content.Type = os.ModeDir
+	}
+	return content
+}
+
+// dirContent returns the content of a common prefix.
+func (c *indexClient) dirContent(prefix string) *ClientContent {
+	url := c.GetURL().Clone()
+	url.Path = string(url.Separator) + c.bucket + string(url.Separator) + prefix
+	return &ClientContent{
+		URL:        url,
+		BucketName: c.bucket,
+		Type:       os.ModeDir,
+		Time:       time.Now(),
+	}
+}
+
+// List - list the index at delimited path, if not recursive. Versions are
+// selected the same way as listings of the object storage do.
+func (c *indexClient) List(ctx context.Context, opts ListOptions) <-chan *ClientContent {
+	contentCh := make(chan *ClientContent)
+	go func() {
+		defer close(contentCh)
+
+		send := func(content *ClientContent) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case contentCh <- content:
+				return nil
+			}
+		}
+
+		idx, err := openBucketIndex(c.alias, c.bucket, true, indexOpenTimeout)
+		if err != nil {
+			send(&ClientContent{Err: err})
+			return
+		}
+		defer idx.Close()
+
+		targetURL := c.GetURL()
+		_, prefix := url2BucketAndObject(&targetURL)
+		separator := string(targetURL.Separator)
+		err = idx.Walk(ctx, prefix, func(versions []indexEntry) (string, error) {
+			key := versions[0].Key
+			if !opts.Recursive {
+				if i := strings.Index(key[len(prefix):], separator); i >= 0 {
+					dir := key[:len(prefix)+i+1]
+					// Skip everything below the common prefix.
+					return dir[:len(dir)-1] + string(rune(targetURL.Separator)+1), send(c.dirContent(dir))
+				}
+			}
+			for _, entry := range versions {
+				if !opts.TimeRef.IsZero() && !entry.ModTime.Before(opts.TimeRef) {
+					continue
+				}
+				if opts.WithDeleteMarkers || !entry.IsDeleteMarker {
+					if e := send(c.content(entry)); e != nil {
+						return "", e
+					}
+				}
+				if !opts.WithOlderVersions {
+					break
+				}
+			}
+			return "", nil
+		})
+		if err != nil {
+			send(&ClientContent{Err: err.Trace(c.GetURL().String())})
+		}
+	}()
+	return contentCh
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/openstor/mc/pkg/hookreader"
+	"github.com/openstor/openstor-go/v7"
+	"github.com/openstor/openstor-go/v7/pkg/encrypt"
+)
+
+// listUploadedParts returns all parts uploaded so far for uploadID.
+func (c *S3Client) listUploadedParts(ctx context.Context, bucket, object, uploadID string) (map[int]openstor.ObjectPart, error) {
+	core := openstor.Core{Client: c.api}
+
+	parts := make(map[int]openstor.ObjectPart)
+	partNumberMarker := 0
+	for {
+		result, e := core.ListObjectParts(ctx, bucket, object, uploadID, partNumberMarker, 1000)
+		if e != nil {
+			return nil, e
+		}
+		for _, part := range result.ObjectParts {
+			parts[part.PartNumber] = part
+		}
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		partNumberMarker = result.NextPartNumberMarker
+	}
+}
+
+// isResumablePut returns true if an upload of size bytes is made of
+// several parts, and can be resumed with the options of the upload.
+func isResumablePut(size int64, opts openstor.PutObjectOptions, putOpts PutOptions) bool {
+	if size < 0 || opts.DisableMultipart || opts.SendContentMd5 || opts.Checksum.IsSet() || putOpts.ifNotExists {
+		return false
+	}
+	totalPartsCount, _, _, e := openstor.OptimalPartInfo(size, opts.PartSize)
+	return e == nil && totalPartsCount > 1
+}
+
+// putObjectResumable uploads an object in parts like PutObject does, with
+// an upload ID known to the caller, so that an interrupted upload can be
+// continued later.
+//
+// When uploadID is the upload of an earlier run for a source unchanged
+// since, parts already present with the expected size are skipped and
+// only the missing ones are read from reader and uploaded.
+//
+// When uploadID is empty, or the upload is gone on the server, a new
+// upload is created and onUploadID is called before any part is uploaded.
+//
+// Uploads are never aborted, so that they can be resumed after errors too.
+func (c *S3Client) putObjectResumable(ctx context.Context, bucket, object, uploadID string, onUploadID func(string), reader io.Reader, size int64, opts openstor.PutObjectOptions) (openstor.UploadInfo, error) {
+	core := openstor.Core{Client: c.api}
+
+	var uploaded map[int]openstor.ObjectPart
+	if uploadID != "" {
+		var e error
+		uploaded, e = c.listUploadedParts(ctx, bucket, object, uploadID)
+		if e != nil {
+			if openstor.ToErrorResponse(e).Code != "NoSuchUpload" {
+				return openstor.UploadInfo{}, e
+			}
+			uploadID = ""
+		}
+	}
+	if uploadID == "" {
+		var e error
+		if uploadID, e = core.NewMultipartUpload(ctx, bucket, object, opts); e != nil {
+			return openstor.UploadInfo{}, e
+		}
+		if onUploadID != nil {
+			onUploadID(uploadID)
+		}
+	}
+
+	totalPartsCount, partSize, lastPartSize, e := openstor.OptimalPartInfo(size, opts.PartSize)
+	if e != nil {
+		return openstor.UploadInfo{}, e
+	}
+
+	var sse openstor.PutObjectPartOptions
+	if opts.ServerSideEncryption != nil && opts.ServerSideEncryption.Type() == encrypt.SSEC {
+		sse.SSE = opts.ServerSideEncryption
+	}
+
+	completeParts := make([]openstor.CompletePart, totalPartsCount)
+	uploadPart := func(partNumber int, data io.Reader, length int64, skip func() error) error {
+		if part, ok := uploaded[partNumber]; ok && part.Size == length {
+			completeParts[partNumber-1] = openstor.CompletePart{PartNumber: partNumber, ETag: part.ETag}
+			return skip()
+		}
+		part, e := core.PutObjectPart(ctx, bucket, object, uploadID, partNumber, data, length, sse)
+		if e != nil {
+			return e
+		}
+		completeParts[partNumber-1] = openstor.CompletePart{PartNumber: partNumber, ETag: part.ETag}
+		return nil
+	}
+	partLength := func(partNumber int) int64 {
+		if partNumber == totalPartsCount {
+			return lastPartSize
+		}
+		return partSize
+	}
+
+	if readerAt, ok := reader.(io.ReaderAt); ok {
+		// Parts are read independently, and uploaded in parallel.
+		e = uploadPartsAt(ctx, readerAt, opts.Progress, totalPartsCount, partSize, partLength, max(int(opts.NumThreads), 1), uploadPart)
+	} else {
+		for partNumber := 1; partNumber <= totalPartsCount && e == nil; partNumber++ {
+			length := partLength(partNumber)
+			data := hookreader.NewHook(io.LimitReader(reader, length), opts.Progress)
+			e = uploadPart(partNumber, data, length, func() error {
+				_, e := io.CopyN(io.Discard, data, length)
+				return e
+			})
+		}
+	}
+	if e != nil {
+		return openstor.UploadInfo{}, e
+	}
+
+	ui, e := core.CompleteMultipartUpload(ctx, bucket, object, uploadID, completeParts, openstor.PutObjectOptions{
+		ServerSideEncryption: opts.ServerSideEncryption,
+	})
+	if e != nil {
+		return ui, e
+	}
+	ui.Size = size
+	return ui, nil
+}
+
+// uploadPartsAt uploads the parts of readerAt with threads workers, it
+// stops at the first error.
+func uploadPartsAt(ctx context.Context, readerAt io.ReaderAt, progress io.Reader, totalPartsCount int, partSize int64, partLength func(int) int64, threads int, uploadPart func(int, io.Reader, int64, func() error) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	partNumbers := make(chan int)
+	for range threads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partNumber := range partNumbers {
+				length := partLength(partNumber)
+				section := io.NewSectionReader(readerAt, int64(partNumber-1)*partSize, length)
+				skip := func() error { return reportSkippedPart(progress, length) }
+				if e := uploadPart(partNumber, hookreader.NewHook(section, progress), length, skip); e != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = e
+					}
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+loop:
+	for partNumber := 1; partNumber <= totalPartsCount; partNumber++ {
+		select {
+		case partNumbers <- partNumber:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(partNumbers)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// reportSkippedPart reports the bytes of a part already present on the
+// server to the progress reader, without reading the part.
+func reportSkippedPart(progress io.Reader, length int64) error {
+	if progress == nil {
+		return nil
+	}
+	buf := make([]byte, min(length, 1<<20))
+	for length > 0 {
+		n := min(length, int64(len(buf)))
+		if _, e := progress.Read(buf[:n]); e != nil && e != io.EOF {
+			return e
+		}
+		length -= n
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/openstor/openstor-go/v7"
+)
+
+// multipartHandler is an http.Handler serving the multipart upload API
+// of a single bucket, it keeps uploads and completed objects in memory.
+type multipartHandler struct {
+	mu       sync.Mutex
+	uploads  map[string]map[int][]byte
+	objects  map[string][]byte
+	putParts []int
+	nextID   int
+}
+
+func newMultipartHandler() *multipartHandler {
+	return &multipartHandler{
+		uploads: make(map[string]map[int][]byte),
+		objects: make(map[string][]byte),
+	}
+}
+
+func partETag(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeXML(w http.ResponseWriter, status int, v any) {
+	buf, _ := xml.Marshal(v)
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+	w.WriteHeader(status)
+	w.Write(buf)
+}
+
+func (h *multipartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		writeXML(w, http.StatusOK, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+		}{})
+		return
+	}
+
+	uploadID := query.Get("uploadId")
+	parts, ok := h.uploads[uploadID]
+	if uploadID != "" && !ok {
+		writeXML(w, http.StatusNotFound, struct {
+			XMLName xml.Name `xml:"Error"`
+			Code    string
+			Message string
+		}{Code: "NoSuchUpload", Message: "The specified multipart upload does not exist."})
+		return
+	}
+
+	type part struct {
+		PartNumber int
+		ETag       string
+		Size       int64
+	}
+	switch {
+	case r.Method == http.MethodPost && uploadID == "":
+		h.nextID++
+		uploadID = fmt.Sprintf("upload-%d", h.nextID)
+		h.uploads[uploadID] = make(map[int][]byte)
+		writeXML(w, http.StatusOK, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadID string `xml:"UploadId"`
+		}{Bucket: "bucket", Key: "object", UploadID: uploadID})
+	case r.Method == http.MethodGet:
+		result := struct {
+			XMLName     xml.Name `xml:"ListPartsResult"`
+			UploadID    string   `xml:"UploadId"`
+			IsTruncated bool
+			Parts       []part `xml:"Part"`
+		}{UploadID: uploadID}
+		for _, partNumber := range slices.Sorted(maps.Keys(parts)) {
+			data := parts[partNumber]
+			result.Parts = append(result.Parts, part{partNumber, partETag(data), int64(len(data))})
+		}
+		writeXML(w, http.StatusOK, result)
+	case r.Method == http.MethodPut:
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		data, e := io.ReadAll(r.Body)
+		if e != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		parts[partNumber] = data
+		h.putParts = append(h.putParts, partNumber)
+		w.Header().Set("ETag", `"`+partETag(data)+`"`)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPost:
+		var complete struct {
+			Parts []part `xml:"Part"`
+		}
+		if e := xml.NewDecoder(r.Body).Decode(&complete); e != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var object []byte
+		for i, p := range complete.Parts {
+			data, ok := parts[p.PartNumber]
+			if !ok || p.PartNumber != i+1 || p.ETag != `"`+partETag(data)+`"` && p.ETag != partETag(data) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			object = append(object, data...)
+		}
+		h.objects[r.URL.Path] = object
+		delete(h.uploads, uploadID)
+		writeXML(w, http.StatusOK, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+			ETag    string
+		}{Bucket: "bucket", Key: "object", ETag: `"` + partETag(object) + `"`})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// onlyReader hides the io.ReaderAt of a reader.
+type onlyReader struct {
+	io.Reader
+}
+
+func TestPutObjectResumable(t *testing.T) {
+	const partSize = 5 << 20
+	data := make([]byte, 2*partSize+1<<20)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	testCases := []struct {
+		name         string
+		reader       func() io.Reader
+		resume       bool
+		uploadGone   bool
+		expectedPuts []int
+	}{
+		{"new upload", func() io.Reader { return bytes.NewReader(data) }, false, false, []int{1, 2, 3}},
+		{"resume", func() io.Reader { return bytes.NewReader(data) }, true, false, []int{2}},
+		{"resume stream", func() io.Reader { return onlyReader{bytes.NewReader(data)} }, true, false, []int{2}},
+		{"resume gone upload", func() io.Reader { return bytes.NewReader(data) }, true, true, []int{1, 2, 3}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := newMultipartHandler()
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			conf := new(Config)
+			conf.HostURL = server.URL + "/bucket/object"
+			conf.AccessKey = "WLGDGYAQYIGI833EV05A"
+			conf.SecretKey = "BYvgJM101sHngl2uzjXS/OBF/aMxAN06JrJ3qJlF"
+			conf.Signature = "S3v2"
+			s3c, err := S3New(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resumeUploadID string
+			if testCase.resume {
+				// An earlier run uploaded the first and last parts only.
+				resumeUploadID = "upload-0"
+				handler.uploads[resumeUploadID] = map[int][]byte{
+					1: data[:partSize],
+					3: data[2*partSize:],
+				}
+				if testCase.uploadGone {
+					delete(handler.uploads, resumeUploadID)
+				}
+			}
+
+			var recorded []string
+			n, err := s3c.Put(context.Background(), testCase.reader(), int64(len(data)), nil, PutOptions{
+				multipartSize:    partSize,
+				multipartThreads: 2,
+				resumeUploadID:   resumeUploadID,
+				onUploadID: func(uploadID string) {
+					recorded = append(recorded, uploadID)
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != int64(len(data)) {
+				t.Fatalf("expected %d bytes uploaded, got %d", len(data), n)
+			}
+
+			handler.mu.Lock()
+			defer handler.mu.Unlock()
+			if !bytes.Equal(handler.objects["/bucket/object"], data) {
+				t.Fatal("uploaded object differs from source")
+			}
+			puts := make(map[int]bool)
+			for _, partNumber := range handler.putParts {
+				puts[partNumber] = true
+			}
+			if len(puts) != len(testCase.expectedPuts) || len(handler.putParts) != len(testCase.expectedPuts) {
+				t.Fatalf("expected parts %v to be uploaded, got %v", testCase.expectedPuts, handler.putParts)
+			}
+			for _, partNumber := range testCase.expectedPuts {
+				if !puts[partNumber] {
+					t.Fatalf("expected parts %v to be uploaded, got %v", testCase.expectedPuts, handler.putParts)
+				}
+			}
+			if testCase.resume && !testCase.uploadGone {
+				if len(recorded) != 0 {
+					t.Fatalf("expected no new upload to be recorded, got %v", recorded)
+				}
+			} else if len(recorded) != 1 || recorded[0] != "upload-1" {
+				t.Fatalf("expected the created upload to be recorded, got %v", recorded)
+			}
+		})
+	}
+}
+
+func TestIsResumablePut(t *testing.T) {
+	const partSize = 5 << 20
+	testCases := []struct {
+		size     int64
+		putOpts  PutOptions
+		expected bool
+	}{
+		{3 * partSize, PutOptions{multipartSize: partSize}, true},
+		{partSize, PutOptions{multipartSize: partSize}, false},
+		{-1, PutOptions{multipartSize: partSize}, false},
+		{3 * partSize, PutOptions{multipartSize: partSize, disableMultipart: true}, false},
+		{3 * partSize, PutOptions{multipartSize: partSize, md5: true}, false},
+		{3 * partSize, PutOptions{multipartSize: partSize, ifNotExists: true}, false},
+	}
+	for i, testCase := range testCases {
+		opts := openstor.PutObjectOptions{
+			PartSize:         testCase.putOpts.multipartSize,
+			DisableMultipart: testCase.putOpts.disableMultipart,
+			SendContentMd5:   testCase.putOpts.md5,
+		}
+		if got := isResumablePut(testCase.size, opts, testCase.putOpts); got != testCase.expected {
+			t.Errorf("Test %d: expected %v, got %v", i+1, testCase.expected, got)
+		}
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
 	"path"
@@ -104,7 +105,7 @@ func newCustomDialContext(c *Config) dialContext {
 			KeepAlive: 15 * time.Second,
 		}
 
-		if ip, ok := globalResolvers[addr]; ok {
+		if ip, ok := c.resolvers()[addr]; ok {
 			if _, port, err := net.SplitHostPort(addr); err == nil {
 				addr = net.JoinHostPort(ip.String(), port)
 			} else {
@@ -126,7 +127,7 @@ func newCustomDialContext(c *Config) dialContext {
 }
 
 // newCustomDialTLSContext setups a custom TLS dialer for any external communication and proxies.
-func newCustomDialTLSContext(tlsConf *tls.Config) dialContext {
+func newCustomDialTLSContext(tlsConf *tls.Config, resolvers map[string]netip.Addr) dialContext {
 	return func(ctx context.Context, network, addr string) (net.Conn, error) {
 		dialer := &tls.Dialer{
 			NetDialer: &net.Dialer{
@@ -136,7 +137,7 @@ func newCustomDialTLSContext(tlsConf *tls.Config) dialContext {
 			Config: tlsConf,
 		}
 
-		if ip, ok := globalResolvers[addr]; ok {
+		if ip, ok := resolvers[addr]; ok {
 			dialer.Config = dialer.Config.Clone()
 			if host, port, err := net.SplitHostPort(addr); err == nil {
 				dialer.Config.ServerName = host // Set SNI
@@ -162,7 +163,9 @@ func getConfigHash(config *Config) uint32 {
 
 	// Generate a hash out of s3Conf.
 	confHash := fnv.New32a()
-	confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey + config.SessionToken))
+	confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey + config.SessionToken + config.Region))
+	confHash.Write([]byte(config.UploadLimit.Scope + config.UploadLimit.Schedule + config.DownloadLimit.Scope + config.DownloadLimit.Schedule))
+	confHash.Write([]byte(config.Profile))
 	confSum := confHash.Sum32()
 	return confSum
 }
@@ -258,7 +261,7 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 			options := openstor.Options{
 				Creds:           credentials.NewChainCredentials(credsChain),
 				Secure:          useTLS,
-				Region:          env.Get("MC_REGION", env.Get("AWS_REGION", "")),
+				Region:          env.Get("MC_REGION", env.Get("AWS_REGION", config.Region)),
 				BucketLookup:    config.Lookup,
 				Transport:       transport,
 				TrailingHeaders: useTrailingHeaders.Load(),
@@ -297,6 +300,26 @@ func (c *S3Client) GetURL() ClientURL {
 	return c.targetURL.Clone()
 }
 
+// notificationEventTypes translates an event name accepted by
+// 'mc event add' to the notification event types it stands for.
+func notificationEventTypes(event string) ([]notification.EventType, bool) {
+	switch event {
+	case "put":
+		return []notification.EventType{notification.ObjectCreatedAll}, true
+	case "delete":
+		return []notification.EventType{notification.ObjectRemovedAll}, true
+	case "get":
+		return []notification.EventType{notification.ObjectAccessedAll}, true
+	case "replica":
+		return []notification.EventType{"s3:Replication:*"}, true
+	case "ilm":
+		return []notification.EventType{"s3:ObjectRestore:*", "s3:ObjectTransition:*"}, true
+	case "scanner":
+		return []notification.EventType{"s3:Scanner:ManyVersions", "s3:Scanner:BigPrefix"}, true
+	}
+	return nil, false
+}
+
 // AddNotificationConfig - Add bucket notification
 func (c *S3Client) AddNotificationConfig(ctx context.Context, arn string, events []string, prefix, suffix string, ignoreExisting bool) *probe.Error {
 	bucket, _ := c.url2BucketAndObject()
@@ -315,24 +338,11 @@ func (c *S3Client) AddNotificationConfig(ctx context.Context, arn string, events
 
 	// Configure events
 	for _, event := range events {
-		switch event {
-		case "put":
-			nc.AddEvents(notification.ObjectCreatedAll)
-		case "delete":
-			nc.AddEvents(notification.ObjectRemovedAll)
-		case "get":
-			nc.AddEvents(notification.ObjectAccessedAll)
-		case "replica":
-			nc.AddEvents(notification.EventType("s3:Replication:*"))
-		case "ilm":
-			nc.AddEvents(notification.EventType("s3:ObjectRestore:*"))
-			nc.AddEvents(notification.EventType("s3:ObjectTransition:*"))
-		case "scanner":
-			nc.AddEvents(notification.EventType("s3:Scanner:ManyVersions"))
-			nc.AddEvents(notification.EventType("s3:Scanner:BigPrefix"))
-		default:
+		eventTypes, ok := notificationEventTypes(event)
+		if !ok {
 			return errInvalidArgument().Trace(events...)
 		}
+		nc.AddEvents(eventTypes...)
 	}
 	if prefix != "" {
 		nc.AddFilterPrefix(prefix)
@@ -396,24 +406,11 @@ func (c *S3Client) RemoveNotificationConfig(ctx context.Context, arn, event, pre
 		events := strings.Split(event, ",")
 		var eventsTyped []notification.EventType
 		for _, e := range events {
-			switch e {
-			case "put":
-				eventsTyped = append(eventsTyped, notification.ObjectCreatedAll)
-			case "delete":
-				eventsTyped = append(eventsTyped, notification.ObjectRemovedAll)
-			case "get":
-				eventsTyped = append(eventsTyped, notification.ObjectAccessedAll)
-			case "replica":
-				eventsTyped = append(eventsTyped, notification.EventType("s3:Replication:*"))
-			case "ilm":
-				eventsTyped = append(eventsTyped, notification.EventType("s3:ObjectRestore:*"))
-				eventsTyped = append(eventsTyped, notification.EventType("s3:ObjectTransition:*"))
-			case "scanner":
-				eventsTyped = append(eventsTyped, notification.EventType("s3:Scanner:ManyVersions"))
-				eventsTyped = append(eventsTyped, notification.EventType("s3:Scanner:BigPrefix"))
-			default:
+			eventTypes, ok := notificationEventTypes(e)
+			if !ok {
 				return errInvalidArgument().Trace(events...)
 			}
+			eventsTyped = append(eventsTyped, eventTypes...)
 		}
 		var err error
 		// based on the arn type, we'll look for the event in the corresponding sublist and delete it if there's a match
@@ -452,6 +449,43 @@ func (c *S3Client) RemoveNotificationConfig(ctx context.Context, arn, event, pre
 	return nil
 }
 
+// removeNotificationConfigExact removes the notification config with
+// exactly the ARN, event types and filters of config.
+func (c *S3Client) removeNotificationConfigExact(ctx context.Context, config NotificationConfig) *probe.Error {
+	bucket, _ := c.url2BucketAndObject()
+	mb, e := c.api.GetBucketNotification(ctx, bucket)
+	if e != nil {
+		return probe.NewError(e)
+	}
+
+	accountArn, e := notification.NewArnFromString(config.Arn)
+	if e != nil {
+		return probe.NewError(invalidArgumentErr(e)).Untrace()
+	}
+	events := make([]notification.EventType, 0, len(config.Events))
+	for _, event := range config.Events {
+		events = append(events, notification.EventType(event))
+	}
+	switch accountArn.Service {
+	case "sns":
+		e = mb.RemoveTopicByArnEventsPrefixSuffix(accountArn, events, config.Prefix, config.Suffix)
+	case "sqs":
+		e = mb.RemoveQueueByArnEventsPrefixSuffix(accountArn, events, config.Prefix, config.Suffix)
+	case "lambda":
+		e = mb.RemoveLambdaByArnEventsPrefixSuffix(accountArn, events, config.Prefix, config.Suffix)
+	default:
+		return errInvalidArgument().Trace(accountArn.Service)
+	}
+	if e != nil {
+		return probe.NewError(e)
+	}
+
+	if e = c.api.SetBucketNotification(ctx, bucket, mb); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
 // NotificationConfig notification config
 type NotificationConfig struct {
 	ID     string   `json:"id"`
@@ -1128,7 +1162,13 @@ func (c *S3Client) Put(ctx context.Context, reader io.Reader, size int64, progre
 		opts.SetMatchETagExcept("*")
 	}
 
-	ui, e := c.api.PutObject(ctx, bucket, object, reader, size, opts)
+	var ui openstor.UploadInfo
+	var e error
+	if (putOpts.resumeUploadID != "" || putOpts.onUploadID != nil) && isResumablePut(size, opts, putOpts) {
+		ui, e = c.putObjectResumable(ctx, bucket, object, putOpts.resumeUploadID, putOpts.onUploadID, reader, size, opts)
+	} else {
+		ui, e = c.api.PutObject(ctx, bucket, object, reader, size, opts)
+	}
 	if e != nil {
 		errResponse := openstor.ToErrorResponse(e)
 		if errResponse.Code == "UnexpectedEOF" || e == io.EOF {
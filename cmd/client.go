@@ -20,10 +20,12 @@ package cmd
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
 	"strings"
@@ -76,6 +78,8 @@ type PutOptions struct {
 	concurrentStream      bool
 	ifNotExists           bool
 	checksum              openstor.ChecksumType
+	resumeUploadID        string
+	onUploadID            func(uploadID string)
 }
 
 // StatOptions holds options of the HEAD operation
@@ -257,11 +261,43 @@ type Config struct {
 	Debug             bool
 	Insecure          bool
 	Lookup            openstor.BucketLookupType
+	Region            string
 	ConnReadDeadline  time.Duration
 	ConnWriteDeadline time.Duration
-	UploadLimit       int64
-	DownloadLimit     int64
+	UploadLimit       transferLimit
+	DownloadLimit     transferLimit
 	Transport         http.RoundTripper
+
+	// Connection settings of the alias profile, merged with the
+	// global ones. The global settings are used when not set.
+	Profile      string
+	CustomHeader http.Header
+	Resolvers    map[string]netip.Addr
+	RootCAs      *x509.CertPool
+}
+
+// customHeader returns the headers added to all requests.
+func (config *Config) customHeader() http.Header {
+	if config.CustomHeader != nil {
+		return config.CustomHeader
+	}
+	return globalCustomHeader
+}
+
+// resolvers returns the static host to IP mappings.
+func (config *Config) resolvers() map[string]netip.Addr {
+	if config.Resolvers != nil {
+		return config.Resolvers
+	}
+	return globalResolvers
+}
+
+// rootCAs returns the trusted certificate authorities.
+func (config *Config) rootCAs() *x509.CertPool {
+	if config.RootCAs != nil {
+		return config.RootCAs
+	}
+	return globalRootCAs
 }
 
 // getCredsChain returns an []credentials.Provider array for the config
@@ -358,10 +394,10 @@ func (config *Config) initTransport(withS3v2 bool) {
 		}
 		if useTLS {
 			tr.DialTLSContext = newCustomDialTLSContext(&tls.Config{
-				RootCAs:            globalRootCAs,
+				RootCAs:            config.rootCAs(),
 				MinVersion:         tls.VersionTLS12,
 				InsecureSkipVerify: config.Insecure,
-			})
+			}, config.resolvers())
 
 			// Because we create a custom TLSClientConfig, we have to opt-in to HTTP/2.
 			// See https://github.com/golang/go/issues/14275
@@ -373,10 +409,10 @@ func (config *Config) initTransport(withS3v2 bool) {
 			// }
 		}
 
-		if len(globalCustomHeader) > 0 {
+		if customHeader := config.customHeader(); len(customHeader) > 0 {
 			transport = &headerTransport{
 				RoundTripper: tr,
-				customHeader: globalCustomHeader.Clone(),
+				customHeader: customHeader.Clone(),
 			}
 		} else {
 			transport = tr
@@ -384,7 +420,7 @@ func (config *Config) initTransport(withS3v2 bool) {
 
 	}
 
-	transport = limiter.New(config.UploadLimit, config.DownloadLimit, transport)
+	transport = limiter.NewWithLimits(getTransferLimiter(config.UploadLimit, "upload"), getTransferLimiter(config.DownloadLimit, "download"), transport)
 
 	if config.Debug {
 		if strings.EqualFold(config.Signature, "S3v4") {
@@ -398,6 +434,10 @@ func (config *Config) initTransport(withS3v2 bool) {
 		}
 	}
 
+	if globalTraceRecorder != nil {
+		transport = globalTraceRecorder.Transport(transport)
+	}
+
 	transport = gzhttp.Transport(transport)
 	config.Transport = transport
 }
@@ -32,6 +32,7 @@ import (
 	"golang.org/x/net/http/httpguts"
 
 	"github.com/dustin/go-humanize"
+	"github.com/openstor/mc/pkg/hookreader"
 	"github.com/openstor/mc/pkg/probe"
 	"github.com/openstor/openstor-go/v7"
 	"github.com/openstor/openstor-go/v7/pkg/encrypt"
@@ -87,7 +88,7 @@ func isAliasURLDir(ctx context.Context, aliasURL string, keys map[string][]prefi
 }
 
 // getSourceStreamMetadataFromURL gets a reader from URL.
-func getSourceStreamMetadataFromURL(ctx context.Context, aliasedURL, versionID string, timeRef time.Time, encKeyDB map[string][]prefixSSEPair, zip bool) (reader io.ReadCloser,
+func getSourceStreamMetadataFromURL(ctx context.Context, aliasedURL, versionID string, timeRef time.Time, encKeyDB map[string][]prefixSSEPair, zip bool, cse *clientEncryption) (reader io.ReadCloser,
 	content *ClientContent, err *probe.Error,
 ) {
 	alias, urlStrFull, _, err := expandAlias(aliasedURL)
@@ -107,12 +108,14 @@ func getSourceStreamMetadataFromURL(ctx context.Context, aliasedURL, versionID s
 			VersionID: versionID,
 			Zip:       zip,
 		},
+		cse: cse,
 	})
 }
 
 type getSourceOpts struct {
 	GetOptions
 	preserve bool
+	cse      *clientEncryption
 }
 
 // getSourceStreamFromURL gets a reader from URL.
@@ -156,11 +159,44 @@ func getSourceStream(ctx context.Context, alias, urlStr string, opts getSourceOp
 		return nil, nil, err.Trace(alias, urlStr)
 	}
 
+	// Client-side encrypted objects can only be decrypted from
+	// the beginning, the requested offset is skipped once decrypted.
+	var offset int64
+	if opts.cse != nil && (opts.RangeStart != 0 || opts.PartNumber != 0) {
+		st, err := sourceClnt.Stat(ctx, StatOptions{versionID: opts.VersionID, sse: opts.SSE})
+		if err != nil {
+			return nil, nil, err.Trace(alias, urlStr)
+		}
+		if isClientEncrypted(st) {
+			if opts.PartNumber != 0 {
+				return nil, nil, errInvalidArgument().Trace(alias, urlStr)
+			}
+			offset, opts.RangeStart = opts.RangeStart, 0
+		}
+	}
+
 	reader, content, err = sourceClnt.Get(ctx, opts.GetOptions)
 	if err != nil {
 		return nil, nil, err.Trace(alias, urlStr)
 	}
 
+	if opts.cse != nil && isClientEncrypted(content) {
+		decReader, err := opts.cse.decrypt(reader, content)
+		if err != nil {
+			reader.Close()
+			return nil, nil, err.Trace(alias, urlStr)
+		}
+		content.Size, _ = clientDecryptedSize(content)
+		if offset > 0 {
+			if _, e := io.CopyN(io.Discard, decReader, offset); e != nil {
+				decReader.Close()
+				return nil, nil, probe.NewError(e).Trace(alias, urlStr)
+			}
+			content.Size -= offset
+		}
+		reader = decReader
+	}
+
 	return reader, content, nil
 }
 
@@ -354,7 +390,7 @@ func uploadSourceToTargetURL(ctx context.Context, uploadOpts uploadSourceToTarge
 	}
 
 	// Optimize for server side copy if the host is same.
-	if sourceAlias == targetAlias && !uploadOpts.isZip && !uploadOpts.urls.checksum.IsSet() {
+	if sourceAlias == targetAlias && !uploadOpts.isZip && !uploadOpts.urls.checksum.IsSet() && uploadOpts.cse == nil {
 		// preserve new metadata and save existing ones.
 		if uploadOpts.preserve {
 			currentMetadata, err := getAllMetadata(ctx, sourceAlias, sourceURL.String(), srcSSE, uploadOpts.urls)
@@ -433,12 +469,18 @@ func uploadSourceToTargetURL(ctx context.Context, uploadOpts uploadSourceToTarge
 				Zip:       uploadOpts.isZip,
 				Preserve:  uploadOpts.preserve,
 			},
+			cse: uploadOpts.cse,
 		})
 		if err != nil {
 			return uploadOpts.urls.WithError(err.Trace(sourceURL.String()))
 		}
 		defer reader.Close()
 
+		// Listings report the encrypted size of client-side encrypted objects.
+		if uploadOpts.cse != nil && sourceAlias != "" {
+			length = content.Size
+		}
+
 		if uploadOpts.updateProgressTotal {
 			pg, ok := uploadOpts.progress.(*progressBar)
 			if ok {
@@ -450,6 +492,9 @@ func uploadSourceToTargetURL(ctx context.Context, uploadOpts uploadSourceToTarge
 		for k, v := range content.Metadata {
 			metadata[k] = v
 		}
+		if uploadOpts.cse != nil {
+			removeClientEncryptionMetadata(metadata)
+		}
 
 		// Get metadata from target content as well
 		for k, v := range uploadOpts.urls.TargetContent.Metadata {
@@ -475,7 +520,8 @@ func uploadSourceToTargetURL(ctx context.Context, uploadOpts uploadSourceToTarge
 		var multipartThreads int
 		var v string
 		if uploadOpts.multipartSize == "" {
-			v = env.Get("MC_UPLOAD_MULTIPART_SIZE", "")
+			profile, _ := getAliasProfile(targetAlias)
+			v = env.Get("MC_UPLOAD_MULTIPART_SIZE", profile.MultipartSize)
 		} else {
 			v = uploadOpts.multipartSize
 		}
@@ -508,7 +554,32 @@ func uploadSourceToTargetURL(ctx context.Context, uploadOpts uploadSourceToTarge
 			checksum:         uploadOpts.urls.checksum,
 		}
 
-		if isReadAt(reader) || length == 0 {
+		// Multipart uploads of journaled sessions are resumable, client-side
+		// encrypted uploads use a new data key every time and are not.
+		if s := uploadOpts.session; s != nil && uploadOpts.cse == nil && isResumableUpload(putOpts.md5, putOpts.checksum) {
+			source := sourceURL.String()
+			putOpts.resumeUploadID = s.ResumableUploadID(uploadOpts.urls)
+			putOpts.onUploadID = func(uploadID string) {
+				s.SetUploadID(source, uploadID)
+			}
+		}
+
+		if uploadOpts.cse != nil && targetAlias != "" {
+			// Progress is reported on the plaintext, the encrypted
+			// stream is slightly larger than the source.
+			encReader, encLength, encMetadata, err := uploadOpts.cse.encrypt(hookreader.NewHook(reader, uploadOpts.progress), length)
+			if err != nil {
+				return uploadOpts.urls.WithError(err.Trace(sourceURL.String()))
+			}
+			for k, v := range encMetadata {
+				putOpts.metadata[k] = v
+			}
+			_, err = putTargetStream(ctx, targetAlias, targetURL.String(), mode, until,
+				legalHold, encReader, encLength, nil, putOpts)
+			if err != nil {
+				return uploadOpts.urls.WithError(err.Trace(sourceURL.String()))
+			}
+		} else if isReadAt(reader) || length == 0 {
 			_, err = putTargetStream(ctx, targetAlias, targetURL.String(), mode, until,
 				legalHold, reader, length, uploadOpts.progress, putOpts)
 		} else {
@@ -589,4 +660,6 @@ type uploadSourceToTargetURLOpts struct {
 	multipartThreads    string
 	updateProgressTotal bool
 	ifNotExists         bool
+	session             *sessionV8
+	cse                 *clientEncryption
 }
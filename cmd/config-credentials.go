@@ -0,0 +1,422 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/env"
+	"github.com/secure-io/sio-go/sioutil"
+)
+
+// Credential stores keeping the secrets of an alias out of the config file.
+//
+//   - "keystore": a local file encrypted with a key derived from a passphrase.
+//   - "helper:NAME": an external process following the git credential helper
+//     protocol. NAME runs `mc-credential-NAME`, an absolute path runs that
+//     program and a leading `!` runs the rest as a shell command, with
+//     `sh -c`, or `cmd /C` on Windows.
+const (
+	credentialStoreKeystore = "keystore"
+	credentialHelperPrefix  = "helper:"
+
+	keystoreVersion = "1"
+)
+
+// keystoreV1 - passphrase protected keystore file.
+type keystoreV1 struct {
+	Version string `json:"version"`
+	Salt    string `json:"salt"`
+	Sealed  string `json:"sealed"`
+}
+
+// aliasCredentials - secrets of an alias kept in a credential store.
+type aliasCredentials struct {
+	SecretKey    string `json:"secretKey"`
+	SessionToken string `json:"sessionToken,omitempty"`
+}
+
+// CredentialsUnavailable - the secrets of an alias cannot be
+// retrieved from its credential store.
+type CredentialsUnavailable struct {
+	Alias string
+	Store string
+	Err   error
+}
+
+func (e CredentialsUnavailable) Error() string {
+	return "Unable to retrieve the credentials of alias `" + e.Alias + "` from `" + e.Store + "`: " + e.Err.Error()
+}
+
+var (
+	// All access to credential stores should be synchronized,
+	// resolved secrets are cached for the lifetime of the process.
+	credentialsMutex    = &sync.Mutex{}
+	resolvedCredentials = make(map[string]aliasCredentials)
+
+	// unlocked keystore.
+	keystoreKey     []byte
+	keystoreSalt    []byte
+	keystoreEntries map[string]aliasCredentials
+)
+
+// Get keystore file path.
+func getKeystorePath() string {
+	return filepath.Join(mustGetMcConfigDir(), globalMCKeystoreFile)
+}
+
+// resolveAliasCredentials fills in the secrets of an alias referencing
+// a credential store. Secrets already present are kept as they are.
+func resolveAliasCredentials(alias string, aliasCfg *aliasConfigV11) *probe.Error {
+	if aliasCfg.CredentialStore == "" || aliasCfg.SecretKey != "" {
+		return nil
+	}
+
+	credentialsMutex.Lock()
+	defer credentialsMutex.Unlock()
+
+	creds, ok := resolvedCredentials[alias]
+	if !ok {
+		var e error
+		switch {
+		case aliasCfg.CredentialStore == credentialStoreKeystore:
+			if e = unlockKeystore(); e == nil {
+				if creds, ok = keystoreEntries[alias]; !ok {
+					e = errors.New("no credentials found in keystore")
+				}
+			}
+		case strings.HasPrefix(aliasCfg.CredentialStore, credentialHelperPrefix):
+			creds, e = runCredentialHelper(aliasCfg.CredentialStore, "get", *aliasCfg, aliasCredentials{})
+			if e == nil && creds.SecretKey == "" {
+				e = errors.New("credential helper returned no password")
+			}
+		default:
+			e = errors.New("unknown credential store")
+		}
+		if e != nil {
+			return probe.NewError(CredentialsUnavailable{Alias: alias, Store: aliasCfg.CredentialStore, Err: e})
+		}
+		resolvedCredentials[alias] = creds
+	}
+
+	aliasCfg.SecretKey = creds.SecretKey
+	aliasCfg.SessionToken = creds.SessionToken
+	return nil
+}
+
+// storeAliasCredentials saves the secrets of an alias in its credential
+// store and removes them from aliasCfg, so that they are never written
+// to the config file.
+func storeAliasCredentials(alias string, aliasCfg *aliasConfigV11) *probe.Error {
+	if aliasCfg.CredentialStore == "" {
+		return nil
+	}
+
+	credentialsMutex.Lock()
+	defer credentialsMutex.Unlock()
+
+	creds := aliasCredentials{SecretKey: aliasCfg.SecretKey, SessionToken: aliasCfg.SessionToken}
+	switch {
+	case aliasCfg.CredentialStore == credentialStoreKeystore:
+		if e := unlockKeystore(); e != nil {
+			return probe.NewError(e).Trace(alias)
+		}
+		keystoreEntries[alias] = creds
+		if e := saveKeystore(); e != nil {
+			return probe.NewError(e).Trace(alias)
+		}
+	case strings.HasPrefix(aliasCfg.CredentialStore, credentialHelperPrefix):
+		if _, e := runCredentialHelper(aliasCfg.CredentialStore, "store", *aliasCfg, creds); e != nil {
+			return probe.NewError(e).Trace(alias)
+		}
+	default:
+		return errInvalidArgument().Trace(aliasCfg.CredentialStore)
+	}
+
+	resolvedCredentials[alias] = creds
+	aliasCfg.SecretKey = ""
+	aliasCfg.SessionToken = ""
+	return nil
+}
+
+// eraseAliasCredentials removes the secrets of an alias from its credential store.
+func eraseAliasCredentials(alias string, aliasCfg aliasConfigV11) *probe.Error {
+	if aliasCfg.CredentialStore == "" {
+		return nil
+	}
+
+	credentialsMutex.Lock()
+	defer credentialsMutex.Unlock()
+
+	delete(resolvedCredentials, alias)
+	switch {
+	case aliasCfg.CredentialStore == credentialStoreKeystore:
+		if _, e := os.Stat(getKeystorePath()); os.IsNotExist(e) {
+			return nil
+		}
+		if e := unlockKeystore(); e != nil {
+			return probe.NewError(e).Trace(alias)
+		}
+		if _, ok := keystoreEntries[alias]; !ok {
+			return nil
+		}
+		delete(keystoreEntries, alias)
+		if e := saveKeystore(); e != nil {
+			return probe.NewError(e).Trace(alias)
+		}
+	case strings.HasPrefix(aliasCfg.CredentialStore, credentialHelperPrefix):
+		if _, e := runCredentialHelper(aliasCfg.CredentialStore, "erase", aliasCfg, aliasCredentials{}); e != nil {
+			return probe.NewError(e).Trace(alias)
+		}
+	}
+	return nil
+}
+
+// profileEncKeyName names the encryption key of a profile in credential
+// stores, alias names never contain a colon.
+func profileEncKeyName(name string) string {
+	return "profile:" + name
+}
+
+// profileEncKeyConfig describes the encryption key of a profile to the
+// credential stores, credential helpers see it as protocol "mc-profile",
+// host NAME and username "enc-c".
+func profileEncKeyConfig(name string, profile profileConfigV11) aliasConfigV11 {
+	return aliasConfigV11{
+		URL:             "mc-profile://" + name,
+		AccessKey:       "enc-c",
+		SecretKey:       profile.EncC,
+		CredentialStore: profile.EncCStore,
+	}
+}
+
+// resolveProfileEncKey fills in the encryption key of a profile from
+// its credential store.
+func resolveProfileEncKey(name string, profile *profileConfigV11) *probe.Error {
+	if profile.EncCStore == "" || profile.EncC != "" {
+		return nil
+	}
+	keyCfg := profileEncKeyConfig(name, *profile)
+	if err := resolveAliasCredentials(profileEncKeyName(name), &keyCfg); err != nil {
+		return err.Trace(name)
+	}
+	profile.EncC = keyCfg.SecretKey
+	return nil
+}
+
+// storeProfileEncKey saves the encryption key of a profile in its
+// credential store and removes it from profile, so that it is never
+// written to the config file.
+func storeProfileEncKey(name string, profile *profileConfigV11) *probe.Error {
+	if profile.EncC == "" {
+		return nil
+	}
+	if profile.EncCStore == "" {
+		return errInvalidArgument().Trace(name)
+	}
+	keyCfg := profileEncKeyConfig(name, *profile)
+	if err := storeAliasCredentials(profileEncKeyName(name), &keyCfg); err != nil {
+		return err.Trace(name)
+	}
+	profile.EncC = ""
+	return nil
+}
+
+// eraseProfileEncKey removes the encryption key of a profile from its
+// credential store.
+func eraseProfileEncKey(name string, profile profileConfigV11) *probe.Error {
+	return eraseAliasCredentials(profileEncKeyName(name), profileEncKeyConfig(name, profile))
+}
+
+// keystoreAssociatedData binds the sealed keystore to its version.
+func keystoreAssociatedData() []byte {
+	return []byte("mc-keystore-v" + keystoreVersion)
+}
+
+// unlockKeystore loads and decrypts the keystore, a new keystore is
+// initialized if none exists yet. The passphrase is asked only once.
+func unlockKeystore() error {
+	if keystoreEntries != nil {
+		return nil
+	}
+
+	data, e := os.ReadFile(getKeystorePath())
+	if os.IsNotExist(e) {
+		passphrase, err := readPassphrase("MC_KEYSTORE_PASSPHRASE", "Enter new keystore passphrase: ")
+		if err != nil {
+			return err.ToGoError()
+		}
+		if !env.IsSet("MC_KEYSTORE_PASSPHRASE") {
+			confirm, err := readPassphrase("MC_KEYSTORE_PASSPHRASE", "Confirm keystore passphrase: ")
+			if err != nil {
+				return err.ToGoError()
+			}
+			if confirm != passphrase {
+				return errors.New("passphrases do not match")
+			}
+		}
+		keystoreSalt = sioutil.MustRandom(32)
+		keystoreKey = derivePassphraseKey([]byte(passphrase), keystoreSalt)
+		keystoreEntries = make(map[string]aliasCredentials)
+		return nil
+	}
+	if e != nil {
+		return e
+	}
+
+	var ks keystoreV1
+	if e = json.Unmarshal(data, &ks); e != nil {
+		return e
+	}
+	if ks.Version != keystoreVersion {
+		return fmt.Errorf("unsupported keystore version `%s`", ks.Version)
+	}
+	salt, e := base64.StdEncoding.DecodeString(ks.Salt)
+	if e != nil {
+		return e
+	}
+	sealed, e := base64.StdEncoding.DecodeString(ks.Sealed)
+	if e != nil {
+		return e
+	}
+
+	passphrase, err := readPassphrase("MC_KEYSTORE_PASSPHRASE", "Enter keystore passphrase: ")
+	if err != nil {
+		return err.ToGoError()
+	}
+	key := derivePassphraseKey([]byte(passphrase), salt)
+	plaintext, e := unsealKey(key, sealed, keystoreAssociatedData())
+	if e != nil {
+		return errors.New("wrong keystore passphrase")
+	}
+	entries := make(map[string]aliasCredentials)
+	if e = json.Unmarshal(plaintext, &entries); e != nil {
+		return e
+	}
+
+	keystoreKey, keystoreSalt, keystoreEntries = key, salt, entries
+	return nil
+}
+
+// saveKeystore encrypts and writes the unlocked keystore.
+func saveKeystore() error {
+	plaintext, e := json.Marshal(keystoreEntries)
+	if e != nil {
+		return e
+	}
+	sealed, e := sealKey(keystoreKey, plaintext, keystoreAssociatedData())
+	if e != nil {
+		return e
+	}
+	data, e := json.MarshalIndent(keystoreV1{
+		Version: keystoreVersion,
+		Salt:    base64.StdEncoding.EncodeToString(keystoreSalt),
+		Sealed:  base64.StdEncoding.EncodeToString(sealed),
+	}, "", "\t")
+	if e != nil {
+		return e
+	}
+
+	if e = os.MkdirAll(mustGetMcConfigDir(), 0o700); e != nil {
+		return e
+	}
+	// Write to a temporary file first, a partially written keystore loses all secrets.
+	tmpPath := getKeystorePath() + ".tmp"
+	if e = os.WriteFile(tmpPath, data, 0o600); e != nil {
+		return e
+	}
+	return os.Rename(tmpPath, getKeystorePath())
+}
+
+// credentialHelperCommand returns the command running a credential helper.
+func credentialHelperCommand(store, operation string) (*exec.Cmd, error) {
+	helper := strings.TrimSpace(strings.TrimPrefix(store, credentialHelperPrefix))
+	if helper == "" {
+		return nil, errors.New("credential helper is empty")
+	}
+	if shellCmd, ok := strings.CutPrefix(helper, "!"); ok {
+		if runtime.GOOS == "windows" {
+			return exec.Command("cmd", "/C", shellCmd+" "+operation), nil
+		}
+		return exec.Command("sh", "-c", shellCmd+` "$@"`, shellCmd, operation), nil
+	}
+	args := strings.Fields(helper)
+	if !filepath.IsAbs(args[0]) {
+		args[0] = "mc-credential-" + args[0]
+	}
+	return exec.Command(args[0], append(args[1:], operation)...), nil
+}
+
+// runCredentialHelper runs the get, store or erase operation of a
+// credential helper. Like git, credentials are described by protocol,
+// host and username, and passed as key=value lines on standard input.
+func runCredentialHelper(store, operation string, aliasCfg aliasConfigV11, creds aliasCredentials) (aliasCredentials, error) {
+	cmd, e := credentialHelperCommand(store, operation)
+	if e != nil {
+		return creds, e
+	}
+
+	var input bytes.Buffer
+	if u, e := url.Parse(aliasCfg.URL); e == nil {
+		fmt.Fprintf(&input, "protocol=%s\nhost=%s\n", u.Scheme, u.Host)
+	}
+	fmt.Fprintf(&input, "username=%s\n", aliasCfg.AccessKey)
+	if operation == "store" {
+		fmt.Fprintf(&input, "password=%s\n", creds.SecretKey)
+		if creds.SessionToken != "" {
+			fmt.Fprintf(&input, "session_token=%s\n", creds.SessionToken)
+		}
+	}
+	input.WriteString("\n")
+
+	var output bytes.Buffer
+	cmd.Stdin = &input
+	cmd.Stdout = &output
+	cmd.Stderr = os.Stderr
+	if e = cmd.Run(); e != nil {
+		return creds, fmt.Errorf("credential helper `%s %s` failed: %w", cmd.Path, operation, e)
+	}
+
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "password":
+			creds.SecretKey = value
+		case "session_token":
+			creds.SessionToken = value
+		}
+	}
+	return creds, scanner.Err()
+}
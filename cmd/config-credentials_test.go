@@ -0,0 +1,152 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/openstor/pkg/v3/quick"
+)
+
+func TestIsValidCredentialStore(t *testing.T) {
+	testCases := []struct {
+		store string
+		valid bool
+	}{
+		{"", true},
+		{"keystore", true},
+		{"helper:pass", true},
+		{"helper:!pass show mc", true},
+		{"helper:", false},
+		{"helper:  ", false},
+		{"vault", false},
+	}
+	for _, testCase := range testCases {
+		if valid := isValidCredentialStore(testCase.store); valid != testCase.valid {
+			t.Errorf("%q: expected %v, got %v", testCase.store, testCase.valid, valid)
+		}
+	}
+}
+
+func TestKeystoreCredentials(t *testing.T) {
+	prevConfigDir := mcCustomConfigDir
+	mcCustomConfigDir = t.TempDir()
+	t.Setenv("MC_KEYSTORE_PASSPHRASE", "correct horse battery staple")
+	resetKeystore := func() {
+		keystoreKey, keystoreSalt, keystoreEntries = nil, nil, nil
+		resolvedCredentials = make(map[string]aliasCredentials)
+	}
+	defer func() {
+		mcCustomConfigDir = prevConfigDir
+		resetKeystore()
+	}()
+
+	aliasCfg := aliasConfigV11{AccessKey: "minio", SecretKey: "minio123", CredentialStore: credentialStoreKeystore}
+	if err := storeAliasCredentials("myminio", &aliasCfg); err != nil {
+		t.Fatal(err)
+	}
+	if aliasCfg.SecretKey != "" {
+		t.Fatal("expected secret key to be removed from the alias config")
+	}
+
+	// Reload the keystore from disk.
+	resetKeystore()
+	if err := resolveAliasCredentials("myminio", &aliasCfg); err != nil {
+		t.Fatal(err)
+	}
+	if aliasCfg.SecretKey != "minio123" {
+		t.Fatalf("expected secret key `minio123`, got `%s`", aliasCfg.SecretKey)
+	}
+
+	resetKeystore()
+	t.Setenv("MC_KEYSTORE_PASSPHRASE", "wrong")
+	aliasCfg.SecretKey = ""
+	err := resolveAliasCredentials("myminio", &aliasCfg)
+	if err == nil {
+		t.Fatal("expected a wrong passphrase to fail")
+	}
+	if _, ok := err.ToGoError().(CredentialsUnavailable); !ok {
+		t.Fatalf("expected CredentialsUnavailable, got %T", err.ToGoError())
+	}
+}
+
+func TestCredentialHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the helper of this test is a POSIX shell function")
+	}
+	aliasCfg := aliasConfigV11{
+		URL:             "https://play.min.io",
+		AccessKey:       "minio",
+		CredentialStore: `helper:!f() { test "$1" = get && grep -q host=play.min.io && echo password=minio123 && echo session_token=token; }; f`,
+	}
+	creds, e := runCredentialHelper(aliasCfg.CredentialStore, "get", aliasCfg, aliasCredentials{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if creds.SecretKey != "minio123" || creds.SessionToken != "token" {
+		t.Fatalf("unexpected credentials %+v", creds)
+	}
+}
+
+func TestMigrateConfigV10ToV11(t *testing.T) {
+	prevConfigDir := mcCustomConfigDir
+	mcCustomConfigDir = t.TempDir()
+	defer func() { mcCustomConfigDir = prevConfigDir }()
+
+	cfgV10 := newConfigV10()
+	cfgV10.Aliases["myminio"] = aliasConfigV10{
+		URL:       "https://localhost:9000",
+		AccessKey: "minio",
+		SecretKey: "minio123",
+		API:       "S3v4",
+		Path:      "auto",
+	}
+	qc, e := quick.NewConfig(cfgV10, nil)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = qc.Save(mustGetMcConfigPath()); e != nil {
+		t.Fatal(e)
+	}
+
+	migrateConfigV10ToV11()
+
+	qc, e = quick.LoadConfig(mustGetMcConfigPath(), nil, newConfigV11())
+	if e != nil {
+		t.Fatal(e)
+	}
+	cfgV11 := qc.Data().(*configV11)
+	if cfgV11.Version != "11" {
+		t.Fatalf("expected version 11, got %s", cfgV11.Version)
+	}
+	expected := aliasConfigV11{
+		URL:       "https://localhost:9000",
+		AccessKey: "minio",
+		SecretKey: "minio123",
+		API:       "S3v4",
+		Path:      "auto",
+	}
+	if got := cfgV11.Aliases["myminio"]; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+	if len(cfgV11.Aliases) != 1 {
+		t.Fatalf("expected 1 alias, got %d", len(cfgV11.Aliases))
+	}
+}
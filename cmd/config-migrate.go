@@ -48,6 +48,8 @@ func migrateConfig() {
 	migrateConfigV8ToV9()
 	// Migrate config V9 to V10
 	migrateConfigV9ToV10()
+	// Migrate config V10 to V11
+	migrateConfigV10ToV11()
 }
 
 // Migrate from config version 1.0 to 1.0.1. Populate example entries and save it back.
@@ -541,3 +543,49 @@ func migrateConfigV9ToV10() {
 
 	console.Infof("Successfully migrated %s from version `9` to version `10`.\n", mustGetMcConfigPath())
 }
+
+// Migrate config version `10` to `11`. Add credential stores of aliases.
+func migrateConfigV10ToV11() {
+	if !isMcConfigExists() {
+		return
+	}
+
+	// Check the config version and quit early if the actual version is out of this function scope
+	anyCfg, e := quick.LoadConfig(mustGetMcConfigPath(), nil, &ConfigAnyVersion{})
+	fatalIf(probe.NewError(e), "Unable to load config version.")
+	if anyCfg.Version() != "10" {
+		return
+	}
+
+	mcCfgV10, e := quick.LoadConfig(mustGetMcConfigPath(), nil, newConfigV10())
+	fatalIf(probe.NewError(e), "Unable to load mc config V10.")
+
+	cfgV11 := newConfigV11()
+	isEmpty := true
+	for alias, aliasCfgV10 := range mcCfgV10.Data().(*configV10).Aliases {
+		isEmpty = false
+		cfgV11.Aliases[alias] = aliasConfigV11{
+			URL:          aliasCfgV10.URL,
+			AccessKey:    aliasCfgV10.AccessKey,
+			SecretKey:    aliasCfgV10.SecretKey,
+			SessionToken: aliasCfgV10.SessionToken,
+			API:          aliasCfgV10.API,
+			Path:         aliasCfgV10.Path,
+			License:      aliasCfgV10.License,
+			APIKey:       aliasCfgV10.APIKey,
+		}
+	}
+
+	if isEmpty {
+		// Load default settings.
+		cfgV11.loadDefaults()
+	}
+
+	mcNewCfgV11, e := quick.NewConfig(cfgV11, nil)
+	fatalIf(probe.NewError(e), "Unable to initialize quick config for config version `11`.")
+
+	e = mcNewCfgV11.Save(mustGetMcConfigPath())
+	fatalIf(probe.NewError(e), "Unable to save config version `11`.")
+
+	console.Infof("Successfully migrated %s from version `10` to version `11`.\n", mustGetMcConfigPath())
+}
@@ -332,4 +332,75 @@ func newConfigV9() *configV9 {
 }
 
 /////////////////// Config V10 ///////////////////
-// RESERVED FOR FUTURE
+
+// aliasConfigV10 configuration of an alias.
+type aliasConfigV10 struct {
+	URL          string `json:"url"`
+	AccessKey    string `json:"accessKey"`
+	SecretKey    string `json:"secretKey"`
+	SessionToken string `json:"sessionToken,omitempty"`
+	API          string `json:"api"`
+	Path         string `json:"path"`
+	License      string `json:"license,omitempty"`
+	APIKey       string `json:"apiKey,omitempty"`
+	Src          string `json:"src,omitempty"`
+}
+
+// configV10 config version.
+type configV10 struct {
+	Version string                    `json:"version"`
+	Aliases map[string]aliasConfigV10 `json:"aliases"`
+}
+
+func newConfigV10() *configV10 {
+	cfg := new(configV10)
+	cfg.Version = "10"
+	cfg.Aliases = make(map[string]aliasConfigV10)
+	return cfg
+}
+
+// SetAlias sets host config if not empty.
+func (c *configV10) setAlias(alias string, cfg aliasConfigV10) {
+	if _, ok := c.Aliases[alias]; !ok {
+		c.Aliases[alias] = cfg
+	}
+}
+
+// load default values for missing entries.
+func (c *configV10) loadDefaults() {
+	// MinIO server running locally.
+	c.setAlias("local", aliasConfigV10{
+		URL:       "http://localhost:9000",
+		AccessKey: "",
+		SecretKey: "",
+		API:       "S3v4",
+		Path:      "auto",
+	})
+
+	// Amazon S3 cloud storage service.
+	c.setAlias("s3", aliasConfigV10{
+		URL:       "https://s3.amazonaws.com",
+		AccessKey: defaultAccessKey,
+		SecretKey: defaultSecretKey,
+		API:       "S3v4",
+		Path:      "dns",
+	})
+
+	// Google cloud storage service.
+	c.setAlias("gcs", aliasConfigV10{
+		URL:       "https://storage.googleapis.com",
+		AccessKey: defaultAccessKey,
+		SecretKey: defaultSecretKey,
+		API:       "S3v2",
+		Path:      "dns",
+	})
+
+	// MinIO anonymous server for demo.
+	c.setAlias("play", aliasConfigV10{
+		URL:       "https://play.min.io",
+		AccessKey: "Q3AM3UQ867SPQQA43P2F",
+		SecretKey: "zuf+tfteSlswRu7BJ86wekitnifILbZam1KYY3TG",
+		API:       "S3v4",
+		Path:      "auto",
+	})
+}
@@ -0,0 +1,354 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"crypto/x509"
+	"maps"
+	"net/http"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+	"github.com/openstor/mc/pkg/limiter"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/urfave/cli/v3"
+)
+
+// profileKeys lists the keys accepted by `mc alias profile set`, each
+// key is named after the command line flag it provides a default for.
+var profileKeys = []string{
+	"limit-upload",
+	"limit-download",
+	"insecure",
+	"custom-header",
+	"resolve",
+	"ca-bundle",
+	"region",
+	"storage-class",
+	"checksum",
+	"multipart-size",
+	"enc-kms",
+	"enc-s3",
+	"enc-c",
+}
+
+// setProfileKey sets a single key of the profile, an empty value clears
+// the key. custom-header and resolve accumulate when set more than once.
+func setProfileKey(profile *profileConfigV11, key, value string) *probe.Error {
+	var e error
+	switch key {
+	case "limit-upload":
+		if value != "" {
+			_, e = limiter.ParseSchedule(value)
+		}
+		profile.LimitUpload = value
+	case "limit-download":
+		if value != "" {
+			_, e = limiter.ParseSchedule(value)
+		}
+		profile.LimitDownload = value
+	case "multipart-size":
+		if value != "" {
+			_, e = humanize.ParseBytes(value)
+		}
+		profile.MultipartSize = value
+	case "insecure":
+		profile.Insecure, e = parseProfileBool(value)
+	case "enc-s3":
+		profile.EncS3, e = parseProfileBool(value)
+	case "custom-header":
+		if value == "" {
+			profile.CustomHeaders = nil
+			break
+		}
+		if _, _, e = parseCustomHeader(value); e == nil {
+			profile.CustomHeaders = append(profile.CustomHeaders, value)
+		}
+	case "resolve":
+		if value == "" {
+			profile.Resolve = nil
+			break
+		}
+		if _, _, e = parseResolveEntry(value); e == nil {
+			profile.Resolve = append(profile.Resolve, value)
+		}
+	case "ca-bundle":
+		if value != "" {
+			_, e = os.Stat(value)
+		}
+		profile.CABundle = value
+	case "region":
+		profile.Region = value
+	case "storage-class":
+		profile.StorageClass = value
+	case "checksum":
+		profile.Checksum = strings.ToUpper(value)
+	case "enc-kms":
+		if value != "" && !validKMSKeyName(value) {
+			return errSSEKMSKeyFormat("Key (" + value + ") is badly formatted.").Trace(key)
+		}
+		profile.EncKMS = value
+	case "enc-c":
+		// Saved to a credential store by storeProfileEncKey().
+		profile.EncC = value
+		if value == "" {
+			profile.EncCStore = ""
+		}
+	default:
+		return errInvalidArgument().Trace(key)
+	}
+	if e != nil {
+		return probe.NewError(e).Trace(key, value)
+	}
+	return nil
+}
+
+func parseProfileBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "", "off", "false":
+		return false, nil
+	case "on", "true":
+		return true, nil
+	}
+	return false, errInvalidArgument().Trace(value).ToGoError()
+}
+
+// profileKeyValues returns the keys set in the profile in profileKeys order.
+func profileKeyValues(profile profileConfigV11) (kvs []string) {
+	add := func(key, value string) {
+		if value != "" {
+			kvs = append(kvs, key+"="+value)
+		}
+	}
+	add("limit-upload", profile.LimitUpload)
+	add("limit-download", profile.LimitDownload)
+	if profile.Insecure {
+		add("insecure", "on")
+	}
+	for _, h := range profile.CustomHeaders {
+		add("custom-header", h)
+	}
+	for _, r := range profile.Resolve {
+		add("resolve", r)
+	}
+	add("ca-bundle", profile.CABundle)
+	add("region", profile.Region)
+	add("storage-class", profile.StorageClass)
+	add("checksum", profile.Checksum)
+	add("multipart-size", profile.MultipartSize)
+	add("enc-kms", profile.EncKMS)
+	if profile.EncS3 {
+		add("enc-s3", "on")
+	}
+	if profile.EncC != "" || profile.EncCStore != "" {
+		add("enc-c", "*REDACTED*")
+	}
+	return kvs
+}
+
+// getProfileConfig returns the named profile from the config file.
+func getProfileConfig(name string) (profileConfigV11, bool) {
+	if name == "" || loadMcConfig == nil {
+		return profileConfigV11{}, false
+	}
+	mcCfg, err := loadMcConfig()
+	if err != nil {
+		return profileConfigV11{}, false
+	}
+	profile, ok := mcCfg.Profiles[name]
+	return profile, ok
+}
+
+// getAliasProfile returns the profile of an alias in the config file,
+// the credentials of the alias are not resolved.
+func getAliasProfile(alias string) (profileConfigV11, bool) {
+	if loadMcConfig == nil {
+		return profileConfigV11{}, false
+	}
+	mcCfg, err := loadMcConfig()
+	if err != nil {
+		return profileConfigV11{}, false
+	}
+	aliasCfg, ok := mcCfg.Aliases[alias]
+	if !ok {
+		return profileConfigV11{}, false
+	}
+	return getProfileConfig(aliasCfg.Profile)
+}
+
+// getProfileAliases returns the aliases referencing the named profile.
+func getProfileAliases(mcCfg *configV11, name string) (aliases []string) {
+	for alias, aliasCfg := range mcCfg.Aliases {
+		if aliasCfg.Profile == name {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// hasFlag returns true if the command itself defines the flag.
+func hasFlag(cmd *cli.Command, name string) bool {
+	for _, f := range cmd.Flags {
+		for _, n := range f.Names() {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyProfileFlags sets the command specific flags from the profiles
+// of the aliases in the arguments, unless they were given on the command
+// line. Encryption keys are set for each alias, the storage class and the
+// checksum of uploads come from the profile of the target, the last
+// argument.
+func applyProfileFlags(cmd *cli.Command) error {
+	// Global flags given before the sub-command have no alias yet.
+	if cmd.Root() == cmd || loadMcConfig == nil {
+		return nil
+	}
+	mcCfg, err := loadMcConfig()
+	if err != nil {
+		return nil
+	}
+
+	args := cmd.Args().Slice()
+	values := make(map[string][]string)
+	seen := make(map[string]bool)
+	for i, arg := range args {
+		alias, _ := url2Alias(arg)
+		name := mcCfg.Aliases[alias].Profile
+		profile, ok := mcCfg.Profiles[name]
+		if name == "" || !ok {
+			continue
+		}
+		if i == len(args)-1 {
+			if profile.StorageClass != "" {
+				values["storage-class"] = []string{profile.StorageClass}
+			}
+			if profile.Checksum != "" {
+				values["checksum"] = []string{profile.Checksum}
+			}
+		}
+		if seen[alias] {
+			continue
+		}
+		seen[alias] = true
+		if profile.EncKMS != "" {
+			values["enc-kms"] = append(values["enc-kms"], alias+"="+profile.EncKMS)
+		}
+		if profile.EncS3 {
+			values["enc-s3"] = append(values["enc-s3"], alias)
+		}
+		// The key is only retrieved when the command needs it.
+		if profile.EncCStore != "" && hasFlag(cmd, "enc-c") && !cmd.IsSet("enc-c") {
+			if err := resolveProfileEncKey(name, &profile); err != nil {
+				return err.ToGoError()
+			}
+			values["enc-c"] = append(values["enc-c"], alias+"="+profile.EncC)
+		}
+	}
+	for name, vs := range values {
+		if !hasFlag(cmd, name) || cmd.IsSet(name) {
+			continue
+		}
+		for _, v := range vs {
+			if e := cmd.Set(name, v); e != nil {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// applyProfileConfig sets the connection settings of the profile on the
+// config of one of its aliases, flags given on the command line win.
+func applyProfileConfig(config *Config, name string, profile profileConfigV11) {
+	config.Profile = name
+	if !globalInsecureSet {
+		config.Insecure = config.Insecure || profile.Insecure
+	}
+
+	if len(profile.CustomHeaders) > 0 {
+		config.CustomHeader = globalCustomHeader.Clone()
+		if config.CustomHeader == nil {
+			config.CustomHeader = make(http.Header)
+		}
+		for _, header := range profile.CustomHeaders {
+			h, hv, e := parseCustomHeader(header)
+			fatalIf(probe.NewError(e).Trace(name), "Invalid custom header in profile `%s`.", name)
+			if _, ok := globalCustomHeader[http.CanonicalHeaderKey(h)]; !ok {
+				config.CustomHeader.Add(h, hv)
+			}
+		}
+	}
+
+	if len(profile.Resolve) > 0 {
+		config.Resolvers = make(map[string]netip.Addr, len(profile.Resolve)+len(globalResolvers))
+		for _, entry := range profile.Resolve {
+			host, addr, e := parseResolveEntry(entry)
+			fatalIf(probe.NewError(e).Trace(name), "Invalid DNS resolve entry in profile `%s`.", name)
+			config.Resolvers[host] = addr
+		}
+		maps.Copy(config.Resolvers, globalResolvers)
+	}
+
+	if profile.CABundle != "" {
+		rootCAs, e := loadCABundle(profile.CABundle)
+		fatalIf(probe.NewError(e).Trace(profile.CABundle), "Unable to load CA bundle of profile `%s`.", name)
+		config.RootCAs = rootCAs
+	}
+}
+
+// caBundles caches the root CAs extended with a CA bundle, by file.
+var caBundles = struct {
+	sync.Mutex
+	pools map[string]*x509.CertPool
+}{pools: make(map[string]*x509.CertPool)}
+
+// loadCABundle returns the root CAs together with the PEM encoded
+// certificates of file.
+func loadCABundle(file string) (*x509.CertPool, error) {
+	caBundles.Lock()
+	defer caBundles.Unlock()
+
+	if pool, ok := caBundles.pools[file]; ok {
+		return pool, nil
+	}
+	pem, e := os.ReadFile(file)
+	if e != nil {
+		return nil, e
+	}
+	var pool *x509.CertPool
+	if globalRootCAs != nil {
+		pool = globalRootCAs.Clone()
+	} else if pool, e = x509.SystemCertPool(); e != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errInvalidArgument().Trace(file).ToGoError()
+	}
+	caBundles.pools[file] = pool
+	return pool, nil
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/urfave/cli/v3"
+)
+
+func TestSetProfileKey(t *testing.T) {
+	caBundle := t.TempDir() + "/ca.pem"
+	if e := os.WriteFile(caBundle, nil, 0o600); e != nil {
+		t.Fatal(e)
+	}
+
+	var profile profileConfigV11
+	for _, kv := range [][2]string{
+		{"limit-upload", "10MiB"},
+		{"insecure", "on"},
+		{"custom-header", "X-Foo: bar"},
+		{"custom-header", "X-Bar: foo"},
+		{"resolve", "s3.local:9000=10.0.0.1"},
+		{"ca-bundle", caBundle},
+		{"checksum", "crc32c"},
+		{"enc-s3", "true"},
+	} {
+		if err := setProfileKey(&profile, kv[0], kv[1]); err != nil {
+			t.Fatalf("%s=%s: %v", kv[0], kv[1], err)
+		}
+	}
+	expected := profileConfigV11{
+		LimitUpload:   "10MiB",
+		Insecure:      true,
+		CustomHeaders: []string{"X-Foo: bar", "X-Bar: foo"},
+		Resolve:       []string{"s3.local:9000=10.0.0.1"},
+		CABundle:      caBundle,
+		Checksum:      "CRC32C",
+		EncS3:         true,
+	}
+	if !reflect.DeepEqual(profile, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, profile)
+	}
+
+	// Empty values clear the key.
+	for _, key := range []string{"limit-upload", "insecure", "custom-header"} {
+		if err := setProfileKey(&profile, key, ""); err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+	}
+	if profile.LimitUpload != "" || profile.Insecure || profile.CustomHeaders != nil {
+		t.Fatalf("expected cleared keys, got %+v", profile)
+	}
+
+	for _, kv := range [][2]string{
+		{"limit-download", "fast"},
+		{"insecure", "maybe"},
+		{"custom-header", "no-colon"},
+		{"resolve", "s3.local=not-an-ip"},
+		{"ca-bundle", caBundle + ".missing"},
+		{"unknown", "value"},
+	} {
+		if err := setProfileKey(&profile, kv[0], kv[1]); err == nil {
+			t.Errorf("%s=%s: expected an error", kv[0], kv[1])
+		}
+	}
+}
+
+func TestProfileEncKeyKeystore(t *testing.T) {
+	prevConfigDir := mcCustomConfigDir
+	mcCustomConfigDir = t.TempDir()
+	t.Setenv("MC_KEYSTORE_PASSPHRASE", "correct horse battery staple")
+	resetKeystore := func() {
+		keystoreKey, keystoreSalt, keystoreEntries = nil, nil, nil
+		resolvedCredentials = make(map[string]aliasCredentials)
+	}
+	defer func() {
+		mcCustomConfigDir = prevConfigDir
+		resetKeystore()
+	}()
+
+	const key = "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE"
+	var profile profileConfigV11
+	if err := setProfileKey(&profile, "enc-c", key); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeProfileEncKey("secure", &profile); err == nil {
+		t.Fatal("expected an error storing a key without credential store")
+	}
+	profile.EncCStore = credentialStoreKeystore
+	if err := storeProfileEncKey("secure", &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.EncC != "" {
+		t.Fatal("expected the key to be removed from the profile")
+	}
+	buf, e := json.Marshal(profile)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if strings.Contains(string(buf), key) {
+		t.Fatalf("key written to the config: %s", buf)
+	}
+	if kvs := profileKeyValues(profile); !reflect.DeepEqual(kvs, []string{"enc-c=*REDACTED*"}) {
+		t.Fatalf("unexpected profile options %v", kvs)
+	}
+
+	// A new process unlocks the keystore again.
+	resetKeystore()
+	if err := resolveProfileEncKey("secure", &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.EncC != key {
+		t.Fatalf("expected key %s, got %s", key, profile.EncC)
+	}
+
+	resetKeystore()
+	if err := eraseProfileEncKey("secure", profile); err != nil {
+		t.Fatal(err)
+	}
+	resetKeystore()
+	profile.EncC = ""
+	if err := resolveProfileEncKey("secure", &profile); err == nil {
+		t.Fatal("expected an error resolving an erased key")
+	}
+
+	// Clearing the key clears its store.
+	if err := setProfileKey(&profile, "enc-c", ""); err != nil {
+		t.Fatal(err)
+	}
+	if profile.EncCStore != "" {
+		t.Fatalf("expected no credential store, got %s", profile.EncCStore)
+	}
+}
+
+func TestProfilePerAlias(t *testing.T) {
+	mcCfg := newConfigV11()
+	mcCfg.Aliases["wan"] = aliasConfigV11{URL: "https://wan.example.com", Profile: "wan"}
+	mcCfg.Aliases["lan"] = aliasConfigV11{URL: "https://lan.example.com", Profile: "lan"}
+	mcCfg.Aliases["plain"] = aliasConfigV11{URL: "https://plain.example.com"}
+	mcCfg.Profiles["wan"] = profileConfigV11{
+		Insecure:      true,
+		CustomHeaders: []string{"X-Site: wan"},
+		Resolve:       []string{"wan.example.com:443=10.0.0.1"},
+		StorageClass:  "STANDARD_IA",
+		EncKMS:        "wan-key",
+	}
+	mcCfg.Profiles["lan"] = profileConfigV11{
+		CustomHeaders: []string{"X-Site: lan"},
+		StorageClass:  "REDUCED_REDUNDANCY",
+		EncKMS:        "lan-key",
+	}
+	saved := loadMcConfig
+	loadMcConfig = func() (*configV11, *probe.Error) { return mcCfg, nil }
+	defer func() { loadMcConfig = saved }()
+
+	for _, testCase := range []struct {
+		alias     string
+		insecure  bool
+		header    string
+		resolvers int
+	}{
+		{"wan", true, "wan", 1},
+		{"lan", false, "lan", 0},
+		{"plain", false, "", 0},
+	} {
+		aliasCfg := mcCfg.Aliases[testCase.alias]
+		config := NewS3Config(testCase.alias, aliasCfg.URL, &aliasCfg)
+		if config.Insecure != testCase.insecure {
+			t.Errorf("%s: expected insecure %v, got %v", testCase.alias, testCase.insecure, config.Insecure)
+		}
+		if got := config.customHeader().Get("X-Site"); got != testCase.header {
+			t.Errorf("%s: expected header %q, got %q", testCase.alias, testCase.header, got)
+		}
+		if got := len(config.resolvers()); got != testCase.resolvers {
+			t.Errorf("%s: expected %d resolvers, got %d", testCase.alias, testCase.resolvers, got)
+		}
+	}
+
+	// --insecure=false on the command line wins over the profile.
+	globalInsecureSet = true
+	aliasCfg := mcCfg.Aliases["wan"]
+	config := NewS3Config("wan", aliasCfg.URL, &aliasCfg)
+	globalInsecureSet = false
+	if config.Insecure {
+		t.Error("wan: expected --insecure=false to win over the profile")
+	}
+
+	var storageClass string
+	var encKMS []string
+	sub := &cli.Command{
+		Name: "cp",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "storage-class"},
+			&cli.StringSliceFlag{Name: "enc-kms"},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			if e := applyProfileFlags(cmd); e != nil {
+				return e
+			}
+			storageClass, encKMS = cmd.String("storage-class"), cmd.StringSlice("enc-kms")
+			return nil
+		},
+	}
+	root := &cli.Command{Name: "mc", Commands: []*cli.Command{sub}}
+	if e := root.Run(context.Background(), []string{"mc", "cp", "wan/bucket/object", "lan/bucket/"}); e != nil {
+		t.Fatal(e)
+	}
+	if storageClass != "REDUCED_REDUNDANCY" {
+		t.Errorf("expected the storage class of the target, got %q", storageClass)
+	}
+	if expected := []string{"wan=wan-key", "lan=lan-key"}; !reflect.DeepEqual(encKMS, expected) {
+		t.Errorf("expected keys %v, got %v", expected, encKMS)
+	}
+}
@@ -42,6 +42,17 @@ func isValidSecretKey(secretKey string) bool {
 	return len(secretKey) >= secretKeyMinLen
 }
 
+// isValidCredentialStore - validate credential store of an alias.
+func isValidCredentialStore(store string) bool {
+	switch {
+	case store == "", store == credentialStoreKeystore:
+		return true
+	case strings.HasPrefix(store, credentialHelperPrefix):
+		return strings.TrimSpace(strings.TrimPrefix(store, credentialHelperPrefix)) != ""
+	}
+	return false
+}
+
 // trimTrailingSeparator - Remove trailing separator.
 func trimTrailingSeparator(hostURL string) string {
 	separator := string(newClientURL(hostURL).Separator)
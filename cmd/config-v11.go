@@ -31,13 +31,13 @@ const (
 
 var (
 	// set once during first load.
-	cacheCfgV10 *configV10
+	cacheCfgV11 *configV11
 	// All access to mc config file should be synchronized.
 	cfgMutex = &sync.RWMutex{}
 )
 
 // aliasConfig configuration of an alias.
-type aliasConfigV10 struct {
+type aliasConfigV11 struct {
 	URL          string `json:"url"`
 	AccessKey    string `json:"accessKey"`
 	SecretKey    string `json:"secretKey"`
@@ -47,33 +47,61 @@ type aliasConfigV10 struct {
 	License      string `json:"license,omitempty"`
 	APIKey       string `json:"apiKey,omitempty"`
 	Src          string `json:"src,omitempty"`
+	// CredentialStore keeps SecretKey and SessionToken out of the
+	// config file, they are resolved on first use of the alias.
+	CredentialStore string `json:"credentialStore,omitempty"`
+	// Profile names the default options applied to commands using the alias.
+	Profile string `json:"profile,omitempty"`
 }
 
-// configV10 config version.
-type configV10 struct {
-	Version string                    `json:"version"`
-	Aliases map[string]aliasConfigV10 `json:"aliases"`
+// profileConfigV11 default options of an alias, they apply only
+// when the corresponding flag is not given on the command line.
+type profileConfigV11 struct {
+	LimitUpload   string   `json:"limitUpload,omitempty"`
+	LimitDownload string   `json:"limitDownload,omitempty"`
+	Insecure      bool     `json:"insecure,omitempty"`
+	CustomHeaders []string `json:"customHeaders,omitempty"`
+	Resolve       []string `json:"resolve,omitempty"`
+	CABundle      string   `json:"caBundle,omitempty"`
+	Region        string   `json:"region,omitempty"`
+	StorageClass  string   `json:"storageClass,omitempty"`
+	Checksum      string   `json:"checksum,omitempty"`
+	MultipartSize string   `json:"multipartSize,omitempty"`
+	EncKMS        string   `json:"encKMS,omitempty"`
+	EncS3         bool     `json:"encS3,omitempty"`
+	// EncC is kept in the credential store EncCStore, it is never
+	// written to the config file.
+	EncC      string `json:"-"`
+	EncCStore string `json:"encCStore,omitempty"`
 }
 
-// newConfigV10 - new config version.
-func newConfigV10() *configV10 {
-	cfg := new(configV10)
+// configV11 config version.
+type configV11 struct {
+	Version  string                      `json:"version"`
+	Aliases  map[string]aliasConfigV11   `json:"aliases"`
+	Profiles map[string]profileConfigV11 `json:"profiles,omitempty"`
+}
+
+// newConfigV11 - new config version.
+func newConfigV11() *configV11 {
+	cfg := new(configV11)
 	cfg.Version = globalMCConfigVersion
-	cfg.Aliases = make(map[string]aliasConfigV10)
+	cfg.Aliases = make(map[string]aliasConfigV11)
+	cfg.Profiles = make(map[string]profileConfigV11)
 	return cfg
 }
 
 // SetAlias sets host config if not empty.
-func (c *configV10) setAlias(alias string, cfg aliasConfigV10) {
+func (c *configV11) setAlias(alias string, cfg aliasConfigV11) {
 	if _, ok := c.Aliases[alias]; !ok {
 		c.Aliases[alias] = cfg
 	}
 }
 
 // load default values for missing entries.
-func (c *configV10) loadDefaults() {
+func (c *configV11) loadDefaults() {
 	// MinIO server running locally.
-	c.setAlias("local", aliasConfigV10{
+	c.setAlias("local", aliasConfigV11{
 		URL:       "http://localhost:9000",
 		AccessKey: "",
 		SecretKey: "",
@@ -82,7 +110,7 @@ func (c *configV10) loadDefaults() {
 	})
 
 	// Amazon S3 cloud storage service.
-	c.setAlias("s3", aliasConfigV10{
+	c.setAlias("s3", aliasConfigV11{
 		URL:       "https://s3.amazonaws.com",
 		AccessKey: defaultAccessKey,
 		SecretKey: defaultSecretKey,
@@ -91,7 +119,7 @@ func (c *configV10) loadDefaults() {
 	})
 
 	// Google cloud storage service.
-	c.setAlias("gcs", aliasConfigV10{
+	c.setAlias("gcs", aliasConfigV11{
 		URL:       "https://storage.googleapis.com",
 		AccessKey: defaultAccessKey,
 		SecretKey: defaultSecretKey,
@@ -100,7 +128,7 @@ func (c *configV10) loadDefaults() {
 	})
 
 	// MinIO anonymous server for demo.
-	c.setAlias("play", aliasConfigV10{
+	c.setAlias("play", aliasConfigV11{
 		URL:       "https://play.min.io",
 		AccessKey: "Q3AM3UQ867SPQQA43P2F",
 		SecretKey: "zuf+tfteSlswRu7BJ86wekitnifILbZam1KYY3TG",
@@ -109,14 +137,14 @@ func (c *configV10) loadDefaults() {
 	})
 }
 
-// loadConfigV10 - loads a new config.
-func loadConfigV10() (*configV10, *probe.Error) {
+// loadConfigV11 - loads a new config.
+func loadConfigV11() (*configV11, *probe.Error) {
 	cfgMutex.RLock()
 	defer cfgMutex.RUnlock()
 
 	// If already cached, return the cached value.
-	if cacheCfgV10 != nil {
-		return cacheCfgV10, nil
+	if cacheCfgV11 != nil {
+		return cacheCfgV11, nil
 	}
 
 	if !isMcConfigExists() {
@@ -124,7 +152,7 @@ func loadConfigV10() (*configV10, *probe.Error) {
 	}
 
 	// Initialize a new config loader.
-	qc, e := quick.NewConfig(newConfigV10(), nil)
+	qc, e := quick.NewConfig(newConfigV11(), nil)
 	if e != nil {
 		return nil, probe.NewError(e)
 	}
@@ -135,27 +163,27 @@ func loadConfigV10() (*configV10, *probe.Error) {
 		return nil, probe.NewError(e)
 	}
 
-	cfgV10 := qc.Data().(*configV10)
+	cfgV11 := qc.Data().(*configV11)
 
 	// Cache config.
-	cacheCfgV10 = cfgV10
+	cacheCfgV11 = cfgV11
 
 	// Success.
-	return cfgV10, nil
+	return cfgV11, nil
 }
 
-// saveConfigV10 - saves an updated config.
-func saveConfigV10(cfgV10 *configV10) *probe.Error {
+// saveConfigV11 - saves an updated config.
+func saveConfigV11(cfgV11 *configV11) *probe.Error {
 	cfgMutex.Lock()
 	defer cfgMutex.Unlock()
 
-	qs, e := quick.NewConfig(cfgV10, nil)
+	qs, e := quick.NewConfig(cfgV11, nil)
 	if e != nil {
 		return probe.NewError(e)
 	}
 
 	// update the cache.
-	cacheCfgV10 = cfgV10
+	cacheCfgV11 = cfgV11
 
 	e = qs.Save(mustGetMcConfigPath())
 	if e != nil {
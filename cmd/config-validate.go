@@ -23,7 +23,7 @@ import (
 )
 
 // Check if version of the config is valid
-func validateConfigVersion(config *configV10) (bool, string) {
+func validateConfigVersion(config *configV11) (bool, string) {
 	if config.Version != globalMCConfigVersion {
 		return false, fmt.Sprintf("Config version '%s' does not match mc config version '%s', please update your binary.\n",
 			config.Version, globalMCConfigVersion)
@@ -32,7 +32,7 @@ func validateConfigVersion(config *configV10) (bool, string) {
 }
 
 // Verifies the config file of the MinIO Client
-func validateConfigFile(config *configV10) (bool, []string) {
+func validateConfigFile(config *configV11) (bool, []string) {
 	ok, err := validateConfigVersion(config)
 	validationSuccessful := true
 	var errors []string
@@ -47,11 +47,15 @@ func validateConfigFile(config *configV10) (bool, []string) {
 			validationSuccessful = false
 			errors = append(errors, aliasErrors...)
 		}
+		if _, ok := config.Profiles[aliasConfig.Profile]; aliasConfig.Profile != "" && !ok {
+			validationSuccessful = false
+			errors = append(errors, "Unknown profile `"+aliasConfig.Profile+"` for host `"+aliasConfig.URL+"`.")
+		}
 	}
 	return validationSuccessful, errors
 }
 
-func validateConfigHost(host aliasConfigV10) (bool, []string) {
+func validateConfigHost(host aliasConfigV11) (bool, []string) {
 	validationSuccessful := true
 	var hostErrors []string
 	if !isValidAPI(strings.ToLower(host.API)) {
@@ -62,5 +66,9 @@ func validateConfigHost(host aliasConfigV10) (bool, []string) {
 		validationSuccessful = false
 		hostErrors = append(hostErrors, errInvalidURL(host.URL).ToGoError().Error())
 	}
+	if !isValidCredentialStore(host.CredentialStore) {
+		validationSuccessful = false
+		hostErrors = append(hostErrors, "Unrecognized credential store `"+host.CredentialStore+"` for host `"+host.URL+"`.")
+	}
 	return validationSuccessful, hostErrors
 }
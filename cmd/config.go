@@ -108,29 +108,29 @@ func mustGetMcConfigPath() string {
 	return path
 }
 
-// newMcConfig - initializes a new version '10' config.
-func newMcConfig() *configV10 {
-	cfg := newConfigV10()
+// newMcConfig - initializes a new version '11' config.
+func newMcConfig() *configV11 {
+	cfg := newConfigV11()
 	cfg.loadDefaults()
 	return cfg
 }
 
 // loadMcConfigCached - returns loadMcConfig with a closure for config cache.
-func loadMcConfigFactory() func() (*configV10, *probe.Error) {
+func loadMcConfigFactory() func() (*configV11, *probe.Error) {
 	// Load once and cache in a closure.
-	cfgCache, err := loadConfigV10()
+	cfgCache, err := loadConfigV11()
 
 	// loadMcConfig - reads configuration file and returns config.
-	return func() (*configV10, *probe.Error) {
+	return func() (*configV11, *probe.Error) {
 		return cfgCache, err
 	}
 }
 
 // loadMcConfig - returns configuration, initialized later.
-var loadMcConfig func() (*configV10, *probe.Error)
+var loadMcConfig func() (*configV11, *probe.Error)
 
 // saveMcConfig - saves configuration file and returns error if any.
-func saveMcConfig(config *configV10) *probe.Error {
+func saveMcConfig(config *configV11) *probe.Error {
 	if config == nil {
 		return errInvalidArgument().Trace()
 	}
@@ -141,7 +141,7 @@ func saveMcConfig(config *configV10) *probe.Error {
 	}
 
 	// Save the config.
-	if err := saveConfigV10(config); err != nil {
+	if err := saveConfigV11(config); err != nil {
 		return err.Trace(mustGetMcConfigPath())
 	}
 
@@ -176,7 +176,7 @@ func isValidAlias(alias string) bool {
 }
 
 // getAliasConfig retrieves host specific configuration such as access keys, signature type.
-func getAliasConfig(alias string) (*aliasConfigV10, *probe.Error) {
+func getAliasConfig(alias string) (*aliasConfigV11, *probe.Error) {
 	mcCfg, err := loadMcConfig()
 	if err != nil {
 		return nil, err.Trace(alias)
@@ -186,6 +186,9 @@ func getAliasConfig(alias string) (*aliasConfigV10, *probe.Error) {
 	if _, ok := mcCfg.Aliases[alias]; ok {
 		hostCfg := mcCfg.Aliases[alias]
 		hostCfg.Src = mustGetMcConfigPath()
+		if err := resolveAliasCredentials(alias, &hostCfg); err != nil {
+			return nil, err.Trace(alias)
+		}
 		return &hostCfg, nil
 	}
 
@@ -194,7 +197,7 @@ func getAliasConfig(alias string) (*aliasConfigV10, *probe.Error) {
 }
 
 // mustGetHostConfig retrieves host specific configuration such as access keys, signature type.
-func mustGetHostConfig(alias string) *aliasConfigV10 {
+func mustGetHostConfig(alias string) *aliasConfigV11 {
 	// look for it in the environment variable first.
 	aliasCfg, _ := expandAliasFromEnv(env.Get(mcEnvHostPrefix+alias, ""))
 
@@ -205,7 +208,14 @@ func mustGetHostConfig(alias string) *aliasConfigV10 {
 	}
 
 	if aliasCfg == nil {
-		aliasCfg, _ = getAliasConfig(alias)
+		var err *probe.Error
+		aliasCfg, err = getAliasConfig(alias)
+		if err != nil {
+			// An alias whose secrets cannot be retrieved is not a missing alias.
+			if _, ok := err.ToGoError().(CredentialsUnavailable); ok {
+				fatalIf(err, "Unable to load credentials.")
+			}
+		}
 	}
 	return aliasCfg
 }
@@ -270,7 +280,7 @@ const (
 	mcEnvConfigFile = "MC_CONFIG_ENV_FILE"
 )
 
-var aliasToConfigMap = make(map[string]*aliasConfigV10)
+var aliasToConfigMap = make(map[string]*aliasConfigV11)
 
 func readAliasesFromFile(envConfigFile string) *probe.Error {
 	r, e := os.Open(envConfigFile)
@@ -302,13 +312,13 @@ func readAliasesFromFile(envConfigFile string) *probe.Error {
 	return nil
 }
 
-func expandAliasFromEnv(envURL string) (*aliasConfigV10, *probe.Error) {
+func expandAliasFromEnv(envURL string) (*aliasConfigV11, *probe.Error) {
 	u, accessKey, secretKey, sessionToken, err := parseEnvURLStr(envURL)
 	if err != nil {
 		return nil, err.Trace(envURL)
 	}
 
-	return &aliasConfigV10{
+	return &aliasConfigV11{
 		URL:          u.String(),
 		API:          "S3v4",
 		AccessKey:    accessKey,
@@ -319,7 +329,7 @@ func expandAliasFromEnv(envURL string) (*aliasConfigV10, *probe.Error) {
 }
 
 // expandAlias expands aliased URL if any match is found, returns as is otherwise.
-func expandAlias(aliasedURL string) (alias, urlStr string, aliasCfg *aliasConfigV10, err *probe.Error) {
+func expandAlias(aliasedURL string) (alias, urlStr string, aliasCfg *aliasConfigV11, err *probe.Error) {
 	// Extract alias from the URL.
 	alias, path := url2Alias(aliasedURL)
 
@@ -345,7 +355,7 @@ func expandAlias(aliasedURL string) (alias, urlStr string, aliasCfg *aliasConfig
 }
 
 // mustExpandAlias expands aliased URL if any match is found, returns as is otherwise.
-func mustExpandAlias(aliasedURL string) (alias, urlStr string, aliasCfg *aliasConfigV10) {
+func mustExpandAlias(aliasedURL string) (alias, urlStr string, aliasCfg *aliasConfigV11) {
 	alias, urlStr, aliasCfg, _ = expandAlias(aliasedURL)
 	return alias, urlStr, aliasCfg
 }
@@ -0,0 +1,222 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/urfave/cli/v3"
+)
+
+// Members of compressed tar archives up to extractBufferSize are read in
+// memory, so that they are uploaded in parallel while the archive is read.
+const extractBufferSize = 8 << 20
+
+// extractJob - a member of an archive to copy to target.
+type extractJob struct {
+	entry  *archiveEntry
+	target string
+	open   func() (io.ReadCloser, error)
+}
+
+// checkExtractSyntax - validate the arguments of `cp --extract`.
+func checkExtractSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 2 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code.
+	}
+	for _, flag := range []string{"zip", "pack", "unpack", "rewind", "version-id", "resume", "older-than", "newer-than", "enc-client-key"} {
+		if cmd.IsSet(flag) {
+			fatalIf(errInvalidArgument().Trace(flag), "--extract cannot be used with --%s.", flag)
+		}
+	}
+	src := cmd.Args().Get(0)
+	if _, _, _, ok := splitArchiveURL(src); !ok {
+		fatalIf(errInvalidArgument().Trace(src), "`%s` is not an archive, only .zip, .tar, .tar.gz, .tgz, .tar.zst and .tzst are supported.", src)
+	}
+}
+
+// mainCopyExtract unpacks an archive, or a folder inside it, into a
+// prefix or a local folder.
+func mainCopyExtract(ctx context.Context, cmd *cli.Command) error {
+	checkExtractSyntax(ctx, cmd)
+
+	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	srcURL, tgtURL := cmd.Args().Get(0), cmd.Args().Get(1)
+	workers := cmd.Int("max-workers")
+	if workers <= 0 {
+		workers = defaultWorkerFactor
+	}
+	return extractArchive(ctx, srcURL, tgtURL, encKeyDB, workers, cmd.String("storage-class"))
+}
+
+func extractArchive(ctx context.Context, srcURL, tgtURL string, encKeyDB map[string][]prefixSSEPair, workers int, storageClass string) error {
+	srcAlias, _ := url2Alias(srcURL)
+	src, err := newArchiveClient(srcURL, getSSE(srcURL, encKeyDB[srcAlias]))
+	fatalIf(err.Trace(srcURL), "Unable to initialize source `%s`.", srcURL)
+	idx, root, err := src.entry(ctx)
+	fatalIf(err.Trace(srcURL), "Unable to read archive `%s`.", srcURL)
+
+	// target returns the URL of a member, members are copied relative
+	// to the folder of the source.
+	tgtAlias, _ := url2Alias(tgtURL)
+	target := func(entry *archiveEntry) string {
+		if !root.isDir() && root.name != "" {
+			if strings.HasSuffix(tgtURL, "/") {
+				return tgtURL + path.Base(entry.name)
+			}
+			return tgtURL
+		}
+		return strings.TrimSuffix(tgtURL, "/") + "/" + strings.TrimPrefix(entry.name, root.name)
+	}
+	selected := func(entry *archiveEntry) bool {
+		if !root.isDir() && root.name != "" {
+			return entry.name == root.name
+		}
+		return strings.HasPrefix(entry.name, root.name) && entry.name != root.name
+	}
+
+	var (
+		mu     sync.Mutex
+		failed bool
+		wg     sync.WaitGroup
+	)
+	copyMember := func(job extractJob) {
+		err := extractMember(ctx, job, encKeyDB[tgtAlias], storageClass)
+		if err != nil {
+			errorIf(err.Trace(srcURL, job.target), "Unable to extract `%s`.", job.entry.name)
+			mu.Lock()
+			failed = true
+			mu.Unlock()
+			return
+		}
+		if !job.entry.isDir() {
+			printMsg(copyMessage{
+				Source: strings.TrimSuffix(srcURL, src.suffix) + "/" + job.entry.name,
+				Target: job.target,
+				Size:   job.entry.size,
+			})
+		}
+	}
+
+	jobs := make(chan extractJob)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				copyMember(job)
+			}
+		}()
+	}
+	send := func(job extractJob) bool {
+		select {
+		case jobs <- job:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var e error
+	if idx.format.seekable() {
+		for _, entry := range idx.entries {
+			if !selected(entry) {
+				continue
+			}
+			if !send(extractJob{
+				entry:  entry,
+				target: target(entry),
+				open:   func() (io.ReadCloser, error) { return idx.open(ctx, entry) },
+			}) {
+				break
+			}
+		}
+	} else {
+		// Compressed archives are read once, from their start.
+		e = scanTarArchive(ctx, idx.reader, idx.format, func(hdr *tar.Header, r io.Reader) (bool, error) {
+			entry := newTarArchiveEntry(hdr)
+			if entry == nil {
+				return true, nil
+			}
+			name, ok := cleanArchiveName(entry.name, entry.isDir())
+			if !ok {
+				return true, nil
+			}
+			entry.name = name
+			if !selected(entry) {
+				return true, nil
+			}
+			job := extractJob{entry: entry, target: target(entry)}
+			if entry.size > extractBufferSize {
+				job.open = func() (io.ReadCloser, error) { return io.NopCloser(r), nil }
+				copyMember(job)
+				return ctx.Err() == nil, nil
+			}
+			data, e := io.ReadAll(r)
+			if e != nil {
+				return false, e
+			}
+			job.open = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+			return send(job), nil
+		})
+	}
+	close(jobs)
+	wg.Wait()
+
+	fatalIf(probe.NewError(e).Trace(srcURL), "Unable to read archive `%s`.", srcURL)
+	if failed || ctx.Err() != nil {
+		return exitStatus(globalErrorExitStatus)
+	}
+	return nil
+}
+
+// extractMember copies a member to its target, folders are only created
+// on local filesystems.
+func extractMember(ctx context.Context, job extractJob, tgtKeys []prefixSSEPair, storageClass string) *probe.Error {
+	clnt, err := newClient(job.target)
+	if err != nil {
+		return err.Trace(job.target)
+	}
+	if job.entry.isDir() {
+		if clnt.GetURL().Type == fileSystem {
+			return clnt.MakeBucket(ctx, "", true, false).Trace(job.target)
+		}
+		return nil
+	}
+
+	reader, e := job.open()
+	if e != nil {
+		return probe.NewError(e).Trace(job.entry.name)
+	}
+	defer reader.Close()
+	_, err = clnt.Put(ctx, reader, job.entry.size, nil, PutOptions{
+		metadata:     map[string]string{"Content-Type": guessURLContentType(job.entry.name)},
+		sse:          getSSE(job.target, tgtKeys),
+		storageClass: storageClass,
+	})
+	return err.Trace(job.target)
+}
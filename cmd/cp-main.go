@@ -22,8 +22,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 
 	"github.com/fatih/color"
 	json "github.com/openstor/colorjson"
@@ -97,10 +99,31 @@ var (
 			Name:  "zip",
 			Usage: "Extract from remote zip file (MinIO server source only)",
 		},
+		&cli.BoolFlag{
+			Name:  "extract",
+			Usage: "unpack a zip, tar, tar.gz or tar.zst archive into the target, read on the client",
+		},
+		&cli.StringFlag{
+			Name:  "pack",
+			Usage: "pack the files of a folder into rolling 'tar' or 'zip' archives and a JSON index",
+		},
+		&cli.StringFlag{
+			Name:  "pack-size",
+			Value: "256MiB",
+			Usage: "size at which --pack starts a new archive",
+		},
+		&cli.BoolFlag{
+			Name:  "unpack",
+			Usage: "restore the files of a pack from its JSON index",
+		},
 		&cli.IntFlag{
 			Name:  "max-workers",
 			Usage: "maximum number of concurrent copies (default: autodetect)",
 		},
+		&cli.StringFlag{
+			Name:  "resume",
+			Usage: "resume an interrupted copy session, see 'mc session list'",
+		},
 		checksumFlag,
 	}
 )
@@ -121,7 +144,7 @@ var cpCmd = cli.Command{
 	Action:       mainCopy,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(append(cpFlags, encFlags...), globalFlags...),
+	Flags:        append(append(append(cpFlags, encFlags...), encClientFlags...), globalFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -135,6 +158,8 @@ FLAGS:
 ENVIRONMENT VARIABLES:
   MC_ENC_KMS: KMS encryption key in the form of (alias/prefix=key).
   MC_ENC_S3: S3 encryption key in the form of (alias/prefix=key).
+  MC_ENC_CLIENT_KEY: path of the key file used to encrypt objects on the client.
+  MC_ENC_CLIENT_PASSPHRASE: passphrase used to encrypt objects on the client.
 
 EXAMPLES:
   01. Copy a list of objects from local file system to Amazon S3 cloud storage.
@@ -194,6 +219,22 @@ EXAMPLES:
   19. Set tags to the uploaded objects
       {{.Prompt}} {{.HelpName}} -r --tags "category=prod&type=backup" ./data/ play/another-bucket/
 
+  20. Resume an interrupted recursive copy, encryption keys are never saved and must be passed again.
+      {{.Prompt}} {{.HelpName}} --resume gxk3mqp9tzr4vwb2
+
+  21. Encrypt a folder on the client before uploading it, objects are decrypted when copied back with the same key.
+      {{.Prompt}} {{.HelpName}} --recursive --enc-client-key ~/.mc/backup.key backup/2014/ play/archive/
+      {{.Prompt}} {{.HelpName}} --recursive --enc-client-key ~/.mc/backup.key play/archive/ backup/2014/
+
+  22. Unpack a tar.gz archive into a prefix, or a folder of a zip archive into a local folder.
+      {{.Prompt}} {{.HelpName}} --extract play/mybucket/site.tar.gz play/www/
+      {{.Prompt}} {{.HelpName}} --extract s3/mybucket/photos.zip/2014/ ~/Pictures/2014/
+
+  23. Pack many small files into archives of 1GiB and restore them, zip packs stored on MinIO can also be
+      browsed with 'mc ls --zip'.
+      {{.Prompt}} {{.HelpName}} --pack tar --pack-size 1GiB ~/logs/ play/mybucket/logs/
+      {{.Prompt}} {{.HelpName}} --unpack play/mybucket/logs/pack-20220101T000000Z.json ~/restore/
+
 `,
 }
 
@@ -272,6 +313,8 @@ func doCopy(ctx context.Context, copyOpts doCopyOpts) URLs {
 		multipartThreads:    copyOpts.multipartThreads,
 		updateProgressTotal: copyOpts.updateProgressTotal,
 		ifNotExists:         copyOpts.ifNotExists,
+		session:             copyOpts.session,
+		cse:                 copyOpts.cse,
 	})
 	if copyOpts.isMvCmd && urls.Error == nil {
 		rmManager.add(ctx, sourceAlias, sourceURL.String())
@@ -306,12 +349,17 @@ func printCopyURLsError(cpURLs *URLs) {
 	}
 }
 
-func doCopySession(ctx context.Context, cancelCopy context.CancelFunc, cmd *cli.Command, encryptionKeys map[string][]prefixSSEPair, isMvCmd bool) error {
+func doCopySession(ctx context.Context, cancelCopy context.CancelFunc, cmd *cli.Command, args []string, encryptionKeys map[string][]prefixSSEPair, isMvCmd bool, session *sessionV8) error {
 	var isCopied func(string) bool
 	var totalObjects, totalBytes int64
 
+	if session != nil {
+		isCopied = session.IsCompleted
+	}
+
 	cpURLsCh := make(chan URLs, 10000)
-	errSeen := false
+	// Set by the listing goroutine as well as by the status loop.
+	var errSeen atomic.Bool
 
 	// Store a progress bar or an accounter
 	var pg ProgressReader
@@ -322,8 +370,8 @@ func doCopySession(ctx context.Context, cancelCopy context.CancelFunc, cmd *cli.
 	} else {
 		pg = newAccounter(totalBytes)
 	}
-	sourceURLs := cmd.Args().Slice()[:len(cmd.Args().Slice())-1]
-	targetURL := cmd.Args().Slice()[len(cmd.Args().Slice())-1] // Last one is target
+	sourceURLs := args[:len(args)-1]
+	targetURL := args[len(args)-1] // Last one is target
 
 	// Check if the target path has object locking enabled
 	withLock, _ := isBucketLockEnabled(ctx, targetURL)
@@ -339,6 +387,9 @@ func doCopySession(ctx context.Context, cancelCopy context.CancelFunc, cmd *cli.
 		md5, checksum = true, openstor.ChecksumNone
 	}
 
+	cse, err := parseClientEncryption(cmd)
+	fatalIf(err, "Unable to parse client-side encryption keys.")
+
 	go func() {
 		totalBytes := int64(0)
 		opts := prepareCopyURLsOpts{
@@ -353,9 +404,11 @@ func doCopySession(ctx context.Context, cancelCopy context.CancelFunc, cmd *cli.
 			isZip:       cmd.Bool("zip"),
 		}
 
+		scanFailed := false
 		for cpURLs := range prepareCopyURLs(ctx, opts) {
 			if cpURLs.Error != nil {
-				errSeen = true
+				errSeen.Store(true)
+				scanFailed = true
 				printCopyURLsError(&cpURLs)
 				break
 			}
@@ -365,6 +418,9 @@ func doCopySession(ctx context.Context, cancelCopy context.CancelFunc, cmd *cli.
 			totalObjects++
 			cpURLsCh <- cpURLs
 		}
+		if !scanFailed {
+			session.ScanDone()
+		}
 		close(cpURLsCh)
 	}()
 
@@ -441,8 +497,9 @@ func doCopySession(ctx context.Context, cancelCopy context.CancelFunc, cmd *cli.
 						return doCopyFake(cpURLs, pg)
 					}, 0)
 				} else {
-					// Print the copy resume summary once in start
+					session.Queued(cpURLs)
 					parallel.queueTask(func() URLs {
+						session.Started(cpURLs)
 						return doCopy(ctx, doCopyOpts{
 							cpURLs:         cpURLs,
 							pg:             pg,
@@ -450,6 +507,8 @@ func doCopySession(ctx context.Context, cancelCopy context.CancelFunc, cmd *cli.
 							isMvCmd:        isMvCmd,
 							preserve:       preserve,
 							isZip:          isZip,
+							session:        session,
+							cse:            cse,
 						})
 					}, cpURLs.SourceContent.Size)
 				}
@@ -458,12 +517,14 @@ func doCopySession(ctx context.Context, cancelCopy context.CancelFunc, cmd *cli.
 	}()
 
 	var retErr error
+	var interrupted bool
 	cpAllFilesErr := true
 
 loop:
 	for {
 		select {
 		case <-globalContext.Done():
+			interrupted = true
 			close(quitCh)
 			cancelCopy()
 			// Receive interrupt notification.
@@ -476,6 +537,9 @@ loop:
 			if !ok {
 				break loop
 			}
+			if session != nil && cpURLs.SourceContent != nil && !session.IsCompleted(cpURLs.SourceContent.URL.String()) {
+				session.Finished(cpURLs)
+			}
 			if cpURLs.Error == nil {
 				cpAllFilesErr = false
 			} else {
@@ -495,7 +559,7 @@ loop:
 					continue loop
 				}
 
-				errSeen = true
+				errSeen.Store(true)
 				if progressReader, pgok := pg.(*progressBar); pgok {
 					if progressReader.Get() > 0 {
 						writeContSize := (int)(cpURLs.SourceContent.Size)
@@ -513,7 +577,7 @@ loop:
 	}
 
 	if progressReader, ok := pg.(*progressBar); ok {
-		if errSeen || (cpAllFilesErr && totalObjects > 0) {
+		if errSeen.Load() || (cpAllFilesErr && totalObjects > 0) {
 			// We only erase a line if we are displaying a progress bar
 			if !globalQuiet && !globalJSON {
 				console.Eraseline()
@@ -523,7 +587,7 @@ loop:
 		}
 	} else {
 		if accntReader, ok := pg.(*accounter); ok {
-			if errSeen || (cpAllFilesErr && totalObjects > 0) {
+			if errSeen.Load() || (cpAllFilesErr && totalObjects > 0) {
 				// We only erase a line if we are displaying a progress bar
 				if !globalQuiet && !globalJSON {
 					console.Eraseline()
@@ -535,10 +599,12 @@ loop:
 	}
 
 	// Source has error
-	if errSeen && totalObjects == 0 && retErr == nil {
+	if errSeen.Load() && totalObjects == 0 && retErr == nil {
 		retErr = exitStatus(globalErrorExitStatus)
 	}
 
+	finishCommandSession(session, interrupted || errSeen.Load() || retErr != nil)
+
 	return retErr
 }
 
@@ -547,7 +613,27 @@ func mainCopy(ctx context.Context, cmd *cli.Command) error {
 	ctx, cancelCopy := context.WithCancel(globalContext)
 	defer cancelCopy()
 
-	checkCopySyntax(ctx, cmd)
+	if cmd.Bool("extract") {
+		return mainCopyExtract(ctx, cmd)
+	}
+	if cmd.IsSet("pack") || cmd.Bool("unpack") {
+		return mainCopyPack(ctx, cmd)
+	}
+
+	var session *sessionV8
+	var args []string
+	if sid := cmd.String("resume"); sid != "" {
+		session = loadCommandSession(sid, "cp")
+		fatalIf(restoreSessionFlags(cmd, session), "Unable to restore flags of session `%s`.", sid)
+		if session.Header.RootPath != "" {
+			// Relative filesystem paths are resolved from the original working folder.
+			fatalIf(probe.NewError(os.Chdir(session.Header.RootPath)), "Unable to change to folder `%s`.", session.Header.RootPath)
+		}
+		args = session.Header.CommandArgs
+	} else {
+		checkCopySyntax(ctx, cmd)
+		args = cmd.Args().Slice()
+	}
 	console.SetColor("Copy", color.New(color.FgGreen, color.Bold))
 
 	var err *probe.Error
@@ -559,7 +645,12 @@ func mainCopy(ctx context.Context, cmd *cli.Command) error {
 	}
 	fatalIf(err, "SSE Error")
 
-	return doCopySession(ctx, cancelCopy, cmd, encryptionKeyMap, false)
+	// Journal recursive and multi source copies, so that they can be resumed.
+	if session == nil && (cmd.Bool("recursive") || len(args) > 2) {
+		session = newCommandSession("cp", args, sessionFlags(cmd, cpFlags))
+	}
+
+	return doCopySession(ctx, cancelCopy, cmd, args, encryptionKeyMap, false, session)
 }
 
 type doCopyOpts struct {
@@ -571,4 +662,6 @@ type doCopyOpts struct {
 	multipartSize            string
 	multipartThreads         string
 	ifNotExists              bool
+	session                  *sessionV8
+	cse                      *clientEncryption
 }
@@ -0,0 +1,714 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/openstor-go/v7/pkg/encrypt"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	packFormatTar = "tar"
+	packFormatZip = "zip"
+
+	// packIndexVersion is the version of the layout of the index object.
+	packIndexVersion = 1
+
+	// Files up to packReadAheadSize are read ahead in parallel while the
+	// archive is written, larger files are streamed when their turn comes.
+	packReadAheadSize = 1 << 20
+)
+
+// packIndex is the JSON object written next to the archives of a pack,
+// it maps the original paths to the archive and the offset of their data.
+type packIndex struct {
+	Version  int           `json:"version"`
+	Format   string        `json:"format"`
+	Created  time.Time     `json:"created"`
+	Source   string        `json:"source"`
+	Archives []packArchive `json:"archives"`
+	Files    []packFile    `json:"files"`
+}
+
+// packArchive is an archive object, named relative to the index.
+type packArchive struct {
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	Files int    `json:"files"`
+}
+
+// packFile is a packed file, Archive is its position in Archives and
+// Offset the position of its data in the archive.
+type packFile struct {
+	Path    string    `json:"path"`
+	Archive int       `json:"archive"`
+	Offset  int64     `json:"offset"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// packMessage is printed for each archive and for the index of a pack.
+type packMessage struct {
+	Status string `json:"status"`
+	Target string `json:"target"`
+	Files  int    `json:"files"`
+	Size   int64  `json:"size"`
+	Index  bool   `json:"index,omitempty"`
+}
+
+func (p packMessage) String() string {
+	if p.Index {
+		return console.Colorize("Copy", fmt.Sprintf("Indexed %d files in `%s`", p.Files, p.Target))
+	}
+	return console.Colorize("Copy", fmt.Sprintf("Packed %d files (%s) into `%s`", p.Files, humanize.IBytes(uint64(p.Size)), p.Target))
+}
+
+func (p packMessage) JSON() string {
+	p.Status = "success"
+	buf, e := json.MarshalIndent(p, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(buf)
+}
+
+// checkPackSyntax - validate the arguments of `cp --pack` and `cp --unpack`.
+func checkPackSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 2 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code.
+	}
+	mode := "--unpack"
+	if cmd.IsSet("pack") {
+		mode = "--pack"
+		if cmd.Bool("unpack") {
+			fatalIf(errInvalidArgument().Trace("unpack"), "--pack cannot be used with --unpack.")
+		}
+		switch cmd.String("pack") {
+		case packFormatTar, packFormatZip:
+		default:
+			fatalIf(errInvalidArgument().Trace(cmd.String("pack")), "--pack only supports `tar` and `zip`.")
+		}
+	} else if cmd.IsSet("pack-size") {
+		fatalIf(errInvalidArgument().Trace("pack-size"), "--pack-size can only be used with --pack.")
+	}
+	flags := []string{"zip", "extract", "rewind", "version-id", "resume", "enc-client-key"}
+	if mode == "--unpack" {
+		flags = append(flags, "older-than", "newer-than")
+	}
+	for _, flag := range flags {
+		if cmd.IsSet(flag) {
+			fatalIf(errInvalidArgument().Trace(flag), "%s cannot be used with --%s.", mode, flag)
+		}
+	}
+}
+
+// mainCopyPack packs a folder into rolling archives, or restores the
+// files of a pack from its index.
+func mainCopyPack(ctx context.Context, cmd *cli.Command) error {
+	checkPackSyntax(ctx, cmd)
+
+	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	srcURL, tgtURL := cmd.Args().Get(0), cmd.Args().Get(1)
+	workers := cmd.Int("max-workers")
+	if workers <= 0 {
+		workers = defaultWorkerFactor
+	}
+	if cmd.Bool("unpack") {
+		return unpackArchives(ctx, srcURL, tgtURL, encKeyDB, workers, cmd.String("storage-class"))
+	}
+
+	packSize, e := humanize.ParseBytes(cmd.String("pack-size"))
+	fatalIf(probe.NewError(e).Trace(cmd.String("pack-size")), "Unable to parse --pack-size.")
+	if packSize == 0 {
+		fatalIf(errInvalidArgument().Trace(cmd.String("pack-size")), "--pack-size must be larger than zero.")
+	}
+	p := &packWriter{
+		format:       cmd.String("pack"),
+		target:       strings.TrimSuffix(tgtURL, "/") + "/pack-" + UTCNow().Format("20060102T150405Z"),
+		packSize:     int64(packSize),
+		encKeyDB:     encKeyDB,
+		storageClass: cmd.String("storage-class"),
+		olderThan:    cmd.String("older-than"),
+		newerThan:    cmd.String("newer-than"),
+	}
+	return p.pack(ctx, srcURL, workers)
+}
+
+// packSource is a file of the source, data holds the content of the
+// files read ahead.
+type packSource struct {
+	rel     string
+	url     string
+	size    int64
+	modTime time.Time
+
+	data  []byte
+	err   *probe.Error
+	ready chan struct{}
+}
+
+// packWriter writes the files of a source into archives of at most
+// packSize bytes, unless a single file is larger, named after target.
+type packWriter struct {
+	format       string
+	target       string
+	packSize     int64
+	encKeyDB     map[string][]prefixSSEPair
+	storageClass string
+	olderThan    string
+	newerThan    string
+
+	index packIndex
+
+	// The archive being written.
+	pipe    *io.PipeWriter
+	counter *countingWriter
+	tw      *tar.Writer
+	zw      *zip.Writer
+	done    chan *probe.Error
+}
+
+func (p *packWriter) archiveURL(i int) string {
+	return fmt.Sprintf("%s-%05d.%s", p.target, i+1, p.format)
+}
+
+// open starts the upload of the next archive.
+func (p *packWriter) open(ctx context.Context) *probe.Error {
+	archiveURL := p.archiveURL(len(p.index.Archives))
+	clnt, err := newClient(archiveURL)
+	if err != nil {
+		return err.Trace(archiveURL)
+	}
+	tgtAlias, _ := url2Alias(archiveURL)
+	opts := PutOptions{
+		metadata:     map[string]string{"Content-Type": guessURLContentType(archiveURL)},
+		sse:          getSSE(archiveURL, p.encKeyDB[tgtAlias]),
+		storageClass: p.storageClass,
+	}
+
+	reader, writer := io.Pipe()
+	p.done = make(chan *probe.Error, 1)
+	go func() {
+		_, err := clnt.Put(ctx, reader, -1, nil, opts)
+		if err != nil {
+			reader.CloseWithError(err.ToGoError())
+		} else {
+			reader.Close()
+		}
+		p.done <- err.Trace(archiveURL)
+	}()
+
+	p.pipe = writer
+	p.counter = &countingWriter{}
+	w := io.MultiWriter(writer, p.counter)
+	if p.format == packFormatZip {
+		p.zw = zip.NewWriter(w)
+	} else {
+		p.tw = tar.NewWriter(w)
+	}
+	p.index.Archives = append(p.index.Archives, packArchive{Name: path.Base(archiveURL)})
+	return nil
+}
+
+// close finishes the archive being written and waits for its upload.
+func (p *packWriter) close() *probe.Error {
+	var e error
+	if p.zw != nil {
+		e = p.zw.Close()
+	} else {
+		e = p.tw.Close()
+	}
+	if e != nil {
+		p.pipe.CloseWithError(e)
+	} else {
+		p.pipe.Close()
+	}
+	err := <-p.done
+	if err == nil && e != nil {
+		err = probe.NewError(e)
+	}
+	archive := &p.index.Archives[len(p.index.Archives)-1]
+	archive.Size = p.counter.n.Load()
+	p.pipe, p.tw, p.zw = nil, nil, nil
+	if err != nil {
+		return err
+	}
+	printMsg(packMessage{
+		Target: p.archiveURL(len(p.index.Archives) - 1),
+		Files:  archive.Files,
+		Size:   archive.Size,
+	})
+	return nil
+}
+
+// add appends a file to the archive being written, a new archive is
+// started when the file does not fit in the current one.
+func (p *packWriter) add(ctx context.Context, src *packSource, reader io.Reader) *probe.Error {
+	if p.pipe != nil && p.counter.n.Load() > 0 && p.counter.n.Load()+src.size > p.packSize {
+		if err := p.close(); err != nil {
+			return err
+		}
+	}
+	if p.pipe == nil {
+		if err := p.open(ctx); err != nil {
+			return err
+		}
+	}
+
+	var (
+		w io.Writer
+		e error
+	)
+	if p.zw != nil {
+		// Members are stored uncompressed, so that their data can be
+		// read at its offset.
+		w, e = p.zw.CreateHeader(&zip.FileHeader{
+			Name:     src.rel,
+			Method:   zip.Store,
+			Modified: src.modTime,
+		})
+		if e == nil {
+			// The writer is buffered, the offset is only known once
+			// the header is flushed.
+			e = p.zw.Flush()
+		}
+	} else {
+		e = p.tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     src.rel,
+			Size:     src.size,
+			Mode:     0o644,
+			ModTime:  src.modTime,
+			Format:   tar.FormatPAX,
+		})
+		w = p.tw
+	}
+	if e != nil {
+		return probe.NewError(e).Trace(src.url)
+	}
+	offset := p.counter.n.Load()
+	if _, e = io.CopyN(w, reader, src.size); e == nil && p.zw != nil {
+		e = p.zw.Flush()
+	}
+	if e != nil {
+		return probe.NewError(e).Trace(src.url)
+	}
+
+	archive := len(p.index.Archives) - 1
+	p.index.Archives[archive].Files++
+	p.index.Files = append(p.index.Files, packFile{
+		Path:    src.rel,
+		Archive: archive,
+		Offset:  offset,
+		Size:    src.size,
+		ModTime: src.modTime,
+	})
+	return nil
+}
+
+// sources lists the files of srcURL in order, the content of small files
+// is read ahead by workers.
+func (p *packWriter) sources(ctx context.Context, srcURL string, workers int) (<-chan *packSource, *probe.Error) {
+	clnt, err := newClient(srcURL)
+	if err != nil {
+		return nil, err.Trace(srcURL)
+	}
+	basePath := filepath.ToSlash(clnt.GetURL().Path)
+	if !strings.HasSuffix(basePath, "/") {
+		basePath += "/"
+	}
+	srcAlias, _ := url2Alias(srcURL)
+
+	fetch := make(chan *packSource)
+	for range workers {
+		go func() {
+			for src := range fetch {
+				src.data, src.err = readPackSource(ctx, src, getSSE(src.url, p.encKeyDB[srcAlias]))
+				close(src.ready)
+			}
+		}()
+	}
+
+	sourcesCh := make(chan *packSource, 2*workers)
+	go func() {
+		defer close(sourcesCh)
+		defer close(fetch)
+		for content := range clnt.List(ctx, ListOptions{Recursive: true, ShowDir: DirNone}) {
+			src := &packSource{ready: make(chan struct{})}
+			if content.Err != nil {
+				src.url = srcURL
+				src.err = content.Err.Trace(srcURL)
+				close(src.ready)
+			} else {
+				if content.Type.IsDir() {
+					continue
+				}
+				if p.olderThan != "" && isOlder(content.Time, p.olderThan) {
+					continue
+				}
+				if p.newerThan != "" && isNewer(content.Time, p.newerThan) {
+					continue
+				}
+				src.rel = strings.TrimPrefix(filepath.ToSlash(content.URL.Path), basePath)
+				src.url = strings.TrimSuffix(srcURL, "/") + "/" + src.rel
+				src.size = content.Size
+				src.modTime = content.Time
+			}
+			select {
+			case sourcesCh <- src:
+			case <-ctx.Done():
+				return
+			}
+			if src.err != nil {
+				continue
+			}
+			if src.size > packReadAheadSize {
+				close(src.ready)
+				continue
+			}
+			select {
+			case fetch <- src:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return sourcesCh, nil
+}
+
+// readPackSource reads the content of a small file.
+func readPackSource(ctx context.Context, src *packSource, sse encrypt.ServerSide) ([]byte, *probe.Error) {
+	reader, err := openPackSource(ctx, src, sse)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, e := io.ReadAll(io.LimitReader(reader, src.size+1))
+	if e != nil {
+		return nil, probe.NewError(e).Trace(src.url)
+	}
+	if int64(len(data)) != src.size {
+		return nil, probe.NewError(UnexpectedEOF{TotalSize: src.size, TotalWritten: int64(len(data))}).Trace(src.url)
+	}
+	return data, nil
+}
+
+func openPackSource(ctx context.Context, src *packSource, sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	clnt, err := newClient(src.url)
+	if err != nil {
+		return nil, err.Trace(src.url)
+	}
+	reader, _, err := clnt.Get(ctx, GetOptions{SSE: sse})
+	if err != nil {
+		return nil, err.Trace(src.url)
+	}
+	return reader, nil
+}
+
+// pack writes the archives then the index of the files of srcURL.
+func (p *packWriter) pack(ctx context.Context, srcURL string, workers int) error {
+	sourcesCh, err := p.sources(ctx, srcURL, workers)
+	fatalIf(err, "Unable to list `%s`.", srcURL)
+
+	srcAlias, _ := url2Alias(srcURL)
+	p.index = packIndex{
+		Version: packIndexVersion,
+		Format:  p.format,
+		Created: UTCNow(),
+		Source:  srcURL,
+	}
+
+	failed := false
+	for src := range sourcesCh {
+		<-src.ready
+		if src.err != nil {
+			errorIf(src.err, "Unable to read `%s`.", src.url)
+			failed = true
+			continue
+		}
+		reader := io.NopCloser(bytes.NewReader(src.data))
+		if src.data == nil {
+			reader, err = openPackSource(ctx, src, getSSE(src.url, p.encKeyDB[srcAlias]))
+			if err != nil {
+				errorIf(err, "Unable to read `%s`.", src.url)
+				failed = true
+				continue
+			}
+		}
+		err = p.add(ctx, src, reader)
+		reader.Close()
+		src.data = nil
+		if err != nil {
+			// Part of the file may already be in the archive, which
+			// cannot be completed.
+			if p.pipe != nil {
+				p.pipe.CloseWithError(err.ToGoError())
+				<-p.done
+			}
+			fatalIf(err, "Unable to pack `%s`.", src.url)
+		}
+	}
+	if ctx.Err() != nil {
+		if p.pipe != nil {
+			p.pipe.CloseWithError(ctx.Err())
+			<-p.done
+		}
+		return exitStatus(globalErrorExitStatus)
+	}
+	if p.pipe != nil {
+		fatalIf(p.close(), "Unable to write archive `%s`.", p.archiveURL(len(p.index.Archives)-1))
+	}
+
+	indexURL := p.target + ".json"
+	fatalIf(writePackIndex(ctx, indexURL, &p.index, p.encKeyDB), "Unable to write index `%s`.", indexURL)
+	printMsg(packMessage{Target: indexURL, Files: len(p.index.Files), Index: true})
+
+	if failed {
+		return exitStatus(globalErrorExitStatus)
+	}
+	return nil
+}
+
+func writePackIndex(ctx context.Context, indexURL string, index *packIndex, encKeyDB map[string][]prefixSSEPair) *probe.Error {
+	buf, e := json.MarshalIndent(index, "", " ")
+	if e != nil {
+		return probe.NewError(e)
+	}
+	clnt, err := newClient(indexURL)
+	if err != nil {
+		return err.Trace(indexURL)
+	}
+	tgtAlias, _ := url2Alias(indexURL)
+	_, err = clnt.Put(ctx, bytes.NewReader(buf), int64(len(buf)), nil, PutOptions{
+		metadata: map[string]string{"Content-Type": "application/json"},
+		sse:      getSSE(indexURL, encKeyDB[tgtAlias]),
+	})
+	return err.Trace(indexURL)
+}
+
+// readPackIndex reads and validates the index of a pack.
+func readPackIndex(ctx context.Context, indexURL string, sse encrypt.ServerSide) (*packIndex, *probe.Error) {
+	clnt, err := newClient(indexURL)
+	if err != nil {
+		return nil, err.Trace(indexURL)
+	}
+	reader, _, err := clnt.Get(ctx, GetOptions{SSE: sse})
+	if err != nil {
+		return nil, err.Trace(indexURL)
+	}
+	defer reader.Close()
+
+	index := &packIndex{}
+	if e := json.NewDecoder(reader).Decode(index); e != nil {
+		return nil, probe.NewError(e).Trace(indexURL)
+	}
+	if index.Version != packIndexVersion {
+		return nil, probe.NewError(fmt.Errorf("unsupported pack index version %d", index.Version)).Trace(indexURL)
+	}
+	if index.Format != packFormatTar && index.Format != packFormatZip {
+		return nil, probe.NewError(fmt.Errorf("unsupported pack format %q", index.Format)).Trace(indexURL)
+	}
+	for i, f := range index.Files {
+		name, ok := cleanArchiveName(f.Path, false)
+		if !ok || f.Archive < 0 || f.Archive >= len(index.Archives) || f.Offset < 0 || f.Size < 0 {
+			return nil, probe.NewError(fmt.Errorf("invalid entry %q", f.Path)).Trace(indexURL)
+		}
+		index.Files[i].Path = name
+	}
+	for _, a := range index.Archives {
+		if a.Name == "" || strings.ContainsAny(a.Name, "/\\") {
+			return nil, probe.NewError(fmt.Errorf("invalid archive %q", a.Name)).Trace(indexURL)
+		}
+	}
+	return index, nil
+}
+
+// unpackArchives restores the files of the pack described by the index
+// at indexURL into tgtURL. Files are read at their offset in the archives,
+// members of zip packs are extracted by the server when it supports it.
+func unpackArchives(ctx context.Context, indexURL, tgtURL string, encKeyDB map[string][]prefixSSEPair, workers int, storageClass string) error {
+	srcAlias, _ := url2Alias(indexURL)
+	srcKeys := encKeyDB[srcAlias]
+	index, err := readPackIndex(ctx, indexURL, getSSE(indexURL, srcKeys))
+	fatalIf(err, "Unable to read index `%s`.", indexURL)
+
+	base := strings.TrimSuffix(indexURL, path.Base(filepath.ToSlash(indexURL)))
+	archiveURL := func(i int) string {
+		return base + index.Archives[i].Name
+	}
+
+	// Archives are read with ranged GETs, in order of their files so
+	// that the streams are reused.
+	readers := make([]*archiveReaderAt, len(index.Archives))
+	reader := func(i int) (*archiveReaderAt, *probe.Error) {
+		if readers[i] != nil {
+			return readers[i], nil
+		}
+		url := archiveURL(i)
+		clnt, err := newClient(url)
+		if err != nil {
+			return nil, err.Trace(url)
+		}
+		sse := getSSE(url, srcKeys)
+		content, err := clnt.Stat(ctx, StatOptions{sse: sse})
+		if err != nil {
+			return nil, err.Trace(url)
+		}
+		readers[i] = &archiveReaderAt{
+			ctx:  ctx,
+			clnt: clnt,
+			opts: GetOptions{SSE: sse, VersionID: content.VersionID},
+			size: content.Size,
+		}
+		return readers[i], nil
+	}
+	defer func() {
+		for _, r := range readers {
+			if r != nil {
+				r.Close()
+			}
+		}
+	}()
+
+	files := make([]packFile, len(index.Files))
+	copy(files, index.Files)
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Archive != files[j].Archive {
+			return files[i].Archive < files[j].Archive
+		}
+		return files[i].Offset < files[j].Offset
+	})
+
+	serverSide := index.Format == packFormatZip && len(files) > 0 && probeServerZip(ctx, archiveURL(files[0].Archive), files[0], srcKeys)
+
+	tgtAlias, _ := url2Alias(tgtURL)
+	var (
+		mu     sync.Mutex
+		failed bool
+		wg     sync.WaitGroup
+	)
+	jobs := make(chan extractJob)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := extractMember(ctx, job, encKeyDB[tgtAlias], storageClass); err != nil {
+					errorIf(err.Trace(indexURL, job.target), "Unable to unpack `%s`.", job.entry.name)
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+					continue
+				}
+				printMsg(copyMessage{
+					Source: indexURL + "/" + job.entry.name,
+					Target: job.target,
+					Size:   job.entry.size,
+				})
+			}
+		}()
+	}
+
+	for _, f := range files {
+		job := extractJob{
+			entry:  &archiveEntry{name: f.Path, size: f.Size, modTime: f.ModTime},
+			target: strings.TrimSuffix(tgtURL, "/") + "/" + f.Path,
+		}
+		if serverSide {
+			url := archiveURL(f.Archive) + "/" + f.Path
+			job.open = func() (io.ReadCloser, error) {
+				return openServerZip(ctx, url, getSSE(url, srcKeys))
+			}
+		} else {
+			r, err := reader(f.Archive)
+			if err != nil {
+				errorIf(err, "Unable to read archive `%s`.", archiveURL(f.Archive))
+				failed = true
+				continue
+			}
+			if f.Offset+f.Size > r.Size() {
+				errorIf(probe.NewError(UnexpectedEOF{TotalSize: f.Offset + f.Size, TotalWritten: r.Size()}).Trace(archiveURL(f.Archive)),
+					"Unable to unpack `%s`.", f.Path)
+				failed = true
+				continue
+			}
+			section := io.NewSectionReader(r, f.Offset, f.Size)
+			job.open = func() (io.ReadCloser, error) { return io.NopCloser(section), nil }
+		}
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failed || ctx.Err() != nil {
+		return exitStatus(globalErrorExitStatus)
+	}
+	return nil
+}
+
+// probeServerZip returns true if the server of a zip pack extracts its
+// members, as MinIO does with `x-minio-extract`. Other servers look for
+// an object named after the member, which does not exist.
+func probeServerZip(ctx context.Context, archiveURL string, f packFile, srcKeys []prefixSSEPair) bool {
+	clnt, err := newClient(archiveURL)
+	if err != nil || clnt.GetURL().Type != objectStorage {
+		return false
+	}
+	url := archiveURL + "/" + f.Path
+	reader, e := openServerZip(ctx, url, getSSE(url, srcKeys))
+	if e != nil {
+		return false
+	}
+	reader.Close()
+	return true
+}
+
+func openServerZip(ctx context.Context, url string, sse encrypt.ServerSide) (io.ReadCloser, error) {
+	clnt, err := newClient(url)
+	if err != nil {
+		return nil, err.ToGoError()
+	}
+	reader, _, err := clnt.Get(ctx, GetOptions{SSE: sse, Zip: true})
+	if err != nil {
+		return nil, err.ToGoError()
+	}
+	return reader, nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackUnpack(t *testing.T) {
+	root := newServeTestRoot(t)
+	src := filepath.Join(root, "docs")
+	files := map[string][]byte{
+		"a.txt":       []byte("0123456789"),
+		"empty":       {},
+		"b/c.txt":     bytes.Repeat([]byte("c"), 3000),
+		"b/d/big.bin": bytes.Repeat([]byte("0123456789abcdef"), (packReadAheadSize+4096)/16),
+	}
+	for name, data := range files {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		if e := os.MkdirAll(filepath.Dir(p), 0o755); e != nil {
+			t.Fatal(e)
+		}
+		if e := os.WriteFile(p, data, 0o644); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	for _, format := range []string{packFormatTar, packFormatZip} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+			tgt := filepath.Join(root, "packs-"+format)
+			p := &packWriter{
+				format:   format,
+				target:   filepath.Join(tgt, "pack-test"),
+				packSize: 4096,
+			}
+			if e := p.pack(ctx, src, 4); e != nil {
+				t.Fatal(e)
+			}
+			indexURL := filepath.Join(tgt, "pack-test.json")
+			index, err := readPackIndex(ctx, indexURL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(index.Files) != len(files) {
+				t.Fatalf("expected %d files in index, got %d", len(files), len(index.Files))
+			}
+			if len(index.Archives) < 2 {
+				t.Fatalf("expected the pack to roll over, got %d archives", len(index.Archives))
+			}
+
+			// Archives are valid and files are at their offset.
+			for _, f := range index.Files {
+				archive, e := os.ReadFile(filepath.Join(tgt, index.Archives[f.Archive].Name))
+				if e != nil {
+					t.Fatal(e)
+				}
+				if got := archive[f.Offset : f.Offset+f.Size]; !bytes.Equal(got, files[f.Path]) {
+					t.Errorf("%s: unexpected content at offset %d", f.Path, f.Offset)
+				}
+			}
+			for _, a := range index.Archives {
+				archive, e := os.ReadFile(filepath.Join(tgt, a.Name))
+				if e != nil {
+					t.Fatal(e)
+				}
+				n := 0
+				if format == packFormatZip {
+					zr, e := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+					if e != nil {
+						t.Fatal(e)
+					}
+					n = len(zr.File)
+				} else {
+					tr := tar.NewReader(bytes.NewReader(archive))
+					for {
+						if _, e = tr.Next(); e == io.EOF {
+							break
+						} else if e != nil {
+							t.Fatal(e)
+						}
+						n++
+					}
+				}
+				if n != a.Files {
+					t.Errorf("%s: expected %d members, got %d", a.Name, a.Files, n)
+				}
+			}
+
+			restore := filepath.Join(root, "restore-"+format)
+			if e := unpackArchives(ctx, indexURL, restore, nil, 4, ""); e != nil {
+				t.Fatal(e)
+			}
+			for name, data := range files {
+				got, e := os.ReadFile(filepath.Join(restore, filepath.FromSlash(name)))
+				if e != nil {
+					t.Fatal(e)
+				}
+				if !bytes.Equal(got, data) {
+					t.Errorf("%s: restored content differs", name)
+				}
+			}
+		})
+	}
+}
+
+func TestReadPackIndexRejectsEscapes(t *testing.T) {
+	root := newServeTestRoot(t)
+	indexURL := filepath.Join(root, "evil.json")
+	index := `{"version":1,"format":"tar","archives":[{"name":"a.tar"}],"files":[{"path":"../../etc/passwd","archive":0}]}`
+	if e := os.WriteFile(indexURL, []byte(index), 0o644); e != nil {
+		t.Fatal(e)
+	}
+	if _, err := readPackIndex(context.Background(), indexURL, nil); err == nil {
+		t.Fatal("expected an error for a path outside of the target")
+	}
+}
@@ -32,7 +32,12 @@ import (
 
 // diff specific flags.
 var (
-	diffFlags = []cli.Flag{}
+	diffFlags = []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "checksum",
+			Usage: "compare object(s) of same size by their checksum",
+		},
+	}
 )
 
 // Compute differences in object name, size, and date between two buckets.
@@ -53,12 +58,16 @@ FLAGS:
   {{range .VisibleFlags}}{{.}}
   {{end}}
 DESCRIPTION:
-  Diff only calculates differences in object name, size and time. It *DOES NOT* compare objects' contents.
+  Diff only calculates differences in object name, size and time. It *DOES NOT* compare objects' contents
+  unless '--checksum' is specified, in which case objects of same size are compared by their ETag or
+  checksum, local files are hashed for the comparison. Objects without a comparable checksum are
+  considered equal.
 
 LEGEND:
   < - object is only in source.
   > - object is only in destination.
   ! - newer object is in source.
+  ! - object differs in content (with --checksum).
 
 EXAMPLES:
   1. Compare a local folder with a folder on Amazon S3 cloud storage.
@@ -66,6 +75,9 @@ EXAMPLES:
 
   2. Compare two folders on a local filesystem.
      {{.Prompt}} {{.HelpName}} ~/Photos /Media/Backup/Photos
+
+  3. Compare a local folder with a folder on MinIO cloud storage, including objects' contents.
+     {{.Prompt}} {{.HelpName}} --checksum ~/Photos play/mybucket/Photos
 `,
 }
 
@@ -96,6 +108,8 @@ func (d diffMessage) String() string {
 		msg = console.Colorize("DiffMetadata", "! "+d.SecondURL)
 	case differInAASourceMTime:
 		msg = console.Colorize("DiffMMSourceMTime", "! "+d.SecondURL)
+	case differInContent:
+		msg = console.Colorize("DiffContent", "! "+d.SecondURL)
 	case differInNone:
 		msg = console.Colorize("DiffInNone", "= "+d.FirstURL)
 	default:
@@ -156,7 +170,7 @@ func checkDiffSyntax(ctx context.Context, cmd *cli.Command, encKeyDB map[string]
 }
 
 // doDiffMain runs the diff.
-func doDiffMain(ctx context.Context, firstURL, secondURL string) error {
+func doDiffMain(ctx context.Context, firstURL, secondURL string, isChecksum bool) error {
 	// Source and targets are always directories
 	sourceSeparator := string(newClientURL(firstURL).Separator)
 	if !strings.HasSuffix(firstURL, sourceSeparator) {
@@ -184,7 +198,7 @@ func doDiffMain(ctx context.Context, firstURL, secondURL string) error {
 	}
 
 	// Diff first and second urls.
-	for diffMsg := range bucketObjectDifference(ctx, firstClient, secondClient) {
+	for diffMsg := range bucketObjectDifference(ctx, firstClient, secondClient, isChecksum) {
 		if diffMsg.Error != nil {
 			errorIf(diffMsg.Error, "Unable to calculate objects difference.")
 			// Ignore error and proceed to next object.
@@ -216,10 +230,11 @@ func mainDiff(ctx context.Context, cmd *cli.Command) error {
 	console.SetColor("DiffSize", color.New(color.FgYellow, color.Bold))
 	console.SetColor("DiffMetadata", color.New(color.FgYellow, color.Bold))
 	console.SetColor("DiffMMSourceMTime", color.New(color.FgYellow, color.Bold))
+	console.SetColor("DiffContent", color.New(color.FgYellow, color.Bold))
 
 	args := cmd.Args()
 	firstURL := args.Get(0)
 	secondURL := args.Get(1)
 
-	return doDiffMain(ctx, firstURL, secondURL)
+	return doDiffMain(ctx, firstURL, secondURL, cmd.Bool("checksum"))
 }
@@ -19,6 +19,10 @@ package cmd
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -42,6 +46,7 @@ const (
 	differInFirst                    // only in source (FIRST)
 	differInSecond                   // only in target (SECOND)
 	differInAASourceMTime            // differs in active-active source modtime
+	differInContent                  // differs in content checksum
 )
 
 func (d differType) String() string {
@@ -56,6 +61,8 @@ func (d differType) String() string {
 		return "mm-source-mtime"
 	case differInType:
 		return "type"
+	case differInContent:
+		return "content"
 	case differInFirst:
 		return "only-in-first"
 	case differInSecond:
@@ -161,9 +168,170 @@ func metadataEqual(m1, m2 map[string]string) bool {
 	return true
 }
 
-func bucketObjectDifference(ctx context.Context, sourceClnt, targetClnt Client) (diffCh chan diffMessage) {
+// contentDifferFunc compares the content of two regular objects of equal size.
+type contentDifferFunc func(src, tgt *ClientContent) (bool, *probe.Error)
+
+// contentChecksumTypes lists the checksums used to compare contents, in
+// order of preference. MD5 is only known from single part ETags.
+var contentChecksumTypes = []string{"SHA256", "CRC64NVME", "CRC32C", "CRC32", "SHA1", "MD5"}
+
+// contentDiffers compares the checksums of src and tgt. Objects on S3 are
+// compared using the checksums stored by the server, local files are hashed
+// with an algorithm the other side knows. When no common checksum can be
+// found the contents are assumed to be equal.
+func contentDiffers(ctx context.Context, srcClnt, tgtClnt Client, src, tgt *ClientContent) (bool, *probe.Error) {
+	if src.Size == 0 {
+		return false, nil
+	}
+
+	srcSums, err := contentChecksums(ctx, srcClnt, src)
+	if err != nil {
+		return false, err.Trace(src.URL.String())
+	}
+	tgtSums, err := contentChecksums(ctx, tgtClnt, tgt)
+	if err != nil {
+		return false, err.Trace(tgt.URL.String())
+	}
+
+	for _, typ := range contentChecksumTypes {
+		if srcSums[typ] != "" && tgtSums[typ] != "" {
+			return srcSums[typ] != tgtSums[typ], nil
+		}
+	}
+
+	srcLocal := src.URL.Type == fileSystem
+	tgtLocal := tgt.URL.Type == fileSystem
+
+	var typ string
+	switch {
+	case srcLocal && tgtLocal:
+		typ = "SHA256"
+	case srcLocal:
+		typ = preferredChecksumType(tgtSums)
+	case tgtLocal:
+		typ = preferredChecksumType(srcSums)
+	}
+	if typ == "" {
+		// Nothing to compare with.
+		return false, nil
+	}
+
+	if srcLocal {
+		sum, err := localChecksum(src.URL.Path, typ)
+		if err != nil {
+			return false, err.Trace(src.URL.String())
+		}
+		srcSums = map[string]string{typ: sum}
+	}
+	if tgtLocal {
+		sum, err := localChecksum(tgt.URL.Path, typ)
+		if err != nil {
+			return false, err.Trace(tgt.URL.String())
+		}
+		tgtSums = map[string]string{typ: sum}
+	}
+	return srcSums[typ] != tgtSums[typ], nil
+}
+
+// preferredChecksumType returns the preferred checksum type found in sums.
+func preferredChecksumType(sums map[string]string) string {
+	for _, typ := range contentChecksumTypes {
+		if sums[typ] != "" {
+			return typ
+		}
+	}
+	return ""
+}
+
+// contentChecksums returns the full object checksums known for an
+// object, issuing a HEAD request when the listing did not carry any.
+// Nothing is returned for local files, they are hashed on demand.
+func contentChecksums(ctx context.Context, clnt Client, ctnt *ClientContent) (map[string]string, *probe.Error) {
+	s3Clnt, ok := clnt.(*S3Client)
+	if !ok {
+		return nil, nil
+	}
+
+	if len(ctnt.Checksum) == 0 {
+		url := ctnt.URL.Clone()
+		objClnt := &S3Client{targetURL: &url, api: s3Clnt.api, virtualStyle: s3Clnt.virtualStyle}
+		st, err := objClnt.Stat(ctx, StatOptions{headOnly: true})
+		if err != nil {
+			return nil, err
+		}
+		ctnt = st
+	}
+
+	sums := make(map[string]string)
+	for typ, sum := range ctnt.Checksum {
+		// Composite checksums of multipart uploads depend on the part size.
+		if !strings.Contains(sum, "-") {
+			sums[typ] = sum
+		}
+	}
+	if etag := strings.Trim(ctnt.ETag, "\""); isMD5ETag(etag) && !isEncryptedContent(ctnt) {
+		sums["MD5"] = strings.ToLower(etag)
+	}
+	return sums, nil
+}
+
+// isMD5ETag returns true for ETags of single part uploads.
+func isMD5ETag(etag string) bool {
+	if len(etag) != 32 {
+		return false
+	}
+	_, e := hex.DecodeString(etag)
+	return e == nil
+}
+
+// isEncryptedContent returns true if the object is encrypted on the
+// server, the ETag of such objects is not the MD5 of their content.
+func isEncryptedContent(ctnt *ClientContent) bool {
+	for k := range ctnt.Metadata {
+		if strings.HasPrefix(strings.ToLower(k), serverEncryptionKeyPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// localChecksum hashes a local file using the given checksum type, encoded
+// the same way the server reports it.
+func localChecksum(path, typ string) (string, *probe.Error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	defer f.Close()
+
+	if typ == "MD5" {
+		h := md5.New()
+		if _, e = io.Copy(h, f); e != nil {
+			return "", probe.NewError(e)
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	for _, ct := range []openstor.ChecksumType{
+		openstor.ChecksumSHA256, openstor.ChecksumCRC64NVME, openstor.ChecksumCRC32C,
+		openstor.ChecksumCRC32, openstor.ChecksumSHA1,
+	} {
+		if ct.String() != typ {
+			continue
+		}
+		checksum, e := ct.ChecksumReader(f)
+		if e != nil {
+			return "", probe.NewError(e)
+		}
+		return checksum.Encoded(), nil
+	}
+	return "", errInvalidArgument().Trace(typ)
+}
+
+func bucketObjectDifference(ctx context.Context, sourceClnt, targetClnt Client, isChecksum bool) (diffCh chan diffMessage) {
 	return objectDifference(ctx, sourceClnt, targetClnt, mirrorOptions{
 		isMetadata: false,
+		isChecksum: isChecksum,
 	})
 }
 
@@ -174,7 +342,13 @@ func objectDifference(ctx context.Context, sourceClnt, targetClnt Client, opts m
 	targetURL := targetClnt.GetURL().String()
 	targetCh := targetClnt.List(ctx, ListOptions{Recursive: true, WithMetadata: opts.isMetadata, ShowDir: DirNone})
 
-	return difference(sourceURL, sourceCh, targetURL, targetCh, opts, false)
+	var contentDiffer contentDifferFunc
+	if opts.isChecksum {
+		contentDiffer = func(src, tgt *ClientContent) (bool, *probe.Error) {
+			return contentDiffers(ctx, sourceClnt, targetClnt, src, tgt)
+		}
+	}
+	return difference(sourceURL, sourceCh, targetURL, targetCh, opts, false, contentDiffer)
 }
 
 func bucketDifference(ctx context.Context, sourceClnt, targetClnt Client, opts mirrorOptions) (diffCh chan diffMessage) {
@@ -221,7 +395,7 @@ func bucketDifference(ctx context.Context, sourceClnt, targetClnt Client, opts m
 		}
 	}()
 
-	return difference(sourceURL, sourceCh, targetURL, targetCh, opts, false)
+	return difference(sourceURL, sourceCh, targetURL, targetCh, opts, false, nil)
 }
 
 func differenceInternal(sourceURL string,
@@ -230,6 +404,7 @@ func differenceInternal(sourceURL string,
 	tgtCh <-chan *ClientContent,
 	opts mirrorOptions,
 	returnSimilar bool,
+	contentDiffer contentDifferFunc,
 	diffCh chan<- diffMessage,
 ) *probe.Error {
 	// Pop first entries from the source and targets
@@ -330,9 +505,12 @@ func differenceInternal(sourceURL string,
 					firstContent:  srcCtnt,
 					secondContent: tgtCtnt,
 				}
+				srcCtnt, srcOk = <-srcCh
+				tgtCtnt, tgtOk = <-tgtCh
 				continue
 			}
-			if srcSize != tgtSize {
+			similar := false
+			if srcSize != tgtSize && (opts.cse == nil || !clientEncryptedSizeEqual(srcSize, tgtSize)) {
 				// Regular files differing in size.
 				diffCh <- diffMessage{
 					FirstURL:      srcCtnt.URL.String(),
@@ -349,7 +527,7 @@ func differenceInternal(sourceURL string,
 					firstContent:  srcCtnt,
 					secondContent: tgtCtnt,
 				}
-			} else if opts.isMetadata &&
+			} else if opts.isMetadata && opts.cse == nil &&
 				!metadataEqual(srcCtnt.UserMetadata, tgtCtnt.UserMetadata) &&
 				!metadataEqual(srcCtnt.Metadata, tgtCtnt.Metadata) {
 
@@ -361,10 +539,28 @@ func differenceInternal(sourceURL string,
 					firstContent:  srcCtnt,
 					secondContent: tgtCtnt,
 				}
+			} else if contentDiffer != nil {
+				// Regular files of same size, compare their checksums.
+				differ, err := contentDiffer(srcCtnt, tgtCtnt)
+				if err != nil {
+					diffCh <- diffMessage{Error: err.Trace(srcCtnt.URL.String(), tgtCtnt.URL.String())}
+				} else if differ {
+					diffCh <- diffMessage{
+						FirstURL:      srcCtnt.URL.String(),
+						SecondURL:     tgtCtnt.URL.String(),
+						Diff:          differInContent,
+						firstContent:  srcCtnt,
+						secondContent: tgtCtnt,
+					}
+				} else {
+					similar = true
+				}
+			} else {
+				similar = true
 			}
 
 			// No differ
-			if returnSimilar {
+			if returnSimilar && similar {
 				diffCh <- diffMessage{
 					FirstURL:      srcCtnt.URL.String(),
 					SecondURL:     tgtCtnt.URL.String(),
@@ -392,13 +588,13 @@ func differenceInternal(sourceURL string,
 
 // objectDifference function finds the difference between all objects
 // recursively in sorted order from source and target.
-func difference(sourceURL string, sourceCh <-chan *ClientContent, targetURL string, targetCh <-chan *ClientContent, opts mirrorOptions, returnSimilar bool) (diffCh chan diffMessage) {
+func difference(sourceURL string, sourceCh <-chan *ClientContent, targetURL string, targetCh <-chan *ClientContent, opts mirrorOptions, returnSimilar bool, contentDiffer contentDifferFunc) (diffCh chan diffMessage) {
 	diffCh = make(chan diffMessage, 10000)
 
 	go func() {
 		defer close(diffCh)
 
-		err := differenceInternal(sourceURL, sourceCh, targetURL, targetCh, opts, returnSimilar, diffCh)
+		err := differenceInternal(sourceURL, sourceCh, targetURL, targetCh, opts, returnSimilar, contentDiffer, diffCh)
 		if err != nil {
 			// handle this specifically for filesystem related errors.
 			switch v := err.ToGoError().(type) {
@@ -18,7 +18,14 @@
 package cmd
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"time"
+
+	"github.com/openstor/mc/pkg/probe"
 )
 
 var testCases = []struct {
@@ -61,3 +68,134 @@ func TestExcludeOptions(t *testing.T) {
 		}
 	}
 }
+
+func TestIsMD5ETag(t *testing.T) {
+	testCases := []struct {
+		etag string
+		md5  bool
+	}{
+		{"d41d8cd98f00b204e9800998ecf8427e", true},
+		{"D41D8CD98F00B204E9800998ECF8427E", true},
+		{"d41d8cd98f00b204e9800998ecf8427e-2", false},
+		{"d41d8cd98f00b204e9800998ecf8427", false},
+		{"z41d8cd98f00b204e9800998ecf8427e", false},
+		{"", false},
+	}
+	for _, test := range testCases {
+		if isMD5ETag(test.etag) != test.md5 {
+			t.Fatalf("Unexpected result %t for ETag %q", !test.md5, test.etag)
+		}
+	}
+}
+
+func TestContentChecksumsETagCase(t *testing.T) {
+	ctnt := &ClientContent{
+		ETag:     `"5D41402ABC4B2A76B9719D911017C592"`,
+		Checksum: map[string]string{"CRC32C": "mnG7TA=="},
+	}
+	sums, err := contentChecksums(context.Background(), &S3Client{}, ctnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Local MD5 checksums are lowercase hex.
+	if sums["MD5"] != "5d41402abc4b2a76b9719d911017c592" {
+		t.Fatalf("Unexpected MD5 checksum %s", sums["MD5"])
+	}
+}
+
+func TestContentDiffers(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, data string) *ClientContent {
+		path := filepath.Join(dir, name)
+		if e := os.WriteFile(path, []byte(data), 0o600); e != nil {
+			t.Fatal(e)
+		}
+		return &ClientContent{URL: *newClientURL(path), Size: int64(len(data))}
+	}
+
+	first := writeFile("first", "hello")
+	same := writeFile("same", "hello")
+	other := writeFile("other", "hellp")
+
+	srcClnt, err := fsNew(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		src, tgt *ClientContent
+		differ   bool
+	}{
+		{first, same, false},
+		{first, other, true},
+		{other, same, true},
+	}
+	for i, test := range testCases {
+		differ, err := contentDiffers(context.Background(), srcClnt, srcClnt, test.src, test.tgt)
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error %v", i+1, err)
+		}
+		if differ != test.differ {
+			t.Fatalf("Test %d: expected %t, got %t", i+1, test.differ, differ)
+		}
+	}
+
+	// Local files are hashed with the algorithm known by the other side.
+	sum, err := localChecksum(first.URL.Path, "MD5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != "5d41402abc4b2a76b9719d911017c592" {
+		t.Fatalf("Unexpected MD5 checksum %s", sum)
+	}
+	sum, err = localChecksum(first.URL.Path, "CRC32C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != "mnG7TA==" {
+		t.Fatalf("Unexpected CRC32C checksum %s", sum)
+	}
+}
+
+func TestDifferenceTypeMismatch(t *testing.T) {
+	listing := func(contents ...*ClientContent) <-chan *ClientContent {
+		ch := make(chan *ClientContent, len(contents))
+		for _, ctnt := range contents {
+			ch <- ctnt
+		}
+		close(ch)
+		return ch
+	}
+	srcCh := listing(
+		&ClientContent{URL: *newClientURL("/src/a"), Size: 1},
+		&ClientContent{URL: *newClientURL("/src/b"), Size: 1},
+	)
+	// A folder on the target where the source has a file.
+	tgtCh := listing(
+		&ClientContent{URL: *newClientURL("/tgt/a"), Type: os.ModeDir},
+		&ClientContent{URL: *newClientURL("/tgt/b"), Size: 1},
+	)
+
+	diffCh := make(chan diffMessage, 10)
+	done := make(chan *probe.Error, 1)
+	go func() {
+		done <- differenceInternal("/src", srcCh, "/tgt", tgtCh, mirrorOptions{}, true, nil, diffCh)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the difference to end after a type mismatch")
+	}
+	close(diffCh)
+
+	var diffs []differType
+	for diff := range diffCh {
+		diffs = append(diffs, diff.Diff)
+	}
+	if expected := []differType{differInType, differInNone}; !slices.Equal(diffs, expected) {
+		t.Fatalf("expected %v, got %v", expected, diffs)
+	}
+}
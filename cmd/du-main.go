@@ -52,6 +52,10 @@ var (
 			Name:  "versions",
 			Usage: "include all object versions",
 		},
+		&cli.BoolFlag{
+			Name:  "index",
+			Usage: "summarize from the local index of the bucket, see 'mc index build'",
+		},
 	}
 )
 
@@ -85,6 +89,9 @@ EXAMPLES:
 
   4. Summarize disk usage of 'jazz-songs' bucket with all objects versions
      {{.Prompt}} {{.HelpName}} --versions s3/jazz-songs/
+
+  5. Summarize disk usage of 'jazz-songs' bucket from its local index built with 'mc index build'
+     {{.Prompt}} {{.HelpName}} --index s3/jazz-songs/
 `,
 }
 
@@ -119,7 +126,7 @@ func (r duMessage) JSON() string {
 	return string(msgBytes)
 }
 
-func du(ctx context.Context, urlStr string, timeRef time.Time, withVersions bool, depth int) (sz, objs int64, err error) {
+func du(ctx context.Context, urlStr string, timeRef time.Time, withVersions, useIndex bool, depth int) (sz, objs int64, err error) {
 	targetAlias, targetURL, _ := mustExpandAlias(urlStr)
 
 	if !strings.HasSuffix(targetURL, "/") {
@@ -131,6 +138,13 @@ func du(ctx context.Context, urlStr string, timeRef time.Time, withVersions bool
 		errorIf(pErr.Trace(urlStr), "Failed to summarize disk usage `%s`.", urlStr)
 		return 0, 0, exitStatus(globalErrorExitStatus) // End of journey.
 	}
+	if useIndex {
+		clnt, pErr = newIndexClient(targetAlias, clnt)
+		if pErr != nil {
+			errorIf(pErr.Trace(urlStr), "Unable to use the index of `%s`.", urlStr)
+			return 0, 0, exitStatus(globalErrorExitStatus)
+		}
+	}
 
 	// No disk usage details below this level,
 	// just do a recursive listing
@@ -174,7 +188,7 @@ func du(ctx context.Context, urlStr string, timeRef time.Time, withVersions bool
 			if targetAlias != "" {
 				subDirAlias = targetAlias + "/" + content.URL.Path
 			}
-			used, n, err := du(ctx, subDirAlias, timeRef, withVersions, depth)
+			used, n, err := du(ctx, subDirAlias, timeRef, withVersions, useIndex, depth)
 			if err != nil {
 				return 0, 0, err
 			}
@@ -235,6 +249,7 @@ func mainDu(ctx context.Context, cmd *cli.Command) error {
 
 	withVersions := cmd.Bool("versions")
 	timeRef := parseRewindFlag(cmd.String("rewind"))
+	useIndex := cmd.Bool("index")
 
 	var duErr error
 	var isDir bool
@@ -244,7 +259,7 @@ func mainDu(ctx context.Context, cmd *cli.Command) error {
 			fatalIf(errInvalidArgument().Trace(urlStr), fmt.Sprintf("Source `%s` is not a folder. Only folders are supported by 'du' command.", urlStr))
 		}
 
-		if _, _, err := du(ctx, urlStr, timeRef, withVersions, depth); duErr == nil {
+		if _, _, err := du(ctx, urlStr, timeRef, withVersions, useIndex, depth); duErr == nil {
 			duErr = err
 		}
 	}
@@ -0,0 +1,369 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/openstor/pkg/v3/env"
+	"github.com/secure-io/sio-go"
+	"github.com/secure-io/sio-go/sioutil"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+// Metadata of client-side encrypted objects. The data is encrypted with a
+// random key using the DARE format, the data key is sealed with the key
+// encryption key derived from a keyfile or a passphrase.
+const (
+	cseAlgorithmKey = "X-Amz-Meta-Mc-Cse-Algorithm"
+	cseSealedKeyKey = "X-Amz-Meta-Mc-Cse-Sealed-Key"
+	cseKeyIDKey     = "X-Amz-Meta-Mc-Cse-Key-Id"
+	cseIVKey        = "X-Amz-Meta-Mc-Cse-Iv"
+	cseSizeKey      = "X-Amz-Meta-Mc-Cse-Size"
+
+	// Authentication tag size of each DARE package.
+	cseTagSize = 16
+
+	cseKeyfilePrefix    = "key:"
+	csePassphrasePrefix = "argon2id:"
+)
+
+var encClientFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "enc-client-key",
+		Usage: "encrypt/decrypt objects on the client with the 256 bit key stored in a file. Formats: Raw, Hex or Base64",
+	},
+	&cli.BoolFlag{
+		Name:  "enc-client-passphrase",
+		Usage: "encrypt/decrypt objects on the client with a key derived from a passphrase, read from MC_ENC_CLIENT_PASSPHRASE or prompted",
+	},
+}
+
+// clientEncryption - keys used to encrypt objects before uploading them
+// and to decrypt them after downloading them.
+type clientEncryption struct {
+	key        []byte
+	keyID      string
+	passphrase []byte
+
+	mutex   sync.Mutex
+	salt    []byte            // salt of the passphrase key of new objects.
+	derived map[string][]byte // passphrase keys by salt.
+}
+
+// parseClientEncryption returns the client-side encryption keys of the
+// command, nil if client-side encryption is not requested.
+func parseClientEncryption(cmd *cli.Command) (*clientEncryption, *probe.Error) {
+	keyFile := cmd.String("enc-client-key")
+	if keyFile == "" {
+		keyFile = env.Get("MC_ENC_CLIENT_KEY", "")
+	}
+	usePassphrase := cmd.Bool("enc-client-passphrase")
+
+	switch {
+	case keyFile != "" && usePassphrase:
+		return nil, errInvalidArgument().Trace(keyFile)
+	case keyFile != "":
+		key, err := readClientEncryptionKey(keyFile)
+		if err != nil {
+			return nil, err.Trace(keyFile)
+		}
+		sum := sha256.Sum256(key)
+		return &clientEncryption{key: key, keyID: cseKeyfilePrefix + hex.EncodeToString(sum[:8])}, nil
+	case usePassphrase:
+		passphrase, err := readPassphrase("MC_ENC_CLIENT_PASSPHRASE", "Enter passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		return &clientEncryption{passphrase: []byte(passphrase), derived: make(map[string][]byte)}, nil
+	}
+	return nil, nil
+}
+
+// readClientEncryptionKey reads a 256 bit key, raw or Hex or Base64 encoded.
+func readClientEncryptionKey(keyFile string) ([]byte, *probe.Error) {
+	data, e := os.ReadFile(keyFile)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	if len(data) == 32 {
+		return data, nil
+	}
+	encoded := string(bytes.TrimSpace(data))
+	if key, e := hex.DecodeString(encoded); e == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, e := base64.StdEncoding.DecodeString(encoded); e == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, probe.NewError(errors.New("key must be 32 bytes, raw or Hex or Base64 encoded"))
+}
+
+// keyEncryptionKey returns the key encryption key identified by keyID.
+func (c *clientEncryption) keyEncryptionKey(keyID string) ([]byte, *probe.Error) {
+	if strings.HasPrefix(keyID, cseKeyfilePrefix) {
+		if c.key == nil || keyID != c.keyID {
+			return nil, probe.NewError(errors.New("object was encrypted with a different key"))
+		}
+		return c.key, nil
+	}
+
+	encodedSalt, ok := strings.CutPrefix(keyID, csePassphrasePrefix)
+	if !ok {
+		return nil, probe.NewError(fmt.Errorf("unknown key `%s`", keyID))
+	}
+	if c.passphrase == nil {
+		return nil, probe.NewError(errors.New("object was encrypted with a passphrase"))
+	}
+	salt, e := base64.StdEncoding.DecodeString(encodedSalt)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key, ok := c.derived[encodedSalt]
+	if !ok {
+		key = derivePassphraseKey(c.passphrase, salt)
+		c.derived[encodedSalt] = key
+	}
+	return key, nil
+}
+
+// newKeyEncryptionKey returns the key encryption key for new objects. A
+// single salt is used per run, so that the passphrase is derived once.
+func (c *clientEncryption) newKeyEncryptionKey() (string, []byte, *probe.Error) {
+	if c.key != nil {
+		return c.keyID, c.key, nil
+	}
+
+	c.mutex.Lock()
+	if c.salt == nil {
+		c.salt = sioutil.MustRandom(32)
+	}
+	keyID := csePassphrasePrefix + base64.StdEncoding.EncodeToString(c.salt)
+	c.mutex.Unlock()
+
+	key, err := c.keyEncryptionKey(keyID)
+	return keyID, key, err
+}
+
+// sealKey encrypts the data key, binding it to the object encryption parameters.
+func sealKey(kek, key, associatedData []byte) ([]byte, error) {
+	block, e := aes.NewCipher(kek)
+	if e != nil {
+		return nil, e
+	}
+	aead, e := cipher.NewGCM(block)
+	if e != nil {
+		return nil, e
+	}
+	nonce := sioutil.MustRandom(aead.NonceSize())
+	return aead.Seal(nonce, nonce, key, associatedData), nil
+}
+
+// unsealKey decrypts a data key sealed by sealKey.
+func unsealKey(kek, sealedKey, associatedData []byte) ([]byte, error) {
+	block, e := aes.NewCipher(kek)
+	if e != nil {
+		return nil, e
+	}
+	aead, e := cipher.NewGCM(block)
+	if e != nil {
+		return nil, e
+	}
+	if len(sealedKey) < aead.NonceSize() {
+		return nil, errors.New("sealed key is too short")
+	}
+	nonce, sealedKey := sealedKey[:aead.NonceSize()], sealedKey[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealedKey, associatedData)
+}
+
+// encrypt returns a reader encrypting size bytes of reader, the size of the
+// encrypted stream and the metadata required to decrypt it.
+func (c *clientEncryption) encrypt(reader io.Reader, size int64) (io.Reader, int64, map[string]string, *probe.Error) {
+	keyID, kek, err := c.newKeyEncryptionKey()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	algorithm := sio.AES_256_GCM
+	if !sioutil.NativeAES() {
+		algorithm = sio.ChaCha20Poly1305
+	}
+	key := sioutil.MustRandom(32)
+	stream, e := algorithm.Stream(key)
+	if e != nil {
+		return nil, 0, nil, probe.NewError(e)
+	}
+	iv := sioutil.MustRandom(stream.NonceSize())
+
+	sealedKey, e := sealKey(kek, key, cseAssociatedData(algorithm.String(), keyID, iv))
+	if e != nil {
+		return nil, 0, nil, probe.NewError(e)
+	}
+
+	metadata := map[string]string{
+		cseAlgorithmKey: algorithm.String(),
+		cseSealedKeyKey: base64.StdEncoding.EncodeToString(sealedKey),
+		cseKeyIDKey:     keyID,
+		cseIVKey:        base64.StdEncoding.EncodeToString(iv),
+		cseSizeKey:      strconv.FormatInt(size, 10),
+	}
+	return stream.EncryptReader(io.LimitReader(reader, size), iv, nil), size + stream.Overhead(size), metadata, nil
+}
+
+// decrypt returns a reader decrypting an object encrypted by encrypt.
+func (c *clientEncryption) decrypt(reader io.ReadCloser, content *ClientContent) (io.ReadCloser, *probe.Error) {
+	algorithm := sio.Algorithm(clientEncryptionMetadata(content, cseAlgorithmKey))
+	keyID := clientEncryptionMetadata(content, cseKeyIDKey)
+	iv, e := base64.StdEncoding.DecodeString(clientEncryptionMetadata(content, cseIVKey))
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	sealedKey, e := base64.StdEncoding.DecodeString(clientEncryptionMetadata(content, cseSealedKeyKey))
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+
+	kek, err := c.keyEncryptionKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	key, e := unsealKey(kek, sealedKey, cseAssociatedData(algorithm.String(), keyID, iv))
+	if e != nil {
+		return nil, probe.NewError(errors.New("unable to unseal the object key, wrong key or passphrase"))
+	}
+	stream, e := algorithm.Stream(key)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	if len(iv) != stream.NonceSize() {
+		return nil, probe.NewError(errors.New("invalid object encryption IV"))
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{stream.DecryptReader(reader, iv, nil), reader}, nil
+}
+
+// readPassphrase reads a passphrase from the environment variable or
+// prompts for it when running in a terminal.
+func readPassphrase(envName, prompt string) (string, *probe.Error) {
+	passphrase := env.Get(envName, "")
+	if passphrase == "" {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return "", probe.NewError(fmt.Errorf("passphrase must be set with %s when not running in a terminal", envName))
+		}
+		fmt.Print(console.Colorize("Prompt", prompt))
+		bytePassphrase, e := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if e != nil {
+			return "", probe.NewError(e)
+		}
+		passphrase = string(bytePassphrase)
+	}
+	if passphrase == "" {
+		return "", probe.NewError(errors.New("passphrase cannot be empty"))
+	}
+	return passphrase, nil
+}
+
+// derivePassphraseKey derives a 256 bit key from a passphrase.
+func derivePassphraseKey(passphrase, salt []byte) []byte {
+	return argon2.IDKey(passphrase, salt, 1, 64*1024, 4, 32)
+}
+
+func cseAssociatedData(algorithm, keyID string, iv []byte) []byte {
+	return []byte(algorithm + "\x00" + keyID + "\x00" + string(iv))
+}
+
+// clientEncryptionMetadata looks up a client-side encryption metadata key,
+// listings and HEAD requests do not report user metadata the same way.
+func clientEncryptionMetadata(content *ClientContent, key string) string {
+	shortKey := strings.TrimPrefix(key, "X-Amz-Meta-")
+	for _, metadata := range []map[string]string{content.Metadata, content.UserMetadata} {
+		for k, v := range metadata {
+			if strings.EqualFold(k, key) || strings.EqualFold(k, shortKey) {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// isClientEncrypted returns true if the object was encrypted on the client.
+func isClientEncrypted(content *ClientContent) bool {
+	return content != nil && clientEncryptionMetadata(content, cseSealedKeyKey) != ""
+}
+
+// clientDecryptedSize returns the size of a client-side encrypted object
+// once decrypted.
+func clientDecryptedSize(content *ClientContent) (int64, bool) {
+	if !isClientEncrypted(content) {
+		return content.Size, false
+	}
+	if size, e := strconv.ParseInt(clientEncryptionMetadata(content, cseSizeKey), 10, 64); e == nil {
+		return size, true
+	}
+	packages := (content.Size + sio.BufSize + cseTagSize - 1) / (sio.BufSize + cseTagSize)
+	return content.Size - packages*cseTagSize, true
+}
+
+// clientEncryptedSize returns the size of size bytes once encrypted.
+func clientEncryptedSize(size int64) int64 {
+	packages := (size + sio.BufSize - 1) / sio.BufSize
+	if packages == 0 {
+		packages = 1
+	}
+	return size + packages*cseTagSize
+}
+
+// removeClientEncryptionMetadata removes the client-side encryption
+// metadata of a decrypted source before it is uploaded again.
+func removeClientEncryptionMetadata(metadata map[string]string) {
+	for k := range metadata {
+		for _, key := range []string{cseAlgorithmKey, cseSealedKeyKey, cseKeyIDKey, cseIVKey, cseSizeKey} {
+			if strings.EqualFold(k, key) {
+				delete(metadata, k)
+			}
+		}
+	}
+}
+
+// clientEncryptedSizeEqual returns true if one of the sizes is the size of
+// the other one once encrypted on the client.
+func clientEncryptedSizeEqual(a, b int64) bool {
+	return a == b || a == clientEncryptedSize(b) || b == clientEncryptedSize(a)
+}
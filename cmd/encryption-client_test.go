@@ -0,0 +1,93 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestClientEncryptionRoundTrip(t *testing.T) {
+	keyfile := &clientEncryption{key: bytes.Repeat([]byte{1}, 32), keyID: "key:0101010101010101"}
+	passphrase := &clientEncryption{passphrase: []byte("correct horse battery staple"), derived: make(map[string][]byte)}
+
+	for _, cse := range []*clientEncryption{keyfile, passphrase} {
+		for _, size := range []int64{0, 1, 16 * 1024, 16*1024 + 1, 100 * 1024} {
+			plaintext := bytes.Repeat([]byte{'a'}, int(size))
+			encReader, encSize, metadata, err := cse.encrypt(bytes.NewReader(plaintext), size)
+			if err != nil {
+				t.Fatalf("size %d: unexpected error: %v", size, err)
+			}
+			ciphertext, e := io.ReadAll(encReader)
+			if e != nil {
+				t.Fatalf("size %d: unexpected error: %v", size, e)
+			}
+			if int64(len(ciphertext)) != encSize || encSize != clientEncryptedSize(size) {
+				t.Fatalf("size %d: expected encrypted size %d, got %d (%d)", size, clientEncryptedSize(size), len(ciphertext), encSize)
+			}
+
+			content := &ClientContent{Size: encSize, Metadata: metadata}
+			if decSize, ok := clientDecryptedSize(content); !ok || decSize != size {
+				t.Fatalf("size %d: expected decrypted size %d, got %d", size, size, decSize)
+			}
+			delete(content.Metadata, cseSizeKey)
+			if decSize, _ := clientDecryptedSize(content); decSize != size {
+				t.Fatalf("size %d: expected decrypted size %d without size metadata, got %d", size, size, decSize)
+			}
+
+			decReader, err := cse.decrypt(io.NopCloser(bytes.NewReader(ciphertext)), content)
+			if err != nil {
+				t.Fatalf("size %d: unexpected error: %v", size, err)
+			}
+			decrypted, e := io.ReadAll(decReader)
+			if e != nil {
+				t.Fatalf("size %d: unexpected error: %v", size, e)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Fatalf("size %d: decrypted data differs", size)
+			}
+		}
+	}
+}
+
+func TestClientEncryptionWrongKey(t *testing.T) {
+	cse := &clientEncryption{passphrase: []byte("secret"), derived: make(map[string][]byte)}
+	encReader, _, metadata, err := cse.encrypt(bytes.NewReader([]byte("data")), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, _ := io.ReadAll(encReader)
+	content := &ClientContent{Size: int64(len(ciphertext)), UserMetadata: map[string]string{}}
+	for k, v := range metadata {
+		// Listings report user metadata without the header prefix.
+		content.UserMetadata[k[len("X-Amz-Meta-"):]] = v
+	}
+	if !isClientEncrypted(content) {
+		t.Fatal("expected object to be reported as encrypted")
+	}
+
+	wrong := &clientEncryption{passphrase: []byte("guess"), derived: make(map[string][]byte)}
+	if _, err := wrong.decrypt(io.NopCloser(bytes.NewReader(ciphertext)), content); err == nil {
+		t.Fatal("expected decryption with a wrong passphrase to fail")
+	}
+	keyfile := &clientEncryption{key: bytes.Repeat([]byte{1}, 32), keyID: "key:0101010101010101"}
+	if _, err := keyfile.decrypt(io.NopCloser(bytes.NewReader(ciphertext)), content); err == nil {
+		t.Fatal("expected decryption with a key file to fail")
+	}
+}
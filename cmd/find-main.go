@@ -93,6 +93,14 @@ var (
 			Name:  "tags",
 			Usage: "match tags with RE2 regex pattern. Specify each with key=regex. MinIO server only.",
 		},
+		&cli.BoolFlag{
+			Name:  "index",
+			Usage: "search the local index of the bucket, see 'mc index build'",
+		},
+		&cli.StringFlag{
+			Name:  "where",
+			Usage: "match objects satisfying a boolean expression (see WHERE)",
+		},
 	}
 )
 
@@ -113,6 +121,7 @@ USAGE:
 FLAGS:
   {{range .VisibleFlags}}{{.}}
   {{end}}
+` + findWhereHelp + `
 EXAMPLES:
   1. Find all "go" files in mybucket.
      {{.Prompt}} {{.HelpName}} s3/mybucket --name "*.go"
@@ -173,6 +182,15 @@ EXAMPLES:
 
   20. Find all objects in mybucket and print them in a custom format.
       {{.Prompt}} {{.HelpName}} s3/mybucket --format "{{.Name}} {{.Size}} {{.ModTime}}"
+
+  21. Find all objects larger than 1GiB in mybucket using its local index built with 'mc index build'.
+      {{.Prompt}} {{.HelpName}} s3/mybucket --index --larger 1GiB
+
+  22. Find objects larger than 1GiB or in the GLACIER storage class, which are not under "tmp/".
+      {{.Prompt}} {{.HelpName}} s3/mybucket --where 'size > 1GiB and (sc == "GLACIER" or tag.env == "prod") and not path ~ "^tmp/"'
+
+  23. Find all noncurrent versions under compliance retention until the end of 2030.
+      {{.Prompt}} {{.HelpName}} s3/mybucket --versions --where 'not latest and retention-mode == COMPLIANCE and retention-until < 2031-01-01'
 `,
 }
 
@@ -224,6 +242,7 @@ type findContext struct {
 	withVersions  bool
 	matchMeta     map[string]*regexp.Regexp
 	matchTags     map[string]*regexp.Regexp
+	where         *findWhere
 
 	// Internal values
 	targetAlias   string
@@ -287,6 +306,11 @@ func mainFind(cliCtx *cli.Command) error {
 	targetAlias, _, hostCfg, err := expandAlias(args[0])
 	fatalIf(err.Trace(args[0]), "Unable to expand alias.")
 
+	if cliCtx.Bool("index") {
+		clnt, err = newIndexClient(targetAlias, clnt)
+		fatalIf(err.Trace(args[0]), "Unable to use the index of `"+args[0]+"`.")
+	}
+
 	var targetFullURL string
 	if hostCfg != nil {
 		targetFullURL = hostCfg.URL
@@ -296,6 +320,12 @@ func mainFind(cliCtx *cli.Command) error {
 		regMatch = regexp.MustCompile(cliCtx.String("regex"))
 	}
 
+	var where *findWhere
+	if cliCtx.String("where") != "" {
+		where, err = parseFindWhere(cliCtx.String("where"))
+		fatalIf(err, "Unable to parse --where expression.")
+	}
+
 	return doFind(ctx, &findContext{
 		Command:       cliCtx,
 		maxDepth:      cliCtx.Uint("maxdepth"),
@@ -317,5 +347,6 @@ func mainFind(cliCtx *cli.Command) error {
 		clnt:          clnt,
 		matchMeta:     getRegexMap(ctx, cliCtx, "metadata"),
 		matchTags:     getRegexMap(ctx, cliCtx, "tags"),
+		where:         where,
 	})
 }
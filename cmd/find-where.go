@@ -0,0 +1,526 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dustin/go-humanize"
+	"github.com/openstor/mc/pkg/probe"
+)
+
+// findWhereHelp documents the --where expression language.
+const findWhereHelp = `WHERE:
+  --where takes a boolean expression of comparisons combined with 'and', 'or',
+  'not' and parentheses, 'and' binds stronger than 'or'. Comparisons are
+  FIELD OP VALUE, boolean fields can be used alone. Values are quoted strings
+  or bare words. Supported operators are ==, !=, <, <=, >, >=, ~ (matches RE2
+  regex) and !~ (does not match RE2 regex).
+
+  Fields:
+    name, path                 base name and path below the search prefix
+    size                       size, values accept units, e.g. 1GiB
+    time                       last modified time, e.g. "2024-01-31T10:00:00Z"
+    age                        time since last modified, e.g. 7d12h
+    sc, storage-class          storage class
+    etag, content-type         ETag and content type
+    version-id                 version ID
+    latest, delete-marker      true for the latest version and delete markers
+    retention-mode             object lock retention mode
+    retention-until            object lock retain until date
+    legal-hold                 object lock legal hold status, ON or OFF
+    replication-status         replication status
+    meta.KEY, tag.KEY          user metadata and tags
+
+  Fields besides name, path, size, time, age, sc, etag and the version fields
+  are only available from MinIO servers.
+`
+
+// Value types of --where fields.
+const (
+	findWhereString = iota
+	findWhereSize
+	findWhereTime
+	findWhereDuration
+	findWhereBool
+)
+
+// findWhereObject - what a --where expression is evaluated against.
+type findWhereObject struct {
+	path     string
+	versions bool
+	content  *ClientContent
+}
+
+// findWhereField - a field of objects which can be compared.
+type findWhereField struct {
+	kind     int
+	metadata bool
+	get      func(o findWhereObject) interface{}
+}
+
+// contentMetadata returns the value of a metadata header of the content,
+// looked up case-insensitively in its user and system metadata.
+func contentMetadata(c *ClientContent, name string) string {
+	for _, m := range []map[string]string{c.UserMetadata, c.Metadata} {
+		for k, v := range m {
+			if strings.EqualFold(k, name) {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// contentRetainUntil returns the object lock retain until date of the content.
+func contentRetainUntil(c *ClientContent) time.Time {
+	t, _ := time.Parse(time.RFC3339, contentMetadata(c, AmzObjectLockRetainUntilDate))
+	return t
+}
+
+var findWhereFields = map[string]findWhereField{
+	"name": {kind: findWhereString, get: func(o findWhereObject) interface{} {
+		return path.Base(o.path)
+	}},
+	"path": {kind: findWhereString, get: func(o findWhereObject) interface{} {
+		return o.path
+	}},
+	"size": {kind: findWhereSize, get: func(o findWhereObject) interface{} {
+		return o.content.Size
+	}},
+	"time": {kind: findWhereTime, get: func(o findWhereObject) interface{} {
+		return o.content.Time
+	}},
+	"age": {kind: findWhereDuration, get: func(o findWhereObject) interface{} {
+		return time.Since(o.content.Time)
+	}},
+	"storage-class": {kind: findWhereString, get: func(o findWhereObject) interface{} {
+		return o.content.StorageClass
+	}},
+	"etag": {kind: findWhereString, get: func(o findWhereObject) interface{} {
+		return strings.Trim(o.content.ETag, "\"")
+	}},
+	"content-type": {kind: findWhereString, metadata: true, get: func(o findWhereObject) interface{} {
+		return contentMetadata(o.content, "Content-Type")
+	}},
+	"version-id": {kind: findWhereString, get: func(o findWhereObject) interface{} {
+		return o.content.VersionID
+	}},
+	"latest": {kind: findWhereBool, get: func(o findWhereObject) interface{} {
+		// Listings without versions only return the latest versions.
+		return o.content.IsLatest || !o.versions
+	}},
+	"delete-marker": {kind: findWhereBool, get: func(o findWhereObject) interface{} {
+		return o.content.IsDeleteMarker
+	}},
+	"retention-mode": {kind: findWhereString, metadata: true, get: func(o findWhereObject) interface{} {
+		if o.content.RetentionMode != "" {
+			return o.content.RetentionMode
+		}
+		return contentMetadata(o.content, AmzObjectLockMode)
+	}},
+	"retention-until": {kind: findWhereTime, metadata: true, get: func(o findWhereObject) interface{} {
+		return contentRetainUntil(o.content)
+	}},
+	"legal-hold": {kind: findWhereString, metadata: true, get: func(o findWhereObject) interface{} {
+		if o.content.LegalHold != "" {
+			return o.content.LegalHold
+		}
+		return contentMetadata(o.content, AmzObjectLockLegalHold)
+	}},
+	"replication-status": {kind: findWhereString, metadata: true, get: func(o findWhereObject) interface{} {
+		if o.content.ReplicationStatus != "" {
+			return o.content.ReplicationStatus
+		}
+		return contentMetadata(o.content, "X-Amz-Replication-Status")
+	}},
+}
+
+// Short names of fields.
+var findWhereFieldAliases = map[string]string{
+	"sc":               "storage-class",
+	"mtime":            "time",
+	"version":          "version-id",
+	"is-latest":        "latest",
+	"is-delete-marker": "delete-marker",
+	"replication":      "replication-status",
+}
+
+// lookupFindWhereField returns the field of a name, including the
+// meta.KEY and tag.KEY fields. Tag keys are case sensitive.
+func lookupFindWhereField(name string) (findWhereField, bool) {
+	prefix, key, _ := strings.Cut(name, ".")
+	switch prefix = strings.ToLower(prefix); {
+	case prefix == "meta" && key != "":
+		return findWhereField{kind: findWhereString, metadata: true, get: func(o findWhereObject) interface{} {
+			if v := contentMetadata(o.content, "X-Amz-Meta-"+key); v != "" {
+				return v
+			}
+			return contentMetadata(o.content, key)
+		}}, true
+	case prefix == "tag" && key != "":
+		return findWhereField{kind: findWhereString, metadata: true, get: func(o findWhereObject) interface{} {
+			return o.content.Tags[key]
+		}}, true
+	}
+	name = strings.ToLower(name)
+	if alias, ok := findWhereFieldAliases[name]; ok {
+		name = alias
+	}
+	field, ok := findWhereFields[name]
+	return field, ok
+}
+
+// findExpr - a node of a parsed --where expression.
+type findExpr interface {
+	eval(o findWhereObject) bool
+}
+
+type findAndExpr struct{ left, right findExpr }
+
+func (e findAndExpr) eval(o findWhereObject) bool { return e.left.eval(o) && e.right.eval(o) }
+
+type findOrExpr struct{ left, right findExpr }
+
+func (e findOrExpr) eval(o findWhereObject) bool { return e.left.eval(o) || e.right.eval(o) }
+
+type findNotExpr struct{ expr findExpr }
+
+func (e findNotExpr) eval(o findWhereObject) bool { return !e.expr.eval(o) }
+
+// findCompareExpr compares a field with a constant value.
+type findCompareExpr struct {
+	field findWhereField
+	op    string
+	value interface{}
+	regex *regexp.Regexp
+}
+
+func (e findCompareExpr) eval(o findWhereObject) bool {
+	v := e.field.get(o)
+	if e.regex != nil {
+		return e.regex.MatchString(v.(string)) == (e.op == "~")
+	}
+
+	var cmp int
+	switch v := v.(type) {
+	case string:
+		cmp = strings.Compare(v, e.value.(string))
+	case int64:
+		cmp = compareOrdered(v, e.value.(int64))
+	case time.Duration:
+		cmp = compareOrdered(v, e.value.(time.Duration))
+	case time.Time:
+		cmp = v.Compare(e.value.(time.Time))
+	case bool:
+		if v != e.value.(bool) {
+			cmp = 1
+		}
+	}
+	switch e.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func compareOrdered[T int64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// findWhere - a parsed --where expression.
+type findWhere struct {
+	expr     findExpr
+	metadata bool
+}
+
+// match returns true if the content at path, relative to the search
+// prefix, satisfies the expression.
+func (w *findWhere) match(path string, versions bool, content *ClientContent) bool {
+	return w.expr.eval(findWhereObject{path: path, versions: versions, content: content})
+}
+
+// findToken - a token of a --where expression.
+type findToken struct {
+	text   string
+	quoted bool
+	offset int
+}
+
+// tokenizeFindWhere splits a --where expression into parentheses,
+// operators, quoted strings and bare words.
+func tokenizeFindWhere(s string) (tokens []findToken, e error) {
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-/:+*", r)
+	}
+	for i := 0; i < len(s); {
+		r := rune(s[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, findToken{text: s[i : i+1], offset: i})
+			i++
+		case strings.ContainsRune("=!<>~&|", r):
+			j := i + 1
+			if j < len(s) && strings.ContainsRune("=~&|", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, findToken{text: s[i:j], offset: i})
+			i = j
+		case r == '"' || r == '\'':
+			j := i + 1
+			for j < len(s) && s[j] != s[i] {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			text := s[i+1 : j]
+			if r == '"' {
+				if text, e = strconv.Unquote(s[i : j+1]); e != nil {
+					return nil, fmt.Errorf("invalid string at offset %d", i)
+				}
+			}
+			tokens = append(tokens, findToken{text: text, quoted: true, offset: i})
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && isWord(rune(s[j])) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected %q at offset %d", s[i], i)
+			}
+			tokens = append(tokens, findToken{text: s[i:j], offset: i})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// findWhereParser - recursive descent parser of --where expressions.
+type findWhereParser struct {
+	tokens   []findToken
+	pos      int
+	metadata bool
+}
+
+func (p *findWhereParser) peek() (findToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return findToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// keyword returns true and consumes the next token if it is one of names.
+func (p *findWhereParser) keyword(names ...string) bool {
+	t, ok := p.peek()
+	if !ok || t.quoted {
+		return false
+	}
+	for _, name := range names {
+		if strings.EqualFold(t.text, name) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *findWhereParser) errorf(format string, args ...interface{}) error {
+	if t, ok := p.peek(); ok {
+		return fmt.Errorf(format+" at offset %d", append(args, t.offset)...)
+	}
+	return fmt.Errorf(format+" at end of expression", args...)
+}
+
+func (p *findWhereParser) parseOr() (findExpr, error) {
+	left, e := p.parseAnd()
+	for e == nil && p.keyword("or", "||") {
+		var right findExpr
+		if right, e = p.parseAnd(); e == nil {
+			left = findOrExpr{left, right}
+		}
+	}
+	return left, e
+}
+
+func (p *findWhereParser) parseAnd() (findExpr, error) {
+	left, e := p.parseNot()
+	for e == nil && p.keyword("and", "&&") {
+		var right findExpr
+		if right, e = p.parseNot(); e == nil {
+			left = findAndExpr{left, right}
+		}
+	}
+	return left, e
+}
+
+func (p *findWhereParser) parseNot() (findExpr, error) {
+	if p.keyword("not", "!") {
+		expr, e := p.parseNot()
+		return findNotExpr{expr}, e
+	}
+	return p.parsePrimary()
+}
+
+func (p *findWhereParser) parsePrimary() (findExpr, error) {
+	if p.keyword("(") {
+		expr, e := p.parseOr()
+		if e != nil {
+			return nil, e
+		}
+		if !p.keyword(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return expr, nil
+	}
+
+	t, ok := p.peek()
+	if !ok || t.quoted {
+		return nil, p.errorf("expected a field")
+	}
+	field, ok := lookupFindWhereField(t.text)
+	if !ok {
+		return nil, p.errorf("unknown field %q", t.text)
+	}
+	p.pos++
+	p.metadata = p.metadata || field.metadata
+
+	var op string
+	for _, candidate := range []string{"==", "=", "!=", "<=", ">=", "<", ">", "~", "!~"} {
+		if p.keyword(candidate) {
+			op = candidate
+			break
+		}
+	}
+	switch op {
+	case "=":
+		op = "=="
+	case "":
+		if field.kind != findWhereBool {
+			return nil, p.errorf("expected an operator after %q", t.text)
+		}
+		return findCompareExpr{field: field, op: "==", value: true}, nil
+	}
+
+	v, ok := p.peek()
+	if !ok || (!v.quoted && strings.ContainsAny(v.text, "()=!<>~&|")) {
+		return nil, p.errorf("expected a value after %q", op)
+	}
+	expr, e := newFindCompareExpr(field, op, v.text)
+	if e != nil {
+		return nil, p.errorf("%v", e)
+	}
+	p.pos++
+	return expr, nil
+}
+
+// newFindCompareExpr converts value to the type of the field.
+func newFindCompareExpr(field findWhereField, op, value string) (findExpr, error) {
+	expr := findCompareExpr{field: field, op: op}
+	if op == "~" || op == "!~" {
+		if field.kind != findWhereString {
+			return nil, fmt.Errorf("%s needs a string field", op)
+		}
+		re, e := regexp.Compile(value)
+		if e != nil {
+			return nil, e
+		}
+		expr.regex = re
+		return expr, nil
+	}
+
+	switch field.kind {
+	case findWhereString:
+		expr.value = value
+	case findWhereSize:
+		size, e := humanize.ParseBytes(value)
+		if e != nil {
+			return nil, fmt.Errorf("invalid size %q", value)
+		}
+		expr.value = int64(size)
+	case findWhereDuration:
+		d, e := ParseDuration(value)
+		if e != nil {
+			return nil, fmt.Errorf("invalid duration %q", value)
+		}
+		expr.value = time.Duration(d)
+	case findWhereTime:
+		for _, format := range append([]string{"2006-01-02", "2006-01-02T15:04"}, rewindSupportedFormat...) {
+			if t, e := time.ParseInLocation(format, value, time.Local); e == nil {
+				expr.value = t
+				return expr, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid time %q", value)
+	case findWhereBool:
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("%s needs a non boolean field", op)
+		}
+		b, e := strconv.ParseBool(value)
+		if e != nil {
+			return nil, fmt.Errorf("invalid boolean %q", value)
+		}
+		expr.value = b
+	}
+	return expr, nil
+}
+
+// parseFindWhere parses a --where expression.
+func parseFindWhere(s string) (*findWhere, *probe.Error) {
+	tokens, e := tokenizeFindWhere(s)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(s)
+	}
+	p := &findWhereParser{tokens: tokens}
+	expr, e := p.parseOr()
+	if e == nil && p.pos < len(p.tokens) {
+		e = p.errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if e != nil {
+		return nil, probe.NewError(e).Trace(s)
+	}
+	return &findWhere{expr: expr, metadata: p.metadata}, nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFindWhere(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		success  bool
+		metadata bool
+	}{
+		{`size > 1GiB`, true, false},
+		{`size > 1GiB and (sc == "STANDARD" or tag.env == "prod") and not path ~ "tmp/"`, true, true},
+		{`latest && !delete-marker || age >= 7d`, true, false},
+		{`retention-until < 2030-01-01 and retention-mode = COMPLIANCE`, true, true},
+		{`name ~ '^report-[0-9]+\.csv$'`, true, false},
+		{`size > `, false, false},
+		{`size ~ "1"`, false, false},
+		{`size > big`, false, false},
+		{`unknown == 1`, false, false},
+		{`(size > 1`, false, false},
+		{`size > 1)`, false, false},
+		{`path ~ "["`, false, false},
+		{`name == "unterminated`, false, false},
+		{`latest > true`, false, false},
+		{`sc`, false, false},
+	}
+	for i, testCase := range testCases {
+		where, err := parseFindWhere(testCase.expr)
+		if testCase.success != (err == nil) {
+			t.Errorf("Test %d: expected success %v, got %v", i+1, testCase.success, err)
+			continue
+		}
+		if err == nil && where.metadata != testCase.metadata {
+			t.Errorf("Test %d: expected metadata %v, got %v", i+1, testCase.metadata, where.metadata)
+		}
+	}
+}
+
+func TestFindWhereMatch(t *testing.T) {
+	now := time.Now()
+	content := func(size int64, sc string, tags map[string]string) *ClientContent {
+		return &ClientContent{
+			Size:         size,
+			StorageClass: sc,
+			Time:         now.Add(-48 * time.Hour),
+			Tags:         tags,
+			VersionID:    "v1",
+			UserMetadata: map[string]string{"X-Amz-Meta-Author": "john"},
+			Metadata: map[string]string{
+				AmzObjectLockMode:            "GOVERNANCE",
+				AmzObjectLockRetainUntilDate: "2030-06-01T00:00:00Z",
+			},
+		}
+	}
+	big := int64(2 << 30)
+
+	testCases := []struct {
+		expr     string
+		path     string
+		versions bool
+		content  *ClientContent
+		match    bool
+	}{
+		{`size > 1GiB`, "a.bin", false, content(big, "STANDARD", nil), true},
+		{`size > 1GiB`, "a.bin", false, content(10, "STANDARD", nil), false},
+		{`size > 1GiB or sc == "GLACIER"`, "a.bin", false, content(10, "GLACIER", nil), true},
+		{`size > 1GiB and not path ~ "tmp/"`, "tmp/a.bin", false, content(big, "STANDARD", nil), false},
+		{`size > 1GiB and (sc == "GLACIER" or tag.env == "prod")`, "a.bin", false, content(big, "STANDARD", map[string]string{"env": "prod"}), true},
+		{`tag.Env == "prod"`, "a.bin", false, content(big, "STANDARD", map[string]string{"env": "prod"}), false},
+		{`not size > 1GiB or size > 1GiB and sc == "GLACIER"`, "a.bin", false, content(big, "STANDARD", nil), false},
+		{`name == "a.bin" and path != "a.bin"`, "dir/a.bin", false, content(1, "", nil), true},
+		{`age > 1d and age < 3d`, "a.bin", false, content(1, "", nil), true},
+		{`latest`, "a.bin", false, content(1, "", nil), true},
+		{`latest`, "a.bin", true, content(1, "", nil), false},
+		{`version-id == v1 and not delete-marker`, "a.bin", true, content(1, "", nil), true},
+		{`meta.author == john and meta.x-amz-meta-author == "john"`, "a.bin", false, content(1, "", nil), true},
+		{`retention-mode == GOVERNANCE and retention-until > 2030-01-01 and retention-until < "2031-01-01T00:00:00Z"`, "a.bin", false, content(1, "", nil), true},
+		{`legal-hold == "ON"`, "a.bin", false, content(1, "", nil), false},
+	}
+	for i, testCase := range testCases {
+		where, err := parseFindWhere(testCase.expr)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i+1, err)
+		}
+		if match := where.match(testCase.path, testCase.versions, testCase.content); match != testCase.match {
+			t.Errorf("Test %d: expected match %v, got %v", i+1, testCase.match, match)
+		}
+	}
+}
@@ -193,6 +193,11 @@ func watchFind(ctxCtx context.Context, ctx *findContext) {
 					Key:  getAliasedPath(ctx, event.Path),
 					Time: time,
 					Size: event.Size,
+				}, &ClientContent{
+					Time:         time,
+					Size:         event.Size,
+					UserMetadata: event.UserMetadata,
+					IsLatest:     true,
 				})
 			}
 		case err, ok := <-watchObj.Errors():
@@ -247,9 +252,9 @@ func getAliasedPath(ctx *findContext, path string) string {
 	return trimSuffixAtMaxDepth(ctx.targetURL, aliasedPath, separator, ctx.maxDepth)
 }
 
-func find(ctxCtx context.Context, ctx *findContext, fileContent contentMessage) {
+func find(ctxCtx context.Context, ctx *findContext, fileContent contentMessage, content *ClientContent) {
 	// Match the incoming content, didn't match return.
-	if !matchFind(ctx, fileContent) {
+	if !matchFind(ctx, fileContent) || !matchFindWhere(ctx, fileContent, content) {
 		return
 	} // For all matching content
 
@@ -277,7 +282,7 @@ func doFind(ctxCtx context.Context, ctx *findContext) error {
 		WithDeleteMarkers: ctx.withVersions,
 		Recursive:         true,
 		ShowDir:           DirFirst,
-		WithMetadata:      len(ctx.matchMeta) > 0 || len(ctx.matchTags) > 0,
+		WithMetadata:      len(ctx.matchMeta) > 0 || len(ctx.matchTags) > 0 || (ctx.where != nil && ctx.where.metadata),
 	}
 
 	// iterate over all content which is within the given directory
@@ -316,7 +321,7 @@ func doFind(ctxCtx context.Context, ctx *findContext) error {
 		}
 
 		// Match the incoming content, didn't match return.
-		if !matchFind(ctx, fileContent) {
+		if !matchFind(ctx, fileContent) || !matchFindWhere(ctx, fileContent, content) {
 			continue
 		} // For all matching content
 
@@ -391,18 +396,31 @@ func stringsReplace(ctx context.Context, args string, fileContent contentMessage
 	return str
 }
 
-// matchFind matches whether fileContent matches appropriately with standard
-// "pattern matching" flags requested by the user, such as "name", "path", "regex" ..etc.
-func matchFind(ctx *findContext, fileContent contentMessage) (match bool) {
-	match = true
+// findRelativePath returns the path of key below the search prefix, file
+// path matching is applied on it.
+func findRelativePath(ctx *findContext, key string) string {
 	prefixPath := ctx.targetURL
 	// Add separator only if targetURL doesn't already have separator.
 	if !strings.HasPrefix(prefixPath, string(ctx.clnt.GetURL().Separator)) {
 		prefixPath = ctx.targetURL + string(ctx.clnt.GetURL().Separator)
 	}
-	// Trim the prefix such that we will apply file path matching techniques
-	// on path excluding the starting prefix.
-	path := strings.TrimPrefix(fileContent.Key, prefixPath)
+	return strings.TrimPrefix(key, prefixPath)
+}
+
+// matchFindWhere matches the content with the --where expression, if any.
+func matchFindWhere(ctx *findContext, fileContent contentMessage, content *ClientContent) bool {
+	if ctx.where == nil {
+		return true
+	}
+	path := strings.TrimPrefix(findRelativePath(ctx, fileContent.Key), string(ctx.clnt.GetURL().Separator))
+	return ctx.where.match(path, ctx.withVersions, content)
+}
+
+// matchFind matches whether fileContent matches appropriately with standard
+// "pattern matching" flags requested by the user, such as "name", "path", "regex" ..etc.
+func matchFind(ctx *findContext, fileContent contentMessage) (match bool) {
+	match = true
+	path := findRelativePath(ctx, fileContent.Key)
 	if match && ctx.ignorePattern != "" {
 		match = !pathMatch(ctx.ignorePattern, path)
 	}
@@ -67,11 +67,15 @@ var globalFlags = []cli.Flag{
 	},
 	&cli.StringFlag{
 		Name:  "limit-upload",
-		Usage: "limits uploads to a maximum rate in KiB/s, MiB/s, GiB/s. (default: unlimited)",
+		Usage: "limits uploads to a maximum rate in KiB/s, MiB/s, GiB/s or to a schedule, see 'mc limit set' (default: unlimited)",
 	},
 	&cli.StringFlag{
 		Name:  "limit-download",
-		Usage: "limits downloads to a maximum rate in KiB/s, MiB/s, GiB/s. (default: unlimited)",
+		Usage: "limits downloads to a maximum rate in KiB/s, MiB/s, GiB/s or to a schedule, see 'mc limit set' (default: unlimited)",
+	},
+	&cli.StringFlag{
+		Name:  "trace-file",
+		Usage: "record all HTTP requests to a HAR (.har) or JSON lines file, see 'mc trace-file summarize'",
 	},
 	&cli.DurationFlag{
 		Name:   "conn-read-deadline",
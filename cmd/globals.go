@@ -32,26 +32,41 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
-	"github.com/dustin/go-humanize"
 	"github.com/muesli/termenv"
 	"github.com/openstor/madmin-go/v4"
+	"github.com/openstor/mc/pkg/httptracer"
+	"github.com/openstor/mc/pkg/limiter"
 	"github.com/openstor/pkg/v3/console"
 	"github.com/urfave/cli/v3"
 	"golang.org/x/net/http/httpguts"
 )
 
 const (
-	globalMCConfigVersion = "10"
+	globalMCConfigVersion = "11"
 
-	globalMCConfigFile = "config.json"
-	globalMCCertsDir   = "certs"
-	globalMCCAsDir     = "CAs"
+	globalMCConfigFile   = "config.json"
+	globalMCKeystoreFile = "keystore.json"
+	globalMCLimitsFile   = "limits.json"
+	globalMCLimitsSocket = "limits.sock"
+	globalMCCertsDir     = "certs"
+	globalMCCAsDir       = "CAs"
 
 	// session config and shared urls related constants
 	globalSessionDir           = "session"
 	globalSharedURLsDataDir    = "share"
 	globalSessionConfigVersion = "8"
 
+	// sync state snapshots related constants
+	globalSyncStateDir     = "sync"
+	globalSyncStateVersion = "1"
+
+	// bandwidth limits related constants
+	globalLimitsVersion = "1"
+
+	// metadata index related constants
+	globalIndexDir     = "index"
+	globalIndexVersion = "1"
+
 	// Profile directory for dumping profiler outputs.
 	globalProfileDir = "profile"
 
@@ -75,6 +90,7 @@ var (
 	globalDebug        = false               // Debug flag set via command line
 	globalNoColor      = false               // No Color flag set via command line
 	globalInsecure     = false               // Insecure flag set via command line
+	globalInsecureSet  = false               // Insecure flag given explicitly via command line
 	globalResolvers    map[string]netip.Addr // Custom mappings from HOST[:PORT] to IP
 	globalAirgapped    = false               // Airgapped flag set via command line
 	globalSubnetConfig []madmin.SubsysConfig // Subnet config
@@ -91,12 +107,16 @@ var (
 	globalConnReadDeadline  time.Duration
 	globalConnWriteDeadline time.Duration
 
-	globalLimitUpload   uint64
-	globalLimitDownload uint64
+	// Bandwidth limit schedules given on the command line.
+	globalLimitUpload   string
+	globalLimitDownload string
 
 	globalContext, globalCancel = context.WithCancel(context.Background())
 
 	globalCustomHeader http.Header
+
+	// Records all HTTP requests when --trace-file is set.
+	globalTraceRecorder *httptracer.Recorder
 )
 
 var (
@@ -138,6 +158,7 @@ func setGlobalsFromContext(ctx context.Context, cmd *cli.Command) (context.Conte
 	globalJSON = globalJSON || json
 	globalNoColor = globalNoColor || noColor || globalJSONLine
 	globalInsecure = globalInsecure || insecure
+	globalInsecureSet = globalInsecureSet || cmd.IsSet("insecure")
 	GlobalDevMode = GlobalDevMode || devMode
 	globalAirgapped = globalAirgapped || airgapped
 
@@ -157,52 +178,37 @@ func setGlobalsFromContext(ctx context.Context, cmd *cli.Command) (context.Conte
 	// 	globalConnWriteDeadline = cmd.GlobalDuration("conn-write-deadline")
 	// }
 
+	// Limits of profiles are applied per alias, see transferLimits().
 	limitUploadStr := cmd.String("limit-upload")
 	// if limitUploadStr == "" {
 	// 	limitUploadStr = cmd.GlobalString("limit-upload")
 	// }
 	if limitUploadStr != "" {
-		var e error
-		globalLimitUpload, e = humanize.ParseBytes(limitUploadStr)
-		if e != nil {
+		if _, e := limiter.ParseSchedule(limitUploadStr); e != nil {
 			return ctx, e
 		}
+		globalLimitUpload = limitUploadStr
 	}
 
 	limitDownloadStr := cmd.String("limit-download")
 	// if limitDownloadStr == "" {
 	// 	limitDownloadStr = cmd.GlobalString("limit-download")
 	// }
-
 	if limitDownloadStr != "" {
-		var e error
-		globalLimitDownload, e = humanize.ParseBytes(limitDownloadStr)
-		if e != nil {
+		if _, e := limiter.ParseSchedule(limitDownloadStr); e != nil {
 			return ctx, e
 		}
+		globalLimitDownload = limitDownloadStr
 	}
 
 	dnsEntries := cmd.StringSlice("resolve")
 	if len(dnsEntries) > 0 {
 		globalResolvers = make(map[string]netip.Addr, len(dnsEntries))
 
-		// Each entry is a HOST[:PORT]=IP pair. This is very similar to cURL's syntax.
 		for _, e := range dnsEntries {
-			i := strings.IndexByte(e, '=')
-			if i < 0 {
-				return ctx, fmt.Errorf("invalid DNS resolve entry %s", e)
-			}
-
-			if strings.ContainsRune(e[:i], ':') {
-				if _, _, err := net.SplitHostPort(e[:i]); err != nil {
-					return ctx, fmt.Errorf("invalid DNS resolve entry %s: %v", e, err)
-				}
-			}
-
-			host := e[:i]
-			addr, err := netip.ParseAddr(e[i+1:])
+			host, addr, err := parseResolveEntry(e)
 			if err != nil {
-				return ctx, fmt.Errorf("invalid DNS resolve entry %s: %v", e, err)
+				return ctx, err
 			}
 			globalResolvers[host] = addr
 		}
@@ -212,18 +218,61 @@ func setGlobalsFromContext(ctx context.Context, cmd *cli.Command) (context.Conte
 	if len(customHeaders) > 0 {
 		globalCustomHeader = make(http.Header)
 		for _, header := range customHeaders {
-			i := strings.IndexByte(header, ':')
-			if i <= 0 {
-				return ctx, fmt.Errorf("invalid custom header entry %s", header)
-			}
-			h := strings.TrimSpace(header[:i])
-			hv := strings.TrimSpace(header[i+1:])
-			if !httpguts.ValidHeaderFieldName(h) || !httpguts.ValidHeaderFieldValue(hv) {
-				return ctx, fmt.Errorf("invalid custom header entry %s", header)
+			h, hv, err := parseCustomHeader(header)
+			if err != nil {
+				return ctx, err
 			}
 			globalCustomHeader.Add(h, hv)
 		}
 	}
 
+	if traceFile := cmd.String("trace-file"); traceFile != "" && globalTraceRecorder == nil {
+		recorder, e := newTraceRecorder(traceFile)
+		if e != nil {
+			return ctx, fmt.Errorf("unable to create trace file %s: %v", traceFile, e)
+		}
+		globalTraceRecorder = recorder
+	}
+
+	// Connection settings of profiles are applied per alias, see
+	// NewS3Config(), only command flags are set here.
+	if e := applyProfileFlags(cmd); e != nil {
+		return ctx, e
+	}
+
 	return ctx, nil
 }
+
+// parseResolveEntry parses a HOST[:PORT]=IP pair. This is very similar to cURL's syntax.
+func parseResolveEntry(e string) (string, netip.Addr, error) {
+	i := strings.IndexByte(e, '=')
+	if i < 0 {
+		return "", netip.Addr{}, fmt.Errorf("invalid DNS resolve entry %s", e)
+	}
+
+	if strings.ContainsRune(e[:i], ':') {
+		if _, _, err := net.SplitHostPort(e[:i]); err != nil {
+			return "", netip.Addr{}, fmt.Errorf("invalid DNS resolve entry %s: %v", e, err)
+		}
+	}
+
+	addr, err := netip.ParseAddr(e[i+1:])
+	if err != nil {
+		return "", netip.Addr{}, fmt.Errorf("invalid DNS resolve entry %s: %v", e, err)
+	}
+	return e[:i], addr, nil
+}
+
+// parseCustomHeader parses a 'NAME: VALUE' header entry.
+func parseCustomHeader(header string) (string, string, error) {
+	i := strings.IndexByte(header, ':')
+	if i <= 0 {
+		return "", "", fmt.Errorf("invalid custom header entry %s", header)
+	}
+	h := strings.TrimSpace(header[:i])
+	hv := strings.TrimSpace(header[i+1:])
+	if !httpguts.ValidHeaderFieldName(h) || !httpguts.ValidHeaderFieldValue(hv) {
+		return "", "", fmt.Errorf("invalid custom header entry %s", header)
+	}
+	return h, hv, nil
+}
@@ -60,7 +60,7 @@ var headCmd = cli.Command{
 	Action:       mainHead,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(append(headFlags, &encCFlag), globalFlags...),
+	Flags:        append(append(append(headFlags, &encCFlag), encClientFlags...), globalFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -86,11 +86,14 @@ EXAMPLES:
 
   4. Display the first lines of a specific object version.
      {{.Prompt}} {{.HelpName}} --version-id "3ddac055-89a7-40fa-8cd3-530a5581b6b8" s3/json-data/population.json
+
+  5. Display the first lines of an object encrypted on the client with a passphrase.
+     {{.Prompt}} {{.HelpName}} --enc-client-passphrase s3/json-data/population.json
 `,
 }
 
 // headURL displays contents of a URL to stdout.
-func headURL(sourceURL, sourceVersion string, timeRef time.Time, encKeyDB map[string][]prefixSSEPair, nlines int64, zip bool) *probe.Error {
+func headURL(sourceURL, sourceVersion string, timeRef time.Time, encKeyDB map[string][]prefixSSEPair, cse *clientEncryption, nlines int64, zip bool) *probe.Error {
 	var reader io.ReadCloser
 	switch sourceURL {
 	case "-":
@@ -98,7 +101,7 @@ func headURL(sourceURL, sourceVersion string, timeRef time.Time, encKeyDB map[st
 	default:
 		var err *probe.Error
 		var content *ClientContent
-		if reader, content, err = getSourceStreamMetadataFromURL(context.Background(), sourceURL, sourceVersion, timeRef, encKeyDB, zip); err != nil {
+		if reader, content, err = getSourceStreamMetadataFromURL(context.Background(), sourceURL, sourceVersion, timeRef, encKeyDB, zip, cse); err != nil {
 			return err.Trace(sourceURL)
 		}
 
@@ -197,6 +200,9 @@ func mainHead(ctx context.Context, cmd *cli.Command) error {
 	encryptionKeys, err := validateAndCreateEncryptionKeys(ctx, cmd)
 	fatalIf(err, "Unable to parse encryption keys.")
 
+	cse, err := parseClientEncryption(cmd)
+	fatalIf(err, "Unable to parse client-side encryption keys.")
+
 	args, versionID, timeRef := parseHeadSyntax(ctx, cmd)
 
 	stdinMode := len(args) == 0
@@ -216,6 +222,7 @@ func mainHead(ctx context.Context, cmd *cli.Command) error {
 			versionID,
 			timeRef,
 			encryptionKeys,
+			cse,
 			cmd.Int64("lines"),
 			cmd.Bool("zip"),
 		)
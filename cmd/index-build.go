@@ -0,0 +1,222 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	jsoncolor "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var indexBuildFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "watch",
+		Usage: "keep the index up to date with bucket notifications after building it",
+	},
+}
+
+var indexBuildCmd = cli.Command{
+	Name:         "build",
+	Usage:        "build a local metadata index of a bucket",
+	Action:       mainIndexBuild,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(indexBuildFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] ALIAS/BUCKET
+
+  The index records all object versions and delete markers of the bucket
+  with their metadata and tags in a local database under the mc config
+  folder. 'mc find', 'mc du', 'mc ls' and 'mc tree' answer from it with
+  the --index flag, without listing the bucket again. Building an index
+  again replaces the previous one once the new index is complete.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Build the index of "mybucket".
+     {{.Prompt}} {{.HelpName}} myminio/mybucket
+
+  2. Build the index of "mybucket" and keep it up to date until interrupted.
+     {{.Prompt}} {{.HelpName}} --watch myminio/mybucket
+`,
+}
+
+// indexBuildMessage container for a built index.
+type indexBuildMessage struct {
+	Status   string `json:"status"`
+	Target   string `json:"target"`
+	Versions int64  `json:"versions"`
+}
+
+func (m indexBuildMessage) String() string {
+	return console.Colorize("IndexMessage", fmt.Sprintf("Indexed %d object versions of `%s`.", m.Versions, m.Target))
+}
+
+func (m indexBuildMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := jsoncolor.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// indexUpdateMessage container for an object updated in the index.
+type indexUpdateMessage struct {
+	Status   string `json:"status"`
+	Key      string `json:"key"`
+	Versions int    `json:"versions"`
+}
+
+func (m indexUpdateMessage) String() string {
+	if m.Versions == 0 {
+		return console.Colorize("IndexUpdate", fmt.Sprintf("Removed `%s` from the index.", m.Key))
+	}
+	return console.Colorize("IndexUpdate", fmt.Sprintf("Updated `%s` in the index.", m.Key))
+}
+
+func (m indexUpdateMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := jsoncolor.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// checkIndexBuildSyntax - validate all the passed arguments
+func checkIndexBuildSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+}
+
+// mainIndexBuild is the handle for "mc index build" command.
+func mainIndexBuild(ctx context.Context, cmd *cli.Command) error {
+	ctx, cancelIndex := context.WithCancel(globalContext)
+	defer cancelIndex()
+
+	checkIndexBuildSyntax(ctx, cmd)
+	console.SetColor("IndexMessage", color.New(color.FgGreen))
+	console.SetColor("IndexUpdate", color.New(color.FgCyan))
+
+	targetURL := strings.TrimSuffix(cmd.Args().First(), "/")
+	alias, bucket, prefix, err := parseIndexTarget(targetURL)
+	fatalIf(err, "Unable to build the index.")
+	if prefix != "" {
+		fatalIf(errInvalidArgument().Trace(targetURL), "Indexes cover whole buckets, please specify ALIAS/BUCKET.")
+	}
+
+	clnt, err := newClient(targetURL)
+	fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
+
+	// Start watching before listing so that no change is missed
+	// while the index is built.
+	var wo *WatchObject
+	if cmd.Bool("watch") {
+		wo, err = clnt.Watch(ctx, WatchOptions{
+			Recursive: true,
+			Events:    []string{"put", "delete"},
+		})
+		fatalIf(err.Trace(targetURL), "Unable to watch `"+targetURL+"`.")
+	}
+
+	versions, err := buildBucketIndex(ctx, clnt, alias, bucket)
+	fatalIf(err.Trace(targetURL), "Unable to build the index.")
+	printMsg(indexBuildMessage{Target: targetURL, Versions: versions})
+
+	if wo == nil {
+		return nil
+	}
+	return watchBucketIndex(ctx, clnt, alias, bucket, wo)
+}
+
+// watchBucketIndex updates the index of the bucket with the current
+// versions of every object reported in bucket notifications.
+func watchBucketIndex(ctx context.Context, clnt Client, alias, bucket string, wo *WatchObject) error {
+	prefix := string(clnt.GetURL().Separator) + bucket + string(clnt.GetURL().Separator)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-wo.Errors():
+			if !ok {
+				return nil
+			}
+			errorIf(err.Trace(clnt.GetURL().String()), "Unable to watch `%s`.", clnt.GetURL())
+		case events, ok := <-wo.Events():
+			if !ok {
+				return nil
+			}
+			var keys []string
+			seen := make(map[string]bool)
+			for _, event := range events {
+				key, ok := strings.CutPrefix(newClientURL(event.Path).Path, prefix)
+				if ok && !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+			for _, key := range keys {
+				versions, err := listIndexVersions(ctx, clnt, alias, bucket, key)
+				if err != nil {
+					errorIf(err.Trace(key), "Unable to list the versions of `%s`.", key)
+					continue
+				}
+				idx, err := openBucketIndex(alias, bucket, false, 0)
+				fatalIf(err, "Unable to open the index.")
+				err = idx.Update(key, versions)
+				idx.Close()
+				fatalIf(err, "Unable to update the index.")
+				printMsg(indexUpdateMessage{Key: alias + "/" + bucket + "/" + key, Versions: len(versions)})
+			}
+		}
+	}
+}
+
+// listIndexVersions lists the current versions of the object named key.
+func listIndexVersions(ctx context.Context, clnt Client, alias, bucket, key string) (versions []indexEntry, err *probe.Error) {
+	objectURL := clnt.GetURL().Clone()
+	objectURL.Path = string(objectURL.Separator) + bucket + string(objectURL.Separator) + key
+	objectClnt, err := newClientFromAlias(alias, objectURL.String())
+	if err != nil {
+		return nil, err
+	}
+	for content := range objectClnt.List(ctx, ListOptions{
+		Recursive:         true,
+		WithMetadata:      true,
+		WithOlderVersions: true,
+		WithDeleteMarkers: true,
+		ShowDir:           DirNone,
+	}) {
+		if content.Err != nil {
+			return nil, content.Err
+		}
+		if content.URL.Path == objectURL.Path {
+			versions = append(versions, newIndexEntry(key, content))
+		}
+	}
+	return versions, nil
+}
@@ -0,0 +1,389 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openstor/mc/pkg/probe"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of an index database.
+var (
+	indexMetaBucket    = []byte("meta")
+	indexObjectsBucket = []byte("objects")
+)
+
+// Keys of the meta bucket.
+var (
+	indexMetaVersion = []byte("version")
+	indexMetaURL     = []byte("url")
+	indexMetaBuilt   = []byte("built")
+	indexMetaUpdated = []byte("updated")
+)
+
+// indexBatchSize is the number of entries written per transaction while
+// building an index.
+const indexBatchSize = 1000
+
+// indexEntry - a single object version as recorded in the index.
+type indexEntry struct {
+	Key               string            `json:"key"`
+	VersionID         string            `json:"versionId,omitempty"`
+	IsLatest          bool              `json:"isLatest,omitempty"`
+	IsDeleteMarker    bool              `json:"isDeleteMarker,omitempty"`
+	Size              int64             `json:"size"`
+	ModTime           time.Time         `json:"lastModified"`
+	ETag              string            `json:"etag,omitempty"`
+	StorageClass      string            `json:"storageClass,omitempty"`
+	ReplicationStatus string            `json:"replicationStatus,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+	UserMetadata      map[string]string `json:"userMetadata,omitempty"`
+	Tags              map[string]string `json:"tags,omitempty"`
+}
+
+func newIndexEntry(key string, c *ClientContent) indexEntry {
+	return indexEntry{
+		Key:               key,
+		VersionID:         c.VersionID,
+		IsLatest:          c.IsLatest || c.VersionID == "",
+		IsDeleteMarker:    c.IsDeleteMarker,
+		Size:              c.Size,
+		ModTime:           c.Time.UTC(),
+		ETag:              c.ETag,
+		StorageClass:      c.StorageClass,
+		ReplicationStatus: c.ReplicationStatus,
+		Metadata:          c.Metadata,
+		UserMetadata:      c.UserMetadata,
+		Tags:              c.Tags,
+	}
+}
+
+// indexKey returns the database key of an object version. Object names
+// cannot contain NUL, so entries sort by object name first and all
+// versions of an object are next to each other.
+func indexKey(key, versionID string) []byte {
+	return []byte(key + "\x00" + versionID)
+}
+
+// indexKeyPrefix returns the prefix shared by all versions of an object.
+func indexKeyPrefix(key string) []byte {
+	return []byte(key + "\x00")
+}
+
+// sortIndexVersions orders the versions of an object the way listings
+// return them, the latest version first and then newest to oldest.
+func sortIndexVersions(versions []indexEntry) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].IsLatest != versions[j].IsLatest {
+			return versions[i].IsLatest
+		}
+		return versions[i].ModTime.After(versions[j].ModTime)
+	})
+}
+
+// Get index dir name.
+func getIndexDir() (string, *probe.Error) {
+	configDir, err := getMcConfigDir()
+	if err != nil {
+		return "", err.Trace()
+	}
+
+	return filepath.Join(configDir, globalIndexDir), nil
+}
+
+// Get index dir name or die. (NOTE: This `Die` approach is only OK for mc like tools.).
+func mustGetIndexDir() string {
+	indexDir, err := getIndexDir()
+	fatalIf(err.Trace(), "Unable to determine index folder.")
+	return indexDir
+}
+
+// getIndexFile returns the database file of the index of a bucket.
+func getIndexFile(alias, bucket string) string {
+	return filepath.Join(mustGetIndexDir(), alias, bucket+".db")
+}
+
+// isIndexExists returns true if the bucket has an index.
+func isIndexExists(alias, bucket string) bool {
+	_, e := os.Stat(getIndexFile(alias, bucket))
+	return e == nil
+}
+
+// bucketIndex - local database of the object versions of a bucket.
+type bucketIndex struct {
+	db     *bolt.DB
+	alias  string
+	bucket string
+}
+
+// indexInfo - details about an index, kept in its meta bucket.
+type indexInfo struct {
+	URL     string    `json:"url"`
+	Built   time.Time `json:"built"`
+	Updated time.Time `json:"updated"`
+}
+
+// openBucketIndex opens the index of a bucket. Only one process can have
+// an index open for writing, readers and writers wait for each other for
+// up to timeout, zero waits forever.
+func openBucketIndex(alias, bucket string, readOnly bool, timeout time.Duration) (*bucketIndex, *probe.Error) {
+	indexFile := getIndexFile(alias, bucket)
+	if !isIndexExists(alias, bucket) {
+		return nil, errIndexNotFound(alias + "/" + bucket).Trace(indexFile)
+	}
+	db, e := bolt.Open(indexFile, 0o600, &bolt.Options{ReadOnly: readOnly, Timeout: timeout})
+	if e != nil {
+		return nil, probe.NewError(e).Trace(indexFile)
+	}
+	idx := &bucketIndex{db: db, alias: alias, bucket: bucket}
+	var version string
+	e = db.View(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket(indexMetaBucket); meta != nil {
+			version = string(meta.Get(indexMetaVersion))
+		}
+		return nil
+	})
+	if e == nil && version != globalIndexVersion {
+		e = errDummy().Trace(indexFile, version).ToGoError()
+	}
+	if e != nil {
+		db.Close()
+		return nil, probe.NewError(e).Trace(indexFile)
+	}
+	return idx, nil
+}
+
+// Close closes the index database.
+func (idx *bucketIndex) Close() *probe.Error {
+	return probe.NewError(idx.db.Close())
+}
+
+// Info returns the details kept in the meta bucket.
+func (idx *bucketIndex) Info() (info indexInfo, err *probe.Error) {
+	e := idx.db.View(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(indexMetaBucket)
+		info.URL = string(meta.Get(indexMetaURL))
+		if e := info.Built.UnmarshalText(meta.Get(indexMetaBuilt)); e != nil {
+			return e
+		}
+		if updated := meta.Get(indexMetaUpdated); updated != nil {
+			return info.Updated.UnmarshalText(updated)
+		}
+		info.Updated = info.Built
+		return nil
+	})
+	return info, probe.NewError(e)
+}
+
+// Walk calls fn with the versions of every object whose name starts with
+// prefix, in lexical order of object names. Versions are sorted as by
+// sortIndexVersions. fn returns the object name to continue from, or an
+// empty string to continue with the next object.
+func (idx *bucketIndex) Walk(ctx context.Context, prefix string, fn func(versions []indexEntry) (seek string, e error)) *probe.Error {
+	e := idx.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(indexObjectsBucket).Cursor()
+		var versions []indexEntry
+		flush := func() (string, error) {
+			if len(versions) == 0 {
+				return "", nil
+			}
+			sortIndexVersions(versions)
+			seek, e := fn(versions)
+			versions = versions[:0]
+			return seek, e
+		}
+
+		k, v := c.Seek([]byte(prefix))
+		for k != nil && bytes.HasPrefix(k, []byte(prefix)) {
+			if e := ctx.Err(); e != nil {
+				return e
+			}
+			var entry indexEntry
+			if e := json.Unmarshal(v, &entry); e != nil {
+				return e
+			}
+			if len(versions) > 0 && versions[0].Key != entry.Key {
+				seek, e := flush()
+				if e != nil {
+					return e
+				}
+				if seek != "" && seek > entry.Key {
+					k, v = c.Seek([]byte(seek))
+					continue
+				}
+			}
+			versions = append(versions, entry)
+			k, v = c.Next()
+		}
+		_, e := flush()
+		return e
+	})
+	return probe.NewError(e)
+}
+
+// Update replaces all versions of the object named key.
+func (idx *bucketIndex) Update(key string, versions []indexEntry) *probe.Error {
+	e := idx.db.Update(func(tx *bolt.Tx) error {
+		objects := tx.Bucket(indexObjectsBucket)
+		c := objects.Cursor()
+		keyPrefix := indexKeyPrefix(key)
+		for k, _ := c.Seek(keyPrefix); k != nil && bytes.HasPrefix(k, keyPrefix); k, _ = c.Seek(keyPrefix) {
+			if e := c.Delete(); e != nil {
+				return e
+			}
+		}
+		for _, entry := range versions {
+			buf, e := json.Marshal(entry)
+			if e != nil {
+				return e
+			}
+			if e = objects.Put(indexKey(entry.Key, entry.VersionID), buf); e != nil {
+				return e
+			}
+		}
+		updated, e := UTCNow().MarshalText()
+		if e != nil {
+			return e
+		}
+		return tx.Bucket(indexMetaBucket).Put(indexMetaUpdated, updated)
+	})
+	return probe.NewError(e).Trace(key)
+}
+
+// buildBucketIndex lists all object versions of clnt, which must point to
+// the bucket, with their metadata and tags into a new index. The new index
+// replaces any previous one only once it is complete.
+func buildBucketIndex(ctx context.Context, clnt Client, alias, bucket string) (entries int64, err *probe.Error) {
+	indexFile := getIndexFile(alias, bucket)
+	if e := os.MkdirAll(filepath.Dir(indexFile), 0o700); e != nil {
+		return 0, probe.NewError(e)
+	}
+	tmpFile := indexFile + ".tmp"
+	os.Remove(tmpFile)
+
+	db, e := bolt.Open(tmpFile, 0o600, &bolt.Options{NoSync: true})
+	if e != nil {
+		return 0, probe.NewError(e).Trace(tmpFile)
+	}
+	defer os.Remove(tmpFile)
+	defer db.Close()
+
+	built, e := UTCNow().MarshalText()
+	if e != nil {
+		return 0, probe.NewError(e)
+	}
+	e = db.Update(func(tx *bolt.Tx) error {
+		meta, e := tx.CreateBucket(indexMetaBucket)
+		if e != nil {
+			return e
+		}
+		if _, e = tx.CreateBucket(indexObjectsBucket); e != nil {
+			return e
+		}
+		for k, v := range map[string][]byte{
+			string(indexMetaVersion): []byte(globalIndexVersion),
+			string(indexMetaURL):     []byte(alias + "/" + bucket),
+			string(indexMetaBuilt):   built,
+		} {
+			if e = meta.Put([]byte(k), v); e != nil {
+				return e
+			}
+		}
+		return nil
+	})
+	if e != nil {
+		return 0, probe.NewError(e).Trace(tmpFile)
+	}
+
+	var batch []indexEntry
+	writeBatch := func() error {
+		e := db.Update(func(tx *bolt.Tx) error {
+			objects := tx.Bucket(indexObjectsBucket)
+			for _, entry := range batch {
+				buf, e := json.Marshal(entry)
+				if e != nil {
+					return e
+				}
+				if e = objects.Put(indexKey(entry.Key, entry.VersionID), buf); e != nil {
+					return e
+				}
+			}
+			return nil
+		})
+		batch = batch[:0]
+		return e
+	}
+
+	prefix := string(clnt.GetURL().Separator) + bucket + string(clnt.GetURL().Separator)
+	for content := range clnt.List(ctx, ListOptions{
+		Recursive:         true,
+		WithMetadata:      true,
+		WithOlderVersions: true,
+		WithDeleteMarkers: true,
+		ShowDir:           DirNone,
+	}) {
+		if content.Err != nil {
+			return entries, content.Err.Trace(clnt.GetURL().String())
+		}
+		key, ok := strings.CutPrefix(content.URL.Path, prefix)
+		if !ok {
+			continue
+		}
+		batch = append(batch, newIndexEntry(key, content))
+		entries++
+		if len(batch) == indexBatchSize {
+			if e = writeBatch(); e != nil {
+				return entries, probe.NewError(e).Trace(tmpFile)
+			}
+		}
+	}
+	if e = writeBatch(); e != nil {
+		return entries, probe.NewError(e).Trace(tmpFile)
+	}
+	if e = db.Sync(); e != nil {
+		return entries, probe.NewError(e).Trace(tmpFile)
+	}
+	if e = db.Close(); e != nil {
+		return entries, probe.NewError(e).Trace(tmpFile)
+	}
+	if e = os.Rename(tmpFile, indexFile); e != nil {
+		return entries, probe.NewError(e).Trace(indexFile)
+	}
+	return entries, nil
+}
+
+// removeBucketIndex removes the index of a bucket.
+func removeBucketIndex(alias, bucket string) *probe.Error {
+	indexFile := getIndexFile(alias, bucket)
+	if e := os.Remove(indexFile); e != nil {
+		if os.IsNotExist(e) {
+			return errIndexNotFound(alias + "/" + bucket).Trace(indexFile)
+		}
+		return probe.NewError(e).Trace(indexFile)
+	}
+	return nil
+}
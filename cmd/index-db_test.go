@@ -0,0 +1,186 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testListClient lists a fixed set of contents.
+type testListClient struct {
+	Client
+	url      ClientURL
+	contents []*ClientContent
+}
+
+func (c *testListClient) GetURL() ClientURL {
+	return c.url
+}
+
+func (c *testListClient) List(ctx context.Context, opts ListOptions) <-chan *ClientContent {
+	contentCh := make(chan *ClientContent, len(c.contents))
+	for _, content := range c.contents {
+		contentCh <- content
+	}
+	close(contentCh)
+	return contentCh
+}
+
+func testIndexClient(t *testing.T) *indexClient {
+	prevConfigDir := mcCustomConfigDir
+	mcCustomConfigDir = t.TempDir()
+	t.Cleanup(func() {
+		mcCustomConfigDir = prevConfigDir
+	})
+
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	version := func(key, versionID string, latest, deleteMarker bool, age int) *ClientContent {
+		return &ClientContent{
+			URL:            *newClientURL("http://localhost:9000/bucket/" + key),
+			Size:           int64(len(key)),
+			Time:           t0.Add(-time.Duration(age) * time.Hour),
+			VersionID:      versionID,
+			IsLatest:       latest,
+			IsDeleteMarker: deleteMarker,
+			Tags:           map[string]string{"key": key},
+		}
+	}
+	clnt := &testListClient{
+		url: *newClientURL("http://localhost:9000/bucket/"),
+		contents: []*ClientContent{
+			version("a.txt", "v2", true, false, 0),
+			version("a.txt", "v1", false, false, 2),
+			version("dir/b.txt", "v1", true, false, 3),
+			version("dir/sub/c.txt", "v1", true, false, 3),
+			version("deleted.txt", "v2", true, true, 1),
+			version("deleted.txt", "v1", false, false, 4),
+		},
+	}
+	entries, err := buildBucketIndex(context.Background(), clnt, "myminio", "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries != int64(len(clnt.contents)) {
+		t.Fatalf("expected %d entries, got %d", len(clnt.contents), entries)
+	}
+	if _, e := os.Stat(getIndexFile("myminio", "bucket") + ".tmp"); !os.IsNotExist(e) {
+		t.Fatalf("expected temporary index to be removed, got %v", e)
+	}
+	return &indexClient{Client: clnt, alias: "myminio", bucket: "bucket"}
+}
+
+func listIndexClient(t *testing.T, c *indexClient, prefix string, opts ListOptions) (listed []string) {
+	c.Client.(*testListClient).url = *newClientURL("http://localhost:9000/bucket/" + prefix)
+	for content := range c.List(context.Background(), opts) {
+		if content.Err != nil {
+			t.Fatal(content.Err)
+		}
+		entry := content.URL.Path
+		if content.Type.IsDir() {
+			entry += " (dir)"
+		} else if content.VersionID != "" {
+			entry += " " + content.VersionID
+		}
+		listed = append(listed, entry)
+	}
+	return listed
+}
+
+func TestIndexClientList(t *testing.T) {
+	c := testIndexClient(t)
+
+	testCases := []struct {
+		prefix   string
+		opts     ListOptions
+		expected []string
+	}{
+		{
+			opts:     ListOptions{Recursive: true},
+			expected: []string{"/bucket/a.txt v2", "/bucket/dir/b.txt v1", "/bucket/dir/sub/c.txt v1"},
+		},
+		{
+			opts:     ListOptions{},
+			expected: []string{"/bucket/a.txt v2", "/bucket/dir/ (dir)"},
+		},
+		{
+			prefix:   "dir/",
+			opts:     ListOptions{},
+			expected: []string{"/bucket/dir/b.txt v1", "/bucket/dir/sub/ (dir)"},
+		},
+		{
+			opts: ListOptions{Recursive: true, WithOlderVersions: true, WithDeleteMarkers: true},
+			expected: []string{
+				"/bucket/a.txt v2", "/bucket/a.txt v1", "/bucket/deleted.txt v2", "/bucket/deleted.txt v1",
+				"/bucket/dir/b.txt v1", "/bucket/dir/sub/c.txt v1",
+			},
+		},
+		{
+			// Rewind before the deletion and the last update of a.txt.
+			opts:     ListOptions{Recursive: true, TimeRef: time.Date(2023, 12, 31, 22, 30, 0, 0, time.UTC)},
+			expected: []string{"/bucket/a.txt v1", "/bucket/deleted.txt v1", "/bucket/dir/b.txt v1", "/bucket/dir/sub/c.txt v1"},
+		},
+	}
+	for i, testCase := range testCases {
+		listed := listIndexClient(t, c, testCase.prefix, testCase.opts)
+		if !reflect.DeepEqual(listed, testCase.expected) {
+			t.Errorf("Test %d: expected %q, got %q", i+1, testCase.expected, listed)
+		}
+	}
+}
+
+func TestBucketIndexUpdate(t *testing.T) {
+	c := testIndexClient(t)
+
+	idx, err := openBucketIndex("myminio", "bucket", false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := UTCNow()
+	err = idx.Update("a.txt", []indexEntry{{Key: "a.txt", VersionID: "v3", IsLatest: true, ModTime: now}})
+	if err == nil {
+		err = idx.Update("dir/b.txt", nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := idx.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.URL != "myminio/bucket" || info.Updated.Before(info.Built) {
+		t.Fatalf("unexpected index info %+v", info)
+	}
+	summary, err := summarizeIndex(context.Background(), idx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.Close()
+	if summary.Objects != 2 || summary.Versions != 3 || summary.DeleteMarkers != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	listed := listIndexClient(t, c, "", ListOptions{Recursive: true, WithOlderVersions: true})
+	expected := []string{"/bucket/a.txt v3", "/bucket/deleted.txt v1", "/bucket/dir/sub/c.txt v1"}
+	if !reflect.DeepEqual(listed, expected) {
+		t.Fatalf("expected %q, got %q", expected, listed)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	jsoncolor "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var indexInfoCmd = cli.Command{
+	Name:         "info",
+	Usage:        "summarize the local metadata index of a bucket",
+	Action:       mainIndexInfo,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        globalFlags,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} ALIAS/BUCKET[/PREFIX]
+
+  Objects and their sizes are the latest versions which are not delete
+  markers, the sizes of all versions are reported separately.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Summarize the index of "mybucket".
+     {{.Prompt}} {{.HelpName}} myminio/mybucket
+
+  2. Summarize the objects under "photos/2024/" per storage class, as JSON.
+     {{.Prompt}} {{.HelpName}} --json myminio/mybucket/photos/2024/
+`,
+}
+
+// indexClassUsage - usage of a storage class.
+type indexClassUsage struct {
+	Objects int64 `json:"objects"`
+	Size    int64 `json:"size"`
+}
+
+// indexInfoMessage container for the summary of an index.
+type indexInfoMessage struct {
+	Status         string                     `json:"status"`
+	Target         string                     `json:"target"`
+	Built          time.Time                  `json:"built"`
+	Updated        time.Time                  `json:"updated"`
+	Objects        int64                      `json:"objects"`
+	Size           int64                      `json:"size"`
+	Versions       int64                      `json:"versions"`
+	VersionsSize   int64                      `json:"versionsSize"`
+	DeleteMarkers  int64                      `json:"deleteMarkers"`
+	StorageClasses map[string]indexClassUsage `json:"storageClasses,omitempty"`
+}
+
+func (m indexInfoMessage) String() string {
+	var b strings.Builder
+	row := func(key, value string) {
+		fmt.Fprintf(&b, "%-16s: %s\n", console.Colorize("Key", key), value)
+	}
+	row("Target", console.Colorize("Target", m.Target))
+	row("Built", m.Built.Local().Format(printDate))
+	row("Updated", m.Updated.Local().Format(printDate))
+	row("Objects", fmt.Sprintf("%d (%s)", m.Objects, humanize.IBytes(uint64(m.Size))))
+	row("Versions", fmt.Sprintf("%d (%s)", m.Versions, humanize.IBytes(uint64(m.VersionsSize))))
+	row("Delete markers", fmt.Sprintf("%d", m.DeleteMarkers))
+
+	classes := make([]string, 0, len(m.StorageClasses))
+	for class := range m.StorageClasses {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		usage := m.StorageClasses[class]
+		row(class, fmt.Sprintf("%d objects (%s)", usage.Objects, humanize.IBytes(uint64(usage.Size))))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (m indexInfoMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := jsoncolor.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// summarizeIndex aggregates the index entries under prefix.
+func summarizeIndex(ctx context.Context, idx *bucketIndex, prefix string) (m indexInfoMessage, err *probe.Error) {
+	m.StorageClasses = make(map[string]indexClassUsage)
+	err = idx.Walk(ctx, prefix, func(versions []indexEntry) (string, error) {
+		for i, entry := range versions {
+			if entry.IsDeleteMarker {
+				m.DeleteMarkers++
+				continue
+			}
+			m.Versions++
+			m.VersionsSize += entry.Size
+			if i > 0 || !entry.IsLatest {
+				continue
+			}
+			m.Objects++
+			m.Size += entry.Size
+			class := entry.StorageClass
+			if class == "" {
+				class = "STANDARD"
+			}
+			usage := m.StorageClasses[class]
+			usage.Objects++
+			usage.Size += entry.Size
+			m.StorageClasses[class] = usage
+		}
+		return "", nil
+	})
+	return m, err
+}
+
+// checkIndexInfoSyntax - validate all the passed arguments
+func checkIndexInfoSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+}
+
+// mainIndexInfo is the handle for "mc index info" command.
+func mainIndexInfo(ctx context.Context, cmd *cli.Command) error {
+	ctx, cancelIndex := context.WithCancel(globalContext)
+	defer cancelIndex()
+
+	checkIndexInfoSyntax(ctx, cmd)
+	console.SetColor("Key", color.New(color.FgCyan))
+	console.SetColor("Target", color.New(color.Bold))
+
+	targetURL := cmd.Args().First()
+	alias, bucket, prefix, err := parseIndexTarget(targetURL)
+	fatalIf(err, "Unable to summarize the index.")
+
+	idx, err := openBucketIndex(alias, bucket, true, indexOpenTimeout)
+	fatalIf(err, "Unable to open the index.")
+	defer idx.Close()
+
+	info, err := idx.Info()
+	fatalIf(err, "Unable to read the index.")
+	msg, err := summarizeIndex(ctx, idx, prefix)
+	fatalIf(err, "Unable to read the index.")
+
+	msg.Target = targetURL
+	msg.Built = info.Built
+	msg.Updated = info.Updated
+	printMsg(msg)
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/urfave/cli/v3"
+)
+
+var indexSubcommands = []*cli.Command{
+	&indexBuildCmd,
+	&indexInfoCmd,
+	&indexRemoveCmd,
+}
+
+var indexCmd = cli.Command{
+	Name:            "index",
+	Usage:           "manage local metadata indexes of buckets",
+	Action:          mainIndex,
+	Before:          setGlobalsFromContext,
+	Flags:           globalFlags,
+	Commands:        indexSubcommands,
+	HideHelpCommand: true,
+}
+
+// mainIndex is the handle for "mc index" command.
+func mainIndex(ctx context.Context, cmd *cli.Command) error {
+	var subCmds []cli.Command
+	for _, c := range indexSubcommands {
+		subCmds = append(subCmds, *c)
+	}
+	commandNotFound(ctx, cmd, subCmds)
+	return nil
+	// Sub-commands like "build", "info" have their own main.
+}
+
+// parseIndexTarget returns the alias and the bucket of ALIAS/BUCKET[/PREFIX].
+func parseIndexTarget(target string) (alias, bucket, prefix string, err *probe.Error) {
+	alias, bucketPath := url2Alias(target)
+	bucket, prefix, _ = strings.Cut(bucketPath, "/")
+	if alias == "" || bucket == "" {
+		return "", "", "", errInvalidArgument().Trace(target)
+	}
+	if _, _, hostCfg, err := expandAlias(alias); err != nil || hostCfg == nil {
+		return "", "", "", errNoMatchingHost(target).Trace(target)
+	}
+	return alias, bucket, prefix, nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	jsoncolor "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var indexRemoveCmd = cli.Command{
+	Name:         "remove",
+	Aliases:      []string{"rm"},
+	Usage:        "remove the local metadata index of a bucket",
+	Action:       mainIndexRemove,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        globalFlags,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} ALIAS/BUCKET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Remove the index of "mybucket".
+     {{.Prompt}} {{.HelpName}} myminio/mybucket
+`,
+}
+
+// indexRemoveMessage container for a removed index.
+type indexRemoveMessage struct {
+	Status string `json:"status"`
+	Target string `json:"target"`
+}
+
+func (m indexRemoveMessage) String() string {
+	return console.Colorize("IndexMessage", fmt.Sprintf("Removed the index of `%s`.", m.Target))
+}
+
+func (m indexRemoveMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := jsoncolor.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// checkIndexRemoveSyntax - validate all the passed arguments
+func checkIndexRemoveSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+}
+
+// mainIndexRemove is the handle for "mc index remove" command.
+func mainIndexRemove(ctx context.Context, cmd *cli.Command) error {
+	checkIndexRemoveSyntax(ctx, cmd)
+	console.SetColor("IndexMessage", color.New(color.FgGreen))
+
+	targetURL := strings.TrimSuffix(cmd.Args().First(), "/")
+	alias, bucket, _, err := parseIndexTarget(targetURL)
+	fatalIf(err, "Unable to remove the index.")
+	fatalIf(removeBucketIndex(alias, bucket), "Unable to remove the index.")
+
+	printMsg(indexRemoveMessage{Target: alias + "/" + bucket})
+	return nil
+}
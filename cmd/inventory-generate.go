@@ -0,0 +1,315 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+	jsoncolor "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var inventoryGenerateFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "format",
+		Value: inventoryFormatCSV,
+		Usage: "format of the report. Valid options are '[csv, parquet, jsonl]'",
+	},
+	&cli.BoolFlag{
+		Name:  "versions",
+		Usage: "report all versions and delete markers, not only the latest versions",
+	},
+	&cli.StringFlag{
+		Name:  "output",
+		Value: "-",
+		Usage: "write the report to a local file, '-' writes to stdout",
+	},
+	&cli.StringFlag{
+		Name:  "target",
+		Usage: "write the report and its manifest to ALIAS/BUCKET[/PREFIX]",
+	},
+	&cli.StringFlag{
+		Name:  "id",
+		Value: "mc-inventory",
+		Usage: "name of the report under the target, as the ID of an S3 Inventory configuration",
+	},
+}
+
+var inventoryGenerateCmd = cli.Command{
+	Name:         "generate",
+	Usage:        "generate an inventory report of a bucket",
+	Action:       mainInventoryGenerate,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(inventoryGenerateFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] ALIAS/BUCKET[/PREFIX]
+
+  The report has one row per object version with the columns of S3 Inventory:
+  Bucket, Key, VersionId, IsLatest, IsDeleteMarker, Size, LastModifiedDate, ETag,
+  StorageClass, ReplicationStatus, ObjectLockRetainUntilDate, ObjectLockMode,
+  ObjectLockLegalHoldStatus, followed by the object Tags. Object lock and tag
+  columns are only filled by servers returning metadata in listings.
+
+  Reports written to a target follow the S3 Inventory layout:
+    TARGET/BUCKET/ID/data/UUID.csv.gz
+    TARGET/BUCKET/ID/YYYY-MM-DDTHH-MMZ/manifest.json
+    TARGET/BUCKET/ID/YYYY-MM-DDTHH-MMZ/manifest.checksum
+  CSV and JSON lines files are gzip compressed and CSV files have no header,
+  the columns are listed in the manifest.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Print a CSV report of the latest versions in "mybucket".
+     {{.Prompt}} {{.HelpName}} myminio/mybucket
+
+  2. Save a parquet report of all versions and delete markers under "photos/".
+     {{.Prompt}} {{.HelpName}} --versions --format parquet --output photos.parquet myminio/mybucket/photos/
+
+  3. Write a report with its manifest to the bucket "reports".
+     {{.Prompt}} {{.HelpName}} --target myminio/reports/inventory myminio/mybucket
+`,
+}
+
+// inventoryMessage container for a written inventory report.
+type inventoryMessage struct {
+	Status   string   `json:"status"`
+	Source   string   `json:"source"`
+	Objects  int64    `json:"objects"`
+	Files    []string `json:"files"`
+	Manifest string   `json:"manifest,omitempty"`
+}
+
+func (m inventoryMessage) String() string {
+	target := strings.Join(m.Files, ", ")
+	if m.Manifest != "" {
+		target = m.Manifest
+	}
+	return console.Colorize("Inventory", fmt.Sprintf("Wrote the inventory of `%s` (%d object versions) to `%s`.", m.Source, m.Objects, target))
+}
+
+func (m inventoryMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := jsoncolor.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// checkInventoryGenerateSyntax - validate all the passed arguments
+func checkInventoryGenerateSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+	switch strings.ToLower(cmd.String("format")) {
+	case inventoryFormatCSV, inventoryFormatParquet, inventoryFormatJSONL:
+	default:
+		fatalIf(errInvalidArgument().Trace(cmd.String("format")),
+			"Unrecognized format. Valid options are `[csv, parquet, jsonl]`.")
+	}
+	if cmd.IsSet("output") && cmd.IsSet("target") {
+		fatalIf(errInvalidArgument(), "--output and --target cannot be used together.")
+	}
+	if target := cmd.String("target"); target != "" {
+		if alias, bucket := url2Alias(target); alias == "" || bucket == "" {
+			fatalIf(errInvalidArgument().Trace(target), "Target must be ALIAS/BUCKET[/PREFIX].")
+		}
+	}
+}
+
+// mainInventoryGenerate is the handle for "mc inventory generate" command.
+func mainInventoryGenerate(ctx context.Context, cmd *cli.Command) error {
+	ctx, cancelInventory := context.WithCancel(globalContext)
+	defer cancelInventory()
+
+	checkInventoryGenerateSyntax(ctx, cmd)
+	console.SetColor("Inventory", color.New(color.FgGreen))
+
+	srcURL := cmd.Args().First()
+	alias, srcPath := url2Alias(srcURL)
+	bucket, _, _ := strings.Cut(srcPath, "/")
+	if alias == "" || bucket == "" {
+		fatalIf(errInvalidArgument().Trace(srcURL), "Source must be ALIAS/BUCKET[/PREFIX].")
+	}
+	clnt, err := newClient(srcURL)
+	fatalIf(err.Trace(srcURL), "Unable to initialize connection.")
+
+	format := strings.ToLower(cmd.String("format"))
+	versions := cmd.Bool("versions")
+
+	if target := cmd.String("target"); target != "" {
+		msg, err := writeInventoryToTarget(ctx, clnt, bucket, format, versions, strings.TrimSuffix(target, "/"), cmd.String("id"))
+		fatalIf(err.Trace(srcURL, target), "Unable to write the inventory report.")
+		msg.Source = srcURL
+		printMsg(msg)
+		return nil
+	}
+
+	output := cmd.String("output")
+	var w io.Writer = os.Stdout
+	if output != "-" {
+		f, e := os.Create(output)
+		fatalIf(probe.NewError(e).Trace(output), "Unable to create the report file.")
+		defer f.Close()
+		w = f
+	}
+	iw, e := newInventoryWriter(w, format, true, false)
+	fatalIf(probe.NewError(e), "Unable to write the inventory report.")
+	objects, err := listInventory(ctx, clnt, bucket, versions, iw)
+	fatalIf(err.Trace(srcURL), "Unable to write the inventory report.")
+	fatalIf(probe.NewError(iw.Close()).Trace(output), "Unable to write the inventory report.")
+
+	if output != "-" {
+		printMsg(inventoryMessage{Source: srcURL, Objects: objects, Files: []string{output}})
+	}
+	return nil
+}
+
+// listInventory writes a row for every object version under clnt.
+func listInventory(ctx context.Context, clnt Client, bucket string, versions bool, w inventoryWriter) (objects int64, err *probe.Error) {
+	opts := ListOptions{
+		Recursive:         true,
+		WithMetadata:      true,
+		WithOlderVersions: versions,
+		WithDeleteMarkers: versions,
+		ShowDir:           DirNone,
+	}
+	prefix := string(clnt.GetURL().Separator) + bucket + string(clnt.GetURL().Separator)
+	for content := range clnt.List(ctx, opts) {
+		if content.Err != nil {
+			return objects, content.Err.Trace(clnt.GetURL().String())
+		}
+		key := strings.TrimPrefix(content.URL.Path, prefix)
+		if e := w.Write(newInventoryRow(bucket, key, content)); e != nil {
+			return objects, probe.NewError(e)
+		}
+		objects++
+	}
+	return objects, nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	n atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// writeInventoryToTarget streams the report to the target bucket and
+// then writes its manifest and the manifest checksum next to it.
+func writeInventoryToTarget(ctx context.Context, clnt Client, bucket, format string, versions bool, target, id string) (inventoryMessage, *probe.Error) {
+	_, targetPath := url2Alias(target)
+	targetBucket, targetPrefix, _ := strings.Cut(targetPath, "/")
+
+	fileFormat, ext := inventoryFileFormat(format)
+	dataKey := path.Join(targetPrefix, bucket, id, "data", uuid.NewString()+ext)
+	dataURL := target + "/" + strings.TrimPrefix(path.Join(bucket, id, "data", path.Base(dataKey)), "/")
+
+	hash := md5.New()
+	counter := &countingWriter{}
+	pr, pw := io.Pipe()
+	var objects int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		iw, e := newInventoryWriter(io.MultiWriter(pw, hash, counter), format, false, true)
+		if e != nil {
+			pw.CloseWithError(e)
+			return
+		}
+		var err *probe.Error
+		objects, err = listInventory(ctx, clnt, bucket, versions, iw)
+		if err != nil {
+			pw.CloseWithError(err.ToGoError())
+			return
+		}
+		pw.CloseWithError(iw.Close())
+	}()
+
+	if err := putInventoryObject(ctx, dataURL, pr, -1, "application/octet-stream"); err != nil {
+		pr.CloseWithError(err.ToGoError())
+		<-done
+		return inventoryMessage{}, err
+	}
+	<-done
+
+	now := UTCNow()
+	manifest := inventoryManifest{
+		SourceBucket:      bucket,
+		DestinationBucket: "arn:aws:s3:::" + targetBucket,
+		Version:           "2016-11-30",
+		CreationTimestamp: strconv.FormatInt(now.UnixMilli(), 10),
+		FileFormat:        fileFormat,
+		FileSchema:        inventoryFileSchema(format),
+		Files: []inventoryManifestFile{{
+			Key:         dataKey,
+			Size:        counter.n.Load(),
+			MD5checksum: hex.EncodeToString(hash.Sum(nil)),
+		}},
+	}
+	manifestJSON, e := json.MarshalIndent(manifest, "", "  ")
+	if e != nil {
+		return inventoryMessage{}, probe.NewError(e)
+	}
+	manifestSum := md5.Sum(manifestJSON)
+
+	manifestDir := target + "/" + path.Join(bucket, id, now.Format("2006-01-02T15-04Z"))
+	manifestURL := manifestDir + "/manifest.json"
+	if err := putInventoryObject(ctx, manifestURL, strings.NewReader(string(manifestJSON)), int64(len(manifestJSON)), "application/json"); err != nil {
+		return inventoryMessage{}, err
+	}
+	checksum := hex.EncodeToString(manifestSum[:])
+	if err := putInventoryObject(ctx, manifestDir+"/manifest.checksum", strings.NewReader(checksum), int64(len(checksum)), "text/plain"); err != nil {
+		return inventoryMessage{}, err
+	}
+
+	return inventoryMessage{Objects: objects, Files: []string{dataURL}, Manifest: manifestURL}, nil
+}
+
+// putInventoryObject uploads a report file to the target.
+func putInventoryObject(ctx context.Context, targetURL string, reader io.Reader, size int64, contentType string) *probe.Error {
+	clnt, err := newClient(targetURL)
+	if err != nil {
+		return err.Trace(targetURL)
+	}
+	_, err = clnt.Put(ctx, reader, size, nil, PutOptions{
+		metadata: map[string]string{"Content-Type": contentType},
+	})
+	return err.Trace(targetURL)
+}
@@ -0,0 +1,245 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Formats of an inventory report.
+const (
+	inventoryFormatCSV     = "csv"
+	inventoryFormatParquet = "parquet"
+	inventoryFormatJSONL   = "jsonl"
+)
+
+// inventoryRow - one object version of an inventory report. Columns
+// follow the S3 Inventory names, Tags is an addition of mc.
+type inventoryRow struct {
+	Bucket                    string            `json:"Bucket" parquet:"bucket"`
+	Key                       string            `json:"Key" parquet:"key"`
+	VersionID                 string            `json:"VersionId,omitempty" parquet:"version_id,optional"`
+	IsLatest                  bool              `json:"IsLatest" parquet:"is_latest"`
+	IsDeleteMarker            bool              `json:"IsDeleteMarker" parquet:"is_delete_marker"`
+	Size                      int64             `json:"Size" parquet:"size"`
+	LastModifiedDate          time.Time         `json:"LastModifiedDate" parquet:"last_modified_date,timestamp(millisecond)"`
+	ETag                      string            `json:"ETag,omitempty" parquet:"e_tag,optional"`
+	StorageClass              string            `json:"StorageClass,omitempty" parquet:"storage_class,optional"`
+	ReplicationStatus         string            `json:"ReplicationStatus,omitempty" parquet:"replication_status,optional"`
+	ObjectLockRetainUntilDate *time.Time        `json:"ObjectLockRetainUntilDate,omitempty" parquet:"object_lock_retain_until_date,optional"`
+	ObjectLockMode            string            `json:"ObjectLockMode,omitempty" parquet:"object_lock_mode,optional"`
+	ObjectLockLegalHoldStatus string            `json:"ObjectLockLegalHoldStatus,omitempty" parquet:"object_lock_legal_hold_status,optional"`
+	Tags                      map[string]string `json:"Tags,omitempty" parquet:"tags,optional"`
+}
+
+// inventoryCSVSchema names the CSV columns, in the order of inventoryRow.
+var inventoryCSVSchema = []string{
+	"Bucket", "Key", "VersionId", "IsLatest", "IsDeleteMarker", "Size", "LastModifiedDate", "ETag",
+	"StorageClass", "ReplicationStatus", "ObjectLockRetainUntilDate", "ObjectLockMode",
+	"ObjectLockLegalHoldStatus", "Tags",
+}
+
+// inventoryTimeFormat - timestamps of CSV reports, as written by S3 Inventory.
+const inventoryTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// newInventoryRow converts a listed object version to a report row.
+func newInventoryRow(bucket, key string, content *ClientContent) inventoryRow {
+	row := inventoryRow{
+		Bucket:            bucket,
+		Key:               key,
+		VersionID:         content.VersionID,
+		IsLatest:          content.IsLatest || content.VersionID == "",
+		IsDeleteMarker:    content.IsDeleteMarker,
+		Size:              content.Size,
+		LastModifiedDate:  content.Time.UTC(),
+		ETag:              strings.Trim(content.ETag, "\""),
+		StorageClass:      content.StorageClass,
+		ReplicationStatus: content.ReplicationStatus,
+		Tags:              content.Tags,
+	}
+	if row.StorageClass == "" && !row.IsDeleteMarker {
+		row.StorageClass = "STANDARD"
+	}
+
+	// Object lock details are only part of listings returning metadata.
+	row.ObjectLockMode = strings.ToUpper(contentMetadata(content, AmzObjectLockMode))
+	row.ObjectLockLegalHoldStatus = strings.ToUpper(contentMetadata(content, AmzObjectLockLegalHold))
+	if t := contentRetainUntil(content); !t.IsZero() {
+		t = t.UTC()
+		row.ObjectLockRetainUntilDate = &t
+	}
+	return row
+}
+
+// csvRecord returns the row as CSV fields.
+func (r inventoryRow) csvRecord() []string {
+	var retainUntil string
+	if r.ObjectLockRetainUntilDate != nil {
+		retainUntil = r.ObjectLockRetainUntilDate.Format(inventoryTimeFormat)
+	}
+	tags := make(url.Values, len(r.Tags))
+	for k, v := range r.Tags {
+		tags.Set(k, v)
+	}
+	return []string{
+		r.Bucket,
+		r.Key,
+		r.VersionID,
+		strconv.FormatBool(r.IsLatest),
+		strconv.FormatBool(r.IsDeleteMarker),
+		strconv.FormatInt(r.Size, 10),
+		r.LastModifiedDate.Format(inventoryTimeFormat),
+		r.ETag,
+		r.StorageClass,
+		r.ReplicationStatus,
+		retainUntil,
+		r.ObjectLockMode,
+		r.ObjectLockLegalHoldStatus,
+		tags.Encode(),
+	}
+}
+
+// inventoryWriter - writes the rows of a report in a given format.
+type inventoryWriter interface {
+	Write(row inventoryRow) error
+	Close() error
+}
+
+// newInventoryWriter returns a writer of the format. CSV reports start
+// with a header unless they are meant for S3 Inventory consumers, which
+// take the columns from the manifest instead. compress gzips CSV and
+// JSON lines reports, parquet is always compressed with snappy.
+func newInventoryWriter(w io.Writer, format string, header, compress bool) (inventoryWriter, error) {
+	var gz *gzip.Writer
+	if compress && format != inventoryFormatParquet {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+	switch format {
+	case inventoryFormatCSV:
+		cw := &inventoryCSVWriter{w: csv.NewWriter(w), gz: gz}
+		if header {
+			if e := cw.w.Write(inventoryCSVSchema); e != nil {
+				return nil, e
+			}
+		}
+		return cw, nil
+	case inventoryFormatJSONL:
+		return &inventoryJSONLWriter{enc: json.NewEncoder(w), gz: gz}, nil
+	case inventoryFormatParquet:
+		return &inventoryParquetWriter{w: parquet.NewGenericWriter[inventoryRow](w, parquet.Compression(&parquet.Snappy))}, nil
+	}
+	return nil, errInvalidArgument().Trace(format).ToGoError()
+}
+
+// inventoryFileFormat returns the format name of the manifest and the
+// extension of report files.
+func inventoryFileFormat(format string) (name, ext string) {
+	switch format {
+	case inventoryFormatCSV:
+		return "CSV", ".csv.gz"
+	case inventoryFormatParquet:
+		return "Parquet", ".parquet"
+	}
+	return "JSONL", ".jsonl.gz"
+}
+
+// inventoryFileSchema returns the schema recorded in the manifest.
+func inventoryFileSchema(format string) string {
+	if format == inventoryFormatParquet {
+		return parquet.SchemaOf(inventoryRow{}).String()
+	}
+	return strings.Join(inventoryCSVSchema, ", ")
+}
+
+type inventoryCSVWriter struct {
+	w  *csv.Writer
+	gz *gzip.Writer
+}
+
+func (c *inventoryCSVWriter) Write(row inventoryRow) error {
+	return c.w.Write(row.csvRecord())
+}
+
+func (c *inventoryCSVWriter) Close() error {
+	c.w.Flush()
+	if e := c.w.Error(); e != nil {
+		return e
+	}
+	if c.gz != nil {
+		return c.gz.Close()
+	}
+	return nil
+}
+
+type inventoryJSONLWriter struct {
+	enc *json.Encoder
+	gz  *gzip.Writer
+}
+
+func (j *inventoryJSONLWriter) Write(row inventoryRow) error {
+	return j.enc.Encode(row)
+}
+
+func (j *inventoryJSONLWriter) Close() error {
+	if j.gz != nil {
+		return j.gz.Close()
+	}
+	return nil
+}
+
+type inventoryParquetWriter struct {
+	w *parquet.GenericWriter[inventoryRow]
+}
+
+func (p *inventoryParquetWriter) Write(row inventoryRow) error {
+	_, e := p.w.Write([]inventoryRow{row})
+	return e
+}
+
+func (p *inventoryParquetWriter) Close() error {
+	return p.w.Close()
+}
+
+// inventoryManifest - manifest of a report written to a bucket, in the
+// format of S3 Inventory manifests.
+type inventoryManifest struct {
+	SourceBucket      string                  `json:"sourceBucket"`
+	DestinationBucket string                  `json:"destinationBucket"`
+	Version           string                  `json:"version"`
+	CreationTimestamp string                  `json:"creationTimestamp"`
+	FileFormat        string                  `json:"fileFormat"`
+	FileSchema        string                  `json:"fileSchema"`
+	Files             []inventoryManifestFile `json:"files"`
+}
+
+// inventoryManifestFile - a report file listed in the manifest.
+type inventoryManifestFile struct {
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	MD5checksum string `json:"MD5checksum"`
+}
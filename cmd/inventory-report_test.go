@@ -0,0 +1,186 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func testInventoryRows() []inventoryRow {
+	retain := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	modified := time.Date(2024, 5, 6, 7, 8, 9, 123000000, time.UTC)
+	return []inventoryRow{
+		{
+			Bucket: "bucket", Key: "a/b.txt", VersionID: "v2", IsLatest: true, Size: 10,
+			LastModifiedDate: modified, ETag: "abc", StorageClass: "STANDARD",
+			ObjectLockRetainUntilDate: &retain, ObjectLockMode: "COMPLIANCE", ObjectLockLegalHoldStatus: "ON",
+			Tags: map[string]string{"team": "a b"},
+		},
+		{
+			Bucket: "bucket", Key: "a/b.txt", VersionID: "v1", IsDeleteMarker: true,
+			LastModifiedDate: modified,
+		},
+	}
+}
+
+func TestNewInventoryRow(t *testing.T) {
+	modified := time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("CET", 3600))
+	content := &ClientContent{
+		Size:         10,
+		Time:         modified,
+		ETag:         `"abc"`,
+		VersionID:    "v2",
+		IsLatest:     true,
+		UserMetadata: map[string]string{"x-amz-object-lock-mode": "compliance"},
+		Metadata: map[string]string{
+			AmzObjectLockLegalHold:       "ON",
+			AmzObjectLockRetainUntilDate: "2030-01-02T03:04:05Z",
+		},
+	}
+	row := newInventoryRow("bucket", "a/b.txt", content)
+	if row.ETag != "abc" || row.StorageClass != "STANDARD" || !row.IsLatest {
+		t.Fatalf("unexpected row %+v", row)
+	}
+	if !row.LastModifiedDate.Equal(modified) || row.LastModifiedDate.Location() != time.UTC {
+		t.Fatalf("expected UTC modification time, got %v", row.LastModifiedDate)
+	}
+	if row.ObjectLockMode != "COMPLIANCE" || row.ObjectLockLegalHoldStatus != "ON" {
+		t.Fatalf("unexpected object lock %q %q", row.ObjectLockMode, row.ObjectLockLegalHoldStatus)
+	}
+	if row.ObjectLockRetainUntilDate == nil || row.ObjectLockRetainUntilDate.Year() != 2030 {
+		t.Fatalf("unexpected retain until date %v", row.ObjectLockRetainUntilDate)
+	}
+
+	// Unversioned objects are always the latest version.
+	row = newInventoryRow("bucket", "key", &ClientContent{})
+	if !row.IsLatest {
+		t.Fatal("expected unversioned object to be the latest version")
+	}
+}
+
+func TestInventoryWriterCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w, e := newInventoryWriter(&buf, inventoryFormatCSV, true, false)
+	if e != nil {
+		t.Fatal(e)
+	}
+	for _, row := range testInventoryRows() {
+		if e = w.Write(row); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	records, e := csv.NewReader(&buf).ReadAll()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(records) != 3 || !reflect.DeepEqual(records[0], inventoryCSVSchema) {
+		t.Fatalf("unexpected records %q", records)
+	}
+	expected := []string{
+		"bucket", "a/b.txt", "v2", "true", "false", "10", "2024-05-06T07:08:09.123Z", "abc",
+		"STANDARD", "", "2030-01-02T03:04:05.000Z", "COMPLIANCE", "ON", "team=a+b",
+	}
+	if !reflect.DeepEqual(records[1], expected) {
+		t.Fatalf("expected %q, got %q", expected, records[1])
+	}
+	if records[2][4] != "true" || records[2][10] != "" {
+		t.Fatalf("unexpected delete marker record %q", records[2])
+	}
+}
+
+func TestInventoryWriterJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	w, e := newInventoryWriter(&buf, inventoryFormatJSONL, false, true)
+	if e != nil {
+		t.Fatal(e)
+	}
+	rows := testInventoryRows()
+	for _, row := range rows {
+		if e = w.Write(row); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	gz, e := gzip.NewReader(&buf)
+	if e != nil {
+		t.Fatal(e)
+	}
+	dec := json.NewDecoder(gz)
+	for i := range rows {
+		var row inventoryRow
+		if e = dec.Decode(&row); e != nil {
+			t.Fatal(e)
+		}
+		if !reflect.DeepEqual(row, rows[i]) {
+			t.Fatalf("expected %+v, got %+v", rows[i], row)
+		}
+	}
+	if e = dec.Decode(&inventoryRow{}); e != io.EOF {
+		t.Fatalf("expected end of report, got %v", e)
+	}
+}
+
+func TestInventoryWriterParquet(t *testing.T) {
+	var buf bytes.Buffer
+	w, e := newInventoryWriter(&buf, inventoryFormatParquet, false, true)
+	if e != nil {
+		t.Fatal(e)
+	}
+	rows := testInventoryRows()
+	for _, row := range rows {
+		if e = w.Write(row); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	read, e := parquet.Read[inventoryRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(read) != len(rows) {
+		t.Fatalf("expected %d rows, got %d", len(rows), len(read))
+	}
+	for i := range rows {
+		if read[i].Key != rows[i].Key || read[i].VersionID != rows[i].VersionID ||
+			read[i].IsDeleteMarker != rows[i].IsDeleteMarker || !read[i].LastModifiedDate.Equal(rows[i].LastModifiedDate) {
+			t.Fatalf("expected %+v, got %+v", rows[i], read[i])
+		}
+	}
+	if read[0].ObjectLockRetainUntilDate == nil || !read[0].ObjectLockRetainUntilDate.Equal(*rows[0].ObjectLockRetainUntilDate) {
+		t.Fatalf("unexpected retain until date %v", read[0].ObjectLockRetainUntilDate)
+	}
+	if read[0].Tags["team"] != "a b" {
+		t.Fatalf("unexpected tags %v", read[0].Tags)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/openstor/mc/pkg/limiter"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/env"
+	"github.com/openstor/pkg/v3/quick"
+)
+
+// limitEntry - bandwidth limit schedules of an alias or a host.
+type limitEntry struct {
+	Upload   string `json:"upload,omitempty"`
+	Download string `json:"download,omitempty"`
+}
+
+// limitsV1 - bandwidth limits of aliases and hosts, keyed by alias name
+// or by HOST[:PORT] of the alias URL.
+type limitsV1 struct {
+	Version string                `json:"version"`
+	Limits  map[string]limitEntry `json:"limits"`
+}
+
+// transferLimit - schedule of a bandwidth limit and the scope sharing its
+// budget, all transfers with the same scope and direction share a budget.
+type transferLimit struct {
+	Schedule string
+	Scope    string
+}
+
+// Get limits file path.
+func getLimitsPath() string {
+	return filepath.Join(mustGetMcConfigDir(), globalMCLimitsFile)
+}
+
+// getLimitsSocket returns the socket of `mc limit serve`.
+func getLimitsSocket() string {
+	return env.Get("MC_LIMIT_SOCKET", filepath.Join(mustGetMcConfigDir(), globalMCLimitsSocket))
+}
+
+// loadLimits loads the limits file, empty limits are returned if it
+// does not exist.
+func loadLimits() (*limitsV1, *probe.Error) {
+	limits := &limitsV1{
+		Version: globalLimitsVersion,
+		Limits:  make(map[string]limitEntry),
+	}
+	limitsFile := getLimitsPath()
+	if _, e := os.Stat(limitsFile); os.IsNotExist(e) {
+		return limits, nil
+	}
+
+	qs, e := quick.NewConfig(limits, nil)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(limitsFile)
+	}
+	if e = qs.Load(limitsFile); e != nil {
+		return nil, probe.NewError(e).Trace(limitsFile)
+	}
+	if limits.Version != globalLimitsVersion {
+		return nil, errDummy().Trace(limitsFile, limits.Version)
+	}
+	if limits.Limits == nil {
+		limits.Limits = make(map[string]limitEntry)
+	}
+	return limits, nil
+}
+
+// save writes the limits file.
+func (l *limitsV1) save() *probe.Error {
+	if e := os.MkdirAll(mustGetMcConfigDir(), 0o700); e != nil {
+		return probe.NewError(e)
+	}
+	qs, e := quick.NewConfig(l, nil)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = qs.Save(getLimitsPath()); e != nil {
+		return probe.NewError(e).Trace(getLimitsPath())
+	}
+	return nil
+}
+
+// cachedLimits is loaded once per process, a broken limits file is
+// reported once and ignored.
+var cachedLimits = sync.OnceValue(func() *limitsV1 {
+	limits, err := loadLimits()
+	if err != nil {
+		errorIf(err, "Unable to load bandwidth limits, transfers are not limited.")
+		return &limitsV1{Limits: make(map[string]limitEntry)}
+	}
+	return limits
+})
+
+// transferLimits returns the upload and download limits of an alias
+// pointing to urlStr.
+func transferLimits(alias, urlStr string, aliasCfg *aliasConfigV11) (upload, download transferLimit) {
+	var profile profileConfigV11
+	if aliasCfg != nil {
+		profile, _ = getProfileConfig(aliasCfg.Profile)
+	}
+	return resolveTransferLimits(cachedLimits(), alias, newClientURL(urlStr).Host, profile)
+}
+
+// resolveTransferLimits picks the limits of each direction, command line
+// flags come first, then the limits of the alias, the limits of the host
+// and last the profile of the alias.
+func resolveTransferLimits(limits *limitsV1, alias, host string, profile profileConfigV11) (upload, download transferLimit) {
+	aliasScope := "alias:" + alias
+	if alias == "" {
+		aliasScope = "host:" + host
+	}
+	resolve := func(flag string, get func(limitEntry) string, fromProfile string) transferLimit {
+		if flag != "" {
+			return transferLimit{Schedule: flag, Scope: aliasScope}
+		}
+		if alias != "" {
+			if schedule := get(limits.Limits[alias]); schedule != "" {
+				return transferLimit{Schedule: schedule, Scope: aliasScope}
+			}
+		}
+		if schedule := get(limits.Limits[host]); schedule != "" {
+			return transferLimit{Schedule: schedule, Scope: "host:" + host}
+		}
+		if fromProfile != "" {
+			return transferLimit{Schedule: fromProfile, Scope: aliasScope}
+		}
+		return transferLimit{}
+	}
+	upload = resolve(globalLimitUpload, func(l limitEntry) string { return l.Upload }, profile.LimitUpload)
+	download = resolve(globalLimitDownload, func(l limitEntry) string { return l.Download }, profile.LimitDownload)
+	return upload, download
+}
+
+// transferLimiters - budgets of the process, shared by all transports.
+var transferLimiters = struct {
+	sync.Mutex
+	limits map[string]limiter.Limit
+	shared *limiter.SharedClient
+	dialed bool
+}{limits: make(map[string]limiter.Limit)}
+
+// getTransferLimiter returns the budget of a limit in the direction,
+// nil if transfers are not limited. Budgets are taken from `mc limit serve`
+// when it is running, so that concurrent mc processes share them.
+func getTransferLimiter(limit transferLimit, direction string) limiter.Limit {
+	if limit.Schedule == "" {
+		return nil
+	}
+	schedule, e := limiter.ParseSchedule(limit.Schedule)
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(limit.Schedule), "Unable to parse the bandwidth limit of `%s`, transfers are not limited.", limit.Scope)
+		return nil
+	}
+
+	transferLimiters.Lock()
+	defer transferLimiters.Unlock()
+
+	key := limit.Scope + "/" + direction
+	if l, ok := transferLimiters.limits[key]; ok {
+		return l
+	}
+	if !transferLimiters.dialed {
+		transferLimiters.dialed = true
+		transferLimiters.shared, _ = limiter.DialShared(getLimitsSocket(), 100*time.Millisecond)
+	}
+	var l limiter.Limit = limiter.NewBucket(schedule)
+	if transferLimiters.shared != nil {
+		l = transferLimiters.shared.Limit(key, schedule, l)
+	}
+	transferLimiters.limits[key] = l
+	return l
+}
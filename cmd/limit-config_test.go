@@ -0,0 +1,74 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import "testing"
+
+func TestResolveTransferLimits(t *testing.T) {
+	limits := &limitsV1{Limits: map[string]limitEntry{
+		"myminio":         {Upload: "10MiB/s 08:00-18:00, unlimited"},
+		"play.min.io":     {Upload: "1MiB/s", Download: "2MiB/s"},
+		"localhost:9000":  {Download: "5MiB/s"},
+		"unrelated-alias": {Upload: "1KiB/s"},
+	}}
+	profile := profileConfigV11{LimitUpload: "3MiB/s", LimitDownload: "4MiB/s"}
+
+	testCases := []struct {
+		alias, host, flag string
+		profile           profileConfigV11
+		upload, download  transferLimit
+	}{
+		// Alias limits come before host limits, per direction.
+		{
+			alias: "myminio", host: "play.min.io",
+			upload:   transferLimit{Schedule: "10MiB/s 08:00-18:00, unlimited", Scope: "alias:myminio"},
+			download: transferLimit{Schedule: "2MiB/s", Scope: "host:play.min.io"},
+		},
+		// Host limits come before the profile.
+		{
+			alias: "local", host: "localhost:9000", profile: profile,
+			upload:   transferLimit{Schedule: "3MiB/s", Scope: "alias:local"},
+			download: transferLimit{Schedule: "5MiB/s", Scope: "host:localhost:9000"},
+		},
+		// Flags come first.
+		{
+			alias: "myminio", host: "play.min.io", flag: "7MiB/s", profile: profile,
+			upload:   transferLimit{Schedule: "7MiB/s", Scope: "alias:myminio"},
+			download: transferLimit{Schedule: "7MiB/s", Scope: "alias:myminio"},
+		},
+		// URLs without an alias are scoped by host.
+		{
+			host:     "play.min.io",
+			upload:   transferLimit{Schedule: "1MiB/s", Scope: "host:play.min.io"},
+			download: transferLimit{Schedule: "2MiB/s", Scope: "host:play.min.io"},
+		},
+		{alias: "other", host: "example.com"},
+	}
+
+	defer func() { globalLimitUpload, globalLimitDownload = "", "" }()
+	for i, testCase := range testCases {
+		globalLimitUpload, globalLimitDownload = testCase.flag, testCase.flag
+		upload, download := resolveTransferLimits(limits, testCase.alias, testCase.host, testCase.profile)
+		if upload != testCase.upload {
+			t.Errorf("Test %d: expected upload %+v, got %+v", i+1, testCase.upload, upload)
+		}
+		if download != testCase.download {
+			t.Errorf("Test %d: expected download %+v, got %+v", i+1, testCase.download, download)
+		}
+	}
+}
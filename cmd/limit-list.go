@@ -0,0 +1,89 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var limitListCmd = cli.Command{
+	Name:         "list",
+	Aliases:      []string{"ls"},
+	Usage:        "list bandwidth limits of aliases and hosts",
+	Action:       mainLimitList,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        globalFlags,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [TARGET]
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. List all bandwidth limits.
+     {{.Prompt}} {{.HelpName}}
+
+  2. Show the bandwidth limits of "myminio".
+     {{.Prompt}} {{.HelpName}} myminio
+`,
+}
+
+// checkLimitListSyntax - validate all the passed arguments
+func checkLimitListSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() > 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+}
+
+// mainLimitList is the handle for "mc limit list" command.
+func mainLimitList(ctx context.Context, cmd *cli.Command) error {
+	checkLimitListSyntax(ctx, cmd)
+	console.SetColor("LimitTarget", color.New(color.FgCyan, color.Bold))
+
+	limits, err := loadLimits()
+	fatalIf(err, "Unable to load bandwidth limits.")
+
+	if target := cmd.Args().First(); target != "" {
+		entry, ok := limits.Limits[target]
+		if !ok {
+			fatalIf(errDummy().Trace(target), "No bandwidth limits set for `%s`.", target)
+		}
+		printMsg(limitMessage{Target: target, Upload: entry.Upload, Download: entry.Download})
+		return nil
+	}
+
+	targets := make([]string, 0, len(limits.Limits))
+	for target := range limits.Limits {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		entry := limits.Limits[target]
+		printMsg(limitMessage{Target: target, Upload: entry.Upload, Download: entry.Download})
+	}
+	return nil
+}
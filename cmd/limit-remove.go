@@ -0,0 +1,74 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/fatih/color"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var limitRemoveCmd = cli.Command{
+	Name:         "remove",
+	Aliases:      []string{"rm"},
+	Usage:        "remove bandwidth limits of an alias or a host",
+	Action:       mainLimitRemove,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        globalFlags,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Remove the bandwidth limits of "myminio".
+     {{.Prompt}} {{.HelpName}} myminio
+`,
+}
+
+// checkLimitRemoveSyntax - validate all the passed arguments
+func checkLimitRemoveSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+}
+
+// mainLimitRemove is the handle for "mc limit remove" command.
+func mainLimitRemove(ctx context.Context, cmd *cli.Command) error {
+	checkLimitRemoveSyntax(ctx, cmd)
+	console.SetColor("LimitMessage", color.New(color.FgGreen))
+
+	target := cmd.Args().First()
+	limits, err := loadLimits()
+	fatalIf(err, "Unable to load bandwidth limits.")
+	if _, ok := limits.Limits[target]; !ok {
+		fatalIf(errDummy().Trace(target), "No bandwidth limits set for `%s`.", target)
+	}
+	delete(limits.Limits, target)
+	fatalIf(limits.save(), "Unable to save bandwidth limits.")
+
+	printMsg(limitMessage{Op: "remove", Target: target})
+	return nil
+}
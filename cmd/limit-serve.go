@@ -0,0 +1,115 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/openstor/mc/pkg/limiter"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var limitServeCmd = cli.Command{
+	Name:         "serve",
+	Usage:        "share bandwidth limits between mc processes on this machine",
+	Action:       mainLimitServe,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        globalFlags,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}}
+
+  While running, all mc processes of the user draw from one budget per
+  alias or host instead of each applying the limits on its own. Processes
+  fall back to their own budget while the server is stopped, and draw from
+  the shared one again once it is restarted.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+ENVIRONMENT VARIABLES:
+  MC_LIMIT_SOCKET: path of the unix socket, defaults to "limits.sock" in the mc config folder.
+
+EXAMPLES:
+  1. Share bandwidth limits between all mc processes.
+     {{.Prompt}} {{.HelpName}}
+`,
+}
+
+// limitServeMessage container for the limit server status.
+type limitServeMessage struct {
+	Status string `json:"status"`
+	Socket string `json:"socket"`
+}
+
+func (m limitServeMessage) String() string {
+	return console.Colorize("LimitMessage", fmt.Sprintf("Sharing bandwidth limits on `%s`.", m.Socket))
+}
+
+func (m limitServeMessage) JSON() string {
+	m.Status = "success"
+	return toJSON(m)
+}
+
+// checkLimitServeSyntax - validate all the passed arguments
+func checkLimitServeSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 0 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+}
+
+// mainLimitServe is the handle for "mc limit serve" command.
+func mainLimitServe(ctx context.Context, cmd *cli.Command) error {
+	checkLimitServeSyntax(ctx, cmd)
+	console.SetColor("LimitMessage", color.New(color.FgGreen))
+
+	socket := getLimitsSocket()
+	// A socket left behind by a killed server is removed, a running
+	// server is never replaced.
+	if conn, e := net.Dial("unix", socket); e == nil {
+		conn.Close()
+		fatalIf(errDummy().Trace(socket), "Bandwidth limits are already shared on `%s`.", socket)
+	}
+	if e := os.Remove(socket); e != nil && !os.IsNotExist(e) {
+		fatalIf(probe.NewError(e).Trace(socket), "Unable to remove stale socket.")
+	}
+
+	l, e := net.Listen("unix", socket)
+	fatalIf(probe.NewError(e).Trace(socket), "Unable to listen on socket.")
+	go func() {
+		<-globalContext.Done()
+		l.Close()
+	}()
+	defer os.Remove(socket)
+
+	printMsg(limitServeMessage{Socket: socket})
+	if e = limiter.NewServer().Serve(l); e != nil && !errors.Is(e, net.ErrClosed) {
+		fatalIf(probe.NewError(e).Trace(socket), "Unable to share bandwidth limits.")
+	}
+	return nil
+}
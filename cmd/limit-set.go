@@ -0,0 +1,159 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+	jsoncolor "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/limiter"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var limitSetFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "upload",
+		Usage: "upload bandwidth schedule, e.g. \"50MiB/s 08:00-18:00 weekdays, unlimited\"",
+	},
+	&cli.StringFlag{
+		Name:  "download",
+		Usage: "download bandwidth schedule, e.g. \"100MiB/s\"",
+	},
+}
+
+var limitSetCmd = cli.Command{
+	Name:         "set",
+	Usage:        "set bandwidth limits of an alias or a host",
+	Action:       mainLimitSet,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(limitSetFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} TARGET [--upload SCHEDULE] [--download SCHEDULE]
+
+  TARGET is an alias name or the HOST[:PORT] of a server. Limits of an alias
+  take precedence over limits of its host, --limit-upload and --limit-download
+  take precedence over both.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+SCHEDULE:
+  A schedule is a comma separated list of rules "RATE [HH:MM-HH:MM] [DAYS]",
+  the first rule matching the current local time applies. RATE is a size per
+  second such as "50MiB/s" or "unlimited", DAYS is a list of days or ranges
+  like "mon-fri", "sat sun", "weekdays", "weekends" or "daily". Time windows
+  may wrap around midnight. Transfers are not limited when no rule matches.
+
+  Rates are adjusted live, a long running "mc mirror --watch" follows the
+  schedule without restarting. Run "mc limit serve" to share the limits
+  between all mc processes on this machine.
+
+EXAMPLES:
+  1. Limit uploads to "myminio" to 50MiB/s during business hours.
+     {{.Prompt}} {{.HelpName}} myminio --upload "50MiB/s 08:00-18:00 weekdays, unlimited"
+
+  2. Limit downloads from a host to 10MiB/s at night, 1MiB/s otherwise.
+     {{.Prompt}} {{.HelpName}} play.min.io --download "10MiB/s 22:00-06:00, 1MiB/s"
+
+  3. Limit uploads and downloads of "myminio" to a fixed rate.
+     {{.Prompt}} {{.HelpName}} myminio --upload 20MiB/s --download 40MiB/s
+`,
+}
+
+// limitMessage container for the limits of a target.
+type limitMessage struct {
+	Status   string `json:"status"`
+	Op       string `json:"op,omitempty"`
+	Target   string `json:"target"`
+	Upload   string `json:"upload,omitempty"`
+	Download string `json:"download,omitempty"`
+}
+
+func (m limitMessage) String() string {
+	switch m.Op {
+	case "set":
+		return console.Colorize("LimitMessage", fmt.Sprintf("Bandwidth limits of `%s` set successfully.", m.Target))
+	case "remove":
+		return console.Colorize("LimitMessage", fmt.Sprintf("Bandwidth limits of `%s` removed successfully.", m.Target))
+	}
+	limits := func(schedule string) string {
+		if schedule == "" {
+			return "unlimited"
+		}
+		return schedule
+	}
+	return fmt.Sprintf("%s\n  %s: %s\n  %s: %s",
+		console.Colorize("LimitTarget", m.Target),
+		"Upload  ", limits(m.Upload),
+		"Download", limits(m.Download))
+}
+
+func (m limitMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := jsoncolor.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// checkLimitSetSyntax - validate all the passed arguments
+func checkLimitSetSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 1 || (!cmd.IsSet("upload") && !cmd.IsSet("download")) {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+	for _, name := range []string{"upload", "download"} {
+		if schedule := cmd.String(name); schedule != "" {
+			_, e := limiter.ParseSchedule(schedule)
+			fatalIf(probe.NewError(e).Trace(schedule), "Invalid --%s schedule.", name)
+		}
+	}
+}
+
+// mainLimitSet is the handle for "mc limit set" command.
+func mainLimitSet(ctx context.Context, cmd *cli.Command) error {
+	checkLimitSetSyntax(ctx, cmd)
+	console.SetColor("LimitMessage", color.New(color.FgGreen))
+
+	target := cmd.Args().First()
+	limits, err := loadLimits()
+	fatalIf(err, "Unable to load bandwidth limits.")
+
+	entry := limits.Limits[target]
+	if cmd.IsSet("upload") {
+		entry.Upload = cmd.String("upload")
+	}
+	if cmd.IsSet("download") {
+		entry.Download = cmd.String("download")
+	}
+	if entry == (limitEntry{}) {
+		delete(limits.Limits, target)
+	} else {
+		limits.Limits[target] = entry
+	}
+	fatalIf(limits.save(), "Unable to save bandwidth limits.")
+
+	printMsg(limitMessage{Op: "set", Target: target, Upload: entry.Upload, Download: entry.Download})
+	return nil
+}
@@ -60,6 +60,14 @@ var (
 			Name:  "zip",
 			Usage: "list files inside zip archive (MinIO servers only)",
 		},
+		&cli.BoolFlag{
+			Name:  "index",
+			Usage: "list from the local index of the bucket, see 'mc index build'",
+		},
+		&cli.BoolFlag{
+			Name:  "archive",
+			Usage: "list files inside zip, tar, tar.gz or tar.zst archives, read on the client",
+		},
 	}
 )
 
@@ -112,6 +120,12 @@ EXAMPLES:
   
   10. List all objects on mybucket, for the GLACIER storage class
      {{.Prompt}} {{.HelpName}} --storage-class 'GLACIER' s3/mybucket 
+
+  11. List all objects on mybucket from its local index built with 'mc index build'.
+     {{.Prompt}} {{.HelpName}} --recursive --index s3/mybucket
+
+  12. List the files of a folder inside a tar.gz archive, on any S3 endpoint or local filesystem.
+     {{.Prompt}} {{.HelpName}} --archive s3/mybucket/logs.tar.gz/2022/
 `,
 }
 
@@ -174,12 +188,24 @@ func checkListSyntax(ctx context.Context, cmd *cli.Command) ([]string, doListOpt
 	withVersions := cmd.Bool("versions")
 	isSummary := cmd.Bool("summarize")
 	listZip := cmd.Bool("zip")
+	listArchive := cmd.Bool("archive")
 
 	timeRef := parseRewindFlag(cmd.String("rewind"))
 
 	if listZip && (withVersions || !timeRef.IsZero()) {
 		fatalIf(errInvalidArgument().Trace(args...), "Zip file listing can only be performed on the latest version")
 	}
+	if cmd.Bool("index") && (isIncomplete || listZip) {
+		fatalIf(errInvalidArgument().Trace(args...), "Incomplete uploads and zip files are not part of indexes")
+	}
+	if listArchive && (listZip || isIncomplete || withVersions || !timeRef.IsZero() || cmd.Bool("index")) {
+		fatalIf(errInvalidArgument().Trace(args...), "--archive cannot be used with --zip, --incomplete, --versions, --rewind or --index")
+	}
+	for _, arg := range args {
+		if _, _, _, ok := splitArchiveURL(arg); listArchive && !ok {
+			fatalIf(errInvalidArgument().Trace(arg), "`"+arg+"` is not an archive, only .zip, .tar, .tar.gz, .tgz, .tar.zst and .tzst are supported.")
+		}
+	}
 	storageClasss := cmd.String("storage-class")
 	opts := doListOptions{
 		timeRef:      timeRef,
@@ -188,6 +214,7 @@ func checkListSyntax(ctx context.Context, cmd *cli.Command) ([]string, doListOpt
 		isSummary:    isSummary,
 		withVersions: withVersions,
 		listZip:      listZip,
+		listArchive:  listArchive,
 		filter:       storageClasss,
 	}
 	return args, opts
@@ -213,19 +240,35 @@ func mainList(ctx context.Context, cmd *cli.Command) error {
 	// check 'ls' cliCtx arguments.
 	args, opts := checkListSyntax(ctx, cmd)
 
+	newTargetClient := newClient
+	if opts.listArchive {
+		newTargetClient = func(urlStr string) (Client, *probe.Error) {
+			clnt, err := newArchiveClient(urlStr, nil)
+			if err != nil {
+				return nil, err
+			}
+			return clnt, nil
+		}
+	}
+
 	var cErr error
 	for _, targetURL := range args {
-		clnt, err := newClient(targetURL)
+		clnt, err := newTargetClient(targetURL)
 		fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
 		if !strings.HasSuffix(targetURL, string(clnt.GetURL().Separator)) {
 			var st *ClientContent
 			st, err = clnt.Stat(ctx, StatOptions{incomplete: opts.isIncomplete, includeVersions: opts.withVersions})
 			if st != nil && err == nil && st.Type.IsDir() {
 				targetURL = targetURL + string(clnt.GetURL().Separator)
-				clnt, err = newClient(targetURL)
+				clnt, err = newTargetClient(targetURL)
 				fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
 			}
 		}
+		if cmd.Bool("index") {
+			alias, _ := url2Alias(targetURL)
+			clnt, err = newIndexClient(alias, clnt)
+			fatalIf(err.Trace(targetURL), "Unable to use the index of `"+targetURL+"`.")
+		}
 		if e := doList(ctx, clnt, opts); e != nil {
 			cErr = e
 		}
@@ -213,6 +213,7 @@ type doListOptions struct {
 	isSummary    bool
 	withVersions bool
 	listZip      bool
+	listArchive  bool
 	filter       string
 }
 
@@ -428,7 +428,9 @@ var appCmds = []*cli.Command{
 	&aliasCmd,
 	&adminCmd,
 	&anonymousCmd,
+	&backupCmd,
 	&batchCmd,
+	&bucketCmd,
 	&cpCmd,
 	&catCmd,
 	&corsCmd,
@@ -441,12 +443,16 @@ var appCmds = []*cli.Command{
 	&headCmd,
 	&ilmCmd,
 	&idpCmd,
+	&indexCmd,
+	&inventoryCmd,
 	&licenseCmd,
 	&legalHoldCmd,
+	&limitCmd,
 	&lsCmd,
 	&mbCmd,
 	&mvCmd,
 	&mirrorCmd,
+	&mountCmd,
 	&odCmd,
 	&pingCmd,
 	&policyCmd,
@@ -458,10 +464,14 @@ var appCmds = []*cli.Command{
 	&rbCmd,
 	&replicateCmd,
 	&readyCmd,
+	&serveCmd,
+	&sessionCmd,
 	&sqlCmd,
 	&statCmd,
 	&supportCmd,
+	&syncCmd,
 	&shareCmd,
+	&traceFileCmd,
 	&treeCmd,
 	&tagCmd,
 	&undoCmd,
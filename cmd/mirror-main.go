@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"os"
 	"path"
 	"path/filepath"
 	"runtime"
@@ -128,6 +129,20 @@ var (
 			Name:  "attr",
 			Usage: "add custom metadata for all objects",
 		},
+		&cli.StringFlag{
+			Name:  "fs-watch-mode",
+			Usage: "watch local folders with \"notify\", \"poll\" or \"auto\" to poll only the folders which cannot be notified",
+			Value: fsWatchModeAuto,
+		},
+		&cli.DurationFlag{
+			Name:  "fs-poll-interval",
+			Usage: "interval between scans of polled local folders",
+			Value: defaultFSPollInterval,
+		},
+		&cli.StringFlag{
+			Name:  "spool",
+			Usage: "persist watched events in a folder before mirroring them, unmirrored events are replayed on restart",
+		},
 		&cli.StringFlag{
 			Name:  "monitoring-address",
 			Usage: "if specified, a new prometheus endpoint will be created to report mirroring activity. (eg: localhost:8081)",
@@ -148,6 +163,14 @@ var (
 			Name:  "max-workers",
 			Usage: "maximum number of concurrent copies (default: autodetect)",
 		},
+		&cli.StringFlag{
+			Name:  "resume",
+			Usage: "resume an interrupted mirror session, see 'mc session list'",
+		},
+		&cli.BoolFlag{
+			Name:  "compare-checksum",
+			Usage: "compare object(s) of same size by checksum, use with '--overwrite' to replace object(s) differing in content",
+		},
 		checksumFlag,
 	}
 )
@@ -159,7 +182,7 @@ var mirrorCmd = cli.Command{
 	Action:       mainMirror,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(append(mirrorFlags, encFlags...), globalFlags...),
+	Flags:        append(append(append(mirrorFlags, encFlags...), encClientFlags...), globalFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -173,6 +196,8 @@ FLAGS:
 ENVIRONMENT VARIABLES:
   MC_ENC_KMS: KMS encryption key in the form of (alias/prefix=key).
   MC_ENC_S3: S3 encryption key in the form of (alias/prefix=key).
+  MC_ENC_CLIENT_KEY: path of the key file used to encrypt objects on the client.
+  MC_ENC_CLIENT_PASSPHRASE: passphrase used to encrypt objects on the client.
 
 EXAMPLES:
   01. Mirror a bucket recursively from MinIO cloud storage to a bucket on Amazon S3 cloud storage.
@@ -227,6 +252,21 @@ EXAMPLES:
   16. Cross mirror between sites in a active-active deployment.
       Site-A: {{.Prompt}} {{.HelpName}} --active-active siteA siteB
       Site-B: {{.Prompt}} {{.HelpName}} --active-active siteB siteA
+
+  17. Resume an interrupted mirror, objects completed before the interruption are not compared again.
+      {{.Prompt}} {{.HelpName}} --resume gxk3mqp9tzr4vwb2
+
+  18. Mirror a local folder to MinIO cloud storage, replacing objects whose content differs even if their size is the same.
+      {{.Prompt}} {{.HelpName}} --compare-checksum --overwrite backup/ play/archive
+
+  19. Mirror a local folder to Amazon S3 cloud storage, encrypting the objects on the client with a passphrase.
+      {{.Prompt}} {{.HelpName}} --enc-client-passphrase backup/ s3/archive
+
+  20. Continuously mirror a bucket, events not mirrored yet survive a restart of mc.
+      {{.Prompt}} {{.HelpName}} --watch --spool ~/.mc/spool/photos play/photos s3/backup-photos
+
+  21. Continuously mirror a folder on a NFS mount, scanning it for changes every minute.
+      {{.Prompt}} {{.HelpName}} --watch --fs-watch-mode poll --fs-poll-interval 1m /mnt/nfs/photos play/photos
 `,
 }
 
@@ -247,6 +287,14 @@ var (
 		Name: "mc_mirror_total_restarts",
 		Help: "The number of mirror restarts",
 	})
+	mirrorSpoolPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mc_mirror_spool_pending_events",
+		Help: "The number of watched events in the spool not mirrored yet",
+	})
+	mirrorSpoolReplayed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mc_mirror_spool_replayed_events",
+		Help: "The total number of spooled events replayed after a restart",
+	})
 	mirrorReplicationDurations = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "mc_mirror_replication_duration",
@@ -528,7 +576,7 @@ func (mj *mirrorJob) doMirror(ctx context.Context, sURLs URLs, event EventInfo)
 
 	if !mj.opts.isRetriable {
 		now := time.Now()
-		ret = uploadSourceToTargetURL(ctx, uploadSourceToTargetURLOpts{urls: sURLs, progress: mj.status, encKeyDB: mj.opts.encKeyDB, preserve: mj.opts.isMetadata, isZip: false})
+		ret = uploadSourceToTargetURL(ctx, uploadSourceToTargetURLOpts{urls: sURLs, progress: mj.status, encKeyDB: mj.opts.encKeyDB, preserve: mj.opts.isMetadata, isZip: false, session: mj.opts.session, cse: mj.opts.cse})
 		if ret.Error == nil {
 			durationMs := time.Since(now).Milliseconds()
 			mirrorReplicationDurations.With(prometheus.Labels{"object_size": convertSizeToTag(sURLs.SourceContent.Size)}).Observe(float64(durationMs))
@@ -547,7 +595,7 @@ func (mj *mirrorJob) doMirror(ctx context.Context, sURLs URLs, event EventInfo)
 		}
 
 		now := time.Now()
-		ret = uploadSourceToTargetURL(ctx, uploadSourceToTargetURLOpts{urls: sURLs, progress: mj.status, encKeyDB: mj.opts.encKeyDB, preserve: mj.opts.isMetadata, isZip: false})
+		ret = uploadSourceToTargetURL(ctx, uploadSourceToTargetURLOpts{urls: sURLs, progress: mj.status, encKeyDB: mj.opts.encKeyDB, preserve: mj.opts.isMetadata, isZip: false, session: mj.opts.session, cse: mj.opts.cse})
 		if ret.Error == nil {
 			durationMs := time.Since(now).Milliseconds()
 			mirrorReplicationDurations.With(prometheus.Labels{"object_size": convertSizeToTag(sURLs.SourceContent.Size)}).Observe(float64(durationMs))
@@ -585,6 +633,10 @@ func (mj *mirrorJob) monitorMirrorStatus(cancel context.CancelFunc) (errDuringMi
 		// Update prometheus fields
 		mirrorTotalOps.Inc()
 
+		if mj.opts.session != nil && sURLs.SourceContent != nil {
+			mj.opts.session.Finished(sURLs)
+		}
+
 		if sURLs.Error != nil {
 			var ignoreErr bool
 
@@ -619,7 +671,9 @@ func (mj *mirrorJob) monitorMirrorStatus(cancel context.CancelFunc) (errDuringMi
 				}
 			}
 
-			if !ignoreErr {
+			if ignoreErr {
+				mj.opts.spool.Ack(sURLs.spoolSeq)
+			} else {
 				mirrorFailedOps.Inc()
 				errDuringMirror = true
 				// Quit mirroring if --skip-errors is not passed
@@ -635,154 +689,202 @@ func (mj *mirrorJob) monitorMirrorStatus(cancel context.CancelFunc) (errDuringMi
 		if sURLs.SourceContent != nil {
 			mirrorTotalUploadedBytes.Add(float64(sURLs.SourceContent.Size))
 		}
+		mj.opts.spool.Ack(sURLs.spoolSeq)
 	}
 
 	return
 }
 
+// watchMirrorEvents mirrors watched events, they are persisted in the
+// spool first if any.
 func (mj *mirrorJob) watchMirrorEvents(ctx context.Context, events []EventInfo) {
-	for _, event := range events {
-		// It will change the expanded alias back to the alias
-		// again, by replacing the sourceUrlFull with the sourceAlias.
-		// This url will be used to mirror.
-		sourceAlias, sourceURLFull, _ := mustExpandAlias(mj.sourceURL)
-
-		// If the passed source URL points to fs, fetch the absolute src path
-		// to correctly calculate targetPath
-		if sourceAlias == "" {
-			tmpSrcURL, e := filepath.Abs(sourceURLFull)
-			if e == nil {
-				sourceURLFull = tmpSrcURL
-			}
+	spooled := make([]spooledEvent, 0, len(events))
+	if mj.opts.spool != nil {
+		var err *probe.Error
+		spooled, err = mj.opts.spool.Append(events)
+		if err != nil {
+			errorIf(err, "Unable to spool events, mirroring them without spooling.")
+			spooled = spooled[:0]
 		}
-		eventPath := event.Path
-		switch runtime.GOOS {
-		case "darwin":
-			// Strip the prefixes in the event path. Happens in darwin OS only
-			eventPath = eventPath[strings.Index(eventPath, sourceURLFull):]
-		case "windows":
-			// Shared folder as source URL and if event path is an absolute path.
-			eventPath = getEventPathURLWin(mj.sourceURL, eventPath)
+	}
+	if len(spooled) == 0 {
+		for _, event := range events {
+			spooled = append(spooled, spooledEvent{event: event})
 		}
+	}
+	mj.applyMirrorEvents(ctx, spooled)
+}
 
-		sourceURL := newClientURL(eventPath)
-
-		// build target path, it is the relative of the eventPath with the sourceUrl
-		// joined to the targetURL.
-		sourceSuffix := strings.TrimPrefix(eventPath, sourceURLFull)
-		// Skip the object, if it matches the Exclude options provided
-		if matchExcludeOptions(mj.opts.excludeOptions, sourceSuffix, sourceURL.Type) {
-			continue
+// applyMirrorEvents queues the tasks mirroring events, events which
+// need no mirroring are acknowledged right away.
+func (mj *mirrorJob) applyMirrorEvents(ctx context.Context, events []spooledEvent) {
+	for _, ev := range events {
+		if !mj.applyMirrorEvent(ctx, ev.seq, ev.event) {
+			mj.opts.spool.Ack(ev.seq)
 		}
-		// Skip the bucket, if it matches the Exclude options provided
-		if matchExcludeBucketOptions(mj.opts.excludeBuckets, sourceSuffix) {
-			continue
+	}
+}
+
+// applyMirrorEvent queues the task mirroring an event, returns false if
+// the event needs no mirroring.
+func (mj *mirrorJob) applyMirrorEvent(ctx context.Context, seq uint64, event EventInfo) bool {
+	// withSeq tags the result of a task with the spooled event.
+	withSeq := func(sURLs URLs) URLs {
+		sURLs.spoolSeq = seq
+		return sURLs
+	}
+
+	// It will change the expanded alias back to the alias
+	// again, by replacing the sourceUrlFull with the sourceAlias.
+	// This url will be used to mirror.
+	sourceAlias, sourceURLFull, _ := mustExpandAlias(mj.sourceURL)
+
+	// If the passed source URL points to fs, fetch the absolute src path
+	// to correctly calculate targetPath
+	if sourceAlias == "" {
+		tmpSrcURL, e := filepath.Abs(sourceURLFull)
+		if e == nil {
+			sourceURLFull = tmpSrcURL
 		}
+	}
+	eventPath := event.Path
+	switch runtime.GOOS {
+	case "darwin":
+		// Strip the prefixes in the event path. Happens in darwin OS only
+		eventPath = eventPath[strings.Index(eventPath, sourceURLFull):]
+	case "windows":
+		// Shared folder as source URL and if event path is an absolute path.
+		eventPath = getEventPathURLWin(mj.sourceURL, eventPath)
+	}
 
-		sc, ok := event.UserMetadata["x-amz-storage-class"]
-		if ok {
-			var found bool
-			for _, esc := range mj.opts.excludeStorageClasses {
-				if esc == sc {
-					found = true
-					break
-				}
-			}
-			if found {
-				continue
+	sourceURL := newClientURL(eventPath)
+
+	// build target path, it is the relative of the eventPath with the sourceUrl
+	// joined to the targetURL.
+	sourceSuffix := strings.TrimPrefix(eventPath, sourceURLFull)
+	// Skip the object, if it matches the Exclude options provided
+	if matchExcludeOptions(mj.opts.excludeOptions, sourceSuffix, sourceURL.Type) {
+		return false
+	}
+	// Skip the bucket, if it matches the Exclude options provided
+	if matchExcludeBucketOptions(mj.opts.excludeBuckets, sourceSuffix) {
+		return false
+	}
+
+	sc, ok := event.UserMetadata["x-amz-storage-class"]
+	if ok {
+		var found bool
+		for _, esc := range mj.opts.excludeStorageClasses {
+			if esc == sc {
+				found = true
+				break
 			}
 		}
+		if found {
+			return false
+		}
+	}
 
-		targetPath := urlJoinPath(mj.targetURL, sourceSuffix)
-
-		// newClient needs the unexpanded  path, newCLientURL needs the expanded path
-		targetAlias, expandedTargetPath, _ := mustExpandAlias(targetPath)
-		targetURL := newClientURL(expandedTargetPath)
-		tgtSSE := getSSE(targetPath, mj.opts.encKeyDB[targetAlias])
-
-		if strings.HasPrefix(string(event.Type), "s3:ObjectCreated:") {
-			sourceModTime, _ := time.Parse(time.RFC3339Nano, event.Time)
-			mirrorURL := URLs{
-				SourceAlias: sourceAlias,
-				SourceContent: &ClientContent{
-					URL:              *sourceURL,
-					RetentionEnabled: event.Type == notification.EventType("s3:ObjectCreated:PutRetention"),
-					LegalHoldEnabled: event.Type == notification.EventType("s3:ObjectCreated:PutLegalHold"),
-					Size:             event.Size,
-					Time:             sourceModTime,
-					Metadata:         event.UserMetadata,
-				},
-				TargetAlias:      targetAlias,
-				TargetContent:    &ClientContent{URL: *targetURL},
-				MD5:              mj.opts.md5,
-				checksum:         mj.opts.checksum,
-				DisableMultipart: mj.opts.disableMultipart,
-				encKeyDB:         mj.opts.encKeyDB,
-			}
-			if mj.opts.activeActive &&
-				event.Type != notification.ObjectCreatedCopy &&
-				event.Type != notification.ObjectCreatedCompleteMultipartUpload &&
-				(getSourceModTimeKey(mirrorURL.SourceContent.Metadata) != "" ||
-					getSourceModTimeKey(mirrorURL.SourceContent.UserMetadata) != "") {
-				// If source has active-active attributes, it means that the
-				// object was uploaded by "mc mirror", hence ignore the event
-				// to avoid copying it.
-				continue
-			}
+	targetPath := urlJoinPath(mj.targetURL, sourceSuffix)
+
+	// newClient needs the unexpanded  path, newCLientURL needs the expanded path
+	targetAlias, expandedTargetPath, _ := mustExpandAlias(targetPath)
+	targetURL := newClientURL(expandedTargetPath)
+	tgtSSE := getSSE(targetPath, mj.opts.encKeyDB[targetAlias])
+
+	if strings.HasPrefix(string(event.Type), "s3:ObjectCreated:") {
+		sourceModTime, _ := time.Parse(time.RFC3339Nano, event.Time)
+		mirrorURL := URLs{
+			SourceAlias: sourceAlias,
+			SourceContent: &ClientContent{
+				URL:              *sourceURL,
+				RetentionEnabled: event.Type == notification.EventType("s3:ObjectCreated:PutRetention"),
+				LegalHoldEnabled: event.Type == notification.EventType("s3:ObjectCreated:PutLegalHold"),
+				Size:             event.Size,
+				Time:             sourceModTime,
+				Metadata:         event.UserMetadata,
+			},
+			TargetAlias:      targetAlias,
+			TargetContent:    &ClientContent{URL: *targetURL},
+			MD5:              mj.opts.md5,
+			checksum:         mj.opts.checksum,
+			DisableMultipart: mj.opts.disableMultipart,
+			encKeyDB:         mj.opts.encKeyDB,
+		}
+		if mj.opts.activeActive &&
+			event.Type != notification.ObjectCreatedCopy &&
+			event.Type != notification.ObjectCreatedCompleteMultipartUpload &&
+			(getSourceModTimeKey(mirrorURL.SourceContent.Metadata) != "" ||
+				getSourceModTimeKey(mirrorURL.SourceContent.UserMetadata) != "") {
+			// If source has active-active attributes, it means that the
+			// object was uploaded by "mc mirror", hence ignore the event
+			// to avoid copying it.
+			return false
+		}
+		mj.parallel.queueTask(func() URLs {
+			return withSeq(mj.doMirrorWatch(ctx, targetPath, tgtSSE, mirrorURL, event))
+		}, mirrorURL.SourceContent.Size)
+		return true
+	} else if event.Type == notification.ObjectRemovedDelete ||
+		event.Type == notification.ObjectRemovedDeleteMarkerCreated ||
+		event.Type == notification.ILMDelMarkerExpirationDelete {
+		if targetAlias != "" && strings.Contains(event.UserAgent, uaMirrorAppName+":"+targetAlias) {
+			// Ignore delete cascading delete events if cyclical.
+			return false
+		}
+		mirrorURL := URLs{
+			SourceAlias:      sourceAlias,
+			SourceContent:    nil,
+			TargetAlias:      targetAlias,
+			TargetContent:    &ClientContent{URL: *targetURL},
+			MD5:              mj.opts.md5,
+			checksum:         mj.opts.checksum,
+			DisableMultipart: mj.opts.disableMultipart,
+			encKeyDB:         mj.opts.encKeyDB,
+		}
+		mirrorURL.TotalCount = mj.status.GetCounts()
+		mirrorURL.TotalSize = mj.status.Get()
+		if mirrorURL.TargetContent != nil && (mj.opts.isRemove || mj.opts.activeActive || mj.opts.isWatch) {
 			mj.parallel.queueTask(func() URLs {
-				return mj.doMirrorWatch(ctx, targetPath, tgtSSE, mirrorURL, event)
-			}, mirrorURL.SourceContent.Size)
-		} else if event.Type == notification.ObjectRemovedDelete ||
-			event.Type == notification.ObjectRemovedDeleteMarkerCreated ||
-			event.Type == notification.ILMDelMarkerExpirationDelete {
-			if targetAlias != "" && strings.Contains(event.UserAgent, uaMirrorAppName+":"+targetAlias) {
-				// Ignore delete cascading delete events if cyclical.
-				continue
-			}
-			mirrorURL := URLs{
-				SourceAlias:      sourceAlias,
-				SourceContent:    nil,
-				TargetAlias:      targetAlias,
-				TargetContent:    &ClientContent{URL: *targetURL},
-				MD5:              mj.opts.md5,
-				checksum:         mj.opts.checksum,
-				DisableMultipart: mj.opts.disableMultipart,
-				encKeyDB:         mj.opts.encKeyDB,
-			}
-			mirrorURL.TotalCount = mj.status.GetCounts()
-			mirrorURL.TotalSize = mj.status.Get()
-			if mirrorURL.TargetContent != nil && (mj.opts.isRemove || mj.opts.activeActive || mj.opts.isWatch) {
-				mj.parallel.queueTask(func() URLs {
-					return mj.doRemove(ctx, mirrorURL, event)
-				}, 0)
-			}
-		} else if event.Type == notification.BucketCreatedAll {
-			mirrorURL := URLs{
-				SourceAlias:   sourceAlias,
-				SourceContent: &ClientContent{URL: *sourceURL},
-				TargetAlias:   targetAlias,
-				TargetContent: &ClientContent{URL: *targetURL},
-			}
-			mj.parallel.queueTaskWithBarrier(func() URLs {
-				return mj.doCreateBucket(ctx, mirrorURL)
-			}, 0)
-		} else if event.Type == notification.BucketRemovedAll && mj.opts.isRemove {
-			mirrorURL := URLs{
-				TargetAlias:   targetAlias,
-				TargetContent: &ClientContent{URL: *targetURL},
-			}
-			mj.parallel.queueTaskWithBarrier(func() URLs {
-				return mj.doDeleteBucket(ctx, mirrorURL)
+				return withSeq(mj.doRemove(ctx, mirrorURL, event))
 			}, 0)
+			return true
 		}
-
+	} else if event.Type == notification.BucketCreatedAll {
+		mirrorURL := URLs{
+			SourceAlias:   sourceAlias,
+			SourceContent: &ClientContent{URL: *sourceURL},
+			TargetAlias:   targetAlias,
+			TargetContent: &ClientContent{URL: *targetURL},
+		}
+		mj.parallel.queueTaskWithBarrier(func() URLs {
+			return withSeq(mj.doCreateBucket(ctx, mirrorURL))
+		}, 0)
+		return true
+	} else if event.Type == notification.BucketRemovedAll && mj.opts.isRemove {
+		mirrorURL := URLs{
+			TargetAlias:   targetAlias,
+			TargetContent: &ClientContent{URL: *targetURL},
+		}
+		mj.parallel.queueTaskWithBarrier(func() URLs {
+			return withSeq(mj.doDeleteBucket(ctx, mirrorURL))
+		}, 0)
+		return true
 	}
+	return false
 }
 
 // this goroutine will watch for notifications, and add modified objects to the queue
 func (mj *mirrorJob) watchMirror(ctx context.Context) {
 	defer mj.watcher.Stop()
 
+	// Events spooled by an earlier run come first, new events are
+	// buffered by the watcher meanwhile.
+	if pending := mj.opts.spool.Pending(); len(pending) > 0 {
+		mirrorSpoolReplayed.Add(float64(len(pending)))
+		mj.applyMirrorEvents(ctx, pending)
+	}
+
 	for {
 		select {
 		case events, ok := <-mj.watcher.Events():
@@ -812,7 +914,18 @@ func (mj *mirrorJob) watchMirror(ctx context.Context) {
 }
 
 func (mj *mirrorJob) watchURL(ctx context.Context, sourceClient Client) *probe.Error {
-	return mj.watcher.Join(ctx, sourceClient, true)
+	modes, err := mj.watcher.Join(ctx, sourceClient, WatchOptions{
+		Recursive:      true,
+		Events:         []string{"put", "delete", "bucket-creation", "bucket-removal"},
+		FSMode:         mj.opts.fsWatchMode,
+		FSPollInterval: mj.opts.fsPollInterval,
+	})
+	for _, mode := range modes {
+		msg := watchModeMessage{WatchMode: mode}
+		mj.status.PrintMsg(msg)
+		mj.status.Println(msg.String())
+	}
+	return err
 }
 
 // Fetch urls that need to be mirrored
@@ -837,6 +950,10 @@ func (mj *mirrorJob) startMirror(ctx context.Context) {
 				if isNewer(sURLs.SourceContent.Time, mj.opts.newerThan) {
 					continue
 				}
+				// Already mirrored by an earlier run of this session.
+				if mj.opts.session.IsCompleted(sURLs.SourceContent.URL.String()) {
+					continue
+				}
 			}
 
 			if sURLs.SourceContent != nil {
@@ -852,7 +969,9 @@ func (mj *mirrorJob) startMirror(ctx context.Context) {
 			sURLs.TotalSize = mj.status.Get()
 
 			if sURLs.SourceContent != nil {
+				mj.opts.session.Queued(sURLs)
 				mj.parallel.queueTask(func() URLs {
+					mj.opts.session.Started(sURLs)
 					return mj.doMirror(ctx, sURLs, EventInfo{})
 				}, sURLs.SourceContent.Size)
 			} else if sURLs.TargetContent != nil && mj.opts.isRemove {
@@ -983,7 +1102,7 @@ func getEventPathURLWin(srcURL, eventPath string) string {
 }
 
 // runMirror - mirrors all buckets to another S3 server
-func runMirror(ctx context.Context, srcURL, dstURL string, cmd *cli.Command, encKeyDB map[string][]prefixSSEPair) bool {
+func runMirror(ctx context.Context, srcURL, dstURL string, cmd *cli.Command, encKeyDB map[string][]prefixSSEPair, cse *clientEncryption, session *sessionV8, spool *mirrorSpool) bool {
 	// Parse metadata.
 	userMetadata := make(map[string]string)
 	if cmd.String("attr") != "" {
@@ -1019,6 +1138,7 @@ func runMirror(ctx context.Context, srcURL, dstURL string, cmd *cli.Command, enc
 		isOverwrite:           isOverwrite,
 		isWatch:               isWatch,
 		isMetadata:            isMetadata,
+		isChecksum:            cmd.Bool("compare-checksum"),
 		isSummary:             cmd.Bool("summary"),
 		isRetriable:           cmd.Bool("retry"),
 		md5:                   md5,
@@ -1035,6 +1155,11 @@ func runMirror(ctx context.Context, srcURL, dstURL string, cmd *cli.Command, enc
 		encKeyDB:              encKeyDB,
 		activeActive:          isActiveActive,
 		maxWorkers:            cmd.Int("max-workers"),
+		session:               session,
+		spool:                 spool,
+		cse:                   cse,
+		fsWatchMode:           cmd.String("fs-watch-mode"),
+		fsPollInterval:        cmd.Duration("fs-poll-interval"),
 	}
 
 	// If we are not using active/active and we are not removing
@@ -1164,6 +1289,7 @@ func runMirror(ctx context.Context, srcURL, dstURL string, cmd *cli.Command, enc
 func mainMirror(ctx context.Context, cmd *cli.Command) error {
 	// Additional command specific theme customization.
 	console.SetColor("Mirror", color.New(color.FgGreen, color.Bold))
+	console.SetColor("WatchMode", color.New(color.FgMagenta))
 
 	ctx, cancelMirror := context.WithCancel(ctx)
 	defer cancelMirror()
@@ -1171,8 +1297,45 @@ func mainMirror(ctx context.Context, cmd *cli.Command) error {
 	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
 	fatalIf(err, "Unable to parse encryption keys.")
 
-	// check 'mirror' cli arguments.
-	srcURL, tgtURL := checkMirrorSyntax(ctx, cmd, encKeyDB)
+	cse, err := parseClientEncryption(cmd)
+	fatalIf(err, "Unable to parse client-side encryption keys.")
+	if cse != nil && cmd.Bool("compare-checksum") {
+		// Checksums of client-side encrypted objects are computed on random ciphertext.
+		fatalIf(errInvalidArgument().Trace(), "You cannot combine --compare-checksum with client-side encryption.")
+	}
+
+	var session *sessionV8
+	var srcURL, tgtURL string
+	if sid := cmd.String("resume"); sid != "" {
+		session = loadCommandSession(sid, "mirror")
+		fatalIf(restoreSessionFlags(cmd, session), "Unable to restore flags of session `%s`.", sid)
+		if session.Header.RootPath != "" {
+			// Relative filesystem paths are resolved from the original working folder.
+			fatalIf(probe.NewError(os.Chdir(session.Header.RootPath)), "Unable to change to folder `%s`.", session.Header.RootPath)
+		}
+		srcURL, tgtURL = session.Header.CommandArgs[0], session.Header.CommandArgs[1]
+	} else {
+		// check 'mirror' cli arguments.
+		srcURL, tgtURL = checkMirrorSyntax(ctx, cmd, encKeyDB)
+	}
+
+	isWatch := cmd.Bool("watch") || cmd.Bool("multi-master") || cmd.Bool("active-active")
+
+	// Journal one-shot mirrors, so that they can be resumed. Continuous
+	// mirroring restarts its listing on its own and is never journaled.
+	if session == nil && !isWatch && !cmd.Bool("fake") && !cmd.Bool("dry-run") {
+		session = newCommandSession("mirror", []string{srcURL, tgtURL}, sessionFlags(cmd, mirrorFlags))
+	}
+
+	var spool *mirrorSpool
+	if spoolDir := cmd.String("spool"); spoolDir != "" {
+		if !isWatch {
+			fatalIf(errInvalidArgument().Trace(spoolDir), "--spool requires --watch or --active-active.")
+		}
+		spool, err = openMirrorSpool(spoolDir, srcURL, tgtURL)
+		fatalIf(err, "Unable to open spool `%s`, it may belong to another mirror.", spoolDir)
+		defer spool.Close()
+	}
 
 	if prometheusAddress := cmd.String("monitoring-address"); prometheusAddress != "" {
 		http.Handle("/metrics", promhttp.Handler())
@@ -1189,12 +1352,13 @@ func mainMirror(ctx context.Context, cmd *cli.Command) error {
 		case <-ctx.Done():
 			return exitStatus(globalErrorExitStatus)
 		default:
-			errorDetected := runMirror(ctx, srcURL, tgtURL, cmd, encKeyDB)
-			if cmd.Bool("watch") || cmd.Bool("multi-master") || cmd.Bool("active-active") {
+			errorDetected := runMirror(ctx, srcURL, tgtURL, cmd, encKeyDB, cse, session, spool)
+			if isWatch {
 				mirrorRestarts.Inc()
 				time.Sleep(time.Duration(r.Float64() * float64(2*time.Second)))
 				continue
 			}
+			finishCommandSession(session, errorDetected || ctx.Err() != nil || globalContext.Err() != nil)
 			if errorDetected {
 				return exitStatus(globalErrorExitStatus)
 			}
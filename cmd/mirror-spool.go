@@ -0,0 +1,282 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/quick"
+)
+
+const (
+	mirrorSpoolVersion = "1"
+	mirrorSpoolHeader  = "spool.json"
+	mirrorSpoolLog     = "events.log"
+
+	// acknowledgements written before the log is compacted.
+	mirrorSpoolCompactAfter = 10000
+)
+
+// mirrorSpoolHeaderV1 - mirror a spool belongs to, a spool is never
+// replayed to another target.
+type mirrorSpoolHeaderV1 struct {
+	Version string `json:"version"`
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+}
+
+// mirrorSpoolRecord - one line of the spool log, either an event or
+// the acknowledgement of an event mirrored successfully.
+type mirrorSpoolRecord struct {
+	Seq   uint64     `json:"seq"`
+	Ack   bool       `json:"ack,omitempty"`
+	Event *EventInfo `json:"event,omitempty"`
+}
+
+// spooledEvent - watch event with its sequence number in the spool,
+// zero if it is not spooled.
+type spooledEvent struct {
+	seq   uint64
+	event EventInfo
+}
+
+// mirrorSpool - durable queue of the watch events of a mirror. Events
+// are appended before they are mirrored and acknowledged after, events
+// never acknowledged are replayed when mirroring restarts.
+type mirrorSpool struct {
+	mutex   sync.Mutex
+	dir     string
+	log     *os.File
+	next    uint64
+	pending map[uint64]EventInfo
+	acks    int
+}
+
+// openMirrorSpool opens or creates the spool of a mirror in dir.
+func openMirrorSpool(dir, source, target string) (*mirrorSpool, *probe.Error) {
+	if e := os.MkdirAll(dir, 0o700); e != nil {
+		return nil, probe.NewError(e).Trace(dir)
+	}
+
+	header := &mirrorSpoolHeaderV1{Version: mirrorSpoolVersion, Source: source, Target: target}
+	headerFile := filepath.Join(dir, mirrorSpoolHeader)
+	qs, e := quick.NewConfig(header, nil)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(dir)
+	}
+	if _, e = os.Stat(headerFile); e == nil {
+		if e = qs.Load(headerFile); e != nil {
+			return nil, probe.NewError(e).Trace(headerFile)
+		}
+		if header.Version != mirrorSpoolVersion || header.Source != source || header.Target != target {
+			return nil, errInvalidArgument().Trace(dir, header.Source, header.Target)
+		}
+	} else if e = qs.Save(headerFile); e != nil {
+		return nil, probe.NewError(e).Trace(headerFile)
+	}
+
+	s := &mirrorSpool{dir: dir, next: 1, pending: make(map[uint64]EventInfo)}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	mirrorSpoolPending.Set(float64(len(s.pending)))
+	return s, nil
+}
+
+// replay reads the log, a partially written trailing line is expected
+// after a crash and ignored.
+func (s *mirrorSpool) replay() *probe.Error {
+	f, e := os.Open(filepath.Join(s.dir, mirrorSpoolLog))
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil
+		}
+		return probe.NewError(e).Trace(s.dir)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		var record mirrorSpoolRecord
+		if e := json.Unmarshal(scanner.Bytes(), &record); e != nil {
+			continue
+		}
+		switch {
+		case record.Ack:
+			delete(s.pending, record.Seq)
+		case record.Event != nil:
+			s.pending[record.Seq] = *record.Event
+		}
+		s.next = max(s.next, record.Seq+1)
+	}
+	if e := scanner.Err(); e != nil {
+		return probe.NewError(e).Trace(s.dir)
+	}
+	return nil
+}
+
+// compact rewrites the log with the pending events only.
+func (s *mirrorSpool) compact() *probe.Error {
+	logFile := filepath.Join(s.dir, mirrorSpoolLog)
+	tmpFile := logFile + ".tmp"
+
+	f, e := os.OpenFile(tmpFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if e != nil {
+		return probe.NewError(e).Trace(tmpFile)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, ev := range s.sortedPending() {
+		if e = enc.Encode(mirrorSpoolRecord{Seq: ev.seq, Event: &ev.event}); e != nil {
+			break
+		}
+	}
+	if e == nil {
+		e = w.Flush()
+	}
+	if e == nil {
+		e = f.Sync()
+	}
+	if cerr := f.Close(); e == nil {
+		e = cerr
+	}
+	if e != nil {
+		os.Remove(tmpFile)
+		return probe.NewError(e).Trace(tmpFile)
+	}
+
+	if s.log != nil {
+		s.log.Close()
+		s.log = nil
+	}
+	if e = os.Rename(tmpFile, logFile); e != nil {
+		return probe.NewError(e).Trace(logFile)
+	}
+	if s.log, e = os.OpenFile(logFile, os.O_WRONLY|os.O_APPEND, 0o600); e != nil {
+		return probe.NewError(e).Trace(logFile)
+	}
+	s.acks = 0
+	return nil
+}
+
+func (s *mirrorSpool) sortedPending() []spooledEvent {
+	events := make([]spooledEvent, 0, len(s.pending))
+	for seq, event := range s.pending {
+		events = append(events, spooledEvent{seq: seq, event: event})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].seq < events[j].seq })
+	return events
+}
+
+// Append persists events before they are mirrored.
+func (s *mirrorSpool) Append(events []EventInfo) ([]spooledEvent, *probe.Error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.log == nil {
+		return nil, errDummy().Trace(s.dir)
+	}
+	spooled := make([]spooledEvent, 0, len(events))
+	var buf []byte
+	for _, event := range events {
+		record, e := json.Marshal(mirrorSpoolRecord{Seq: s.next, Event: &event})
+		if e != nil {
+			return nil, probe.NewError(e)
+		}
+		buf = append(append(buf, record...), '\n')
+		spooled = append(spooled, spooledEvent{seq: s.next, event: event})
+		s.next++
+	}
+	if _, e := s.log.Write(buf); e != nil {
+		return nil, probe.NewError(e).Trace(s.dir)
+	}
+	if e := s.log.Sync(); e != nil {
+		return nil, probe.NewError(e).Trace(s.dir)
+	}
+	for _, ev := range spooled {
+		s.pending[ev.seq] = ev.event
+	}
+	mirrorSpoolPending.Set(float64(len(s.pending)))
+	return spooled, nil
+}
+
+// Ack acknowledges an event mirrored successfully or skipped, it is not
+// replayed anymore. Acknowledgements are not synced, an event mirrored
+// again after a crash is harmless.
+func (s *mirrorSpool) Ack(seq uint64) {
+	if s == nil || seq == 0 {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.pending[seq]; !ok || s.log == nil {
+		return
+	}
+	delete(s.pending, seq)
+	mirrorSpoolPending.Set(float64(len(s.pending)))
+
+	record, e := json.Marshal(mirrorSpoolRecord{Seq: seq, Ack: true})
+	if e == nil {
+		_, e = s.log.Write(append(record, '\n'))
+	}
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(s.dir), "Unable to acknowledge event in spool.")
+		return
+	}
+	if s.acks++; s.acks >= mirrorSpoolCompactAfter {
+		errorIf(s.compact(), "Unable to compact spool.")
+	}
+}
+
+// Pending returns the events not acknowledged yet, in spool order.
+func (s *mirrorSpool) Pending() []spooledEvent {
+	if s == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.sortedPending()
+}
+
+// Close closes the spool log.
+func (s *mirrorSpool) Close() *probe.Error {
+	if s == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.log == nil {
+		return nil
+	}
+	e := s.log.Close()
+	s.log = nil
+	return probe.NewError(e)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openstor/openstor-go/v7/pkg/notification"
+)
+
+func TestMirrorSpool(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := openMirrorSpool(dir, "play/photos", "s3/backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := []EventInfo{
+		{Path: "play/photos/a", Type: notification.ObjectCreatedPut, Size: 1},
+		{Path: "play/photos/b", Type: notification.ObjectCreatedPut, Size: 2},
+		{Path: "play/photos/c", Type: notification.ObjectRemovedDelete},
+	}
+	spooled, err := spool.Append(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spooled) != 3 || spooled[0].seq != 1 || spooled[2].seq != 3 {
+		t.Fatalf("unexpected spooled events %+v", spooled)
+	}
+	spool.Ack(spooled[1].seq)
+	spool.Ack(0) // not spooled, ignored
+	if err = spool.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash while writing leaves a partial line behind.
+	f, e := os.OpenFile(filepath.Join(dir, mirrorSpoolLog), os.O_WRONLY|os.O_APPEND, 0o600)
+	if e != nil {
+		t.Fatal(e)
+	}
+	f.WriteString(`{"seq":4,"event":{"Pa`)
+	f.Close()
+
+	spool, err = openMirrorSpool(dir, "play/photos", "s3/backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := spool.Pending()
+	if len(pending) != 2 || pending[0].event.Path != "play/photos/a" || pending[1].event.Path != "play/photos/c" {
+		t.Fatalf("unexpected pending events %+v", pending)
+	}
+	if pending[1].event.Type != notification.ObjectRemovedDelete {
+		t.Fatalf("unexpected event type %s", pending[1].event.Type)
+	}
+
+	// Sequence numbers keep increasing after a restart.
+	spooled, err = spool.Append(events[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spooled[0].seq != 4 {
+		t.Fatalf("expected sequence 4, got %d", spooled[0].seq)
+	}
+	for _, ev := range spool.Pending() {
+		spool.Ack(ev.seq)
+	}
+	spool.Close()
+
+	spool, err = openMirrorSpool(dir, "play/photos", "s3/backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending = spool.Pending(); len(pending) != 0 {
+		t.Fatalf("expected no pending events, got %+v", pending)
+	}
+	spool.Close()
+
+	if _, err = openMirrorSpool(dir, "play/photos", "s3/other"); err == nil {
+		t.Fatal("expected a spool of another mirror to be rejected")
+	}
+}
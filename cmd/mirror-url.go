@@ -214,7 +214,7 @@ func deltaSourceTarget(ctx context.Context, sourceURL, targetURL string, opts mi
 			// No difference, continue.
 		case differInType:
 			URLsCh <- URLs{Error: errInvalidTarget(diffMsg.SecondURL)}
-		case differInSize, differInMetadata, differInAASourceMTime:
+		case differInSize, differInMetadata, differInAASourceMTime, differInContent:
 			if !opts.isOverwrite && !opts.isFake && !opts.activeActive {
 				// Size or time or etag differs but --overwrite not set.
 				URLsCh <- URLs{
@@ -267,6 +267,7 @@ func deltaSourceTarget(ctx context.Context, sourceURL, targetURL string, opts mi
 type mirrorOptions struct {
 	isFake, isOverwrite, activeActive                     bool
 	isWatch, isRemove, isMetadata                         bool
+	isChecksum                                            bool
 	isRetriable                                           bool
 	isSummary                                             bool
 	skipErrors                                            bool
@@ -279,6 +280,11 @@ type mirrorOptions struct {
 	checksum                                              openstor.ChecksumType
 	sourceListingOnly                                     bool
 	maxWorkers                                            int
+	session                                               *sessionV8
+	spool                                                 *mirrorSpool
+	fsWatchMode                                           string
+	fsPollInterval                                        time.Duration
+	cse                                                   *clientEncryption
 }
 
 // Prepares urls that need to be copied or removed based on requested options.
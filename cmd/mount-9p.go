@@ -0,0 +1,544 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Messages of 9P2000, see http://man.cat-v.org/plan_9/5/intro
+const (
+	p9Tversion uint8 = 100 + iota
+	p9Rversion
+	p9Tauth
+	p9Rauth
+	p9Tattach
+	p9Rattach
+	p9Terror // not used
+	p9Rerror
+	p9Tflush
+	p9Rflush
+	p9Twalk
+	p9Rwalk
+	p9Topen
+	p9Ropen
+	p9Tcreate
+	p9Rcreate
+	p9Tread
+	p9Rread
+	p9Twrite
+	p9Rwrite
+	p9Tclunk
+	p9Rclunk
+	p9Tremove
+	p9Rremove
+	p9Tstat
+	p9Rstat
+	p9Twstat
+	p9Rwstat
+)
+
+const (
+	p9Version = "9P2000"
+
+	// Size of the header of messages, size[4] type[1] tag[2].
+	p9HeaderSize = 7
+	// Size of the header of Tread and Rwrite, data of reads fits in
+	// the negotiated msize minus it.
+	p9IOHeaderSize = 24
+	p9MaxMsize     = 1 << 20
+	p9MaxWalkElem  = 16
+
+	p9QTDir = 0x80
+	p9DMDir = 0x80000000
+
+	p9OModeMask = 3
+	p9ORead     = 0
+	p9OExec     = 3
+	p9OTrunc    = 0x10
+	p9ORClose   = 0x40
+)
+
+// Errors, with the strings of libc which clients map to errno values.
+const (
+	p9ErrNotFound    = "No such file or directory"
+	p9ErrPermission  = "Permission denied"
+	p9ErrReadOnly    = "Read-only file system"
+	p9ErrNotDir      = "Not a directory"
+	p9ErrBadFid      = "Bad file descriptor"
+	p9ErrInvalid     = "Invalid argument"
+	p9ErrInterrupted = "Interrupted system call"
+	p9ErrIO          = "Input/output error"
+	p9ErrNoAuth      = "authentication not required"
+)
+
+// p9Qid - unique identification of a file for the server.
+type p9Qid struct {
+	typ     uint8
+	version uint32
+	path    uint64
+}
+
+// p9Decoder decodes the fields of a message, short is set once a field
+// is past its end.
+type p9Decoder struct {
+	buf   []byte
+	short bool
+}
+
+func (d *p9Decoder) next(n int) []byte {
+	if d.short || len(d.buf) < n {
+		d.short = true
+		return make([]byte, n)
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *p9Decoder) u8() uint8   { return d.next(1)[0] }
+func (d *p9Decoder) u16() uint16 { return binary.LittleEndian.Uint16(d.next(2)) }
+func (d *p9Decoder) u32() uint32 { return binary.LittleEndian.Uint32(d.next(4)) }
+func (d *p9Decoder) u64() uint64 { return binary.LittleEndian.Uint64(d.next(8)) }
+func (d *p9Decoder) str() string { return string(d.next(int(d.u16()))) }
+
+// p9Encoder appends the fields of a message.
+type p9Encoder struct {
+	buf []byte
+}
+
+func (e *p9Encoder) u8(v uint8)   { e.buf = append(e.buf, v) }
+func (e *p9Encoder) u16(v uint16) { e.buf = binary.LittleEndian.AppendUint16(e.buf, v) }
+func (e *p9Encoder) u32(v uint32) { e.buf = binary.LittleEndian.AppendUint32(e.buf, v) }
+func (e *p9Encoder) u64(v uint64) { e.buf = binary.LittleEndian.AppendUint64(e.buf, v) }
+
+func (e *p9Encoder) str(s string) {
+	e.u16(uint16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *p9Encoder) qid(q p9Qid) {
+	e.u8(q.typ)
+	e.u32(q.version)
+	e.u64(q.path)
+}
+
+// stat appends the directory entry of a node.
+func (e *p9Encoder) stat(node *mountNode) {
+	var s p9Encoder
+	s.u16(0) // type
+	s.u32(0) // dev
+	s.qid(node.qid())
+	mode, length := uint32(0o444), uint64(node.size)
+	if node.isDir() {
+		mode, length = p9DMDir|0o555, 0
+	}
+	s.u32(mode)
+	var mtime uint32
+	if !node.mtime.IsZero() {
+		mtime = uint32(node.mtime.Unix())
+	}
+	s.u32(mtime) // atime
+	s.u32(mtime)
+	s.u64(length)
+	s.str(node.name())
+	for range 3 {
+		s.str("mc") // uid, gid, muid
+	}
+	e.u16(uint16(len(s.buf)))
+	e.buf = append(e.buf, s.buf...)
+}
+
+// p9Fid - a file of a connection.
+type p9Fid struct {
+	node *mountNode
+	open bool
+
+	// Entries of an open folder and the position of the next read.
+	mu        sync.Mutex
+	entries   [][]byte
+	nextEntry int
+	nextRead  uint64
+}
+
+// p9Conn serves the requests of a connection concurrently.
+type p9Conn struct {
+	fs    *mountFS
+	conn  net.Conn
+	msize uint32
+
+	wmu sync.Mutex
+
+	mu      sync.Mutex
+	fids    map[uint32]*p9Fid
+	pending map[uint16]*p9Pending
+	wg      sync.WaitGroup
+}
+
+// p9Pending - a request being served, which can be flushed.
+type p9Pending struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// serve9P serves fs over 9P2000 to the connections of listener until
+// ctx is canceled.
+func serve9P(ctx context.Context, listener net.Listener, fs *mountFS) error {
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+	for {
+		conn, e := listener.Accept()
+		if e != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return e
+		}
+		c := &p9Conn{
+			fs:      fs,
+			conn:    conn,
+			msize:   p9MaxMsize,
+			fids:    make(map[uint32]*p9Fid),
+			pending: make(map[uint16]*p9Pending),
+		}
+		go c.serve(ctx)
+	}
+}
+
+func (c *p9Conn) serve(ctx context.Context) {
+	stop := context.AfterFunc(ctx, func() { c.conn.Close() })
+	defer stop()
+	defer c.conn.Close()
+	defer c.wg.Wait()
+
+	defer c.abort()
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		var sizeBuf [4]byte
+		if _, e := io.ReadFull(reader, sizeBuf[:]); e != nil {
+			return
+		}
+		size := binary.LittleEndian.Uint32(sizeBuf[:])
+		if size < p9HeaderSize || size > c.msize {
+			return
+		}
+		msg := make([]byte, size-4)
+		if _, e := io.ReadFull(reader, msg); e != nil {
+			return
+		}
+		typ, tag := msg[0], binary.LittleEndian.Uint16(msg[1:3])
+		d := &p9Decoder{buf: msg[3:]}
+
+		switch typ {
+		case p9Tversion:
+			// Aborts all requests and resets the session.
+			c.abort()
+			c.wg.Wait()
+			c.version(tag, d)
+		case p9Tflush:
+			oldTag := d.u16()
+			c.mu.Lock()
+			p := c.pending[oldTag]
+			c.mu.Unlock()
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				if p != nil {
+					p.cancel()
+					<-p.done
+				}
+				c.reply(p9Rflush, tag, nil)
+			}()
+		default:
+			rctx, rcancel := context.WithCancel(ctx)
+			p := &p9Pending{cancel: rcancel, done: make(chan struct{})}
+			c.mu.Lock()
+			c.pending[tag] = p
+			c.mu.Unlock()
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				defer close(p.done)
+				defer rcancel()
+				body, ename := c.handle(rctx, typ, d)
+				c.mu.Lock()
+				delete(c.pending, tag)
+				c.mu.Unlock()
+				if d.short && ename == "" {
+					ename = p9ErrInvalid
+				}
+				if ename != "" {
+					var e p9Encoder
+					e.str(ename)
+					c.reply(p9Rerror, tag, e.buf)
+					return
+				}
+				c.reply(typ+1, tag, body)
+			}()
+		}
+	}
+}
+
+// abort cancels all pending requests.
+func (c *p9Conn) abort() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.pending {
+		p.cancel()
+	}
+}
+
+func (c *p9Conn) reply(typ uint8, tag uint16, body []byte) {
+	e := p9Encoder{buf: make([]byte, 0, p9HeaderSize+len(body))}
+	e.u32(uint32(p9HeaderSize + len(body)))
+	e.u8(typ)
+	e.u16(tag)
+	e.buf = append(e.buf, body...)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.conn.Write(e.buf)
+}
+
+func (c *p9Conn) version(tag uint16, d *p9Decoder) {
+	msize, version := d.u32(), d.str()
+	c.mu.Lock()
+	c.fids = make(map[uint32]*p9Fid)
+	c.mu.Unlock()
+
+	c.msize = min(msize, p9MaxMsize)
+	if version != p9Version && !strings.HasPrefix(version, p9Version+".") {
+		version = "unknown"
+	} else {
+		// Extensions like 9P2000.L or 9P2000.u are not supported.
+		version = p9Version
+	}
+	var e p9Encoder
+	e.u32(c.msize)
+	e.str(version)
+	c.reply(p9Rversion, tag, e.buf)
+}
+
+// handle serves a request and returns the body of its reply or an error.
+func (c *p9Conn) handle(ctx context.Context, typ uint8, d *p9Decoder) ([]byte, string) {
+	var e p9Encoder
+	switch typ {
+	case p9Tauth:
+		return nil, p9ErrNoAuth
+	case p9Tattach:
+		fid := d.u32()
+		if d.short {
+			return nil, p9ErrInvalid
+		}
+		root := &mountNode{}
+		if ename := c.newFid(fid, root); ename != "" {
+			return nil, ename
+		}
+		e.qid(root.qid())
+	case p9Twalk:
+		fid, newFid, n := d.u32(), d.u32(), int(d.u16())
+		if n > p9MaxWalkElem {
+			return nil, p9ErrInvalid
+		}
+		names := make([]string, n)
+		for i := range names {
+			names[i] = d.str()
+		}
+		if d.short {
+			return nil, p9ErrInvalid
+		}
+		f, ename := c.fid(fid)
+		if ename != "" {
+			return nil, ename
+		}
+		if f.open {
+			return nil, p9ErrBadFid
+		}
+		node, qids, ename := c.walk(ctx, f.node, names)
+		if len(qids) == 0 && n > 0 {
+			return nil, ename
+		}
+		if len(qids) == n {
+			if newFid == fid {
+				c.mu.Lock()
+				f.node = node
+				c.mu.Unlock()
+			} else if ename = c.newFid(newFid, node); ename != "" {
+				return nil, ename
+			}
+		}
+		e.u16(uint16(len(qids)))
+		for _, qid := range qids {
+			e.qid(qid)
+		}
+	case p9Topen:
+		fid, mode := d.u32(), d.u8()
+		f, ename := c.fid(fid)
+		if ename != "" {
+			return nil, ename
+		}
+		if m := mode & p9OModeMask; (m != p9ORead && m != p9OExec) || mode&(p9OTrunc|p9ORClose) != 0 {
+			return nil, p9ErrReadOnly
+		}
+		if f.open {
+			return nil, p9ErrBadFid
+		}
+		if f.node.isDir() {
+			nodes, err := c.fs.readDir(ctx, f.node)
+			if err != nil {
+				return nil, mountErrorString(err.ToGoError())
+			}
+			f.entries = make([][]byte, len(nodes))
+			for i, node := range nodes {
+				var s p9Encoder
+				s.stat(node)
+				f.entries[i] = s.buf
+			}
+		}
+		f.open = true
+		e.qid(f.node.qid())
+		e.u32(c.msize - p9IOHeaderSize)
+	case p9Tread:
+		fid, offset, count := d.u32(), d.u64(), d.u32()
+		f, ename := c.fid(fid)
+		if ename != "" {
+			return nil, ename
+		}
+		if !f.open {
+			return nil, p9ErrBadFid
+		}
+		count = min(count, c.msize-p9IOHeaderSize)
+		data, ename := c.read(ctx, f, offset, count)
+		if ename != "" {
+			return nil, ename
+		}
+		e.u32(uint32(len(data)))
+		e.buf = append(e.buf, data...)
+	case p9Tstat:
+		f, ename := c.fid(d.u32())
+		if ename != "" {
+			return nil, ename
+		}
+		var s p9Encoder
+		s.stat(f.node)
+		e.u16(uint16(len(s.buf)))
+		e.buf = append(e.buf, s.buf...)
+	case p9Tclunk, p9Tremove:
+		fid := d.u32()
+		c.mu.Lock()
+		_, ok := c.fids[fid]
+		delete(c.fids, fid)
+		c.mu.Unlock()
+		if !ok {
+			return nil, p9ErrBadFid
+		}
+		if typ == p9Tremove {
+			return nil, p9ErrReadOnly
+		}
+	case p9Tcreate, p9Twrite, p9Twstat:
+		return nil, p9ErrReadOnly
+	default:
+		return nil, p9ErrInvalid
+	}
+	return e.buf, ""
+}
+
+func (c *p9Conn) fid(fid uint32) (*p9Fid, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.fids[fid]
+	if !ok {
+		return nil, p9ErrBadFid
+	}
+	return f, ""
+}
+
+func (c *p9Conn) newFid(fid uint32, node *mountNode) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.fids[fid]; ok {
+		return p9ErrBadFid
+	}
+	c.fids[fid] = &p9Fid{node: node}
+	return ""
+}
+
+// walk walks names from node and returns the last node reached with the
+// qids of the nodes walked, and the error which stopped the walk if any.
+func (c *p9Conn) walk(ctx context.Context, node *mountNode, names []string) (*mountNode, []p9Qid, string) {
+	var qids []p9Qid
+	for _, name := range names {
+		if !node.isDir() {
+			return node, qids, p9ErrNotDir
+		}
+		switch name {
+		case "..":
+			node = node.parent()
+		case ".":
+		default:
+			child, err := c.fs.lookup(ctx, node, name)
+			if err != nil {
+				return node, qids, mountErrorString(err.ToGoError())
+			}
+			if child == nil {
+				return node, qids, p9ErrNotFound
+			}
+			node = child
+		}
+		qids = append(qids, node.qid())
+	}
+	return node, qids, ""
+}
+
+// read reads a file, or the whole entries of a folder which fit in count
+// bytes, folders are read sequentially from offset 0.
+func (c *p9Conn) read(ctx context.Context, f *p9Fid, offset uint64, count uint32) ([]byte, string) {
+	if f.node.isDir() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if offset == 0 {
+			f.nextEntry, f.nextRead = 0, 0
+		} else if offset != f.nextRead {
+			return nil, p9ErrInvalid
+		}
+		var data []byte
+		for f.nextEntry < len(f.entries) && len(data)+len(f.entries[f.nextEntry]) <= int(count) {
+			data = append(data, f.entries[f.nextEntry]...)
+			f.nextEntry++
+		}
+		f.nextRead += uint64(len(data))
+		return data, ""
+	}
+
+	if offset >= uint64(f.node.size) {
+		return nil, ""
+	}
+	data := make([]byte, min(uint64(count), uint64(f.node.size)-offset))
+	n, e := c.fs.readAt(ctx, f.node, data, int64(offset))
+	if e != nil && n == 0 {
+		return nil, mountErrorString(e)
+	}
+	return data[:n], ""
+}
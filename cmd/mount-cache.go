@@ -0,0 +1,167 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// mountCache keeps blocks of objects in files of a local folder, the
+// least recently used blocks are removed once maxSize is exceeded.
+type mountCache struct {
+	dir       string
+	blockSize int64
+	maxSize   int64
+
+	mu       sync.Mutex
+	size     int64
+	lru      *list.List // of *mountBlock, most recently used first.
+	blocks   map[string]*list.Element
+	fetching map[string]*mountFetch
+}
+
+type mountBlock struct {
+	id   string
+	size int64
+}
+
+// mountFetch - a block being fetched, shared by concurrent readers.
+type mountFetch struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+func newMountCache(dir string, blockSize, maxSize int64) *mountCache {
+	return &mountCache{
+		dir:       dir,
+		blockSize: blockSize,
+		maxSize:   maxSize,
+		lru:       list.New(),
+		blocks:    make(map[string]*list.Element),
+		fetching:  make(map[string]*mountFetch),
+	}
+}
+
+func (c *mountCache) path(id string) string {
+	return filepath.Join(c.dir, id[:2], id)
+}
+
+// block returns the block n of an object, object identifies the content
+// of the object and changes with it. fetch reads length bytes at offset
+// of the object when the block is not cached.
+func (c *mountCache) block(ctx context.Context, object string, n int64, fetch func(ctx context.Context, offset, length int64) ([]byte, error)) ([]byte, error) {
+	sum := sha256.Sum256([]byte(object + "\x00" + strconv.FormatInt(n, 10)))
+	id := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	if elem, ok := c.blocks[id]; ok {
+		c.lru.MoveToFront(elem)
+		c.mu.Unlock()
+		if data, e := os.ReadFile(c.path(id)); e == nil {
+			return data, nil
+		}
+		// Removed meanwhile, fetch it again.
+		c.mu.Lock()
+	}
+	if f, ok := c.fetching[id]; ok {
+		c.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.data, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	f := &mountFetch{done: make(chan struct{})}
+	c.fetching[id] = f
+	c.mu.Unlock()
+
+	f.data, f.err = fetch(ctx, n*c.blockSize, c.blockSize)
+	if f.err == nil {
+		// The cache is best effort, blocks are served even if they
+		// cannot be stored.
+		c.store(id, f.data)
+	}
+	c.mu.Lock()
+	delete(c.fetching, id)
+	c.mu.Unlock()
+	close(f.done)
+	return f.data, f.err
+}
+
+func (c *mountCache) store(id string, data []byte) {
+	p := c.path(id)
+	if e := os.MkdirAll(filepath.Dir(p), 0o700); e != nil {
+		return
+	}
+	tmp := p + ".tmp"
+	if e := os.WriteFile(tmp, data, 0o600); e != nil {
+		os.Remove(tmp)
+		return
+	}
+	if e := os.Rename(tmp, p); e != nil {
+		os.Remove(tmp)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.blocks[id]; ok {
+		c.size -= elem.Value.(*mountBlock).size
+		c.lru.Remove(elem)
+	}
+	c.blocks[id] = c.lru.PushFront(&mountBlock{id: id, size: int64(len(data))})
+	c.size += int64(len(data))
+	for c.size > c.maxSize && c.lru.Len() > 1 {
+		oldest := c.lru.Back()
+		block := c.lru.Remove(oldest).(*mountBlock)
+		delete(c.blocks, block.id)
+		c.size -= block.size
+		os.Remove(c.path(block.id))
+	}
+}
+
+// readAt reads len(p) bytes of an object of size bytes at offset through
+// the blocks of the cache.
+func (c *mountCache) readAt(ctx context.Context, object string, size int64, p []byte, offset int64, fetch func(ctx context.Context, offset, length int64) ([]byte, error)) (int, error) {
+	var read int
+	for read < len(p) && offset < size {
+		n := offset / c.blockSize
+		data, e := c.block(ctx, object, n, fetch)
+		if e != nil {
+			return read, e
+		}
+		start := offset - n*c.blockSize
+		if start >= int64(len(data)) {
+			// The object is shorter than expected.
+			break
+		}
+		copied := copy(p[read:], data[start:])
+		read += copied
+		offset += int64(copied)
+	}
+	return read, nil
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/openstor-go/v7"
+	"github.com/openstor/openstor-go/v7/pkg/encrypt"
+)
+
+// Listings of folders are refreshed after mountListTTL, unless the
+// mounted view is rewound since it does not change then.
+const mountListTTL = time.Minute
+
+// mountNode - a file or a folder of the mounted tree, keys of folders
+// end with "/" and the key of the root is "".
+type mountNode struct {
+	key       string
+	size      int64
+	mtime     time.Time
+	etag      string
+	versionID string
+}
+
+func (n *mountNode) isDir() bool {
+	return n.key == "" || strings.HasSuffix(n.key, "/")
+}
+
+func (n *mountNode) name() string {
+	if n.key == "" {
+		return "/"
+	}
+	name := strings.TrimSuffix(n.key, "/")
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// parent returns the folder of the node, the root is its own parent.
+func (n *mountNode) parent() *mountNode {
+	key := strings.TrimSuffix(n.key, "/")
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		return &mountNode{key: key[:i+1]}
+	}
+	return &mountNode{}
+}
+
+// id identifies the content of a file in the block cache.
+func (n *mountNode) id() string {
+	return strings.Join([]string{n.key, n.versionID, n.etag, strconv.FormatInt(n.size, 10), strconv.FormatInt(n.mtime.UnixNano(), 10)}, "\x00")
+}
+
+// qid returns the unique identification of the node for 9P.
+func (n *mountNode) qid() p9Qid {
+	h := fnv.New64a()
+	h.Write([]byte(n.key))
+	qid := p9Qid{path: h.Sum64()}
+	if n.isDir() {
+		qid.typ = p9QTDir
+		return qid
+	}
+	v := fnv.New32a()
+	v.Write([]byte(n.id()))
+	qid.version = v.Sum32()
+	return qid
+}
+
+// mountDir - cached listing of a folder.
+type mountDir struct {
+	mu      sync.Mutex
+	fetched time.Time
+	nodes   []*mountNode // sorted by name.
+}
+
+// mountFS is the read-only tree of `mc mount`, folders are listed with
+// Client.List the same way `mc ls` shows them and files are read with
+// ranged Client.Get through the block cache.
+type mountFS struct {
+	// aliased URL mounted, without trailing slash.
+	root     string
+	encKeyDB map[string][]prefixSSEPair
+	timeRef  time.Time
+	cache    *mountCache
+
+	mu   sync.Mutex
+	dirs map[string]*mountDir
+}
+
+func newMountFS(root string, encKeyDB map[string][]prefixSSEPair, timeRef time.Time, cache *mountCache) *mountFS {
+	return &mountFS{
+		root:     strings.TrimSuffix(root, "/"),
+		encKeyDB: encKeyDB,
+		timeRef:  timeRef,
+		cache:    cache,
+		dirs:     make(map[string]*mountDir),
+	}
+}
+
+// client returns a client of the key, with its encryption key if any.
+func (fs *mountFS) client(key string) (Client, encrypt.ServerSide, *probe.Error) {
+	urlStr := fs.root + "/" + key
+	clnt, err := newClient(urlStr)
+	if err != nil {
+		return nil, nil, err.Trace(urlStr)
+	}
+	alias, _ := url2Alias(urlStr)
+	return clnt, getSSE(urlStr, fs.encKeyDB[alias]), nil
+}
+
+// readDir returns the members of a folder, sorted by name.
+func (fs *mountFS) readDir(ctx context.Context, dir *mountNode) ([]*mountNode, *probe.Error) {
+	fs.mu.Lock()
+	d, ok := fs.dirs[dir.key]
+	if !ok {
+		d = &mountDir{}
+		fs.dirs[dir.key] = d
+	}
+	fs.mu.Unlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.fetched.IsZero() && (!fs.timeRef.IsZero() || time.Since(d.fetched) < mountListTTL) {
+		return d.nodes, nil
+	}
+	nodes, err := fs.list(ctx, dir.key)
+	if err != nil {
+		return nil, err
+	}
+	d.nodes, d.fetched = nodes, time.Now()
+	return nodes, nil
+}
+
+func (fs *mountFS) list(ctx context.Context, dirKey string) ([]*mountNode, *probe.Error) {
+	clnt, _, err := fs.client(dirKey)
+	if err != nil {
+		return nil, err
+	}
+	basePath := filepath.ToSlash(clnt.GetURL().Path)
+	if !strings.HasSuffix(basePath, "/") {
+		basePath += "/"
+	}
+
+	byName := make(map[string]*mountNode)
+	for content := range clnt.List(ctx, ListOptions{ShowDir: DirFirst, TimeRef: fs.timeRef}) {
+		if content.Err != nil {
+			if ctx.Err() != nil {
+				return nil, probe.NewError(ctx.Err())
+			}
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(content.URL.Path), basePath), "/")
+		if name == "" || strings.Contains(name, "/") || name == "." || name == ".." {
+			continue
+		}
+		node := &mountNode{
+			key:       dirKey + name,
+			size:      content.Size,
+			mtime:     content.Time,
+			etag:      content.ETag,
+			versionID: content.VersionID,
+		}
+		if content.Type.IsDir() {
+			node.key += "/"
+			node.size = 0
+		}
+		// An object and a prefix of the same name show as a folder.
+		if prev, ok := byName[name]; ok && prev.isDir() {
+			continue
+		}
+		byName[name] = node
+	}
+
+	nodes := make([]*mountNode, 0, len(byName))
+	for _, node := range byName {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].name() < nodes[j].name() })
+	return nodes, nil
+}
+
+// lookup returns the member name of a folder, nil if it does not exist.
+func (fs *mountFS) lookup(ctx context.Context, dir *mountNode, name string) (*mountNode, *probe.Error) {
+	nodes, err := fs.readDir(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(nodes), func(i int) bool { return nodes[i].name() >= name })
+	if i < len(nodes) && nodes[i].name() == name {
+		return nodes[i], nil
+	}
+	return nil, nil
+}
+
+// readAt reads a file at offset through the block cache.
+func (fs *mountFS) readAt(ctx context.Context, node *mountNode, p []byte, offset int64) (int, error) {
+	return fs.cache.readAt(ctx, node.id(), node.size, p, offset, func(ctx context.Context, offset, length int64) ([]byte, error) {
+		clnt, sse, err := fs.client(node.key)
+		if err != nil {
+			return nil, err.ToGoError()
+		}
+		reader, _, err := clnt.Get(ctx, GetOptions{SSE: sse, VersionID: node.versionID, RangeStart: offset})
+		if err != nil {
+			return nil, err.ToGoError()
+		}
+		defer reader.Close()
+		data := make([]byte, min(length, node.size-offset))
+		n, e := io.ReadFull(reader, data)
+		if e == io.ErrUnexpectedEOF {
+			e = nil
+		}
+		return data[:n], e
+	})
+}
+
+// mountErrorString returns the error of a 9P reply, with the strings
+// clients map to errno values.
+func mountErrorString(e error) string {
+	switch e.(type) {
+	case ObjectMissing, PathNotFound, BucketDoesNotExist, ObjectNameEmpty:
+		return p9ErrNotFound
+	case PathInsufficientPermission:
+		return p9ErrPermission
+	}
+	if errors.Is(e, context.Canceled) {
+		return p9ErrInterrupted
+	}
+	switch openstor.ToErrorResponse(e).StatusCode {
+	case http.StatusNotFound:
+		return p9ErrNotFound
+	case http.StatusForbidden:
+		return p9ErrPermission
+	}
+	return p9ErrIO
+}
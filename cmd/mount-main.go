@@ -0,0 +1,188 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	json "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var mountFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "listen",
+		Usage: "address the 9P server listens on",
+		Value: "127.0.0.1:5640",
+	},
+	&cli.StringFlag{
+		Name:  "rewind",
+		Usage: "show the objects as they were at a time in the past",
+	},
+	&cli.StringFlag{
+		Name:  "cache-dir",
+		Usage: "folder of the block cache (default: a temporary folder)",
+	},
+	&cli.StringFlag{
+		Name:  "cache-size",
+		Usage: "maximum size of the block cache",
+		Value: "1GiB",
+	},
+	&cli.StringFlag{
+		Name:  "block-size",
+		Usage: "size of the blocks read from objects and cached",
+		Value: "1MiB",
+	},
+}
+
+var mountCmd = cli.Command{
+	Name:         "mount",
+	Usage:        "serve a bucket or folder as a read-only 9P filesystem",
+	Action:       mainMount,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(append(mountFlags, encFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  TARGET is served read-only over 9P2000 by a userspace server, without FUSE,
+  and can be mounted with the 9p filesystem of Linux:
+
+    mount -t 9p -o trans=tcp,port=5640,version=9p2000,ro 127.0.0.1 /mnt/bucket
+
+  Prefixes show as folders and objects as files, as listed by 'mc ls'.
+  Listings are refreshed after a minute. Files are read in blocks with ranged
+  requests, blocks are kept in --cache-dir up to --cache-size and removed when
+  the command exits. With --rewind, the view shows the versions of the objects
+  at that time and does not change.
+
+EXAMPLES:
+  1. Serve the bucket 'logs' of alias 'myminio' and mount it on /mnt/logs.
+     {{.Prompt}} {{.HelpName}} myminio/logs
+     {{.Prompt}} sudo mount -t 9p -o trans=tcp,port=5640,version=9p2000,ro 127.0.0.1 /mnt/logs
+
+  2. Serve the objects of a bucket as they were 7 days ago.
+     {{.Prompt}} {{.HelpName}} --rewind 7d myminio/logs
+
+  3. Serve a prefix on another port with a larger cache on a local disk.
+     {{.Prompt}} {{.HelpName}} --listen 127.0.0.1:5641 --cache-dir /var/cache/mc --cache-size 20GiB myminio/logs/2022/
+`,
+}
+
+// mountMessage tells where the filesystem is served.
+type mountMessage struct {
+	Status   string    `json:"status"`
+	Endpoint string    `json:"endpoint"`
+	Target   string    `json:"target"`
+	Rewind   time.Time `json:"rewind,omitempty"`
+	CacheDir string    `json:"cacheDir"`
+	Command  string    `json:"mountCommand"`
+}
+
+func (m mountMessage) JSON() string {
+	m.Status = "success"
+	buf, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(buf)
+}
+
+func (m mountMessage) String() string {
+	msg := fmt.Sprintf("Serving `%s` read-only over 9P at %s", m.Target, console.Colorize("Endpoint", m.Endpoint))
+	if !m.Rewind.IsZero() {
+		msg += fmt.Sprintf(" as of %s", m.Rewind.Format(printDate))
+	}
+	return msg + "\nMount with: " + console.Colorize("Command", m.Command)
+}
+
+func checkMountSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+	if cmd.String("rewind") != "" && parseRewindFlag(cmd.String("rewind")).IsZero() {
+		fatalIf(errInvalidArgument().Trace(cmd.String("rewind")), "Unable to parse --rewind.")
+	}
+}
+
+// mainMount is the handle for "mc mount" command.
+func mainMount(ctx context.Context, cmd *cli.Command) error {
+	console.SetColor("Endpoint", color.New(color.FgCyan, color.Bold))
+	console.SetColor("Command", color.New(color.Bold))
+
+	checkMountSyntax(ctx, cmd)
+
+	target := cmd.Args().Get(0)
+	timeRef := parseRewindFlag(cmd.String("rewind"))
+
+	var sizes [2]int64
+	for i, name := range []string{"cache-size", "block-size"} {
+		n, e := humanize.ParseBytes(cmd.String(name))
+		fatalIf(probe.NewError(e).Trace(cmd.String(name)), "Unable to parse --%s.", name)
+		if n == 0 {
+			fatalIf(errInvalidArgument().Trace(cmd.String(name)), "--%s must be larger than zero.", name)
+		}
+		sizes[i] = int64(n)
+	}
+
+	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	clnt, err := newClient(target)
+	fatalIf(err.Trace(target), "Unable to initialize target `%s`.", target)
+	content, err := clnt.Stat(ctx, StatOptions{})
+	fatalIf(err.Trace(target), "Unable to mount `%s`.", target)
+	if !content.Type.IsDir() {
+		fatalIf(errInvalidArgument().Trace(target), "`%s` is not a bucket or a folder.", target)
+	}
+
+	cacheDir, e := os.MkdirTemp(cmd.String("cache-dir"), "mc-mount-")
+	fatalIf(probe.NewError(e), "Unable to create the block cache.")
+	defer os.RemoveAll(cacheDir)
+
+	fs := newMountFS(target, encKeyDB, timeRef, newMountCache(cacheDir, sizes[1], sizes[0]))
+
+	listener, e := net.Listen("tcp", cmd.String("listen"))
+	fatalIf(probe.NewError(e).Trace(cmd.String("listen")), "Unable to listen on `%s`.", cmd.String("listen"))
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	printMsg(mountMessage{
+		Endpoint: listener.Addr().String(),
+		Target:   target,
+		Rewind:   timeRef,
+		CacheDir: cacheDir,
+		Command:  fmt.Sprintf("mount -t 9p -o trans=tcp,port=%s,version=%s,ro %s MOUNTPOINT", port, "9p2000", host),
+	})
+
+	e = serve9P(globalContext, listener, fs)
+	fatalIf(probe.NewError(e), "Unable to serve `%s`.", target)
+	return nil
+}
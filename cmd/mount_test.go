@@ -0,0 +1,233 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// p9TestClient sends requests one at a time.
+type p9TestClient struct {
+	t    *testing.T
+	conn net.Conn
+}
+
+func (c *p9TestClient) rpc(typ uint8, body func(e *p9Encoder)) (uint8, *p9Decoder) {
+	c.t.Helper()
+	var e p9Encoder
+	e.u32(0)
+	e.u8(typ)
+	e.u16(1)
+	if body != nil {
+		body(&e)
+	}
+	binary.LittleEndian.PutUint32(e.buf, uint32(len(e.buf)))
+	if _, err := c.conn.Write(e.buf); err != nil {
+		c.t.Fatal(err)
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(c.conn, size[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	msg := make([]byte, binary.LittleEndian.Uint32(size[:])-4)
+	if _, err := io.ReadFull(c.conn, msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg[0], &p9Decoder{buf: msg[3:]}
+}
+
+func (c *p9TestClient) expect(typ uint8, body func(e *p9Encoder)) *p9Decoder {
+	c.t.Helper()
+	rtyp, d := c.rpc(typ, body)
+	if rtyp != typ+1 {
+		c.t.Fatalf("request %d: got reply %d %q", typ, rtyp, d.str())
+	}
+	return d
+}
+
+func (c *p9TestClient) expectError(typ uint8, body func(e *p9Encoder), ename string) {
+	c.t.Helper()
+	rtyp, d := c.rpc(typ, body)
+	if got := d.str(); rtyp != p9Rerror || got != ename {
+		c.t.Fatalf("request %d: expected error %q, got reply %d %q", typ, ename, rtyp, got)
+	}
+}
+
+func (c *p9TestClient) walk(fid, newFid uint32, names ...string) *p9Decoder {
+	return c.expect(p9Twalk, func(e *p9Encoder) {
+		e.u32(fid)
+		e.u32(newFid)
+		e.u16(uint16(len(names)))
+		for _, name := range names {
+			e.str(name)
+		}
+	})
+}
+
+func TestMount9P(t *testing.T) {
+	root := newServeTestRoot(t)
+	if e := os.WriteFile(filepath.Join(root, "big"), bytes.Repeat([]byte("0123456789"), 100), 0o644); e != nil {
+		t.Fatal(e)
+	}
+	fs := newMountFS(root, nil, time.Time{}, newMountCache(t.TempDir(), 64, 1<<20))
+
+	listener, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go serve9P(ctx, listener, fs)
+
+	conn, e := net.Dial("tcp", listener.Addr().String())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer conn.Close()
+	c := &p9TestClient{t: t, conn: conn}
+
+	d := c.expect(p9Tversion, func(e *p9Encoder) {
+		e.u32(8192)
+		e.str("9P2000.L")
+	})
+	if msize, version := d.u32(), d.str(); msize != 8192 || version != p9Version {
+		t.Fatalf("Tversion: got %d %q", msize, version)
+	}
+	c.expect(p9Tattach, func(e *p9Encoder) {
+		e.u32(0)
+		e.u32(^uint32(0))
+		e.str("user")
+		e.str("")
+	})
+
+	// Walk to a file and read a range crossing blocks.
+	if n := c.walk(0, 1, "docs", "a.txt").u16(); n != 2 {
+		t.Fatalf("Twalk: got %d qids", n)
+	}
+	c.expectError(p9Topen, func(e *p9Encoder) {
+		e.u32(1)
+		e.u8(1) // OWRITE
+	}, p9ErrReadOnly)
+	c.expect(p9Topen, func(e *p9Encoder) {
+		e.u32(1)
+		e.u8(p9ORead)
+	})
+	d = c.expect(p9Tread, func(e *p9Encoder) {
+		e.u32(1)
+		e.u64(3)
+		e.u32(4)
+	})
+	if data := d.next(int(d.u32())); string(data) != "3456" {
+		t.Fatalf("Tread: got %q", data)
+	}
+
+	c.walk(0, 2, "big")
+	c.expect(p9Topen, func(e *p9Encoder) {
+		e.u32(2)
+		e.u8(p9ORead)
+	})
+	d = c.expect(p9Tread, func(e *p9Encoder) {
+		e.u32(2)
+		e.u64(60)
+		e.u32(10)
+	})
+	if data := d.next(int(d.u32())); string(data) != "0123456789" {
+		t.Fatalf("Tread across blocks: got %q", data)
+	}
+
+	// Walk errors.
+	c.expectError(p9Twalk, func(e *p9Encoder) {
+		e.u32(0)
+		e.u32(3)
+		e.u16(1)
+		e.str("missing")
+	}, p9ErrNotFound)
+	if n := c.walk(0, 3, "docs", "missing").u16(); n != 1 {
+		t.Fatalf("partial Twalk: got %d qids", n)
+	}
+	c.expectError(p9Tstat, func(e *p9Encoder) { e.u32(3) }, p9ErrBadFid)
+
+	// Read the root folder.
+	c.walk(0, 4)
+	c.expect(p9Topen, func(e *p9Encoder) {
+		e.u32(4)
+		e.u8(p9ORead)
+	})
+	d = c.expect(p9Tread, func(e *p9Encoder) {
+		e.u32(4)
+		e.u64(0)
+		e.u32(8192)
+	})
+	entries := &p9Decoder{buf: d.next(int(d.u32()))}
+	var names []string
+	var modes []uint32
+	for len(entries.buf) > 0 {
+		entries.u16() // size
+		entries.u16() // type
+		entries.u32() // dev
+		entries.next(13)
+		modes = append(modes, entries.u32())
+		entries.u32() // atime
+		entries.u32() // mtime
+		entries.u64() // length
+		names = append(names, entries.str())
+		entries.str()
+		entries.str()
+		entries.str()
+	}
+	if len(names) != 2 || names[0] != "big" || names[1] != "docs" || modes[0]&p9DMDir != 0 || modes[1]&p9DMDir == 0 {
+		t.Fatalf("folder read: got %v %x", names, modes)
+	}
+
+	c.expectError(p9Tremove, func(e *p9Encoder) { e.u32(1) }, p9ErrReadOnly)
+	c.expectError(p9Tclunk, func(e *p9Encoder) { e.u32(1) }, p9ErrBadFid)
+	c.expect(p9Tclunk, func(e *p9Encoder) { e.u32(2) })
+}
+
+func TestMountCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	cache := newMountCache(dir, 4, 8)
+	var fetches int
+	fetch := func(_ context.Context, offset, length int64) ([]byte, error) {
+		fetches++
+		return bytes.Repeat([]byte{byte('a' + offset/4)}, int(length)), nil
+	}
+
+	p := make([]byte, 12)
+	if n, e := cache.readAt(context.Background(), "obj", 12, p, 0, fetch); e != nil || n != 12 || string(p) != "aaaabbbbcccc" {
+		t.Fatalf("got %d %q %v", n, p, e)
+	}
+	if fetches != 3 || cache.size != 8 || cache.lru.Len() != 2 {
+		t.Fatalf("expected 3 fetches and 2 cached blocks, got %d, %d, %d bytes", fetches, cache.lru.Len(), cache.size)
+	}
+	// The last blocks are cached, the first one was evicted.
+	if _, e := cache.readAt(context.Background(), "obj", 12, p[:8], 4, fetch); e != nil || fetches != 3 {
+		t.Fatalf("expected cached blocks, got %d fetches, %v", fetches, e)
+	}
+	if _, e := cache.readAt(context.Background(), "obj", 12, p[:4], 0, fetch); e != nil || fetches != 4 {
+		t.Fatalf("expected the evicted block to be fetched, got %d fetches, %v", fetches, e)
+	}
+}
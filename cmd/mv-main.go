@@ -74,7 +74,7 @@ var mvCmd = cli.Command{
 	Action:       mainMove,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(append(mvFlags, encFlags...), globalFlags...),
+	Flags:        append(append(append(mvFlags, encFlags...), encClientFlags...), globalFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -232,7 +232,7 @@ func mainMove(ctx context.Context, cmd *cli.Command) error {
 	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
 	fatalIf(err, "Unable to parse encryption keys.")
 
-	e := doCopySession(ctx, cancelMove, cmd, encKeyDB, true)
+	e := doCopySession(ctx, cancelMove, cmd, cmd.Args().Slice(), encKeyDB, true, nil)
 
 	console.Colorize("Copy", "Waiting for move operations to complete")
 	rmManager.close()
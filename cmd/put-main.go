@@ -65,7 +65,7 @@ var putCmd = cli.Command{
 	Action:       mainPut,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(append(encFlags, globalFlags...), putFlags...),
+	Flags:        append(append(append(encFlags, encClientFlags...), globalFlags...), putFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -79,6 +79,8 @@ FLAGS:
 ENVIRONMENT VARIABLES:
   MC_ENC_KMS: KMS encryption key in the form of (alias/prefix=key).
   MC_ENC_S3: S3 encryption key in the form of (alias/prefix=key).
+  MC_ENC_CLIENT_KEY: path of the key file used to encrypt objects on the client.
+  MC_ENC_CLIENT_PASSPHRASE: passphrase used to encrypt objects on the client.
 
 EXAMPLES:
   1. Put an object from local file system to S3 storage
@@ -98,6 +100,9 @@ EXAMPLES:
 
   6. Put an object to MinIO storage and assign REDUCED_REDUNDANCY storage-class to the uploaded object.
       {{.Prompt}} {{.HelpName}} --storage-class REDUCED_REDUNDANCY myobject.txt play/mybucket
+
+  7. Put an object to MinIO storage encrypted on the client with the key stored in a file.
+      {{.Prompt}} {{.HelpName}} --enc-client-key ~/.mc/backup.key path-to/object play/mybucket/object
 `,
 }
 
@@ -137,6 +142,9 @@ func mainPut(ctx context.Context, cmd *cli.Command) (e error) {
 	fatalIf(err, "SSE Error")
 	md5, checksum := parseChecksum(cmd)
 
+	cse, err := parseClientEncryption(cmd)
+	fatalIf(err, "Unable to parse client-side encryption keys.")
+
 	if args.Len() < 2 {
 		fatalIf(errInvalidArgument().Trace(args.Slice()...), "Invalid number of arguments.")
 	}
@@ -208,6 +216,7 @@ func mainPut(ctx context.Context, cmd *cli.Command) (e error) {
 				multipartSize:    size,
 				multipartThreads: strconv.Itoa(threads),
 				ifNotExists:      cmd.Bool("if-not-exists"),
+				cse:              cse,
 			})
 			if urls.Error != nil {
 				showLastProgressBar(pg, urls.Error.ToGoError())
@@ -0,0 +1,347 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/openstor-go/v7"
+	"github.com/openstor/openstor-go/v7/pkg/encrypt"
+)
+
+var (
+	errServeReadOnly     = errors.New("read-only")
+	errServeInvalidKey   = errors.New("invalid object name")
+	errServeInvalidRange = errors.New("invalid range")
+)
+
+// serveBackend proxies the requests of `mc serve` to the served alias
+// through the Client interface, with the encryption keys of the command.
+type serveBackend struct {
+	// aliased URL served, e.g. play/bucket or /data, without trailing slash.
+	root     string
+	encKeyDB map[string][]prefixSSEPair
+	readOnly bool
+}
+
+// serveEntry - an object or a folder, key of folders ends with "/".
+type serveEntry struct {
+	Key         string
+	Size        int64
+	Time        time.Time
+	ETag        string
+	ContentType string
+}
+
+// IsDir returns true for folders.
+func (e serveEntry) IsDir() bool {
+	return e.Key == "" || strings.HasSuffix(e.Key, "/")
+}
+
+func newServeBackend(root string, encKeyDB map[string][]prefixSSEPair, readOnly bool) *serveBackend {
+	return &serveBackend{
+		root:     strings.TrimSuffix(root, "/"),
+		encKeyDB: encKeyDB,
+		readOnly: readOnly,
+	}
+}
+
+// cleanServeKey validates a key received by the endpoint, a trailing
+// slash is kept since it denotes a folder. Backslashes are rejected, a
+// local folder served on Windows would take them as path separators.
+func cleanServeKey(key string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if strings.ContainsRune(key, '\\') {
+		return "", errServeInvalidKey
+	}
+	for _, elem := range strings.Split(key, "/") {
+		if elem == "." || elem == ".." || strings.ContainsRune(elem, 0) {
+			return "", errServeInvalidKey
+		}
+	}
+	return key, nil
+}
+
+// client returns a client of the key, with its encryption key if any.
+func (b *serveBackend) client(key string) (Client, encrypt.ServerSide, *probe.Error) {
+	k, e := cleanServeKey(key)
+	if e != nil {
+		return nil, nil, probe.NewError(e).Trace(key)
+	}
+	urlStr := b.root + "/" + k
+	clnt, err := newClient(urlStr)
+	if err != nil {
+		return nil, nil, err.Trace(urlStr)
+	}
+	alias, _ := url2Alias(urlStr)
+	return clnt, getSSE(urlStr, b.encKeyDB[alias]), nil
+}
+
+func (b *serveBackend) entry(key string, content *ClientContent) serveEntry {
+	entry := serveEntry{
+		Key:  key,
+		Size: content.Size,
+		Time: content.Time,
+		ETag: content.ETag,
+	}
+	if content.Type.IsDir() && !entry.IsDir() {
+		entry.Key += "/"
+	}
+	if entry.IsDir() {
+		entry.Size = 0
+	} else if ct := content.Metadata["Content-Type"]; ct != "" {
+		entry.ContentType = ct
+	} else {
+		entry.ContentType = guessURLContentType(key)
+	}
+	return entry
+}
+
+// Stat returns the object or folder of key.
+func (b *serveBackend) Stat(ctx context.Context, key string) (serveEntry, *probe.Error) {
+	clnt, sse, err := b.client(key)
+	if err != nil {
+		return serveEntry{}, err
+	}
+	content, err := clnt.Stat(ctx, StatOptions{sse: sse})
+	if err != nil {
+		return serveEntry{}, err.Trace(key)
+	}
+	return b.entry(strings.TrimPrefix(key, "/"), content), nil
+}
+
+// List lists the objects and folders under the folder prefix, keys are
+// relative to the served root.
+func (b *serveBackend) List(ctx context.Context, prefix string, recursive bool) (<-chan serveEntry, *probe.Error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	clnt, _, err := b.client(prefix)
+	if err != nil {
+		return nil, err
+	}
+	base := clnt.GetURL()
+	basePath := filepath.ToSlash(base.Path)
+	if !strings.HasSuffix(basePath, "/") {
+		basePath += "/"
+	}
+
+	entriesCh := make(chan serveEntry)
+	go func() {
+		defer close(entriesCh)
+		for content := range clnt.List(ctx, ListOptions{Recursive: recursive, ShowDir: DirFirst, WithMetadata: false}) {
+			if content.Err != nil {
+				continue
+			}
+			rel := strings.TrimPrefix(filepath.ToSlash(content.URL.Path), basePath)
+			if rel == "" || rel == "/" {
+				continue
+			}
+			if recursive && content.Type.IsDir() {
+				continue
+			}
+			select {
+			case entriesCh <- b.entry(prefix+rel, content):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return entriesCh, nil
+}
+
+// Get returns length bytes of the object starting at offset, length
+// is ignored if negative.
+func (b *serveBackend) Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, *probe.Error) {
+	clnt, sse, err := b.client(key)
+	if err != nil {
+		return nil, err
+	}
+	reader, _, err := clnt.Get(ctx, GetOptions{SSE: sse, RangeStart: offset})
+	if err != nil {
+		return nil, err.Trace(key)
+	}
+	if length < 0 {
+		return reader, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(reader, length), reader}, nil
+}
+
+// Put uploads an object, size is -1 if unknown.
+func (b *serveBackend) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) *probe.Error {
+	if b.readOnly {
+		return probe.NewError(errServeReadOnly).Trace(key)
+	}
+	clnt, sse, err := b.client(key)
+	if err != nil {
+		return err
+	}
+	metadata := map[string]string{}
+	if contentType != "" {
+		metadata["Content-Type"] = contentType
+	}
+	_, err = clnt.Put(ctx, reader, size, nil, PutOptions{metadata: metadata, sse: sse})
+	return err.Trace(key)
+}
+
+// MakeDir creates a folder, an empty object with a trailing slash on
+// object storage.
+func (b *serveBackend) MakeDir(ctx context.Context, key string) *probe.Error {
+	if b.readOnly {
+		return probe.NewError(errServeReadOnly).Trace(key)
+	}
+	key = strings.TrimSuffix(key, "/") + "/"
+	clnt, _, err := b.client(key)
+	if err != nil {
+		return err
+	}
+	if clnt.GetURL().Type == fileSystem {
+		return clnt.MakeBucket(ctx, "", true, false).Trace(key)
+	}
+	_, err = clnt.Put(ctx, strings.NewReader(""), 0, nil, PutOptions{})
+	return err.Trace(key)
+}
+
+// Remove removes an object, or all objects of a folder if the key ends
+// with a slash.
+func (b *serveBackend) Remove(ctx context.Context, key string) *probe.Error {
+	if b.readOnly {
+		return probe.NewError(errServeReadOnly).Trace(key)
+	}
+	clnt, _, err := b.client(key)
+	if err != nil {
+		return err
+	}
+
+	contentCh := make(chan *ClientContent)
+	go func() {
+		defer close(contentCh)
+		if !strings.HasSuffix(key, "/") {
+			contentCh <- &ClientContent{URL: clnt.GetURL()}
+			return
+		}
+		for content := range clnt.List(ctx, ListOptions{Recursive: true, ShowDir: DirLast}) {
+			if content.Err != nil {
+				continue
+			}
+			select {
+			case contentCh <- content:
+			case <-ctx.Done():
+				return
+			}
+		}
+		// The folder itself, ignored by object storage if it has no marker.
+		contentCh <- &ClientContent{URL: clnt.GetURL()}
+	}()
+
+	var rerr *probe.Error
+	for result := range clnt.Remove(ctx, false, false, false, false, contentCh) {
+		if result.Err != nil && rerr == nil {
+			if _, ok := result.Err.ToGoError().(ObjectMissing); !ok || !strings.HasSuffix(key, "/") {
+				rerr = result.Err.Trace(key)
+			}
+		}
+	}
+	return rerr
+}
+
+// serveHTTPStatus maps errors of the backend to HTTP status codes.
+func serveHTTPStatus(err *probe.Error) int {
+	switch e := err.ToGoError(); e.(type) {
+	case ObjectMissing, PathNotFound, BucketDoesNotExist, ObjectNameEmpty:
+		return http.StatusNotFound
+	case PathInsufficientPermission:
+		return http.StatusForbidden
+	default:
+		switch {
+		case errors.Is(e, errServeReadOnly):
+			return http.StatusForbidden
+		case errors.Is(e, errServeInvalidKey):
+			return http.StatusBadRequest
+		}
+		if errResp := openstor.ToErrorResponse(e); errResp.StatusCode != 0 {
+			return errResp.StatusCode
+		}
+		return http.StatusInternalServerError
+	}
+}
+
+// parseServeRange parses a single range of a Range header, multiple
+// ranges are not supported and ignored as allowed by RFC 7233.
+func parseServeRange(header string, size int64) (offset, length int64, ok bool, e error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, nil
+	}
+	switch {
+	case first == "":
+		n, e := strconv.ParseInt(last, 10, 64)
+		if e != nil || n <= 0 {
+			return 0, 0, false, errServeInvalidRange
+		}
+		n = min(n, size)
+		return size - n, n, true, nil
+	default:
+		start, e := strconv.ParseInt(first, 10, 64)
+		if e != nil || start < 0 || start >= size {
+			return 0, 0, false, errServeInvalidRange
+		}
+		end := size - 1
+		if last != "" {
+			if end, e = strconv.ParseInt(last, 10, 64); e != nil || end < start {
+				return 0, 0, false, errServeInvalidRange
+			}
+			end = min(end, size-1)
+		}
+		return start, end - start + 1, true, nil
+	}
+}
+
+// serveETag quotes an ETag for HTTP headers.
+func serveETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) {
+		return etag
+	}
+	return strconv.Quote(etag)
+}
+
+// serveKeyPath returns the URL path of a key.
+func serveKeyPath(prefix, key string) string {
+	p := path.Join("/", prefix, key)
+	if strings.HasSuffix(key, "/") {
+		p += "/"
+	}
+	return (&url.URL{Path: p}).EscapedPath()
+}
@@ -0,0 +1,342 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	csubtle "crypto/subtle"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/openstor/mc/pkg/probe"
+)
+
+// serveHTTPHandler serves the objects over plain HTTP, or over WebDAV
+// (class 1, without locks) which adds folder listings and creation.
+type serveHTTPHandler struct {
+	backend *serveBackend
+	webdav  bool
+}
+
+func (h *serveHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, e := cleanServeKey(r.URL.Path)
+	if e != nil {
+		http.Error(w, e.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.get(w, r, key)
+	case http.MethodPut:
+		h.put(w, r, key)
+	case http.MethodDelete:
+		h.delete(w, r, key)
+	case http.MethodOptions:
+		w.Header().Set("Allow", h.allow())
+		if h.webdav {
+			w.Header().Set("DAV", "1")
+		}
+		w.WriteHeader(http.StatusOK)
+	case "PROPFIND":
+		if !h.webdav {
+			h.notAllowed(w)
+			return
+		}
+		h.propfind(w, r, key)
+	case "MKCOL":
+		if !h.webdav {
+			h.notAllowed(w)
+			return
+		}
+		h.mkcol(w, r, key)
+	default:
+		h.notAllowed(w)
+	}
+}
+
+func (h *serveHTTPHandler) allow() string {
+	methods := "OPTIONS, GET, HEAD"
+	if !h.backend.readOnly {
+		methods += ", PUT, DELETE"
+	}
+	if h.webdav {
+		methods += ", PROPFIND"
+		if !h.backend.readOnly {
+			methods += ", MKCOL"
+		}
+	}
+	return methods
+}
+
+func (h *serveHTTPHandler) notAllowed(w http.ResponseWriter) {
+	w.Header().Set("Allow", h.allow())
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+func (h *serveHTTPHandler) error(w http.ResponseWriter, err *probe.Error) {
+	status := serveHTTPStatus(err)
+	http.Error(w, http.StatusText(status), status)
+}
+
+func (h *serveHTTPHandler) get(w http.ResponseWriter, r *http.Request, key string) {
+	if key == "" || strings.HasSuffix(key, "/") {
+		h.index(w, r, key)
+		return
+	}
+	entry, err := h.backend.Stat(r.Context(), key)
+	if err != nil {
+		h.error(w, err)
+		return
+	}
+	if entry.IsDir() {
+		http.Redirect(w, r, serveKeyPath("", key+"/"), http.StatusMovedPermanently)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Accept-Ranges", "bytes")
+	header.Set("Content-Type", entry.ContentType)
+	header.Set("Last-Modified", entry.Time.UTC().Format(http.TimeFormat))
+	if entry.ETag != "" {
+		header.Set("ETag", serveETag(entry.ETag))
+	}
+
+	offset, length, status := int64(0), entry.Size, http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		start, n, ok, e := parseServeRange(rangeHeader, entry.Size)
+		if e != nil {
+			header.Set("Content-Range", fmt.Sprintf("bytes */%d", entry.Size))
+			http.Error(w, http.StatusText(http.StatusRequestedRangeNotSatisfiable), http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if ok {
+			offset, length, status = start, n, http.StatusPartialContent
+			header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+n-1, entry.Size))
+		}
+	}
+	header.Set("Content-Length", strconv.FormatInt(length, 10))
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+
+	reader, err := h.backend.Get(r.Context(), key, offset, length)
+	if err != nil {
+		header.Del("Content-Length")
+		h.error(w, err)
+		return
+	}
+	defer reader.Close()
+	w.WriteHeader(status)
+	io.Copy(w, reader)
+}
+
+var serveIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Index of /{{.Prefix}}</title></head>
+<body>
+<h1>Index of /{{.Prefix}}</h1>
+<table>
+{{if .Prefix}}<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{end}}{{range .Entries}}<tr><td><a href="{{.Href}}">{{.Name}}</a></td><td>{{.Size}}</td><td>{{.Time}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// index lists a folder as HTML.
+func (h *serveHTTPHandler) index(w http.ResponseWriter, r *http.Request, prefix string) {
+	entriesCh, err := h.backend.List(r.Context(), prefix, false)
+	if err != nil {
+		h.error(w, err)
+		return
+	}
+	type indexEntry struct {
+		Href, Name, Size, Time string
+	}
+	var entries []indexEntry
+	for entry := range entriesCh {
+		name := strings.TrimPrefix(entry.Key, prefix)
+		e := indexEntry{Href: serveKeyPath("", entry.Key), Name: name}
+		if !entry.IsDir() {
+			e.Size = strconv.FormatInt(entry.Size, 10)
+			e.Time = entry.Time.UTC().Format(http.TimeFormat)
+		}
+		entries = append(entries, e)
+	}
+	if entries == nil && prefix != "" {
+		// Folders only exist as long as they contain objects.
+		if _, err := h.backend.Stat(r.Context(), prefix); err != nil {
+			h.error(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	serveIndexTemplate.Execute(w, struct {
+		Prefix  string
+		Entries []indexEntry
+	}{prefix, entries})
+}
+
+func (h *serveHTTPHandler) put(w http.ResponseWriter, r *http.Request, key string) {
+	if key == "" || strings.HasSuffix(key, "/") {
+		http.Error(w, "cannot upload to a folder", http.StatusBadRequest)
+		return
+	}
+	if err := h.backend.Put(r.Context(), key, r.Body, r.ContentLength, r.Header.Get("Content-Type")); err != nil {
+		h.error(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *serveHTTPHandler) delete(w http.ResponseWriter, r *http.Request, key string) {
+	if key == "" {
+		http.Error(w, "cannot remove the served folder", http.StatusForbidden)
+		return
+	}
+	if h.webdav && !strings.HasSuffix(key, "/") {
+		// Collections are removed with their members.
+		if entry, err := h.backend.Stat(r.Context(), key); err == nil && entry.IsDir() {
+			key += "/"
+		}
+	}
+	if err := h.backend.Remove(r.Context(), key); err != nil {
+		h.error(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *serveHTTPHandler) mkcol(w http.ResponseWriter, r *http.Request, key string) {
+	if r.ContentLength > 0 {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+	if _, err := h.backend.Stat(r.Context(), key); err == nil {
+		h.notAllowed(w)
+		return
+	}
+	if err := h.backend.MakeDir(r.Context(), key); err != nil {
+		h.error(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// WebDAV multistatus response of PROPFIND.
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"D:multistatus"`
+	XMLNS     string        `xml:"xmlns:D,attr"`
+	Responses []davResponse `xml:"D:response"`
+}
+
+type davResponse struct {
+	Href     string      `xml:"D:href"`
+	Propstat davPropstat `xml:"D:propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"D:prop"`
+	Status string  `xml:"D:status"`
+}
+
+type davProp struct {
+	DisplayName   string          `xml:"D:displayname"`
+	ResourceType  davResourceType `xml:"D:resourcetype"`
+	ContentLength *int64          `xml:"D:getcontentlength,omitempty"`
+	LastModified  string          `xml:"D:getlastmodified,omitempty"`
+	ContentType   string          `xml:"D:getcontenttype,omitempty"`
+	ETag          string          `xml:"D:getetag,omitempty"`
+}
+
+type davResourceType struct {
+	Collection *struct{} `xml:"D:collection,omitempty"`
+}
+
+func newDavResponse(entry serveEntry) davResponse {
+	prop := davProp{DisplayName: path.Base("/" + strings.TrimSuffix(entry.Key, "/"))}
+	if !entry.Time.IsZero() {
+		prop.LastModified = entry.Time.UTC().Format(http.TimeFormat)
+	}
+	if entry.IsDir() {
+		prop.ResourceType.Collection = &struct{}{}
+	} else {
+		size := entry.Size
+		prop.ContentLength = &size
+		prop.ContentType = entry.ContentType
+		prop.ETag = serveETag(entry.ETag)
+	}
+	return davResponse{
+		Href: serveKeyPath("", entry.Key),
+		Propstat: davPropstat{
+			Prop:   prop,
+			Status: "HTTP/1.1 200 OK",
+		},
+	}
+}
+
+// propfind returns all properties of a resource and of its members with
+// depth 1, infinite depth is served as depth 1.
+func (h *serveHTTPHandler) propfind(w http.ResponseWriter, r *http.Request, key string) {
+	entry, err := h.backend.Stat(r.Context(), key)
+	if err != nil {
+		h.error(w, err)
+		return
+	}
+	ms := davMultistatus{XMLNS: "DAV:", Responses: []davResponse{newDavResponse(entry)}}
+	if entry.IsDir() && r.Header.Get("Depth") != "0" {
+		entriesCh, err := h.backend.List(r.Context(), entry.Key, false)
+		if err != nil {
+			h.error(w, err)
+			return
+		}
+		for member := range entriesCh {
+			ms.Responses = append(ms.Responses, newDavResponse(member))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(ms)
+}
+
+// serveBasicAuth requires the credentials with HTTP basic authentication.
+func serveBasicAuth(handler http.Handler, user, password string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok || csubtle.ConstantTimeCompare([]byte(u), []byte(user)) != 1 ||
+			csubtle.ConstantTimeCompare([]byte(p), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="mc serve", charset="UTF-8"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,245 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	json "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/openstor/pkg/v3/env"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	serveProtocolWebDAV = "webdav"
+	serveProtocolHTTP   = "http"
+	serveProtocolS3     = "s3"
+)
+
+var serveFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "listen",
+		Usage: "address the endpoint listens on",
+		Value: "127.0.0.1:8080",
+	},
+	&cli.StringFlag{
+		Name:  "protocol",
+		Usage: "protocol of the endpoint, \"webdav\", \"http\" or \"s3\"",
+		Value: serveProtocolWebDAV,
+	},
+	&cli.StringFlag{
+		Name:  "access-key",
+		Usage: "user of basic authentication, or access key with --protocol s3 (default: generated)",
+	},
+	&cli.StringFlag{
+		Name:  "secret-key",
+		Usage: "password of basic authentication, or secret key with --protocol s3 (default: generated)",
+	},
+	&cli.BoolFlag{
+		Name:  "read-only",
+		Usage: "reject requests which modify objects",
+	},
+}
+
+var serveCmd = cli.Command{
+	Name:         "serve",
+	Usage:        "expose a bucket or folder over a local WebDAV, HTTP or S3 endpoint",
+	Action:       mainServe,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(append(serveFlags, encFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+PROTOCOLS:
+  webdav: WebDAV class 1, without locks, for file managers and legacy tools.
+  http:   plain HTTP, GET and HEAD with ranges, folder indexes, PUT and DELETE.
+  s3:     S3 API of a single bucket, named after the last element of TARGET,
+          with path-style requests signed with signature V4.
+
+  Requests are proxied to TARGET with the credentials, encryption keys and
+  settings of its alias. The endpoint requires HTTP basic authentication, or
+  signed requests with --protocol s3, with --access-key and --secret-key.
+  Credentials are generated and printed when they are not provided.
+
+ENVIRONMENT VARIABLES:
+  MC_SERVE_ACCESS_KEY: user or access key, instead of --access-key.
+  MC_SERVE_SECRET_KEY: password or secret key, instead of --secret-key.
+
+EXAMPLES:
+  1. Serve the bucket 'photos' of alias 'myminio' over WebDAV on port 8080.
+     {{.Prompt}} {{.HelpName}} myminio/photos
+
+  2. Serve a bucket read-only over plain HTTP on all interfaces, with known credentials.
+     {{.Prompt}} {{.HelpName}} --protocol http --listen :8080 --read-only --access-key reader --secret-key secret123 myminio/photos
+
+  3. Serve a bucket encrypted with SSE-C as a local S3 endpoint.
+     {{.Prompt}} {{.HelpName}} --protocol s3 --listen 127.0.0.1:9900 --enc-c "myminio/photos/=MzJieXRlc2xvbmdzZWNyZWFrZXltdXN0cHJvdmlkZWQ" myminio/photos
+`,
+}
+
+// serveMessage tells where the endpoint listens.
+type serveMessage struct {
+	Status    string `json:"status"`
+	Endpoint  string `json:"endpoint"`
+	Protocol  string `json:"protocol"`
+	Target    string `json:"target"`
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+	ReadOnly  bool   `json:"readOnly"`
+	Bucket    string `json:"bucket,omitempty"`
+}
+
+func (s serveMessage) JSON() string {
+	s.Status = "success"
+	buf, e := json.MarshalIndent(s, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(buf)
+}
+
+func (s serveMessage) String() string {
+	var b strings.Builder
+	mode := ""
+	if s.ReadOnly {
+		mode = " read-only"
+	}
+	fmt.Fprintf(&b, "Serving `%s`%s over %s at %s\n", s.Target, mode, s.Protocol, console.Colorize("Endpoint", s.Endpoint))
+	if s.Bucket != "" {
+		fmt.Fprintf(&b, "Bucket: %s\n", s.Bucket)
+	}
+	fmt.Fprintf(&b, "Access Key: %s\n", console.Colorize("Credentials", s.AccessKey))
+	fmt.Fprintf(&b, "Secret Key: %s", console.Colorize("Credentials", s.SecretKey))
+	return b.String()
+}
+
+func checkServeSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() != 1 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+	switch cmd.String("protocol") {
+	case serveProtocolWebDAV, serveProtocolHTTP, serveProtocolS3:
+	default:
+		fatalIf(errInvalidArgument().Trace(cmd.String("protocol")), "Unknown --protocol, use \"webdav\", \"http\" or \"s3\".")
+	}
+}
+
+// serveCredentials returns the credentials of the flags or of the
+// environment, generated ones if none are provided.
+func serveCredentials(cmd *cli.Command) (accessKey, secretKey string) {
+	accessKey = cmd.String("access-key")
+	if accessKey == "" {
+		accessKey = env.Get("MC_SERVE_ACCESS_KEY", "")
+	}
+	secretKey = cmd.String("secret-key")
+	if secretKey == "" {
+		secretKey = env.Get("MC_SERVE_SECRET_KEY", "")
+	}
+	if (accessKey == "") != (secretKey == "") {
+		fatalIf(errInvalidArgument(), "--access-key and --secret-key must be provided together.")
+	}
+	if accessKey == "" {
+		var err *probe.Error
+		accessKey, secretKey, err = generateCredentials()
+		fatalIf(err, "Unable to generate credentials.")
+	}
+	return accessKey, secretKey
+}
+
+// mainServe is the handle for "mc serve" command.
+func mainServe(ctx context.Context, cmd *cli.Command) error {
+	console.SetColor("Endpoint", color.New(color.FgCyan, color.Bold))
+	console.SetColor("Credentials", color.New(color.FgGreen))
+
+	checkServeSyntax(ctx, cmd)
+
+	target := strings.TrimSuffix(cmd.Args().Get(0), "/")
+	protocol := cmd.String("protocol")
+
+	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	backend := newServeBackend(target, encKeyDB, cmd.Bool("read-only"))
+	_, err = backend.Stat(ctx, "")
+	fatalIf(err.Trace(target), "Unable to serve `%s`.", target)
+
+	accessKey, secretKey := serveCredentials(cmd)
+
+	msg := serveMessage{
+		Protocol:  protocol,
+		Target:    target,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		ReadOnly:  backend.readOnly,
+	}
+
+	var handler http.Handler
+	switch protocol {
+	case serveProtocolS3:
+		uploads := newServeMultipart()
+		defer uploads.Close()
+		msg.Bucket = path.Base(target)
+		handler = &serveS3Handler{
+			backend: backend,
+			bucket:  msg.Bucket,
+			auth:    &serveSigV4{accessKey: accessKey, secretKey: secretKey, now: time.Now},
+			uploads: uploads,
+		}
+	default:
+		handler = serveBasicAuth(&serveHTTPHandler{
+			backend: backend,
+			webdav:  protocol == serveProtocolWebDAV,
+		}, accessKey, secretKey)
+	}
+
+	listener, e := net.Listen("tcp", cmd.String("listen"))
+	fatalIf(probe.NewError(e).Trace(cmd.String("listen")), "Unable to listen on `%s`.", cmd.String("listen"))
+
+	msg.Endpoint = "http://" + listener.Addr().String()
+	printMsg(msg)
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: time.Minute,
+	}
+	go func() {
+		<-globalContext.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	if e = server.Serve(listener); !errors.Is(e, http.ErrServerClosed) {
+		fatalIf(probe.NewError(e), "Unable to serve `%s`.", target)
+	}
+	return nil
+}
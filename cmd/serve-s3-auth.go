@@ -0,0 +1,397 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openstor/openstor-go/v7/pkg/s3utils"
+)
+
+const (
+	serveSignV4Algorithm          = "AWS4-HMAC-SHA256"
+	serveSignV4ChunkAlgorithm     = "AWS4-HMAC-SHA256-PAYLOAD"
+	serveUnsignedPayload          = "UNSIGNED-PAYLOAD"
+	serveStreamingPayload         = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	serveStreamingPayloadTrailer  = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	serveStreamingUnsignedTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+	serveEmptySHA256              = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	serveAmzDateFormat            = "20060102T150405Z"
+
+	// Maximum difference between the clocks of the client and mc.
+	serveMaxClockSkew = 15 * time.Minute
+)
+
+// serveSigV4 verifies AWS signature V4 of requests signed with a single
+// key pair, in the Authorization header or presigned.
+type serveSigV4 struct {
+	accessKey string
+	secretKey string
+	now       func() time.Time
+}
+
+// serveV4Request - signature fields of a request.
+type serveV4Request struct {
+	accessKey     string
+	date          time.Time
+	scope         string
+	region        string
+	service       string
+	signedHeaders []string
+	signature     string
+	payload       string
+}
+
+func serveHMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func serveSHA256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *serveSigV4) signingKey(v serveV4Request) []byte {
+	key := serveHMAC([]byte("AWS4"+s.secretKey), v.date.Format("20060102"))
+	key = serveHMAC(key, v.region)
+	key = serveHMAC(key, v.service)
+	return serveHMAC(key, "aws4_request")
+}
+
+// parseCredential parses "AK/20220101/us-east-1/s3/aws4_request".
+func (v *serveV4Request) parseCredential(credential string) *serveS3Error {
+	fields := strings.Split(credential, "/")
+	if len(fields) != 5 || fields[4] != "aws4_request" {
+		return serveErrAuthorizationMalformed
+	}
+	v.accessKey = fields[0]
+	v.region = fields[2]
+	v.service = fields[3]
+	v.scope = strings.Join(fields[1:], "/")
+	return nil
+}
+
+// parseAuthorization parses the Authorization header.
+func parseServeV4Authorization(header string) (v serveV4Request, err *serveS3Error) {
+	fields, found := strings.CutPrefix(header, serveSignV4Algorithm+" ")
+	if !found {
+		return v, serveErrSignatureVersion
+	}
+	for _, field := range strings.Split(fields, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch name {
+		case "Credential":
+			if err = v.parseCredential(value); err != nil {
+				return v, err
+			}
+		case "SignedHeaders":
+			v.signedHeaders = strings.Split(value, ";")
+		case "Signature":
+			v.signature = value
+		}
+	}
+	if v.accessKey == "" || len(v.signedHeaders) == 0 || v.signature == "" {
+		return v, serveErrAuthorizationMalformed
+	}
+	return v, nil
+}
+
+// verify checks the signature of r and returns the reader of its payload,
+// which fails once the payload was read if it does not match.
+func (s *serveSigV4) verify(r *http.Request) (io.Reader, *serveS3Error) {
+	var (
+		v     serveV4Request
+		err   *serveS3Error
+		query = r.URL.Query()
+	)
+	switch {
+	case r.Header.Get("Authorization") != "":
+		if v, err = parseServeV4Authorization(r.Header.Get("Authorization")); err != nil {
+			return nil, err
+		}
+		amzDate := r.Header.Get("X-Amz-Date")
+		if amzDate == "" {
+			amzDate = r.Header.Get("Date")
+		}
+		date, e := time.Parse(serveAmzDateFormat, amzDate)
+		if e != nil {
+			return nil, serveErrMissingDate
+		}
+		v.date = date
+		if skew := s.now().Sub(date); skew > serveMaxClockSkew || skew < -serveMaxClockSkew {
+			return nil, serveErrRequestTimeTooSkewed
+		}
+		v.payload = r.Header.Get("X-Amz-Content-Sha256")
+		if v.payload == "" {
+			v.payload = serveEmptySHA256
+		}
+	case query.Get("X-Amz-Algorithm") != "":
+		if query.Get("X-Amz-Algorithm") != serveSignV4Algorithm {
+			return nil, serveErrSignatureVersion
+		}
+		if err = v.parseCredential(query.Get("X-Amz-Credential")); err != nil {
+			return nil, err
+		}
+		date, e := time.Parse(serveAmzDateFormat, query.Get("X-Amz-Date"))
+		if e != nil {
+			return nil, serveErrMissingDate
+		}
+		v.date = date
+		expires, e := strconv.ParseInt(query.Get("X-Amz-Expires"), 10, 64)
+		if e != nil || expires < 0 {
+			return nil, serveErrAuthorizationMalformed
+		}
+		if s.now().After(date.Add(time.Duration(expires) * time.Second)) {
+			return nil, serveErrExpiredPresignRequest
+		}
+		v.signedHeaders = strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+		v.signature = query.Get("X-Amz-Signature")
+		v.payload = query.Get("X-Amz-Content-Sha256")
+		if v.payload == "" {
+			v.payload = serveUnsignedPayload
+		}
+		query.Del("X-Amz-Signature")
+	default:
+		return nil, serveErrAccessDenied
+	}
+
+	if v.accessKey != s.accessKey {
+		return nil, serveErrInvalidAccessKeyID
+	}
+	if !strings.HasPrefix(v.scope, v.date.Format("20060102")+"/") {
+		return nil, serveErrAuthorizationMalformed
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		s3utils.EncodePath(r.URL.Path),
+		strings.ReplaceAll(query.Encode(), "+", "%20"),
+		serveCanonicalHeaders(r, v.signedHeaders),
+		strings.Join(v.signedHeaders, ";"),
+		v.payload,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		serveSignV4Algorithm,
+		v.date.Format(serveAmzDateFormat),
+		v.scope,
+		serveSHA256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	signingKey := s.signingKey(v)
+	signature := hex.EncodeToString(serveHMAC(signingKey, stringToSign))
+	if !hmac.Equal([]byte(signature), []byte(v.signature)) {
+		return nil, serveErrSignatureDoesNotMatch
+	}
+
+	var body io.Reader = r.Body
+	switch v.payload {
+	case serveUnsignedPayload:
+	case serveStreamingPayload, serveStreamingPayloadTrailer, serveStreamingUnsignedTrailer:
+		body = &serveChunkedReader{
+			reader:     bufio.NewReader(r.Body),
+			signed:     v.payload != serveStreamingUnsignedTrailer,
+			signingKey: signingKey,
+			prefix:     serveSignV4ChunkAlgorithm + "\n" + v.date.Format(serveAmzDateFormat) + "\n" + v.scope + "\n",
+			prevSig:    signature,
+			hasher:     sha256.New(),
+		}
+	default:
+		sum, e := hex.DecodeString(v.payload)
+		if e != nil || len(sum) != sha256.Size {
+			return nil, serveErrContentSHA256Mismatch
+		}
+		body = newServeHashReader(body, sha256.New(), sum, serveErrContentSHA256Mismatch)
+	}
+	if contentMD5 := r.Header.Get("Content-Md5"); contentMD5 != "" {
+		sum, e := base64.StdEncoding.DecodeString(contentMD5)
+		if e != nil || len(sum) != md5.Size {
+			return nil, serveErrInvalidDigest
+		}
+		body = newServeHashReader(body, md5.New(), sum, serveErrBadDigest)
+	}
+	return body, nil
+}
+
+// serveCanonicalHeaders returns the signed headers as canonicalized by
+// the client, Go moves some of them out of the header map.
+func serveCanonicalHeaders(r *http.Request, signedHeaders []string) string {
+	var b strings.Builder
+	for _, name := range signedHeaders {
+		var values []string
+		switch name {
+		case "host":
+			values = []string{r.Host}
+		case "content-length":
+			values = []string{strconv.FormatInt(r.ContentLength, 10)}
+		case "transfer-encoding":
+			values = r.TransferEncoding
+		default:
+			values = r.Header.Values(name)
+		}
+		for i := range values {
+			values[i] = strings.Join(strings.Fields(values[i]), " ")
+		}
+		b.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+	return b.String()
+}
+
+// serveHashReader fails at the end of the payload if its hash differs.
+type serveHashReader struct {
+	reader   io.Reader
+	hasher   hash.Hash
+	expected []byte
+	err      *serveS3Error
+}
+
+func newServeHashReader(reader io.Reader, hasher hash.Hash, expected []byte, err *serveS3Error) io.Reader {
+	return &serveHashReader{reader: reader, hasher: hasher, expected: expected, err: err}
+}
+
+func (h *serveHashReader) Read(p []byte) (int, error) {
+	n, e := h.reader.Read(p)
+	h.hasher.Write(p[:n])
+	if e == io.EOF && !bytes.Equal(h.hasher.Sum(nil), h.expected) {
+		return n, h.err
+	}
+	return n, e
+}
+
+// serveChunkedReader decodes an aws-chunked payload and verifies the
+// signature of each chunk. Trailing headers, such as checksums, are
+// read and ignored.
+type serveChunkedReader struct {
+	reader     *bufio.Reader
+	signed     bool
+	signingKey []byte
+	prefix     string
+	prevSig    string
+
+	hasher    hash.Hash
+	remaining int64
+	chunkSig  string
+	err       error
+}
+
+func (c *serveChunkedReader) Read(p []byte) (n int, e error) {
+	for c.err == nil {
+		if c.remaining > 0 {
+			p = p[:min(int64(len(p)), c.remaining)]
+			n, e = c.reader.Read(p)
+			c.hasher.Write(p[:n])
+			c.remaining -= int64(n)
+			if e == io.EOF {
+				e = io.ErrUnexpectedEOF
+			}
+			if e != nil {
+				c.err = e
+			} else if c.remaining == 0 {
+				c.err = c.endChunk()
+			}
+			if n > 0 || c.err != nil {
+				return n, c.err
+			}
+			continue
+		}
+		c.err = c.nextChunk()
+	}
+	return 0, c.err
+}
+
+// nextChunk reads the header of the next chunk, the last one is empty.
+func (c *serveChunkedReader) nextChunk() error {
+	line, e := c.readLine()
+	if e != nil {
+		return e
+	}
+	sizeHex, ext, _ := strings.Cut(line, ";")
+	size, e := strconv.ParseInt(sizeHex, 16, 64)
+	if e != nil || size < 0 {
+		return serveErrIncompleteBody
+	}
+	if c.signed {
+		sig, found := strings.CutPrefix(ext, "chunk-signature=")
+		if !found {
+			return serveErrSignatureDoesNotMatch
+		}
+		c.chunkSig = sig
+	}
+	c.hasher.Reset()
+	c.remaining = size
+	if size > 0 {
+		return nil
+	}
+	if e = c.verifyChunk(); e != nil {
+		return e
+	}
+	// Trailing headers, up to an empty line.
+	for {
+		line, e := c.readLine()
+		if e != nil {
+			return e
+		}
+		if line == "" {
+			return io.EOF
+		}
+	}
+}
+
+// endChunk verifies a chunk once read.
+func (c *serveChunkedReader) endChunk() error {
+	if line, e := c.readLine(); e != nil || line != "" {
+		return serveErrIncompleteBody
+	}
+	return c.verifyChunk()
+}
+
+func (c *serveChunkedReader) verifyChunk() error {
+	if !c.signed {
+		return nil
+	}
+	stringToSign := c.prefix + c.prevSig + "\n" + serveEmptySHA256 + "\n" + hex.EncodeToString(c.hasher.Sum(nil))
+	signature := hex.EncodeToString(serveHMAC(c.signingKey, stringToSign))
+	if !hmac.Equal([]byte(signature), []byte(c.chunkSig)) {
+		return serveErrSignatureDoesNotMatch
+	}
+	c.prevSig = signature
+	return nil
+}
+
+func (c *serveChunkedReader) readLine() (string, error) {
+	line, e := c.reader.ReadString('\n')
+	if e != nil {
+		if e == io.EOF {
+			return "", serveErrIncompleteBody
+		}
+		return "", e
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
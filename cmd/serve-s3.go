@@ -0,0 +1,779 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openstor/mc/pkg/probe"
+)
+
+const serveS3XMLNS = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// serveS3Error - an S3 API error.
+type serveS3Error struct {
+	Code    string
+	Message string
+	Status  int
+}
+
+func (e *serveS3Error) Error() string {
+	return e.Message
+}
+
+var (
+	serveErrAccessDenied           = &serveS3Error{"AccessDenied", "Access Denied.", http.StatusForbidden}
+	serveErrAuthorizationMalformed = &serveS3Error{"AuthorizationHeaderMalformed", "The authorization header is malformed.", http.StatusBadRequest}
+	serveErrBadDigest              = &serveS3Error{"BadDigest", "The Content-Md5 you specified did not match what we received.", http.StatusBadRequest}
+	serveErrBucketAlreadyOwned     = &serveS3Error{"BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.", http.StatusConflict}
+	serveErrContentSHA256Mismatch  = &serveS3Error{"XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed.", http.StatusBadRequest}
+	serveErrExpiredPresignRequest  = &serveS3Error{"AccessDenied", "Request has expired.", http.StatusForbidden}
+	serveErrIncompleteBody         = &serveS3Error{"IncompleteBody", "You did not provide the number of bytes specified by the Content-Length HTTP header.", http.StatusBadRequest}
+	serveErrInternal               = &serveS3Error{"InternalError", "We encountered an internal error, please try again.", http.StatusInternalServerError}
+	serveErrInvalidAccessKeyID     = &serveS3Error{"InvalidAccessKeyId", "The Access Key Id you provided does not exist in our records.", http.StatusForbidden}
+	serveErrInvalidArgument        = &serveS3Error{"InvalidArgument", "Invalid argument.", http.StatusBadRequest}
+	serveErrInvalidDigest          = &serveS3Error{"InvalidDigest", "The Content-Md5 you specified is not valid.", http.StatusBadRequest}
+	serveErrInvalidPart            = &serveS3Error{"InvalidPart", "One or more of the specified parts could not be found.", http.StatusBadRequest}
+	serveErrInvalidRange           = &serveS3Error{"InvalidRange", "The requested range is not satisfiable.", http.StatusRequestedRangeNotSatisfiable}
+	serveErrMalformedXML           = &serveS3Error{"MalformedXML", "The XML you provided was not well-formed.", http.StatusBadRequest}
+	serveErrMethodNotAllowed       = &serveS3Error{"MethodNotAllowed", "The specified method is not allowed against this resource.", http.StatusMethodNotAllowed}
+	serveErrMissingDate            = &serveS3Error{"AccessDenied", "AWS authentication requires a valid Date or x-amz-date header.", http.StatusForbidden}
+	serveErrNoSuchBucket           = &serveS3Error{"NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound}
+	serveErrNoSuchKey              = &serveS3Error{"NoSuchKey", "The specified key does not exist.", http.StatusNotFound}
+	serveErrNoSuchUpload           = &serveS3Error{"NoSuchUpload", "The specified multipart upload does not exist.", http.StatusNotFound}
+	serveErrNotImplemented         = &serveS3Error{"NotImplemented", "A header or query you provided implies functionality that is not implemented.", http.StatusNotImplemented}
+	serveErrRequestTimeTooSkewed   = &serveS3Error{"RequestTimeTooSkewed", "The difference between the request time and the server's time is too large.", http.StatusForbidden}
+	serveErrSignatureDoesNotMatch  = &serveS3Error{"SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", http.StatusForbidden}
+	serveErrSignatureVersion       = &serveS3Error{"AccessDenied", "Only signature version 4 is supported.", http.StatusForbidden}
+)
+
+// serveS3ErrorOf converts an error of the backend.
+func serveS3ErrorOf(err *probe.Error) *serveS3Error {
+	var s3Err *serveS3Error
+	if errors.As(err.ToGoError(), &s3Err) {
+		return s3Err
+	}
+	switch serveHTTPStatus(err) {
+	case http.StatusNotFound:
+		return serveErrNoSuchKey
+	case http.StatusForbidden:
+		return serveErrAccessDenied
+	case http.StatusBadRequest:
+		return serveErrInvalidArgument
+	}
+	return &serveS3Error{serveErrInternal.Code, err.ToGoError().Error(), http.StatusInternalServerError}
+}
+
+// serveS3Handler serves the objects with a subset of the S3 API, for a
+// single bucket, path-style, signed with signature V4.
+type serveS3Handler struct {
+	backend *serveBackend
+	bucket  string
+	auth    *serveSigV4
+	uploads *serveMultipart
+}
+
+func (h *serveS3Handler) writeXML(w http.ResponseWriter, status int, v interface{}) {
+	buf, e := xml.Marshal(v)
+	if e != nil {
+		h.writeError(w, nil, serveErrInternal)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Length", strconv.Itoa(len(xml.Header)+len(buf)))
+	w.WriteHeader(status)
+	io.WriteString(w, xml.Header)
+	w.Write(buf)
+}
+
+func (h *serveS3Handler) writeError(w http.ResponseWriter, r *http.Request, err *serveS3Error) {
+	resp := struct {
+		XMLName   xml.Name `xml:"Error"`
+		Code      string
+		Message   string
+		Resource  string `xml:",omitempty"`
+		RequestID string `xml:"RequestId"`
+	}{Code: err.Code, Message: err.Message, RequestID: w.Header().Get("X-Amz-Request-Id")}
+	if r != nil {
+		resp.Resource = r.URL.Path
+	}
+	if r != nil && r.Method == http.MethodHead {
+		w.WriteHeader(err.Status)
+		return
+	}
+	h.writeXML(w, err.Status, resp)
+}
+
+func (h *serveS3Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Amz-Request-Id", strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:16]))
+	w.Header().Set("Server", "mc-serve")
+
+	body, err := h.auth.verify(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+	switch {
+	case bucket == "":
+		if r.Method != http.MethodGet {
+			h.writeError(w, r, serveErrMethodNotAllowed)
+			return
+		}
+		h.listBuckets(w)
+		return
+	case bucket != h.bucket:
+		h.writeError(w, r, serveErrNoSuchBucket)
+		return
+	}
+	if key != "" {
+		if _, e := cleanServeKey(key); e != nil {
+			h.writeError(w, r, serveErrInvalidArgument)
+			return
+		}
+	}
+	write := r.Method == http.MethodPut || r.Method == http.MethodPost || r.Method == http.MethodDelete
+	if write && h.backend.readOnly {
+		h.writeError(w, r, serveErrAccessDenied)
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case key == "" && r.Method == http.MethodGet && query.Has("location"):
+		h.writeXML(w, http.StatusOK, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+			XMLNS   string   `xml:"xmlns,attr"`
+		}{XMLNS: serveS3XMLNS})
+	case key == "" && r.Method == http.MethodGet && query.Has("uploads"):
+		h.writeXML(w, http.StatusOK, struct {
+			XMLName xml.Name `xml:"ListMultipartUploadsResult"`
+			XMLNS   string   `xml:"xmlns,attr"`
+			Bucket  string
+		}{XMLNS: serveS3XMLNS, Bucket: h.bucket})
+	case key == "" && r.Method == http.MethodGet && !serveHasSubresource(query):
+		h.listObjects(w, r)
+	case key == "" && r.Method == http.MethodPost && query.Has("delete"):
+		h.deleteObjects(w, r, body)
+	case key == "" && r.Method == http.MethodPut && len(query) == 0:
+		h.writeError(w, r, serveErrBucketAlreadyOwned)
+	case key == "":
+		h.writeError(w, r, serveErrNotImplemented)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		h.createUpload(w, r, key)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		h.uploadPart(w, r, key, body)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		h.completeUpload(w, r, key, body)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		if h.uploads.abort(query.Get("uploadId"), key) != nil {
+			h.writeError(w, r, serveErrNoSuchUpload)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && query.Has("uploadId"):
+		h.listParts(w, r, key)
+	case serveHasSubresource(query) || r.Header.Get("X-Amz-Copy-Source") != "":
+		h.writeError(w, r, serveErrNotImplemented)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		h.getObject(w, r, key)
+	case r.Method == http.MethodPut:
+		h.putObject(w, r, key, body)
+	case r.Method == http.MethodDelete:
+		if err := h.backend.Remove(r.Context(), key); err != nil && serveHTTPStatus(err) != http.StatusNotFound {
+			h.writeError(w, r, serveS3ErrorOf(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.writeError(w, r, serveErrMethodNotAllowed)
+	}
+}
+
+// serveHasSubresource returns true if the query selects a sub-resource,
+// like ?acl or ?tagging, rather than listing or request parameters.
+func serveHasSubresource(query map[string][]string) bool {
+	for k := range query {
+		switch k {
+		case "prefix", "delimiter", "marker", "max-keys", "list-type", "continuation-token",
+			"start-after", "fetch-owner", "encoding-type", "versionId", "partNumber",
+			"response-content-type", "response-content-disposition", "response-cache-control",
+			"response-content-encoding", "response-content-language", "response-expires":
+		default:
+			if !strings.HasPrefix(k, "X-Amz-") && !strings.HasPrefix(k, "x-id") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (h *serveS3Handler) listBuckets(w http.ResponseWriter) {
+	type bucket struct {
+		Name         string
+		CreationDate string
+	}
+	h.writeXML(w, http.StatusOK, struct {
+		XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		Owner   struct{ ID, DisplayName string }
+		Buckets []bucket `xml:"Buckets>Bucket"`
+	}{
+		XMLNS:   serveS3XMLNS,
+		Buckets: []bucket{{Name: h.bucket, CreationDate: time.Unix(0, 0).UTC().Format(time.RFC3339)}},
+	})
+}
+
+// serveS3Object - an object of a listing.
+type serveS3Object struct {
+	Key          string
+	LastModified string
+	ETag         string `xml:",omitempty"`
+	Size         int64
+	StorageClass string
+}
+
+func (h *serveS3Handler) listObjects(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	v2 := query.Get("list-type") == "2"
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	if delimiter != "" && delimiter != "/" {
+		h.writeError(w, r, serveErrNotImplemented)
+		return
+	}
+	maxKeys := 1000
+	if v := query.Get("max-keys"); v != "" {
+		n, e := strconv.Atoi(v)
+		if e != nil || n < 0 {
+			h.writeError(w, r, serveErrInvalidArgument)
+			return
+		}
+		maxKeys = min(n, 1000)
+	}
+	marker := query.Get("marker")
+	if v2 {
+		marker = query.Get("continuation-token")
+		if marker == "" {
+			marker = query.Get("start-after")
+		}
+	}
+
+	// Listings are resumed after the marker, in the order of the backend.
+	dir := prefix[:strings.LastIndex(prefix, "/")+1]
+	entriesCh, err := h.backend.List(r.Context(), dir, delimiter == "")
+	if err != nil {
+		h.writeError(w, r, serveS3ErrorOf(err))
+		return
+	}
+	var (
+		contents       []serveS3Object
+		commonPrefixes []string
+		next           string
+		truncated      bool
+		skipping       = marker != ""
+	)
+	for entry := range entriesCh {
+		if !strings.HasPrefix(entry.Key, prefix) {
+			continue
+		}
+		if skipping {
+			skipping = entry.Key != marker
+			continue
+		}
+		if len(contents)+len(commonPrefixes) == maxKeys {
+			truncated = true
+			break
+		}
+		next = entry.Key
+		if entry.IsDir() {
+			commonPrefixes = append(commonPrefixes, entry.Key)
+			continue
+		}
+		contents = append(contents, serveS3Object{
+			Key:          entry.Key,
+			LastModified: entry.Time.UTC().Format("2006-01-02T15:04:05.000Z"),
+			ETag:         serveETag(entry.ETag),
+			Size:         entry.Size,
+			StorageClass: "STANDARD",
+		})
+	}
+	if truncated {
+		// Stop listing in the background.
+		go func() {
+			for range entriesCh {
+			}
+		}()
+	}
+
+	type commonPrefix struct{ Prefix string }
+	resp := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		XMLNS                 string   `xml:"xmlns,attr"`
+		Name                  string
+		Prefix                string
+		Marker                *string `xml:",omitempty"`
+		NextMarker            string  `xml:",omitempty"`
+		ContinuationToken     string  `xml:",omitempty"`
+		NextContinuationToken string  `xml:",omitempty"`
+		StartAfter            string  `xml:",omitempty"`
+		KeyCount              *int    `xml:",omitempty"`
+		MaxKeys               int
+		Delimiter             string `xml:",omitempty"`
+		IsTruncated           bool
+		Contents              []serveS3Object
+		CommonPrefixes        []commonPrefix
+	}{
+		XMLNS:       serveS3XMLNS,
+		Name:        h.bucket,
+		Prefix:      prefix,
+		MaxKeys:     maxKeys,
+		Delimiter:   delimiter,
+		IsTruncated: truncated,
+		Contents:    contents,
+	}
+	for _, p := range commonPrefixes {
+		resp.CommonPrefixes = append(resp.CommonPrefixes, commonPrefix{p})
+	}
+	if v2 {
+		keyCount := len(contents) + len(commonPrefixes)
+		resp.KeyCount = &keyCount
+		resp.ContinuationToken = query.Get("continuation-token")
+		resp.StartAfter = query.Get("start-after")
+		if truncated {
+			resp.NextContinuationToken = next
+		}
+	} else {
+		resp.Marker = &marker
+		if truncated {
+			resp.NextMarker = next
+		}
+	}
+	h.writeXML(w, http.StatusOK, resp)
+}
+
+func (h *serveS3Handler) getObject(w http.ResponseWriter, r *http.Request, key string) {
+	if strings.HasSuffix(key, "/") {
+		h.writeError(w, r, serveErrNoSuchKey)
+		return
+	}
+	entry, err := h.backend.Stat(r.Context(), key)
+	if err != nil {
+		h.writeError(w, r, serveS3ErrorOf(err))
+		return
+	}
+	if entry.IsDir() {
+		h.writeError(w, r, serveErrNoSuchKey)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Accept-Ranges", "bytes")
+	header.Set("Content-Type", entry.ContentType)
+	header.Set("Last-Modified", entry.Time.UTC().Format(http.TimeFormat))
+	if entry.ETag != "" {
+		header.Set("ETag", serveETag(entry.ETag))
+	}
+	for k, v := range r.URL.Query() {
+		if name, ok := strings.CutPrefix(k, "response-"); ok && len(v) > 0 {
+			header.Set(name, v[0])
+		}
+	}
+
+	offset, length, status := int64(0), entry.Size, http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		start, n, ok, e := parseServeRange(rangeHeader, entry.Size)
+		if e != nil {
+			header.Set("Content-Range", fmt.Sprintf("bytes */%d", entry.Size))
+			h.writeError(w, r, serveErrInvalidRange)
+			return
+		}
+		if ok {
+			offset, length, status = start, n, http.StatusPartialContent
+			header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+n-1, entry.Size))
+		}
+	}
+	header.Set("Content-Length", strconv.FormatInt(length, 10))
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+
+	reader, err := h.backend.Get(r.Context(), key, offset, length)
+	if err != nil {
+		header.Del("Content-Length")
+		h.writeError(w, r, serveS3ErrorOf(err))
+		return
+	}
+	defer reader.Close()
+	w.WriteHeader(status)
+	io.Copy(w, reader)
+}
+
+// serveDecodedLength returns the length of the payload, which differs
+// from the content length for aws-chunked payloads.
+func serveDecodedLength(r *http.Request) (int64, *serveS3Error) {
+	if v := r.Header.Get("X-Amz-Decoded-Content-Length"); v != "" {
+		n, e := strconv.ParseInt(v, 10, 64)
+		if e != nil || n < 0 {
+			return 0, serveErrInvalidArgument
+		}
+		return n, nil
+	}
+	return r.ContentLength, nil
+}
+
+func (h *serveS3Handler) putObject(w http.ResponseWriter, r *http.Request, key string, body io.Reader) {
+	size, s3Err := serveDecodedLength(r)
+	if s3Err != nil {
+		h.writeError(w, r, s3Err)
+		return
+	}
+	hasher := md5.New()
+	if err := h.backend.Put(r.Context(), key, io.TeeReader(body, hasher), size, r.Header.Get("Content-Type")); err != nil {
+		h.writeError(w, r, serveS3ErrorOf(err))
+		return
+	}
+	w.Header().Set("ETag", serveETag(hex.EncodeToString(hasher.Sum(nil))))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *serveS3Handler) deleteObjects(w http.ResponseWriter, r *http.Request, body io.Reader) {
+	var req struct {
+		Quiet   bool
+		Objects []struct{ Key string } `xml:"Object"`
+	}
+	if e := xml.NewDecoder(io.LimitReader(body, 4<<20)).Decode(&req); e != nil || len(req.Objects) > 1000 {
+		h.writeError(w, r, serveErrMalformedXML)
+		return
+	}
+	type deleteError struct {
+		Key, Code, Message string
+	}
+	resp := struct {
+		XMLName xml.Name `xml:"DeleteResult"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		Deleted []struct{ Key string }
+		Error   []deleteError
+	}{XMLNS: serveS3XMLNS}
+	for _, object := range req.Objects {
+		var err *probe.Error
+		if _, e := cleanServeKey(object.Key); e != nil || strings.HasSuffix(object.Key, "/") && object.Key == "/" {
+			err = probe.NewError(errServeInvalidKey)
+		} else {
+			err = h.backend.Remove(r.Context(), object.Key)
+		}
+		if err != nil && serveHTTPStatus(err) != http.StatusNotFound {
+			s3Err := serveS3ErrorOf(err)
+			resp.Error = append(resp.Error, deleteError{object.Key, s3Err.Code, s3Err.Message})
+			continue
+		}
+		if !req.Quiet {
+			resp.Deleted = append(resp.Deleted, struct{ Key string }{object.Key})
+		}
+	}
+	h.writeXML(w, http.StatusOK, resp)
+}
+
+func (h *serveS3Handler) createUpload(w http.ResponseWriter, r *http.Request, key string) {
+	uploadID, e := h.uploads.create(key, r.Header.Get("Content-Type"))
+	if e != nil {
+		h.writeError(w, r, serveErrInternal)
+		return
+	}
+	h.writeXML(w, http.StatusOK, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		XMLNS    string   `xml:"xmlns,attr"`
+		Bucket   string
+		Key      string
+		UploadID string `xml:"UploadId"`
+	}{XMLNS: serveS3XMLNS, Bucket: h.bucket, Key: key, UploadID: uploadID})
+}
+
+func (h *serveS3Handler) uploadPart(w http.ResponseWriter, r *http.Request, key string, body io.Reader) {
+	partNumber, e := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if e != nil || partNumber < 1 || partNumber > 10000 {
+		h.writeError(w, r, serveErrInvalidArgument)
+		return
+	}
+	etag, e := h.uploads.putPart(r.URL.Query().Get("uploadId"), key, partNumber, body)
+	if e != nil {
+		var s3Err *serveS3Error
+		if !errors.As(e, &s3Err) {
+			s3Err = serveErrInternal
+		}
+		h.writeError(w, r, s3Err)
+		return
+	}
+	w.Header().Set("ETag", serveETag(etag))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *serveS3Handler) listParts(w http.ResponseWriter, r *http.Request, key string) {
+	parts, e := h.uploads.parts(r.URL.Query().Get("uploadId"), key)
+	if e != nil {
+		h.writeError(w, r, serveErrNoSuchUpload)
+		return
+	}
+	type part struct {
+		PartNumber   int
+		ETag         string
+		Size         int64
+		LastModified string
+	}
+	resp := struct {
+		XMLName  xml.Name `xml:"ListPartsResult"`
+		XMLNS    string   `xml:"xmlns,attr"`
+		Bucket   string
+		Key      string
+		UploadID string `xml:"UploadId"`
+		Parts    []part `xml:"Part"`
+	}{XMLNS: serveS3XMLNS, Bucket: h.bucket, Key: key, UploadID: r.URL.Query().Get("uploadId")}
+	for _, p := range parts {
+		resp.Parts = append(resp.Parts, part{p.number, serveETag(p.etag), p.size, p.time.UTC().Format("2006-01-02T15:04:05.000Z")})
+	}
+	h.writeXML(w, http.StatusOK, resp)
+}
+
+func (h *serveS3Handler) completeUpload(w http.ResponseWriter, r *http.Request, key string, body io.Reader) {
+	var req struct {
+		Parts []struct {
+			PartNumber int
+			ETag       string
+		} `xml:"Part"`
+	}
+	if e := xml.NewDecoder(io.LimitReader(body, 4<<20)).Decode(&req); e != nil || len(req.Parts) == 0 {
+		h.writeError(w, r, serveErrMalformedXML)
+		return
+	}
+	uploadID := r.URL.Query().Get("uploadId")
+	upload, parts, e := h.uploads.complete(uploadID, key)
+	if e != nil {
+		h.writeError(w, r, serveErrNoSuchUpload)
+		return
+	}
+
+	var (
+		readers []io.Reader
+		size    int64
+		md5s    []byte
+	)
+	for i, p := range req.Parts {
+		part, ok := parts[p.PartNumber]
+		if !ok || strings.Trim(p.ETag, `"`) != part.etag || (i > 0 && p.PartNumber <= req.Parts[i-1].PartNumber) {
+			h.uploads.release(uploadID)
+			h.writeError(w, r, serveErrInvalidPart)
+			return
+		}
+		f, e := os.Open(part.path)
+		if e != nil {
+			h.uploads.release(uploadID)
+			h.writeError(w, r, serveErrInternal)
+			return
+		}
+		defer f.Close()
+		readers = append(readers, f)
+		size += part.size
+		sum, _ := hex.DecodeString(part.etag)
+		md5s = append(md5s, sum...)
+	}
+	if err := h.backend.Put(r.Context(), key, io.MultiReader(readers...), size, upload.contentType); err != nil {
+		h.uploads.release(uploadID)
+		h.writeError(w, r, serveS3ErrorOf(err))
+		return
+	}
+	h.uploads.abort(uploadID, key)
+
+	sum := md5.Sum(md5s)
+	h.writeXML(w, http.StatusOK, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		Bucket  string
+		Key     string
+		ETag    string
+	}{XMLNS: serveS3XMLNS, Bucket: h.bucket, Key: key, ETag: serveETag(fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(req.Parts)))})
+}
+
+// serveMultipart keeps the parts of multipart uploads in a local folder
+// until they are completed and uploaded as a single object.
+type serveMultipart struct {
+	mu      sync.Mutex
+	dir     string
+	uploads map[string]*serveUpload
+}
+
+type serveUpload struct {
+	key         string
+	contentType string
+	parts       map[int]servePart
+	completing  bool
+}
+
+type servePart struct {
+	number int
+	path   string
+	etag   string
+	size   int64
+	time   time.Time
+}
+
+func newServeMultipart() *serveMultipart {
+	return &serveMultipart{uploads: make(map[string]*serveUpload)}
+}
+
+func (m *serveMultipart) create(key, contentType string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dir == "" {
+		dir, e := os.MkdirTemp("", "mc-serve-")
+		if e != nil {
+			return "", e
+		}
+		m.dir = dir
+	}
+	uploadID := uuid.NewString()
+	if e := os.Mkdir(filepath.Join(m.dir, uploadID), 0o700); e != nil {
+		return "", e
+	}
+	m.uploads[uploadID] = &serveUpload{key: key, contentType: contentType, parts: make(map[int]servePart)}
+	return uploadID, nil
+}
+
+func (m *serveMultipart) get(uploadID, key string) (*serveUpload, error) {
+	upload, ok := m.uploads[uploadID]
+	if !ok || upload.key != key || upload.completing {
+		return nil, serveErrNoSuchUpload
+	}
+	return upload, nil
+}
+
+func (m *serveMultipart) putPart(uploadID, key string, number int, body io.Reader) (string, error) {
+	m.mu.Lock()
+	_, e := m.get(uploadID, key)
+	m.mu.Unlock()
+	if e != nil {
+		return "", e
+	}
+
+	f, e := os.CreateTemp(filepath.Join(m.dir, uploadID), "part-")
+	if e != nil {
+		return "", e
+	}
+	hasher := md5.New()
+	size, e := io.Copy(io.MultiWriter(f, hasher), body)
+	if ce := f.Close(); e == nil {
+		e = ce
+	}
+	if e != nil {
+		os.Remove(f.Name())
+		return "", e
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, e := m.get(uploadID, key)
+	if e != nil {
+		os.Remove(f.Name())
+		return "", e
+	}
+	if old, ok := upload.parts[number]; ok {
+		os.Remove(old.path)
+	}
+	part := servePart{number: number, path: f.Name(), etag: hex.EncodeToString(hasher.Sum(nil)), size: size, time: time.Now()}
+	upload.parts[number] = part
+	return part.etag, nil
+}
+
+func (m *serveMultipart) parts(uploadID, key string) ([]servePart, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	upload, e := m.get(uploadID, key)
+	if e != nil {
+		return nil, e
+	}
+	parts := make([]servePart, 0, len(upload.parts))
+	for n := 1; len(parts) < len(upload.parts); n++ {
+		if part, ok := upload.parts[n]; ok {
+			parts = append(parts, part)
+		}
+	}
+	return parts, nil
+}
+
+// complete marks the upload as being completed and returns its parts.
+func (m *serveMultipart) complete(uploadID, key string) (*serveUpload, map[int]servePart, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	upload, e := m.get(uploadID, key)
+	if e != nil {
+		return nil, nil, e
+	}
+	upload.completing = true
+	return upload, upload.parts, nil
+}
+
+// release allows completing the upload again after a failure.
+func (m *serveMultipart) release(uploadID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if upload, ok := m.uploads[uploadID]; ok {
+		upload.completing = false
+	}
+}
+
+func (m *serveMultipart) abort(uploadID, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	upload, ok := m.uploads[uploadID]
+	if !ok || upload.key != key {
+		return serveErrNoSuchUpload
+	}
+	delete(m.uploads, uploadID)
+	return os.RemoveAll(filepath.Join(m.dir, uploadID))
+}
+
+// Close removes the parts of all uploads.
+func (m *serveMultipart) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.uploads = make(map[string]*serveUpload)
+	if m.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(m.dir)
+}
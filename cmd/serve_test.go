@@ -0,0 +1,260 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/openstor-go/v7"
+	"github.com/openstor/openstor-go/v7/pkg/credentials"
+)
+
+func TestParseServeRange(t *testing.T) {
+	testCases := []struct {
+		header         string
+		offset, length int64
+		ok, err        bool
+	}{
+		{"bytes=0-9", 0, 10, true, false},
+		{"bytes=5-", 5, 95, true, false},
+		{"bytes=-10", 90, 10, true, false},
+		{"bytes=-1000", 0, 100, true, false},
+		{"bytes=90-1000", 90, 10, true, false},
+		{"bytes=100-", 0, 0, false, true},
+		{"bytes=9-5", 0, 0, false, true},
+		{"bytes=0-1,5-6", 0, 0, false, false},
+		{"items=0-1", 0, 0, false, false},
+	}
+	for _, tc := range testCases {
+		offset, length, ok, e := parseServeRange(tc.header, 100)
+		if (e != nil) != tc.err || ok != tc.ok || offset != tc.offset || length != tc.length {
+			t.Errorf("%s: got %d, %d, %v, %v", tc.header, offset, length, ok, e)
+		}
+	}
+}
+
+func TestCleanServeKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"/":         "",
+		"/a/b.txt":  "a/b.txt",
+		"/a/b/":     "a/b/",
+		"/a/../b":   "",
+		"/./a":      "",
+		"/a/..":     "",
+		"/a/b..txt": "a/b..txt",
+		`/..\..\a`:  "",
+		`/a\b.txt`:  "",
+	} {
+		got, e := cleanServeKey(key)
+		if want == "" && key != "/" {
+			if e == nil {
+				t.Errorf("%s: expected an error, got %q", key, got)
+			}
+			continue
+		}
+		if e != nil || got != want {
+			t.Errorf("%s: expected %q, got %q, %v", key, want, got, e)
+		}
+	}
+}
+
+func newServeTestRoot(t *testing.T) string {
+	// Local paths are served without loading the configuration.
+	saved := loadMcConfig
+	loadMcConfig = func() (*configV11, *probe.Error) { return newMcConfig(), nil }
+	t.Cleanup(func() { loadMcConfig = saved })
+
+	root := t.TempDir()
+	if e := os.MkdirAll(filepath.Join(root, "docs"), 0o755); e != nil {
+		t.Fatal(e)
+	}
+	if e := os.WriteFile(filepath.Join(root, "docs", "a.txt"), []byte("0123456789"), 0o644); e != nil {
+		t.Fatal(e)
+	}
+	return root
+}
+
+func TestServeHTTP(t *testing.T) {
+	root := newServeTestRoot(t)
+	handler := &serveHTTPHandler{backend: newServeBackend(root, nil, false), webdav: true}
+	server := httptest.NewServer(serveBasicAuth(handler, "user", "password"))
+	defer server.Close()
+
+	do := func(method, p, body string, header map[string]string) (*http.Response, string) {
+		t.Helper()
+		req, e := http.NewRequest(method, server.URL+p, strings.NewReader(body))
+		if e != nil {
+			t.Fatal(e)
+		}
+		req.SetBasicAuth("user", "password")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, e := http.DefaultClient.Do(req)
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/docs/a.txt", nil)
+	req.SetBasicAuth("user", "wrong")
+	resp, e := http.DefaultClient.Do(req)
+	if e != nil {
+		t.Fatal(e)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with wrong credentials, got %d", resp.StatusCode)
+	}
+
+	resp, body := do(http.MethodGet, "/docs/a.txt", "", map[string]string{"Range": "bytes=2-4"})
+	if resp.StatusCode != http.StatusPartialContent || body != "234" {
+		t.Fatalf("range GET: got %d %q", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Range"); got != "bytes 2-4/10" {
+		t.Fatalf("range GET: got Content-Range %q", got)
+	}
+	resp, _ = do(http.MethodGet, "/docs/a.txt", "", map[string]string{"Range": "bytes=20-"})
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("unsatisfiable range: got %d", resp.StatusCode)
+	}
+
+	resp, _ = do(http.MethodPut, "/docs/b.txt", "hello", nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("PUT: got %d", resp.StatusCode)
+	}
+	if data, e := os.ReadFile(filepath.Join(root, "docs", "b.txt")); e != nil || string(data) != "hello" {
+		t.Fatalf("PUT: got %q, %v", data, e)
+	}
+
+	resp, body = do("PROPFIND", "/docs/", "", map[string]string{"Depth": "1"})
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND: got %d", resp.StatusCode)
+	}
+	for _, href := range []string{"<D:href>/docs/</D:href>", "<D:href>/docs/a.txt</D:href>", "<D:href>/docs/b.txt</D:href>"} {
+		if !strings.Contains(body, href) {
+			t.Errorf("PROPFIND: %s missing in %s", href, body)
+		}
+	}
+
+	resp, _ = do(http.MethodGet, "/../etc/passwd", "", nil)
+	if resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET outside of the root: got %d", resp.StatusCode)
+	}
+
+	resp, _ = do(http.MethodDelete, "/docs/b.txt", "", nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE: got %d", resp.StatusCode)
+	}
+	if _, e := os.Stat(filepath.Join(root, "docs", "b.txt")); !os.IsNotExist(e) {
+		t.Fatalf("DELETE: file still exists, %v", e)
+	}
+
+	handler.backend.readOnly = true
+	resp, _ = do(http.MethodPut, "/docs/c.txt", "hello", nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("read-only PUT: got %d", resp.StatusCode)
+	}
+	resp, _ = do(http.MethodDelete, "/docs/a.txt", "", nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("read-only DELETE: got %d", resp.StatusCode)
+	}
+}
+
+func TestServeS3(t *testing.T) {
+	root := newServeTestRoot(t)
+	uploads := newServeMultipart()
+	defer uploads.Close()
+	server := httptest.NewServer(&serveS3Handler{
+		backend: newServeBackend(root, nil, false),
+		bucket:  "bucket",
+		auth:    &serveSigV4{accessKey: "access", secretKey: "secret1234", now: time.Now},
+		uploads: uploads,
+	})
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	s3Client, e := openstor.New(u.Host, &openstor.Options{
+		Creds:  credentials.NewStaticV4("access", "secret1234", ""),
+		Region: "us-east-1",
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	ctx := context.Background()
+
+	data := bytes.Repeat([]byte("mc serve "), 1000)
+	if _, e = s3Client.PutObject(ctx, "bucket", "dir/obj", bytes.NewReader(data), int64(len(data)), openstor.PutObjectOptions{}); e != nil {
+		t.Fatal(e)
+	}
+	if got, e := os.ReadFile(filepath.Join(root, "dir", "obj")); e != nil || !bytes.Equal(got, data) {
+		t.Fatalf("PutObject: got %d bytes, %v", len(got), e)
+	}
+
+	opts := openstor.GetObjectOptions{}
+	opts.SetRange(9, 17)
+	obj, e := s3Client.GetObject(ctx, "bucket", "dir/obj", opts)
+	if e != nil {
+		t.Fatal(e)
+	}
+	got, e := io.ReadAll(obj)
+	obj.Close()
+	if e != nil || string(got) != "mc serve " {
+		t.Fatalf("ranged GetObject: got %q, %v", got, e)
+	}
+
+	var keys []string
+	for info := range s3Client.ListObjects(ctx, "bucket", openstor.ListObjectsOptions{Recursive: true}) {
+		if info.Err != nil {
+			t.Fatal(info.Err)
+		}
+		keys = append(keys, info.Key)
+	}
+	if strings.Join(keys, ",") != "dir/obj,docs/a.txt" {
+		t.Fatalf("ListObjects: got %v", keys)
+	}
+
+	if e = s3Client.RemoveObject(ctx, "bucket", "dir/obj", openstor.RemoveObjectOptions{}); e != nil {
+		t.Fatal(e)
+	}
+	if _, e = os.Stat(filepath.Join(root, "dir", "obj")); !os.IsNotExist(e) {
+		t.Fatalf("RemoveObject: object still exists, %v", e)
+	}
+
+	wrong, _ := openstor.New(u.Host, &openstor.Options{
+		Creds:  credentials.NewStaticV4("access", "wrong", ""),
+		Region: "us-east-1",
+	})
+	if _, e = wrong.StatObject(ctx, "bucket", "docs/a.txt", openstor.StatObjectOptions{}); e == nil {
+		t.Fatal("expected an error with a wrong secret key")
+	}
+}
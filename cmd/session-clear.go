@@ -0,0 +1,105 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/fatih/color"
+	json "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var sessionClearFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "all",
+		Usage: "clear all saved sessions",
+	},
+}
+
+var sessionClearCmd = cli.Command{
+	Name:         "clear",
+	Usage:        "clear saved sessions",
+	Action:       mainSessionClear,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(sessionClearFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] [SESSION-ID...]
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Clear a saved session.
+     {{.Prompt}} {{.HelpName}} gxk3mqp9tzr4vwb2
+
+  2. Clear all saved sessions.
+     {{.Prompt}} {{.HelpName}} --all
+
+  Incomplete uploads left by cleared sessions can be removed with 'mc rm --incomplete'.
+`,
+}
+
+// sessionClearMessage container for cleared session.
+type sessionClearMessage struct {
+	Status    string `json:"status"`
+	SessionID string `json:"sessionId"`
+}
+
+// String colorized clear session message.
+func (s sessionClearMessage) String() string {
+	return console.Colorize("ClearSession", "Session `"+s.SessionID+"` cleared successfully.")
+}
+
+// JSON jsonified clear session message.
+func (s sessionClearMessage) JSON() string {
+	s.Status = "success"
+	clearBytes, e := json.MarshalIndent(s, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(clearBytes)
+}
+
+// mainSessionClear is the handle for "mc session clear" command.
+func mainSessionClear(ctx context.Context, cmd *cli.Command) error {
+	all := cmd.Bool("all")
+	if all == cmd.Args().Present() {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code.
+	}
+
+	console.SetColor("ClearSession", color.New(color.FgGreen, color.Bold))
+
+	sids := cmd.Args().Slice()
+	if all {
+		sids = getSessionIDs()
+	}
+	for _, sid := range sids {
+		if !isSessionExists(sid) {
+			fatalIf(errInvalidSession(sid).Trace(sid), "Unable to clear session.")
+		}
+		fatalIf(removeSession(sid), "Unable to clear session `%s`.", sid)
+		printMsg(sessionClearMessage{SessionID: sid})
+	}
+	return nil
+}
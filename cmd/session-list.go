@@ -0,0 +1,124 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	json "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var sessionListCmd = cli.Command{
+	Name:         "list",
+	Aliases:      []string{"ls"},
+	Usage:        "list saved sessions",
+	Action:       mainSessionList,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        globalFlags,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}}
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. List all sessions saved by interrupted copy and mirror commands.
+     {{.Prompt}} {{.HelpName}}
+`,
+}
+
+// sessionListMessage container for a saved session.
+type sessionListMessage struct {
+	Status      string        `json:"status"`
+	SessionID   string        `json:"sessionId"`
+	Time        time.Time     `json:"time"`
+	CommandType string        `json:"commandType"`
+	CommandArgs []string      `json:"commandArgs"`
+	Stat        sessionV8Stat `json:"stat"`
+}
+
+// String colorized session message.
+func (s sessionListMessage) String() string {
+	msg := console.Colorize("SessionID", s.SessionID)
+	msg += " " + console.Colorize("SessionTime", "["+s.Time.Local().Format(printDate)+"]")
+	msg += " " + console.Colorize("Command", "mc "+s.CommandType+" "+strings.Join(s.CommandArgs, " "))
+	msg += fmt.Sprintf(" (%d/%d objects, %s/%s",
+		s.Stat.CompletedObjects, s.Stat.TotalObjects,
+		humanize.IBytes(uint64(s.Stat.CompletedBytes)), humanize.IBytes(uint64(s.Stat.TotalBytes)))
+	if s.Stat.FailedObjects > 0 {
+		msg += fmt.Sprintf(", %d failed", s.Stat.FailedObjects)
+	}
+	if s.Stat.InFlightObjects > 0 {
+		msg += fmt.Sprintf(", %d in-flight", s.Stat.InFlightObjects)
+	}
+	if s.Stat.PendingUploads > 0 {
+		msg += fmt.Sprintf(", %d resumable uploads", s.Stat.PendingUploads)
+	}
+	if !s.Stat.Scanned {
+		msg += ", listing incomplete"
+	}
+	return msg + ")"
+}
+
+// JSON jsonified session message.
+func (s sessionListMessage) JSON() string {
+	s.Status = "success"
+	sessionBytes, e := json.MarshalIndent(s, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(sessionBytes)
+}
+
+// mainSessionList is the handle for "mc session list" command.
+func mainSessionList(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Present() {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code.
+	}
+
+	console.SetColor("SessionID", color.New(color.FgYellow, color.Bold))
+	console.SetColor("SessionTime", color.New(color.FgGreen))
+	console.SetColor("Command", color.New(color.FgWhite, color.Bold))
+
+	for _, sid := range getSessionIDs() {
+		s, err := loadSessionV8(sid)
+		if err != nil {
+			errorIf(err.Trace(sid), "Unable to load session `%s`.", sid)
+			continue
+		}
+		printMsg(sessionListMessage{
+			SessionID:   sid,
+			Time:        s.Header.When,
+			CommandType: s.Header.CommandType,
+			CommandArgs: s.Header.CommandArgs,
+			Stat:        s.Stat(),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,388 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/quick"
+)
+
+// Journal operations recorded for every URL handled in a session.
+const (
+	sessionOpQueued  = "queued"
+	sessionOpStarted = "started"
+	sessionOpDone    = "done"
+	sessionOpFailed  = "failed"
+	sessionOpUpload  = "upload"
+	sessionOpScanned = "scanned"
+)
+
+// sessionV8Header - persisted header of a cp/mirror session.
+type sessionV8Header struct {
+	Version      string              `json:"version"`
+	When         time.Time           `json:"time"`
+	RootPath     string              `json:"workingFolder"`
+	CommandType  string              `json:"commandType"`
+	CommandArgs  []string            `json:"cmd-args"`
+	CommandFlags map[string][]string `json:"cmd-flags"`
+}
+
+// sessionV8JournalEntry - one line of the session journal.
+type sessionV8JournalEntry struct {
+	Op       string    `json:"op"`
+	Source   string    `json:"src,omitempty"`
+	Target   string    `json:"tgt,omitempty"`
+	Size     int64     `json:"size,omitempty"`
+	ModTime  time.Time `json:"mtime,omitzero"`
+	ETag     string    `json:"etag,omitempty"`
+	UploadID string    `json:"uploadId,omitempty"`
+}
+
+// sessionV8Entry - replayed state of a single URL in the journal.
+type sessionV8Entry struct {
+	Source   string
+	Target   string
+	Size     int64
+	ModTime  time.Time
+	ETag     string
+	Status   string
+	UploadID string
+}
+
+// sessionV8 - session header together with its append-only journal.
+type sessionV8 struct {
+	Header    sessionV8Header
+	SessionID string
+
+	mutex   *sync.Mutex
+	journal *os.File
+
+	// replayed journal state, key is the source URL.
+	entries map[string]*sessionV8Entry
+	order   []string
+	scanned bool
+}
+
+// newSessionV8 creates a new session of the given command type.
+func newSessionV8(sid, commandType string) *sessionV8 {
+	s := &sessionV8{
+		SessionID: sid,
+		mutex:     &sync.Mutex{},
+		entries:   make(map[string]*sessionV8Entry),
+	}
+	s.Header.Version = globalSessionConfigVersion
+	s.Header.When = UTCNow()
+	s.Header.CommandType = commandType
+	s.Header.CommandFlags = make(map[string][]string)
+	if rootPath, e := os.Getwd(); e == nil {
+		s.Header.RootPath = rootPath
+	}
+	return s
+}
+
+// loadSessionV8 loads the session header and replays its journal.
+func loadSessionV8(sid string) (*sessionV8, *probe.Error) {
+	if !isSessionExists(sid) {
+		return nil, errInvalidSession(sid).Trace(sid)
+	}
+
+	s := newSessionV8(sid, "")
+	qs, e := quick.NewConfig(&s.Header, nil)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(sid)
+	}
+	if e = qs.Load(getSessionFile(sid)); e != nil {
+		return nil, probe.NewError(e).Trace(sid)
+	}
+	if s.Header.Version != globalSessionConfigVersion {
+		return nil, errInvalidSession(sid).Trace(s.Header.Version)
+	}
+
+	f, e := os.Open(getSessionDataFile(sid))
+	if e != nil {
+		if os.IsNotExist(e) {
+			return s, nil
+		}
+		return nil, probe.NewError(e).Trace(sid)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var entry sessionV8JournalEntry
+		if e := json.Unmarshal(scanner.Bytes(), &entry); e != nil {
+			// A partially written trailing line is expected after a crash.
+			continue
+		}
+		s.apply(entry)
+	}
+	if e := scanner.Err(); e != nil {
+		return nil, probe.NewError(e).Trace(sid)
+	}
+	return s, nil
+}
+
+// apply updates the in-memory state with a journal entry.
+func (s *sessionV8) apply(entry sessionV8JournalEntry) {
+	if entry.Op == sessionOpScanned {
+		s.scanned = true
+		return
+	}
+	st, ok := s.entries[entry.Source]
+	if !ok {
+		st = &sessionV8Entry{Source: entry.Source}
+		s.entries[entry.Source] = st
+		s.order = append(s.order, entry.Source)
+	}
+	if entry.Op == sessionOpQueued {
+		if !st.isSameSource(entry.Target, entry.Size, entry.ModTime, entry.ETag) {
+			// The upload of an earlier run was made for another object.
+			st.UploadID = ""
+		}
+		st.Target = entry.Target
+		st.Size = entry.Size
+		st.ModTime = entry.ModTime
+		st.ETag = entry.ETag
+	}
+	switch entry.Op {
+	case sessionOpUpload:
+		st.UploadID = entry.UploadID
+	case sessionOpDone:
+		st.Status = entry.Op
+		st.UploadID = ""
+	default:
+		st.Status = entry.Op
+	}
+}
+
+// Save persists the session header and opens the journal for appending.
+func (s *sessionV8) Save() *probe.Error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	qs, e := quick.NewConfig(&s.Header, nil)
+	if e != nil {
+		return probe.NewError(e).Trace(s.SessionID)
+	}
+	if e = qs.Save(getSessionFile(s.SessionID)); e != nil {
+		return probe.NewError(e).Trace(s.SessionID)
+	}
+	return s.openJournal()
+}
+
+// Open opens the journal of a loaded session for appending.
+func (s *sessionV8) Open() *probe.Error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.openJournal()
+}
+
+func (s *sessionV8) openJournal() *probe.Error {
+	if s.journal != nil {
+		return nil
+	}
+	f, e := os.OpenFile(getSessionDataFile(s.SessionID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if e != nil {
+		return probe.NewError(e).Trace(s.SessionID)
+	}
+	s.journal = f
+	return nil
+}
+
+// record appends an entry to the journal and applies it in memory.
+// Journal write errors are reported once and journaling stops, the
+// transfer itself is never interrupted because of the journal.
+func (s *sessionV8) record(entry sessionV8JournalEntry) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.apply(entry)
+	if s.journal == nil {
+		return
+	}
+	buf, e := json.Marshal(entry)
+	if e != nil {
+		return
+	}
+	if _, e = s.journal.Write(append(buf, '\n')); e != nil {
+		errorIf(probe.NewError(e).Trace(s.SessionID), "Unable to write to session journal, disabling it.")
+		s.journal.Close()
+		s.journal = nil
+	}
+}
+
+// Queued records that the URL was handed over to the parallel manager.
+func (s *sessionV8) Queued(urls URLs) {
+	s.record(sessionV8JournalEntry{
+		Op:      sessionOpQueued,
+		Source:  urls.SourceContent.URL.String(),
+		Target:  urls.TargetContent.URL.String(),
+		Size:    urls.SourceContent.Size,
+		ModTime: urls.SourceContent.Time,
+		ETag:    urls.SourceContent.ETag,
+	})
+}
+
+// Started records that a worker picked up the URL.
+func (s *sessionV8) Started(urls URLs) {
+	s.record(sessionV8JournalEntry{Op: sessionOpStarted, Source: urls.SourceContent.URL.String()})
+}
+
+// Finished records the outcome of the URL.
+func (s *sessionV8) Finished(urls URLs) {
+	op := sessionOpDone
+	if urls.Error != nil {
+		op = sessionOpFailed
+	}
+	s.record(sessionV8JournalEntry{Op: op, Source: urls.SourceContent.URL.String()})
+}
+
+// SetUploadID records the multipart upload ID of an in-flight URL, as
+// soon as the upload is created.
+func (s *sessionV8) SetUploadID(source, uploadID string) {
+	s.record(sessionV8JournalEntry{Op: sessionOpUpload, Source: source, UploadID: uploadID})
+}
+
+// ScanDone records that all source URLs were queued.
+func (s *sessionV8) ScanDone() {
+	s.record(sessionV8JournalEntry{Op: sessionOpScanned})
+}
+
+// IsCompleted returns true if the source URL was copied successfully.
+func (s *sessionV8) IsCompleted(source string) bool {
+	if s == nil {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	st, ok := s.entries[source]
+	return ok && st.Status == sessionOpDone
+}
+
+// InFlight returns the URLs started but never finished, in journal order.
+func (s *sessionV8) InFlight() (entries []sessionV8Entry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, source := range s.order {
+		if st := s.entries[source]; st.Status == sessionOpStarted {
+			entries = append(entries, *st)
+		}
+	}
+	return entries
+}
+
+// isSameSource returns true if the entry was recorded for a source of the
+// same size, modification time and ETag, copied to the same target.
+func (st *sessionV8Entry) isSameSource(target string, size int64, modTime time.Time, etag string) bool {
+	return st.Target == target && st.Size == size && st.ModTime.Equal(modTime) && st.ETag == etag
+}
+
+// ResumableUploadID returns the multipart upload ID recorded for the
+// URL, if the source is unchanged and copied to the same target, so that
+// the parts already uploaded still hold its content. A source rewritten
+// since, even with the same size, or queued again for another target
+// forgets its upload ID.
+func (s *sessionV8) ResumableUploadID(urls URLs) string {
+	if s == nil {
+		return ""
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	source := urls.SourceContent
+	st, ok := s.entries[source.URL.String()]
+	if !ok || !st.isSameSource(urls.TargetContent.URL.String(), source.Size, source.Time, source.ETag) {
+		return ""
+	}
+	return st.UploadID
+}
+
+// Stat summarizes the journal.
+func (s *sessionV8) Stat() (stat sessionV8Stat) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stat.Scanned = s.scanned
+	for _, st := range s.entries {
+		stat.TotalObjects++
+		stat.TotalBytes += st.Size
+		switch st.Status {
+		case sessionOpDone:
+			stat.CompletedObjects++
+			stat.CompletedBytes += st.Size
+		case sessionOpStarted:
+			stat.InFlightObjects++
+			if st.UploadID != "" {
+				stat.PendingUploads++
+			}
+		case sessionOpFailed:
+			stat.FailedObjects++
+		}
+	}
+	return stat
+}
+
+// sessionV8Stat - summary of a session journal.
+type sessionV8Stat struct {
+	Scanned          bool  `json:"scanned"`
+	TotalObjects     int64 `json:"totalObjects"`
+	TotalBytes       int64 `json:"totalBytes"`
+	CompletedObjects int64 `json:"completedObjects"`
+	CompletedBytes   int64 `json:"completedBytes"`
+	InFlightObjects  int64 `json:"inFlightObjects"`
+	FailedObjects    int64 `json:"failedObjects"`
+	PendingUploads   int64 `json:"pendingUploads"`
+}
+
+// Close closes the journal, the session stays on disk.
+func (s *sessionV8) Close() *probe.Error {
+	if s == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.journal == nil {
+		return nil
+	}
+	e := s.journal.Close()
+	s.journal = nil
+	return probe.NewError(e)
+}
+
+// Delete closes and removes the session from disk.
+func (s *sessionV8) Delete() *probe.Error {
+	if s == nil {
+		return nil
+	}
+	s.Close()
+	return removeSession(s.SessionID)
+}
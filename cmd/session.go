@@ -0,0 +1,216 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/openstor-go/v7"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+// Get session dir name.
+func getSessionDir() (string, *probe.Error) {
+	configDir, err := getMcConfigDir()
+	if err != nil {
+		return "", err.Trace()
+	}
+
+	sessionDir := filepath.Join(configDir, globalSessionDir)
+	return sessionDir, nil
+}
+
+// Get session dir name or die. (NOTE: This `Die` approach is only OK for mc like tools.).
+func mustGetSessionDir() string {
+	sessionDir, err := getSessionDir()
+	fatalIf(err.Trace(), "Unable to determine session folder.")
+	return sessionDir
+}
+
+// Check if the session dir exists.
+func isSessionDirExists() bool {
+	if _, e := os.Stat(mustGetSessionDir()); e != nil {
+		return false
+	}
+	return true
+}
+
+// Create config session dir.
+func createSessionDir() *probe.Error {
+	if e := os.MkdirAll(mustGetSessionDir(), 0o700); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// Get session header file.
+func getSessionFile(sid string) string {
+	return filepath.Join(mustGetSessionDir(), sid+".json")
+}
+
+// Get session journal file.
+func getSessionDataFile(sid string) string {
+	return filepath.Join(mustGetSessionDir(), sid+".data")
+}
+
+// Check if the session exists.
+func isSessionExists(sid string) bool {
+	if _, e := os.Stat(getSessionFile(sid)); e != nil {
+		return false
+	}
+	return true
+}
+
+// Remove session header and journal.
+func removeSession(sid string) *probe.Error {
+	// Journal first, a header without journal is still a valid session.
+	if e := os.Remove(getSessionDataFile(sid)); e != nil && !os.IsNotExist(e) {
+		return probe.NewError(e).Trace(sid)
+	}
+	if e := os.Remove(getSessionFile(sid)); e != nil && !os.IsNotExist(e) {
+		return probe.NewError(e).Trace(sid)
+	}
+	return nil
+}
+
+// getSessionIDs returns all saved session IDs sorted by name.
+func getSessionIDs() (sids []string) {
+	entries, e := os.ReadDir(mustGetSessionDir())
+	if e != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if sid, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			sids = append(sids, sid)
+		}
+	}
+	sort.Strings(sids)
+	return sids
+}
+
+// newSessionID generates a session ID not used by any saved session.
+func newSessionID() string {
+	src := rand.NewSource(time.Now().UnixNano())
+	for {
+		sid := randString(16, src, "")
+		if !isSessionExists(sid) {
+			return sid
+		}
+	}
+}
+
+// Initialize session directory, if not done already.
+func initSessionDir() {
+	if !isSessionDirExists() {
+		fatalIf(createSessionDir().Trace(mustGetSessionDir()),
+			"Failed to create session `"+mustGetSessionDir()+"` folder.")
+	}
+}
+
+// newCommandSession creates and saves a new session for the command.
+// Returns nil if the session cannot be saved, sessions are best effort.
+func newCommandSession(commandType string, args []string, flags map[string][]string) *sessionV8 {
+	initSessionDir()
+
+	s := newSessionV8(newSessionID(), commandType)
+	s.Header.CommandArgs = args
+	s.Header.CommandFlags = flags
+	if err := s.Save(); err != nil {
+		errorIf(err.Trace(s.SessionID), "Unable to save session, resuming will not be possible.")
+		return nil
+	}
+	return s
+}
+
+// loadCommandSession loads a saved session of the given command type
+// and reopens its journal.
+func loadCommandSession(sid, commandType string) *sessionV8 {
+	s, err := loadSessionV8(sid)
+	fatalIf(err, "Unable to load session.")
+	if s.Header.CommandType != commandType {
+		fatalIf(errDummy().Trace(sid),
+			"Session `%s` was created by `mc %s`, please resume it with `mc %s --resume %s`.",
+			sid, s.Header.CommandType, s.Header.CommandType, sid)
+	}
+	fatalIf(s.Open(), "Unable to reopen session journal.")
+	return s
+}
+
+// finishCommandSession removes the session after a clean run,
+// otherwise keeps it and tells the user how to resume.
+func finishCommandSession(s *sessionV8, failed bool) {
+	if s == nil {
+		return
+	}
+	if !failed {
+		errorIf(s.Delete(), "Unable to remove session `%s`.", s.SessionID)
+		return
+	}
+	errorIf(s.Close(), "Unable to close session `%s`.", s.SessionID)
+	if !globalQuiet && !globalJSON {
+		console.Eraseline()
+		console.Infof("Session saved as `%s`, resume with `mc %s --resume %s`.\n",
+			s.SessionID, s.Header.CommandType, s.SessionID)
+	}
+}
+
+// sessionFlags collects the values of the flags explicitly set on the
+// command line, so that they can be restored when resuming. Encryption
+// keys are deliberately never written to the session.
+func sessionFlags(cmd *cli.Command, flags []cli.Flag) map[string][]string {
+	values := make(map[string][]string)
+	for _, f := range flags {
+		name := f.Names()[0]
+		if name == "resume" || !cmd.IsSet(name) {
+			continue
+		}
+		switch v := cmd.Value(name).(type) {
+		case []string:
+			values[name] = v
+		default:
+			values[name] = []string{fmt.Sprint(v)}
+		}
+	}
+	return values
+}
+
+// restoreSessionFlags sets the flags saved in the session on the command.
+func restoreSessionFlags(cmd *cli.Command, s *sessionV8) *probe.Error {
+	for name, values := range s.Header.CommandFlags {
+		for _, v := range values {
+			if e := cmd.Set(name, v); e != nil {
+				return probe.NewError(e).Trace(name, v)
+			}
+		}
+	}
+	return nil
+}
+
+// the checksum of resumable multipart uploads is always computed by the
+// server, uploads requiring client side checksums are restarted instead.
+func isResumableUpload(md5 bool, checksum openstor.ChecksumType) bool {
+	return !md5 && !checksum.IsSet()
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newSessionTestURLs(source, target string, size int64) URLs {
+	return URLs{
+		SourceContent: &ClientContent{URL: *newClientURL(source), Size: size},
+		TargetContent: &ClientContent{URL: *newClientURL(target)},
+	}
+}
+
+func TestSessionJournalReplay(t *testing.T) {
+	prevConfigDir := mcCustomConfigDir
+	mcCustomConfigDir = t.TempDir()
+	defer func() { mcCustomConfigDir = prevConfigDir }()
+
+	args := []string{"/data", "myminio/bucket"}
+	flags := map[string][]string{"recursive": {"true"}}
+	s := newCommandSession("cp", args, flags)
+	if s == nil {
+		t.Fatal("unable to create session")
+	}
+
+	done := newSessionTestURLs("/data/done", "myminio/bucket/done", 10)
+	failed := newSessionTestURLs("/data/failed", "myminio/bucket/failed", 20)
+	inFlight := newSessionTestURLs("/data/inflight", "myminio/bucket/inflight", 30)
+	queued := newSessionTestURLs("/data/queued", "myminio/bucket/queued", 40)
+	for _, urls := range []URLs{done, failed, inFlight, queued} {
+		s.Queued(urls)
+	}
+	for _, urls := range []URLs{done, failed, inFlight} {
+		s.Started(urls)
+	}
+	s.Finished(done)
+	failed.Error = errDummy()
+	s.Finished(failed)
+	s.SetUploadID(inFlight.SourceContent.URL.String(), "upload-1")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate an entry partially written when the process was killed.
+	f, e := os.OpenFile(getSessionDataFile(s.SessionID), os.O_WRONLY|os.O_APPEND, 0o600)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = f.WriteString(`{"op":"done","src":"/data/inf`); e != nil {
+		t.Fatal(e)
+	}
+	f.Close()
+
+	loaded, err := loadSessionV8(s.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Header.CommandType != "cp" || !reflect.DeepEqual(loaded.Header.CommandArgs, args) || !reflect.DeepEqual(loaded.Header.CommandFlags, flags) {
+		t.Fatalf("unexpected session header %+v", loaded.Header)
+	}
+	if !loaded.IsCompleted("/data/done") || loaded.IsCompleted("/data/failed") || loaded.IsCompleted("/data/inflight") {
+		t.Fatal("unexpected completed URLs after replay")
+	}
+	expected := []sessionV8Entry{{
+		Source:   "/data/inflight",
+		Target:   inFlight.TargetContent.URL.String(),
+		Size:     30,
+		Status:   sessionOpStarted,
+		UploadID: "upload-1",
+	}}
+	if got := loaded.InFlight(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected in-flight %+v, got %+v", expected, got)
+	}
+	expectedStat := sessionV8Stat{
+		TotalObjects:     4,
+		TotalBytes:       100,
+		CompletedObjects: 1,
+		CompletedBytes:   10,
+		InFlightObjects:  1,
+		FailedObjects:    1,
+		PendingUploads:   1,
+	}
+	if got := loaded.Stat(); got != expectedStat {
+		t.Fatalf("expected stat %+v, got %+v", expectedStat, got)
+	}
+
+	if uploadID := loaded.ResumableUploadID(inFlight); uploadID != "upload-1" {
+		t.Fatalf("expected upload-1 to be resumed, got %q", uploadID)
+	}
+	if uploadID := loaded.ResumableUploadID(newSessionTestURLs("/data/inflight", "myminio/bucket/inflight", 31)); uploadID != "" {
+		t.Fatalf("expected upload of a resized source not to be resumed, got %q", uploadID)
+	}
+	if uploadID := loaded.ResumableUploadID(newSessionTestURLs("/data/inflight", "myminio/other/inflight", 30)); uploadID != "" {
+		t.Fatalf("expected upload to another target not to be resumed, got %q", uploadID)
+	}
+	rewritten := newSessionTestURLs("/data/inflight", "myminio/bucket/inflight", 30)
+	rewritten.SourceContent.Time = time.Now()
+	if uploadID := loaded.ResumableUploadID(rewritten); uploadID != "" {
+		t.Fatalf("expected upload of a rewritten source not to be resumed, got %q", uploadID)
+	}
+	rewritten = newSessionTestURLs("/data/inflight", "myminio/bucket/inflight", 30)
+	rewritten.SourceContent.ETag = "d41d8cd98f00b204e9800998ecf8427e"
+	if uploadID := loaded.ResumableUploadID(rewritten); uploadID != "" {
+		t.Fatalf("expected upload of a source with another ETag not to be resumed, got %q", uploadID)
+	}
+
+	// Resume the session, the source changed in the meantime.
+	if err = loaded.Open(); err != nil {
+		t.Fatal(err)
+	}
+	loaded.Queued(queued)
+	loaded.Queued(newSessionTestURLs("/data/inflight", "myminio/bucket/inflight", 31))
+	loaded.ScanDone()
+	if err = loaded.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := loadSessionV8(s.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploadID := resumed.ResumableUploadID(newSessionTestURLs("/data/inflight", "myminio/bucket/inflight", 31)); uploadID != "" {
+		t.Fatalf("expected upload of a requeued resized source to be forgotten, got %q", uploadID)
+	}
+	if stat := resumed.Stat(); !stat.Scanned || stat.TotalObjects != 4 || stat.PendingUploads != 0 {
+		t.Fatalf("unexpected stat after resume %+v", stat)
+	}
+
+	finishCommandSession(resumed, false)
+	if isSessionExists(s.SessionID) {
+		t.Fatal("expected session to be removed after a clean run")
+	}
+	if _, e = os.Stat(getSessionDataFile(s.SessionID)); !os.IsNotExist(e) {
+		t.Fatalf("expected session journal to be removed, got %v", e)
+	}
+}
+
+func TestSessionListClear(t *testing.T) {
+	prevConfigDir := mcCustomConfigDir
+	mcCustomConfigDir = t.TempDir()
+	defer func() { mcCustomConfigDir = prevConfigDir }()
+
+	var sids []string
+	for range 3 {
+		s := newCommandSession("mirror", []string{"/data", "myminio/bucket"}, nil)
+		if s == nil {
+			t.Fatal("unable to create session")
+		}
+		s.Queued(newSessionTestURLs("/data/a", "myminio/bucket/a", 1))
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		sids = append(sids, s.SessionID)
+	}
+	// A header without journal is listed as well.
+	if e := os.Remove(getSessionDataFile(sids[2])); e != nil {
+		t.Fatal(e)
+	}
+
+	listed := getSessionIDs()
+	if len(listed) != len(sids) {
+		t.Fatalf("expected %d sessions, got %v", len(sids), listed)
+	}
+	for i := 1; i < len(listed); i++ {
+		if listed[i-1] >= listed[i] {
+			t.Fatalf("expected sorted session IDs, got %v", listed)
+		}
+	}
+
+	ctx := context.Background()
+	listCmd := sessionListCmd
+	if e := listCmd.Run(ctx, []string{"list"}); e != nil {
+		t.Fatal(e)
+	}
+
+	clearCmd := sessionClearCmd
+	if e := clearCmd.Run(ctx, []string{"clear", sids[0]}); e != nil {
+		t.Fatal(e)
+	}
+	if isSessionExists(sids[0]) {
+		t.Fatalf("expected session %s to be cleared", sids[0])
+	}
+	if _, e := os.Stat(getSessionDataFile(sids[0])); !os.IsNotExist(e) {
+		t.Fatalf("expected journal of session %s to be cleared, got %v", sids[0], e)
+	}
+	if got := len(getSessionIDs()); got != 2 {
+		t.Fatalf("expected 2 sessions left, got %d", got)
+	}
+
+	clearCmd = sessionClearCmd
+	if e := clearCmd.Run(ctx, []string{"clear", "--all"}); e != nil {
+		t.Fatal(e)
+	}
+	if left := getSessionIDs(); len(left) != 0 {
+		t.Fatalf("expected all sessions to be cleared, got %v", left)
+	}
+}
@@ -51,16 +51,19 @@ func trapSignals(sig ...os.Signal) {
 	// Cancel the global context
 	globalCancel()
 
-	var exitCode int
+	os.Exit(signalExitStatus(s))
+}
+
+// signalExitStatus returns the exit status of the process stopped by s.
+func signalExitStatus(s os.Signal) int {
 	switch s.String() {
 	case "interrupt":
-		exitCode = globalCancelExitStatus
+		return globalCancelExitStatus
 	case "killed":
-		exitCode = globalKillExitStatus
+		return globalKillExitStatus
 	case "terminated":
-		exitCode = globalTerminatExitStatus
+		return globalTerminatExitStatus
 	default:
-		exitCode = globalErrorExitStatus
+		return globalErrorExitStatus
 	}
-	os.Exit(exitCode)
 }
@@ -0,0 +1,652 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	json "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+// Conflict policies of 'mc sync'.
+const (
+	syncConflictFail       = "fail"
+	syncConflictNewestWins = "newest-wins"
+	syncConflictKeepBoth   = "keep-both"
+)
+
+// sync specific flags.
+var (
+	syncFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:  "conflict",
+			Usage: "how to resolve object(s) changed on both sides: 'fail', 'newest-wins' or 'keep-both'",
+			Value: syncConflictFail,
+		},
+		&cli.StringFlag{
+			Name:  "conflict-suffix",
+			Usage: "suffix added to the older version of a conflicting object with '--conflict keep-both' (default: '.conflict-TIMESTAMP')",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "only show the changes that would be propagated",
+		},
+		&cli.StringSliceFlag{
+			Name:  "exclude",
+			Usage: "exclude object(s) that match specified object name pattern",
+		},
+		&cli.BoolFlag{
+			Name:  "reset",
+			Usage: "forget the state of previous syncs, nothing is removed on either side",
+		},
+		&cli.IntFlag{
+			Name:  "max-workers",
+			Usage: "maximum number of concurrent transfers",
+			Value: 4,
+		},
+	}
+)
+
+// Synchronize two folders in both directions.
+var syncCmd = cli.Command{
+	Name:         "sync",
+	Usage:        "synchronize object(s) between two sites in both directions",
+	Action:       mainSync,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(append(syncFlags, encFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] FIRST SECOND
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  Sync keeps a snapshot of the objects both sides agreed on after the last run in the
+  'sync' folder of the configuration. Objects created, modified or removed on one side
+  since then are created, modified or removed on the other side.
+
+  Objects changed on both sides are conflicts, they are resolved by '--conflict':
+    fail         report the conflict and leave both sides untouched (default).
+    newest-wins  the most recently modified version is copied over the other one.
+    keep-both    like newest-wins, the older version is kept on both sides with a suffix.
+  A removal never wins over a modification, the modified object is copied back instead.
+
+  The first sync of two folders only creates the objects missing on either side, objects
+  present on both sides with different content are conflicts.
+
+ENVIRONMENT VARIABLES:
+  MC_ENC_KMS: KMS encryption key in the form of (alias/prefix=key).
+  MC_ENC_S3: S3 encryption key in the form of (alias/prefix=key).
+
+EXAMPLES:
+  1. Synchronize a local folder with a bucket on MinIO cloud storage.
+     {{.Prompt}} {{.HelpName}} ~/Documents play/documents
+
+  2. Synchronize two buckets, the most recent version of objects changed on both sides wins.
+     {{.Prompt}} {{.HelpName}} --conflict newest-wins site1/photos site2/photos
+
+  3. Synchronize two buckets, keeping both versions of objects changed on both sides.
+     {{.Prompt}} {{.HelpName}} --conflict keep-both --conflict-suffix .old site1/photos site2/photos
+
+  4. Show what would be synchronized, without changing anything.
+     {{.Prompt}} {{.HelpName}} --dry-run ~/Documents play/documents
+`,
+}
+
+// syncMessage container for sync messages.
+type syncMessage struct {
+	Status string `json:"status"`
+	Op     string `json:"op"`
+	Source string `json:"source,omitempty"`
+	Target string `json:"target,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// String colorized sync message.
+func (s syncMessage) String() string {
+	switch s.Op {
+	case "remove":
+		return console.Colorize("SyncRemove", fmt.Sprintf("Removed `%s`.", s.Target))
+	case "conflict":
+		return console.Colorize("SyncConflict", fmt.Sprintf("Conflict between `%s` and `%s`, %s.", s.Source, s.Target, s.Reason))
+	}
+	return console.Colorize("SyncCopy", fmt.Sprintf("`%s` -> `%s`", s.Source, s.Target))
+}
+
+// JSON jsonified sync message.
+func (s syncMessage) JSON() string {
+	s.Status = "success"
+	jsonMessageBytes, e := json.MarshalIndent(s, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(jsonMessageBytes)
+}
+
+// syncSide - one of the two synchronized folders.
+type syncSide struct {
+	alias string
+	url   string
+	clnt  Client
+}
+
+// objectURL returns the URL of path on this side.
+func (s syncSide) objectURL(path string) string {
+	return urlJoinPath(s.url, path)
+}
+
+// displayURL returns the aliased URL of path on this side.
+func (s syncSide) displayURL(path string) string {
+	return filepath.ToSlash(filepath.Join(s.alias, newClientURL(s.objectURL(path)).Path))
+}
+
+// stateKey identifies the side in the sync state. Aliased URLs are kept
+// as is, so that the state survives changes of the alias endpoint, local
+// folders are made absolute.
+func (s syncSide) stateKey(aliasedURL string) string {
+	if s.alias == "" {
+		return filepath.ToSlash(filepath.Clean(s.url))
+	}
+	return filepath.ToSlash(filepath.Clean(aliasedURL))
+}
+
+// syncPath - a path listed on one or both sides.
+type syncPath struct {
+	path          string
+	first, second *ClientContent
+}
+
+// syncDecision - what to do with a path.
+type syncDecision int
+
+const (
+	syncNone syncDecision = iota
+	syncForget
+	syncCopyToFirst
+	syncCopyToSecond
+	syncRemoveFromFirst
+	syncRemoveFromSecond
+	syncConflict
+)
+
+// decideSync compares both sides of a path with the state recorded by the
+// last sync. known is false if the path is not in the state. equal is only
+// called for objects of same size changed on both sides.
+func decideSync(p syncPath, prev syncStateEntry, known bool, equal func() bool) syncDecision {
+	if p.first == nil && p.second == nil {
+		return syncForget
+	}
+
+	firstChanged := !known || !prev.First.unchanged(p.first)
+	secondChanged := !known || !prev.Second.unchanged(p.second)
+	// An object is removed if it existed on this side after the last sync.
+	firstRemoved := p.first == nil && prev.First != nil
+	secondRemoved := p.second == nil && prev.Second != nil
+
+	switch {
+	case !firstChanged && !secondChanged:
+		return syncNone
+	case firstChanged && !secondChanged:
+		if p.first == nil {
+			return syncRemoveFromSecond
+		}
+		return syncCopyToSecond
+	case !firstChanged && secondChanged:
+		if p.second == nil {
+			return syncRemoveFromFirst
+		}
+		return syncCopyToFirst
+	}
+
+	// Changed on both sides.
+	switch {
+	case p.second == nil:
+		if firstRemoved || secondRemoved {
+			return syncConflict
+		}
+		return syncCopyToSecond
+	case p.first == nil:
+		if firstRemoved || secondRemoved {
+			return syncConflict
+		}
+		return syncCopyToFirst
+	}
+	if p.first.Size == p.second.Size && equal() {
+		return syncNone
+	}
+	return syncConflict
+}
+
+// resolveSyncConflict returns the copy resolving a conflict according to
+// policy, keepLoser is set if the older version must be kept as well.
+func resolveSyncConflict(p syncPath, policy string) (decision syncDecision, keepLoser bool) {
+	switch {
+	case policy == syncConflictFail:
+		return syncConflict, false
+	case p.first == nil:
+		// Modifications win over removals.
+		return syncCopyToFirst, false
+	case p.second == nil:
+		return syncCopyToSecond, false
+	case p.second.Time.After(p.first.Time):
+		return syncCopyToFirst, policy == syncConflictKeepBoth
+	}
+	return syncCopyToSecond, policy == syncConflictKeepBoth
+}
+
+// conflictPath inserts suffix before the extension of the object name.
+func conflictPath(p, suffix string) string {
+	ext := path.Ext(p)
+	if strings.Contains(ext, "/") {
+		ext = ""
+	}
+	return strings.TrimSuffix(p, ext) + suffix + ext
+}
+
+// syncJob - state of a running sync.
+type syncJob struct {
+	first, second  syncSide
+	policy, suffix string
+	dryRun         bool
+	encKeyDB       map[string][]prefixSSEPair
+
+	mutex     sync.Mutex
+	state     *syncStateV1
+	newState  map[string]syncStateEntry
+	failed    int
+	conflicts int
+}
+
+// listSync lists both sides and pairs the objects by path. Any listing
+// error aborts the sync, a partial listing would look like removals.
+func (sj *syncJob) listSync(ctx context.Context, excludes []string) ([]syncPath, *probe.Error) {
+	firstCh := sj.first.clnt.List(ctx, ListOptions{Recursive: true, ShowDir: DirNone})
+	secondCh := sj.second.clnt.List(ctx, ListOptions{Recursive: true, ShowDir: DirNone})
+
+	var paths []syncPath
+	for d := range difference(sj.first.url, firstCh, sj.second.url, secondCh, mirrorOptions{}, true, nil) {
+		if d.Error != nil {
+			return nil, d.Error
+		}
+		var p syncPath
+		switch d.Diff {
+		case differInFirst:
+			p = syncPath{path: strings.TrimPrefix(d.FirstURL, sj.first.url), first: d.firstContent}
+		case differInSecond:
+			p = syncPath{path: strings.TrimPrefix(d.SecondURL, sj.second.url), second: d.secondContent}
+		case differInType:
+			return nil, errInvalidTarget(d.SecondURL).Trace(d.FirstURL)
+		default:
+			p = syncPath{path: strings.TrimPrefix(d.FirstURL, sj.first.url), first: d.firstContent, second: d.secondContent}
+		}
+		p.path = filepath.ToSlash(p.path)
+		if matchExcludeOptions(excludes, p.path, newClientURL(sj.first.url).Type) {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// copyObject copies path from one side to another, under a new name if set.
+func (sj *syncJob) copyObject(ctx context.Context, from, to syncSide, content *ClientContent, targetPath string) *probe.Error {
+	printMsg(syncMessage{
+		Op:     "copy",
+		Source: from.displayURL(strings.TrimPrefix(content.URL.String(), from.url)),
+		Target: to.displayURL(targetPath),
+		Size:   content.Size,
+	})
+	if sj.dryRun {
+		return nil
+	}
+	urls := uploadSourceToTargetURL(ctx, uploadSourceToTargetURLOpts{
+		urls: URLs{
+			SourceAlias:   from.alias,
+			SourceContent: content,
+			TargetAlias:   to.alias,
+			TargetContent: &ClientContent{URL: *newClientURL(to.objectURL(targetPath))},
+		},
+		encKeyDB: sj.encKeyDB,
+	})
+	return urls.Error
+}
+
+// removeObject removes path from a side.
+func (sj *syncJob) removeObject(ctx context.Context, side syncSide, path string) *probe.Error {
+	printMsg(syncMessage{Op: "remove", Target: side.displayURL(path)})
+	if sj.dryRun {
+		return nil
+	}
+	clnt, err := newClientFromAlias(side.alias, side.objectURL(path))
+	if err != nil {
+		return err
+	}
+	contentCh := make(chan *ClientContent, 1)
+	contentCh <- &ClientContent{URL: *newClientURL(side.objectURL(path))}
+	close(contentCh)
+	for result := range clnt.Remove(ctx, false, false, false, false, contentCh) {
+		if result.Err != nil {
+			return result.Err
+		}
+	}
+	return nil
+}
+
+// statObject returns the current state of path on a side, nil if missing.
+func (sj *syncJob) statObject(ctx context.Context, side syncSide, path string) (*syncStateObject, *probe.Error) {
+	clnt, err := newClientFromAlias(side.alias, side.objectURL(path))
+	if err != nil {
+		return nil, err
+	}
+	content, err := clnt.Stat(ctx, StatOptions{headOnly: true})
+	if err != nil {
+		switch err.ToGoError().(type) {
+		case ObjectMissing, PathNotFound:
+			return nil, nil
+		}
+		return nil, err
+	}
+	return newSyncStateObject(content), nil
+}
+
+// recordState updates the new state of path from both sides.
+func (sj *syncJob) recordState(ctx context.Context, path string) *probe.Error {
+	if sj.dryRun {
+		return nil
+	}
+	first, err := sj.statObject(ctx, sj.first, path)
+	if err != nil {
+		return err.Trace(path)
+	}
+	second, err := sj.statObject(ctx, sj.second, path)
+	if err != nil {
+		return err.Trace(path)
+	}
+
+	sj.mutex.Lock()
+	defer sj.mutex.Unlock()
+	if first != nil || second != nil {
+		sj.newState[path] = syncStateEntry{First: first, Second: second}
+	}
+	return nil
+}
+
+// keepState carries the previous state of path over, if any.
+func (sj *syncJob) keepState(path string) {
+	sj.mutex.Lock()
+	defer sj.mutex.Unlock()
+	if entry, ok := sj.state.Entries[path]; ok {
+		sj.newState[path] = entry
+	}
+}
+
+// syncOne propagates the changes of a single path.
+func (sj *syncJob) syncOne(ctx context.Context, p syncPath) {
+	prev, known := sj.state.Entries[p.path]
+	decision := decideSync(p, prev, known, func() bool {
+		differ, err := contentDiffers(ctx, sj.first.clnt, sj.second.clnt, p.first, p.second)
+		return err == nil && !differ
+	})
+
+	var keepLoser bool
+	if decision == syncConflict {
+		decision, keepLoser = resolveSyncConflict(p, sj.policy)
+	}
+
+	var err *probe.Error
+	switch decision {
+	case syncNone:
+		sj.mutex.Lock()
+		sj.newState[p.path] = syncStateEntry{First: newSyncStateObject(p.first), Second: newSyncStateObject(p.second)}
+		sj.mutex.Unlock()
+		return
+	case syncForget:
+		return
+	case syncConflict:
+		reason := "modified on both sides"
+		if p.first == nil || p.second == nil {
+			reason = "removed on one side and modified on the other"
+		}
+		printMsg(syncMessage{
+			Op:     "conflict",
+			Source: sj.first.displayURL(p.path),
+			Target: sj.second.displayURL(p.path),
+			Reason: reason,
+		})
+		sj.keepState(p.path)
+		sj.mutex.Lock()
+		sj.conflicts++
+		sj.mutex.Unlock()
+		return
+	case syncCopyToFirst, syncCopyToSecond:
+		from, to, content, loser := sj.first, sj.second, p.first, p.second
+		if decision == syncCopyToFirst {
+			from, to, content, loser = sj.second, sj.first, p.second, p.first
+		}
+		if keepLoser {
+			kept := conflictPath(p.path, sj.suffix)
+			if err = sj.copyObject(ctx, to, to, loser, kept); err == nil {
+				err = sj.copyObject(ctx, to, from, loser, kept)
+			}
+			if err == nil {
+				err = sj.recordState(ctx, kept)
+			}
+		}
+		if err == nil {
+			err = sj.copyObject(ctx, from, to, content, p.path)
+		}
+	case syncRemoveFromFirst:
+		err = sj.removeObject(ctx, sj.first, p.path)
+	case syncRemoveFromSecond:
+		err = sj.removeObject(ctx, sj.second, p.path)
+	}
+
+	if err == nil {
+		err = sj.recordState(ctx, p.path)
+	}
+	if err != nil {
+		errorIf(err.Trace(p.path), "Unable to synchronize `%s`.", p.path)
+		sj.keepState(p.path)
+		sj.mutex.Lock()
+		sj.failed++
+		sj.mutex.Unlock()
+	}
+}
+
+// newSyncSide expands an aliased URL of a synchronized folder.
+func newSyncSide(aliasedURL string) syncSide {
+	separator := string(newClientURL(aliasedURL).Separator)
+	if !strings.HasSuffix(aliasedURL, separator) {
+		aliasedURL += separator
+	}
+	alias, urlStr, _ := mustExpandAlias(aliasedURL)
+	if alias == "" {
+		// Listings of local folders report absolute paths.
+		if absURL, e := filepath.Abs(urlStr); e == nil {
+			urlStr = absURL + separator
+		}
+	}
+	clnt, err := newClientFromAlias(alias, urlStr)
+	fatalIf(err.Trace(aliasedURL), "Unable to initialize `%s`.", aliasedURL)
+	return syncSide{alias: alias, url: urlStr, clnt: clnt}
+}
+
+// checkSyncSyntax - validate all the passed arguments
+func checkSyncSyntax(ctx context.Context, cmd *cli.Command, encKeyDB map[string][]prefixSSEPair) {
+	if cmd.NArg() != 2 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+	for _, arg := range cmd.Args().Slice() {
+		if strings.TrimSpace(arg) == "" {
+			fatalIf(errInvalidArgument().Trace(cmd.Args().Slice()...), "Unable to validate empty argument.")
+		}
+		_, content, err := url2Stat(ctx, url2StatOptions{urlStr: arg, encKeyDB: encKeyDB})
+		if err != nil {
+			// Empty prefixes are fine.
+			if _, ok := err.ToGoError().(ObjectMissing); !ok {
+				fatalIf(err.Trace(arg), "Unable to stat `%s`.", arg)
+			}
+			continue
+		}
+		if !content.Type.IsDir() {
+			fatalIf(errInvalidArgument().Trace(arg), "`%s` is not a folder.", arg)
+		}
+	}
+
+	switch policy := cmd.String("conflict"); policy {
+	case syncConflictFail, syncConflictNewestWins, syncConflictKeepBoth:
+	default:
+		fatalIf(errInvalidArgument().Trace(policy), "Unknown conflict policy `%s`.", policy)
+	}
+	if cmd.Int("max-workers") < 1 {
+		fatalIf(errInvalidArgument().Trace(fmt.Sprint(cmd.Int("max-workers"))), "`--max-workers` must be at least 1.")
+	}
+}
+
+// mainSync is the entry point for sync command.
+func mainSync(ctx context.Context, cmd *cli.Command) error {
+	ctx, cancelSync := context.WithCancel(globalContext)
+	defer cancelSync()
+
+	console.SetColor("SyncCopy", color.New(color.FgGreen))
+	console.SetColor("SyncRemove", color.New(color.FgRed))
+	console.SetColor("SyncConflict", color.New(color.FgYellow, color.Bold))
+
+	encKeyDB, err := validateAndCreateEncryptionKeys(ctx, cmd)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	checkSyncSyntax(ctx, cmd, encKeyDB)
+
+	firstArg, secondArg := cmd.Args().Get(0), cmd.Args().Get(1)
+	suffix := cmd.String("conflict-suffix")
+	if suffix == "" {
+		suffix = ".conflict-" + UTCNow().Format("20060102T150405Z")
+	}
+	sj := &syncJob{
+		first:    newSyncSide(firstArg),
+		second:   newSyncSide(secondArg),
+		policy:   cmd.String("conflict"),
+		suffix:   suffix,
+		dryRun:   cmd.Bool("dry-run"),
+		encKeyDB: encKeyDB,
+		newState: make(map[string]syncStateEntry),
+	}
+
+	firstKey, secondKey := sj.first.stateKey(firstArg), sj.second.stateKey(secondArg)
+	if cmd.Bool("reset") && !sj.dryRun {
+		fatalIf(removeSyncState(firstKey, secondKey), "Unable to remove the sync state.")
+	}
+	sj.state, err = loadSyncState(firstKey, secondKey)
+	fatalIf(err, "Unable to load the state of the last sync.")
+	if cmd.Bool("reset") {
+		sj.state.Entries = make(map[string]syncStateEntry)
+	}
+
+	paths, err := sj.listSync(ctx, cmd.StringSlice("exclude"))
+	fatalIf(err, "Unable to list `%s` and `%s`.", firstArg, secondArg)
+
+	// Refuse to propagate the removal of everything, this usually is
+	// a misconfigured alias or a wrong path rather than the intent.
+	if len(sj.state.Entries) > 0 {
+		var firstCount, secondCount int
+		for _, p := range paths {
+			if p.first != nil {
+				firstCount++
+			}
+			if p.second != nil {
+				secondCount++
+			}
+		}
+		for arg, count := range map[string]int{firstArg: firstCount, secondArg: secondCount} {
+			if count == 0 {
+				fatalIf(errDummy().Trace(arg),
+					"`%s` is empty, refusing to remove all objects on the other side. Use `--reset` to copy them back instead.", arg)
+			}
+		}
+	}
+
+	// Paths excluded or not listed anymore keep their previous state,
+	// unless they disappeared from both sides.
+	listed := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		listed[p.path] = struct{}{}
+	}
+	for path, entry := range sj.state.Entries {
+		if _, ok := listed[path]; !ok && matchExcludeOptions(cmd.StringSlice("exclude"), path, newClientURL(sj.first.url).Type) {
+			sj.newState[path] = entry
+		}
+	}
+
+	pathCh := make(chan syncPath)
+	var wg sync.WaitGroup
+	for i := 0; i < cmd.Int("max-workers"); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range pathCh {
+				sj.syncOne(ctx, p)
+			}
+		}()
+	}
+loop:
+	for _, p := range paths {
+		select {
+		case <-ctx.Done():
+			break loop
+		case pathCh <- p:
+		}
+	}
+	close(pathCh)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		// Interrupted, paths not processed keep their previous state.
+		for path, entry := range sj.state.Entries {
+			if _, ok := sj.newState[path]; !ok {
+				sj.newState[path] = entry
+			}
+		}
+	}
+
+	if !sj.dryRun {
+		sj.state.Entries = sj.newState
+		fatalIf(sj.state.save(), "Unable to save the sync state.")
+	}
+
+	if sj.conflicts > 0 {
+		errorIf(errDummy().Trace(firstArg, secondArg),
+			"%d conflict(s) left unresolved, use `--conflict` to resolve them.", sj.conflicts)
+	}
+	if sj.failed > 0 || sj.conflicts > 0 || ctx.Err() != nil {
+		return exitStatus(globalErrorExitStatus)
+	}
+	return nil
+}
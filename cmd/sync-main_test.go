@@ -0,0 +1,114 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecideSync(t *testing.T) {
+	now := time.Now()
+	obj := func(size int64, mtime time.Time) *ClientContent {
+		return &ClientContent{Size: size, Time: mtime}
+	}
+	agreed := syncStateEntry{
+		First:  &syncStateObject{Size: 1, ModTime: now},
+		Second: &syncStateObject{Size: 1, ModTime: now},
+	}
+	equal := func() bool { return true }
+	differ := func() bool { return false }
+
+	testCases := []struct {
+		first, second *ClientContent
+		prev          syncStateEntry
+		known         bool
+		equal         func() bool
+		decision      syncDecision
+	}{
+		// Never synced.
+		{obj(1, now), nil, syncStateEntry{}, false, differ, syncCopyToSecond},
+		{nil, obj(1, now), syncStateEntry{}, false, differ, syncCopyToFirst},
+		{obj(1, now), obj(1, now), syncStateEntry{}, false, equal, syncNone},
+		{obj(1, now), obj(1, now), syncStateEntry{}, false, differ, syncConflict},
+		{obj(1, now), obj(2, now), syncStateEntry{}, false, equal, syncConflict},
+		// Unchanged since the last sync.
+		{obj(1, now), obj(1, now), agreed, true, differ, syncNone},
+		{obj(1, now.Add(time.Millisecond)), obj(1, now), agreed, true, differ, syncNone},
+		{nil, nil, agreed, true, differ, syncForget},
+		// Changed on one side.
+		{obj(2, now), obj(1, now), agreed, true, differ, syncCopyToSecond},
+		{obj(1, now), obj(1, now.Add(time.Hour)), agreed, true, differ, syncCopyToFirst},
+		{nil, obj(1, now), agreed, true, differ, syncRemoveFromSecond},
+		{obj(1, now), nil, agreed, true, differ, syncRemoveFromFirst},
+		// Changed on both sides.
+		{obj(2, now), obj(2, now.Add(time.Hour)), agreed, true, equal, syncNone},
+		{obj(2, now), obj(3, now.Add(time.Hour)), agreed, true, equal, syncConflict},
+		{nil, obj(2, now), agreed, true, differ, syncConflict},
+		{obj(2, now), nil, agreed, true, differ, syncConflict},
+	}
+
+	for i, test := range testCases {
+		decision := decideSync(syncPath{first: test.first, second: test.second}, test.prev, test.known, test.equal)
+		if decision != test.decision {
+			t.Errorf("Test %d: expected decision %d, got %d", i+1, test.decision, decision)
+		}
+	}
+}
+
+func TestResolveSyncConflict(t *testing.T) {
+	older := &ClientContent{Size: 1, Time: time.Now()}
+	newer := &ClientContent{Size: 2, Time: older.Time.Add(time.Minute)}
+
+	testCases := []struct {
+		first, second *ClientContent
+		policy        string
+		decision      syncDecision
+		keepLoser     bool
+	}{
+		{older, newer, syncConflictFail, syncConflict, false},
+		{older, newer, syncConflictNewestWins, syncCopyToFirst, false},
+		{newer, older, syncConflictNewestWins, syncCopyToSecond, false},
+		{older, newer, syncConflictKeepBoth, syncCopyToFirst, true},
+		{nil, older, syncConflictNewestWins, syncCopyToFirst, false},
+		{older, nil, syncConflictKeepBoth, syncCopyToSecond, false},
+	}
+
+	for i, test := range testCases {
+		decision, keepLoser := resolveSyncConflict(syncPath{first: test.first, second: test.second}, test.policy)
+		if decision != test.decision || keepLoser != test.keepLoser {
+			t.Errorf("Test %d: expected (%d, %t), got (%d, %t)", i+1, test.decision, test.keepLoser, decision, keepLoser)
+		}
+	}
+}
+
+func TestConflictPath(t *testing.T) {
+	testCases := []struct {
+		path, expected string
+	}{
+		{"report.pdf", "report.old.pdf"},
+		{"dir/report.tar.gz", "dir/report.tar.old.gz"},
+		{"dir.d/README", "dir.d/README.old"},
+		{"noext", "noext.old"},
+	}
+	for _, test := range testCases {
+		if got := conflictPath(test.path, ".old"); got != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, got)
+		}
+	}
+}
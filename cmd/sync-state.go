@@ -0,0 +1,151 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/quick"
+)
+
+// syncStateObject - state of an object on one side after the last sync.
+type syncStateObject struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"lastModified"`
+	ETag    string    `json:"etag,omitempty"`
+}
+
+// syncStateEntry - state of a path on both sides after the last sync.
+type syncStateEntry struct {
+	First  *syncStateObject `json:"first,omitempty"`
+	Second *syncStateObject `json:"second,omitempty"`
+}
+
+// syncStateV1 - snapshot of the tree both sides agreed on after the last sync.
+type syncStateV1 struct {
+	Version string                    `json:"version"`
+	First   string                    `json:"first"`
+	Second  string                    `json:"second"`
+	Time    time.Time                 `json:"time"`
+	Entries map[string]syncStateEntry `json:"entries"`
+}
+
+func newSyncStateObject(c *ClientContent) *syncStateObject {
+	if c == nil {
+		return nil
+	}
+	return &syncStateObject{Size: c.Size, ModTime: c.Time, ETag: c.ETag}
+}
+
+// unchanged returns true if the content is the same as recorded in the state.
+// Modification times are compared at second precision since listings and
+// HEAD requests do not report them with the same precision.
+func (o *syncStateObject) unchanged(c *ClientContent) bool {
+	if o == nil || c == nil {
+		return o == nil && c == nil
+	}
+	if o.Size != c.Size || o.ETag != c.ETag {
+		return false
+	}
+	return o.ModTime.Truncate(time.Second).Equal(c.Time.Truncate(time.Second))
+}
+
+// Get sync state dir name.
+func getSyncStateDir() (string, *probe.Error) {
+	configDir, err := getMcConfigDir()
+	if err != nil {
+		return "", err.Trace()
+	}
+
+	return filepath.Join(configDir, globalSyncStateDir), nil
+}
+
+// Get sync state dir name or die. (NOTE: This `Die` approach is only OK for mc like tools.).
+func mustGetSyncStateDir() string {
+	syncStateDir, err := getSyncStateDir()
+	fatalIf(err.Trace(), "Unable to determine sync state folder.")
+	return syncStateDir
+}
+
+// getSyncStateFile returns the state file of a pair of URLs, the state
+// is specific to the order of the URLs.
+func getSyncStateFile(first, second string) string {
+	sum := sha256.Sum256([]byte(first + "\n" + second))
+	return filepath.Join(mustGetSyncStateDir(), hex.EncodeToString(sum[:8])+".json")
+}
+
+// loadSyncState loads the state of the last sync between first and second,
+// an empty state is returned if they were never synced.
+func loadSyncState(first, second string) (*syncStateV1, *probe.Error) {
+	state := &syncStateV1{
+		Version: globalSyncStateVersion,
+		First:   first,
+		Second:  second,
+		Entries: make(map[string]syncStateEntry),
+	}
+
+	stateFile := getSyncStateFile(first, second)
+	if _, e := os.Stat(stateFile); os.IsNotExist(e) {
+		return state, nil
+	}
+
+	qs, e := quick.NewConfig(state, nil)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(stateFile)
+	}
+	if e = qs.Load(stateFile); e != nil {
+		return nil, probe.NewError(e).Trace(stateFile)
+	}
+	if state.Version != globalSyncStateVersion {
+		return nil, errDummy().Trace(stateFile, state.Version)
+	}
+	if state.Entries == nil {
+		state.Entries = make(map[string]syncStateEntry)
+	}
+	return state, nil
+}
+
+// save writes the state to the config folder.
+func (s *syncStateV1) save() *probe.Error {
+	if e := os.MkdirAll(mustGetSyncStateDir(), 0o700); e != nil {
+		return probe.NewError(e)
+	}
+
+	s.Time = UTCNow()
+	qs, e := quick.NewConfig(s, nil)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = qs.Save(getSyncStateFile(s.First, s.Second)); e != nil {
+		return probe.NewError(e).Trace(s.First, s.Second)
+	}
+	return nil
+}
+
+// removeSyncState forgets the state of the last sync between first and second.
+func removeSyncState(first, second string) *probe.Error {
+	if e := os.Remove(getSyncStateFile(first, second)); e != nil && !os.IsNotExist(e) {
+		return probe.NewError(e)
+	}
+	return nil
+}
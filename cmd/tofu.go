@@ -95,7 +95,7 @@ func promptTrustSelfSignedCert(ctx context.Context, endpoint, alias string) (*x5
 			Proxy: http.ProxyFromEnvironment,
 			DialTLSContext: newCustomDialTLSContext(&tls.Config{
 				RootCAs: globalRootCAs, // make sure to use loaded certs before probing
-			}),
+			}, globalResolvers),
 		},
 	}
 
@@ -169,7 +169,7 @@ func fetchPeerCertificate(ctx context.Context, endpoint string) (*x509.Certifica
 		Transport: &http.Transport{
 			DialTLSContext: newCustomDialTLSContext(&tls.Config{
 				InsecureSkipVerify: true,
-			}),
+			}, globalResolvers),
 		},
 	}
 	resp, e := client.Do(req)
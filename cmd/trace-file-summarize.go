@@ -0,0 +1,246 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	jsoncolor "github.com/openstor/colorjson"
+	"github.com/openstor/mc/pkg/httptracer"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/urfave/cli/v3"
+)
+
+var traceFileSummarizeCmd = cli.Command{
+	Name:         "summarize",
+	Usage:        "summarize latencies and errors per API of trace files",
+	Action:       mainTraceFileSummarize,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        globalFlags,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} FILE [FILE...]
+
+  FILE is a HAR or JSON lines file recorded with --trace-file. Latencies
+  are measured from sending the request to reading the last byte of the
+  response, retries are requests repeating a failed one.
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Record the requests of a mirror and summarize them.
+     {{.Prompt}} mc mirror --trace-file mirror.jsonl /data myminio/backup
+     {{.Prompt}} {{.HelpName}} mirror.jsonl
+
+  2. Summarize a HAR file as JSON.
+     {{.Prompt}} {{.HelpName}} --json support.har
+`,
+}
+
+// traceFileSummary - requests of an API in trace files.
+type traceFileSummary struct {
+	API           string         `json:"api"`
+	Count         int            `json:"count"`
+	Errors        int            `json:"errors"`
+	Retries       int            `json:"retries"`
+	P50           time.Duration  `json:"p50"`
+	P90           time.Duration  `json:"p90"`
+	P99           time.Duration  `json:"p99"`
+	Max           time.Duration  `json:"max"`
+	TTFB          time.Duration  `json:"ttfbP50"`
+	RequestBytes  int64          `json:"requestBytes"`
+	ResponseBytes int64          `json:"responseBytes"`
+	ErrorCounts   map[string]int `json:"errorCounts,omitempty"`
+}
+
+// traceFileError returns the error of an entry, empty if it succeeded.
+func traceFileError(entry httptracer.Entry) string {
+	if entry.Error != "" {
+		return entry.Error
+	}
+	if entry.StatusCode >= 400 {
+		return strconv.Itoa(entry.StatusCode) + " " + http.StatusText(entry.StatusCode)
+	}
+	return ""
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(float64(len(sorted))*p/100+0.5) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+// summarizeTraceEntries aggregates entries per API, sorted by API name.
+func summarizeTraceEntries(entries []httptracer.Entry) []traceFileSummary {
+	type apiEntries struct {
+		summary       traceFileSummary
+		latency, ttfb []time.Duration
+	}
+	apis := make(map[string]*apiEntries)
+	for _, entry := range entries {
+		api := entry.API
+		if api == "" {
+			api = entry.Method
+		}
+		a, ok := apis[api]
+		if !ok {
+			a = &apiEntries{summary: traceFileSummary{API: api, ErrorCounts: make(map[string]int)}}
+			apis[api] = a
+		}
+		a.summary.Count++
+		if entry.Attempt > 1 {
+			a.summary.Retries++
+		}
+		if e := traceFileError(entry); e != "" {
+			a.summary.Errors++
+			a.summary.ErrorCounts[e]++
+		}
+		a.summary.RequestBytes += entry.RequestBytes
+		a.summary.ResponseBytes += entry.ResponseBytes
+		a.latency = append(a.latency, entry.Timings.Total)
+		if entry.Timings.TTFB > 0 {
+			a.ttfb = append(a.ttfb, entry.Timings.TTFB)
+		}
+	}
+
+	summaries := make([]traceFileSummary, 0, len(apis))
+	for _, a := range apis {
+		sort.Slice(a.latency, func(i, j int) bool { return a.latency[i] < a.latency[j] })
+		sort.Slice(a.ttfb, func(i, j int) bool { return a.ttfb[i] < a.ttfb[j] })
+		a.summary.P50 = percentile(a.latency, 50)
+		a.summary.P90 = percentile(a.latency, 90)
+		a.summary.P99 = percentile(a.latency, 99)
+		a.summary.Max = a.latency[len(a.latency)-1]
+		a.summary.TTFB = percentile(a.ttfb, 50)
+		if len(a.summary.ErrorCounts) == 0 {
+			a.summary.ErrorCounts = nil
+		}
+		summaries = append(summaries, a.summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].API < summaries[j].API })
+	return summaries
+}
+
+// traceFileSummaryMessage container for the summary of trace files.
+type traceFileSummaryMessage struct {
+	Status    string             `json:"status"`
+	Files     []string           `json:"files"`
+	Requests  int                `json:"requests"`
+	Summaries []traceFileSummary `json:"apis"`
+}
+
+func (m traceFileSummaryMessage) String() string {
+	var b strings.Builder
+	width := len("API")
+	for _, s := range m.Summaries {
+		width = max(width, len(s.API))
+	}
+	row := func(cols ...string) {
+		fmt.Fprintf(&b, "%-*s %7s %7s %7s %9s %9s %9s %9s %9s %10s %10s\n", width,
+			cols[0], cols[1], cols[2], cols[3], cols[4], cols[5], cols[6], cols[7], cols[8], cols[9], cols[10])
+	}
+	b.WriteString(console.Colorize("TraceFileHeader", fmt.Sprintf("%d requests in %s\n", m.Requests, strings.Join(m.Files, ", "))))
+	row("API", "COUNT", "ERRORS", "RETRIES", "P50", "P90", "P99", "MAX", "TTFB P50", "SENT", "RECEIVED")
+	round := func(d time.Duration) string {
+		return d.Round(time.Millisecond / 10).String()
+	}
+	for _, s := range m.Summaries {
+		row(s.API, strconv.Itoa(s.Count), strconv.Itoa(s.Errors), strconv.Itoa(s.Retries),
+			round(s.P50), round(s.P90), round(s.P99), round(s.Max), round(s.TTFB),
+			humanize.IBytes(uint64(s.RequestBytes)), humanize.IBytes(uint64(s.ResponseBytes)))
+	}
+
+	first := true
+	for _, s := range m.Summaries {
+		errs := make([]string, 0, len(s.ErrorCounts))
+		for e := range s.ErrorCounts {
+			errs = append(errs, e)
+		}
+		sort.Slice(errs, func(i, j int) bool {
+			if s.ErrorCounts[errs[i]] != s.ErrorCounts[errs[j]] {
+				return s.ErrorCounts[errs[i]] > s.ErrorCounts[errs[j]]
+			}
+			return errs[i] < errs[j]
+		})
+		for _, e := range errs {
+			if first {
+				b.WriteString(console.Colorize("TraceFileHeader", "\nErrors:\n"))
+				first = false
+			}
+			fmt.Fprintf(&b, "  %s: %s (%d)\n", s.API, console.Colorize("TraceFileError", e), s.ErrorCounts[e])
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (m traceFileSummaryMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := jsoncolor.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// checkTraceFileSummarizeSyntax - validate all the passed arguments
+func checkTraceFileSummarizeSyntax(ctx context.Context, cmd *cli.Command) {
+	if cmd.Args().Len() == 0 {
+		showCommandHelpAndExit(ctx, cmd, 1) // last argument is exit code
+	}
+}
+
+// mainTraceFileSummarize is the handle for "mc trace-file summarize" command.
+func mainTraceFileSummarize(ctx context.Context, cmd *cli.Command) error {
+	checkTraceFileSummarizeSyntax(ctx, cmd)
+	console.SetColor("TraceFileHeader", color.New(color.Bold))
+	console.SetColor("TraceFileError", color.New(color.FgRed))
+
+	var entries []httptracer.Entry
+	for _, name := range cmd.Args().Slice() {
+		f, e := os.Open(name)
+		fatalIf(probe.NewError(e).Trace(name), "Unable to open trace file.")
+		e = httptracer.ReadEntries(f, func(entry httptracer.Entry) error {
+			entries = append(entries, entry)
+			return nil
+		})
+		f.Close()
+		fatalIf(probe.NewError(e).Trace(name), "Unable to read trace file.")
+	}
+
+	printMsg(traceFileSummaryMessage{
+		Files:     cmd.Args().Slice(),
+		Requests:  len(entries),
+		Summaries: summarizeTraceEntries(entries),
+	})
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/openstor/mc/pkg/httptracer"
+)
+
+// newTraceRecorder records all HTTP requests to a trace file, in HAR
+// format if the name ends with ".har", as JSON lines otherwise.
+func newTraceRecorder(name string) (*httptracer.Recorder, error) {
+	w, e := httptracer.Create(name, "mc", ReleaseTag)
+	if e != nil {
+		return nil, e
+	}
+	recorder := httptracer.NewRecorder(w)
+	recorder.Name = s3APIName
+	return recorder, nil
+}
+
+// s3Subresources maps the sub-resources of S3 requests to the names of
+// their APIs, without the "Get", "Put" or "Delete" verb and the "Bucket"
+// or "Object" kind when they depend on the request.
+var s3Subresources = []struct {
+	query string
+	name  string
+	// kind is prepended by "Bucket" or "Object", verb is prepended
+	kind, verb bool
+}{
+	{"tagging", "Tagging", true, true},
+	{"acl", "Acl", true, true},
+	{"retention", "ObjectRetention", false, true},
+	{"legal-hold", "ObjectLegalHold", false, true},
+	{"object-lock", "ObjectLockConfiguration", false, true},
+	{"versioning", "BucketVersioning", false, true},
+	{"lifecycle", "BucketLifecycle", false, true},
+	{"policy", "BucketPolicy", false, true},
+	{"location", "GetBucketLocation", false, false},
+	{"replication", "BucketReplication", false, true},
+	{"encryption", "BucketEncryption", false, true},
+	{"notification", "BucketNotification", false, true},
+	{"cors", "BucketCors", false, true},
+	{"versions", "ListObjectVersions", false, false},
+	{"events", "ListenBucketNotification", false, false},
+	{"select", "SelectObjectContent", false, false},
+	{"restore", "RestoreObject", false, false},
+	{"attributes", "GetObjectAttributes", false, false},
+}
+
+// s3APIName returns the name of the S3 API of a request, following the
+// names of the AWS S3 API reference. Requests are assumed to use path
+// style, "/bucket/object".
+func s3APIName(req *http.Request) string {
+	urlPath := strings.Trim(req.URL.Path, "/")
+	if strings.HasPrefix(urlPath, "minio/admin/") {
+		op := path.Base(urlPath)
+		return "Admin" + strings.ToUpper(op[:1]) + op[1:]
+	}
+
+	bucket, object, _ := strings.Cut(urlPath, "/")
+	isObject := object != ""
+	query := req.URL.Query()
+	isCopy := req.Header.Get("X-Amz-Copy-Source") != ""
+
+	verb := map[string]string{
+		http.MethodGet:    "Get",
+		http.MethodHead:   "Get",
+		http.MethodPut:    "Put",
+		http.MethodPost:   "Put",
+		http.MethodDelete: "Delete",
+	}[req.Method]
+
+	switch {
+	case query.Has("uploadId"):
+		switch req.Method {
+		case http.MethodGet:
+			return "ListParts"
+		case http.MethodPut:
+			if isCopy {
+				return "UploadPartCopy"
+			}
+			return "UploadPart"
+		case http.MethodPost:
+			return "CompleteMultipartUpload"
+		case http.MethodDelete:
+			return "AbortMultipartUpload"
+		}
+	case query.Has("uploads"):
+		if req.Method == http.MethodPost {
+			return "CreateMultipartUpload"
+		}
+		return "ListMultipartUploads"
+	case query.Has("delete") && req.Method == http.MethodPost:
+		return "DeleteObjects"
+	case query.Get("list-type") == "2":
+		return "ListObjectsV2"
+	}
+
+	for _, sub := range s3Subresources {
+		if !query.Has(sub.query) {
+			continue
+		}
+		name := sub.name
+		if sub.kind {
+			if isObject {
+				name = "Object" + name
+			} else {
+				name = "Bucket" + name
+			}
+		}
+		if sub.verb {
+			name = verb + name
+		}
+		return name
+	}
+
+	switch {
+	case bucket == "":
+		return "ListBuckets"
+	case !isObject:
+		switch req.Method {
+		case http.MethodGet:
+			return "ListObjects"
+		case http.MethodHead:
+			return "HeadBucket"
+		case http.MethodPut:
+			return "CreateBucket"
+		case http.MethodDelete:
+			return "DeleteBucket"
+		case http.MethodPost:
+			return "PostObject"
+		}
+	default:
+		switch req.Method {
+		case http.MethodGet:
+			return "GetObject"
+		case http.MethodHead:
+			return "HeadObject"
+		case http.MethodPut:
+			if isCopy {
+				return "CopyObject"
+			}
+			return "PutObject"
+		case http.MethodDelete:
+			return "DeleteObject"
+		}
+	}
+	return req.Method
+}
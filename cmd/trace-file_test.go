@@ -0,0 +1,107 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/openstor/mc/pkg/httptracer"
+)
+
+func TestS3APIName(t *testing.T) {
+	testCases := []struct {
+		method, url string
+		copy        bool
+		api         string
+	}{
+		{http.MethodGet, "/", false, "ListBuckets"},
+		{http.MethodHead, "/bucket/", false, "HeadBucket"},
+		{http.MethodPut, "/bucket", false, "CreateBucket"},
+		{http.MethodGet, "/bucket/?list-type=2&prefix=a", false, "ListObjectsV2"},
+		{http.MethodGet, "/bucket?versions=", false, "ListObjectVersions"},
+		{http.MethodGet, "/bucket/dir/object", false, "GetObject"},
+		{http.MethodHead, "/bucket/object", false, "HeadObject"},
+		{http.MethodPut, "/bucket/object", false, "PutObject"},
+		{http.MethodPut, "/bucket/object", true, "CopyObject"},
+		{http.MethodDelete, "/bucket/object?versionId=1", false, "DeleteObject"},
+		{http.MethodPost, "/bucket/?delete=", false, "DeleteObjects"},
+		{http.MethodPost, "/bucket/object?uploads=", false, "CreateMultipartUpload"},
+		{http.MethodPut, "/bucket/object?partNumber=1&uploadId=x", false, "UploadPart"},
+		{http.MethodPut, "/bucket/object?partNumber=1&uploadId=x", true, "UploadPartCopy"},
+		{http.MethodPost, "/bucket/object?uploadId=x", false, "CompleteMultipartUpload"},
+		{http.MethodDelete, "/bucket/object?uploadId=x", false, "AbortMultipartUpload"},
+		{http.MethodGet, "/bucket/?tagging=", false, "GetBucketTagging"},
+		{http.MethodPut, "/bucket/object?tagging=", false, "PutObjectTagging"},
+		{http.MethodDelete, "/bucket?lifecycle=", false, "DeleteBucketLifecycle"},
+		{http.MethodGet, "/bucket?location=", false, "GetBucketLocation"},
+		{http.MethodGet, "/minio/admin/v3/info", false, "AdminInfo"},
+	}
+	for i, testCase := range testCases {
+		req, e := http.NewRequest(testCase.method, "http://localhost:9000"+testCase.url, nil)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if testCase.copy {
+			req.Header.Set("X-Amz-Copy-Source", "/bucket/source")
+		}
+		if api := s3APIName(req); api != testCase.api {
+			t.Errorf("Test %d: expected %s for %s %s, got %s", i+1, testCase.api, testCase.method, testCase.url, api)
+		}
+	}
+}
+
+func TestSummarizeTraceEntries(t *testing.T) {
+	var entries []httptracer.Entry
+	for i := 1; i <= 100; i++ {
+		entries = append(entries, httptracer.Entry{
+			API:          "GetObject",
+			StatusCode:   http.StatusOK,
+			Attempt:      1,
+			RequestBytes: 1,
+			Timings:      httptracer.Timings{Total: time.Duration(i) * time.Millisecond, TTFB: time.Millisecond},
+		})
+	}
+	entries = append(entries,
+		httptracer.Entry{API: "PutObject", StatusCode: http.StatusServiceUnavailable, Attempt: 1},
+		httptracer.Entry{API: "PutObject", Error: "connection reset by peer", Attempt: 2},
+		httptracer.Entry{API: "PutObject", StatusCode: http.StatusOK, Attempt: 3, Timings: httptracer.Timings{Total: time.Second}},
+	)
+
+	summaries := summarizeTraceEntries(entries)
+	if len(summaries) != 2 {
+		t.Fatalf("expected 2 APIs, got %d", len(summaries))
+	}
+	get, put := summaries[0], summaries[1]
+	if get.API != "GetObject" || get.Count != 100 || get.Errors != 0 || get.RequestBytes != 100 {
+		t.Errorf("unexpected GetObject summary %+v", get)
+	}
+	if get.P50 != 50*time.Millisecond || get.P90 != 90*time.Millisecond || get.P99 != 99*time.Millisecond || get.Max != 100*time.Millisecond {
+		t.Errorf("unexpected GetObject percentiles %+v", get)
+	}
+	if get.TTFB != time.Millisecond {
+		t.Errorf("unexpected GetObject TTFB %s", get.TTFB)
+	}
+	if put.API != "PutObject" || put.Count != 3 || put.Errors != 2 || put.Retries != 2 || put.Max != time.Second {
+		t.Errorf("unexpected PutObject summary %+v", put)
+	}
+	if put.ErrorCounts["503 Service Unavailable"] != 1 || put.ErrorCounts["connection reset by peer"] != 1 {
+		t.Errorf("unexpected PutObject errors %v", put.ErrorCounts)
+	}
+}
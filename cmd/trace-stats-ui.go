@@ -322,11 +322,34 @@ func roundDur(d time.Duration) time.Duration {
 }
 
 func initTraceStatsUI(allFlag bool, maxEntries int, traces <-chan madmin.ServiceTraceInfo) *traceStatsUI {
+	stats := &statTrace{Calls: make(map[string]statItem, 20)}
+	go func() {
+		for t := range traces {
+			stats.add(t)
+		}
+	}()
+	return newTraceStatsUI(allFlag, maxEntries, stats)
+}
+
+// newTraceStatsUI returns the table of the calls in stats.
+func newTraceStatsUI(allFlag bool, maxEntries int, stats *statTrace) *traceStatsUI {
 	meter := spinner.New()
 	meter.Spinner = spinner.Meter
 	meter.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 	// Use half the default fps to reduce flickering
 	meter.Spinner.FPS = time.Second / 3
+	setTraceStatsColors()
+	return &traceStatsUI{
+		started:    time.Now(),
+		meter:      meter,
+		maxEntries: maxEntries,
+		current:    stats,
+		allFlag:    allFlag,
+	}
+}
+
+// setTraceStatsColors sets the console colors of the trace stats tables.
+func setTraceStatsColors() {
 	console.SetColor("metrics-duration", color.New(color.FgWhite))
 	console.SetColor("metrics-size", color.New(color.FgGreen))
 	console.SetColor("metrics-dur", color.New(color.FgGreen))
@@ -338,17 +361,4 @@ func initTraceStatsUI(allFlag bool, maxEntries int, traces <-chan madmin.Service
 	console.SetColor("metrics-number", color.New(color.FgWhite))
 	console.SetColor("metrics-number-secondary", color.New(color.FgBlue))
 	console.SetColor("metrics-zero", color.New(color.FgWhite))
-	stats := &statTrace{Calls: make(map[string]statItem, 20)}
-	go func() {
-		for t := range traces {
-			stats.add(t)
-		}
-	}()
-	return &traceStatsUI{
-		started:    time.Now(),
-		meter:      meter,
-		maxEntries: maxEntries,
-		current:    stats,
-		allFlag:    allFlag,
-	}
 }
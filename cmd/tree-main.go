@@ -75,6 +75,10 @@ var treeFlags = []cli.Flag{
 		Name:  "rewind",
 		Usage: "display tree no later than specified date",
 	},
+	&cli.BoolFlag{
+		Name:  "index",
+		Usage: "list from the local index of the bucket, see 'mc index build'",
+	},
 }
 
 // trees files and folders.
@@ -109,6 +113,9 @@ EXAMPLES:
 
    5. List all directories upto depth level '2' in tree format.
       {{.Prompt}} {{.HelpName}} --depth 2 myminio/mybucket/
+
+   6. List all directories in "mybucket" from its local index built with 'mc index build'.
+      {{.Prompt}} {{.HelpName}} --index myminio/mybucket/
 `,
 }
 
@@ -138,7 +145,7 @@ func parseTreeSyntax(ctx context.Context, cmd *cli.Command) (args []string, dept
 }
 
 // doTree - list all entities inside a folder in a tree format.
-func doTree(ctx context.Context, url string, timeRef time.Time, level int, branchString string, depth int, includeFiles bool) error {
+func doTree(ctx context.Context, url string, timeRef time.Time, level int, branchString string, depth int, includeFiles, useIndex bool) error {
 	targetAlias, targetURL, _ := mustExpandAlias(url)
 	if !strings.HasSuffix(targetURL, "/") {
 		targetURL += "/"
@@ -146,6 +153,10 @@ func doTree(ctx context.Context, url string, timeRef time.Time, level int, branc
 
 	clnt, err := newClientFromAlias(targetAlias, targetURL)
 	fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
+	if useIndex {
+		clnt, err = newIndexClient(targetAlias, clnt)
+		fatalIf(err.Trace(targetURL), "Unable to use the index of `"+targetURL+"`.")
+	}
 
 	prefixPath := clnt.GetURL().Path
 	separator := string(clnt.GetURL().Separator)
@@ -227,7 +238,7 @@ func doTree(ctx context.Context, url string, timeRef time.Time, level int, branc
 			}
 
 			if depth == -1 || level <= depth {
-				if err := doTree(ctx, url, timeRef, level+1, currbranchString, depth, includeFiles); err != nil {
+				if err := doTree(ctx, url, timeRef, level+1, currbranchString, depth, includeFiles, useIndex); err != nil {
 					return err
 				}
 			}
@@ -274,6 +285,7 @@ func mainTree(ctx context.Context, cmd *cli.Command) error {
 
 	// parse 'tree' cliCtx arguments.
 	args, depth, includeFiles, timeRef := parseTreeSyntax(ctx, cmd)
+	useIndex := cmd.Bool("index")
 
 	// mimic operating system tool behavior.
 	if len(args) == 0 {
@@ -283,7 +295,7 @@ func mainTree(ctx context.Context, cmd *cli.Command) error {
 	var cErr error
 	for _, targetURL := range args {
 		if !globalJSON {
-			if e := doTree(ctx, targetURL, timeRef, 1, "", depth, includeFiles); e != nil {
+			if e := doTree(ctx, targetURL, timeRef, 1, "", depth, includeFiles, useIndex); e != nil {
 				cErr = e
 			}
 		} else {
@@ -293,6 +305,10 @@ func mainTree(ctx context.Context, cmd *cli.Command) error {
 			}
 			clnt, err := newClientFromAlias(targetAlias, targetURL)
 			fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
+			if useIndex {
+				clnt, err = newIndexClient(targetAlias, clnt)
+				fatalIf(err.Trace(targetURL), "Unable to use the index of `"+targetURL+"`.")
+			}
 			opts := doListOptions{
 				timeRef:      timeRef,
 				isRecursive:  true,
@@ -194,3 +194,17 @@ var errSSEClientKeyFormat = func(msg string) *probe.Error {
 	m += msg
 	return probe.NewError(sseClientKeyFormatErr(errors.New(m))).Untrace()
 }
+
+type invalidSessionErr error
+
+var errInvalidSession = func(sid string) *probe.Error {
+	msg := "Session `" + sid + "` does not exist or is not a valid session. Use `mc session list` to see saved sessions."
+	return probe.NewError(invalidSessionErr(errors.New(msg))).Untrace()
+}
+
+type indexNotFoundErr error
+
+var errIndexNotFound = func(target string) *probe.Error {
+	msg := "No index found for `" + target + "`. Use `mc index build " + target + "` to create it."
+	return probe.NewError(indexNotFoundErr(errors.New(msg))).Untrace()
+}
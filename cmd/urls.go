@@ -34,6 +34,7 @@ type URLs struct {
 	DisableMultipart bool
 	checksum         openstor.ChecksumType
 	encKeyDB         map[string][]prefixSSEPair
+	spoolSeq         uint64       // watch event in the mirror spool, zero if none
 	Error            *probe.Error `json:"-"`
 	ErrorCond        differType   `json:"-"`
 }
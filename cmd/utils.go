@@ -128,7 +128,7 @@ func splitStr(path, sep string, n int) []string {
 
 // NewS3Config simply creates a new Config struct using the passed
 // parameters.
-func NewS3Config(alias, urlStr string, aliasCfg *aliasConfigV10) *Config {
+func NewS3Config(alias, urlStr string, aliasCfg *aliasConfigV11) *Config {
 	// We have a valid alias and hostConfig. We populate the
 	// credentials from the match found in the config file.
 	s3Config := new(Config)
@@ -139,8 +139,6 @@ func NewS3Config(alias, urlStr string, aliasCfg *aliasConfigV10) *Config {
 	s3Config.Insecure = globalInsecure
 	s3Config.ConnReadDeadline = globalConnReadDeadline
 	s3Config.ConnWriteDeadline = globalConnWriteDeadline
-	s3Config.UploadLimit = int64(globalLimitUpload)
-	s3Config.DownloadLimit = int64(globalLimitDownload)
 
 	s3Config.HostURL = urlStr
 	s3Config.Alias = alias
@@ -150,7 +148,12 @@ func NewS3Config(alias, urlStr string, aliasCfg *aliasConfigV10) *Config {
 		s3Config.SessionToken = aliasCfg.SessionToken
 		s3Config.Signature = aliasCfg.API
 		s3Config.Lookup = getLookupType(aliasCfg.Path)
+		if profile, ok := getProfileConfig(aliasCfg.Profile); ok {
+			s3Config.Region = profile.Region
+			applyProfileConfig(s3Config, aliasCfg.Profile, profile)
+		}
 	}
+	s3Config.UploadLimit, s3Config.DownloadLimit = transferLimits(alias, urlStr, aliasCfg)
 	return s3Config
 }
 
@@ -364,7 +367,7 @@ func httpClient(reqTimeout time.Duration) *http.Client {
 	}
 }
 
-func getPrometheusToken(hostConfig *aliasConfigV10) (string, error) {
+func getPrometheusToken(hostConfig *aliasConfigV11) (string, error) {
 	jwt := jwtgo.NewWithClaims(jwtgo.SigningMethodHS512, jwtgo.RegisteredClaims{
 		ExpiresAt: jwtgo.NewNumericDate(UTCNow().Add(defaultPrometheusJWTExpiry)),
 		Subject:   hostConfig.AccessKey,
@@ -20,15 +20,22 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	humanize "github.com/dustin/go-humanize"
 	"github.com/fatih/color"
+	"github.com/google/shlex"
 	json "github.com/openstor/colorjson"
 	"github.com/openstor/mc/pkg/probe"
 	"github.com/openstor/openstor-go/v7/pkg/notification"
 	"github.com/openstor/pkg/v3/console"
+	"github.com/openstor/pkg/v3/env"
 	"github.com/urfave/cli/v3"
 )
 
@@ -50,6 +57,54 @@ var watchFlags = []cli.Flag{
 		Name:  "recursive",
 		Usage: "recursively watch for events",
 	},
+	&cli.StringFlag{
+		Name:  "fs-watch-mode",
+		Usage: "watch local folders with \"notify\", \"poll\" or \"auto\" to poll only the folders which cannot be notified",
+		Value: fsWatchModeAuto,
+	},
+	&cli.DurationFlag{
+		Name:  "fs-poll-interval",
+		Usage: "interval between scans of polled local folders",
+		Value: defaultFSPollInterval,
+	},
+	&cli.StringSliceFlag{
+		Name:  "event-type",
+		Usage: "filter events by type, wildcards are allowed (e.g. \"s3:ObjectCreated:*\")",
+	},
+	&cli.StringFlag{
+		Name:  "min-size",
+		Usage: "filter events of objects smaller than size (e.g. 1MiB)",
+	},
+	&cli.StringFlag{
+		Name:  "max-size",
+		Usage: "filter events of objects larger than size (e.g. 1GiB)",
+	},
+	&cli.StringFlag{
+		Name:  "webhook",
+		Usage: "post batches of events as JSON to a URL",
+	},
+	&cli.StringFlag{
+		Name:  "webhook-secret",
+		Usage: "sign webhook requests with HMAC-SHA256 in the \"X-Mc-Signature\" header",
+	},
+	&cli.IntFlag{
+		Name:  "batch-size",
+		Usage: "maximum number of events posted in one webhook request",
+		Value: 100,
+	},
+	&cli.DurationFlag{
+		Name:  "batch-interval",
+		Usage: "maximum time events wait for a webhook batch to fill up",
+		Value: time.Second,
+	},
+	&cli.StringFlag{
+		Name:  "exec",
+		Usage: "run a command for each event, with the placeholders of 'mc find --exec' and {event}",
+	},
+	&cli.IntFlag{
+		Name:  "max-retries",
+		Usage: "exit after failing to deliver events this many times in a row (default: retry forever)",
+	},
 }
 
 var watchCmd = cli.Command{
@@ -68,6 +123,26 @@ USAGE:
 FLAGS:
   {{range .VisibleFlags}}{{.}}
   {{end}}
+DELIVERY:
+  Events are delivered at least once to --webhook and --exec. Failed
+  deliveries are retried with an exponential backoff, newer events wait
+  meanwhile. Webhook requests are JSON documents {"id": ..., "events": [...]},
+  the id is kept on retries and sent in the "X-Mc-Delivery" header so that
+  receivers can drop duplicates. With --webhook-secret the HMAC-SHA256 of
+  the body is sent as "X-Mc-Signature: sha256=<hex>". On exit, events not
+  delivered yet are still sent for up to 10 seconds.
+
+LOCAL FOLDERS:
+  Local folders are watched with the notifications of the OS. Folders which
+  cannot be watched, because the inotify limits are reached or because they
+  are on a network filesystem like NFS, are scanned every --fs-poll-interval
+  for files whose size or modification time changed instead. Scanned files
+  are reported once they stopped changing, "get" events are not reported.
+  The mode of each folder is printed first.
+
+ENVIRONMENT VARIABLES:
+  MC_WATCH_WEBHOOK_SECRET: secret signing webhook requests, instead of --webhook-secret.
+
 EXAMPLES:
   1. Watch new S3 operations on a MinIO server
      {{.Prompt}} {{.HelpName}} play/testbucket
@@ -86,6 +161,15 @@ EXAMPLES:
 
   6. Watch for events on local directory.
      {{.Prompt}} {{.HelpName}} /usr/share
+
+  7. Forward new objects larger than 1MiB to a webhook, signed with a secret.
+     {{.Prompt}} {{.HelpName}} --events put --min-size 1MiB --webhook https://hooks.example.com/mc --webhook-secret s3cr3t play/testbucket
+
+  8. Run a command for each removed ".jpg" object.
+     {{.Prompt}} {{.HelpName}} --event-type "s3:ObjectRemoved:*" --suffix .jpg --exec "purge-cache {}" play/testbucket
+
+  9. Watch a large local directory, scanning the folders exceeding the inotify limits every 10 seconds.
+     {{.Prompt}} {{.HelpName}} --fs-poll-interval 10s /data
 `,
 }
 
@@ -96,6 +180,27 @@ func checkWatchSyntax(ctx context.Context, cmd *cli.Command) {
 	}
 }
 
+// watchModeMessage tells how a local folder is watched.
+type watchModeMessage struct {
+	Status string `json:"status"`
+	WatchMode
+}
+
+func (m watchModeMessage) JSON() string {
+	m.Status = "success"
+	buf, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(buf)
+}
+
+func (m watchModeMessage) String() string {
+	msg := fmt.Sprintf("Watching `%s` with %s", m.Path, m.Mode)
+	if m.Reason != "" {
+		msg += " (" + m.Reason + ")"
+	}
+	return console.Colorize("WatchMode", msg+".")
+}
+
 // watchMessage container to hold one event notification
 type watchMessage struct {
 	Status string `json:"status"`
@@ -136,6 +241,7 @@ func mainWatch(ctx context.Context, cmd *cli.Command) error {
 	console.SetColor("Size", color.New(color.FgYellow))
 	console.SetColor("EventType", color.New(color.FgCyan, color.Bold))
 	console.SetColor("ObjectName", color.New(color.Bold))
+	console.SetColor("WatchMode", color.New(color.FgMagenta))
 
 	checkWatchSyntax(ctx, cmd)
 
@@ -147,27 +253,103 @@ func mainWatch(ctx context.Context, cmd *cli.Command) error {
 	events := strings.Split(cmd.String("events"), ",")
 	recursive := cmd.Bool("recursive")
 
+	filter := watchFilter{
+		types:  cmd.StringSlice("event-type"),
+		prefix: prefix,
+		suffix: suffix,
+	}
+	for name, size := range map[string]*int64{"min-size": &filter.minSize, "max-size": &filter.maxSize} {
+		if v := cmd.String(name); v != "" {
+			n, e := humanize.ParseBytes(v)
+			fatalIf(probe.NewError(e).Trace(v), "Unable to parse --%s.", name)
+			*size = int64(n)
+		}
+	}
+
 	s3Client, pErr := newClient(path)
 	if pErr != nil {
 		fatalIf(pErr.Trace(), "Unable to parse the provided url.")
 	}
+	alias, _ := url2Alias(path)
+	targetURL := s3Client.GetURL()
 
 	options := WatchOptions{
-		Recursive: recursive,
-		Events:    events,
-		Prefix:    prefix,
-		Suffix:    suffix,
+		Recursive:      recursive,
+		Events:         events,
+		Prefix:         prefix,
+		Suffix:         suffix,
+		FSMode:         cmd.String("fs-watch-mode"),
+		FSPollInterval: cmd.Duration("fs-poll-interval"),
 	}
 
 	ctx, cancelWatch := context.WithCancel(globalContext)
 	defer cancelWatch()
 
+	// Initialize.. waitgroup to track the go-routine.
+	var wg sync.WaitGroup
+
+	// Start the deliveries of events, if any.
+	var sinkChs []chan watchSinkEvent
+	startSink := func(sink watchSink, batchSize int) {
+		sinkCh := make(chan watchSinkEvent)
+		sinkChs = append(sinkChs, sinkCh)
+		delivery := watchDelivery{
+			sink:       sink,
+			maxRetries: cmd.Int("max-retries"),
+			backoff:    time.Second,
+			maxBackoff: time.Minute,
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			delivery.run(ctx, sinkCh, batchSize, cmd.Duration("batch-interval"))
+		}()
+	}
+	if webhook := cmd.String("webhook"); webhook != "" {
+		u, e := url.Parse(webhook)
+		if e != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			fatalIf(errInvalidArgument().Trace(webhook), "Invalid --webhook URL.")
+		}
+		secret := cmd.String("webhook-secret")
+		if secret == "" {
+			secret = env.Get("MC_WATCH_WEBHOOK_SECRET", "")
+		}
+		startSink(&watchWebhookSink{
+			url:    webhook,
+			secret: secret,
+			client: httpClient(time.Minute),
+		}, max(cmd.Int("batch-size"), 1))
+	}
+	if args := cmd.String("exec"); args != "" {
+		if _, e := shlex.Split(args); e != nil {
+			fatalIf(probe.NewError(e).Trace(args), "Unable to parse --exec.")
+		}
+		// Commands run once per event, in the order of the events.
+		startSink(&watchExecSink{args: args}, 1)
+	}
+
+	// Stop on signals like trapSignals does, but exit only once the
+	// pending events were delivered.
+	stopSignal := make(chan os.Signal, 1)
+	if len(sinkChs) > 0 {
+		GlobalTrapSignals = false
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			s := <-sigCh
+			signal.Stop(sigCh)
+			stopSignal <- s
+			stopProfiling()
+			globalCancel()
+		}()
+	}
+
 	// Start watching on events
 	wo, err := s3Client.Watch(ctx, options)
 	fatalIf(err, "Unable to watch on the specified bucket.")
-
-	// Initialize.. waitgroup to track the go-routine.
-	var wg sync.WaitGroup
+	for _, mode := range wo.Modes {
+		printMsg(watchModeMessage{WatchMode: mode})
+	}
 
 	// Increment wait group to wait subsequent routine.
 	wg.Add(1)
@@ -175,6 +357,11 @@ func mainWatch(ctx context.Context, cmd *cli.Command) error {
 	// Start routine to watching on events.
 	go func() {
 		defer wg.Done()
+		defer func() {
+			for _, sinkCh := range sinkChs {
+				close(sinkCh)
+			}
+		}()
 
 		// Wait for all events.
 		for {
@@ -188,6 +375,28 @@ func mainWatch(ctx context.Context, cmd *cli.Command) error {
 					return
 				}
 				for _, event := range events {
+					key := watchEventKey(targetURL, event.Path)
+					if !filter.match(key, event) {
+						continue
+					}
+					sinkEvent := watchSinkEvent{
+						Time:      event.Time,
+						Type:      event.Type,
+						Path:      watchEventAliasPath(alias, targetURL, event.Path),
+						Key:       key,
+						Size:      event.Size,
+						Host:      event.Host,
+						Port:      event.Port,
+						UserAgent: event.UserAgent,
+					}
+					for _, sinkCh := range sinkChs {
+						// Blocks while a sink is retrying, events are not dropped.
+						select {
+						case sinkCh <- sinkEvent:
+						case <-ctx.Done():
+							return
+						}
+					}
 					msg := watchMessage{}
 					msg.Event.Path = event.Path
 					msg.Event.Size = event.Size
@@ -213,5 +422,10 @@ func mainWatch(ctx context.Context, cmd *cli.Command) error {
 	// Wait on the routine to be finished or exit.
 	wg.Wait()
 
+	select {
+	case s := <-stopSignal:
+		os.Exit(signalExitStatus(s))
+	default:
+	}
 	return nil
 }
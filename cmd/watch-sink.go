@@ -0,0 +1,319 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+	"github.com/google/uuid"
+	"github.com/openstor/mc/pkg/probe"
+	"github.com/openstor/openstor-go/v7/pkg/notification"
+	"github.com/openstor/pkg/v3/console"
+	"github.com/openstor/pkg/v3/wildcard"
+)
+
+// watchFilter - client side filters of watched events, applied on
+// top of the filters of the server, if any.
+type watchFilter struct {
+	types            []string // wildcard patterns, e.g. "s3:ObjectCreated:*"
+	prefix, suffix   string
+	minSize, maxSize int64 // zero is no limit
+}
+
+// match returns true if the event with key, relative to its bucket or
+// to the watched folder, passes the filter.
+func (f watchFilter) match(key string, event EventInfo) bool {
+	if len(f.types) > 0 {
+		matched := false
+		for _, pattern := range f.types {
+			if wildcard.Match(pattern, string(event.Type)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if !strings.HasPrefix(key, f.prefix) || !strings.HasSuffix(key, f.suffix) {
+		return false
+	}
+	if f.minSize > 0 && event.Size < f.minSize {
+		return false
+	}
+	if f.maxSize > 0 && event.Size > f.maxSize {
+		return false
+	}
+	return true
+}
+
+// watchEventKey returns the key of an event path relative to the bucket
+// of an S3 target, or relative to the watched folder of a local target.
+func watchEventKey(targetURL ClientURL, eventPath string) string {
+	if targetURL.Type == objectStorage {
+		u, e := url.Parse(eventPath)
+		if e != nil {
+			return eventPath
+		}
+		_, key, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+		return key
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(eventPath, targetURL.Path), string(targetURL.Separator))
+}
+
+// watchEventAliasPath returns the event path with the alias of the
+// target instead of the endpoint, as used by other commands.
+func watchEventAliasPath(alias string, targetURL ClientURL, eventPath string) string {
+	if targetURL.Type != objectStorage || alias == "" {
+		return eventPath
+	}
+	u, e := url.Parse(eventPath)
+	if e != nil {
+		return eventPath
+	}
+	return alias + u.Path
+}
+
+// watchSinkEvent - event delivered to webhooks and commands.
+type watchSinkEvent struct {
+	Time      string                 `json:"time"`
+	Type      notification.EventType `json:"type"`
+	Path      string                 `json:"path"`
+	Key       string                 `json:"key"`
+	Size      int64                  `json:"size"`
+	Host      string                 `json:"host,omitempty"`
+	Port      string                 `json:"port,omitempty"`
+	UserAgent string                 `json:"userAgent,omitempty"`
+}
+
+// watchSinkBatch - events delivered together, the ID is the same for
+// all attempts so that receivers can drop duplicates.
+type watchSinkBatch struct {
+	ID     string           `json:"id"`
+	Events []watchSinkEvent `json:"events"`
+}
+
+// watchSink delivers batches of events.
+type watchSink interface {
+	// Name describes the sink in error messages.
+	Name() string
+	Deliver(ctx context.Context, batch watchSinkBatch) error
+}
+
+// watchWebhookSink posts batches of events as JSON to a URL.
+type watchWebhookSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// watchSignature returns the value of the signature header of body.
+func watchSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *watchWebhookSink) Name() string {
+	return s.url
+}
+
+func (s *watchWebhookSink) Deliver(ctx context.Context, batch watchSinkBatch) error {
+	body, e := json.Marshal(batch)
+	if e != nil {
+		return e
+	}
+	req, e := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if e != nil {
+		return e
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mc/"+ReleaseTag)
+	req.Header.Set("X-Mc-Delivery", batch.ID)
+	if s.secret != "" {
+		req.Header.Set("X-Mc-Signature", watchSignature(s.secret, body))
+	}
+	res, e := s.client.Do(req)
+	if e != nil {
+		return e
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", res.Status)
+	}
+	return nil
+}
+
+// watchExecSink runs a command for each event, placeholders are the
+// ones of `mc find --exec` and {event} for the event type.
+type watchExecSink struct {
+	args string
+}
+
+func (s *watchExecSink) Name() string {
+	return s.args
+}
+
+func (s *watchExecSink) Deliver(ctx context.Context, batch watchSinkBatch) error {
+	split, e := shlex.Split(s.args)
+	if e != nil {
+		return e
+	}
+	if len(split) == 0 {
+		return nil
+	}
+	for _, event := range batch.Events {
+		eventTime, _ := time.Parse(time.RFC3339Nano, event.Time)
+		content := contentMessage{Key: event.Path, Size: event.Size, Time: eventTime}
+		args := make([]string, len(split))
+		for i, arg := range split {
+			arg = strings.ReplaceAll(arg, "{event}", string(event.Type))
+			args[i] = stringsReplace(ctx, arg, content)
+		}
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if e := cmd.Run(); e != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("%w: %s", e, msg)
+			}
+			return e
+		}
+		console.PrintC(stdout.String())
+	}
+	return nil
+}
+
+// watchDelivery retries failed deliveries with an exponential backoff,
+// events are delivered at least once.
+type watchDelivery struct {
+	sink       watchSink
+	maxRetries int // zero retries forever
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// deliver delivers a batch, it blocks until the batch was delivered,
+// retries are exhausted or ctx is canceled.
+func (d watchDelivery) deliver(ctx context.Context, batch watchSinkBatch) *probe.Error {
+	backoff := d.backoff
+	for attempt := 1; ; attempt++ {
+		e := d.sink.Deliver(ctx, batch)
+		if e == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return probe.NewError(ctx.Err())
+		}
+		if d.maxRetries > 0 && attempt > d.maxRetries {
+			return probe.NewError(e).Trace(d.sink.Name(), batch.ID)
+		}
+		errorIf(probe.NewError(e).Trace(d.sink.Name()), "Unable to deliver events to `%s`, retrying in %s.", d.sink.Name(), backoff)
+
+		select {
+		case <-ctx.Done():
+			return probe.NewError(ctx.Err())
+		case <-time.After(backoff):
+		}
+		// Exponential backoff with up to 50% jitter.
+		backoff = min(2*backoff, d.maxBackoff)
+		backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+	}
+}
+
+// watchDeliveryGrace - how long the pending events are still delivered
+// for once the watch is stopped.
+const watchDeliveryGrace = 10 * time.Second
+
+// run delivers the events received on eventsCh in batches of up to
+// batchSize events, a partial batch is delivered after interval. The
+// pending batch is delivered on exit, within watchDeliveryGrace when ctx
+// is canceled.
+func (d watchDelivery) run(ctx context.Context, eventsCh <-chan watchSinkEvent, batchSize int, interval time.Duration) {
+	var batch []watchSinkEvent
+	var batchID string
+	flush := func(ctx context.Context) *probe.Error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if batchID == "" {
+			batchID = uuid.NewString()
+		}
+		// A batch not delivered is kept with its ID, to be sent again as is.
+		if err := d.deliver(ctx, watchSinkBatch{ID: batchID, Events: batch}); err != nil {
+			return err
+		}
+		batch, batchID = nil, ""
+		return nil
+	}
+	flushOrFail := func() {
+		if err := flush(ctx); err != nil && ctx.Err() == nil {
+			fatalIf(err, "Unable to deliver %d event(s) to `%s`.", len(batch), d.sink.Name())
+		}
+	}
+	exit := func() {
+		if ctx.Err() == nil {
+			flushOrFail()
+		}
+		if ctx.Err() == nil || len(batch) == 0 {
+			return
+		}
+		graceCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), watchDeliveryGrace)
+		defer cancel()
+		if err := flush(graceCtx); err != nil {
+			errorIf(err, "Unable to deliver %d event(s) to `%s` before exiting.", len(batch), d.sink.Name())
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-eventsCh:
+			if !ok {
+				exit()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-ctx.Done():
+			exit()
+			return
+		}
+		flushOrFail()
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openstor/openstor-go/v7/pkg/notification"
+)
+
+func TestWatchFilter(t *testing.T) {
+	testCases := []struct {
+		filter watchFilter
+		key    string
+		event  EventInfo
+		match  bool
+	}{
+		{watchFilter{}, "a/b.jpg", EventInfo{Type: notification.ObjectCreatedPut}, true},
+		{watchFilter{types: []string{"s3:ObjectCreated:*"}}, "a.jpg", EventInfo{Type: notification.ObjectCreatedPut}, true},
+		{watchFilter{types: []string{"s3:ObjectCreated:*"}}, "a.jpg", EventInfo{Type: notification.ObjectRemovedDelete}, false},
+		{watchFilter{types: []string{"s3:ObjectAccessed:*", "s3:ObjectRemoved:*"}}, "a.jpg", EventInfo{Type: notification.ObjectRemovedDelete}, true},
+		{watchFilter{prefix: "photos/", suffix: ".jpg"}, "photos/a.jpg", EventInfo{}, true},
+		{watchFilter{prefix: "photos/", suffix: ".jpg"}, "videos/a.jpg", EventInfo{}, false},
+		{watchFilter{prefix: "photos/", suffix: ".jpg"}, "photos/a.png", EventInfo{}, false},
+		{watchFilter{minSize: 10}, "a", EventInfo{Size: 9}, false},
+		{watchFilter{minSize: 10}, "a", EventInfo{Size: 10}, true},
+		{watchFilter{maxSize: 10}, "a", EventInfo{Size: 11}, false},
+		{watchFilter{minSize: 1, maxSize: 10}, "a", EventInfo{Size: 5}, true},
+	}
+	for i, testCase := range testCases {
+		if match := testCase.filter.match(testCase.key, testCase.event); match != testCase.match {
+			t.Errorf("Test %d: expected %v, got %v", i+1, testCase.match, match)
+		}
+	}
+}
+
+func TestWatchEventKey(t *testing.T) {
+	s3URL := ClientURL{Type: objectStorage, Path: "/bucket/photos", Separator: '/'}
+	fsURL := ClientURL{Type: fileSystem, Path: "/data/photos", Separator: '/'}
+
+	testCases := []struct {
+		targetURL ClientURL
+		path      string
+		key       string
+		aliasPath string
+	}{
+		{s3URL, "https://play.min.io/bucket/photos/a.jpg", "photos/a.jpg", "play/bucket/photos/a.jpg"},
+		{s3URL, "https://play.min.io/bucket/a%20b.jpg", "a b.jpg", "play/bucket/a b.jpg"},
+		{fsURL, "/data/photos/2022/a.jpg", "2022/a.jpg", "/data/photos/2022/a.jpg"},
+	}
+	for i, testCase := range testCases {
+		if key := watchEventKey(testCase.targetURL, testCase.path); key != testCase.key {
+			t.Errorf("Test %d: expected key %q, got %q", i+1, testCase.key, key)
+		}
+		if aliasPath := watchEventAliasPath("play", testCase.targetURL, testCase.path); aliasPath != testCase.aliasPath {
+			t.Errorf("Test %d: expected path %q, got %q", i+1, testCase.aliasPath, aliasPath)
+		}
+	}
+}
+
+func TestWatchWebhookDelivery(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		attempts  int
+		ids       []string
+		delivered []watchSinkEvent
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if r.Header.Get("X-Mc-Signature") != watchSignature("secret", body) {
+			t.Errorf("Unexpected signature %q", r.Header.Get("X-Mc-Signature"))
+		}
+		ids = append(ids, r.Header.Get("X-Mc-Delivery"))
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var batch watchSinkBatch
+		if e := json.Unmarshal(body, &batch); e != nil {
+			t.Error(e)
+		}
+		if batch.ID != r.Header.Get("X-Mc-Delivery") {
+			t.Errorf("Expected delivery ID %q, got %q", r.Header.Get("X-Mc-Delivery"), batch.ID)
+		}
+		delivered = append(delivered, batch.Events...)
+	}))
+	defer server.Close()
+
+	delivery := watchDelivery{
+		sink:       &watchWebhookSink{url: server.URL, secret: "secret", client: server.Client()},
+		maxRetries: 3,
+		backoff:    time.Millisecond,
+		maxBackoff: time.Millisecond,
+	}
+	eventsCh := make(chan watchSinkEvent)
+	done := make(chan struct{})
+	go func() {
+		delivery.run(context.Background(), eventsCh, 2, time.Hour)
+		close(done)
+	}()
+	for _, key := range []string{"a", "b", "c"} {
+		eventsCh <- watchSinkEvent{Key: key, Type: notification.ObjectCreatedPut}
+	}
+	close(eventsCh)
+	<-done
+
+	if attempts != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", attempts)
+	}
+	if ids[0] != ids[1] || ids[1] == ids[2] {
+		t.Errorf("Expected the delivery ID to be kept on retries only, got %v", ids)
+	}
+	if len(delivered) != 3 || delivered[0].Key != "a" || delivered[2].Key != "c" {
+		t.Errorf("Unexpected delivered events %v", delivered)
+	}
+}
+
+func TestWatchDeliveryOnCancel(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		delivered []watchSinkEvent
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch watchSinkBatch
+		if e := json.NewDecoder(r.Body).Decode(&batch); e != nil {
+			t.Error(e)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, batch.Events...)
+	}))
+	defer server.Close()
+
+	delivery := watchDelivery{
+		sink:       &watchWebhookSink{url: server.URL, client: server.Client()},
+		maxRetries: 3,
+		backoff:    time.Millisecond,
+		maxBackoff: time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	eventsCh := make(chan watchSinkEvent)
+	done := make(chan struct{})
+	go func() {
+		delivery.run(ctx, eventsCh, 2, time.Hour)
+		close(done)
+	}()
+	eventsCh <- watchSinkEvent{Key: "a", Type: notification.ObjectCreatedPut}
+	cancel()
+	<-done
+
+	if len(delivered) != 1 || delivered[0].Key != "a" {
+		t.Errorf("Expected the pending event to be delivered on cancel, got %v", delivered)
+	}
+}
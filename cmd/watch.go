@@ -45,6 +45,11 @@ type WatchOptions struct {
 	Suffix    string
 	Events    []string
 	Recursive bool
+
+	// Watching local folders only: notify, poll or auto.
+	FSMode string
+	// Polling interval of local folders watched in poll mode.
+	FSPollInterval time.Duration
 }
 
 // WatchObject captures watch channels to read and listen on.
@@ -55,6 +60,8 @@ type WatchObject struct {
 	ErrorChan chan *probe.Error
 	// will stop the watcher goroutines
 	DoneChan chan struct{}
+	// how local folders are watched
+	Modes []WatchMode
 }
 
 // Events returns the chan receiving events
@@ -111,14 +118,11 @@ func (w *Watcher) Stop() {
 	w.wg.Wait()
 }
 
-// Join the watcher with client
-func (w *Watcher) Join(ctx context.Context, client Client, recursive bool) *probe.Error {
-	wo, err := client.Watch(ctx, WatchOptions{
-		Recursive: recursive,
-		Events:    []string{"put", "delete", "bucket-creation", "bucket-removal"},
-	})
+// Join the watcher with client, returns how local folders are watched.
+func (w *Watcher) Join(ctx context.Context, client Client, options WatchOptions) ([]WatchMode, *probe.Error) {
+	wo, err := client.Watch(ctx, options)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	w.o = append(w.o, wo)
@@ -150,5 +154,5 @@ func (w *Watcher) Join(ctx context.Context, client Client, recursive bool) *prob
 		}
 	}()
 
-	return nil
+	return wo.Modes, nil
 }
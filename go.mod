@@ -14,7 +14,6 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/inconshreveable/mousetrap v1.1.0
 	github.com/jedib0t/go-pretty/v6 v6.6.7
-	github.com/juju/ratelimit v1.0.2
 	github.com/klauspost/compress v1.18.0
 	github.com/mattn/go-ieproxy v0.0.12
 	github.com/mattn/go-isatty v0.0.20
@@ -28,16 +27,20 @@ require (
 	github.com/openstor/openstor-go/v7 v7.0.0-20251030005016-01c5488cd1e8
 	github.com/openstor/pkg/v3 v3.0.0-20251030004824-001670001f5f
 	github.com/openstor/selfupdate v0.0.0-20251030001556-08935b517eb3
+	github.com/parquet-go/parquet-go v0.25.1
 	github.com/pkg/xattr v0.4.10
 	github.com/posener/complete v1.2.3
 	github.com/prometheus/client_golang v1.22.0
 	github.com/prometheus/procfs v0.16.1
 	github.com/rjeczalik/notify v0.9.3
 	github.com/rs/xid v1.6.0
+	github.com/secure-io/sio-go v0.3.1
 	github.com/shirou/gopsutil/v4 v4.25.9
 	github.com/tidwall/gjson v1.18.0
 	github.com/urfave/cli/v3 v3.5.0
 	github.com/vbauerster/mpb/v8 v8.9.3
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/crypto v0.43.0
 	golang.org/x/net v0.45.0
 	golang.org/x/sys v0.37.0
 	golang.org/x/term v0.36.0
@@ -50,6 +53,7 @@ require (
 	aead.dev/minisign v0.3.0 // indirect
 	github.com/VividCortex/ewma v1.2.0 // indirect
 	github.com/acarl005/stripansi v0.0.0-20180116102854-5a71ef0e047d // indirect
+	github.com/andybalholm/brotli v1.1.0 // indirect
 	github.com/aymanbagabas/go-osc52/v2 v2.0.1 // indirect
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
@@ -91,6 +95,7 @@ require (
 	github.com/openstor/crc64nvme v0.0.0-20251029033624-a8be6069b19b // indirect
 	github.com/openstor/md5-simd v0.0.0-20251030001503-8bd65c23d2c1 // indirect
 	github.com/philhofer/fwd v1.2.0 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
 	github.com/power-devops/perfstat v0.0.0-20240221224432-82ca36839d55 // indirect
 	github.com/prometheus/client_model v0.6.2 // indirect
 	github.com/prometheus/common v0.65.0 // indirect
@@ -99,7 +104,6 @@ require (
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/rogpeppe/go-internal v1.13.1 // indirect
 	github.com/safchain/ethtool v0.6.1 // indirect
-	github.com/secure-io/sio-go v0.3.1 // indirect
 	github.com/segmentio/asm v1.2.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
@@ -113,7 +117,6 @@ require (
 	go.etcd.io/etcd/client/v3 v3.6.1 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
 	go.uber.org/zap v1.27.0 // indirect
-	golang.org/x/crypto v0.43.0 // indirect
 	golang.org/x/sync v0.17.0 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20250603155806-513f23925822 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250603155806-513f23925822 // indirect
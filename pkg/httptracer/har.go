@@ -0,0 +1,336 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package httptracer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HAR 1.2 structures, see http://www.softwareishard.com/blog/har-12-spec/
+// Fields starting with "_" are custom fields, allowed by the spec.
+
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// harTimings are in milliseconds, -1 when not applicable.
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+	API             string      `json:"_api,omitempty"`
+	Attempt         int         `json:"_attempt"`
+	Error           string      `json:"_error,omitempty"`
+	ConnReused      bool        `json:"_connReused"`
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range h {
+		for _, value := range values {
+			headers = append(headers, harNameValue{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+func fromHARHeaders(headers []harNameValue) http.Header {
+	h := make(http.Header, len(headers))
+	for _, header := range headers {
+		h.Add(header.Name, header.Value)
+	}
+	return h
+}
+
+func harMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func fromHARMillis(ms float64) time.Duration {
+	if ms < 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func toHAREntry(entry Entry) harEntry {
+	proto := entry.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	h := harEntry{
+		StartedDateTime: entry.Time,
+		Time:            harMillis(entry.Timings.Total),
+		Request: harRequest{
+			Method:      entry.Method,
+			URL:         entry.URL,
+			HTTPVersion: proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(entry.RequestHeader),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    entry.RequestBytes,
+		},
+		Response: harResponse{
+			Status:      entry.StatusCode,
+			StatusText:  http.StatusText(entry.StatusCode),
+			HTTPVersion: proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(entry.ResponseHeader),
+			Content: harContent{
+				Size:     entry.ResponseBytes,
+				MimeType: entry.ResponseHeader.Get("Content-Type"),
+			},
+			HeadersSize: -1,
+			BodySize:    entry.ResponseBytes,
+		},
+		Timings: harTimings{
+			Blocked: harMillis(entry.Timings.Blocked),
+			DNS:     -1,
+			Connect: -1,
+			SSL:     -1,
+			Send:    harMillis(entry.Timings.Send),
+			Wait:    harMillis(entry.Timings.TTFB),
+			Receive: harMillis(entry.Timings.Receive),
+		},
+		API:        entry.API,
+		Attempt:    entry.Attempt,
+		Error:      entry.Error,
+		ConnReused: entry.ConnReused,
+	}
+	if u, e := url.Parse(entry.URL); e == nil {
+		for name, values := range u.Query() {
+			for _, value := range values {
+				h.Request.QueryString = append(h.Request.QueryString, harNameValue{Name: name, Value: value})
+			}
+		}
+		sort.SliceStable(h.Request.QueryString, func(i, j int) bool {
+			return h.Request.QueryString[i].Name < h.Request.QueryString[j].Name
+		})
+	}
+	if entry.RemoteAddr != "" {
+		h.ServerIPAddress = entry.RemoteAddr
+		if i := strings.LastIndexByte(entry.RemoteAddr, ':'); i > 0 {
+			h.ServerIPAddress = strings.Trim(entry.RemoteAddr[:i], "[]")
+			h.Connection = entry.RemoteAddr[i+1:]
+		}
+	}
+	if !entry.ConnReused {
+		// HAR includes the TLS handshake in the connect time.
+		h.Timings.DNS = harMillis(entry.Timings.DNS)
+		h.Timings.Connect = harMillis(entry.Timings.Connect + entry.Timings.TLS)
+		if entry.Timings.TLS > 0 {
+			h.Timings.SSL = harMillis(entry.Timings.TLS)
+		}
+	}
+	return h
+}
+
+func fromHAREntry(h harEntry) Entry {
+	entry := Entry{
+		Time:           h.StartedDateTime,
+		API:            h.API,
+		Method:         h.Request.Method,
+		URL:            h.Request.URL,
+		Proto:          h.Response.HTTPVersion,
+		Attempt:        max(h.Attempt, 1),
+		StatusCode:     h.Response.Status,
+		Error:          h.Error,
+		RequestHeader:  fromHARHeaders(h.Request.Headers),
+		ResponseHeader: fromHARHeaders(h.Response.Headers),
+		RequestBytes:   max(h.Request.BodySize, 0),
+		ResponseBytes:  max(h.Response.BodySize, 0),
+		ConnReused:     h.ConnReused,
+		Timings: Timings{
+			Blocked: fromHARMillis(h.Timings.Blocked),
+			DNS:     fromHARMillis(h.Timings.DNS),
+			TLS:     fromHARMillis(h.Timings.SSL),
+			Send:    fromHARMillis(h.Timings.Send),
+			TTFB:    fromHARMillis(h.Timings.Wait),
+			Receive: fromHARMillis(h.Timings.Receive),
+			Total:   fromHARMillis(h.Time),
+		},
+	}
+	entry.Timings.Connect = max(fromHARMillis(h.Timings.Connect)-entry.Timings.TLS, 0)
+	if h.ServerIPAddress != "" {
+		entry.RemoteAddr = h.ServerIPAddress
+		if h.Connection != "" {
+			entry.RemoteAddr += ":" + h.Connection
+		}
+	}
+	return entry
+}
+
+// harFooter closes the entries array and the log.
+const harFooter = "\n]}}\n"
+
+// harWriter keeps the file a valid HAR document after every entry, so
+// that nothing is lost if the process exits without closing it.
+type harWriter struct {
+	f       *os.File
+	entries int
+}
+
+func newHARWriter(f *os.File, creator, version string) (*harWriter, error) {
+	header, e := json.Marshal(struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+	}{"1.2", harCreator{Name: creator, Version: version}})
+	if e != nil {
+		f.Close()
+		return nil, e
+	}
+	// Open the log object and add the entries array to it.
+	header = append(header[:len(header)-1], []byte(`,"entries":[`)...)
+	if _, e = f.WriteString(`{"log":` + string(header) + harFooter); e != nil {
+		f.Close()
+		return nil, e
+	}
+	return &harWriter{f: f}, nil
+}
+
+func (w *harWriter) Write(entry Entry) error {
+	buf, e := json.Marshal(toHAREntry(entry))
+	if e != nil {
+		return e
+	}
+	if _, e = w.f.Seek(-int64(len(harFooter)), io.SeekEnd); e != nil {
+		return e
+	}
+	prefix := "\n"
+	if w.entries > 0 {
+		prefix = ",\n"
+	}
+	w.entries++
+	_, e = w.f.WriteString(prefix + string(buf) + harFooter)
+	return e
+}
+
+func (w *harWriter) Close() error {
+	return w.f.Close()
+}
+
+// ReadEntries reads the entries of a trace file written as HAR or
+// JSON lines and calls fn for each of them.
+func ReadEntries(r io.Reader, fn func(Entry) error) error {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+
+	var first json.RawMessage
+	if e := dec.Decode(&first); e != nil {
+		if e == io.EOF {
+			return nil
+		}
+		return e
+	}
+
+	var har harLog
+	if bytes.Contains(first, []byte(`"log"`)) && json.Unmarshal(first, &har) == nil && har.Log.Version != "" {
+		for _, h := range har.Log.Entries {
+			if e := fn(fromHAREntry(h)); e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+
+	for raw := first; ; {
+		var entry Entry
+		if e := json.Unmarshal(raw, &entry); e != nil {
+			return e
+		}
+		if e := fn(entry); e != nil {
+			return e
+		}
+		raw = nil
+		if e := dec.Decode(&raw); e != nil {
+			if errors.Is(e, io.EOF) {
+				return nil
+			}
+			return e
+		}
+	}
+}
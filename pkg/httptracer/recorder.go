@@ -0,0 +1,401 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package httptracer
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Timings - time spent in each phase of a request, phases that did not
+// happen, like DNS on a reused connection, are zero.
+type Timings struct {
+	Blocked time.Duration `json:"blocked"` // waiting for a connection
+	DNS     time.Duration `json:"dns"`
+	Connect time.Duration `json:"connect"` // TCP connect, without TLS
+	TLS     time.Duration `json:"tls"`
+	Send    time.Duration `json:"send"`    // writing the request
+	TTFB    time.Duration `json:"ttfb"`    // request written to first response byte
+	Receive time.Duration `json:"receive"` // reading the response body
+	Total   time.Duration `json:"total"`
+}
+
+// Entry - a recorded request and its response.
+type Entry struct {
+	Time           time.Time   `json:"time"`
+	API            string      `json:"api,omitempty"`
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	Proto          string      `json:"proto,omitempty"`
+	Attempt        int         `json:"attempt"`
+	StatusCode     int         `json:"statusCode,omitempty"`
+	Error          string      `json:"error,omitempty"`
+	RequestHeader  http.Header `json:"requestHeader,omitempty"`
+	ResponseHeader http.Header `json:"responseHeader,omitempty"`
+	RequestBytes   int64       `json:"requestBytes"`
+	ResponseBytes  int64       `json:"responseBytes"`
+	RemoteAddr     string      `json:"remoteAddr,omitempty"`
+	ConnReused     bool        `json:"connReused"`
+	Timings        Timings     `json:"timings"`
+}
+
+// Retryable returns true if the request failed in a way that S3 clients
+// retry, a following request to the same URL is then a retry.
+func (e Entry) Retryable() bool {
+	return e.Error != "" || e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// redactedHeaders are replaced by "**REDACTED**" in recorded requests.
+var redactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Amz-Security-Token",
+	"X-Amz-Server-Side-Encryption-Customer-Key",
+	"X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key",
+}
+
+// redactedQuery are the query parameters of presigned URLs replaced
+// by "**REDACTED**".
+var redactedQuery = []string{
+	"X-Amz-Credential",
+	"X-Amz-Signature",
+	"X-Amz-Security-Token",
+	"AWSAccessKeyId",
+	"Signature",
+}
+
+const redacted = "**REDACTED**"
+
+// RedactHeader returns a copy of h without credentials and keys.
+func RedactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range redactedHeaders {
+		if _, ok := h[name]; ok {
+			h.Set(name, redacted)
+		}
+	}
+	return h
+}
+
+// RedactURL returns u without the credentials of presigned URLs.
+func RedactURL(u *url.URL) string {
+	redactedURL := *u
+	redactedURL.User = nil
+	query := u.Query()
+	found := false
+	for _, name := range redactedQuery {
+		if query.Has(name) {
+			query.Set(name, redacted)
+			found = true
+		}
+	}
+	if found {
+		redactedURL.RawQuery = query.Encode()
+	}
+	return redactedURL.String()
+}
+
+// EntryWriter writes recorded entries.
+type EntryWriter interface {
+	Write(entry Entry) error
+	Close() error
+}
+
+// Recorder records every request going through its transports.
+type Recorder struct {
+	// Name returns the API name of a request, optional.
+	Name func(req *http.Request) string
+
+	w  EntryWriter
+	mu sync.Mutex
+	// attempt number of the last request of each method and URL
+	// which failed in a retryable way.
+	failed map[string]int
+	err    error
+}
+
+// NewRecorder records requests to w.
+func NewRecorder(w EntryWriter) *Recorder {
+	return &Recorder{w: w, failed: make(map[string]int)}
+}
+
+// Create creates a trace file, in HAR format if the name ends with
+// ".har", as JSON lines otherwise.
+func Create(name, creator, version string) (EntryWriter, error) {
+	f, e := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if e != nil {
+		return nil, e
+	}
+	if strings.EqualFold(filepath.Ext(name), ".har") {
+		return newHARWriter(f, creator, version)
+	}
+	return &jsonlWriter{f: f}, nil
+}
+
+// Close closes the underlying writer.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.w.Close()
+}
+
+// Err returns the first error writing an entry, no more entries are
+// written after an error.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// attempt returns the attempt number of a request starting now.
+func (r *Recorder) attempt(key string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.failed[key] + 1
+}
+
+func (r *Recorder) write(key string, entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry.Retryable() {
+		r.failed[key] = entry.Attempt
+	} else {
+		delete(r.failed, key)
+	}
+	if r.err != nil {
+		return
+	}
+	r.err = r.w.Write(entry)
+}
+
+// Transport returns a transport recording the requests made with it.
+func (r *Recorder) Transport(transport http.RoundTripper) http.RoundTripper {
+	return &recordTransport{recorder: r, transport: transport}
+}
+
+type recordTransport struct {
+	recorder  *Recorder
+	transport http.RoundTripper
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.transport == nil {
+		return nil, errors.New("Invalid Argument")
+	}
+
+	key := req.Method + " " + req.URL.String()
+	rec := &recording{
+		recorder: t.recorder,
+		key:      key,
+		start:    time.Now(),
+		entry: Entry{
+			Method:        req.Method,
+			URL:           RedactURL(req.URL),
+			Attempt:       t.recorder.attempt(key),
+			RequestHeader: RedactHeader(req.Header),
+		},
+	}
+	rec.entry.Time = rec.start
+	if t.recorder.Name != nil {
+		rec.entry.API = t.recorder.Name(req)
+	}
+
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), rec.clientTrace()))
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &countingBody{ReadCloser: req.Body, n: &rec.requestBytes}
+	}
+
+	res, e := t.transport.RoundTrip(req)
+	if e != nil {
+		rec.entry.Error = e.Error()
+		rec.finish()
+		return res, e
+	}
+
+	rec.mu.Lock()
+	rec.entry.StatusCode = res.StatusCode
+	rec.entry.Proto = res.Proto
+	rec.entry.ResponseHeader = RedactHeader(res.Header)
+	rec.mu.Unlock()
+	if res.Body == nil || res.Body == http.NoBody {
+		rec.finish()
+		return res, nil
+	}
+	res.Body = &recordBody{ReadCloser: res.Body, rec: rec}
+	return res, nil
+}
+
+// recording - state of a request in flight.
+type recording struct {
+	recorder *Recorder
+	key      string
+	start    time.Time
+
+	// the request body may still be written by the transport
+	// while the response is read.
+	requestBytes atomic.Int64
+
+	mu                               sync.Mutex
+	entry                            Entry
+	dnsStart, connectStart, tlsStart time.Time
+	gotConn, wroteRequest, firstByte time.Time
+	done                             bool
+}
+
+func (rec *recording) clientTrace() *httptrace.ClientTrace {
+	locked := func(f func()) {
+		rec.mu.Lock()
+		f()
+		rec.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { locked(func() { rec.dnsStart = time.Now() }) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			locked(func() { rec.entry.Timings.DNS += time.Since(rec.dnsStart) })
+		},
+		ConnectStart: func(string, string) { locked(func() { rec.connectStart = time.Now() }) },
+		ConnectDone: func(string, string, error) {
+			locked(func() { rec.entry.Timings.Connect += time.Since(rec.connectStart) })
+		},
+		TLSHandshakeStart: func() { locked(func() { rec.tlsStart = time.Now() }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			locked(func() { rec.entry.Timings.TLS += time.Since(rec.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			locked(func() {
+				rec.gotConn = time.Now()
+				rec.entry.ConnReused = info.Reused
+				if info.Conn != nil {
+					rec.entry.RemoteAddr = info.Conn.RemoteAddr().String()
+				}
+			})
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { locked(func() { rec.wroteRequest = time.Now() }) },
+		GotFirstResponseByte: func() { locked(func() { rec.firstByte = time.Now() }) },
+	}
+}
+
+// finish computes the timings and writes the entry, only once.
+func (rec *recording) finish() {
+	rec.mu.Lock()
+	if rec.done {
+		rec.mu.Unlock()
+		return
+	}
+	rec.done = true
+	end := time.Now()
+
+	rec.entry.RequestBytes = rec.requestBytes.Load()
+	t := &rec.entry.Timings
+	t.Total = end.Sub(rec.start)
+	if !rec.gotConn.IsZero() {
+		// Blocked is the wait for a connection besides dialing it.
+		t.Blocked = max(rec.gotConn.Sub(rec.start)-t.DNS-t.Connect-t.TLS, 0)
+		if !rec.wroteRequest.IsZero() {
+			t.Send = rec.wroteRequest.Sub(rec.gotConn)
+		}
+	}
+	if !rec.firstByte.IsZero() {
+		if !rec.wroteRequest.IsZero() {
+			// Small requests may get their first byte before the
+			// body is written, like for "100 Continue".
+			t.TTFB = max(rec.firstByte.Sub(rec.wroteRequest), 0)
+		}
+		t.Receive = end.Sub(rec.firstByte)
+	}
+	entry := rec.entry
+	rec.mu.Unlock()
+
+	rec.recorder.write(rec.key, entry)
+}
+
+// countingBody counts the bytes read from the request body.
+type countingBody struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, e := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, e
+}
+
+// recordBody counts the bytes of the response body, the entry is written
+// once the body is read entirely or closed.
+type recordBody struct {
+	io.ReadCloser
+	rec *recording
+}
+
+func (b *recordBody) Read(p []byte) (int, error) {
+	n, e := b.ReadCloser.Read(p)
+	b.rec.mu.Lock()
+	b.rec.entry.ResponseBytes += int64(n)
+	b.rec.mu.Unlock()
+	if e != nil {
+		if e != io.EOF {
+			b.rec.mu.Lock()
+			b.rec.entry.Error = e.Error()
+			b.rec.mu.Unlock()
+		}
+		b.rec.finish()
+	}
+	return n, e
+}
+
+func (b *recordBody) Close() error {
+	e := b.ReadCloser.Close()
+	b.rec.finish()
+	return e
+}
+
+// jsonlWriter writes one entry per line.
+type jsonlWriter struct {
+	f *os.File
+}
+
+func (w *jsonlWriter) Write(entry Entry) error {
+	buf, e := json.Marshal(entry)
+	if e != nil {
+		return e
+	}
+	_, e = w.f.Write(append(buf, '\n'))
+	return e
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.f.Close()
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package httptracer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+
+	for _, name := range []string{"trace.har", "trace.jsonl"} {
+		calls.Store(0)
+		file := filepath.Join(t.TempDir(), name)
+		w, e := Create(file, "mc", "test")
+		if e != nil {
+			t.Fatal(e)
+		}
+		recorder := NewRecorder(w)
+		recorder.Name = func(*http.Request) string { return "PutObject" }
+		client := &http.Client{Transport: recorder.Transport(http.DefaultTransport)}
+
+		for i := 0; i < 2; i++ {
+			req, _ := http.NewRequest(http.MethodPut, server.URL+"/bucket/object?X-Amz-Signature=secret", strings.NewReader("12345"))
+			req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=secret")
+			res, e := client.Do(req)
+			if e != nil {
+				t.Fatal(e)
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		if e = recorder.Close(); e != nil {
+			t.Fatal(e)
+		}
+
+		buf, e := os.ReadFile(file)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if strings.Contains(string(buf), "secret") {
+			t.Fatalf("%s: credentials were not redacted", name)
+		}
+
+		f, e := os.Open(file)
+		if e != nil {
+			t.Fatal(e)
+		}
+		var entries []Entry
+		e = ReadEntries(f, func(entry Entry) error {
+			entries = append(entries, entry)
+			return nil
+		})
+		f.Close()
+		if e != nil {
+			t.Fatalf("%s: %v", name, e)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("%s: expected 2 entries, got %d", name, len(entries))
+		}
+		first, second := entries[0], entries[1]
+		if first.StatusCode != http.StatusServiceUnavailable || first.Attempt != 1 {
+			t.Errorf("%s: unexpected first entry %+v", name, first)
+		}
+		if second.StatusCode != http.StatusOK || second.Attempt != 2 {
+			t.Errorf("%s: expected a retry, got %+v", name, second)
+		}
+		if second.API != "PutObject" || second.RequestBytes != 5 || second.ResponseBytes != 11 {
+			t.Errorf("%s: unexpected second entry %+v", name, second)
+		}
+		if second.Timings.Total <= 0 {
+			t.Errorf("%s: expected timings, got %+v", name, second.Timings)
+		}
+	}
+}
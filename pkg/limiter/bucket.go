@@ -0,0 +1,99 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// Limit - a bandwidth budget which transfers take bytes from.
+type Limit interface {
+	// Wait blocks until n bytes can be transferred.
+	Wait(n int)
+}
+
+// Bucket - token bucket whose rate follows a schedule, the rate is
+// adjusted live when the schedule moves to another rule. A bucket holds
+// at most one second worth of tokens.
+type Bucket struct {
+	mu       sync.Mutex
+	schedule *Schedule
+	rate     int64
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+}
+
+// NewBucket returns a bucket limiting at the rate of the schedule.
+func NewBucket(schedule *Schedule) *Bucket {
+	return &Bucket{schedule: schedule, now: time.Now}
+}
+
+// SetSchedule replaces the schedule of the bucket.
+func (b *Bucket) SetSchedule(schedule *Schedule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.schedule = schedule
+}
+
+// Schedule returns the schedule of the bucket.
+func (b *Bucket) Schedule() *Schedule {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.schedule
+}
+
+// Reserve takes n bytes from the bucket and returns how long the caller
+// must wait before transferring them.
+func (b *Bucket) Reserve(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	rate := b.schedule.Rate(now)
+	if rate != b.rate {
+		// Start over with the new rate, a debt taken at a lower
+		// rate must not slow down the new one or the reverse.
+		b.rate = rate
+		b.tokens = float64(rate)
+		b.last = now
+	}
+	if rate <= 0 {
+		return 0
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * float64(rate)
+	b.tokens = min(b.tokens, float64(rate))
+	b.last = now
+
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / float64(rate) * float64(time.Second))
+}
+
+// Wait blocks until n bytes can be transferred.
+func (b *Bucket) Wait(n int) {
+	if d := b.Reserve(n); d > 0 {
+		time.Sleep(d)
+	}
+}
@@ -22,21 +22,34 @@ import (
 	"errors"
 	"io"
 	"net/http"
-
-	"github.com/juju/ratelimit"
 )
 
 type limiter struct {
-	upload    *ratelimit.Bucket
-	download  *ratelimit.Bucket
+	upload    Limit
+	download  Limit
 	transport http.RoundTripper // HTTP transport that needs to be intercepted
 }
 
-func (l limiter) limitReader(r io.Reader, b *ratelimit.Bucket) io.Reader {
-	if b == nil {
+// limitReader waits for the budget after every read, like
+// ratelimit.Reader does.
+type limitReader struct {
+	r     io.Reader
+	limit Limit
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	n, e := l.r.Read(p)
+	if n > 0 {
+		l.limit.Wait(n)
+	}
+	return n, e
+}
+
+func (l limiter) limitReader(r io.Reader, limit Limit) io.Reader {
+	if limit == nil {
 		return r
 	}
-	return ratelimit.Reader(r, b)
+	return &limitReader{r: r, limit: limit}
 }
 
 // RoundTrip executes user provided request and response hooks for each HTTP call.
@@ -70,26 +83,26 @@ func (l limiter) RoundTrip(req *http.Request) (res *http.Response, err error) {
 
 // New return a ratelimited transport
 func New(uploadLimit, downloadLimit int64, transport http.RoundTripper) http.RoundTripper {
-	if uploadLimit == 0 && downloadLimit == 0 {
-		return transport
-	}
-
-	var (
-		uploadBucket   *ratelimit.Bucket
-		downloadBucket *ratelimit.Bucket
-	)
-
+	var upload, download Limit
 	if uploadLimit > 0 {
-		uploadBucket = ratelimit.NewBucketWithRate(float64(uploadLimit), uploadLimit)
+		upload = NewBucket(NewFixedSchedule(uploadLimit))
 	}
-
 	if downloadLimit > 0 {
-		downloadBucket = ratelimit.NewBucketWithRate(float64(downloadLimit), downloadLimit)
+		download = NewBucket(NewFixedSchedule(downloadLimit))
 	}
+	return NewWithLimits(upload, download, transport)
+}
 
+// NewWithLimits returns a transport taking the bytes of request bodies
+// from upload and the bytes of response bodies from download, nil limits
+// are unlimited. Limits can be shared by several transports.
+func NewWithLimits(upload, download Limit, transport http.RoundTripper) http.RoundTripper {
+	if upload == nil && download == nil {
+		return transport
+	}
 	return &limiter{
-		upload:    uploadBucket,
-		download:  downloadBucket,
+		upload:    upload,
+		download:  download,
 		transport: transport,
 	}
 }
@@ -0,0 +1,262 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package limiter
+
+import (
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// 2024-01-01 is a monday.
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, 0, 0, time.Local)
+	}
+	saturday := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 6, hour, minute, 0, 0, time.Local)
+	}
+
+	testCases := []struct {
+		spec string
+		at   time.Time
+		rate int64
+	}{
+		{"1MiB/s", monday(12, 0), 1 << 20},
+		{"1MiB", saturday(3, 0), 1 << 20},
+		{"50MiB/s 08:00-18:00 weekdays, unlimited", monday(8, 0), 50 << 20},
+		{"50MiB/s 08:00-18:00 weekdays, unlimited", monday(18, 0), 0},
+		{"50MiB/s 08:00-18:00 weekdays, unlimited", saturday(12, 0), 0},
+		{"10MiB/s 22:00-06:00, 1MiB/s", monday(23, 30), 10 << 20},
+		{"10MiB/s 22:00-06:00, 1MiB/s", monday(5, 59), 10 << 20},
+		{"10MiB/s 22:00-06:00, 1MiB/s", monday(6, 0), 1 << 20},
+		{"2MiB/s weekends, 1MiB/s sun-tue", saturday(1, 0), 2 << 20},
+		{"2MiB/s sat sun", monday(1, 0), 0},
+		{"2MiB/s fri-mon", monday(1, 0), 2 << 20},
+		{"2MiB/s 18:00-24:00 daily", monday(23, 59), 2 << 20},
+	}
+	for i, testCase := range testCases {
+		s, e := ParseSchedule(testCase.spec)
+		if e != nil {
+			t.Fatalf("Test %d: unexpected error %v", i+1, e)
+		}
+		if rate := s.Rate(testCase.at); rate != testCase.rate {
+			t.Errorf("Test %d: expected rate %d at %s, got %d", i+1, testCase.rate, testCase.at, rate)
+		}
+	}
+
+	for _, spec := range []string{"", "fast", "1MiB/s 08:00", "1MiB/s 25:00-26:00", "1MiB/s someday", "1MiB/s 01:00-02:00 03:00-04:00", "1MiB/s,"} {
+		if _, e := ParseSchedule(spec); e == nil {
+			t.Errorf("Expected %q to fail", spec)
+		}
+	}
+}
+
+func TestBucketReserve(t *testing.T) {
+	now := time.Date(2024, 1, 1, 17, 59, 0, 0, time.Local)
+	s, e := ParseSchedule("1000 08:00-18:00, 4000")
+	if e != nil {
+		t.Fatal(e)
+	}
+	b := NewBucket(s)
+	b.now = func() time.Time { return now }
+
+	// A burst of one second is allowed.
+	if d := b.Reserve(1000); d != 0 {
+		t.Fatalf("expected no wait, got %s", d)
+	}
+	if d := b.Reserve(500); d != 500*time.Millisecond {
+		t.Fatalf("expected 500ms wait, got %s", d)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if d := b.Reserve(1000); d != time.Second {
+		t.Fatalf("expected 1s wait, got %s", d)
+	}
+
+	// The rate follows the schedule, the debt does not carry over.
+	now = now.Add(time.Minute)
+	if d := b.Reserve(4000); d != 0 {
+		t.Fatalf("expected no wait after the rate change, got %s", d)
+	}
+	if d := b.Reserve(2000); d != 500*time.Millisecond {
+		t.Fatalf("expected 500ms wait, got %s", d)
+	}
+
+	b.SetSchedule(NewFixedSchedule(0))
+	if d := b.Reserve(1 << 30); d != 0 {
+		t.Fatalf("expected no wait when unlimited, got %s", d)
+	}
+}
+
+func TestSharedLimit(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "limits.sock")
+	l, e := net.Listen("unix", socket)
+	if e != nil {
+		t.Skip("unix sockets are not supported:", e)
+	}
+	server := NewServer()
+	go server.Serve(l)
+	defer l.Close()
+
+	schedule := NewFixedSchedule(1000)
+	var clients []*SharedClient
+	for i := 0; i < 2; i++ {
+		c, e := DialShared(socket, time.Second)
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer c.Close()
+		clients = append(clients, c)
+	}
+
+	// Both clients draw from the same budget.
+	if d, e := clients[0].reserve(sharedRequest{Key: "alias:myminio/upload", Schedule: schedule.String(), N: 1000}); e != nil || d != 0 {
+		t.Fatalf("expected no wait, got %s, %v", d, e)
+	}
+	if d, e := clients[1].reserve(sharedRequest{Key: "alias:myminio/upload", Schedule: schedule.String(), N: 1000}); e != nil || d < 900*time.Millisecond {
+		t.Fatalf("expected about 1s wait, got %s, %v", d, e)
+	}
+	if d, e := clients[1].reserve(sharedRequest{Key: "alias:other/upload", Schedule: schedule.String(), N: 1000}); e != nil || d != 0 {
+		t.Fatalf("expected no wait for another key, got %s, %v", d, e)
+	}
+
+	// Another schedule for the same key does not reset the budget.
+	other := NewFixedSchedule(2000)
+	if d, e := clients[0].reserve(sharedRequest{Key: "alias:myminio/upload", Schedule: other.String(), N: 2000}); e != nil || d != 0 {
+		t.Fatalf("expected no wait, got %s, %v", d, e)
+	}
+	if d, e := clients[1].reserve(sharedRequest{Key: "alias:myminio/upload", Schedule: schedule.String(), N: 1000}); e != nil || d < 1900*time.Millisecond {
+		t.Fatalf("expected about 2s wait, got %s, %v", d, e)
+	}
+	if d, e := clients[1].reserve(sharedRequest{Key: "alias:myminio/upload", Schedule: other.String(), N: 2000}); e != nil || d < 900*time.Millisecond {
+		t.Fatalf("expected about 1s wait, got %s, %v", d, e)
+	}
+
+	if _, e := clients[0].reserve(sharedRequest{Key: "alias:myminio/upload", Schedule: "fast", N: 1}); e == nil {
+		t.Fatal("expected an invalid schedule to fail")
+	}
+
+	// A closed connection falls back to the local limit.
+	fallback := &countingLimit{}
+	limit := clients[0].Limit("alias:myminio/download", schedule, fallback)
+	clients[0].Close()
+	limit.Wait(10)
+	if fallback.n != 10 {
+		t.Fatalf("expected the fallback to be used, got %d", fallback.n)
+	}
+}
+
+func TestSharedLimitRedial(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "limits.sock")
+	l, e := net.Listen("unix", socket)
+	if e != nil {
+		t.Skip("unix sockets are not supported:", e)
+	}
+	go NewServer().Serve(l)
+	defer l.Close()
+
+	saved := sharedRedialInterval
+	sharedRedialInterval = 50 * time.Millisecond
+	defer func() { sharedRedialInterval = saved }()
+
+	c, e := DialShared(socket, time.Second)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+
+	// Concurrent requests share the connection.
+	req := sharedRequest{Key: "alias:myminio/upload", Schedule: NewFixedSchedule(0).String(), N: 1}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, e := c.reserve(req); e != nil {
+				t.Error(e)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// A lost connection is dialed again.
+	c.mu.Lock()
+	c.conn.Close()
+	c.mu.Unlock()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, e = c.reserve(req); e == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the connection to be dialed again, got %v", e)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSharedLimitTimeout(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "limits.sock")
+	l, e := net.Listen("unix", socket)
+	if e != nil {
+		t.Skip("unix sockets are not supported:", e)
+	}
+	defer l.Close()
+
+	// A server accepting connections but never answering.
+	go func() {
+		for {
+			conn, e := l.Accept()
+			if e != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	c, e := DialShared(socket, 50*time.Millisecond)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+
+	fallback := &countingLimit{}
+	done := make(chan struct{})
+	go func() {
+		c.Limit("alias:myminio/upload", NewFixedSchedule(1000), fallback).Wait(10)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the request to time out")
+	}
+	if fallback.n != 10 {
+		t.Fatalf("expected the fallback to be used, got %d", fallback.n)
+	}
+}
+
+type countingLimit struct {
+	n int
+}
+
+func (c *countingLimit) Wait(n int) {
+	c.n += n
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// dayNames maps day names to week days.
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// rule - a rate applying during a time window on some week days.
+type rule struct {
+	rate int64 // bytes per second, 0 is unlimited
+
+	// minutes since midnight, the window wraps around midnight
+	// if from is after to. Both zero matches the whole day.
+	from, to int
+
+	days [7]bool
+}
+
+func (r rule) matches(t time.Time) bool {
+	if !r.days[t.Weekday()] {
+		return false
+	}
+	if r.from == r.to {
+		return true
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if r.from < r.to {
+		return minute >= r.from && minute < r.to
+	}
+	return minute >= r.from || minute < r.to
+}
+
+// Schedule - bandwidth limit depending on the time of day and the
+// day of the week.
+type Schedule struct {
+	rules []rule
+	spec  string
+}
+
+// ParseSchedule parses a comma separated list of rules, each is a rate
+// optionally followed by a time window and days of the week, such as
+//
+//	50MiB 08:00-18:00 weekdays, 10MiB 08:00-18:00 weekends, unlimited
+//
+// The first rule matching the local time applies, transfers are not
+// limited if no rule matches. Rates accept the units of humanize,
+// an optional "/s" suffix and "unlimited". Days are names (mon, tue, ...),
+// ranges (mon-fri), weekdays, weekends or daily. A plain rate like "1MiB"
+// limits at all times.
+func ParseSchedule(spec string) (*Schedule, error) {
+	s := &Schedule{spec: strings.TrimSpace(spec)}
+	if s.spec == "" {
+		return nil, errors.New("empty schedule")
+	}
+	for _, ruleSpec := range strings.Split(s.spec, ",") {
+		r, e := parseRule(strings.Fields(ruleSpec))
+		if e != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", strings.TrimSpace(ruleSpec), e)
+		}
+		s.rules = append(s.rules, r)
+	}
+	return s, nil
+}
+
+// NewFixedSchedule returns a schedule limiting at rate at all times.
+func NewFixedSchedule(rate int64) *Schedule {
+	r := rule{rate: rate}
+	for i := range r.days {
+		r.days[i] = true
+	}
+	return &Schedule{rules: []rule{r}, spec: strconv.FormatInt(rate, 10)}
+}
+
+func parseRule(fields []string) (r rule, e error) {
+	if len(fields) == 0 {
+		return r, errors.New("missing rate")
+	}
+	rate := strings.TrimSuffix(strings.ToLower(fields[0]), "/s")
+	if rate != "unlimited" {
+		n, e := humanize.ParseBytes(rate)
+		if e != nil {
+			return r, fmt.Errorf("invalid rate %q", fields[0])
+		}
+		r.rate = int64(n)
+	}
+
+	var hasWindow, hasDays bool
+	for _, field := range fields[1:] {
+		field = strings.ToLower(field)
+		if strings.Contains(field, ":") {
+			if hasWindow {
+				return r, errors.New("more than one time window")
+			}
+			if r.from, r.to, e = parseWindow(field); e != nil {
+				return r, e
+			}
+			hasWindow = true
+			continue
+		}
+		if e = parseDays(field, &r.days); e != nil {
+			return r, e
+		}
+		hasDays = true
+	}
+	if !hasDays {
+		for i := range r.days {
+			r.days[i] = true
+		}
+	}
+	return r, nil
+}
+
+// parseWindow parses HH:MM-HH:MM into minutes since midnight.
+func parseWindow(field string) (from, to int, e error) {
+	fromStr, toStr, ok := strings.Cut(field, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid time window %q", field)
+	}
+	parse := func(s string) (int, error) {
+		if s == "24:00" {
+			return 0, nil
+		}
+		t, e := time.Parse("15:04", s)
+		if e != nil {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if from, e = parse(fromStr); e != nil {
+		return 0, 0, e
+	}
+	if to, e = parse(toStr); e != nil {
+		return 0, 0, e
+	}
+	return from, to, nil
+}
+
+// parseDays marks the days of field in days.
+func parseDays(field string, days *[7]bool) error {
+	switch field {
+	case "daily":
+		field = "sun-sat"
+	case "weekdays":
+		field = "mon-fri"
+	case "weekends":
+		field = "sat-sun"
+	}
+	fromStr, toStr, isRange := strings.Cut(field, "-")
+	from, ok := dayNames[fromStr]
+	if !ok {
+		return fmt.Errorf("invalid day %q", fromStr)
+	}
+	to := from
+	if isRange {
+		if to, ok = dayNames[toStr]; !ok {
+			return fmt.Errorf("invalid day %q", toStr)
+		}
+	}
+	for d := from; ; d = (d + 1) % 7 {
+		days[d] = true
+		if d == to {
+			return nil
+		}
+	}
+}
+
+// Rate returns the limit in bytes per second at t, 0 means unlimited.
+func (s *Schedule) Rate(t time.Time) int64 {
+	for _, r := range s.rules {
+		if r.matches(t) {
+			return r.rate
+		}
+	}
+	return 0
+}
+
+// String returns the schedule as given to ParseSchedule.
+func (s *Schedule) String() string {
+	return s.spec
+}
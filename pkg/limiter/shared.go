@@ -0,0 +1,275 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package limiter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// errSharedUnavailable - the server cannot be reached for now.
+var errSharedUnavailable = errors.New("shared limits server unavailable")
+
+// sharedRequest - a request to take bytes from a shared budget.
+type sharedRequest struct {
+	Key      string `json:"key"`
+	Schedule string `json:"schedule"`
+	N        int    `json:"n"`
+}
+
+// sharedResponse - how long to wait before transferring the bytes.
+type sharedResponse struct {
+	Wait  time.Duration `json:"wait"`
+	Error string        `json:"error,omitempty"`
+}
+
+// Server - shares budgets between processes over a local socket. Every
+// budget is identified by a key and a schedule, processes sending another
+// schedule for the same key draw from another budget.
+type Server struct {
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+// NewServer returns a server without budgets.
+func NewServer() *Server {
+	return &Server{buckets: make(map[string]*Bucket)}
+}
+
+// reserve takes bytes from the budget of the request.
+func (s *Server) reserve(req sharedRequest) (time.Duration, error) {
+	// A schedule replacing the one of the bucket would reset its tokens
+	// on every request of processes disagreeing on the schedule.
+	key := req.Key + "\x00" + req.Schedule
+
+	s.mu.Lock()
+	b, ok := s.buckets[key]
+	if !ok {
+		schedule, e := ParseSchedule(req.Schedule)
+		if e != nil {
+			s.mu.Unlock()
+			return 0, e
+		}
+		b = NewBucket(schedule)
+		s.buckets[key] = b
+	}
+	s.mu.Unlock()
+	return b.Reserve(req.N), nil
+}
+
+// Serve accepts connections on l until it is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, e := l.Accept()
+		if e != nil {
+			if errors.Is(e, net.ErrClosed) {
+				return nil
+			}
+			return e
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	enc := json.NewEncoder(conn)
+	for {
+		var req sharedRequest
+		if e := dec.Decode(&req); e != nil {
+			return
+		}
+		var resp sharedResponse
+		wait, e := s.reserve(req)
+		if e != nil {
+			resp.Error = e.Error()
+		}
+		resp.Wait = wait
+		if e = enc.Encode(resp); e != nil {
+			return
+		}
+	}
+}
+
+// sharedRedialInterval - minimum delay between two connection attempts
+// to a server that went away.
+var sharedRedialInterval = time.Second
+
+// SharedClient - takes bytes from the budgets of a Server. Requests of
+// concurrent transfers are pipelined on a single connection, the server
+// answers them in order. The connection is dialed again once lost.
+type SharedClient struct {
+	socket  string
+	timeout time.Duration
+
+	mu       sync.Mutex
+	conn     net.Conn
+	enc      *json.Encoder
+	pending  []chan sharedResponse // in request order
+	lastDial time.Time
+	closed   bool
+}
+
+// DialShared connects to the server listening on the unix socket. The
+// timeout applies to dialing and to waiting for every response.
+func DialShared(socket string, timeout time.Duration) (*SharedClient, error) {
+	c := &SharedClient{socket: socket, timeout: timeout}
+	if e := c.connect(); e != nil {
+		return nil, e
+	}
+	return c, nil
+}
+
+// connect dials the server if not connected, c.mu must be held.
+func (c *SharedClient) connect() error {
+	if c.closed {
+		return net.ErrClosed
+	}
+	if c.conn != nil {
+		return nil
+	}
+	if time.Since(c.lastDial) < sharedRedialInterval {
+		return errSharedUnavailable
+	}
+	c.lastDial = time.Now()
+	conn, e := net.DialTimeout("unix", c.socket, c.timeout)
+	if e != nil {
+		return e
+	}
+	c.conn = conn
+	c.enc = json.NewEncoder(conn)
+	go c.readResponses(conn)
+	return nil
+}
+
+// disconnect closes conn if it is still in use, pending requests fail.
+// c.mu must be held.
+func (c *SharedClient) disconnect(conn net.Conn) error {
+	if c.conn != conn {
+		return nil
+	}
+	e := c.conn.Close()
+	c.conn = nil
+	for _, ch := range c.pending {
+		close(ch)
+	}
+	c.pending = nil
+	return e
+}
+
+// readResponses hands the responses read from conn to the pending
+// requests, until conn fails or is replaced.
+func (c *SharedClient) readResponses(conn net.Conn) {
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var resp sharedResponse
+		e := dec.Decode(&resp)
+
+		c.mu.Lock()
+		if e != nil || c.conn != conn || len(c.pending) == 0 {
+			c.disconnect(conn)
+			c.mu.Unlock()
+			return
+		}
+		ch := c.pending[0]
+		c.pending = c.pending[1:]
+		c.mu.Unlock()
+
+		ch <- resp
+	}
+}
+
+func (c *SharedClient) reserve(req sharedRequest) (time.Duration, error) {
+	ch := make(chan sharedResponse, 1)
+
+	c.mu.Lock()
+	if e := c.connect(); e != nil {
+		c.mu.Unlock()
+		return 0, e
+	}
+	conn := c.conn
+	c.pending = append(c.pending, ch)
+	if e := c.enc.Encode(req); e != nil {
+		c.disconnect(conn)
+	}
+	c.mu.Unlock()
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	var resp sharedResponse
+	var ok bool
+	select {
+	case resp, ok = <-ch:
+	case <-timer.C:
+		// Responses are matched to requests by their order, a server
+		// not answering in time cannot be used anymore.
+		c.mu.Lock()
+		c.disconnect(conn)
+		c.mu.Unlock()
+	}
+	if !ok {
+		return 0, errSharedUnavailable
+	}
+	if resp.Error != "" {
+		return 0, errors.New(resp.Error)
+	}
+	return resp.Wait, nil
+}
+
+// Close closes the connection to the server, it is not dialed again.
+func (c *SharedClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	return c.disconnect(c.conn)
+}
+
+// Limit returns the budget identified by key on the server. The local
+// bucket fallback is used while the server cannot be reached.
+func (c *SharedClient) Limit(key string, schedule *Schedule, fallback Limit) Limit {
+	return &sharedLimit{client: c, key: key, schedule: schedule, fallback: fallback}
+}
+
+type sharedLimit struct {
+	client   *SharedClient
+	key      string
+	schedule *Schedule
+	fallback Limit
+}
+
+func (l *sharedLimit) Wait(n int) {
+	wait, e := l.client.reserve(sharedRequest{Key: l.key, Schedule: l.schedule.String(), N: n})
+	if e != nil {
+		l.fallback.Wait(n)
+		return
+	}
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}